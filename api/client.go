@@ -384,6 +384,8 @@ type Client struct {
 	wrappingLookupFunc WrappingLookupFunc
 	mfaCreds           []string
 	policyOverride     bool
+	readYourWrites     bool
+	lastIndex          string
 }
 
 // NewClient returns a new client for the given configuration.
@@ -700,6 +702,30 @@ func (c *Client) SetPolicyOverride(override bool) {
 	c.policyOverride = override
 }
 
+// SetReadYourWrites sets whether the client requires each request to observe
+// the storage writes of its previous requests. The storage index returned by
+// the server in the X-Vault-Index header is sent back with the following
+// requests, and the requests failing because a node hasn't caught up yet are
+// retried.
+func (c *Client) SetReadYourWrites(preventStaleReads bool) {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	c.readYourWrites = preventStaleReads
+	if !preventStaleReads {
+		c.lastIndex = ""
+	}
+}
+
+// ReadYourWrites returns whether the client requires each request to observe
+// the storage writes of its previous requests.
+func (c *Client) ReadYourWrites() bool {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	return c.readYourWrites
+}
+
 // NewRequest creates a new raw request object to query the Vault server
 // configured for this client. This is an advanced method and generally
 // doesn't need to be called externally.
@@ -710,6 +736,7 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	mfaCreds := c.mfaCreds
 	wrappingLookupFunc := c.wrappingLookupFunc
 	policyOverride := c.policyOverride
+	lastIndex := c.lastIndex
 	c.modifyLock.RUnlock()
 
 	var host = addr.Host
@@ -756,6 +783,12 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 
 	req.Headers = c.Headers()
 	req.PolicyOverride = policyOverride
+	if lastIndex != "" {
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
+		req.Headers.Set(consts.IndexHeaderName, lastIndex)
+	}
 
 	return req
 }
@@ -773,6 +806,7 @@ func (c *Client) RawRequest(r *Request) (*Response, error) {
 func (c *Client) RawRequestWithContext(ctx context.Context, r *Request) (*Response, error) {
 	c.modifyLock.RLock()
 	token := c.token
+	readYourWrites := c.readYourWrites
 
 	c.config.modifyLock.RLock()
 	limiter := c.config.Limiter
@@ -828,6 +862,9 @@ START:
 
 	if checkRetry == nil {
 		checkRetry = retryablehttp.DefaultRetryPolicy
+		if readYourWrites {
+			checkRetry = staleReadRetryPolicy
+		}
 	}
 
 	client := &retryablehttp.Client{
@@ -844,6 +881,14 @@ START:
 	resp, err := client.Do(req)
 	if resp != nil {
 		result = &Response{Response: resp}
+
+		if index := resp.Header.Get(consts.IndexHeaderName); readYourWrites && index != "" {
+			c.modifyLock.Lock()
+			if c.readYourWrites {
+				c.lastIndex = index
+			}
+			c.modifyLock.Unlock()
+		}
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "tls: oversized") {
@@ -894,3 +939,14 @@ START:
 
 	return result, nil
 }
+
+// staleReadRetryPolicy is the default retry policy of the clients requiring
+// read-after-write consistency. On top of the default policy, the requests
+// failing because the node hasn't applied the required storage writes yet are
+// retried.
+func staleReadRetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if err == nil && resp != nil && resp.StatusCode == http.StatusPreconditionFailed {
+		return true, nil
+	}
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
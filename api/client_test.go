@@ -2,11 +2,15 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/helper/consts"
 )
@@ -97,6 +101,60 @@ func TestClientToken(t *testing.T) {
 	}
 }
 
+func TestClientReadYourWrites(t *testing.T) {
+	var lock sync.Mutex
+	var received []string
+	stale := 1
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		received = append(received, req.Header.Get(consts.IndexHeaderName))
+		if req.Header.Get(consts.IndexHeaderName) == "index2" && stale > 0 {
+			stale--
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set(consts.IndexHeaderName, fmt.Sprintf("index%d", len(received)))
+	}
+
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+	config.Backoff = func(_, _ time.Duration, _ int, _ *http.Response) time.Duration {
+		return time.Millisecond
+	}
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The index isn't sent until read-after-write consistency is enabled
+	if _, err := client.RawRequest(client.NewRequest("GET", "/")); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadYourWrites(true)
+	for i := 0; i < 2; i++ {
+		if _, err := client.RawRequest(client.NewRequest("GET", "/")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The last request fails once with a 412 and is retried
+	expected := []string{"", "", "index2", "index2"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, received)
+	}
+
+	client.SetReadYourWrites(false)
+	if _, err := client.RawRequest(client.NewRequest("GET", "/")); err != nil {
+		t.Fatal(err)
+	}
+	if received[len(received)-1] != "" {
+		t.Fatalf("unexpected index sent: %v", received)
+	}
+}
+
 func TestClientHostHeader(t *testing.T) {
 	handler := func(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte(req.Host))
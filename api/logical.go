@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 
@@ -145,6 +146,22 @@ func (c *Logical) WriteBytes(path string, data []byte) (*Secret, error) {
 	return c.write(path, r)
 }
 
+// JSONMergePatch applies the data to the given path with JSON merge patch
+// semantics (RFC 7386): nested objects are merged, null values remove the key
+// and any other value replaces the existing one.
+func (c *Logical) JSONMergePatch(path string, data map[string]interface{}) (*Secret, error) {
+	r := c.c.NewRequest("PATCH", "/v1/"+path)
+	if r.Headers == nil {
+		r.Headers = make(http.Header)
+	}
+	r.Headers.Set("Content-Type", "application/merge-patch+json")
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	return c.write(path, r)
+}
+
 func (c *Logical) write(path string, request *Request) (*Secret, error) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -19,6 +19,17 @@ var (
 	// to bootstrap mounting plugins.
 	PluginMetadataModeEnv = "VAULT_PLUGIN_METADATA_MODE"
 
+	// PluginAutoMTLSEnv is the ENV name used to tell the plugin that vault
+	// passes the generated TLS certificate directly, in which case no unwrap
+	// token is passed.
+	PluginAutoMTLSEnv = "VAULT_PLUGIN_AUTOMTLS_ENABLED"
+
+	// PluginTLSCertEnv and PluginTLSKeyEnv are the ENV names used to pass the
+	// base64 encoded certificate and private key to the plugin in automatic
+	// mTLS mode.
+	PluginTLSCertEnv = "VAULT_PLUGIN_TLS_CERT"
+	PluginTLSKeyEnv  = "VAULT_PLUGIN_TLS_KEY"
+
 	// PluginUnwrapTokenEnv is the ENV name used to pass unwrap tokens to the
 	// plugin.
 	PluginUnwrapTokenEnv = "VAULT_UNWRAP_TOKEN"
@@ -68,12 +79,20 @@ func (f *PluginAPIClientMeta) GetTLSConfig() *TLSConfig {
 }
 
 // VaultPluginTLSProvider is run inside a plugin and retrieves the response
-// wrapped TLS certificate from vault. It returns a configured TLS Config.
+// wrapped TLS certificate from vault. It returns a configured TLS Config. In
+// automatic mTLS mode the certificate is read from the ENV instead, so the
+// plugin doesn't need to reach the vault API.
 func VaultPluginTLSProvider(apiTLSConfig *TLSConfig) func() (*tls.Config, error) {
 	if os.Getenv(PluginMetadataModeEnv) == "true" {
 		return nil
 	}
 
+	if os.Getenv(PluginAutoMTLSEnv) == "true" {
+		return func() (*tls.Config, error) {
+			return pluginServerTLSConfig(os.Getenv(PluginTLSCertEnv), os.Getenv(PluginTLSKeyEnv))
+		}
+	}
+
 	return func() (*tls.Config, error) {
 		unwrapToken := os.Getenv(PluginUnwrapTokenEnv)
 
@@ -129,61 +148,64 @@ func VaultPluginTLSProvider(apiTLSConfig *TLSConfig) func() (*tls.Config, error)
 			return nil, errors.New("error during token unwrap request: secret is nil")
 		}
 
-		// Retrieve and parse the server's certificate
-		serverCertBytesRaw, ok := secret.Data["ServerCert"].(string)
+		serverCertB64, ok := secret.Data["ServerCert"].(string)
 		if !ok {
 			return nil, errors.New("error unmarshalling certificate")
 		}
-
-		serverCertBytes, err := base64.StdEncoding.DecodeString(serverCertBytesRaw)
-		if err != nil {
-			return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
-		}
-
-		serverCert, err := x509.ParseCertificate(serverCertBytes)
-		if err != nil {
-			return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
-		}
-
-		// Retrieve and parse the server's private key
 		serverKeyB64, ok := secret.Data["ServerKey"].(string)
 		if !ok {
 			return nil, errors.New("error unmarshalling certificate")
 		}
 
-		serverKeyRaw, err := base64.StdEncoding.DecodeString(serverKeyB64)
-		if err != nil {
-			return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
-		}
+		return pluginServerTLSConfig(serverCertB64, serverKeyB64)
+	}
+}
 
-		serverKey, err := x509.ParseECPrivateKey(serverKeyRaw)
-		if err != nil {
-			return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
-		}
+// pluginServerTLSConfig builds the TLS config of the plugin out of the base64
+// encoded certificate and private key generated by vault.
+func pluginServerTLSConfig(serverCertB64, serverKeyB64 string) (*tls.Config, error) {
+	serverCertBytes, err := base64.StdEncoding.DecodeString(serverCertB64)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
+	}
 
-		// Add CA cert to the cert pool
-		caCertPool := x509.NewCertPool()
-		caCertPool.AddCert(serverCert)
+	serverCert, err := x509.ParseCertificate(serverCertBytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
+	}
 
-		// Build a certificate object out of the server's cert and private key.
-		cert := tls.Certificate{
-			Certificate: [][]byte{serverCertBytes},
-			PrivateKey:  serverKey,
-			Leaf:        serverCert,
-		}
+	serverKeyRaw, err := base64.StdEncoding.DecodeString(serverKeyB64)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
+	}
 
-		// Setup TLS config
-		tlsConfig := &tls.Config{
-			ClientCAs:  caCertPool,
-			RootCAs:    caCertPool,
-			ClientAuth: tls.RequireAndVerifyClientCert,
-			// TLS 1.2 minimum
-			MinVersion:   tls.VersionTLS12,
-			Certificates: []tls.Certificate{cert},
-			ServerName:   serverCert.Subject.CommonName,
-		}
-		tlsConfig.BuildNameToCertificate()
+	serverKey, err := x509.ParseECPrivateKey(serverKeyRaw)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing certificate: {{err}}", err)
+	}
+
+	// Add CA cert to the cert pool
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(serverCert)
 
-		return tlsConfig, nil
+	// Build a certificate object out of the server's cert and private key.
+	cert := tls.Certificate{
+		Certificate: [][]byte{serverCertBytes},
+		PrivateKey:  serverKey,
+		Leaf:        serverCert,
 	}
+
+	// Setup TLS config
+	tlsConfig := &tls.Config{
+		ClientCAs:  caCertPool,
+		RootCAs:    caCertPool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		// TLS 1.2 minimum
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   serverCert.Subject.CommonName,
+	}
+	tlsConfig.BuildNameToCertificate()
+
+	return tlsConfig, nil
 }
@@ -132,21 +132,29 @@ type GetPluginInput struct {
 
 	// Type of the plugin. Required.
 	Type consts.PluginType `json:"type"`
+
+	// Version of the plugin. Optional.
+	Version string `json:"version,omitempty"`
 }
 
 // GetPluginResponse is the response from the GetPlugin call.
 type GetPluginResponse struct {
-	Args    []string `json:"args"`
-	Builtin bool     `json:"builtin"`
-	Command string   `json:"command"`
-	Name    string   `json:"name"`
-	SHA256  string   `json:"sha256"`
+	Args     []string `json:"args"`
+	Builtin  bool     `json:"builtin"`
+	Command  string   `json:"command"`
+	Name     string   `json:"name"`
+	SHA256   string   `json:"sha256"`
+	Version  string   `json:"version"`
+	Versions []string `json:"versions"`
 }
 
 // GetPlugin retrieves information about the plugin.
 func (c *Sys) GetPlugin(i *GetPluginInput) (*GetPluginResponse, error) {
 	path := catalogPathByType(i.Type, i.Name)
 	req := c.c.NewRequest(http.MethodGet, path)
+	if i.Version != "" {
+		req.Params.Set("version", i.Version)
+	}
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -182,6 +190,9 @@ type RegisterPluginInput struct {
 
 	// SHA256 is the shasum of the plugin.
 	SHA256 string `json:"sha256,omitempty"`
+
+	// Version is the semantic version of the plugin. Optional.
+	Version string `json:"version,omitempty"`
 }
 
 // RegisterPlugin registers the plugin with the given information.
@@ -209,6 +220,9 @@ type DeregisterPluginInput struct {
 
 	// Type of the plugin. Required.
 	Type consts.PluginType `json:"type"`
+
+	// Version of the plugin. Optional.
+	Version string `json:"version,omitempty"`
 }
 
 // DeregisterPlugin removes the plugin with the given name from the plugin
@@ -216,6 +230,9 @@ type DeregisterPluginInput struct {
 func (c *Sys) DeregisterPlugin(i *DeregisterPluginInput) error {
 	path := catalogPathByType(i.Type, i.Name)
 	req := c.c.NewRequest(http.MethodDelete, path)
+	if i.Version != "" {
+		req.Params.Set("version", i.Version)
+	}
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -278,8 +295,8 @@ type ReloadStatus struct {
 
 // ReloadStatusResponse is the combined response of all known completed plugin reloads
 type ReloadStatusResponse struct {
-	ReloadID string                   `mapstructure:"reload_id"`
-	Results  map[string]*ReloadStatus `mapstructure:"results"`
+	ReloadID string                   `json:"reload_id" mapstructure:"reload_id"`
+	Results  map[string]*ReloadStatus `json:"results" mapstructure:"results"`
 }
 
 // ReloadPluginStatusInput is used as input to the ReloadStatusPlugin function.
@@ -326,6 +343,50 @@ func (c *Sys) ReloadPluginStatus(reloadStatusInput *ReloadPluginStatusInput) (*R
 
 }
 
+// UpgradePluginInput is used as input to the UpgradePlugin function.
+type UpgradePluginInput struct {
+	// Plugin is the name of the plugin to upgrade, as registered in the plugin catalog
+	Plugin string `json:"plugin"`
+
+	// Version is the version of the plugin to upgrade the mounts to
+	Version string `json:"version"`
+
+	// Mounts is the array of string mount paths of the plugin backends to
+	// upgrade. All the mounts of the plugin are upgraded if empty.
+	Mounts []string `json:"mounts,omitempty"`
+}
+
+// UpgradePlugin pins mounted plugin backends to another version of the plugin
+// and reloads them, returning the upgraded mount paths. The upgrade is rolled
+// back if any of the mounts fails to reload.
+func (c *Sys) UpgradePlugin(i *UpgradePluginInput) ([]string, error) {
+	path := "/v1/sys/plugins/upgrade/backend"
+	req := c.c.NewRequest(http.MethodPut, path)
+
+	if err := req.SetJSONBody(i); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	resp, err := c.c.RawRequestWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Mounts []string `json:"mounts"`
+		} `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return result.Data.Mounts, nil
+}
+
 // catalogPathByType is a helper to construct the proper API path by plugin type
 func catalogPathByType(pluginType consts.PluginType, name string) string {
 	path := fmt.Sprintf("/v1/sys/plugins/catalog/%s/%s", pluginType, name)
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 func (c *Sys) Rotate() error {
@@ -68,10 +70,72 @@ func (c *Sys) KeyStatus() (*KeyStatus, error) {
 	}
 	result.InstallTime = installTime
 
+	// Older servers don't report the encryptions
+	if encryptionsRaw, ok := secret.Data["encryptions"]; ok {
+		encryptions, ok := encryptionsRaw.(json.Number)
+		if !ok {
+			return nil, errors.New("could not convert encryptions to a number")
+		}
+		result.Encryptions, err = encryptions.Int64()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &result, err
 }
 
 type KeyStatus struct {
 	Term        int       `json:"term"`
 	InstallTime time.Time `json:"install_time"`
+	Encryptions int64     `json:"encryptions"`
+}
+
+func (c *Sys) RotateConfig() (*RotateConfig, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/rotate/config")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result RotateConfig
+	err = mapstructure.Decode(secret.Data, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, err
+}
+
+func (c *Sys) PutRotateConfig(config *RotateConfig) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/rotate/config")
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+type RotateConfig struct {
+	Enabled       bool   `json:"enabled" mapstructure:"enabled"`
+	MaxOperations int64  `json:"max_operations" mapstructure:"max_operations"`
+	Interval      string `json:"interval" mapstructure:"interval"`
 }
@@ -36,6 +36,12 @@ type Backend interface {
 	Invalidate(context.Context)
 }
 
+// Flusher is implemented by the backends buffering the entries they log, so
+// that the entries are persisted before Vault seals or steps down.
+type Flusher interface {
+	Flush(context.Context) error
+}
+
 // BackendConfig contains configuration parameters used in the factory func to
 // instantiate audit backends
 type BackendConfig struct {
@@ -47,6 +53,9 @@ type BackendConfig struct {
 
 	// Config is the opaque user configuration provided when mounting
 	Config map[string]string
+
+	// ClusterName is the name of the Vault cluster, if known
+	ClusterName string
 }
 
 // Factory is the factory function to create an audit backend.
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -90,8 +91,9 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 		reqType = "request"
 	}
 	reqEntry := &AuditRequestEntry{
-		Type:  reqType,
-		Error: errString,
+		Type:      reqType,
+		Error:     errString,
+		ErrorInfo: getErrorInfo(in.Request, nil, in.OuterErr),
 
 		Auth: &AuditAuth{
 			ClientToken:               auth.ClientToken,
@@ -107,6 +109,7 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 			RemainingUses:             req.ClientTokenRemainingUses,
 			TokenType:                 auth.TokenType.String(),
 			TokenTTL:                  int64(auth.TTL.Seconds()),
+			PolicyResults:             getPolicyResults(auth),
 		},
 
 		Request: &AuditRequest{
@@ -253,8 +256,9 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 		respType = "response"
 	}
 	respEntry := &AuditResponseEntry{
-		Type:  respType,
-		Error: errString,
+		Type:      respType,
+		Error:     errString,
+		ErrorInfo: getErrorInfo(in.Request, in.Response, in.OuterErr),
 		Auth: &AuditAuth{
 			ClientToken:               auth.ClientToken,
 			Accessor:                  auth.Accessor,
@@ -269,6 +273,7 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 			EntityID:                  auth.EntityID,
 			TokenType:                 auth.TokenType.String(),
 			TokenTTL:                  int64(auth.TTL.Seconds()),
+			PolicyResults:             getPolicyResults(auth),
 		},
 
 		Request: &AuditRequest{
@@ -312,27 +317,33 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 	if !config.OmitTime {
 		respEntry.Time = time.Now().UTC().Format(time.RFC3339Nano)
 	}
+	if in.Duration > 0 {
+		respEntry.DurationMs = float64(in.Duration) / float64(time.Millisecond)
+	}
 
 	return f.AuditFormatWriter.WriteResponse(w, respEntry)
 }
 
 // AuditRequestEntry is the structure of a request audit log entry in Audit.
 type AuditRequestEntry struct {
-	Time    string        `json:"time,omitempty"`
-	Type    string        `json:"type,omitempty"`
-	Auth    *AuditAuth    `json:"auth,omitempty"`
-	Request *AuditRequest `json:"request,omitempty"`
-	Error   string        `json:"error,omitempty"`
+	Time      string          `json:"time,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	Auth      *AuditAuth      `json:"auth,omitempty"`
+	Request   *AuditRequest   `json:"request,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	ErrorInfo *AuditErrorInfo `json:"error_info,omitempty"`
 }
 
 // AuditResponseEntry is the structure of a response audit log entry in Audit.
 type AuditResponseEntry struct {
-	Time     string         `json:"time,omitempty"`
-	Type     string         `json:"type,omitempty"`
-	Auth     *AuditAuth     `json:"auth,omitempty"`
-	Request  *AuditRequest  `json:"request,omitempty"`
-	Response *AuditResponse `json:"response,omitempty"`
-	Error    string         `json:"error,omitempty"`
+	Time       string          `json:"time,omitempty"`
+	Type       string          `json:"type,omitempty"`
+	Auth       *AuditAuth      `json:"auth,omitempty"`
+	Request    *AuditRequest   `json:"request,omitempty"`
+	Response   *AuditResponse  `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	ErrorInfo  *AuditErrorInfo `json:"error_info,omitempty"`
+	DurationMs float64         `json:"duration_ms,omitempty"`
 }
 
 type AuditRequest struct {
@@ -379,6 +390,27 @@ type AuditAuth struct {
 	TokenType                 string              `json:"token_type,omitempty"`
 	TokenTTL                  int64               `json:"token_ttl,omitempty"`
 	TokenIssueTime            string              `json:"token_issue_time,omitempty"`
+	PolicyResults             *AuditPolicyResults `json:"policy_results,omitempty"`
+}
+
+type AuditPolicyResults struct {
+	Allowed          bool              `json:"allowed"`
+	GrantingPolicies []AuditPolicyInfo `json:"granting_policies,omitempty"`
+}
+
+type AuditPolicyInfo struct {
+	Name        string `json:"name,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// AuditErrorInfo classifies the error of a failed request so that, for
+// instance, denied requests can be told apart from server faults.
+type AuditErrorInfo struct {
+	Type             string `json:"type"`
+	Status           int    `json:"status"`
+	PermissionDenied bool   `json:"permission_denied"`
+	ServerError      bool   `json:"server_error"`
 }
 
 type AuditSecret struct {
@@ -407,6 +439,78 @@ func getRemoteAddr(req *logical.Request) string {
 	return ""
 }
 
+// getPolicyResults converts the policy evaluation results attached by core
+// into their audit representation
+func getPolicyResults(auth *logical.Auth) *AuditPolicyResults {
+	if auth == nil || auth.PolicyResults == nil {
+		return nil
+	}
+
+	ret := &AuditPolicyResults{
+		Allowed: auth.PolicyResults.Allowed,
+	}
+	for _, pi := range auth.PolicyResults.GrantingPolicies {
+		ret.GrantingPolicies = append(ret.GrantingPolicies, AuditPolicyInfo{
+			Name:        pi.Name,
+			NamespaceID: pi.NamespaceID,
+			Type:        pi.Type,
+		})
+	}
+	return ret
+}
+
+// getErrorInfo classifies the error of a request, if there was one, using the
+// same rules that determine the status code of the HTTP response.
+func getErrorInfo(req *logical.Request, resp *logical.Response, outerErr error) *AuditErrorInfo {
+	err := outerErr
+	if err == nil {
+		err = resp.Error()
+	}
+	if err == nil {
+		return nil
+	}
+
+	// Only the presence of a response matters for the status code; avoid
+	// passing it through as its error data may not be a string
+	var statusResp *logical.Response
+	if resp != nil {
+		statusResp = new(logical.Response)
+	}
+	status, _ := logical.RespondErrorCommon(req, statusResp, err)
+	logical.AdjustErrorStatusCode(&status, err)
+
+	var errType string
+	switch {
+	case status == http.StatusBadRequest:
+		errType = "invalid_request"
+	case status == http.StatusForbidden:
+		errType = "permission_denied"
+	case status == http.StatusNotFound:
+		errType = "unsupported_path"
+	case status == http.StatusMethodNotAllowed:
+		errType = "unsupported_operation"
+	case status == http.StatusRequestEntityTooLarge:
+		errType = "request_too_large"
+	case status == http.StatusTooManyRequests:
+		errType = "rate_limited"
+	case status == http.StatusBadGateway:
+		errType = "upstream_error"
+	case status == http.StatusServiceUnavailable:
+		errType = "unavailable"
+	case status >= 500:
+		errType = "internal"
+	default:
+		errType = "client_error"
+	}
+
+	return &AuditErrorInfo{
+		Type:             errType,
+		Status:           status,
+		PermissionDenied: status == http.StatusForbidden,
+		ServerError:      status >= 500,
+	}
+}
+
 func getClientCertificateSerialNumber(connState *tls.ConnectionState) string {
 	if connState == nil || len(connState.VerifiedChains) == 0 || len(connState.VerifiedChains[0]) == 0 {
 		return ""
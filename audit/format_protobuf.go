@@ -0,0 +1,420 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/audit/pb"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ProtobufFormatWriter is an AuditFormatWriter implementation that structures
+// data into length-delimited protocol buffer messages as described by
+// audit/pb/types.proto. Each entry is written as a varint encoded length
+// followed by a marshaled pb.Entry.
+type ProtobufFormatWriter struct {
+	SaltFunc func(context.Context) (*salt.Salt, error)
+}
+
+func (f *ProtobufFormatWriter) WriteRequest(w io.Writer, req *AuditRequestEntry) error {
+	if req == nil {
+		return fmt.Errorf("request entry was nil, cannot encode")
+	}
+
+	reqEntry, err := requestEntryToProto(req)
+	if err != nil {
+		return err
+	}
+
+	return writeDelimited(w, &pb.Entry{
+		Entry: &pb.Entry_Request{
+			Request: reqEntry,
+		},
+	})
+}
+
+func (f *ProtobufFormatWriter) WriteResponse(w io.Writer, resp *AuditResponseEntry) error {
+	if resp == nil {
+		return fmt.Errorf("response entry was nil, cannot encode")
+	}
+
+	respEntry, err := responseEntryToProto(resp)
+	if err != nil {
+		return err
+	}
+
+	return writeDelimited(w, &pb.Entry{
+		Entry: &pb.Entry_Response{
+			Response: respEntry,
+		},
+	})
+}
+
+func (f *ProtobufFormatWriter) Salt(ctx context.Context) (*salt.Salt, error) {
+	return f.SaltFunc(ctx)
+}
+
+// writeDelimited writes the message prefixed by its varint encoded size using
+// a single call to Write so that entries are never interleaved.
+func writeDelimited(w io.Writer, m proto.Message) error {
+	msgBytes, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(msgBytes))
+	n := binary.PutUvarint(buf, uint64(len(msgBytes)))
+	buf = append(buf[:n], msgBytes...)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+// ReadProtobufEntry reads a single length-delimited entry written by
+// ProtobufFormatWriter. It returns io.EOF when no more entries are available.
+func ReadProtobufEntry(r *bufio.Reader) (*pb.Entry, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	msgBytes := make([]byte, size)
+	if _, err := io.ReadFull(r, msgBytes); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	entry := new(pb.Entry)
+	if err := proto.Unmarshal(msgBytes, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func requestEntryToProto(req *AuditRequestEntry) (*pb.RequestEntry, error) {
+	request, err := requestToProto(req.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RequestEntry{
+		Time:      req.Time,
+		Type:      req.Type,
+		Auth:      authToProto(req.Auth),
+		Request:   request,
+		Error:     req.Error,
+		ErrorInfo: errorInfoToProto(req.ErrorInfo),
+	}, nil
+}
+
+func responseEntryToProto(resp *AuditResponseEntry) (*pb.ResponseEntry, error) {
+	request, err := requestToProto(resp.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := responseToProto(resp.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ResponseEntry{
+		Time:       resp.Time,
+		Type:       resp.Type,
+		Auth:       authToProto(resp.Auth),
+		Request:    request,
+		Response:   response,
+		Error:      resp.Error,
+		ErrorInfo:  errorInfoToProto(resp.ErrorInfo),
+		DurationMs: resp.DurationMs,
+	}, nil
+}
+
+func requestToProto(req *AuditRequest) (*pb.Request, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	data, err := dataToStruct(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &pb.Request{
+		ID:                            req.ID,
+		ReplicationCluster:            req.ReplicationCluster,
+		Operation:                     string(req.Operation),
+		MountType:                     req.MountType,
+		ClientToken:                   req.ClientToken,
+		ClientTokenAccessor:           req.ClientTokenAccessor,
+		Path:                          req.Path,
+		Data:                          data,
+		PolicyOverride:                req.PolicyOverride,
+		RemoteAddress:                 req.RemoteAddr,
+		WrapTTL:                       int64(req.WrapTTL),
+		Headers:                       headersToProto(req.Headers),
+		ClientCertificateSerialNumber: req.ClientCertificateSerialNumber,
+	}
+	if req.Namespace != nil {
+		ret.Namespace = &pb.Namespace{
+			ID:   req.Namespace.ID,
+			Path: req.Namespace.Path,
+		}
+	}
+
+	return ret, nil
+}
+
+func responseToProto(resp *AuditResponse) (*pb.Response, error) {
+	if resp == nil {
+		return nil, nil
+	}
+
+	data, err := dataToStruct(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &pb.Response{
+		Auth:      authToProto(resp.Auth),
+		MountType: resp.MountType,
+		Data:      data,
+		Warnings:  resp.Warnings,
+		Redirect:  resp.Redirect,
+		Headers:   headersToProto(resp.Headers),
+	}
+	if resp.Secret != nil {
+		ret.Secret = &pb.Secret{
+			LeaseID: resp.Secret.LeaseID,
+		}
+	}
+	if resp.WrapInfo != nil {
+		ret.WrapInfo = &pb.ResponseWrapInfo{
+			TTL:             int64(resp.WrapInfo.TTL),
+			Token:           resp.WrapInfo.Token,
+			Accessor:        resp.WrapInfo.Accessor,
+			CreationTime:    resp.WrapInfo.CreationTime,
+			CreationPath:    resp.WrapInfo.CreationPath,
+			WrappedAccessor: resp.WrapInfo.WrappedAccessor,
+		}
+	}
+
+	return ret, nil
+}
+
+func authToProto(auth *AuditAuth) *pb.Auth {
+	if auth == nil {
+		return nil
+	}
+
+	ret := &pb.Auth{
+		ClientToken:      auth.ClientToken,
+		Accessor:         auth.Accessor,
+		DisplayName:      auth.DisplayName,
+		Policies:         auth.Policies,
+		TokenPolicies:    auth.TokenPolicies,
+		IdentityPolicies: auth.IdentityPolicies,
+		NoDefaultPolicy:  auth.NoDefaultPolicy,
+		Metadata:         auth.Metadata,
+		NumUses:          int64(auth.NumUses),
+		RemainingUses:    int64(auth.RemainingUses),
+		EntityID:         auth.EntityID,
+		TokenType:        auth.TokenType,
+		TokenTTL:         auth.TokenTTL,
+		TokenIssueTime:   auth.TokenIssueTime,
+	}
+
+	if len(auth.ExternalNamespacePolicies) > 0 {
+		ret.ExternalNamespacePolicies = make(map[string]*pb.PolicyList, len(auth.ExternalNamespacePolicies))
+		for ns, policies := range auth.ExternalNamespacePolicies {
+			ret.ExternalNamespacePolicies[ns] = &pb.PolicyList{
+				Policies: policies,
+			}
+		}
+	}
+
+	if auth.PolicyResults != nil {
+		ret.PolicyResults = &pb.PolicyResults{
+			Allowed: auth.PolicyResults.Allowed,
+		}
+		for _, pi := range auth.PolicyResults.GrantingPolicies {
+			ret.PolicyResults.GrantingPolicies = append(ret.PolicyResults.GrantingPolicies, &pb.PolicyInfo{
+				Name:        pi.Name,
+				NamespaceID: pi.NamespaceID,
+				Type:        pi.Type,
+			})
+		}
+	}
+
+	return ret
+}
+
+func errorInfoToProto(info *AuditErrorInfo) *pb.ErrorInfo {
+	if info == nil {
+		return nil
+	}
+
+	return &pb.ErrorInfo{
+		Type:             info.Type,
+		Status:           int32(info.Status),
+		PermissionDenied: info.PermissionDenied,
+		ServerError:      info.ServerError,
+	}
+}
+
+func headersToProto(headers map[string][]string) map[string]*pb.HeaderValues {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	ret := make(map[string]*pb.HeaderValues, len(headers))
+	for k, v := range headers {
+		ret[k] = &pb.HeaderValues{
+			Values: v,
+		}
+	}
+	return ret
+}
+
+// dataToStruct converts request or response data into a Struct. Values may be
+// of any type a backend returns, so they are normalized to the values the
+// JSON formatter would have emitted.
+func dataToStruct(data map[string]interface{}) (*structpb.Struct, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	ret := &structpb.Struct{
+		Fields: make(map[string]*structpb.Value, len(data)),
+	}
+	for k, v := range data {
+		value, err := toStructValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("error converting field %q: %w", k, err)
+		}
+		ret.Fields[k] = value
+	}
+	return ret, nil
+}
+
+// toStructValue converts a value into a structpb.Value, walking maps, slices
+// and pointers. Other types are converted through their JSON encoding.
+func toStructValue(v interface{}) (*structpb.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return structpb.NewNullValue(), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return structpb.NewStringValue(v.String()), nil
+		}
+		return structpb.NewNumberValue(f), nil
+	case string:
+		return structpb.NewStringValue(strings.ToValidUTF8(v, "\uFFFD")), nil
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64, []byte:
+		return structpb.NewValue(v)
+	case map[string]interface{}:
+		s, err := dataToStruct(v)
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStructValue(s), nil
+	case []interface{}:
+		return toListValue(len(v), func(i int) interface{} { return v[i] })
+	case json.Marshaler, encoding.TextMarshaler:
+		return jsonToStructValue(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return structpb.NewNullValue(), nil
+		}
+		return toStructValue(rv.Elem().Interface())
+	case reflect.Map:
+		if rv.IsNil() {
+			return structpb.NewNullValue(), nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return jsonToStructValue(v)
+		}
+		s := &structpb.Struct{
+			Fields: make(map[string]*structpb.Value, rv.Len()),
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			value, err := toStructValue(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			s.Fields[iter.Key().String()] = value
+		}
+		return structpb.NewStructValue(s), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return structpb.NewNullValue(), nil
+		}
+		// Byte slices are encoded in base64, like encoding/json does
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return jsonToStructValue(v)
+		}
+		fallthrough
+	case reflect.Array:
+		return toListValue(rv.Len(), func(i int) interface{} { return rv.Index(i).Interface() })
+	case reflect.Bool:
+		return structpb.NewBoolValue(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return structpb.NewNumberValue(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return structpb.NewNumberValue(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return structpb.NewNumberValue(rv.Float()), nil
+	case reflect.String:
+		return toStructValue(rv.String())
+	}
+	return jsonToStructValue(v)
+}
+
+func toListValue(n int, index func(int) interface{}) (*structpb.Value, error) {
+	l := &structpb.ListValue{
+		Values: make([]*structpb.Value, n),
+	}
+	for i := 0; i < n; i++ {
+		value, err := toStructValue(index(i))
+		if err != nil {
+			return nil, err
+		}
+		l.Values[i] = value
+	}
+	return structpb.NewListValue(l), nil
+}
+
+// jsonToStructValue converts a value through its JSON encoding, for the types
+// defining their own encoding, such as time.Time, and structs
+func jsonToStructValue(v interface{}) (*structpb.Value, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return toStructValue(decoded)
+}
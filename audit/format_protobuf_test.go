@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFormatProtobuf_roundTrip(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltFunc := func(context.Context) (*salt.Salt, error) {
+		return salter, nil
+	}
+
+	formatter := AuditFormatter{
+		AuditFormatWriter: &ProtobufFormatWriter{
+			SaltFunc: saltFunc,
+		},
+	}
+
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			Accessor:    "bar",
+			DisplayName: "testtoken",
+			Policies:    []string{"default"},
+			TokenType:   logical.TokenTypeService,
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+		},
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+			Data: map[string]interface{}{
+				"value":  "bar",
+				"nested": map[string]interface{}{"count": 3},
+			},
+			Headers: map[string][]string{
+				"foo": []string{"bar"},
+			},
+		},
+		Response: &logical.Response{
+			Data: map[string]interface{}{
+				"ok": true,
+			},
+		},
+		Duration: 2 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	ctx := namespace.RootContext(nil)
+	config := FormatterConfig{HMACAccessor: true}
+	if err := formatter.FormatRequest(ctx, &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := formatter.FormatResponse(ctx, &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(&buf)
+
+	entry, err := ReadProtobufEntry(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := entry.GetRequest()
+	if req == nil {
+		t.Fatalf("expected request entry, got %#v", entry)
+	}
+	if req.Type != "request" || req.Request.Path != "secret/foo" || req.Request.Operation != "update" {
+		t.Fatalf("bad request entry: %#v", req)
+	}
+	if req.Auth.ClientToken != salter.GetIdentifiedHMAC("foo") {
+		t.Fatalf("expected client token to be hashed, got %q", req.Auth.ClientToken)
+	}
+	if req.Auth.Accessor != salter.GetIdentifiedHMAC("bar") {
+		t.Fatalf("expected accessor to be hashed, got %q", req.Auth.Accessor)
+	}
+	if req.Auth.TokenTTL != 3600 {
+		t.Fatalf("bad token ttl: %d", req.Auth.TokenTTL)
+	}
+	if v := req.Request.Data.Fields["value"].GetStringValue(); v != salter.GetIdentifiedHMAC("bar") {
+		t.Fatalf("expected data to be hashed, got %q", v)
+	}
+	if v := req.Request.Data.Fields["nested"].GetStructValue().Fields["count"].GetNumberValue(); v != 3 {
+		t.Fatalf("bad nested value: %v", v)
+	}
+	if v := req.Request.Headers["foo"].GetValues(); len(v) != 1 || v[0] != "bar" {
+		t.Fatalf("bad headers: %v", v)
+	}
+	if req.Request.Namespace.ID != namespace.RootNamespaceID {
+		t.Fatalf("bad namespace: %#v", req.Request.Namespace)
+	}
+
+	entry, err = ReadProtobufEntry(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := entry.GetResponse()
+	if resp == nil {
+		t.Fatalf("expected response entry, got %#v", entry)
+	}
+	if resp.Type != "response" || resp.DurationMs != 2 {
+		t.Fatalf("bad response entry: %#v", resp)
+	}
+	if !resp.Response.Data.Fields["ok"].GetBoolValue() {
+		t.Fatalf("bad response data: %#v", resp.Response.Data)
+	}
+
+	if _, err := ReadProtobufEntry(r); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestDataToStruct(t *testing.T) {
+	type custom struct {
+		Name string `json:"name"`
+	}
+	ttl := 30
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	data := map[string]interface{}{
+		"nil":        nil,
+		"string":     "foo",
+		"invalid":    "foo\xff",
+		"int":        42,
+		"number":     json.Number("1.5"),
+		"strings":    []string{"a", "b"},
+		"string_map": map[string]string{"key": "value"},
+		"nested":     map[string]interface{}{"list": []interface{}{1, "two", true}},
+		"bytes":      []byte("foo"),
+		"pointer":    &ttl,
+		"time":       now,
+		"struct":     custom{Name: "bar"},
+	}
+
+	s, err := dataToStruct(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The result must match what the JSON formatter would have emitted
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := new(structpb.Struct)
+	if err := protojson.Unmarshal(jsonBytes, expected); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(s, expected) {
+		t.Fatalf("bad struct:\n%v\nexpected:\n%v", s, expected)
+	}
+
+	if _, err := dataToStruct(map[string]interface{}{"func": func() {}}); err == nil {
+		t.Fatal("expected an error for a value without JSON encoding")
+	}
+}
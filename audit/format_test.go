@@ -1,11 +1,18 @@
 package audit
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"io/ioutil"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -70,3 +77,132 @@ func TestFormatResponseErrors(t *testing.T) {
 		t.Fatal("expected error due to nil writer")
 	}
 }
+
+func TestFormatResponse_enrichment(t *testing.T) {
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: (&noopFormatWriter{}).Salt,
+		},
+	}
+
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			TokenType:   logical.TokenTypeService,
+			PolicyResults: &logical.PolicyResults{
+				Allowed: true,
+				GrantingPolicies: []logical.PolicyInfo{
+					{Name: "default", NamespaceID: "root", Type: "acl"},
+				},
+			},
+		},
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "secret/foo",
+		},
+		Duration: 1500 * time.Microsecond,
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatResponse(namespace.RootContext(nil), &buf, FormatterConfig{}, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var entry AuditResponseEntry
+	if err := jsonutil.DecodeJSON(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.DurationMs != 1.5 {
+		t.Fatalf("bad duration: %v", entry.DurationMs)
+	}
+	expected := &AuditPolicyResults{
+		Allowed: true,
+		GrantingPolicies: []AuditPolicyInfo{
+			{Name: "default", NamespaceID: "root", Type: "acl"},
+		},
+	}
+	if !reflect.DeepEqual(entry.Auth.PolicyResults, expected) {
+		t.Fatalf("bad policy results: %#v", entry.Auth.PolicyResults)
+	}
+}
+
+func TestFormat_errorInfo(t *testing.T) {
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: (&noopFormatWriter{}).Salt,
+		},
+	}
+
+	cases := map[string]struct {
+		resp     *logical.Response
+		err      error
+		expected *AuditErrorInfo
+	}{
+		"success": {
+			&logical.Response{Data: map[string]interface{}{"foo": "bar"}},
+			nil,
+			nil,
+		},
+		"permission denied": {
+			nil,
+			logical.ErrPermissionDenied,
+			&AuditErrorInfo{Type: "permission_denied", Status: 403, PermissionDenied: true},
+		},
+		"error response": {
+			logical.ErrorResponse("missing field"),
+			logical.ErrInvalidRequest,
+			&AuditErrorInfo{Type: "invalid_request", Status: 400},
+		},
+		"rate limited": {
+			nil,
+			errwrap.Wrapf("request rejected: {{err}}", logical.ErrRateLimitQuotaExceeded),
+			&AuditErrorInfo{Type: "rate_limited", Status: 429},
+		},
+		"internal": {
+			nil,
+			errors.New("storage failure"),
+			&AuditErrorInfo{Type: "internal", Status: 500, ServerError: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			in := &logical.LogInput{
+				Request: &logical.Request{
+					Operation: logical.UpdateOperation,
+					Path:      "secret/foo",
+				},
+				Response: tc.resp,
+				OuterErr: tc.err,
+			}
+
+			var buf bytes.Buffer
+			if err := formatter.FormatResponse(namespace.RootContext(nil), &buf, FormatterConfig{}, in); err != nil {
+				t.Fatal(err)
+			}
+			var respEntry AuditResponseEntry
+			if err := jsonutil.DecodeJSON(buf.Bytes(), &respEntry); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(respEntry.ErrorInfo, tc.expected) {
+				t.Fatalf("bad response error info: %#v", respEntry.ErrorInfo)
+			}
+
+			// Request entries never have a response to go by
+			if tc.resp == nil {
+				buf.Reset()
+				if err := formatter.FormatRequest(namespace.RootContext(nil), &buf, FormatterConfig{}, in); err != nil {
+					t.Fatal(err)
+				}
+				var reqEntry AuditRequestEntry
+				if err := jsonutil.DecodeJSON(buf.Bytes(), &reqEntry); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(reqEntry.ErrorInfo, tc.expected) {
+					t.Fatalf("bad request error info: %#v", reqEntry.ErrorInfo)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// VerifyConfig configures a call to Verify.
+type VerifyConfig struct {
+	// Format is the format the log was written in, either "json" or
+	// "protobuf". Defaults to "json".
+	Format string
+
+	// Prefix is the prefix configured on the audit device, if any. It is
+	// stripped from every line before decoding.
+	Prefix string
+
+	// Values are plaintext values to search the log for. Each value is run
+	// through HashFunc and entries containing the result are reported.
+	Values []string
+
+	// HashFunc computes the HMAC of a value as the audit device would. It
+	// must be set if Values is not empty.
+	HashFunc func(string) (string, error)
+}
+
+// VerifyResult is the outcome of a call to Verify.
+type VerifyResult struct {
+	Requests  int            `json:"requests"`
+	Responses int            `json:"responses"`
+	Matches   []*VerifyMatch `json:"matches"`
+	Problems  []*VerifyIssue `json:"problems"`
+}
+
+// VerifyMatch describes an entry containing the HMAC of a searched value.
+type VerifyMatch struct {
+	File      string `json:"file,omitempty"`
+	Entry     int    `json:"entry"`
+	Type      string `json:"type"`
+	Time      string `json:"time"`
+	RequestID string `json:"request_id"`
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Value     string `json:"value"`
+}
+
+// VerifyIssue describes an integrity problem found in the log.
+type VerifyIssue struct {
+	File    string `json:"file,omitempty"`
+	Entry   int    `json:"entry"`
+	Message string `json:"message"`
+}
+
+// verifyEntry holds the fields of an audit entry needed for verification.
+type verifyEntry struct {
+	Type    string `json:"type"`
+	Time    string `json:"time"`
+	Request *struct {
+		ID        string `json:"id"`
+		Operation string `json:"operation"`
+		Path      string `json:"path"`
+	} `json:"request"`
+}
+
+// Request states tracked by a Verifier to pair responses with their requests
+const (
+	verifyRequested = iota + 1
+	verifyResponded
+)
+
+// Verifier checks audit logs, carrying the requests seen in a log over to the
+// next ones, so that a log rotated into several files can be verified as a
+// whole by passing the files in order.
+type Verifier struct {
+	config *VerifyConfig
+	hashes map[string]string
+	result *VerifyResult
+
+	// requests holds the state of each request ID
+	requests    map[string]int
+	seenRequest bool
+}
+
+// NewVerifier returns a Verifier with the given configuration.
+func NewVerifier(config *VerifyConfig) (*Verifier, error) {
+	if config == nil {
+		config = &VerifyConfig{}
+	}
+	if len(config.Values) > 0 && config.HashFunc == nil {
+		return nil, fmt.Errorf("a hash function is required to search for values")
+	}
+	switch config.Format {
+	case "", "json", "protobuf":
+	default:
+		return nil, fmt.Errorf("unsupported format %q", config.Format)
+	}
+
+	// Hash the searched values up front, keyed by their HMAC
+	hashes := make(map[string]string, len(config.Values))
+	for _, v := range config.Values {
+		hash, err := config.HashFunc(v)
+		if err != nil {
+			return nil, errwrap.Wrapf("error hashing value: {{err}}", err)
+		}
+		hashes[hash] = v
+	}
+
+	return &Verifier{
+		config: config,
+		hashes: hashes,
+		result: &VerifyResult{
+			Matches:  []*VerifyMatch{},
+			Problems: []*VerifyIssue{},
+		},
+		requests: make(map[string]int),
+	}, nil
+}
+
+// Result returns the outcome of the logs verified so far.
+func (v *Verifier) Result() *VerifyResult {
+	return v.result
+}
+
+// Verify reads an audit log from r, checking that every entry is well formed
+// and that every response follows its request. Timestamps are not checked for
+// order, as entries are timestamped before being serialized by the device, so
+// concurrent requests can be written out of order. If values are configured,
+// entries containing their HMACs are reported as matches.
+func Verify(r io.Reader, config *VerifyConfig) (*VerifyResult, error) {
+	v, err := NewVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+	v.Verify("", r)
+	return v.Result(), nil
+}
+
+// Verify reads the audit log named file from r, adding its problems and
+// matches to the result.
+func (v *Verifier) Verify(file string, r io.Reader) {
+	config := v.config
+	hashes := v.hashes
+	result := v.result
+
+	var next func() ([]byte, error)
+	reader := bufio.NewReader(r)
+	switch config.Format {
+	case "", "json":
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, 64*1024*1024)
+		next = func() ([]byte, error) {
+			for scanner.Scan() {
+				line := bytes.TrimSpace(scanner.Bytes())
+				if len(line) == 0 {
+					continue
+				}
+				return bytes.TrimPrefix(line, []byte(config.Prefix)), nil
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	case "protobuf":
+		next = func() ([]byte, error) {
+			entry, err := ReadProtobufEntry(reader)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case entry.GetRequest() != nil:
+				return protojson.MarshalOptions{UseProtoNames: true}.Marshal(entry.GetRequest())
+			case entry.GetResponse() != nil:
+				return protojson.MarshalOptions{UseProtoNames: true}.Marshal(entry.GetResponse())
+			default:
+				return []byte("{}"), nil
+			}
+		}
+	}
+
+	problem := func(i int, format string, args ...interface{}) {
+		result.Problems = append(result.Problems, &VerifyIssue{
+			File:    file,
+			Entry:   i,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	requests := v.requests
+
+	for i := 1; ; i++ {
+		raw, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			problem(i, "error reading entry: %v", err)
+			if config.Format == "protobuf" {
+				// Without a valid length the rest of the stream can't be
+				// framed, so stop here
+				break
+			}
+			continue
+		}
+
+		var entry verifyEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			problem(i, "malformed entry: %v", err)
+			continue
+		}
+
+		switch entry.Type {
+		case "request":
+			result.Requests++
+		case "response":
+			result.Responses++
+		default:
+			problem(i, "unknown entry type %q", entry.Type)
+		}
+
+		if entry.Time == "" {
+			problem(i, "entry has no timestamp")
+		} else if _, err := time.Parse(time.RFC3339Nano, entry.Time); err != nil {
+			problem(i, "invalid timestamp %q", entry.Time)
+		}
+
+		var id, operation, path string
+		if entry.Request != nil {
+			id, operation, path = entry.Request.ID, entry.Request.Operation, entry.Request.Path
+		}
+		switch {
+		case id == "":
+			problem(i, "entry has no request ID")
+		case entry.Type == "request":
+			if _, ok := requests[id]; ok {
+				problem(i, "duplicate request entry for request %q", id)
+			}
+			requests[id] = verifyRequested
+			v.seenRequest = true
+		case entry.Type == "response":
+			switch requests[id] {
+			case verifyRequested:
+				requests[id] = verifyResponded
+			case verifyResponded:
+				problem(i, "duplicate response entry for request %q", id)
+			default:
+				// Responses logged before any request belong to requests that
+				// were in flight when the log began, such as the request that
+				// enabled the device
+				if v.seenRequest {
+					problem(i, "response for request %q has no matching request entry", id)
+				}
+				requests[id] = verifyResponded
+			}
+		}
+
+		if len(hashes) == 0 {
+			continue
+		}
+
+		// Walk the entry with the same code the formatters use to hash it,
+		// looking for any of the searched HMACs
+		var data map[string]interface{}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			problem(i, "malformed entry: %v", err)
+			continue
+		}
+		found := make(map[string]struct{})
+		err = HashStructure(data, func(s string) string {
+			if value, ok := hashes[s]; ok {
+				found[value] = struct{}{}
+			}
+			return s
+		}, nil)
+		if err != nil {
+			problem(i, "error walking entry: %v", err)
+			continue
+		}
+		for _, value := range config.Values {
+			if _, ok := found[value]; !ok {
+				continue
+			}
+			delete(found, value)
+			result.Matches = append(result.Matches, &VerifyMatch{
+				File:      file,
+				Entry:     i,
+				Type:      entry.Type,
+				Time:      entry.Time,
+				RequestID: id,
+				Operation: operation,
+				Path:      path,
+				Value:     value,
+			})
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVerify(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltFunc := func(context.Context) (*salt.Salt, error) {
+		return salter, nil
+	}
+	hashFunc := func(s string) (string, error) {
+		return HashString(salter, s), nil
+	}
+
+	logInput := func(id, path, secret string) *logical.LogInput {
+		return &logical.LogInput{
+			Auth: &logical.Auth{
+				ClientToken: "foo",
+				TokenType:   logical.TokenTypeService,
+			},
+			Request: &logical.Request{
+				ID:        id,
+				Operation: logical.UpdateOperation,
+				Path:      path,
+				Data: map[string]interface{}{
+					"nested": map[string]interface{}{
+						"value": secret,
+					},
+				},
+			},
+			Response: &logical.Response{},
+		}
+	}
+
+	for _, format := range []string{"json", "protobuf"} {
+		t.Run(format, func(t *testing.T) {
+			var writer AuditFormatWriter
+			switch format {
+			case "json":
+				writer = &JSONFormatWriter{SaltFunc: saltFunc}
+			case "protobuf":
+				writer = &ProtobufFormatWriter{SaltFunc: saltFunc}
+			}
+			formatter := AuditFormatter{AuditFormatWriter: writer}
+			ctx := namespace.RootContext(nil)
+
+			var buf bytes.Buffer
+			in := logInput("one", "secret/foo", "s3cr3t")
+			if err := formatter.FormatRequest(ctx, &buf, FormatterConfig{}, in); err != nil {
+				t.Fatal(err)
+			}
+			if err := formatter.FormatResponse(ctx, &buf, FormatterConfig{}, in); err != nil {
+				t.Fatal(err)
+			}
+			in = logInput("two", "secret/bar", "other")
+			if err := formatter.FormatResponse(ctx, &buf, FormatterConfig{}, in); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := Verify(&buf, &VerifyConfig{
+				Format:   format,
+				Values:   []string{"s3cr3t", "missing"},
+				HashFunc: hashFunc,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Requests != 1 || result.Responses != 2 {
+				t.Fatalf("bad counts: %#v", result)
+			}
+			if len(result.Matches) != 2 {
+				t.Fatalf("expected 2 matches, got %d", len(result.Matches))
+			}
+			for i, m := range result.Matches {
+				if m.Entry != i+1 || m.RequestID != "one" || m.Path != "secret/foo" || m.Value != "s3cr3t" {
+					t.Fatalf("bad match: %#v", m)
+				}
+			}
+			if len(result.Problems) != 1 || result.Problems[0].Entry != 3 {
+				t.Fatalf("expected an unmatched response problem, got %#v", result.Problems)
+			}
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		// The first response is timestamped before its request, as concurrent
+		// requests can be written out of order, and is not a problem
+		log := strings.Join([]string{
+			`{"time":"2020-01-01T00:00:01Z","type":"request","request":{"id":"one"}}`,
+			`not json`,
+			`{"time":"2020-01-01T00:00:00Z","type":"response","request":{"id":"one"}}`,
+			`{"time":"2020-01-01T00:00:02Z","type":"response","request":{"id":"one"}}`,
+			`{"time":"yesterday","type":"request","request":{"id":"two"}}`,
+		}, "\n")
+
+		result, err := Verify(strings.NewReader(log), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []int{2, 4, 5}
+		if len(result.Problems) != len(expected) {
+			t.Fatalf("bad problems: %#v", result.Problems)
+		}
+		for i, p := range result.Problems {
+			if p.Entry != expected[i] {
+				t.Fatalf("bad problem %d: %#v", i, p)
+			}
+		}
+	})
+	t.Run("rotated", func(t *testing.T) {
+		// Requests in flight when the log was rotated have their response in
+		// the next file
+		files := []string{
+			strings.Join([]string{
+				`{"time":"2020-01-01T00:00:00Z","type":"response","request":{"id":"zero"}}`,
+				`{"time":"2020-01-01T00:00:01Z","type":"request","request":{"id":"one"}}`,
+				`{"time":"2020-01-01T00:00:02Z","type":"request","request":{"id":"two"}}`,
+				`{"time":"2020-01-01T00:00:02Z","type":"response","request":{"id":"two"}}`,
+			}, "\n"),
+			strings.Join([]string{
+				`{"time":"2020-01-01T00:00:03Z","type":"response","request":{"id":"one"}}`,
+				`{"time":"2020-01-01T00:00:04Z","type":"response","request":{"id":"three"}}`,
+			}, "\n"),
+		}
+
+		v, err := NewVerifier(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, f := range files {
+			v.Verify(fmt.Sprintf("audit-%d.log", i), strings.NewReader(f))
+		}
+
+		result := v.Result()
+		if result.Requests != 2 || result.Responses != 4 {
+			t.Fatalf("bad counts: %#v", result)
+		}
+		if len(result.Problems) != 1 {
+			t.Fatalf("expected a single problem, got %#v", result.Problems)
+		}
+		if p := result.Problems[0]; p.File != "audit-1.log" || p.Entry != 2 {
+			t.Fatalf("bad problem: %#v", p)
+		}
+	})
+}
@@ -11,9 +11,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -47,10 +49,13 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		format = "json"
 	}
 	switch format {
-	case "json", "jsonx":
+	case "json", "jsonx", "protobuf":
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
+	if format == "protobuf" && conf.Config["prefix"] != "" {
+		return nil, fmt.Errorf("prefix is not supported with the protobuf format")
+	}
 
 	// Check if hashing of accessor is disabled
 	hmacAccessor := true
@@ -84,9 +89,59 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		}
 	}
 
+	// Check if size or age based rotation is configured
+	var maxSize int64
+	if maxSizeRaw, ok := conf.Config["max_size"]; ok {
+		v, err := strconv.ParseInt(maxSizeRaw, 10, 64)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing max_size: {{err}}", err)
+		}
+		if v < 0 {
+			return nil, fmt.Errorf("max_size cannot be negative")
+		}
+		maxSize = v
+	}
+
+	var maxAge time.Duration
+	if maxAgeRaw, ok := conf.Config["max_age"]; ok {
+		v, err := parseutil.ParseDurationSecond(maxAgeRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing max_age: {{err}}", err)
+		}
+		if v < 0 {
+			return nil, fmt.Errorf("max_age cannot be negative")
+		}
+		maxAge = v
+	}
+
+	var maxBackups int
+	if maxBackupsRaw, ok := conf.Config["max_backups"]; ok {
+		v, err := strconv.Atoi(maxBackupsRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing max_backups: {{err}}", err)
+		}
+		if v < 0 {
+			return nil, fmt.Errorf("max_backups cannot be negative")
+		}
+		maxBackups = v
+	}
+
+	compress := false
+	if compressRaw, ok := conf.Config["compress"]; ok {
+		v, err := strconv.ParseBool(compressRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing compress: {{err}}", err)
+		}
+		compress = v
+	}
+
 	b := &Backend{
 		path:       path,
 		mode:       mode,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		salt:       new(atomic.Value),
@@ -111,6 +166,10 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 			Prefix:   conf.Config["prefix"],
 			SaltFunc: b.Salt,
 		}
+	case "protobuf":
+		b.formatter.AuditFormatWriter = &audit.ProtobufFormatWriter{
+			SaltFunc: b.Salt,
+		}
 	}
 
 	switch path {
@@ -130,9 +189,9 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 
 // Backend is the audit backend for the file-based audit store.
 //
-// NOTE: This audit backend is currently very simple: it appends to a file.
-// It doesn't do anything more at the moment to assist with rotation
-// or reset the write cursor, this should be done in the future.
+// The backend appends to a file. If max_size or max_age are configured the
+// file is rotated in-process once either limit is reached; otherwise rotation
+// is left to external tooling which must send a SIGHUP afterwards.
 type Backend struct {
 	path string
 
@@ -143,6 +202,15 @@ type Backend struct {
 	f        *os.File
 	mode     os.FileMode
 
+	// Rotation settings and the state of the currently open file. These are
+	// protected by fileLock.
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	size       int64
+	openedAt   time.Time
+
 	saltMutex  sync.RWMutex
 	salt       *atomic.Value
 	saltConfig *salt.Config
@@ -212,10 +280,17 @@ func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer)
 			b.fileLock.Unlock()
 			return err
 		}
+		if b.shouldRotate(int64(buf.Len())) {
+			if err := b.rotate(); err != nil {
+				b.fileLock.Unlock()
+				return err
+			}
+		}
 		writer = b.f
 	}
 
-	if _, err := reader.WriteTo(writer); err == nil {
+	if n, err := reader.WriteTo(writer); err == nil {
+		b.size += n
 		b.fileLock.Unlock()
 		return nil
 	} else if b.path == "stdout" {
@@ -235,7 +310,8 @@ func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer)
 	}
 
 	reader.Seek(0, io.SeekStart)
-	_, err := reader.WriteTo(writer)
+	n, err := reader.WriteTo(b.f)
+	b.size += n
 	b.fileLock.Unlock()
 	return err
 }
@@ -273,6 +349,17 @@ func (b *Backend) open() error {
 		return err
 	}
 
+	b.size = 0
+	b.openedAt = time.Now()
+	if info, err := b.f.Stat(); err == nil {
+		b.size = info.Size()
+		// An existing file keeps its age, so that reopening it or restarting
+		// Vault doesn't postpone its rotation
+		if b.maxAge > 0 && b.size > 0 {
+			b.openedAt = b.createdAt(info)
+		}
+	}
+
 	// Change the file mode in case the log file already existed. We special
 	// case /dev/null since we can't chmod it and bypass if the mode is zero
 	switch b.path {
@@ -313,6 +400,27 @@ func (b *Backend) Reload(_ context.Context) error {
 	return b.open()
 }
 
+// Flush commits the entries written to the file to stable storage.
+func (b *Backend) Flush(_ context.Context) error {
+	switch b.path {
+	case "stdout", "discard":
+		return nil
+	}
+
+	b.fileLock.Lock()
+	defer b.fileLock.Unlock()
+
+	if b.f == nil {
+		return nil
+	}
+
+	// Devices such as /dev/null can't be synced
+	if info, err := b.f.Stat(); err == nil && !info.Mode().IsRegular() {
+		return nil
+	}
+	return b.f.Sync()
+}
+
 func (b *Backend) Invalidate(_ context.Context) {
 	b.saltMutex.Lock()
 	defer b.saltMutex.Unlock()
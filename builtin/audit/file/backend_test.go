@@ -93,6 +93,191 @@ func TestAuditFile_fileModeExisting(t *testing.T) {
 	}
 }
 
+func TestAuditFile_rotate(t *testing.T) {
+	path, err := ioutil.TempDir("", "vault-test_audit_file-rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	file := filepath.Join(path, "audit.log")
+
+	config := map[string]string{
+		"path":        file,
+		"max_size":    "512",
+		"max_backups": "2",
+		"compress":    "true",
+	}
+
+	sink, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+			Data: map[string]interface{}{
+				"value": "bar",
+			},
+		},
+	}
+
+	ctx := namespace.RootContext(nil)
+	for i := 0; i < 20; i++ {
+		if err := sink.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+		// Ensure rotated files get distinct names
+		time.Sleep(time.Millisecond)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 512 {
+		t.Fatalf("expected active file to be rotated, size is %d", info.Size())
+	}
+
+	var backups []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		backups, err = sink.(*Backend).listBackups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := len(backups) == 2
+		for _, backup := range backups {
+			if filepath.Ext(backup) != ".gz" {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected two compressed backups, got %v", backups)
+}
+
+func TestAuditFile_rotateExistingAge(t *testing.T) {
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+		},
+	}
+
+	cases := map[string]struct {
+		modTime   time.Duration
+		rotatedAt time.Duration
+		rotate    bool
+	}{
+		"old file":               {-2 * time.Hour, 0, true},
+		"recent file":            {-time.Minute, 0, false},
+		"old rotation":           {-time.Minute, -2 * time.Hour, true},
+		"recent rotation":        {-time.Minute, -10 * time.Minute, false},
+		"rotation after writing": {-2 * time.Hour, -time.Minute, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, err := ioutil.TempDir("", "vault-test_audit_file-age")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(path)
+
+			file := filepath.Join(path, "audit.log")
+			if err := ioutil.WriteFile(file, []byte("{}\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			if err := os.Chtimes(file, now, now.Add(tc.modTime)); err != nil {
+				t.Fatal(err)
+			}
+			if tc.rotatedAt != 0 {
+				backup := (&Backend{path: file}).backupName(now.Add(tc.rotatedAt))
+				if err := ioutil.WriteFile(backup, []byte("{}\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config := map[string]string{
+				"path":    file,
+				"max_age": "1h",
+			}
+			sink, err := Factory(context.Background(), &audit.BackendConfig{
+				SaltConfig: &salt.Config{},
+				SaltView:   &logical.InmemStorage{},
+				Config:     config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := sink.(*Backend)
+
+			if err := sink.LogRequest(namespace.RootContext(nil), in); err != nil {
+				t.Fatal(err)
+			}
+
+			backups, err := b.listBackups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := 0
+			if tc.rotatedAt != 0 {
+				expected++
+			}
+			if tc.rotate {
+				expected++
+			}
+			if len(backups) != expected {
+				t.Fatalf("expected %d backups, got %v", expected, backups)
+			}
+		})
+	}
+}
+
+func TestAuditFile_flush(t *testing.T) {
+	path, err := ioutil.TempDir("", "vault-test_audit_file-flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	for _, file := range []string{filepath.Join(path, "audit.log"), "/dev/null"} {
+		sink, err := Factory(context.Background(), &audit.BackendConfig{
+			SaltConfig: &salt.Config{},
+			SaltView:   &logical.InmemStorage{},
+			Config:     map[string]string{"path": file},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		in := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "secret/foo",
+			},
+		}
+		if err := sink.LogRequest(namespace.RootContext(nil), in); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := sink.(audit.Flusher).Flush(context.Background()); err != nil {
+			t.Fatalf("error flushing %q: %v", file, err)
+		}
+	}
+}
+
 func BenchmarkAuditFile_request(b *testing.B) {
 	config := map[string]string{
 		"path": "/dev/null",
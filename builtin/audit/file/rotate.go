@@ -0,0 +1,194 @@
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat is used to name rotated files. It sorts lexically in
+// chronological order, which pruneBackups relies on.
+const rotateTimeFormat = "20060102T150405.000000000Z"
+
+// cleanupLock serializes compression and pruning of rotated files so that a
+// burst of rotations doesn't race on the same backups.
+var cleanupLock sync.Mutex
+
+// shouldRotate returns whether writing n more bytes to the current file would
+// exceed one of the configured rotation limits. The file lock must be held
+// before calling this.
+func (b *Backend) shouldRotate(n int64) bool {
+	if b.f == nil {
+		return false
+	}
+	if b.maxSize > 0 && b.size > 0 && b.size+n > b.maxSize {
+		return true
+	}
+	if b.maxAge > 0 && time.Since(b.openedAt) >= b.maxAge {
+		return true
+	}
+	return false
+}
+
+// rotate moves the current file aside using a timestamped name and opens a
+// fresh file in its place. Compression and pruning of old backups happen in
+// the background so that requests are not blocked on them. The file lock must
+// be held before calling this.
+func (b *Backend) rotate() error {
+	if b.f != nil {
+		if err := b.f.Close(); err != nil {
+			return err
+		}
+		b.f = nil
+	}
+
+	backup := b.backupName(time.Now())
+	if err := os.Rename(b.path, backup); err != nil {
+		return err
+	}
+
+	if err := b.open(); err != nil {
+		return err
+	}
+
+	if b.compress || b.maxBackups > 0 {
+		go b.cleanupBackups(backup)
+	}
+
+	return nil
+}
+
+// createdAt returns when the existing file at the path was started. That is
+// when the newest backup was rotated out, if it was before the last write to
+// the file, and otherwise the time of the last write.
+func (b *Backend) createdAt(info os.FileInfo) time.Time {
+	modTime := info.ModTime()
+
+	backups, err := b.listBackups()
+	if err != nil || len(backups) == 0 {
+		return modTime
+	}
+	rotatedAt, ok := b.backupTime(filepath.Base(backups[len(backups)-1]))
+	if !ok || rotatedAt.After(modTime) {
+		return modTime
+	}
+	return rotatedAt
+}
+
+// backupName returns the name for a file rotated at the given time, keeping
+// the original extension so that tooling keyed off it continues to work.
+func (b *Backend) backupName(t time.Time) string {
+	ext := filepath.Ext(b.path)
+	prefix := strings.TrimSuffix(b.path, ext)
+	return prefix + "-" + t.UTC().Format(rotateTimeFormat) + ext
+}
+
+func (b *Backend) cleanupBackups(backup string) {
+	cleanupLock.Lock()
+	defer cleanupLock.Unlock()
+
+	if b.compress {
+		// On failure the uncompressed backup is left in place
+		b.compressBackup(backup)
+	}
+	if b.maxBackups > 0 {
+		b.pruneBackups()
+	}
+}
+
+func (b *Backend) compressBackup(backup string) error {
+	in, err := os.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(backup+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, b.mode)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(backup + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(backup + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(backup + ".gz")
+		return err
+	}
+
+	return os.Remove(backup)
+}
+
+// pruneBackups removes the oldest rotated files so that at most maxBackups
+// remain, whether or not they have been compressed.
+func (b *Backend) pruneBackups() error {
+	backups, err := b.listBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) <= b.maxBackups {
+		return nil
+	}
+
+	for _, backup := range backups[:len(backups)-b.maxBackups] {
+		os.Remove(backup)
+	}
+	return nil
+}
+
+// listBackups returns the rotated files for this backend, oldest first.
+func (b *Backend) listBackups() ([]string, error) {
+	dir := filepath.Dir(b.path)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if _, ok := b.backupTime(file.Name()); !ok {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, file.Name()))
+	}
+
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// backupTime returns the time a file was rotated at from its name, and
+// whether the name is one of a file rotated by this backend.
+func (b *Backend) backupTime(name string) (time.Time, bool) {
+	ext := filepath.Ext(b.path)
+	prefix := strings.TrimSuffix(filepath.Base(b.path), ext) + "-"
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+
+	ts := strings.TrimPrefix(name, prefix)
+	ts = strings.TrimSuffix(ts, ".gz")
+	ts = strings.TrimSuffix(ts, ext)
+	t, err := time.Parse(rotateTimeFormat, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
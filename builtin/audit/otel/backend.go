@@ -0,0 +1,362 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/builtin/audit/otel/otlp"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// scopeName identifies the instrumentation scope of the emitted log records.
+const scopeName = "github.com/hashicorp/vault/audit"
+
+func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
+	if conf.SaltConfig == nil {
+		return nil, fmt.Errorf("nil salt config")
+	}
+	if conf.SaltView == nil {
+		return nil, fmt.Errorf("nil salt view")
+	}
+
+	endpoint, ok := conf.Config["endpoint"]
+	if !ok || endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	timeoutRaw, ok := conf.Config["timeout"]
+	if !ok {
+		timeoutRaw = "5s"
+	}
+	timeout, err := parseutil.ParseDurationSecond(timeoutRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	insecure := false
+	if raw, ok := conf.Config["insecure"]; ok {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		insecure = value
+	}
+
+	var tlsConfig *tls.Config
+	if !insecure {
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+		if caFile := conf.Config["ca_cert"]; caFile != "" {
+			pem, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, errwrap.Wrapf("error reading CA certificate: {{err}}", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %q", caFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	headers, err := parseKeyValues(conf.Config["headers"])
+	if err != nil {
+		return nil, errwrap.Wrapf("invalid headers: {{err}}", err)
+	}
+
+	resourceAttributes, err := parseKeyValues(conf.Config["resource_attributes"])
+	if err != nil {
+		return nil, errwrap.Wrapf("invalid resource_attributes: {{err}}", err)
+	}
+
+	// Check if hashing of accessor is disabled
+	hmacAccessor := true
+	if hmacAccessorRaw, ok := conf.Config["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(hmacAccessorRaw)
+		if err != nil {
+			return nil, err
+		}
+		hmacAccessor = value
+	}
+
+	// Check if raw logging is enabled
+	logRaw := false
+	if raw, ok := conf.Config["log_raw"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logRaw = b
+	}
+
+	b := &Backend{
+		saltConfig: conf.SaltConfig,
+		saltView:   conf.SaltView,
+		formatConfig: audit.FormatterConfig{
+			Raw:          logRaw,
+			HMACAccessor: hmacAccessor,
+		},
+
+		endpoint:           endpoint,
+		timeout:            timeout,
+		tlsConfig:          tlsConfig,
+		headers:            metadata.New(headers),
+		clusterName:        conf.ClusterName,
+		resourceAttributes: resourceAttributes,
+	}
+	b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
+		SaltFunc: b.Salt,
+	}
+
+	return b, nil
+}
+
+// Backend is the audit backend that exports entries as OpenTelemetry log
+// records over OTLP/gRPC.
+type Backend struct {
+	conn   *grpc.ClientConn
+	client otlp.LogsServiceClient
+
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+
+	endpoint           string
+	timeout            time.Duration
+	tlsConfig          *tls.Config
+	headers            metadata.MD
+	clusterName        string
+	resourceAttributes map[string]string
+
+	sync.Mutex
+
+	saltMutex  sync.RWMutex
+	salt       *salt.Salt
+	saltConfig *salt.Config
+	saltView   logical.Storage
+}
+
+var _ audit.Backend = (*Backend)(nil)
+
+func (b *Backend) GetHash(ctx context.Context, data string) (string, error) {
+	salt, err := b.Salt(ctx)
+	if err != nil {
+		return "", err
+	}
+	return audit.HashString(salt, data), nil
+}
+
+func (b *Backend) LogRequest(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.export(ctx, buf.Bytes())
+}
+
+func (b *Backend) LogResponse(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.export(ctx, buf.Bytes())
+}
+
+// export sends a single formatted entry to the collector. Entries are sent
+// synchronously so that a failure to export is reported to the broker, in
+// the same way as the other audit devices.
+func (b *Backend) export(ctx context.Context, entry []byte) error {
+	req, err := b.newExportRequest(ctx, entry, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// The lock only guards the connection, the entries are exported
+	// concurrently
+	b.Lock()
+	if b.client == nil {
+		if err := b.connect(); err != nil {
+			b.Unlock()
+			return err
+		}
+	}
+	client := b.client
+	b.Unlock()
+
+	exportCtx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	if len(b.headers) > 0 {
+		exportCtx = metadata.NewOutgoingContext(exportCtx, b.headers)
+	}
+
+	resp, err := client.Export(exportCtx, req)
+	if err != nil {
+		return errwrap.Wrapf("error exporting audit entry: {{err}}", err)
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.RejectedLogRecords > 0 {
+		return fmt.Errorf("collector rejected audit entry: %s", ps.ErrorMessage)
+	}
+
+	return nil
+}
+
+// newExportRequest builds the OTLP request for an entry formatted as JSON.
+// The entry becomes the structured body of the log record, and the cluster
+// and namespace of the request are set as resource attributes.
+func (b *Backend) newExportRequest(ctx context.Context, entry []byte, now time.Time) (*otlp.ExportLogsServiceRequest, error) {
+	var data map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(entry))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, errwrap.Wrapf("error decoding formatted entry: {{err}}", err)
+	}
+
+	resourceAttrs := []*otlp.KeyValue{
+		stringKeyValue("service.name", "vault"),
+		stringKeyValue("service.version", version.GetVersion().VersionNumber()),
+	}
+	if b.clusterName != "" {
+		resourceAttrs = append(resourceAttrs, stringKeyValue("vault.cluster.name", b.clusterName))
+	}
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		resourceAttrs = append(resourceAttrs,
+			stringKeyValue("vault.namespace.id", ns.ID),
+			stringKeyValue("vault.namespace.path", ns.Path))
+	}
+	for _, k := range sortedKeys(b.resourceAttributes) {
+		resourceAttrs = append(resourceAttrs, stringKeyValue(k, b.resourceAttributes[k]))
+	}
+
+	entryType, _ := data["type"].(string)
+	record := &otlp.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       otlp.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+		Body:                 toAnyValue(data),
+		Attributes: []*otlp.KeyValue{
+			stringKeyValue("vault.audit.type", entryType),
+		},
+	}
+	if errString, _ := data["error"].(string); errString != "" {
+		record.SeverityNumber = otlp.SeverityNumber_SEVERITY_NUMBER_ERROR
+		record.SeverityText = "ERROR"
+	}
+	if req, ok := data["request"].(map[string]interface{}); ok {
+		for _, field := range []string{"id", "operation", "path"} {
+			if v, ok := req[field].(string); ok && v != "" {
+				record.Attributes = append(record.Attributes, stringKeyValue("vault.request."+field, v))
+			}
+		}
+	}
+
+	return &otlp.ExportLogsServiceRequest{
+		ResourceLogs: []*otlp.ResourceLogs{
+			{
+				Resource: &otlp.Resource{
+					Attributes: resourceAttrs,
+				},
+				ScopeLogs: []*otlp.ScopeLogs{
+					{
+						Scope: &otlp.InstrumentationScope{
+							Name: scopeName,
+						},
+						LogRecords: []*otlp.LogRecord{record},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (b *Backend) connect() error {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+		b.client = nil
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if b.tlsConfig != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(b.tlsConfig))}
+	}
+
+	conn, err := grpc.Dial(b.endpoint, opts...)
+	if err != nil {
+		return err
+	}
+
+	b.conn = conn
+	b.client = otlp.NewLogsServiceClient(conn)
+	return nil
+}
+
+func (b *Backend) Reload(_ context.Context) error {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.connect()
+}
+
+func (b *Backend) Salt(ctx context.Context) (*salt.Salt, error) {
+	b.saltMutex.RLock()
+	if b.salt != nil {
+		defer b.saltMutex.RUnlock()
+		return b.salt, nil
+	}
+	b.saltMutex.RUnlock()
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(ctx, b.saltView, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *Backend) Invalidate(_ context.Context) {
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	b.salt = nil
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs.
+func parseKeyValues(raw string) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		ret[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return ret, nil
+}
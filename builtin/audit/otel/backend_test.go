@@ -0,0 +1,189 @@
+package otel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/builtin/audit/otel/otlp"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testCollector struct {
+	otlp.UnimplementedLogsServiceServer
+
+	l        sync.Mutex
+	requests []*otlp.ExportLogsServiceRequest
+	md       []metadata.MD
+}
+
+func (c *testCollector) Export(ctx context.Context, req *otlp.ExportLogsServiceRequest) (*otlp.ExportLogsServiceResponse, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.requests = append(c.requests, req)
+	c.md = append(c.md, md)
+	return &otlp.ExportLogsServiceResponse{}, nil
+}
+
+func testServer(t *testing.T) (*testCollector, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &testCollector{}
+	server := grpc.NewServer()
+	otlp.RegisterLogsServiceServer(server, collector)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	return collector, ln.Addr().String()
+}
+
+func attributes(kvs []*otlp.KeyValue) map[string]string {
+	ret := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		ret[kv.Key] = kv.Value.GetStringValue()
+	}
+	return ret
+}
+
+func TestAuditOTel_export(t *testing.T) {
+	collector, addr := testServer(t)
+
+	b, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"endpoint":            addr,
+			"insecure":            "true",
+			"headers":             "x-api-key=secret",
+			"resource_attributes": "deployment.environment=test",
+		},
+		ClusterName: "vault-cluster-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+		},
+		Request: &logical.Request{
+			ID:        "request-id",
+			Operation: logical.ReadOperation,
+			Path:      "secret/foo",
+		},
+		Response: &logical.Response{
+			Data: map[string]interface{}{
+				"value": "bar",
+				"count": 3,
+			},
+		},
+		OuterErr: logical.ErrPermissionDenied,
+	}
+	if err := b.LogRequest(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.LogResponse(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.l.Lock()
+	defer collector.l.Unlock()
+	if len(collector.requests) != 2 {
+		t.Fatalf("expected 2 exports, got %d", len(collector.requests))
+	}
+	if v := collector.md[0].Get("x-api-key"); len(v) != 1 || v[0] != "secret" {
+		t.Fatalf("bad headers: %v", collector.md[0])
+	}
+
+	rl := collector.requests[1].ResourceLogs[0]
+	resource := attributes(rl.Resource.Attributes)
+	for k, v := range map[string]string{
+		"service.name":           "vault",
+		"vault.cluster.name":     "vault-cluster-test",
+		"vault.namespace.id":     "ns1",
+		"vault.namespace.path":   "ns1/",
+		"deployment.environment": "test",
+	} {
+		if resource[k] != v {
+			t.Fatalf("bad resource attribute %q: %q", k, resource[k])
+		}
+	}
+
+	record := rl.ScopeLogs[0].LogRecords[0]
+	if record.SeverityNumber != otlp.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Fatalf("bad severity: %v", record.SeverityNumber)
+	}
+	attrs := attributes(record.Attributes)
+	if attrs["vault.audit.type"] != "response" || attrs["vault.request.id"] != "request-id" || attrs["vault.request.path"] != "secret/foo" {
+		t.Fatalf("bad record attributes: %v", attrs)
+	}
+
+	// The body carries the hashed entry
+	var response *otlp.KeyValueList
+	for _, kv := range record.Body.GetKvlistValue().Values {
+		if kv.Key == "response" {
+			response = kv.Value.GetKvlistValue()
+		}
+	}
+	if response == nil {
+		t.Fatalf("response missing from body: %v", record.Body)
+	}
+	var data *otlp.KeyValueList
+	for _, kv := range response.Values {
+		if kv.Key == "data" {
+			data = kv.Value.GetKvlistValue()
+		}
+	}
+	if data == nil {
+		t.Fatalf("data missing from response: %v", response)
+	}
+	for _, kv := range data.Values {
+		switch kv.Key {
+		case "value":
+			if v := kv.Value.GetStringValue(); v == "bar" || v == "" {
+				t.Fatalf("expected value to be hashed, got %q", v)
+			}
+		case "count":
+			if v := kv.Value.GetIntValue(); v != 3 {
+				t.Fatalf("bad count: %v", kv.Value)
+			}
+		}
+	}
+}
+
+func TestAuditOTel_config(t *testing.T) {
+	cases := map[string]map[string]string{
+		"missing endpoint": {},
+		"bad timeout":      {"endpoint": "localhost:4317", "timeout": "soon"},
+		"bad headers":      {"endpoint": "localhost:4317", "headers": "novalue"},
+		"missing ca":       {"endpoint": "localhost:4317", "ca_cert": "/does/not/exist"},
+	}
+
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Factory(context.Background(), &audit.BackendConfig{
+				SaltConfig: &salt.Config{},
+				SaltView:   &logical.InmemStorage{},
+				Config:     config,
+			})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
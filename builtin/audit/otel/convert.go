@@ -0,0 +1,78 @@
+package otel
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hashicorp/vault/builtin/audit/otel/otlp"
+)
+
+func stringKeyValue(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{
+		Key: key,
+		Value: &otlp.AnyValue{
+			Value: &otlp.AnyValue_StringValue{StringValue: value},
+		},
+	}
+}
+
+// toAnyValue converts a value decoded from JSON into an OTLP AnyValue, keeping
+// the structure of objects and arrays.
+func toAnyValue(v interface{}) *otlp.AnyValue {
+	switch v := v.(type) {
+	case nil:
+		return &otlp.AnyValue{}
+	case string:
+		return &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &otlp.AnyValue{Value: &otlp.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &otlp.AnyValue{Value: &otlp.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &otlp.AnyValue{Value: &otlp.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: v.String()}}
+	case float64:
+		return &otlp.AnyValue{Value: &otlp.AnyValue_DoubleValue{DoubleValue: v}}
+	case []interface{}:
+		values := make([]*otlp.AnyValue, 0, len(v))
+		for _, elem := range v {
+			values = append(values, toAnyValue(elem))
+		}
+		return &otlp.AnyValue{Value: &otlp.AnyValue_ArrayValue{ArrayValue: &otlp.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		values := make([]*otlp.KeyValue, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			values = append(values, &otlp.KeyValue{
+				Key:   k,
+				Value: toAnyValue(v[k]),
+			})
+		}
+		return &otlp.AnyValue{Value: &otlp.AnyValue_KvlistValue{KvlistValue: &otlp.KeyValueList{Values: values}}}
+	default:
+		// Values decoded from JSON are limited to the types above, but fall
+		// back to their encoding rather than dropping anything
+		b, _ := json.Marshal(v)
+		return &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: string(b)}}
+	}
+}
+
+// sortedKeys returns the keys of a map in order, so that records are built
+// deterministically.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -48,10 +48,13 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		format = "json"
 	}
 	switch format {
-	case "json", "jsonx":
+	case "json", "jsonx", "protobuf":
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
+	if format == "protobuf" && conf.Config["prefix"] != "" {
+		return nil, fmt.Errorf("prefix is not supported with the protobuf format")
+	}
 
 	// Check if hashing of accessor is disabled
 	hmacAccessor := true
@@ -97,6 +100,10 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 			Prefix:   conf.Config["prefix"],
 			SaltFunc: b.Salt,
 		}
+	case "protobuf":
+		b.formatter.AuditFormatWriter = &audit.ProtobufFormatWriter{
+			SaltFunc: b.Salt,
+		}
 	}
 
 	return b, nil
@@ -2,9 +2,11 @@ package cert
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"sync"
 
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -39,6 +41,10 @@ func Backend() *backend {
 	}
 
 	b.crlUpdateMutex = &sync.RWMutex{}
+	b.ocspClient = cleanhttp.DefaultPooledClient()
+	b.ocspCache = &ocspCache{
+		responses: map[string]*ocspCacheEntry{},
+	}
 
 	return &b
 }
@@ -49,6 +55,9 @@ type backend struct {
 
 	crls           map[string]CRLInfo
 	crlUpdateMutex *sync.RWMutex
+
+	ocspClient *http.Client
+	ocspCache  *ocspCache
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
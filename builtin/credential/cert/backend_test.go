@@ -2,18 +2,21 @@ package cert
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/pem"
 	mathrand "math/rand"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"path/filepath"
 
 	"github.com/go-test/deep"
 	"github.com/hashicorp/go-sockaddr"
 
+	"golang.org/x/crypto/ocsp"
 	"golang.org/x/net/http2"
 
 	"crypto/rsa"
@@ -2008,3 +2011,238 @@ func TestBackend_CertUpgrade(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestBackend_OCSP(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ocsp-ca"},
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The responder key is trusted through ocsp_ca_certificates
+	responderKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responderTemplate := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "ocsp-responder"},
+		SerialNumber: big.NewInt(3),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}
+	responderBytes, err := x509.CreateCertificate(rand.Reader, responderTemplate, responderTemplate, responderKey.Public(), responderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responderCert, err := x509.ParseCertificate(responderBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The responder answers with the status set by the test, signed by the
+	// signer set by the test, or fails
+	var status int
+	var fail bool
+	var queries int
+	var nextUpdate time.Time
+	signerCert, signerKey := caCert, crypto.Signer(caKey)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp, err := ocsp.CreateResponse(caCert, signerCert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now(),
+			NextUpdate:   nextUpdate,
+			RevokedAt:    time.Now(),
+		}, signerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "ocsp-client"},
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		OCSPServer:   []string{server.URL},
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connState := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{clientCert},
+	}
+
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeCert := func(data map[string]interface{}) {
+		t.Helper()
+		data["certificate"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caBytes}))
+		data["policies"] = "ocsp"
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "certs/ocsp",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+	}
+	login := func(expectSuccess bool) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Connection: &logical.Connection{
+				ConnState: &connState,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectSuccess && (resp == nil || resp.IsError() || resp.Auth == nil) {
+			t.Fatalf("expected login to succeed: %#v", resp)
+		}
+		if !expectSuccess && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected login to fail: %#v", resp)
+		}
+	}
+
+	// Invalid OCSP server URLs are rejected
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/ocsp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"certificate":           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caBytes})),
+			"ocsp_servers_override": "ldap://example.com",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err:%v resp:%#v", err, resp)
+	}
+
+	// A revoked certificate is accepted while OCSP checking is disabled
+	status = ocsp.Revoked
+	writeCert(map[string]interface{}{})
+	login(true)
+	if queries != 0 {
+		t.Fatalf("expected no OCSP query, got %d", queries)
+	}
+
+	writeCert(map[string]interface{}{"ocsp_enabled": true})
+	login(false)
+
+	status = ocsp.Good
+	login(true)
+
+	// Failures to fetch the status only allow the login when failing open
+	fail = true
+	login(false)
+	writeCert(map[string]interface{}{"ocsp_enabled": true, "ocsp_fail_open": true})
+	login(true)
+	fail = false
+
+	// Even when failing open, a revoked status denies the login
+	status = ocsp.Revoked
+	login(false)
+
+	// The override replaces the servers listed in the certificate
+	writeCert(map[string]interface{}{
+		"ocsp_enabled":          true,
+		"ocsp_fail_open":        false,
+		"ocsp_servers_override": "http://127.0.0.1:1",
+	})
+	login(false)
+	writeCert(map[string]interface{}{
+		"ocsp_enabled":           true,
+		"ocsp_servers_override":  []string{"http://127.0.0.1:1", server.URL},
+		"ocsp_query_all_servers": true,
+	})
+	status = ocsp.Good
+	login(true)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "certs/ocsp",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["ocsp_query_all_servers"] != true || len(resp.Data["ocsp_servers_override"].([]string)) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The responses are cached until their next update
+	signerCert, signerKey = responderCert, responderKey
+	nextUpdate = time.Now().Add(time.Hour)
+	writeCert(map[string]interface{}{
+		"ocsp_enabled":         true,
+		"ocsp_ca_certificates": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: responderBytes})),
+	})
+	login(true)
+	cachedQueries := queries
+	login(true)
+	if queries != cachedQueries {
+		t.Fatalf("expected the response to be cached, got %d queries", queries-cachedQueries)
+	}
+
+	// A cached response is not accepted by an entry that does not trust its
+	// signer
+	writeCert(map[string]interface{}{"ocsp_enabled": true, "ocsp_ca_certificates": ""})
+	login(false)
+}
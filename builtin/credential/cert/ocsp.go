@@ -0,0 +1,240 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// ocspTimeout bounds each request to an OCSP server
+	ocspTimeout = 10 * time.Second
+
+	// ocspMaxResponseSize bounds the size of the responses read from OCSP
+	// servers
+	ocspMaxResponseSize = 1 << 20
+
+	// ocspClockSkew is the tolerated skew between the clocks of Vault and
+	// the OCSP servers
+	ocspClockSkew = 5 * time.Minute
+)
+
+// ocspCache holds the raw OCSP responses until their next update, keyed by
+// the OCSP server, the issuer and the serial number of the certificate. The
+// responses are verified again against the signers trusted by the cert entry
+// of each login, since the cache is shared by all the entries.
+type ocspCache struct {
+	sync.RWMutex
+	responses map[string]*ocspCacheEntry
+}
+
+type ocspCacheEntry struct {
+	raw        []byte
+	nextUpdate time.Time
+}
+
+// ocspCacheKey identifies the response of a server for a certificate. The
+// issuer is identified by its public key, which unlike its subject key ID is
+// always present.
+func ocspCacheKey(server string, cert, issuer *x509.Certificate) string {
+	issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+	return fmt.Sprintf("%s/%x/%s", server, issuerKeyHash, cert.SerialNumber)
+}
+
+func (c *ocspCache) get(key string) []byte {
+	c.RLock()
+	defer c.RUnlock()
+
+	entry := c.responses[key]
+	if entry == nil || time.Now().After(entry.nextUpdate) {
+		return nil
+	}
+	return entry.raw
+}
+
+func (c *ocspCache) put(key string, raw []byte, resp *ocsp.Response) {
+	// Responses without a next update may change at any time
+	if resp.NextUpdate.IsZero() {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Expired responses are dropped when adding new ones, so the cache is
+	// bounded by the number of certificates seen within an update period
+	now := time.Now()
+	for k, v := range c.responses {
+		if now.After(v.nextUpdate) {
+			delete(c.responses, k)
+		}
+	}
+	c.responses[key] = &ocspCacheEntry{
+		raw:        raw,
+		nextUpdate: resp.NextUpdate,
+	}
+}
+
+// checkOCSP returns an error if the client certificate is revoked according
+// to the OCSP servers, or if its status cannot be determined and the entry
+// does not fail open
+func (b *backend) checkOCSP(ctx context.Context, clientCert *x509.Certificate, chain []*x509.Certificate, entry *CertEntry) error {
+	if !entry.OcspEnabled {
+		return nil
+	}
+
+	status, err := b.ocspStatus(ctx, clientCert, chain, entry)
+	switch {
+	case status == ocsp.Revoked:
+		return fmt.Errorf("the client certificate has been revoked")
+	case err != nil && entry.OcspFailOpen:
+		b.Logger().Warn("unable to check the revocation status of the client certificate, allowing it", "serial_number", clientCert.SerialNumber.String(), "error", err)
+		return nil
+	case err != nil:
+		return errwrap.Wrapf("unable to check the revocation status of the client certificate: {{err}}", err)
+	}
+	return nil
+}
+
+// ocspStatus queries the OCSP servers for the status of the certificate.
+// An error is returned unless the status is good or revoked.
+func (b *backend) ocspStatus(ctx context.Context, cert *x509.Certificate, chain []*x509.Certificate, entry *CertEntry) (int, error) {
+	issuer := findIssuer(cert, chain)
+	if issuer == nil {
+		return ocsp.Unknown, fmt.Errorf("issuer of the client certificate not found")
+	}
+
+	servers := entry.OcspServersOverride
+	if len(servers) == 0 {
+		servers = cert.OCSPServer
+	}
+	if len(servers) == 0 {
+		return ocsp.Unknown, fmt.Errorf("no OCSP server configured or listed in the client certificate")
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return ocsp.Unknown, err
+	}
+	signers := append([]*x509.Certificate{issuer}, parsePEM([]byte(entry.OcspCaCertificates))...)
+
+	var errs *multierror.Error
+	status := ocsp.Unknown
+	for _, server := range servers {
+		resp, err := b.ocspResponse(ctx, server, request, cert, issuer, signers)
+		if err != nil {
+			errs = multierror.Append(errs, errwrap.Wrapf(server+": {{err}}", err))
+			continue
+		}
+
+		switch resp.Status {
+		case ocsp.Revoked:
+			return ocsp.Revoked, nil
+		case ocsp.Good:
+			status = ocsp.Good
+			if !entry.OcspQueryAllServers {
+				return status, nil
+			}
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("%s: unknown certificate status", server))
+		}
+	}
+
+	if status == ocsp.Good {
+		return status, nil
+	}
+	return status, errs.ErrorOrNil()
+}
+
+// ocspResponse returns the response of the server for the certificate, from
+// the cache if the cached response is signed by one of the signers
+func (b *backend) ocspResponse(ctx context.Context, server string, request []byte, cert, issuer *x509.Certificate, signers []*x509.Certificate) (*ocsp.Response, error) {
+	key := ocspCacheKey(server, cert, issuer)
+	if raw := b.ocspCache.get(key); raw != nil {
+		if resp, err := parseOCSPResponse(raw, cert, signers); err == nil {
+			return resp, nil
+		}
+	}
+
+	raw, err := b.queryOCSP(ctx, server, request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := parseOCSPResponse(raw, cert, signers)
+	if err != nil {
+		return nil, err
+	}
+	b.ocspCache.put(key, raw, resp)
+	return resp, nil
+}
+
+// queryOCSP sends the request to the server, and returns its raw response
+func (b *backend) queryOCSP(ctx context.Context, server string, request []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, ocspTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, server, bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := b.ocspClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	return ioutil.ReadAll(http.MaxBytesReader(nil, httpResp.Body, ocspMaxResponseSize))
+}
+
+// parseOCSPResponse returns the response for the certificate if it is signed
+// by one of the signers and currently valid
+func parseOCSPResponse(raw []byte, cert *x509.Certificate, signers []*x509.Certificate) (*ocsp.Response, error) {
+	var errs *multierror.Error
+	for _, signer := range signers {
+		resp, err := ocsp.ParseResponseForCert(raw, cert, signer)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		now := time.Now()
+		if resp.ThisUpdate.After(now.Add(ocspClockSkew)) {
+			return nil, fmt.Errorf("response is not valid yet")
+		}
+		if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(now.Add(-ocspClockSkew)) {
+			return nil, fmt.Errorf("response has expired")
+		}
+		return resp, nil
+	}
+	return nil, errwrap.Wrapf("invalid response: {{err}}", errs.ErrorOrNil())
+}
+
+// findIssuer returns the certificate of the chain that signed cert
+func findIssuer(cert *x509.Certificate, chain []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range chain {
+		if candidate.Equal(cert) {
+			continue
+		}
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
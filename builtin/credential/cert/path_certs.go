@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -114,6 +115,58 @@ formatted as "oid:value". Expects the extension value to be some type of ASN1 en
 All values much match. Supports globbing on "value".`,
 			},
 
+			"ocsp_enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Whether to check the revocation status of the client certificate with OCSP.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "OCSP Enabled",
+					Group: "OCSP",
+				},
+			},
+
+			"ocsp_ca_certificates": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Additional CA certificates, PEM encoded, trusted to
+sign the OCSP responses. The issuer of the client certificate is always
+trusted.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:     "OCSP CA Certificates",
+					Group:    "OCSP",
+					EditType: "file",
+				},
+			},
+
+			"ocsp_servers_override": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of OCSP server URLs to query,
+instead of the ones listed in the client certificate.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "OCSP Servers Override",
+					Group: "OCSP",
+				},
+			},
+
+			"ocsp_fail_open": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to allow the login when no OCSP server gives a
+definitive answer. A certificate reported as revoked is always rejected.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "OCSP Fail Open",
+					Group: "OCSP",
+				},
+			},
+
+			"ocsp_query_all_servers": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to query all the OCSP servers, and reject the
+login if any of them reports the certificate as revoked, instead of using the
+first answer.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "OCSP Query All Servers",
+					Group: "OCSP",
+				},
+			},
+
 			"display_name": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `The display name to use for clients using this
@@ -243,6 +296,11 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 		"allowed_uri_sans":             cert.AllowedURISANs,
 		"allowed_organizational_units": cert.AllowedOrganizationalUnits,
 		"required_extensions":          cert.RequiredExtensions,
+		"ocsp_enabled":                 cert.OcspEnabled,
+		"ocsp_ca_certificates":         cert.OcspCaCertificates,
+		"ocsp_servers_override":        cert.OcspServersOverride,
+		"ocsp_fail_open":               cert.OcspFailOpen,
+		"ocsp_query_all_servers":       cert.OcspQueryAllServers,
 	}
 	cert.PopulateTokenData(data)
 
@@ -309,6 +367,21 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if requiredExtensionsRaw, ok := d.GetOk("required_extensions"); ok {
 		cert.RequiredExtensions = requiredExtensionsRaw.([]string)
 	}
+	if ocspEnabledRaw, ok := d.GetOk("ocsp_enabled"); ok {
+		cert.OcspEnabled = ocspEnabledRaw.(bool)
+	}
+	if ocspCaCertificatesRaw, ok := d.GetOk("ocsp_ca_certificates"); ok {
+		cert.OcspCaCertificates = ocspCaCertificatesRaw.(string)
+	}
+	if ocspServersOverrideRaw, ok := d.GetOk("ocsp_servers_override"); ok {
+		cert.OcspServersOverride = ocspServersOverrideRaw.([]string)
+	}
+	if ocspFailOpenRaw, ok := d.GetOk("ocsp_fail_open"); ok {
+		cert.OcspFailOpen = ocspFailOpenRaw.(bool)
+	}
+	if ocspQueryAllServersRaw, ok := d.GetOk("ocsp_query_all_servers"); ok {
+		cert.OcspQueryAllServers = ocspQueryAllServersRaw.(bool)
+	}
 
 	// Get tokenutil fields
 	if err := cert.ParseTokenFields(req, d); err != nil {
@@ -376,6 +449,14 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if len(parsed) == 0 {
 		return logical.ErrorResponse("failed to parse certificate"), nil
 	}
+	if cert.OcspCaCertificates != "" && len(parsePEM([]byte(cert.OcspCaCertificates))) == 0 {
+		return logical.ErrorResponse("failed to parse ocsp_ca_certificates"), nil
+	}
+	for _, server := range cert.OcspServersOverride {
+		if u, err := url.Parse(server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid OCSP server URL %q", server)), nil
+		}
+	}
 
 	// If the certificate is not a CA cert, then ensure that x509.ExtKeyUsageClientAuth is set
 	if !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
@@ -425,6 +506,11 @@ type CertEntry struct {
 	AllowedOrganizationalUnits []string
 	RequiredExtensions         []string
 	BoundCIDRs                 []*sockaddr.SockAddrMarshaler
+	OcspEnabled                bool
+	OcspCaCertificates         string
+	OcspServersOverride        []string
+	OcspFailOpen               bool
+	OcspQueryAllServers        bool
 }
 
 const pathCertHelpSyn = `
@@ -438,4 +524,8 @@ that are allowed to authenticate.
 Deleting a certificate will not revoke auth for prior authenticated connections.
 To do this, do a revoke on "login". If you don't need to revoke login immediately,
 then the next renew will cause the lease to expire.
+
+When OCSP checking is enabled, the revocation status of the client certificate
+is also queried from the OCSP servers listed in the certificate, or from the
+configured override servers, on every login and renewal.
 `
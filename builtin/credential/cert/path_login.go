@@ -207,6 +207,11 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 			if tCert.SerialNumber.Cmp(clientCert.SerialNumber) == 0 &&
 				bytes.Equal(tCert.AuthorityKeyId, clientCert.AuthorityKeyId) &&
 				b.matchesConstraints(clientCert, trustedNonCA.Certificates, trustedNonCA) {
+				// The issuer of a registered non-CA certificate can only
+				// come from the chain sent by the client
+				if err := b.checkOCSP(ctx, clientCert, connState.PeerCertificates, trustedNonCA.Entry); err != nil {
+					return nil, logical.ErrorResponse(err.Error()), nil
+				}
 				return trustedNonCA, nil, nil
 			}
 		}
@@ -220,6 +225,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 
 	// Search for a ParsedCert that intersects with the validated chains and any additional constraints
 	matches := make([]*ParsedCert, 0)
+	matchedChains := make([][]*x509.Certificate, 0)
 	for _, trust := range trusted { // For each ParsedCert in the config
 		for _, tCert := range trust.Certificates { // For each certificate in the entry
 			for _, chain := range trustedChains { // For each root chain that we matched
@@ -228,6 +234,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 						b.matchesConstraints(clientCert, chain, trust) { // validate client cert + matched chain against the config
 						// Add the match to the list
 						matches = append(matches, trust)
+						matchedChains = append(matchedChains, chain)
 					}
 				}
 			}
@@ -239,8 +246,17 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 		return nil, logical.ErrorResponse("no chain matching all constraints could be found for this login certificate"), nil
 	}
 
-	// Return the first matching entry (for backwards compatibility, we continue to just pick one if multiple match)
-	return matches[0], nil, nil
+	// Return the first matching entry that passes its OCSP check (for
+	// backwards compatibility, we continue to just pick one if multiple match)
+	var ocspErr error
+	for i, match := range matches {
+		if err := b.checkOCSP(ctx, clientCert, matchedChains[i], match.Entry); err != nil {
+			ocspErr = err
+			continue
+		}
+		return match, nil, nil
+	}
+	return nil, logical.ErrorResponse(ocspErr.Error()), nil
 }
 
 func (b *backend) matchesConstraints(clientCert *x509.Certificate, trustedChain []*x509.Certificate, config *ParsedCert) bool {
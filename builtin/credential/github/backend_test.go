@@ -3,7 +3,10 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -204,3 +207,86 @@ func testAccLogin(t *testing.T, policies []string) logicaltest.TestStep {
 		Check: logicaltest.TestCheckAuth(policies),
 	}
 }
+
+func TestBackend_fineGrainedToken(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer github_pat_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login": "octocat", "id": 1}`)
+	})
+	mux.HandleFunc("/user/memberships/orgs/acme", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"state": "active", "organization": {"login": "acme", "id": 10}}`)
+	})
+	mux.HandleFunc("/user/memberships/orgs/other", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/orgs/acme/teams", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 100, "name": "Dev Ops", "slug": "dev-ops"}, {"id": 101, "name": "admins", "slug": "admins"}]`)
+	})
+	mux.HandleFunc("/teams/100/memberships/octocat", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"state": "active"}`)
+	})
+	mux.HandleFunc("/teams/101/memberships/octocat", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	storage := &logical.InmemStorage{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		StorageView: storage,
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour,
+			MaxLeaseTTLVal:     time.Hour,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	write("config", map[string]interface{}{
+		"organization": "acme",
+		"base_url":     srv.URL + "/",
+	})
+	write("map/teams/dev-ops", map[string]interface{}{"value": "devops"})
+	write("map/teams/admins", map[string]interface{}{"value": "admins"})
+
+	resp := write("login", map[string]interface{}{"token": "github_pat_test"})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"devops"}) {
+		t.Fatalf("bad: policies %v", resp.Auth.Policies)
+	}
+	if resp.Auth.Metadata["org"] != "acme" || resp.Auth.Metadata["username"] != "octocat" {
+		t.Fatalf("bad: metadata %v", resp.Auth.Metadata)
+	}
+	if len(resp.Auth.GroupAliases) != 2 {
+		t.Fatalf("bad: group aliases %#v", resp.Auth.GroupAliases)
+	}
+
+	write("config", map[string]interface{}{
+		"organization": "other",
+	})
+	resp = write("login", map[string]interface{}{"token": "github_pat_test"})
+	if !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
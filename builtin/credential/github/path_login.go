@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -14,6 +15,10 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// fineGrainedTokenPrefix is the prefix of the fine-grained personal access
+// tokens
+const fineGrainedTokenPrefix = "github_pat_"
+
 func pathLogin(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "login",
@@ -184,7 +189,58 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		return nil, nil, err
 	}
 
-	// Verify that the user is part of the organization
+	// Fine-grained personal access tokens cannot list the organizations and
+	// teams of the user, so their membership is checked through the
+	// organization instead
+	var org *github.Organization
+	var teamNames []string
+	if strings.HasPrefix(token, fineGrainedTokenPrefix) {
+		org, teamNames, err = orgTeamsFromOrg(ctx, client, config.Organization, *user.Login)
+	} else {
+		org, teamNames, err = orgTeamsFromUser(ctx, client, config.Organization)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if org == nil {
+		return nil, logical.ErrorResponse("user is not part of required org"), nil
+	}
+
+	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &verifyCredentialsResp{
+		User:      user,
+		Org:       org,
+		Policies:  append(groupPoliciesList, userPoliciesList...),
+		TeamNames: teamNames,
+		Config:    config,
+	}, nil, nil
+}
+
+type verifyCredentialsResp struct {
+	User      *github.User
+	Org       *github.Organization
+	Policies  []string
+	TeamNames []string
+
+	// This is just a cache to send back to the caller
+	Config *config
+}
+
+// orgTeamsFromUser returns the organization and the names of the teams of the
+// user, from the organizations and teams listed for the token. The returned
+// organization is nil if the user is not part of it.
+func orgTeamsFromUser(ctx context.Context, client *github.Client, orgName string) (*github.Organization, []string, error) {
 	var org *github.Organization
 
 	orgOpt := &github.ListOptions{
@@ -205,13 +261,13 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 	}
 
 	for _, o := range allOrgs {
-		if strings.EqualFold(*o.Login, config.Organization) {
+		if strings.EqualFold(*o.Login, orgName) {
 			org = o
 			break
 		}
 	}
 	if org == nil {
-		return nil, logical.ErrorResponse("user is not part of required org"), nil
+		return nil, nil, nil
 	}
 
 	// Get the teams that this user is part of to determine the policies
@@ -241,39 +297,69 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		}
 
 		// Append the names so we can get the policies
-		teamNames = append(teamNames, *t.Name)
-		if *t.Name != *t.Slug {
-			teamNames = append(teamNames, *t.Slug)
-		}
+		teamNames = append(teamNames, teamAliases(t)...)
 	}
 
-	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
+	return org, teamNames, nil
+}
 
-	if err != nil {
+// orgTeamsFromOrg returns the organization and the names of the teams of the
+// user, by checking the membership of the user in the organization and in each
+// of its teams. This requires read access to the organization members, which
+// fine-grained tokens have to be granted explicitly. The returned organization
+// is nil if the user is not an active member of it.
+func orgTeamsFromOrg(ctx context.Context, client *github.Client, orgName, userName string) (*github.Organization, []string, error) {
+	membership, resp, err := client.Organizations.GetOrgMembership(ctx, "", orgName)
+	switch {
+	case isNotFound(resp):
+		return nil, nil, nil
+	case err != nil:
 		return nil, nil, err
+	case membership.GetState() != "active" || membership.Organization == nil:
+		return nil, nil, nil
 	}
+	org := membership.Organization
 
-	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
+	var teamNames []string
 
-	if err != nil {
-		return nil, nil, err
+	teamOpt := &github.ListOptions{
+		PerPage: 100,
 	}
 
-	return &verifyCredentialsResp{
-		User:      user,
-		Org:       org,
-		Policies:  append(groupPoliciesList, userPoliciesList...),
-		TeamNames: teamNames,
-		Config:    config,
-	}, nil, nil
+	for {
+		teams, resp, err := client.Teams.ListTeams(ctx, org.GetLogin(), teamOpt)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range teams {
+			teamMembership, resp, err := client.Teams.GetTeamMembership(ctx, t.GetID(), userName)
+			switch {
+			case isNotFound(resp):
+				continue
+			case err != nil:
+				return nil, nil, err
+			case teamMembership.GetState() != "active":
+				continue
+			}
+			teamNames = append(teamNames, teamAliases(t)...)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		teamOpt.Page = resp.NextPage
+	}
+
+	return org, teamNames, nil
 }
 
-type verifyCredentialsResp struct {
-	User      *github.User
-	Org       *github.Organization
-	Policies  []string
-	TeamNames []string
+// teamAliases returns the names a team can be mapped with
+func teamAliases(t *github.Team) []string {
+	if *t.Name != *t.Slug {
+		return []string{*t.Name, *t.Slug}
+	}
+	return []string{*t.Name}
+}
 
-	// This is just a cache to send back to the caller
-	Config *config
+func isNotFound(resp *github.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
 }
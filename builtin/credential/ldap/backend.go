@@ -43,11 +43,14 @@ func Backend() *backend {
 			pathGroupsList(&b),
 			pathUsers(&b),
 			pathUsersList(&b),
+			pathTestLogin(&b),
 		},
 			mfa.MFAPaths(b.Backend, pathLogin(&b))...,
 		),
 
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+		Clean:       b.cleanup,
 		BackendType: logical.TypeCredential,
 	}
 
@@ -56,20 +59,56 @@ func Backend() *backend {
 
 type backend struct {
 	*framework.Backend
+
+	connectionPool connectionPool
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch key {
+	case "config":
+		b.connectionPool.reset()
+	}
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.connectionPool.reset()
 }
 
 func (b *backend) Login(ctx context.Context, req *logical.Request, username string, password string) ([]string, *logical.Response, []string, error) {
+	result, resp, err := b.login(ctx, req, username, password)
+	if err != nil || (resp != nil && resp.IsError()) {
+		return nil, resp, nil, err
+	}
+	return result.Policies, resp, result.Groups, nil
+}
+
+// loginResult describes the steps of a login, for the test-login endpoint
+type loginResult struct {
+	UserBindDN  string
+	UserDN      string
+	LDAPGroups  []string
+	LocalGroups []string
+	Groups      []string
+	Policies    []string
+
+	// Err is the detailed error of a failed login, which is only logged
+	// otherwise
+	Err error
+}
+
+func (b *backend) login(ctx context.Context, req *logical.Request, username string, password string) (*loginResult, *logical.Response, error) {
+	result := &loginResult{}
 
 	cfg, err := b.Config(ctx, req)
 	if err != nil {
-		return nil, nil, nil, err
+		return result, nil, err
 	}
 	if cfg == nil {
-		return nil, logical.ErrorResponse("ldap backend not configured"), nil, nil
+		return result, logical.ErrorResponse("ldap backend not configured"), nil
 	}
 
 	if cfg.DenyNullBind && len(password) == 0 {
-		return nil, logical.ErrorResponse("password cannot be of zero length when passwordless binds are being denied"), nil, nil
+		return result, logical.ErrorResponse("password cannot be of zero length when passwordless binds are being denied"), nil
 	}
 
 	ldapClient := ldaputil.Client{
@@ -77,24 +116,31 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		LDAP:   ldaputil.NewLDAP(),
 	}
 
-	c, err := ldapClient.DialLDAP(cfg.ConfigEntry)
+	c, err := b.connectionPool.get(&ldapClient, cfg.ConfigEntry)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil, nil
+		result.Err = err
+		return result, logical.ErrorResponse(err.Error()), nil
 	}
 	if c == nil {
-		return nil, logical.ErrorResponse("invalid connection returned from LDAP dial"), nil, nil
+		return result, logical.ErrorResponse("invalid connection returned from LDAP dial"), nil
 	}
 
-	// Clean connection
-	defer c.Close()
+	// Return the connection to the pool, unless the login failed since the
+	// connection may be in an unknown state
+	var reuse bool
+	defer func() {
+		b.connectionPool.put(c, reuse)
+	}()
 
 	userBindDN, err := ldapClient.GetUserBindDN(cfg.ConfigEntry, c, username)
 	if err != nil {
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("error getting user bind DN", "error", err)
 		}
-		return nil, logical.ErrorResponse("ldap operation failed: unable to retrieve user bind DN"), nil, nil
+		result.Err = err
+		return result, logical.ErrorResponse("ldap operation failed: unable to retrieve user bind DN"), logical.ErrInvalidCredentials
 	}
+	result.UserBindDN = userBindDN
 
 	if b.Logger().IsDebug() {
 		b.Logger().Debug("user binddn fetched", "username", username, "binddn", userBindDN)
@@ -110,7 +156,8 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("ldap bind failed", "error", err)
 		}
-		return nil, logical.ErrorResponse("ldap operation failed: failed to bind as user"), nil, nil
+		result.Err = err
+		return result, logical.ErrorResponse("ldap operation failed: failed to bind as user"), logical.ErrInvalidCredentials
 	}
 
 	// We re-bind to the BindDN if it's defined because we assume
@@ -120,7 +167,8 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 			if b.Logger().IsDebug() {
 				b.Logger().Debug("error while attempting to re-bind with the BindDN User", "error", err)
 			}
-			return nil, logical.ErrorResponse("ldap operation failed: failed to re-bind with the BindDN user"), nil, nil
+			result.Err = err
+			return result, logical.ErrorResponse("ldap operation failed: failed to re-bind with the BindDN user"), nil
 		}
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("re-bound to original binddn")
@@ -129,24 +177,33 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 
 	userDN, err := ldapClient.GetUserDN(cfg.ConfigEntry, c, userBindDN, username)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil, nil
+		result.Err = err
+		return result, logical.ErrorResponse(err.Error()), nil
 	}
+	result.UserDN = userDN
 
+	// Anonymous group searches need a connection that was never bound, so
+	// it is not taken from the pool
+	var groupConn ldaputil.Connection = c
 	if cfg.AnonymousGroupSearch {
-		c, err = ldapClient.DialLDAP(cfg.ConfigEntry)
+		groupConn, err = ldapClient.DialLDAP(cfg.ConfigEntry)
 		if err != nil {
-			return nil, logical.ErrorResponse("ldap operation failed: failed to connect to LDAP server"), nil, nil
+			result.Err = err
+			return result, logical.ErrorResponse("ldap operation failed: failed to connect to LDAP server"), nil
 		}
-		defer c.Close() // Defer closing of this connection as the deferal above closes the other defined connection
+		defer groupConn.Close()
 	}
 
-	ldapGroups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, c, userDN, username)
+	ldapGroups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, groupConn, userDN, username)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil, nil
+		result.Err = err
+		return result, logical.ErrorResponse(err.Error()), nil
 	}
+	result.LDAPGroups = ldapGroups
 	if b.Logger().IsDebug() {
 		b.Logger().Debug("groups fetched from server", "num_server_groups", len(ldapGroups), "server_groups", ldapGroups)
 	}
+	reuse = true
 
 	ldapResponse := &logical.Response{
 		Data: map[string]interface{}{},
@@ -171,9 +228,11 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 			b.Logger().Debug("adding local groups", "num_local_groups", len(user.Groups), "local_groups", user.Groups)
 		}
 		allGroups = append(allGroups, user.Groups...)
+		result.LocalGroups = user.Groups
 	}
 	// Merge local and LDAP groups
 	allGroups = append(allGroups, ldapGroups...)
+	result.Groups = allGroups
 
 	canonicalGroups := allGroups
 	// If not case sensitive, lowercase all
@@ -196,9 +255,9 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		policies = append(policies, user.Policies...)
 	}
 	// Policies from each group may overlap
-	policies = strutil.RemoveDuplicates(policies, true)
+	result.Policies = strutil.RemoveDuplicates(policies, true)
 
-	return policies, ldapResponse, allGroups, nil
+	return result, ldapResponse, nil
 }
 
 const backendHelp = `
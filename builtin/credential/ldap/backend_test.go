@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -434,6 +435,7 @@ func TestBackend_basic(t *testing.T) {
 
 			// Authenticate
 			testAccStepLogin(t, "hermes conrad", "hermes"),
+			testAccStepTestLogin(t, "hermes conrad", "hermes"),
 
 			// Verify both groups mappings can be listed back
 			testAccStepGroupList(t, []string{"engineers", "admin_staff"}),
@@ -855,6 +857,34 @@ func testAccStepLogin(t *testing.T, user string, pass string) logicaltest.TestSt
 	}
 }
 
+func testAccStepTestLogin(t *testing.T, user string, pass string) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.UpdateOperation,
+		Path:      "test-login/" + user,
+		Data: map[string]interface{}{
+			"password": pass,
+		},
+
+		Check: func(resp *logical.Response) error {
+			if resp.Auth != nil {
+				return fmt.Errorf("expected no token to be issued")
+			}
+			if resp.Data["success"] != true {
+				return fmt.Errorf("expected a successful login: %#v", resp.Data)
+			}
+			if resp.Data["user_dn"] != "cn=Hermes Conrad,ou=people,dc=planetexpress,dc=com" {
+				return fmt.Errorf("bad user_dn: %#v", resp.Data["user_dn"])
+			}
+			policies := resp.Data["policies"].([]string)
+			sort.Strings(policies)
+			if !reflect.DeepEqual(policies, []string{"bar", "foo"}) {
+				return fmt.Errorf("bad policies: %#v", policies)
+			}
+			return nil
+		},
+	}
+}
+
 func testAccStepLoginNoAttachedPolicies(t *testing.T, user string, pass string) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -1002,6 +1032,7 @@ func TestLdapAuthBackend_ConfigUpgrade(t *testing.T) {
 			CaseSensitiveNames:       falseBool,
 			UsePre111GroupCNBehavior: new(bool),
 			RequestTimeout:           cfg.RequestTimeout,
+			ReferralHosts:            defParams.ReferralHosts,
 		},
 	}
 
@@ -1034,3 +1065,119 @@ func TestLdapAuthBackend_ConfigUpgrade(t *testing.T) {
 	}
 
 }
+
+func TestLdapAuthBackend_TestLogin(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "config",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"url":             "ldap://127.0.0.1:1",
+			"userdn":          "ou=people,dc=example,dc=com",
+			"max_page_size":   500,
+			"chase_referrals": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "config",
+		Operation: logical.ReadOperation,
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Data["max_page_size"] != 500 || resp.Data["chase_referrals"] != true {
+		t.Fatalf("bad config: %#v", resp.Data)
+	}
+
+	// The test login reports the error instead of failing
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "test-login/alice",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"password": "password",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Data["success"] != false || resp.Auth != nil {
+		t.Fatalf("expected the login to fail: %#v", resp)
+	}
+	if !strings.Contains(resp.Data["error"].(string), "127.0.0.1:1") {
+		t.Fatalf("expected the error to name the server: %#v", resp.Data["error"])
+	}
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "config",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_page_size": -1,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
+// fakeConnection is an LDAP connection that only tracks whether it is closed
+type fakeConnection struct {
+	ldaputil.Connection
+	closed bool
+}
+
+func (c *fakeConnection) Close() {
+	c.closed = true
+}
+
+func TestConnectionPool(t *testing.T) {
+	var pool connectionPool
+
+	first := &pooledConnection{Connection: &fakeConnection{}}
+	second := &pooledConnection{Connection: &fakeConnection{}}
+	pool.put(first, true)
+	pool.put(second, false)
+	if !second.Connection.(*fakeConnection).closed {
+		t.Fatal("expected the connection not to be reused")
+	}
+
+	conn, err := pool.get(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn != first {
+		t.Fatal("expected the idle connection to be reused")
+	}
+
+	// Connections opened before a reset are not reused
+	pool.put(conn, true)
+	pool.reset()
+	if !first.Connection.(*fakeConnection).closed {
+		t.Fatal("expected the idle connection to be closed")
+	}
+	third := &pooledConnection{Connection: &fakeConnection{}}
+	pool.put(third, true)
+	if !third.Connection.(*fakeConnection).closed || len(pool.idle) != 0 {
+		t.Fatal("expected the connection of the previous configuration to be closed")
+	}
+
+	// Expired connections are closed
+	fourth := &pooledConnection{Connection: &fakeConnection{}, generation: pool.generation}
+	pool.put(fourth, true)
+	fourth.idleSince = time.Now().Add(-maxIdleTime)
+	conn, err = pool.get(&ldaputil.Client{
+		Logger: hclog.NewNullLogger(),
+		LDAP:   ldaputil.NewLDAP(),
+	}, &ldaputil.ConfigEntry{Url: "ldap://127.0.0.1:1"})
+	if err == nil || conn != nil || !fourth.Connection.(*fakeConnection).closed {
+		t.Fatalf("expected a new connection to be dialed: %#v, %v", conn, err)
+	}
+}
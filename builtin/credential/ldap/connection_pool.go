@@ -0,0 +1,100 @@
+package ldap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
+)
+
+const (
+	// maxIdleConnections is the number of idle connections kept open to
+	// the LDAP servers
+	maxIdleConnections = 10
+
+	// maxIdleTime is how long an idle connection is kept open, which is
+	// below the idle timeout of the common LDAP servers
+	maxIdleTime = 2 * time.Minute
+)
+
+// connectionPool keeps the connections to the LDAP servers open between
+// logins. Every login starts with a bind, so connections are reused
+// regardless of the identity they were last bound with.
+type connectionPool struct {
+	sync.Mutex
+
+	idle []*pooledConnection
+
+	// generation is incremented when the configuration changes, so that
+	// connections opened with a previous configuration are not reused
+	generation int
+}
+
+type pooledConnection struct {
+	ldaputil.Connection
+
+	generation int
+	idleSince  time.Time
+}
+
+// get returns an idle connection, or opens a new one
+func (p *connectionPool) get(client *ldaputil.Client, cfg *ldaputil.ConfigEntry) (*pooledConnection, error) {
+	p.Lock()
+	generation := p.generation
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if time.Since(conn.idleSince) < maxIdleTime && !isClosing(conn.Connection) {
+			p.Unlock()
+			return conn, nil
+		}
+		conn.Close()
+	}
+	p.Unlock()
+
+	conn, err := client.DialLDAP(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, nil
+	}
+	return &pooledConnection{
+		Connection: conn,
+		generation: generation,
+	}, nil
+}
+
+// put returns the connection to the pool if it can be reused, and closes it
+// otherwise
+func (p *connectionPool) put(conn *pooledConnection, reuse bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if !reuse || conn.generation != p.generation || len(p.idle) >= maxIdleConnections || isClosing(conn.Connection) {
+		conn.Close()
+		return
+	}
+	conn.idleSince = time.Now()
+	p.idle = append(p.idle, conn)
+}
+
+// reset closes the idle connections, and prevents the connections in use
+// from being reused
+func (p *connectionPool) reset() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+	p.generation++
+}
+
+// isClosing reports whether the connection was closed, by the server or
+// after an error
+func isClosing(conn ldaputil.Connection) bool {
+	c, ok := conn.(interface{ IsClosing() bool })
+	return ok && c.IsClosing()
+}
@@ -154,6 +154,9 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		return nil, err
 	}
 
+	// Connections to the previously configured servers are not reused
+	b.connectionPool.reset()
+
 	return nil, nil
 }
 
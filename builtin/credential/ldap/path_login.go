@@ -74,9 +74,9 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 	password := d.Get("password").(string)
 
 	policies, resp, groupNames, err := b.Login(ctx, req, username, password)
-	// Handle an internal error
+	// Handle an internal error, or invalid credentials
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
 	if resp != nil {
 		// Handle a logical error
@@ -163,6 +163,62 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	return resp, nil
 }
 
+func pathTestLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `test-login/(?P<username>.+)`,
+		Fields: map[string]*framework.FieldSchema{
+			"username": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "DN (distinguished name) to be used for login.",
+			},
+
+			"password": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Password for this user.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTestLogin,
+		},
+
+		HelpSynopsis:    pathTestLoginSyn,
+		HelpDescription: pathTestLoginDesc,
+	}
+}
+
+func (b *backend) pathTestLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := d.Get("username").(string)
+	password := d.Get("password").(string)
+
+	result, resp, err := b.login(ctx, req, username, password)
+	if err != nil && err != logical.ErrInvalidCredentials {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"user_bind_dn": result.UserBindDN,
+		"user_dn":      result.UserDN,
+		"ldap_groups":  result.LDAPGroups,
+		"local_groups": result.LocalGroups,
+		"policies":     result.Policies,
+		"success":      resp == nil || !resp.IsError(),
+	}
+	testResp := &logical.Response{
+		Data: data,
+	}
+	if resp != nil {
+		if resp.IsError() {
+			data["error"] = resp.Error().Error()
+			if result.Err != nil {
+				data["error"] = fmt.Sprintf("%s: %s", data["error"], result.Err)
+			}
+		}
+		testResp.Warnings = resp.Warnings
+	}
+	return testResp, nil
+}
+
 const pathLoginSyn = `
 Log in with a username and password.
 `
@@ -171,3 +227,14 @@ const pathLoginDesc = `
 This endpoint authenticates using a username and password. Please be sure to
 read the note on escaping from the path-help for the 'config' endpoint.
 `
+
+const pathTestLoginSyn = `
+Test a login with a username and password, without issuing a token.
+`
+
+const pathTestLoginDesc = `
+This endpoint runs the same steps as the "login" endpoint without issuing a
+token, and returns the DNs, groups and policies found for the user, along with
+the detailed error of a failed login. It is meant to diagnose the
+configuration, so unlike "login" it requires authentication.
+`
@@ -57,7 +57,11 @@ type backend struct {
 	*framework.Backend
 }
 
-func (b *backend) Login(ctx context.Context, req *logical.Request, username string, password string) ([]string, *logical.Response, []string, error) {
+// Login authenticates the user with Okta. When totp is set, it is used as the
+// second factor, and Okta Verify Push is used otherwise. The second factor is
+// not checked when verifyMFA is false, which is used to renew the tokens of
+// logins made with a one time passcode that cannot be replayed.
+func (b *backend) Login(ctx context.Context, req *logical.Request, username, password, totp string, verifyMFA bool) ([]string, *logical.Response, []string, error) {
 	cfg, err := b.Config(ctx, req.Storage)
 	if err != nil {
 		return nil, nil, nil, err
@@ -160,7 +164,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		// active factor enrollment). This bypass removes visibility
 		// into the authenticating user's password expiry, but still ensures the
 		// credentials are valid and the user is not locked out.
-		if cfg.BypassOktaMFA {
+		if cfg.BypassOktaMFA || !verifyMFA {
 			result.Status = "SUCCESS"
 			break
 		}
@@ -168,15 +172,21 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		factorAvailable := false
 
 		var selectedFactor mfaFactor
-		// only okta push is currently supported
+		// Okta Verify Push, and TOTP factors of any provider such as Okta
+		// Verify or Google Authenticator, are supported
 		for _, v := range result.Embedded.Factors {
-			if v.Type == "push" && v.Provider == "OKTA" {
+			switch {
+			case totp == "" && v.Type == "push" && v.Provider == "OKTA",
+				totp != "" && v.Type == "token:software:totp":
 				factorAvailable = true
 				selectedFactor = v
 			}
 		}
 
 		if !factorAvailable {
+			if totp != "" {
+				return nil, logical.ErrorResponse("a TOTP factor is required in order to perform MFA with a passcode"), nil, nil
+			}
 			return nil, logical.ErrorResponse("Okta Verify Push factor is required in order to perform MFA"), nil, nil
 		}
 
@@ -184,6 +194,9 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		payload := map[string]interface{}{
 			"stateToken": result.StateToken,
 		}
+		if totp != "" {
+			payload["passCode"] = totp
+		}
 		verifyReq, err := shim.NewRequest("POST", requestPath, payload)
 		if err != nil {
 			return nil, nil, nil, err
@@ -38,6 +38,10 @@ func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, erro
 		"password": password,
 	}
 
+	if totp, ok := m["totp"]; ok {
+		data["totp"] = totp
+	}
+
 	mfa_method, ok := m["method"]
 	if ok {
 		data["method"] = mfa_method
@@ -75,12 +79,20 @@ Usage: vault login -method=okta [CONFIG K=V...]
 
       $ vault login -method=okta username=bob password=password
 
+  Authenticate as "bob" with a TOTP passcode instead of Okta Verify Push:
+
+      $ vault login -method=okta username=bob totp=123456
+
 Configuration:
 
   password=<string>
       Okta password to use for authentication. If not provided, the CLI will
       prompt for this on stdin.
 
+  totp=<string>
+      TOTP passcode to use as the second factor. If not provided, Okta Verify
+      Push is used when the user is required to complete MFA.
+
   username=<string>
       Okta username to use for authentication.
 `
@@ -24,6 +24,11 @@ func pathLogin(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Password for this user.",
 			},
+
+			"totp": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "TOTP passcode of the user, used as the second factor instead of Okta Verify Push.",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -54,8 +59,9 @@ func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Requ
 func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
+	totp := d.Get("totp").(string)
 
-	policies, resp, groupNames, err := b.Login(ctx, req, username, password)
+	policies, resp, groupNames, err := b.Login(ctx, req, username, password, totp, true)
 	// Handle an internal error
 	if err != nil {
 		return nil, err
@@ -80,7 +86,8 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 			"policies": strings.Join(policies, ","),
 		},
 		InternalData: map[string]interface{}{
-			"password": password,
+			"password":  password,
+			"used_totp": totp != "",
 		},
 		DisplayName: username,
 		Alias: &logical.Alias{
@@ -112,12 +119,16 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	username := req.Auth.Metadata["username"]
 	password := req.Auth.InternalData["password"].(string)
 
+	// Passcodes cannot be replayed, so the second factor of logins made with
+	// a passcode is not checked again
+	usedTOTP, _ := req.Auth.InternalData["used_totp"].(bool)
+
 	cfg, err := b.getConfig(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	loginPolicies, resp, groupNames, err := b.Login(ctx, req, username, password)
+	loginPolicies, resp, groupNames, err := b.Login(ctx, req, username, password, "", !usedTOTP)
 	if err != nil || (resp != nil && resp.IsError()) {
 		return resp, err
 	}
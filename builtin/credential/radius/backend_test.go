@@ -3,6 +3,7 @@ package radius
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
 	"strconv"
@@ -13,6 +14,8 @@ import (
 	"github.com/hashicorp/vault/helper/testhelpers/docker"
 	logicaltest "github.com/hashicorp/vault/helper/testhelpers/logical"
 	"github.com/hashicorp/vault/sdk/logical"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
 )
 
 const (
@@ -306,3 +309,96 @@ func testAccUserLoginPolicy(t *testing.T, user string, data map[string]interface
 		},
 	}
 }
+
+func TestBackend_challenge(t *testing.T) {
+	const secret = "testing123"
+
+	// The server challenges valid passwords, and accepts the passcode sent
+	// in response to the challenge
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := radius.PacketServer{
+		SecretSource: radius.StaticSecretSource([]byte(secret)),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			password := rfc2865.UserPassword_GetString(r.Packet)
+			switch {
+			case password == "password" && rfc2865.State_GetString(r.Packet) == "":
+				resp := r.Response(radius.CodeAccessChallenge)
+				rfc2865.State_SetString(resp, "challenge")
+				rfc2865.ReplyMessage_SetString(resp, "Enter your passcode")
+				w.Write(resp)
+			case password == "123456" && rfc2865.State_GetString(r.Packet) == "challenge":
+				w.Write(r.Response(radius.CodeAccessAccept))
+			default:
+				w.Write(r.Response(radius.CodeAccessReject))
+			}
+		}),
+	}
+	go server.Serve(conn)
+	defer server.Shutdown(context.Background())
+
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"host":                       "127.0.0.1",
+			"port":                       port,
+			"secret":                     secret,
+			"unregistered_user_policies": "foo",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	login := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login/alice",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp = login(map[string]interface{}{"password": "password"})
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "Enter your passcode") {
+		t.Fatalf("expected the passcode to be required: %#v", resp)
+	}
+	resp = login(map[string]interface{}{"password": "password", "otp": "000000"})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an invalid passcode to be denied: %#v", resp)
+	}
+	resp = login(map[string]interface{}{"password": "password", "otp": "123456"})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed: %#v", resp)
+	}
+
+	// The passcode is not needed to renew the token
+	resp.Auth.TokenPolicies = resp.Auth.Policies
+	renewResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RenewOperation,
+		Path:      "login",
+		Storage:   storage,
+		Auth:      resp.Auth,
+	})
+	if err != nil || renewResp == nil || renewResp.IsError() || renewResp.Auth == nil {
+		t.Fatalf("err:%v resp:%#v", err, renewResp)
+	}
+}
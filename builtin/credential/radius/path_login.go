@@ -35,6 +35,11 @@ func pathLogin(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Password for this user.",
 			},
+
+			"otp": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "One time passcode sent in response to an Access-Challenge of the RADIUS server, for servers requesting a second factor after the password.",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -84,6 +89,7 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
+	otp := d.Get("otp").(string)
 
 	if username == "" {
 		username = d.Get("urlusername").(string)
@@ -96,7 +102,7 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return logical.ErrorResponse("password cannot be empty"), nil
 	}
 
-	policies, resp, err := b.RadiusLogin(ctx, req, username, password)
+	policies, resp, err := b.RadiusLogin(ctx, req, username, password, otp, true)
 	// Handle an internal error
 	if err != nil {
 		return nil, err
@@ -115,6 +121,7 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		},
 		InternalData: map[string]interface{}{
 			"password": password,
+			"used_otp": otp != "",
 		},
 		DisplayName: username,
 		Alias: &logical.Alias{
@@ -143,10 +150,14 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	username := req.Auth.Metadata["username"]
 	password := req.Auth.InternalData["password"].(string)
 
+	// Passcodes cannot be replayed, so the challenge following the password
+	// of logins made with a passcode is accepted
+	usedOTP, _ := req.Auth.InternalData["used_otp"].(bool)
+
 	var resp *logical.Response
 	var loginPolicies []string
 
-	loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password)
+	loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password, "", !usedOTP)
 	if err != nil || (resp != nil && resp.IsError()) {
 		return resp, err
 	}
@@ -165,7 +176,11 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	return &logical.Response{Auth: req.Auth}, nil
 }
 
-func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+// RadiusLogin authenticates the user with the RADIUS server. When the server
+// answers the password with an Access-Challenge, otp is sent in response. The
+// challenge is accepted without a response when verifyChallenge is false,
+// which is used to renew the tokens of logins made with a one time passcode.
+func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username, password, otp string, verifyChallenge bool) ([]string, *logical.Response, error) {
 	cfg, err := b.Config(ctx, req)
 	if err != nil {
 		return nil, nil, err
@@ -176,25 +191,33 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 
 	hostport := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
 
-	packet := radius.New(radius.CodeAccessRequest, []byte(cfg.Secret))
-	UserName_SetString(packet, username)
-	UserPassword_SetString(packet, password)
-	if cfg.NasIdentifier != "" {
-		NASIdentifier_AddString(packet, cfg.NasIdentifier)
-	}
-	packet.Add(5, radius.NewInteger(uint32(cfg.NasPort)))
-
-	client := radius.Client{
-		Dialer: net.Dialer{
-			Timeout: time.Duration(cfg.DialTimeout) * time.Second,
-		},
-	}
-	clientCtx, cancelFunc := context.WithTimeout(ctx, time.Duration(cfg.ReadTimeout)*time.Second)
-	received, err := client.Exchange(clientCtx, packet, hostport)
-	cancelFunc()
+	packet := newAccessRequest(cfg, username, password)
+	received, err := exchange(ctx, cfg, packet, hostport)
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
+
+	if received.Code == radius.CodeAccessChallenge {
+		switch {
+		case otp != "":
+			packet = newAccessRequest(cfg, username, otp)
+			if state := State_Get(received); state != nil {
+				State_Set(packet, state)
+			}
+			received, err = exchange(ctx, cfg, packet, hostport)
+			if err != nil {
+				return nil, logical.ErrorResponse(err.Error()), nil
+			}
+		case !verifyChallenge:
+			received.Code = radius.CodeAccessAccept
+		default:
+			msg := "the authentication server requires a one time passcode, set in the otp parameter"
+			if reply := ReplyMessage_GetString(received); reply != "" {
+				msg = fmt.Sprintf("%s: %s", msg, reply)
+			}
+			return nil, logical.ErrorResponse(msg), nil
+		}
+	}
 	if received.Code != radius.CodeAccessAccept {
 		return nil, logical.ErrorResponse("access denied by the authentication server"), nil
 	}
@@ -213,6 +236,42 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 	return policies, &logical.Response{}, nil
 }
 
+func newAccessRequest(cfg *ConfigEntry, username, password string) *radius.Packet {
+	packet := radius.New(radius.CodeAccessRequest, []byte(cfg.Secret))
+	UserName_SetString(packet, username)
+	setUserPassword(packet, password)
+	if cfg.NasIdentifier != "" {
+		NASIdentifier_AddString(packet, cfg.NasIdentifier)
+	}
+	packet.Add(5, radius.NewInteger(uint32(cfg.NasPort)))
+	return packet
+}
+
+// setUserPassword sets the User-Password attribute. The password is null
+// padded to a multiple of 16 bytes as in RFC 2865, since the encoding reads
+// the padding from the capacity of the given slice.
+func setUserPassword(packet *radius.Packet, password string) error {
+	padded := make([]byte, len(password), (len(password)/16+1)*16)
+	copy(padded, password)
+	attr, err := radius.NewUserPassword(padded, packet.Secret, packet.Authenticator[:])
+	if err != nil {
+		return err
+	}
+	packet.Set(UserPassword_Type, attr)
+	return nil
+}
+
+func exchange(ctx context.Context, cfg *ConfigEntry, packet *radius.Packet, hostport string) (*radius.Packet, error) {
+	client := radius.Client{
+		Dialer: net.Dialer{
+			Timeout: time.Duration(cfg.DialTimeout) * time.Second,
+		},
+	}
+	clientCtx, cancelFunc := context.WithTimeout(ctx, time.Duration(cfg.ReadTimeout)*time.Second)
+	defer cancelFunc()
+	return client.Exchange(clientCtx, packet, hostport)
+}
+
 const pathLoginSyn = `
 Log in with a username and password.
 `
@@ -220,4 +279,7 @@ Log in with a username and password.
 const pathLoginDesc = `
 This endpoint authenticates using a username and password. Please be sure to
 read the note on escaping from the path-help for the 'config' endpoint.
+
+If the RADIUS server answers the password with an Access-Challenge, the "otp"
+parameter is sent as the response to the challenge.
 `
@@ -88,11 +88,11 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 	passwordBytes := []byte(password)
 	if !legacyPassword {
 		if err := bcrypt.CompareHashAndPassword(userPassword, passwordBytes); err != nil {
-			return logical.ErrorResponse("invalid username or password"), nil
+			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 	} else {
 		if subtle.ConstantTimeCompare(userPassword, passwordBytes) != 1 {
-			return logical.ErrorResponse("invalid username or password"), nil
+			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 	}
 
@@ -100,7 +100,7 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return nil, userError
 	}
 	if user == nil {
-		return logical.ErrorResponse("invalid username or password"), nil
+		return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 	}
 
 	// Check for a CIDR match.
@@ -262,6 +262,69 @@ func TestRoleCRUDWithPermissionsBoundary(t *testing.T) {
 	}
 }
 
+func TestRoleCRUDWithSessionTags(t *testing.T) {
+	roleName := "test_session_tags"
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	roleData := map[string]interface{}{
+		"credential_type": assumedRoleCred,
+		"role_arns":       []string{"arn:aws:iam::123456789012:role/SomeRole"},
+		"session_tags":    []string{"team=engineering", "cost_center=1234"},
+		"external_id":     "external-id",
+	}
+	request := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/" + roleName,
+		Storage:   config.StorageView,
+		Data:      roleData,
+	}
+	resp, err := b.HandleRequest(context.Background(), request)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: role creation failed. resp:%#v\nerr:%v", resp, err)
+	}
+
+	request = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/" + roleName,
+		Storage:   config.StorageView,
+	}
+	resp, err = b.HandleRequest(context.Background(), request)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: reading role failed. resp:%#v\nerr:%v", resp, err)
+	}
+	expectedTags := map[string]string{"team": "engineering", "cost_center": "1234"}
+	if !reflect.DeepEqual(resp.Data["session_tags"], expectedTags) {
+		t.Errorf("bad: expected session_tags of %#v, got %#v instead", expectedTags, resp.Data["session_tags"])
+	}
+	if resp.Data["external_id"] != "external-id" {
+		t.Errorf("bad: expected external_id of external-id, got %s instead", resp.Data["external_id"])
+	}
+
+	// Session tags can't be set on IAM users
+	roleData = map[string]interface{}{
+		"credential_type": iamUserCred,
+		"policy_arns":     []string{adminAccessPolicyARN},
+		"session_tags":    map[string]interface{}{"team": "engineering"},
+	}
+	request = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/" + roleName + "_iam_user",
+		Storage:   config.StorageView,
+		Data:      roleData,
+	}
+	resp, err = b.HandleRequest(context.Background(), request)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("bad: expected role creation to fail. resp:%#v\nerr:%v", resp, err)
+	}
+}
+
 func TestRoleWithPermissionsBoundaryValidation(t *testing.T) {
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -392,6 +455,8 @@ func TestRoleEntryValidationAssumedRoleCred(t *testing.T) {
 		PolicyDocument:  allowAllPolicyDocument,
 		DefaultSTSTTL:   2,
 		MaxSTSTTL:       3,
+		SessionTags:     map[string]string{"team": "engineering"},
+		ExternalID:      "external-id",
 	}
 	if err := roleEntry.validate(); err != nil {
 		t.Errorf("bad: valid roleEntry %#v failed validation: %v", roleEntry, err)
@@ -421,11 +486,17 @@ func TestRoleEntryValidationFederationTokenCred(t *testing.T) {
 		PolicyArns:      []string{adminAccessPolicyARN},
 		DefaultSTSTTL:   2,
 		MaxSTSTTL:       3,
+		SessionTags:     map[string]string{"team": "engineering"},
 	}
 	if err := roleEntry.validate(); err != nil {
 		t.Errorf("bad: valid roleEntry %#v failed validation: %v", roleEntry, err)
 	}
 
+	roleEntry.ExternalID = "external-id"
+	if roleEntry.validate() == nil {
+		t.Errorf("bad: invalid roleEntry with unrecognized ExternalID %#v passed validation", roleEntry)
+	}
+	roleEntry.ExternalID = ""
 	roleEntry.RoleArns = []string{"arn:aws:iam::123456789012:role/SomeRole"}
 	if roleEntry.validate() == nil {
 		t.Errorf("bad: invalid roleEntry with unrecognized RoleArns %#v passed validation", roleEntry)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -67,7 +68,7 @@ func genUsername(displayName, policyName, userType string) (ret string, warning
 
 func (b *backend) getFederationToken(ctx context.Context, s logical.Storage,
 	displayName, policyName, policy string, policyARNs []string,
-	iamGroups []string, lifeTimeInSeconds int64) (*logical.Response, error) {
+	iamGroups []string, sessionTags map[string]string, lifeTimeInSeconds int64) (*logical.Response, error) {
 
 	groupPolicies, groupPolicyARNs, err := b.getGroupPolicies(ctx, s, iamGroups)
 	if err != nil {
@@ -101,6 +102,9 @@ func (b *backend) getFederationToken(ctx context.Context, s logical.Storage,
 	if len(policyARNs) > 0 {
 		getTokenInput.PolicyArns = convertPolicyARNs(policyARNs)
 	}
+	if len(sessionTags) > 0 {
+		getTokenInput.Tags = convertSessionTags(sessionTags)
+	}
 
 	// If neither a policy document nor policy ARNs are specified, then GetFederationToken will
 	// return credentials equivalent to that of the Vault server itself. We probably don't want
@@ -141,7 +145,8 @@ func (b *backend) getFederationToken(ctx context.Context, s logical.Storage,
 
 func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	displayName, roleName, roleArn, policy string, policyARNs []string,
-	iamGroups []string, lifeTimeInSeconds int64) (*logical.Response, error) {
+	iamGroups []string, sessionTags map[string]string, externalID string,
+	lifeTimeInSeconds int64) (*logical.Response, error) {
 
 	// grab any IAM group policies associated with the vault role, both inline
 	// and managed
@@ -178,6 +183,12 @@ func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	if len(policyARNs) > 0 {
 		assumeRoleInput.SetPolicyArns(convertPolicyARNs(policyARNs))
 	}
+	if len(sessionTags) > 0 {
+		assumeRoleInput.SetTags(convertSessionTags(sessionTags))
+	}
+	if externalID != "" {
+		assumeRoleInput.SetExternalId(externalID)
+	}
 	tokenResp, err := stsClient.AssumeRole(assumeRoleInput)
 
 	if err != nil {
@@ -406,3 +417,22 @@ func convertPolicyARNs(policyARNs []string) []*sts.PolicyDescriptorType {
 	}
 	return retval
 }
+
+// convertSessionTags returns the session tags sorted by key, so that requests
+// are deterministic
+func convertSessionTags(sessionTags map[string]string) []*sts.Tag {
+	keys := make([]string, 0, len(sessionTags))
+	for key := range sessionTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	retval := make([]*sts.Tag, 0, len(keys))
+	for _, key := range keys {
+		retval = append(retval, &sts.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(sessionTags[key]),
+		})
+	}
+	return retval
+}
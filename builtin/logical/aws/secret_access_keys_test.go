@@ -1,7 +1,11 @@
 package aws
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 func TestNormalizeDisplayName_NormRequired(t *testing.T) {
@@ -42,3 +46,17 @@ func TestNormalizeDisplayName_NormNotRequired(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertSessionTags(t *testing.T) {
+	tags := convertSessionTags(map[string]string{
+		"team":        "engineering",
+		"cost_center": "1234",
+	})
+	expected := []*sts.Tag{
+		{Key: aws.String("cost_center"), Value: aws.String("1234")},
+		{Key: aws.String("team"), Value: aws.String("engineering")},
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, tags)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"encoding/json"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -525,6 +528,106 @@ func testBackendBasic(t *testing.T, version string) {
 	})
 }
 
+func TestBackend_RolesNamespacePartition(t *testing.T) {
+	// Consul Enterprise is required for namespaces and partitions, so the
+	// requests are checked against a fake server
+	var created consulapi.ACLToken
+	var createQuery, deleteQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/acl/token":
+			createQuery = r.URL.RawQuery
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Fatal(err)
+			}
+			created.AccessorID = "accessor"
+			created.SecretID = "secret"
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/acl/token/accessor":
+			deleteQuery = r.URL.RawQuery
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	request(logical.UpdateOperation, "config/access", map[string]interface{}{
+		"address": server.Listener.Addr().String(),
+		"token":   "management",
+	})
+	request(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"consul_roles":     "reader,writer",
+		"consul_namespace": "ns1",
+		"partition":        "part1",
+		"local":            true,
+	})
+	resp := request(logical.ReadOperation, "roles/test", nil)
+	if !reflect.DeepEqual(resp.Data["consul_roles"], []string{"reader", "writer"}) ||
+		resp.Data["consul_namespace"] != "ns1" || resp.Data["partition"] != "part1" {
+		t.Fatalf("bad role: %#v", resp.Data)
+	}
+
+	resp = request(logical.ReadOperation, "creds/test", nil)
+	if resp.Data["token"] != "secret" || resp.Data["consul_namespace"] != "ns1" || resp.Data["partition"] != "part1" {
+		t.Fatalf("bad creds: %#v", resp.Data)
+	}
+	if len(created.Roles) != 2 || created.Roles[0].Name != "reader" || created.Roles[1].Name != "writer" ||
+		created.Namespace != "ns1" || !created.Local {
+		t.Fatalf("bad token: %#v", created)
+	}
+	if createQuery != "partition=part1" {
+		t.Fatalf("bad query: %q", createQuery)
+	}
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   config.StorageView,
+		Secret:    resp.Secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleteQuery != "ns=ns1&partition=part1" {
+		t.Fatalf("bad query: %q", deleteQuery)
+	}
+
+	// Namespaces and partitions only apply to tokens created from policies
+	// and roles
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/legacy",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"policy":    base64.StdEncoding.EncodeToString([]byte(testPolicy)),
+			"partition": "part1",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+}
+
 func TestBackend_crud(t *testing.T) {
 	b, _ := Factory(context.Background(), logical.TestBackendConfig())
 	logicaltest.Test(t, logicaltest.TestCase{
@@ -3,12 +3,15 @@ package consul
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
-func (b *backend) client(ctx context.Context, s logical.Storage) (*api.Client, error, error) {
+// client returns a Consul client for the configuration. If partition is set,
+// the requests are scoped to that admin partition.
+func (b *backend) client(ctx context.Context, s logical.Storage, partition string) (*api.Client, error, error) {
 	conf, userErr, intErr := b.readConfigAccess(ctx, s)
 	if intErr != nil {
 		return nil, nil, intErr
@@ -28,6 +31,35 @@ func (b *backend) client(ctx context.Context, s logical.Storage) (*api.Client, e
 	consulConf.TLSConfig.CertPEM = []byte(conf.ClientCert)
 	consulConf.TLSConfig.KeyPEM = []byte(conf.ClientKey)
 
+	if partition != "" {
+		httpClient, err := api.NewHttpClient(consulConf.Transport, consulConf.TLSConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		httpClient.Transport = &partitionTransport{
+			partition: partition,
+			next:      httpClient.Transport,
+		}
+		consulConf.HttpClient = httpClient
+	}
+
 	client, err := api.NewClient(consulConf)
 	return client, nil, err
 }
+
+// partitionTransport sets the admin partition of the requests, which the
+// version of the Consul API client in use has no option for
+type partitionTransport struct {
+	partition string
+	next      http.RoundTripper
+}
+
+func (t *partitionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they're given
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("partition", t.partition)
+	req.URL.RawQuery = query.Encode()
+
+	return t.next.RoundTrip(req)
+}
@@ -41,6 +41,24 @@ for 'client' tokens. Required for Consul pre-1.4.`,
 for Consul 1.4 or above.`,
 			},
 
+			"consul_roles": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of Consul roles to attach to the token.
+Available in Consul 1.5 and above.`,
+			},
+
+			"consul_namespace": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Consul namespace in which the token is created.
+Available in Consul Enterprise 1.7 and above.`,
+			},
+
+			"partition": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Consul admin partition in which the token is
+created. Available in Consul Enterprise 1.11 and above.`,
+			},
+
 			"local": &framework.FieldSchema{
 				Type: framework.TypeBool,
 				Description: `Indicates that the token should not be replicated globally 
@@ -126,6 +144,15 @@ func (b *backend) pathRolesRead(ctx context.Context, req *logical.Request, d *fr
 	if len(result.Policies) > 0 {
 		resp.Data["policies"] = result.Policies
 	}
+	if len(result.ConsulRoles) > 0 {
+		resp.Data["consul_roles"] = result.ConsulRoles
+	}
+	if result.ConsulNamespace != "" {
+		resp.Data["consul_namespace"] = result.ConsulNamespace
+	}
+	if result.Partition != "" {
+		resp.Data["partition"] = result.Partition
+	}
 	return resp, nil
 }
 
@@ -134,14 +161,17 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 	policy := d.Get("policy").(string)
 	name := d.Get("name").(string)
 	policies := d.Get("policies").([]string)
+	consulRoles := d.Get("consul_roles").([]string)
+	consulNamespace := d.Get("consul_namespace").(string)
+	partition := d.Get("partition").(string)
 	local := d.Get("local").(bool)
 
-	if len(policies) == 0 {
+	if len(policies) == 0 && len(consulRoles) == 0 {
 		switch tokenType {
 		case "client":
 			if policy == "" {
 				return logical.ErrorResponse(
-					"Use either a policy document, or a list of policies, depending on your Consul version"), nil
+					"Use either a policy document, or a list of policies or roles, depending on your Consul version"), nil
 			}
 		case "management":
 		default:
@@ -149,6 +179,10 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 				"token_type must be \"client\" or \"management\""), nil
 		}
 	}
+	if policy != "" && (len(consulRoles) > 0 || consulNamespace != "" || partition != "") {
+		return logical.ErrorResponse(
+			"consul_roles, consul_namespace and partition can't be used with a policy document"), nil
+	}
 
 	policyRaw, err := base64.StdEncoding.DecodeString(policy)
 	if err != nil {
@@ -174,12 +208,15 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 	}
 
 	entry, err := logical.StorageEntryJSON("policy/"+name, roleConfig{
-		Policy:    string(policyRaw),
-		Policies:  policies,
-		TokenType: tokenType,
-		TTL:       ttl,
-		MaxTTL:    maxTTL,
-		Local:     local,
+		Policy:          string(policyRaw),
+		Policies:        policies,
+		ConsulRoles:     consulRoles,
+		ConsulNamespace: consulNamespace,
+		Partition:       partition,
+		TokenType:       tokenType,
+		TTL:             ttl,
+		MaxTTL:          maxTTL,
+		Local:           local,
 	})
 	if err != nil {
 		return nil, err
@@ -201,10 +238,13 @@ func (b *backend) pathRolesDelete(ctx context.Context, req *logical.Request, d *
 }
 
 type roleConfig struct {
-	Policy    string        `json:"policy"`
-	Policies  []string      `json:"policies"`
-	TTL       time.Duration `json:"lease"`
-	MaxTTL    time.Duration `json:"max_ttl"`
-	TokenType string        `json:"token_type"`
-	Local     bool          `json:"local"`
+	Policy          string        `json:"policy"`
+	Policies        []string      `json:"policies"`
+	ConsulRoles     []string      `json:"consul_roles"`
+	ConsulNamespace string        `json:"consul_namespace"`
+	Partition       string        `json:"partition"`
+	TTL             time.Duration `json:"lease"`
+	MaxTTL          time.Duration `json:"max_ttl"`
+	TokenType       string        `json:"token_type"`
+	Local           bool          `json:"local"`
 }
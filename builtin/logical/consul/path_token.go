@@ -52,7 +52,7 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 	}
 
 	// Get the consul client
-	c, userErr, intErr := b.client(ctx, req.Storage)
+	c, userErr, intErr := b.client(ctx, req.Storage, result.Partition)
 	if intErr != nil {
 		return nil, intErr
 	}
@@ -97,10 +97,18 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 			Name: policyName,
 		})
 	}
+	var roleLink = []*api.ACLTokenRoleLink{}
+	for _, roleName := range result.ConsulRoles {
+		roleLink = append(roleLink, &api.ACLTokenRoleLink{
+			Name: roleName,
+		})
+	}
 	token, _, err := c.ACL().TokenCreate(&api.ACLToken{
 		Description: tokenName,
 		Policies:    policyLink,
+		Roles:       roleLink,
 		Local:       result.Local,
+		Namespace:   result.ConsulNamespace,
 	}, writeOpts)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
@@ -108,13 +116,17 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 
 	// Use the helper to create the secret
 	s := b.Secret(SecretTokenType).Response(map[string]interface{}{
-		"token":    token.SecretID,
-		"accessor": token.AccessorID,
-		"local":    token.Local,
+		"token":            token.SecretID,
+		"accessor":         token.AccessorID,
+		"local":            token.Local,
+		"consul_namespace": token.Namespace,
+		"partition":        result.Partition,
 	}, map[string]interface{}{
-		"token":   token.AccessorID,
-		"role":    role,
-		"version": tokenPolicyType,
+		"token":            token.AccessorID,
+		"role":             role,
+		"version":          tokenPolicyType,
+		"consul_namespace": token.Namespace,
+		"partition":        result.Partition,
 	})
 	s.Secret.TTL = result.TTL
 	s.Secret.MaxTTL = result.MaxTTL
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -58,7 +59,12 @@ func (b *backend) secretTokenRenew(ctx context.Context, req *logical.Request, d
 }
 
 func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	c, userErr, intErr := b.client(ctx, req.Storage)
+	// Tokens are deleted from the namespace and partition they were
+	// created in
+	namespace, _ := req.Secret.InternalData["consul_namespace"].(string)
+	partition, _ := req.Secret.InternalData["partition"].(string)
+
+	c, userErr, intErr := b.client(ctx, req.Storage, partition)
 	if intErr != nil {
 		return nil, intErr
 	}
@@ -90,7 +96,9 @@ func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d
 			return nil, err
 		}
 	case tokenPolicyType:
-		_, err := c.ACL().TokenDelete(tokenRaw.(string), nil)
+		_, err := c.ACL().TokenDelete(tokenRaw.(string), &api.WriteOptions{
+			Namespace: namespace,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,116 @@
+package kmip
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Factory returns a KMIP backend that satisfies the logical.Backend interface
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend returns the configured KMIP backend
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				caPath,
+				"objects/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathCA(&b),
+			pathListScopes(&b),
+			pathScope(&b),
+			pathListRoles(&b),
+			pathRole(&b),
+			pathListCredentials(&b),
+			pathCredentialGenerate(&b),
+			pathCredentialSign(&b),
+			pathCredentialLookup(&b),
+			pathCredentialRevoke(&b),
+		},
+
+		InitializeFunc: b.initialize,
+		Clean:          b.cleanup,
+		BackendType:    logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// lock protects server, and serializes the changes to the configuration
+	// and the CA
+	lock   sync.Mutex
+	server *server
+}
+
+// initialize starts the KMIP server if the engine has been configured
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	if !b.serves() {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	config, err := readConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	return b.restartServer(ctx, req.Storage, config)
+}
+
+// serves returns whether this node should run the KMIP server. Only the
+// active node of the cluster writes to storage, so the server is not run on
+// performance standbys, nor on performance secondaries unless the mount is
+// local.
+func (b *backend) serves() bool {
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby | consts.ReplicationDRSecondary) {
+		return false
+	}
+	return b.System().LocalMount() || !b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary)
+}
+
+func (b *backend) cleanup(ctx context.Context) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.server != nil {
+		b.server.stop()
+		b.server = nil
+	}
+}
+
+const backendHelp = `
+The KMIP secrets engine makes Vault act as a KMIP server, so that databases,
+storage appliances and other KMIP clients can create and fetch their keys,
+for example the keys used for transparent data encryption, from Vault.
+
+After mounting this secrets engine, configure the listener with the "config"
+path. Managed objects are partitioned by scopes, created with the "scope/"
+path, and the KMIP operations clients can perform are granted by the roles
+of a scope. Clients authenticate with the TLS certificates generated for a
+role.
+`
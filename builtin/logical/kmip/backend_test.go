@@ -0,0 +1,545 @@
+package kmip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func getBackend(t *testing.T) (*backend, *logical.BackendConfig) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(*backend), config
+}
+
+type requestFunc func(op logical.Operation, path string, data map[string]interface{}) *logical.Response
+
+func requestHelpers(t *testing.T, b *backend, config *logical.BackendConfig) (requestFunc, func(logical.Operation, string, map[string]interface{})) {
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error: %s: %#v", path, resp)
+		}
+	}
+	return request, expectError
+}
+
+func TestBackend_Paths(t *testing.T) {
+	b, config := getBackend(t)
+	defer b.Clean(context.Background())
+	request, expectError := requestHelpers(t, b, config)
+
+	resp := request(logical.ReadOperation, "config", nil)
+	if resp.Data["tls_ca_key_type"] != "ec" || resp.Data["tls_ca_key_bits"] != 521 || resp.Data["default_tls_client_ttl"] != int64(86400) {
+		t.Fatalf("bad default config: %#v", resp.Data)
+	}
+	expectError(logical.ReadOperation, "ca", nil)
+
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{
+		"tls_ca_key_type": "ec",
+		"tls_ca_key_bits": 2048,
+	})
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{
+		"tls_min_version": "ssl3",
+	})
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"listen_addrs":                "127.0.0.1:0",
+		"tls_ca_key_bits":             256,
+		"default_tls_client_key_bits": 256,
+	})
+	if b.server == nil {
+		t.Fatal("expected the server to be started")
+	}
+	resp = request(logical.ReadOperation, "ca", nil)
+	caPEM := resp.Data["ca_pem"].(string)
+	if strings.Count(caPEM, "BEGIN CERTIFICATE") != 2 {
+		t.Fatalf("bad CA: %s", caPEM)
+	}
+
+	// Changing the client settings keeps the CA and the server
+	server := b.server
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"default_tls_client_ttl": "1h",
+	})
+	if b.server != server {
+		t.Fatal("expected the server not to be restarted")
+	}
+	resp = request(logical.ReadOperation, "ca", nil)
+	if resp.Data["ca_pem"] != caPEM {
+		t.Fatal("expected the CA to be kept")
+	}
+
+	// Roles need a scope
+	expectError(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"operation_all": true,
+	})
+	request(logical.UpdateOperation, "scope/s1", nil)
+	request(logical.UpdateOperation, "scope/s2", nil)
+	resp = request(logical.ListOperation, "scope/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad scopes: %#v", keys)
+	}
+
+	expectError(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"operation_all": true,
+		"operation_get": true,
+	})
+	expectError(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"tls_client_key_type": "rsa",
+		"tls_client_key_bits": 256,
+	})
+	request(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"operation_all": true,
+	})
+	resp = request(logical.ReadOperation, "scope/s1/role/r1", nil)
+	if resp.Data["operation_all"] != true || len(resp.Data) != 1 {
+		t.Fatalf("bad role: %#v", resp.Data)
+	}
+
+	// Individual operations replace the pseudo-operations, and are merged
+	request(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"operation_get":    true,
+		"operation_create": true,
+	})
+	request(logical.UpdateOperation, "scope/s1/role/r1", map[string]interface{}{
+		"operation_create": false,
+		"operation_locate": true,
+	})
+	resp = request(logical.ReadOperation, "scope/s1/role/r1", nil)
+	if resp.Data["operation_get"] != true || resp.Data["operation_locate"] != true || len(resp.Data) != 2 {
+		t.Fatalf("bad role: %#v", resp.Data)
+	}
+	resp = request(logical.ListOperation, "scope/s1/role/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "r1" {
+		t.Fatalf("bad roles: %#v", keys)
+	}
+
+	// Generated credentials
+	expectError(logical.UpdateOperation, "scope/s1/role/missing/credential/generate", nil)
+	resp = request(logical.UpdateOperation, "scope/s1/role/r1/credential/generate", nil)
+	serial := resp.Data["serial_number"].(string)
+	cert, err := parseCertificate(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SerialNumber.String() != serial {
+		t.Fatalf("bad serial number: %s", serial)
+	}
+	if _, err := tls.X509KeyPair([]byte(resp.Data["certificate"].(string)), []byte(resp.Data["private_key"].(string))); err != nil {
+		t.Fatal(err)
+	}
+	if chain := resp.Data["ca_chain"].([]string); strings.Join(chain, "") != caPEM {
+		t.Fatalf("bad CA chain: %#v", chain)
+	}
+
+	resp = request(logical.UpdateOperation, "scope/s1/role/r1/credential/generate", map[string]interface{}{
+		"format": "der",
+	})
+	der, err := base64.StdEncoding.DecodeString(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	derSerial := resp.Data["serial_number"].(string)
+
+	resp = request(logical.ReadOperation, "scope/s1/role/r1/credential/lookup", map[string]interface{}{
+		"serial_number": serial,
+	})
+	if _, ok := resp.Data["private_key"]; ok || resp.Data["serial_number"] != serial {
+		t.Fatalf("bad lookup: %#v", resp.Data)
+	}
+
+	// Signed credentials must use the key of the role
+	csr := func(curve elliptic.Curve) string {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "client"},
+		}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+	}
+	expectError(logical.UpdateOperation, "scope/s1/role/r1/credential/sign", map[string]interface{}{
+		"csr": csr(elliptic.P384()),
+	})
+	resp = request(logical.UpdateOperation, "scope/s1/role/r1/credential/sign", map[string]interface{}{
+		"csr": csr(elliptic.P256()),
+	})
+	if _, ok := resp.Data["private_key"]; ok {
+		t.Fatalf("expected no private key: %#v", resp.Data)
+	}
+	signedCert := resp.Data["certificate"].(string)
+
+	resp = request(logical.ListOperation, "scope/s1/role/r1/credential", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 3 {
+		t.Fatalf("bad credentials: %#v", keys)
+	}
+
+	// Revocation
+	expectError(logical.UpdateOperation, "scope/s2/role/r1/credential/revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	request(logical.UpdateOperation, "scope/s1/role/r1/credential/revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	request(logical.UpdateOperation, "scope/s1/role/r1/credential/revoke", map[string]interface{}{
+		"certificate": signedCert,
+	})
+	resp = request(logical.ListOperation, "scope/s1/role/r1/credential", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != derSerial {
+		t.Fatalf("bad credentials: %#v", keys)
+	}
+
+	// Deleting the scope deletes the roles and their credentials
+	request(logical.DeleteOperation, "scope/s1", nil)
+	resp = request(logical.ListOperation, "scope/s1/role/", nil)
+	if resp != nil && len(resp.Data) != 0 {
+		t.Fatalf("expected no roles: %#v", resp.Data)
+	}
+	if entry, err := readSerial(context.Background(), config.StorageView, derSerial); err != nil || entry != nil {
+		t.Fatalf("expected the serial number to be deleted: %#v, %v", entry, err)
+	}
+
+	// Changing the CA key generates a new CA
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"tls_ca_key_bits": 384,
+	})
+	if b.server == server {
+		t.Fatal("expected the server to be restarted")
+	}
+	resp = request(logical.ReadOperation, "ca", nil)
+	if resp.Data["ca_pem"] == caPEM {
+		t.Fatal("expected a new CA")
+	}
+}
+
+// kmipClient sends KMIP requests over a TLS connection
+type kmipClient struct {
+	t    *testing.T
+	conn *tls.Conn
+}
+
+func newKMIPClient(t *testing.T, addr string, data map[string]interface{}) *kmipClient {
+	cert, err := tls.X509KeyPair([]byte(data["certificate"].(string)), []byte(data["private_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	for _, ca := range data["ca_chain"].([]string) {
+		pool.AppendCertsFromPEM([]byte(ca))
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   "localhost",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kmipClient{t: t, conn: conn}
+}
+
+func batchItem(operation uint32, payload ...*item) *item {
+	return structure(tagBatchItem,
+		enumeration(tagOperation, operation),
+		structure(tagRequestPayload, payload...),
+	)
+}
+
+// send sends the batch items, and returns the batch items of the response
+func (c *kmipClient) send(batchItems ...*item) []*item {
+	c.t.Helper()
+
+	msg := structure(tagRequestMessage,
+		structure(tagRequestHeader,
+			structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, 1),
+				integer(tagProtocolVersionMinor, 4),
+			),
+			integer(tagBatchCount, int32(len(batchItems))),
+		),
+	)
+	msg.add(batchItems...)
+	encoded, err := msg.encode()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.conn.Write(encoded); err != nil {
+		c.t.Fatal(err)
+	}
+
+	response, err := readMessage(c.conn)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if response.Tag != tagResponseMessage {
+		c.t.Fatalf("bad response: %#v", response)
+	}
+	return response.all(tagBatchItem)
+}
+
+// expectSuccess returns the response payloads, failing if any batch item
+// failed
+func (c *kmipClient) expectSuccess(batchItems ...*item) []*item {
+	c.t.Helper()
+	var payloads []*item
+	for _, response := range c.send(batchItems...) {
+		if status, _ := response.child(tagResultStatus).enum(); status != resultStatusSuccess {
+			message, _ := response.child(tagResultMessage).text()
+			c.t.Fatalf("operation failed: %s", message)
+		}
+		payloads = append(payloads, response.child(tagResponsePayload))
+	}
+	return payloads
+}
+
+func (c *kmipClient) expectFailure(reason uint32, batchItem *item) {
+	c.t.Helper()
+	response := c.send(batchItem)[0]
+	if status, _ := response.child(tagResultStatus).enum(); status != resultStatusOperationFailed {
+		c.t.Fatalf("expected the operation to fail: %#v", response)
+	}
+	if actual, _ := response.child(tagResultReason).enum(); actual != reason {
+		message, _ := response.child(tagResultMessage).text()
+		c.t.Fatalf("expected reason %d, got %d: %s", reason, actual, message)
+	}
+}
+
+func templateAttribute(attributes ...*item) *item {
+	return structure(tagTemplateAttribute, attributes...)
+}
+
+func attribute(name string, value *item) *item {
+	value.Tag = tagAttributeValue
+	return structure(tagAttribute, textString(tagAttributeName, name), value)
+}
+
+func nameAttribute(name string) *item {
+	return attribute(attributeName, structure(0,
+		textString(tagNameValue, name),
+		enumeration(tagNameType, nameTypeUninterpretedTextString),
+	))
+}
+
+func TestBackend_KMIP(t *testing.T) {
+	b, config := getBackend(t)
+	defer b.Clean(context.Background())
+	request, _ := requestHelpers(t, b, config)
+
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"listen_addrs":                "127.0.0.1:0",
+		"tls_ca_key_bits":             256,
+		"default_tls_client_key_bits": 256,
+	})
+	addr := b.server.listeners[0].Addr().String()
+
+	request(logical.UpdateOperation, "scope/s1", nil)
+	request(logical.UpdateOperation, "scope/s2", nil)
+	request(logical.UpdateOperation, "scope/s1/role/admin", map[string]interface{}{
+		"operation_all": true,
+	})
+	request(logical.UpdateOperation, "scope/s1/role/reader", map[string]interface{}{
+		"operation_get":    true,
+		"operation_locate": true,
+	})
+	request(logical.UpdateOperation, "scope/s2/role/admin", map[string]interface{}{
+		"operation_all": true,
+	})
+
+	admin := newKMIPClient(t, addr, request(logical.UpdateOperation, "scope/s1/role/admin/credential/generate", nil).Data)
+	defer admin.conn.Close()
+	readerCreds := request(logical.UpdateOperation, "scope/s1/role/reader/credential/generate", nil).Data
+	reader := newKMIPClient(t, addr, readerCreds)
+	defer reader.conn.Close()
+	other := newKMIPClient(t, addr, request(logical.UpdateOperation, "scope/s2/role/admin/credential/generate", nil).Data)
+	defer other.conn.Close()
+
+	payloads := admin.expectSuccess(batchItem(operationDiscoverVersions))
+	if versions := payloads[0].all(tagProtocolVersion); len(versions) != len(protocolVersions) {
+		t.Fatalf("bad versions: %#v", versions)
+	}
+
+	// Create a key, and use the ID placeholder to activate and get it
+	payloads = admin.expectSuccess(
+		batchItem(operationCreate,
+			enumeration(tagObjectType, objectTypeSymmetricKey),
+			templateAttribute(
+				attribute(attributeCryptographicAlgorithm, enumeration(0, cryptographicAlgorithmAES)),
+				attribute(attributeCryptographicLength, integer(0, 256)),
+				attribute(attributeCryptographicUsageMask, integer(0, 0x0C)),
+				nameAttribute("tde-key"),
+			),
+		),
+		batchItem(operationActivate),
+		batchItem(operationGet),
+	)
+	id, _ := payloads[0].child(tagUniqueIdentifier).text()
+	key, _ := payloads[2].child(tagSymmetricKey).child(tagKeyBlock).child(tagKeyValue).child(tagKeyMaterial).bytes()
+	if id == "" || len(key) != 32 {
+		t.Fatalf("bad key %q: %x", id, key)
+	}
+
+	// Register a key
+	registered := []byte("0123456789abcdef")
+	payloads = admin.expectSuccess(batchItem(operationRegister,
+		enumeration(tagObjectType, objectTypeSymmetricKey),
+		templateAttribute(nameAttribute("registered")),
+		structure(tagSymmetricKey,
+			structure(tagKeyBlock,
+				enumeration(tagKeyFormatType, keyFormatTypeRaw),
+				structure(tagKeyValue, byteString(tagKeyMaterial, registered)),
+				enumeration(tagCryptographicAlgorithm, cryptographicAlgorithmAES),
+				integer(tagCryptographicLength, 128),
+			),
+		),
+	))
+	registeredID, _ := payloads[0].child(tagUniqueIdentifier).text()
+
+	admin.expectFailure(resultReasonInvalidField, batchItem(operationCreate,
+		enumeration(tagObjectType, objectTypeSymmetricKey),
+		templateAttribute(
+			attribute(attributeCryptographicAlgorithm, enumeration(0, cryptographicAlgorithmAES)),
+			attribute(attributeCryptographicLength, integer(0, 100)),
+		),
+	))
+
+	// The reader can locate and get the keys of its scope, but not change them
+	payloads = reader.expectSuccess(batchItem(operationLocate, nameAttribute("registered")))
+	if ids := payloads[0].all(tagUniqueIdentifier); len(ids) != 1 || ids[0].Value != registeredID {
+		t.Fatalf("bad located objects: %#v", ids)
+	}
+	payloads = reader.expectSuccess(batchItem(operationLocate,
+		attribute(attributeState, enumeration(0, stateActive)),
+	))
+	if ids := payloads[0].all(tagUniqueIdentifier); len(ids) != 1 || ids[0].Value != id {
+		t.Fatalf("bad located objects: %#v", ids)
+	}
+	payloads = reader.expectSuccess(batchItem(operationGet, textString(tagUniqueIdentifier, registeredID)))
+	material, _ := payloads[0].child(tagSymmetricKey).child(tagKeyBlock).child(tagKeyValue).child(tagKeyMaterial).bytes()
+	if string(material) != string(registered) {
+		t.Fatalf("bad key material: %q", material)
+	}
+	reader.expectFailure(resultReasonPermissionDenied, batchItem(operationDestroy, textString(tagUniqueIdentifier, registeredID)))
+
+	// Other scopes don't see the keys
+	other.expectFailure(resultReasonItemNotFound, batchItem(operationGet, textString(tagUniqueIdentifier, id)))
+	payloads = other.expectSuccess(batchItem(operationLocate))
+	if ids := payloads[0].all(tagUniqueIdentifier); len(ids) != 0 {
+		t.Fatalf("bad located objects: %#v", ids)
+	}
+
+	// Attributes
+	admin.expectSuccess(batchItem(operationAddAttribute,
+		textString(tagUniqueIdentifier, id),
+		attribute("x-purpose", textString(0, "tde")),
+	))
+	admin.expectFailure(resultReasonInvalidField, batchItem(operationAddAttribute,
+		textString(tagUniqueIdentifier, id),
+		attribute(attributeCryptographicLength, integer(0, 128)),
+	))
+	payloads = admin.expectSuccess(batchItem(operationGetAttributeList, textString(tagUniqueIdentifier, id)))
+	var names []string
+	for _, name := range payloads[0].all(tagAttributeName) {
+		names = append(names, name.Value.(string))
+	}
+	if joined := strings.Join(names, ","); !strings.Contains(joined, attributeActivationDate) || !strings.Contains(joined, "x-purpose") {
+		t.Fatalf("bad attribute list: %s", joined)
+	}
+	payloads = admin.expectSuccess(batchItem(operationGetAttributes,
+		textString(tagUniqueIdentifier, id),
+		textString(tagAttributeName, attributeState),
+		textString(tagAttributeName, attributeName),
+	))
+	attributes := payloads[0].all(tagAttribute)
+	if len(attributes) != 2 {
+		t.Fatalf("bad attributes: %#v", attributes)
+	}
+	if state, _ := attributes[0].child(tagAttributeValue).enum(); state != stateActive {
+		t.Fatalf("bad state: %d", state)
+	}
+
+	// Rekey, revoke and destroy
+	payloads = admin.expectSuccess(batchItem(operationReKey, textString(tagUniqueIdentifier, id)))
+	if newID, _ := payloads[0].child(tagUniqueIdentifier).text(); newID == "" || newID == id {
+		t.Fatalf("bad rekeyed object: %q", newID)
+	}
+	admin.expectFailure(resultReasonIllegalOperation, batchItem(operationDestroy, textString(tagUniqueIdentifier, id)))
+	admin.expectSuccess(
+		batchItem(operationRevoke,
+			textString(tagUniqueIdentifier, id),
+			structure(tagRevocationReason, enumeration(tagRevocationReasonCode, revocationReasonKeyCompromise)),
+		),
+		batchItem(operationDestroy, textString(tagUniqueIdentifier, id)),
+	)
+	admin.expectFailure(resultReasonItemNotFound, batchItem(operationGet, textString(tagUniqueIdentifier, id)))
+
+	// Revoking the credential applies to the open connection, and new
+	// connections are rejected
+	request(logical.UpdateOperation, "scope/s1/role/reader/credential/revoke", map[string]interface{}{
+		"serial_number": readerCreds["serial_number"],
+	})
+	reader.expectFailure(resultReasonPermissionDenied, batchItem(operationGet, textString(tagUniqueIdentifier, registeredID)))
+	rejected := newKMIPClient(t, addr, readerCreds)
+	defer rejected.conn.Close()
+	if _, err := readMessage(rejected.conn); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+
+	// Scopes with objects are only deleted with force
+	_, expectError := requestHelpers(t, b, config)
+	expectError(logical.DeleteOperation, "scope/s1", nil)
+	request(logical.DeleteOperation, "scope/s1", map[string]interface{}{
+		"force": true,
+	})
+	admin.expectFailure(resultReasonPermissionDenied, batchItem(operationLocate))
+
+	// Stopping the server closes the connections
+	b.Clean(context.Background())
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Fatal("expected the listener to be closed")
+	}
+	if _, err := readMessage(other.conn); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+}
@@ -0,0 +1,283 @@
+package kmip
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	caPath = "ca"
+
+	caCommonName             = "vault-kmip-default"
+	intermediateCommonName   = caCommonName + "-intermediate"
+	caValidity               = 10 * 365 * 24 * time.Hour
+	certificateBackdate      = 30 * time.Second
+	serverCertificateBackoff = 24 * time.Hour
+)
+
+// caBundle is the CA issuing the certificates of the server and the clients.
+// The root only signs the intermediate, which signs everything else.
+type caBundle struct {
+	KeyType                 string `json:"key_type"`
+	KeyBits                 int    `json:"key_bits"`
+	RootCertificate         string `json:"root_certificate"`
+	RootKey                 string `json:"root_key"`
+	IntermediateCertificate string `json:"intermediate_certificate"`
+	IntermediateKey         string `json:"intermediate_key"`
+}
+
+// parsedCA is a caBundle ready to issue certificates
+type parsedCA struct {
+	root            *x509.Certificate
+	intermediate    *x509.Certificate
+	intermediateKey crypto.Signer
+}
+
+func readCA(ctx context.Context, s logical.Storage) (*caBundle, error) {
+	entry, err := s.Get(ctx, caPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	ca := &caBundle{}
+	if err := entry.DecodeJSON(ca); err != nil {
+		return nil, errwrap.Wrapf("error reading kmip CA: {{err}}", err)
+	}
+	return ca, nil
+}
+
+func writeCA(ctx context.Context, s logical.Storage, ca *caBundle) error {
+	entry, err := logical.StorageEntryJSON(caPath, ca)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// generateCA creates a new root and intermediate CA with keys of the given
+// type and length
+func generateCA(keyType string, keyBits int) (*caBundle, error) {
+	rootKey, rootKeyPEM, err := generateKey(keyType, keyBits)
+	if err != nil {
+		return nil, err
+	}
+	intermediateKey, intermediateKeyPEM, err := generateKey(keyType, keyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	root, err := createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             now.Add(-certificateBackdate),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            1,
+	}, nil, rootKey.Public(), rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediate, err := createCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: intermediateCommonName},
+		NotBefore:             now.Add(-certificateBackdate),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}, root, intermediateKey.Public(), rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &caBundle{
+		KeyType:                 keyType,
+		KeyBits:                 keyBits,
+		RootCertificate:         encodeCertificate(root),
+		RootKey:                 rootKeyPEM,
+		IntermediateCertificate: encodeCertificate(intermediate),
+		IntermediateKey:         intermediateKeyPEM,
+	}, nil
+}
+
+func (ca *caBundle) parse() (*parsedCA, error) {
+	root, err := parseCertificate(ca.RootCertificate)
+	if err != nil {
+		return nil, err
+	}
+	intermediate, err := parseCertificate(ca.IntermediateCertificate)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := certutil.ParsePEMBundle(ca.IntermediateKey)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.PrivateKey == nil {
+		return nil, fmt.Errorf("no private key found for the kmip intermediate CA")
+	}
+
+	return &parsedCA{
+		root:            root,
+		intermediate:    intermediate,
+		intermediateKey: bundle.PrivateKey,
+	}, nil
+}
+
+// chain returns the PEM encoded CA certificates, intermediate first
+func (ca *caBundle) chain() []string {
+	return []string{ca.IntermediateCertificate, ca.RootCertificate}
+}
+
+// pool returns a pool with the root and intermediate certificates
+func (p *parsedCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.root)
+	pool.AddCert(p.intermediate)
+	return pool
+}
+
+// issueServerCertificate issues the certificate presented by the KMIP server
+func (p *parsedCA) issueServerCertificate(config *kmipConfig) (*x509.Certificate, crypto.Signer, error) {
+	key, _, err := generateKey(config.TLSCAKeyType, config.TLSCAKeyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	for _, ip := range config.ServerIPs {
+		ips = append(ips, net.ParseIP(ip))
+	}
+
+	var commonName string
+	if len(config.ServerHostnames) > 0 {
+		commonName = config.ServerHostnames[0]
+	}
+
+	now := time.Now()
+	cert, err := createCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		DNSNames:    config.ServerHostnames,
+		IPAddresses: ips,
+		NotBefore:   now.Add(-certificateBackdate),
+		NotAfter:    p.intermediate.NotAfter.Add(-serverCertificateBackoff),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, p.intermediate, key.Public(), p.intermediateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// issueClientCertificate issues a client certificate for the public key
+func (p *parsedCA) issueClientCertificate(pub crypto.PublicKey, ttl time.Duration) (*x509.Certificate, error) {
+	now := time.Now()
+	notAfter := now.Add(ttl)
+	if notAfter.After(p.intermediate.NotAfter) {
+		notAfter = p.intermediate.NotAfter
+	}
+
+	return createCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: intermediateCommonName + "-client"},
+		NotBefore:   now.Add(-certificateBackdate),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, p.intermediate, pub, p.intermediateKey)
+}
+
+// createCertificate signs the template with a new serial number. A nil
+// parent creates a self-signed certificate.
+func createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	serial, err := certutil.GenerateSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+
+	subjKeyID, err := subjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	template.SubjectKeyId = subjKeyID
+
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, errwrap.Wrapf("unable to create certificate: {{err}}", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+func subjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	id := sha1.Sum(der)
+	return id[:], nil
+}
+
+// generateKey returns a new private key, and its PEM encoding
+func generateKey(keyType string, keyBits int) (crypto.Signer, string, error) {
+	bundle := &certutil.ParsedCertBundle{}
+	if err := certutil.GeneratePrivateKey(keyType, keyBits, bundle); err != nil {
+		return nil, "", err
+	}
+
+	blockType := certutil.PKCS1Block
+	if bundle.PrivateKeyType == certutil.ECPrivateKey {
+		blockType = certutil.ECBlock
+	}
+	block := &pem.Block{
+		Type:  string(blockType),
+		Bytes: bundle.PrivateKeyBytes,
+	}
+	return bundle.PrivateKey, string(pem.EncodeToMemory(block)), nil
+}
+
+// validateKeyTypeLength is certutil.ValidateKeyTypeLength, without the "any"
+// key type that makes no sense for generated keys
+func validateKeyTypeLength(keyType string, keyBits int) error {
+	if keyType != "rsa" && keyType != "ec" {
+		return fmt.Errorf("unknown key type %s", keyType)
+	}
+	return certutil.ValidateKeyTypeLength(keyType, keyBits)
+}
+
+func encodeCertificate(cert *x509.Certificate) string {
+	block := &pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Raw,
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+func parseCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("unable to decode the PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,169 @@
+package kmip
+
+import "fmt"
+
+// tag identifies a TTLV item, see section 9.1.3.1 of the KMIP specification
+type tag uint32
+
+const (
+	tagActivationDate         tag = 0x420001
+	tagAttribute              tag = 0x420008
+	tagAttributeIndex         tag = 0x420009
+	tagAttributeName          tag = 0x42000A
+	tagAttributeValue         tag = 0x42000B
+	tagBatchCount             tag = 0x42000D
+	tagBatchItem              tag = 0x42000F
+	tagCryptographicAlgorithm tag = 0x420028
+	tagCryptographicLength    tag = 0x42002A
+	tagCryptographicUsageMask tag = 0x42002C
+	tagKeyBlock               tag = 0x420040
+	tagKeyFormatType          tag = 0x420042
+	tagKeyMaterial            tag = 0x420043
+	tagKeyValue               tag = 0x420045
+	tagMaximumItems           tag = 0x42004F
+	tagName                   tag = 0x420053
+	tagNameType               tag = 0x420054
+	tagNameValue              tag = 0x420055
+	tagObjectType             tag = 0x420057
+	tagOperation              tag = 0x42005C
+	tagProtocolVersion        tag = 0x420069
+	tagProtocolVersionMajor   tag = 0x42006A
+	tagProtocolVersionMinor   tag = 0x42006B
+	tagRequestHeader          tag = 0x420077
+	tagRequestMessage         tag = 0x420078
+	tagRequestPayload         tag = 0x420079
+	tagResponseHeader         tag = 0x42007A
+	tagResponseMessage        tag = 0x42007B
+	tagResponsePayload        tag = 0x42007C
+	tagResultMessage          tag = 0x42007D
+	tagResultReason           tag = 0x42007E
+	tagResultStatus           tag = 0x42007F
+	tagRevocationReason       tag = 0x420081
+	tagRevocationReasonCode   tag = 0x420082
+	tagState                  tag = 0x42008D
+	tagSymmetricKey           tag = 0x42008F
+	tagTemplateAttribute      tag = 0x420091
+	tagTimeStamp              tag = 0x420092
+	tagUniqueBatchItemID      tag = 0x420093
+	tagUniqueIdentifier       tag = 0x420094
+)
+
+func (t tag) String() string {
+	return fmt.Sprintf("0x%06X", uint32(t))
+}
+
+// Operations supported by the server
+const (
+	operationCreate           uint32 = 0x01
+	operationRegister         uint32 = 0x03
+	operationReKey            uint32 = 0x04
+	operationLocate           uint32 = 0x08
+	operationGet              uint32 = 0x0A
+	operationGetAttributes    uint32 = 0x0B
+	operationGetAttributeList uint32 = 0x0C
+	operationAddAttribute     uint32 = 0x0D
+	operationActivate         uint32 = 0x12
+	operationRevoke           uint32 = 0x13
+	operationDestroy          uint32 = 0x14
+	operationDiscoverVersions uint32 = 0x1E
+)
+
+// operationNames maps the supported operations to the name of the role
+// parameter granting them
+var operationNames = map[uint32]string{
+	operationActivate:         "operation_activate",
+	operationAddAttribute:     "operation_add_attribute",
+	operationCreate:           "operation_create",
+	operationDestroy:          "operation_destroy",
+	operationDiscoverVersions: "operation_discover_versions",
+	operationGet:              "operation_get",
+	operationGetAttributeList: "operation_get_attribute_list",
+	operationGetAttributes:    "operation_get_attributes",
+	operationLocate:           "operation_locate",
+	operationRegister:         "operation_register",
+	operationReKey:            "operation_rekey",
+	operationRevoke:           "operation_revoke",
+}
+
+const (
+	resultStatusSuccess         uint32 = 0
+	resultStatusOperationFailed uint32 = 1
+)
+
+const (
+	resultReasonItemNotFound          uint32 = 0x01
+	resultReasonInvalidMessage        uint32 = 0x04
+	resultReasonOperationNotSupported uint32 = 0x05
+	resultReasonMissingData           uint32 = 0x06
+	resultReasonInvalidField          uint32 = 0x07
+	resultReasonIllegalOperation      uint32 = 0x0B
+	resultReasonPermissionDenied      uint32 = 0x0C
+	resultReasonGeneralFailure        uint32 = 0x100
+)
+
+const (
+	objectTypeSymmetricKey uint32 = 0x02
+)
+
+const (
+	statePreActive   uint32 = 0x01
+	stateActive      uint32 = 0x02
+	stateDeactivated uint32 = 0x03
+	stateCompromised uint32 = 0x04
+)
+
+const (
+	cryptographicAlgorithmAES uint32 = 0x03
+)
+
+const (
+	keyFormatTypeRaw uint32 = 0x01
+)
+
+const (
+	nameTypeUninterpretedTextString uint32 = 0x01
+)
+
+const (
+	revocationReasonKeyCompromise uint32 = 0x02
+)
+
+// protocolVersions lists the protocol versions understood by the server,
+// most preferred first
+var protocolVersions = [][2]int32{
+	{1, 4},
+	{1, 3},
+	{1, 2},
+	{1, 1},
+	{1, 0},
+}
+
+// Attribute names supported by the server
+const (
+	attributeActivationDate         = "Activation Date"
+	attributeCryptographicAlgorithm = "Cryptographic Algorithm"
+	attributeCryptographicLength    = "Cryptographic Length"
+	attributeCryptographicUsageMask = "Cryptographic Usage Mask"
+	attributeName                   = "Name"
+	attributeObjectType             = "Object Type"
+	attributeState                  = "State"
+	attributeUniqueIdentifier       = "Unique Identifier"
+)
+
+// kmipError is returned by the operation handlers to report the result
+// reason of a failed batch item
+type kmipError struct {
+	reason  uint32
+	message string
+}
+
+func (e *kmipError) Error() string {
+	return e.message
+}
+
+func newError(reason uint32, format string, args ...interface{}) error {
+	return &kmipError{
+		reason:  reason,
+		message: fmt.Sprintf(format, args...),
+	}
+}
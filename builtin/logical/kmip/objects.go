@@ -0,0 +1,228 @@
+package kmip
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func objectPrefix(scope string) string {
+	return "objects/" + scope + "/"
+}
+
+// managedObject is a KMIP managed object. Only symmetric keys are supported.
+type managedObject struct {
+	UniqueIdentifier       string    `json:"unique_identifier"`
+	ObjectType             uint32    `json:"object_type"`
+	State                  uint32    `json:"state"`
+	CryptographicAlgorithm uint32    `json:"cryptographic_algorithm"`
+	CryptographicLength    int32     `json:"cryptographic_length"`
+	CryptographicUsageMask int32     `json:"cryptographic_usage_mask"`
+	Names                  []string  `json:"names"`
+	ActivationDate         time.Time `json:"activation_date"`
+	KeyMaterial            []byte    `json:"key_material"`
+
+	// CustomAttributes holds the text values of the attributes whose name
+	// starts with "x-"
+	CustomAttributes map[string][]string `json:"custom_attributes"`
+}
+
+func newObject() (*managedObject, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &managedObject{
+		UniqueIdentifier: id,
+		ObjectType:       objectTypeSymmetricKey,
+		State:            statePreActive,
+		CustomAttributes: map[string][]string{},
+	}, nil
+}
+
+func readObject(ctx context.Context, s logical.Storage, scope, id string) (*managedObject, error) {
+	entry, err := s.Get(ctx, objectPrefix(scope)+id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	object := &managedObject{}
+	if err := entry.DecodeJSON(object); err != nil {
+		return nil, err
+	}
+	if object.CustomAttributes == nil {
+		object.CustomAttributes = map[string][]string{}
+	}
+	return object, nil
+}
+
+func writeObject(ctx context.Context, s logical.Storage, scope string, object *managedObject) error {
+	entry, err := logical.StorageEntryJSON(objectPrefix(scope)+object.UniqueIdentifier, object)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// attributeNames returns the names of the attributes the object has
+func (o *managedObject) attributeNames() []string {
+	names := []string{
+		attributeUniqueIdentifier,
+		attributeObjectType,
+		attributeState,
+		attributeCryptographicAlgorithm,
+		attributeCryptographicLength,
+		attributeCryptographicUsageMask,
+	}
+	if len(o.Names) > 0 {
+		names = append(names, attributeName)
+	}
+	if !o.ActivationDate.IsZero() {
+		names = append(names, attributeActivationDate)
+	}
+	for name := range o.CustomAttributes {
+		names = append(names, name)
+	}
+	return names
+}
+
+// attributes returns the TTLV attributes of the object with the given name
+func (o *managedObject) attributes(name string) []*item {
+	var values []*item
+	switch name {
+	case attributeUniqueIdentifier:
+		values = append(values, textString(tagAttributeValue, o.UniqueIdentifier))
+	case attributeObjectType:
+		values = append(values, enumeration(tagAttributeValue, o.ObjectType))
+	case attributeState:
+		values = append(values, enumeration(tagAttributeValue, o.State))
+	case attributeCryptographicAlgorithm:
+		values = append(values, enumeration(tagAttributeValue, o.CryptographicAlgorithm))
+	case attributeCryptographicLength:
+		values = append(values, integer(tagAttributeValue, o.CryptographicLength))
+	case attributeCryptographicUsageMask:
+		values = append(values, integer(tagAttributeValue, o.CryptographicUsageMask))
+	case attributeName:
+		for _, n := range o.Names {
+			values = append(values, structure(tagAttributeValue,
+				textString(tagNameValue, n),
+				enumeration(tagNameType, nameTypeUninterpretedTextString),
+			))
+		}
+	case attributeActivationDate:
+		if !o.ActivationDate.IsZero() {
+			values = append(values, dateTime(tagAttributeValue, o.ActivationDate))
+		}
+	default:
+		for _, v := range o.CustomAttributes[name] {
+			values = append(values, textString(tagAttributeValue, v))
+		}
+	}
+
+	attributes := make([]*item, 0, len(values))
+	for i, v := range values {
+		attribute := structure(tagAttribute, textString(tagAttributeName, name))
+		if i > 0 {
+			attribute.add(integer(tagAttributeIndex, int32(i)))
+		}
+		attribute.add(v)
+		attributes = append(attributes, attribute)
+	}
+	return attributes
+}
+
+// setAttribute sets an attribute of the object from a TTLV Attribute
+// structure. Single valued attributes are replaced, while names and custom
+// attributes are added.
+func (o *managedObject) setAttribute(attribute *item) error {
+	name, ok := attribute.child(tagAttributeName).text()
+	if !ok {
+		return newError(resultReasonMissingData, "attribute name is required")
+	}
+	value := attribute.child(tagAttributeValue)
+	if value == nil {
+		return newError(resultReasonMissingData, "attribute value is required for %q", name)
+	}
+
+	invalid := newError(resultReasonInvalidField, "invalid value for attribute %q", name)
+	switch name {
+	case attributeCryptographicAlgorithm:
+		v, ok := value.enum()
+		if !ok {
+			return invalid
+		}
+		o.CryptographicAlgorithm = v
+	case attributeCryptographicLength:
+		v, ok := value.int()
+		if !ok {
+			return invalid
+		}
+		o.CryptographicLength = v
+	case attributeCryptographicUsageMask:
+		v, ok := value.int()
+		if !ok {
+			return invalid
+		}
+		o.CryptographicUsageMask = v
+	case attributeName:
+		v, ok := value.child(tagNameValue).text()
+		if !ok || v == "" {
+			return invalid
+		}
+		o.Names = append(o.Names, v)
+	default:
+		if !customAttribute(name) {
+			return newError(resultReasonInvalidField, "attribute %q cannot be set", name)
+		}
+		v, ok := value.text()
+		if !ok {
+			return invalid
+		}
+		o.CustomAttributes[name] = append(o.CustomAttributes[name], v)
+	}
+	return nil
+}
+
+// customAttribute returns whether the attribute name is a custom attribute
+func customAttribute(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "x-")
+}
+
+// matches returns whether the object has all the attributes of the Locate
+// request
+func (o *managedObject) matches(attributes []*item) bool {
+	for _, attribute := range attributes {
+		name, _ := attribute.child(tagAttributeName).text()
+		value := attribute.child(tagAttributeValue)
+
+		found := false
+		for _, candidate := range o.attributes(name) {
+			if sameValue(candidate.child(tagAttributeValue), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a, b *item) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	encodedA, errA := (&item{Type: a.Type, Value: a.Value}).encode()
+	encodedB, errB := (&item{Type: b.Type, Value: b.Value}).encode()
+	if errA != nil || errB != nil {
+		return false
+	}
+	return string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,504 @@
+package kmip
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// operationRequest is a batch item of a request message
+type operationRequest struct {
+	scope   string
+	payload *item
+
+	// placeholder is the ID placeholder of the message: the unique
+	// identifier of the object created or located by a previous batch item,
+	// used when a batch item omits the unique identifier
+	placeholder *string
+}
+
+type operationFunc func(*server, *operationRequest) ([]*item, error)
+
+var operations = map[uint32]operationFunc{
+	operationActivate:         (*server).activate,
+	operationAddAttribute:     (*server).addAttribute,
+	operationCreate:           (*server).create,
+	operationDestroy:          (*server).destroy,
+	operationDiscoverVersions: (*server).discoverVersions,
+	operationGet:              (*server).get,
+	operationGetAttributeList: (*server).getAttributeList,
+	operationGetAttributes:    (*server).getAttributes,
+	operationLocate:           (*server).locate,
+	operationRegister:         (*server).register,
+	operationReKey:            (*server).rekey,
+	operationRevoke:           (*server).revoke,
+}
+
+// handleMessage processes a request message and returns the response message
+func (s *server) handleMessage(c *client, msg *item) *item {
+	version := structure(tagProtocolVersion,
+		integer(tagProtocolVersionMajor, protocolVersions[0][0]),
+		integer(tagProtocolVersionMinor, protocolVersions[0][1]),
+	)
+
+	var responses []*item
+	header := msg.child(tagRequestHeader)
+	batchItems := msg.all(tagBatchItem)
+	if msg.Tag != tagRequestMessage || header == nil || len(batchItems) == 0 {
+		responses = append(responses, failure(structure(tagBatchItem), newError(resultReasonInvalidMessage, "invalid request message")))
+	} else {
+		if v := header.child(tagProtocolVersion); v != nil {
+			version = v
+		}
+
+		role, authErr := s.authenticate(c)
+		var placeholder string
+		for _, batchItem := range batchItems {
+			responses = append(responses, s.handleBatchItem(c, role, authErr, batchItem, &placeholder))
+		}
+	}
+
+	response := structure(tagResponseMessage,
+		structure(tagResponseHeader,
+			version,
+			dateTime(tagTimeStamp, time.Now()),
+			integer(tagBatchCount, int32(len(responses))),
+		),
+	)
+	response.add(responses...)
+	return response
+}
+
+func (s *server) handleBatchItem(c *client, role *roleEntry, authErr error, batchItem *item, placeholder *string) *item {
+	response := structure(tagBatchItem)
+	operation, ok := batchItem.child(tagOperation).enum()
+	if ok {
+		response.add(enumeration(tagOperation, operation))
+	}
+	if id := batchItem.child(tagUniqueBatchItemID); id != nil {
+		response.add(id)
+	}
+
+	var payload []*item
+	var err error
+	switch {
+	case !ok:
+		err = newError(resultReasonInvalidMessage, "operation is required")
+	case authErr != nil:
+		err = authErr
+	case operations[operation] == nil:
+		err = newError(resultReasonOperationNotSupported, "operation %d is not supported", operation)
+	case !role.allowed(operation):
+		err = newError(resultReasonPermissionDenied, "operation %s is not allowed by role %q", operationNames[operation], c.role)
+	default:
+		s.objectsLock.Lock()
+		payload, err = operations[operation](s, &operationRequest{
+			scope:       c.scope,
+			payload:     batchItem.child(tagRequestPayload),
+			placeholder: placeholder,
+		})
+		s.objectsLock.Unlock()
+	}
+	if err != nil {
+		if _, ok := err.(*kmipError); !ok {
+			s.logger.Error("error processing KMIP operation", "operation", operation, "error", err)
+		}
+		return failure(response, err)
+	}
+
+	response.add(enumeration(tagResultStatus, resultStatusSuccess))
+	response.add(structure(tagResponsePayload, payload...))
+	return response
+}
+
+// failure adds the result of a failed operation to the batch item
+func failure(response *item, err error) *item {
+	reason, message := resultReasonGeneralFailure, "internal error"
+	if kerr, ok := err.(*kmipError); ok {
+		reason, message = kerr.reason, kerr.message
+	}
+	response.add(
+		enumeration(tagResultStatus, resultStatusOperationFailed),
+		enumeration(tagResultReason, reason),
+		textString(tagResultMessage, message),
+	)
+	return response
+}
+
+// uniqueIdentifier returns the unique identifier of the request, or the ID
+// placeholder if it is omitted
+func (r *operationRequest) uniqueIdentifier() (string, error) {
+	if id, ok := r.payload.child(tagUniqueIdentifier).text(); ok {
+		return id, nil
+	}
+	if *r.placeholder != "" {
+		return *r.placeholder, nil
+	}
+	return "", newError(resultReasonMissingData, "unique identifier is required")
+}
+
+// object returns the managed object of the request
+func (s *server) object(r *operationRequest) (*managedObject, error) {
+	id, err := r.uniqueIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	object, err := readObject(s.ctx, s.storage, r.scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, newError(resultReasonItemNotFound, "object %q not found", id)
+	}
+	return object, nil
+}
+
+// checkKey validates the algorithm and length of a symmetric key
+func checkKey(object *managedObject) error {
+	if object.CryptographicAlgorithm != cryptographicAlgorithmAES {
+		return newError(resultReasonInvalidField, "only AES keys are supported")
+	}
+	switch object.CryptographicLength {
+	case 128, 192, 256:
+	default:
+		return newError(resultReasonInvalidField, "invalid AES key length %d", object.CryptographicLength)
+	}
+	if object.KeyMaterial != nil && len(object.KeyMaterial)*8 != int(object.CryptographicLength) {
+		return newError(resultReasonInvalidField, "the key material is not %d bits long", object.CryptographicLength)
+	}
+	return nil
+}
+
+func checkObjectType(r *operationRequest) error {
+	objectType, ok := r.payload.child(tagObjectType).enum()
+	if !ok {
+		return newError(resultReasonMissingData, "object type is required")
+	}
+	if objectType != objectTypeSymmetricKey {
+		return newError(resultReasonInvalidField, "only symmetric keys are supported")
+	}
+	return nil
+}
+
+func setTemplateAttributes(object *managedObject, r *operationRequest) error {
+	for _, attribute := range r.payload.child(tagTemplateAttribute).all(tagAttribute) {
+		if err := object.setAttribute(attribute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) create(r *operationRequest) ([]*item, error) {
+	if err := checkObjectType(r); err != nil {
+		return nil, err
+	}
+	object, err := newObject()
+	if err != nil {
+		return nil, err
+	}
+	if err := setTemplateAttributes(object, r); err != nil {
+		return nil, err
+	}
+	if err := checkKey(object); err != nil {
+		return nil, err
+	}
+
+	object.KeyMaterial = make([]byte, object.CryptographicLength/8)
+	if _, err := rand.Read(object.KeyMaterial); err != nil {
+		return nil, err
+	}
+	if err := writeObject(s.ctx, s.storage, r.scope, object); err != nil {
+		return nil, err
+	}
+
+	*r.placeholder = object.UniqueIdentifier
+	return []*item{
+		enumeration(tagObjectType, object.ObjectType),
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) register(r *operationRequest) ([]*item, error) {
+	if err := checkObjectType(r); err != nil {
+		return nil, err
+	}
+	keyBlock := r.payload.child(tagSymmetricKey).child(tagKeyBlock)
+	if keyBlock == nil {
+		return nil, newError(resultReasonMissingData, "symmetric key is required")
+	}
+	if format, _ := keyBlock.child(tagKeyFormatType).enum(); format != keyFormatTypeRaw {
+		return nil, newError(resultReasonInvalidField, "only raw keys are supported")
+	}
+	material, ok := keyBlock.child(tagKeyValue).child(tagKeyMaterial).bytes()
+	if !ok || len(material) == 0 {
+		return nil, newError(resultReasonMissingData, "key material is required")
+	}
+
+	object, err := newObject()
+	if err != nil {
+		return nil, err
+	}
+	object.KeyMaterial = material
+	object.CryptographicLength = int32(len(material) * 8)
+	if v, ok := keyBlock.child(tagCryptographicAlgorithm).enum(); ok {
+		object.CryptographicAlgorithm = v
+	}
+	if err := setTemplateAttributes(object, r); err != nil {
+		return nil, err
+	}
+	if err := checkKey(object); err != nil {
+		return nil, err
+	}
+	if err := writeObject(s.ctx, s.storage, r.scope, object); err != nil {
+		return nil, err
+	}
+
+	*r.placeholder = object.UniqueIdentifier
+	return []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) rekey(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+	if object.State != statePreActive && object.State != stateActive {
+		return nil, newError(resultReasonIllegalOperation, "object %q cannot be rekeyed in its current state", object.UniqueIdentifier)
+	}
+
+	replacement, err := newObject()
+	if err != nil {
+		return nil, err
+	}
+	replacement.CryptographicAlgorithm = object.CryptographicAlgorithm
+	replacement.CryptographicLength = object.CryptographicLength
+	replacement.CryptographicUsageMask = object.CryptographicUsageMask
+	replacement.Names = object.Names
+	replacement.CustomAttributes = object.CustomAttributes
+	if object.State == stateActive {
+		replacement.State = stateActive
+		replacement.ActivationDate = time.Now().UTC()
+	}
+
+	replacement.KeyMaterial = make([]byte, replacement.CryptographicLength/8)
+	if _, err := rand.Read(replacement.KeyMaterial); err != nil {
+		return nil, err
+	}
+	if err := writeObject(s.ctx, s.storage, r.scope, replacement); err != nil {
+		return nil, err
+	}
+
+	*r.placeholder = replacement.UniqueIdentifier
+	return []*item{
+		textString(tagUniqueIdentifier, replacement.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) locate(r *operationRequest) ([]*item, error) {
+	maximum, limited := r.payload.child(tagMaximumItems).int()
+	attributes := r.payload.all(tagAttribute)
+
+	ids, err := s.storage.List(s.ctx, objectPrefix(r.scope))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*item
+	for _, id := range ids {
+		if limited && len(result) >= int(maximum) {
+			break
+		}
+		object, err := readObject(s.ctx, s.storage, r.scope, id)
+		if err != nil {
+			return nil, err
+		}
+		if object == nil || !object.matches(attributes) {
+			continue
+		}
+		result = append(result, textString(tagUniqueIdentifier, object.UniqueIdentifier))
+	}
+
+	if len(result) > 0 {
+		*r.placeholder, _ = result[0].text()
+	}
+	return result, nil
+}
+
+func (s *server) get(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*item{
+		enumeration(tagObjectType, object.ObjectType),
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+		structure(tagSymmetricKey,
+			structure(tagKeyBlock,
+				enumeration(tagKeyFormatType, keyFormatTypeRaw),
+				structure(tagKeyValue,
+					byteString(tagKeyMaterial, object.KeyMaterial),
+				),
+				enumeration(tagCryptographicAlgorithm, object.CryptographicAlgorithm),
+				integer(tagCryptographicLength, object.CryptographicLength),
+			),
+		),
+	}, nil
+}
+
+func (s *server) getAttributes(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range r.payload.all(tagAttributeName) {
+		if v, ok := name.text(); ok {
+			names = append(names, v)
+		}
+	}
+	if len(names) == 0 {
+		names = object.attributeNames()
+	}
+
+	result := []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}
+	for _, name := range names {
+		result = append(result, object.attributes(name)...)
+	}
+	return result, nil
+}
+
+func (s *server) getAttributeList(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}
+	for _, name := range object.attributeNames() {
+		result = append(result, textString(tagAttributeName, name))
+	}
+	return result, nil
+}
+
+func (s *server) addAttribute(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+	attribute := r.payload.child(tagAttribute)
+	if attribute == nil {
+		return nil, newError(resultReasonMissingData, "attribute is required")
+	}
+
+	// The cryptographic attributes are set when creating the object
+	name, _ := attribute.child(tagAttributeName).text()
+	if name != attributeName && !customAttribute(name) {
+		return nil, newError(resultReasonInvalidField, "attribute %q cannot be added", name)
+	}
+	if err := object.setAttribute(attribute); err != nil {
+		return nil, err
+	}
+	if err := writeObject(s.ctx, s.storage, r.scope, object); err != nil {
+		return nil, err
+	}
+
+	return []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+		attribute,
+	}, nil
+}
+
+func (s *server) activate(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+	if object.State != statePreActive {
+		return nil, newError(resultReasonIllegalOperation, "object %q is not pre-active", object.UniqueIdentifier)
+	}
+
+	object.State = stateActive
+	object.ActivationDate = time.Now().UTC()
+	if err := writeObject(s.ctx, s.storage, r.scope, object); err != nil {
+		return nil, err
+	}
+
+	return []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) revoke(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+	reason, ok := r.payload.child(tagRevocationReason).child(tagRevocationReasonCode).enum()
+	if !ok {
+		return nil, newError(resultReasonMissingData, "revocation reason is required")
+	}
+
+	switch {
+	case reason == revocationReasonKeyCompromise:
+		object.State = stateCompromised
+	case object.State != stateCompromised:
+		object.State = stateDeactivated
+	}
+	if err := writeObject(s.ctx, s.storage, r.scope, object); err != nil {
+		return nil, err
+	}
+
+	return []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) destroy(r *operationRequest) ([]*item, error) {
+	object, err := s.object(r)
+	if err != nil {
+		return nil, err
+	}
+	if object.State == stateActive {
+		return nil, newError(resultReasonIllegalOperation, "object %q must be revoked before being destroyed", object.UniqueIdentifier)
+	}
+
+	if err := s.storage.Delete(s.ctx, objectPrefix(r.scope)+object.UniqueIdentifier); err != nil {
+		return nil, err
+	}
+
+	return []*item{
+		textString(tagUniqueIdentifier, object.UniqueIdentifier),
+	}, nil
+}
+
+func (s *server) discoverVersions(r *operationRequest) ([]*item, error) {
+	var result []*item
+	requested := r.payload.all(tagProtocolVersion)
+	for _, supported := range protocolVersions {
+		version := structure(tagProtocolVersion,
+			integer(tagProtocolVersionMajor, supported[0]),
+			integer(tagProtocolVersionMinor, supported[1]),
+		)
+		if len(requested) == 0 {
+			result = append(result, version)
+			continue
+		}
+		for _, v := range requested {
+			major, _ := v.child(tagProtocolVersionMajor).int()
+			minor, _ := v.child(tagProtocolVersionMinor).int()
+			if major == supported[0] && minor == supported[1] {
+				result = append(result, version)
+				break
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package kmip
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathCA(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ca",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCARead,
+		},
+
+		HelpSynopsis:    pathCAHelpSyn,
+		HelpDescription: pathCAHelpDesc,
+	}
+}
+
+func (b *backend) pathCARead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ca, err := readCA(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if ca == nil {
+		return logical.ErrorResponse("the kmip engine has not been configured"), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ca_pem": strings.Join(ca.chain(), ""),
+		},
+	}, nil
+}
+
+const pathCAHelpSyn = `
+Read the CA certificates of the KMIP server.
+`
+
+const pathCAHelpDesc = `
+This path returns the PEM encoded intermediate and root CA certificates that
+issue the certificates of the KMIP server and its clients.
+`
@@ -0,0 +1,308 @@
+package kmip
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const configPath = "config"
+
+// tlsVersions maps the accepted values of tls_min_version to their
+// crypto/tls value
+var tlsVersions = map[string]uint16{
+	"tls10": tls.VersionTLS10,
+	"tls11": tls.VersionTLS11,
+	"tls12": tls.VersionTLS12,
+	"tls13": tls.VersionTLS13,
+}
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"listen_addrs": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Default:     []string{"127.0.0.1:5696"},
+				Description: "Addresses and ports the KMIP server listens on.",
+			},
+			"connection_timeout": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     1,
+				Description: "Duration within which connections must become ready.",
+			},
+			"server_hostnames": &framework.FieldSchema{
+				Type:    framework.TypeCommaStringSlice,
+				Default: []string{"localhost"},
+				Description: `Hostnames to include in the server's TLS certificate
+as SAN DNS names. The first is used as the common name.`,
+			},
+			"server_ips": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `IPs to include in the server's TLS certificate as
+SAN IP addresses. Localhost is always included.`,
+			},
+			"tls_ca_key_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "ec",
+				Description: `CA key type, "rsa" or "ec".`,
+			},
+			"tls_ca_key_bits": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     521,
+				Description: "CA key bits, valid values depend on the key type.",
+			},
+			"tls_min_version": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "tls12",
+				Description: `Minimum TLS version to accept: "tls10", "tls11", "tls12" or "tls13".`,
+			},
+			"default_tls_client_key_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "ec",
+				Description: `Client certificate key type, "rsa" or "ec".`,
+			},
+			"default_tls_client_key_bits": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     521,
+				Description: "Client certificate key bits, valid values depend on the key type.",
+			},
+			"default_tls_client_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     86400,
+				Description: "Client certificate TTL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type kmipConfig struct {
+	ListenAddrs             []string      `json:"listen_addrs"`
+	ConnectionTimeout       time.Duration `json:"connection_timeout"`
+	ServerHostnames         []string      `json:"server_hostnames"`
+	ServerIPs               []string      `json:"server_ips"`
+	TLSCAKeyType            string        `json:"tls_ca_key_type"`
+	TLSCAKeyBits            int           `json:"tls_ca_key_bits"`
+	TLSMinVersion           string        `json:"tls_min_version"`
+	DefaultTLSClientKeyType string        `json:"default_tls_client_key_type"`
+	DefaultTLSClientKeyBits int           `json:"default_tls_client_key_bits"`
+	DefaultTLSClientTTL     time.Duration `json:"default_tls_client_ttl"`
+}
+
+func defaultConfig() *kmipConfig {
+	return &kmipConfig{
+		ListenAddrs:             []string{"127.0.0.1:5696"},
+		ConnectionTimeout:       time.Second,
+		ServerHostnames:         []string{"localhost"},
+		ServerIPs:               []string{},
+		TLSCAKeyType:            "ec",
+		TLSCAKeyBits:            521,
+		TLSMinVersion:           "tls12",
+		DefaultTLSClientKeyType: "ec",
+		DefaultTLSClientKeyBits: 521,
+		DefaultTLSClientTTL:     24 * time.Hour,
+	}
+}
+
+// serverSettingsEqual returns whether the settings used by the running
+// server are the same in both configurations. The client settings only
+// matter when generating credentials.
+func (c *kmipConfig) serverSettingsEqual(other *kmipConfig) bool {
+	return strutil.EquivalentSlices(c.ListenAddrs, other.ListenAddrs) &&
+		c.ConnectionTimeout == other.ConnectionTimeout &&
+		strutil.EquivalentSlices(c.ServerHostnames, other.ServerHostnames) &&
+		strutil.EquivalentSlices(c.ServerIPs, other.ServerIPs) &&
+		c.TLSCAKeyType == other.TLSCAKeyType &&
+		c.TLSCAKeyBits == other.TLSCAKeyBits &&
+		c.TLSMinVersion == other.TLSMinVersion
+}
+
+func (c *kmipConfig) validate() error {
+	if len(c.ListenAddrs) == 0 {
+		return fmt.Errorf("at least one listen address is required")
+	}
+	for _, addr := range c.ListenAddrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid listen address %q: %v", addr, err)
+		}
+	}
+	if c.ConnectionTimeout <= 0 {
+		return fmt.Errorf("connection_timeout must be positive")
+	}
+	if len(c.ServerHostnames) == 0 {
+		return fmt.Errorf("at least one server hostname is required")
+	}
+	for _, ip := range c.ServerIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid server IP %q", ip)
+		}
+	}
+	if err := validateKeyTypeLength(c.TLSCAKeyType, c.TLSCAKeyBits); err != nil {
+		return errwrap.Wrapf("invalid CA key: {{err}}", err)
+	}
+	if _, ok := tlsVersions[c.TLSMinVersion]; !ok {
+		return fmt.Errorf("invalid tls_min_version %q", c.TLSMinVersion)
+	}
+	if err := validateKeyTypeLength(c.DefaultTLSClientKeyType, c.DefaultTLSClientKeyBits); err != nil {
+		return errwrap.Wrapf("invalid default client key: {{err}}", err)
+	}
+	if c.DefaultTLSClientTTL <= 0 {
+		return fmt.Errorf("default_tls_client_ttl must be positive")
+	}
+	return nil
+}
+
+func readConfig(ctx context.Context, s logical.Storage) (*kmipConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	config := &kmipConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("error reading kmip configuration: {{err}}", err)
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = defaultConfig()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"listen_addrs":                config.ListenAddrs,
+			"connection_timeout":          config.ConnectionTimeout.String(),
+			"server_hostnames":            config.ServerHostnames,
+			"server_ips":                  config.ServerIPs,
+			"tls_ca_key_type":             config.TLSCAKeyType,
+			"tls_ca_key_bits":             config.TLSCAKeyBits,
+			"tls_min_version":             config.TLSMinVersion,
+			"default_tls_client_key_type": config.DefaultTLSClientKeyType,
+			"default_tls_client_key_bits": config.DefaultTLSClientKeyBits,
+			"default_tls_client_ttl":      int64(config.DefaultTLSClientTTL / time.Second),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	previous, err := readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	config := defaultConfig()
+	if previous != nil {
+		*config = *previous
+	}
+
+	if v, ok := d.GetOk("listen_addrs"); ok {
+		config.ListenAddrs = v.([]string)
+	}
+	if v, ok := d.GetOk("connection_timeout"); ok {
+		config.ConnectionTimeout = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("server_hostnames"); ok {
+		config.ServerHostnames = v.([]string)
+	}
+	if v, ok := d.GetOk("server_ips"); ok {
+		config.ServerIPs = v.([]string)
+	}
+	if v, ok := d.GetOk("tls_ca_key_type"); ok {
+		config.TLSCAKeyType = v.(string)
+	}
+	if v, ok := d.GetOk("tls_ca_key_bits"); ok {
+		config.TLSCAKeyBits = v.(int)
+	}
+	if v, ok := d.GetOk("tls_min_version"); ok {
+		config.TLSMinVersion = v.(string)
+	}
+	if v, ok := d.GetOk("default_tls_client_key_type"); ok {
+		config.DefaultTLSClientKeyType = v.(string)
+	}
+	if v, ok := d.GetOk("default_tls_client_key_bits"); ok {
+		config.DefaultTLSClientKeyBits = v.(int)
+	}
+	if v, ok := d.GetOk("default_tls_client_ttl"); ok {
+		config.DefaultTLSClientTTL = time.Duration(v.(int)) * time.Second
+	}
+
+	if err := config.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// The CA is only generated again when its key changes, since that
+	// invalidates all the client certificates
+	ca, err := readCA(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	newCA := ca == nil || ca.KeyType != config.TLSCAKeyType || ca.KeyBits != config.TLSCAKeyBits
+	if newCA {
+		ca, err = generateCA(config.TLSCAKeyType, config.TLSCAKeyBits)
+		if err != nil {
+			return nil, errwrap.Wrapf("unable to generate the kmip CA: {{err}}", err)
+		}
+		if err := writeCA(ctx, req.Storage, ca); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if !b.serves() {
+		return nil, nil
+	}
+	if b.server != nil && !newCA && previous != nil && config.serverSettingsEqual(previous) {
+		return nil, nil
+	}
+	if err := b.restartServer(ctx, req.Storage, config); err != nil {
+		return nil, errwrap.Wrapf("unable to start the kmip server: {{err}}", err)
+	}
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the KMIP server.
+`
+
+const pathConfigHelpDesc = `
+This path configures the addresses the KMIP server listens on, the TLS
+certificates of the server, and the defaults of the client certificates.
+
+The first write generates the CA issuing the certificates and starts the
+server. Later writes restart it when the server settings change. Changing the
+key type or bits of the CA generates a new CA, which invalidates the client
+certificates generated before.
+`
@@ -0,0 +1,504 @@
+package kmip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const serialPrefix = "serials/"
+
+func credentialPrefix(scope, role string) string {
+	return "credentials/" + scope + "/" + role + "/"
+}
+
+func credentialPattern(suffix string) string {
+	return "scope/" + framework.GenericNameRegex("scope") + "/role/" + framework.GenericNameRegex("role") + "/credential" + suffix
+}
+
+func credentialFields(extra map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields := map[string]*framework.FieldSchema{
+		"scope": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the scope.",
+		},
+		"role": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the role.",
+		},
+	}
+	for name, schema := range extra {
+		fields[name] = schema
+	}
+	return fields
+}
+
+var formatField = &framework.FieldSchema{
+	Type:        framework.TypeString,
+	Default:     "pem",
+	Description: `Format of the certificates and private key: "pem", "pem_bundle" or "der".`,
+}
+
+func pathListCredentials(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: credentialPattern("/?$"),
+		Fields:  credentialFields(nil),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathCredentialList,
+		},
+
+		HelpSynopsis:    pathListCredentialsHelpSyn,
+		HelpDescription: pathListCredentialsHelpDesc,
+	}
+}
+
+func pathCredentialGenerate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: credentialPattern("/generate"),
+		Fields: credentialFields(map[string]*framework.FieldSchema{
+			"format": formatField,
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCredentialGenerate,
+		},
+
+		HelpSynopsis:    pathCredentialGenerateHelpSyn,
+		HelpDescription: pathCredentialGenerateHelpDesc,
+	}
+}
+
+func pathCredentialSign(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: credentialPattern("/sign"),
+		Fields: credentialFields(map[string]*framework.FieldSchema{
+			"format": formatField,
+			"csr": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "PEM encoded certificate signing request.",
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCredentialSign,
+		},
+
+		HelpSynopsis:    pathCredentialSignHelpSyn,
+		HelpDescription: pathCredentialSignHelpDesc,
+	}
+}
+
+func pathCredentialLookup(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: credentialPattern("/lookup"),
+		Fields: credentialFields(map[string]*framework.FieldSchema{
+			"format": formatField,
+			"serial_number": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Serial number of the certificate.",
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredentialLookup,
+		},
+
+		HelpSynopsis:    pathCredentialLookupHelpSyn,
+		HelpDescription: pathCredentialLookupHelpDesc,
+	}
+}
+
+func pathCredentialRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: credentialPattern("/revoke"),
+		Fields: credentialFields(map[string]*framework.FieldSchema{
+			"serial_number": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Serial number of the certificate to revoke.",
+			},
+			"certificate": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "PEM encoded certificate to revoke.",
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCredentialRevoke,
+		},
+
+		HelpSynopsis:    pathCredentialRevokeHelpSyn,
+		HelpDescription: pathCredentialRevokeHelpDesc,
+	}
+}
+
+// credentialEntry is stored for each certificate of a role
+type credentialEntry struct {
+	Certificate string `json:"certificate"`
+}
+
+// serialEntry maps the serial number of a certificate to its scope and role,
+// to authenticate the KMIP clients
+type serialEntry struct {
+	Scope string `json:"scope"`
+	Role  string `json:"role"`
+}
+
+func readSerial(ctx context.Context, s logical.Storage, serial string) (*serialEntry, error) {
+	entry, err := s.Get(ctx, serialPrefix+serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	result := &serialEntry{}
+	if err := entry.DecodeJSON(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func deleteCredential(ctx context.Context, s logical.Storage, scope, role, serial string) error {
+	if err := s.Delete(ctx, serialPrefix+serial); err != nil {
+		return err
+	}
+	return s.Delete(ctx, credentialPrefix(scope, role)+serial)
+}
+
+func (b *backend) pathCredentialList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serials, err := req.Storage.List(ctx, credentialPrefix(d.Get("scope").(string), d.Get("role").(string)))
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(serials), nil
+}
+
+func (b *backend) pathCredentialGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.issueCredential(ctx, req, d, nil)
+}
+
+func (b *backend) pathCredentialSign(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	block, _ := pem.Decode([]byte(d.Get("csr").(string)))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return logical.ErrorResponse("csr must be a PEM encoded certificate request"), nil
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to parse csr: %v", err)), nil
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid csr signature: %v", err)), nil
+	}
+	return b.issueCredential(ctx, req, d, csr)
+}
+
+// issueCredential issues a client certificate for the role, for a new key or
+// the key of the csr
+func (b *backend) issueCredential(ctx context.Context, req *logical.Request, d *framework.FieldData, csr *x509.CertificateRequest) (*logical.Response, error) {
+	scope := d.Get("scope").(string)
+	roleName := d.Get("role").(string)
+	format := d.Get("format").(string)
+	if format != "pem" && format != "pem_bundle" && format != "der" {
+		return logical.ErrorResponse(fmt.Sprintf("invalid format %q", format)), nil
+	}
+
+	role, err := readRole(ctx, req.Storage, scope, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q does not exist in scope %q", roleName, scope)), nil
+	}
+
+	config, err := readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := readCA(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || ca == nil {
+		return logical.ErrorResponse("the kmip engine has not been configured"), nil
+	}
+	parsed, err := ca.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, keyBits, ttl := role.clientSettings(config)
+
+	var keyPEM string
+	var cert *x509.Certificate
+	if csr == nil {
+		key, encoded, err := generateKey(keyType, keyBits)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM = encoded
+		cert, err = parsed.issueClientCertificate(key.Public(), ttl)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := checkPublicKey(csr.PublicKey, keyType, keyBits); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		cert, err = parsed.issueClientCertificate(csr.PublicKey, ttl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	serial := cert.SerialNumber.String()
+	entry, err := logical.StorageEntryJSON(credentialPrefix(scope, roleName)+serial, &credentialEntry{
+		Certificate: encodeCertificate(cert),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	entry, err = logical.StorageEntryJSON(serialPrefix+serial, &serialEntry{
+		Scope: scope,
+		Role:  roleName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	data, err := formatCredential(format, encodeCertificate(cert), keyPEM, ca)
+	if err != nil {
+		return nil, err
+	}
+	data["serial_number"] = serial
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// checkPublicKey returns an error if the key does not have the type and
+// length of the role
+func checkPublicKey(pub interface{}, keyType string, keyBits int) error {
+	var actualType string
+	var actualBits int
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		actualType, actualBits = "rsa", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		actualType, actualBits = "ec", key.Curve.Params().BitSize
+	default:
+		return fmt.Errorf("unsupported csr key type %T", pub)
+	}
+
+	if actualType != keyType || actualBits != keyBits {
+		return fmt.Errorf("the csr key must be a %d bits %s key, got a %d bits %s key", keyBits, keyType, actualBits, actualType)
+	}
+	return nil
+}
+
+// formatCredential returns the response data for a PEM encoded certificate
+// and private key. The private key is only known when generating a
+// credential.
+func formatCredential(format, certPEM, keyPEM string, ca *caBundle) (map[string]interface{}, error) {
+	chain := ca.chain()
+	data := map[string]interface{}{}
+
+	switch format {
+	case "pem":
+		data["certificate"] = certPEM
+		data["ca_chain"] = chain
+		if keyPEM != "" {
+			data["private_key"] = keyPEM
+		}
+
+	case "pem_bundle":
+		data["certificate"] = keyPEM + certPEM + strings.Join(chain, "")
+		data["ca_chain"] = chain
+		if keyPEM != "" {
+			data["private_key"] = keyPEM
+		}
+
+	case "der":
+		encoded, err := pemToDER(certPEM)
+		if err != nil {
+			return nil, err
+		}
+		data["certificate"] = encoded
+
+		var derChain []string
+		for _, c := range chain {
+			encoded, err := pemToDER(c)
+			if err != nil {
+				return nil, err
+			}
+			derChain = append(derChain, encoded)
+		}
+		data["ca_chain"] = derChain
+
+		if keyPEM != "" {
+			encoded, err := pemToDER(keyPEM)
+			if err != nil {
+				return nil, err
+			}
+			data["private_key"] = encoded
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+
+	return data, nil
+}
+
+// pemToDER returns the base64 encoded DER bytes of a PEM block
+func pemToDER(data string) (string, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return "", fmt.Errorf("unable to decode PEM data")
+	}
+	return base64.StdEncoding.EncodeToString(block.Bytes), nil
+}
+
+func (b *backend) pathCredentialLookup(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	scope := d.Get("scope").(string)
+	role := d.Get("role").(string)
+	serial := d.Get("serial_number").(string)
+	if serial == "" {
+		return logical.ErrorResponse("serial_number is required"), nil
+	}
+	format := d.Get("format").(string)
+	if format != "pem" && format != "pem_bundle" && format != "der" {
+		return logical.ErrorResponse(fmt.Sprintf("invalid format %q", format)), nil
+	}
+
+	entry, err := req.Storage.Get(ctx, credentialPrefix(scope, role)+serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	credential := &credentialEntry{}
+	if err := entry.DecodeJSON(credential); err != nil {
+		return nil, err
+	}
+
+	ca, err := readCA(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if ca == nil {
+		return logical.ErrorResponse("the kmip engine has not been configured"), nil
+	}
+
+	data, err := formatCredential(format, credential.Certificate, "", ca)
+	if err != nil {
+		return nil, err
+	}
+	data["serial_number"] = serial
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathCredentialRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	scope := d.Get("scope").(string)
+	role := d.Get("role").(string)
+	serial := d.Get("serial_number").(string)
+	certificate := d.Get("certificate").(string)
+
+	switch {
+	case serial != "" && certificate != "":
+		return logical.ErrorResponse("only one of serial_number or certificate may be set"), nil
+	case certificate != "":
+		cert, err := parseCertificate(certificate)
+		if err != nil {
+			return logical.ErrorResponse(errwrap.Wrapf("invalid certificate: {{err}}", err).Error()), nil
+		}
+		serial = cert.SerialNumber.String()
+	case serial == "":
+		return logical.ErrorResponse("one of serial_number or certificate is required"), nil
+	}
+	if _, ok := new(big.Int).SetString(serial, 10); !ok {
+		return logical.ErrorResponse(fmt.Sprintf("invalid serial number %q", serial)), nil
+	}
+
+	// Only revoke the certificates of this role
+	owner, err := readSerial(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil || owner.Scope != scope || owner.Role != role {
+		return logical.ErrorResponse(fmt.Sprintf("no certificate with serial number %s in role %q", serial, role)), nil
+	}
+
+	if err := deleteCredential(ctx, req.Storage, scope, role, serial); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathListCredentialsHelpSyn = `
+List the serial numbers of the certificates of a role.
+`
+
+const pathListCredentialsHelpDesc = `
+This path lists the serial numbers of the client certificates generated or
+signed for a role that have not been revoked.
+`
+
+const pathCredentialGenerateHelpSyn = `
+Generate a client certificate for a role.
+`
+
+const pathCredentialGenerateHelpDesc = `
+This path generates a private key and a client certificate the KMIP clients
+use to perform the operations of the role. The private key is only returned
+by this path.
+`
+
+const pathCredentialSignHelpSyn = `
+Sign a certificate signing request for a role.
+`
+
+const pathCredentialSignHelpDesc = `
+This path issues a client certificate for the key of a certificate signing
+request, so that the private key never leaves the KMIP client. The key type
+and bits of the request must match the ones of the role.
+`
+
+const pathCredentialLookupHelpSyn = `
+Read a client certificate of a role.
+`
+
+const pathCredentialLookupHelpDesc = `
+This path returns the client certificate with the given serial number, and
+the CA chain. The private key cannot be read.
+`
+
+const pathCredentialRevokeHelpSyn = `
+Revoke a client certificate of a role.
+`
+
+const pathCredentialRevokeHelpDesc = `
+This path revokes a client certificate, given either its serial number or the
+certificate itself. The KMIP server rejects the connections using a revoked
+certificate.
+`
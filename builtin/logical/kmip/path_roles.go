@@ -0,0 +1,339 @@
+package kmip
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	operationAll  = "operation_all"
+	operationNone = "operation_none"
+)
+
+func rolePrefix(scope string) string {
+	return "roles/" + scope + "/"
+}
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "scope/" + framework.GenericNameRegex("scope") + "/role/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"scope": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the scope.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	fields := map[string]*framework.FieldSchema{
+		"scope": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the scope.",
+		},
+		"role": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the role.",
+		},
+		"tls_client_key_type": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Client certificate key type, "rsa" or "ec". Overrides default_tls_client_key_type.`,
+		},
+		"tls_client_key_bits": &framework.FieldSchema{
+			Type:        framework.TypeInt,
+			Description: "Client certificate key bits. Overrides default_tls_client_key_bits.",
+		},
+		"tls_client_ttl": &framework.FieldSchema{
+			Type:        framework.TypeDurationSecond,
+			Description: "Client certificate TTL. Overrides default_tls_client_ttl.",
+		},
+		operationAll: &framework.FieldSchema{
+			Type:        framework.TypeBool,
+			Description: "Grant all the operations. May not be set with any other operation.",
+		},
+		operationNone: &framework.FieldSchema{
+			Type:        framework.TypeBool,
+			Description: "Grant no operation. May not be set with any other operation.",
+		},
+	}
+	for _, name := range operationNames {
+		fields[name] = &framework.FieldSchema{
+			Type:        framework.TypeBool,
+			Description: "Grant the KMIP operation.",
+		}
+	}
+
+	return &framework.Path{
+		Pattern: "scope/" + framework.GenericNameRegex("scope") + "/role/" + framework.GenericNameRegex("role"),
+		Fields:  fields,
+
+		ExistenceCheck: b.roleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+type roleEntry struct {
+	TLSClientKeyType string        `json:"tls_client_key_type"`
+	TLSClientKeyBits int           `json:"tls_client_key_bits"`
+	TLSClientTTL     time.Duration `json:"tls_client_ttl"`
+
+	// Operations holds the names of the granted operations, or only
+	// operation_all or operation_none
+	Operations []string `json:"operations"`
+}
+
+// allowed returns whether the role grants the KMIP operation
+func (r *roleEntry) allowed(operation uint32) bool {
+	name, ok := operationNames[operation]
+	if !ok {
+		return false
+	}
+	for _, op := range r.Operations {
+		if op == operationAll || op == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clientSettings returns the key type, key bits and TTL of the client
+// certificates of the role
+func (r *roleEntry) clientSettings(config *kmipConfig) (string, int, time.Duration) {
+	keyType, keyBits, ttl := config.DefaultTLSClientKeyType, config.DefaultTLSClientKeyBits, config.DefaultTLSClientTTL
+	if r.TLSClientKeyType != "" {
+		keyType = r.TLSClientKeyType
+	}
+	if r.TLSClientKeyBits != 0 {
+		keyBits = r.TLSClientKeyBits
+	}
+	if r.TLSClientTTL != 0 {
+		ttl = r.TLSClientTTL
+	}
+	return keyType, keyBits, ttl
+}
+
+func readRole(ctx context.Context, s logical.Storage, scope, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, rolePrefix(scope)+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	role := &roleEntry{}
+	if err := entry.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// deleteRole deletes the role and revokes its credentials
+func deleteRole(ctx context.Context, s logical.Storage, scope, name string) error {
+	serials, err := s.List(ctx, credentialPrefix(scope, name))
+	if err != nil {
+		return err
+	}
+	for _, serial := range serials {
+		if err := deleteCredential(ctx, s, scope, name, serial); err != nil {
+			return err
+		}
+	}
+	return s.Delete(ctx, rolePrefix(scope)+name)
+}
+
+func (b *backend) roleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := readRole(ctx, req.Storage, d.Get("scope").(string), d.Get("role").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, rolePrefix(d.Get("scope").(string)))
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := readRole(ctx, req.Storage, d.Get("scope").(string), d.Get("role").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{}
+	if role.TLSClientKeyType != "" {
+		data["tls_client_key_type"] = role.TLSClientKeyType
+	}
+	if role.TLSClientKeyBits != 0 {
+		data["tls_client_key_bits"] = role.TLSClientKeyBits
+	}
+	if role.TLSClientTTL != 0 {
+		data["tls_client_ttl"] = int64(role.TLSClientTTL / time.Second)
+	}
+	for _, op := range role.Operations {
+		data[op] = true
+	}
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	scopeName := d.Get("scope").(string)
+	name := d.Get("role").(string)
+
+	scope, err := readScope(ctx, req.Storage, scopeName)
+	if err != nil {
+		return nil, err
+	}
+	if scope == nil {
+		return logical.ErrorResponse(fmt.Sprintf("scope %q does not exist", scopeName)), nil
+	}
+
+	role, err := readRole(ctx, req.Storage, scopeName, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if v, ok := d.GetOk("tls_client_key_type"); ok {
+		role.TLSClientKeyType = v.(string)
+	}
+	if v, ok := d.GetOk("tls_client_key_bits"); ok {
+		role.TLSClientKeyBits = v.(int)
+	}
+	if v, ok := d.GetOk("tls_client_ttl"); ok {
+		role.TLSClientTTL = time.Duration(v.(int)) * time.Second
+	}
+	if role.TLSClientKeyType != "" || role.TLSClientKeyBits != 0 {
+		// Validate the key against the defaults for the missing setting
+		keyType, keyBits, _ := role.clientSettings(defaultConfig())
+		if err := validateKeyTypeLength(keyType, keyBits); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	if role.TLSClientTTL < 0 {
+		return logical.ErrorResponse("tls_client_ttl cannot be negative"), nil
+	}
+
+	operations, err := roleOperations(role.Operations, d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	role.Operations = operations
+
+	entry, err := logical.StorageEntryJSON(rolePrefix(scopeName)+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// roleOperations applies the operation fields of the request to the current
+// operations of a role. The pseudo-operations operation_all and
+// operation_none replace all the others, and are replaced when individual
+// operations are set.
+func roleOperations(current []string, d *framework.FieldData) ([]string, error) {
+	var pseudo []string
+	for _, name := range []string{operationAll, operationNone} {
+		if v, ok := d.GetOk(name); ok && v.(bool) {
+			pseudo = append(pseudo, name)
+		}
+	}
+
+	granted := map[string]bool{}
+	for _, op := range current {
+		if op != operationAll && op != operationNone {
+			granted[op] = true
+		}
+	}
+	individual := false
+	for _, name := range operationNames {
+		if v, ok := d.GetOk(name); ok {
+			individual = true
+			granted[name] = v.(bool)
+		}
+	}
+
+	switch {
+	case len(pseudo) > 1 || (len(pseudo) == 1 && individual):
+		return nil, fmt.Errorf("%s and %s may not be set with any other operation", operationAll, operationNone)
+	case len(pseudo) == 1:
+		return pseudo, nil
+	case !individual:
+		return current, nil
+	}
+
+	operations := []string{}
+	for name, ok := range granted {
+		if ok {
+			operations = append(operations, name)
+		}
+	}
+	sort.Strings(operations)
+	return operations, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := deleteRole(ctx, req.Storage, d.Get("scope").(string), d.Get("role").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathListRolesHelpSyn = `
+List the roles of a scope.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the roles of a scope.
+`
+
+const pathRoleHelpSyn = `
+Manage the roles of a scope.
+`
+
+const pathRoleHelpDesc = `
+A role grants a set of KMIP operations on the managed objects of its scope to
+the clients using its certificates. Each operation is granted with its
+"operation_" parameter. "operation_all" and "operation_none" grant all or no
+operations, and may not be set with any other operation.
+
+The key type, key bits and TTL of the client certificates of the role default
+to the values set on the "config" path.
+
+Deleting a role revokes its credentials.
+`
@@ -0,0 +1,143 @@
+package kmip
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const scopePrefix = "scopes/"
+
+func pathListScopes(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "scope/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathScopeList,
+		},
+
+		HelpSynopsis:    pathListScopesHelpSyn,
+		HelpDescription: pathListScopesHelpDesc,
+	}
+}
+
+func pathScope(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "scope/" + framework.GenericNameRegex("scope"),
+		Fields: map[string]*framework.FieldSchema{
+			"scope": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the scope.",
+			},
+			"force": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Delete the scope even if it contains managed
+objects, which are deleted as well.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathScopeWrite,
+			logical.DeleteOperation: b.pathScopeDelete,
+		},
+
+		HelpSynopsis:    pathScopeHelpSyn,
+		HelpDescription: pathScopeHelpDesc,
+	}
+}
+
+type scopeEntry struct {
+	Name string `json:"name"`
+}
+
+func readScope(ctx context.Context, s logical.Storage, name string) (*scopeEntry, error) {
+	entry, err := s.Get(ctx, scopePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	scope := &scopeEntry{}
+	if err := entry.DecodeJSON(scope); err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+func (b *backend) pathScopeList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	scopes, err := req.Storage.List(ctx, scopePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(scopes), nil
+}
+
+func (b *backend) pathScopeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("scope").(string)
+
+	entry, err := logical.StorageEntryJSON(scopePrefix+name, &scopeEntry{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathScopeDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("scope").(string)
+
+	objects, err := req.Storage.List(ctx, objectPrefix(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) > 0 && !d.Get("force").(bool) {
+		return logical.ErrorResponse(fmt.Sprintf("scope %q contains %d managed objects, use force to delete it", name, len(objects))), nil
+	}
+
+	roles, err := req.Storage.List(ctx, rolePrefix(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if err := deleteRole(ctx, req.Storage, name, role); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range objects {
+		if err := req.Storage.Delete(ctx, objectPrefix(name)+id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := req.Storage.Delete(ctx, scopePrefix+name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathListScopesHelpSyn = `
+List the scopes.
+`
+
+const pathListScopesHelpDesc = `
+This path lists the scopes partitioning the KMIP managed objects.
+`
+
+const pathScopeHelpSyn = `
+Manage the scopes partitioning the KMIP managed objects.
+`
+
+const pathScopeHelpDesc = `
+A scope is a partition of the KMIP managed objects: the clients of the roles
+of a scope only see the objects of that scope.
+
+Deleting a scope deletes its roles and revokes their credentials. If the scope
+contains managed objects, the deletion fails unless "force" is set, in which
+case the objects are deleted as well.
+`
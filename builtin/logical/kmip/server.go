@@ -0,0 +1,225 @@
+package kmip
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// server is the KMIP server, which serves the managed objects to the clients
+// authenticated with the certificates generated by the engine
+type server struct {
+	logger  log.Logger
+	storage logical.Storage
+	timeout time.Duration
+
+	listeners []net.Listener
+
+	lock  sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+
+	// objectsLock serializes the operations on the managed objects
+	objectsLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// restartServer stops the running server, if any, and starts a new one with
+// the configuration. It must be called with b.lock held.
+func (b *backend) restartServer(ctx context.Context, s logical.Storage, config *kmipConfig) error {
+	if b.server != nil {
+		b.server.stop()
+		b.server = nil
+	}
+
+	ca, err := readCA(ctx, s)
+	if err != nil {
+		return err
+	}
+	if ca == nil {
+		return nil
+	}
+	parsed, err := ca.parse()
+	if err != nil {
+		return err
+	}
+	cert, key, err := parsed.issueServerCertificate(config)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{cert.Raw, parsed.intermediate.Raw},
+				PrivateKey:  key,
+				Leaf:        cert,
+			},
+		},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  parsed.pool(),
+		MinVersion: tlsVersions[config.TLSMinVersion],
+	}
+
+	srv := &server{
+		logger:  b.Logger().Named("server"),
+		storage: s,
+		timeout: config.ConnectionTimeout,
+		conns:   map[net.Conn]struct{}{},
+	}
+	srv.ctx, srv.cancel = context.WithCancel(context.Background())
+
+	for _, addr := range config.ListenAddrs {
+		ln, err := tls.Listen("tcp", addr, tlsConfig)
+		if err != nil {
+			srv.stop()
+			return errwrap.Wrapf("unable to listen on "+addr+": {{err}}", err)
+		}
+		srv.listeners = append(srv.listeners, ln)
+	}
+	for _, ln := range srv.listeners {
+		srv.wg.Add(1)
+		go srv.serve(ln)
+		srv.logger.Info("listening for KMIP requests", "address", ln.Addr().String())
+	}
+
+	b.server = srv
+	return nil
+}
+
+// stop closes the listeners and the connections, and waits for the
+// connection handlers to return
+func (s *server) stop() {
+	s.cancel()
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+
+	s.lock.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *server) serve(ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			s.logger.Error("error accepting connection", "error", err)
+			return
+		}
+
+		// Connections accepted while stopping are closed here, since stop
+		// may already have closed the tracked ones
+		s.lock.Lock()
+		if s.ctx.Err() != nil {
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(conn.(*tls.Conn))
+	}
+}
+
+// client is the scope and role of an authenticated client
+type client struct {
+	serial string
+	scope  string
+	role   string
+}
+
+func (s *server) handleConn(conn *tls.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+	}()
+
+	logger := s.logger.With("remote_addr", conn.RemoteAddr().String())
+
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	if err := conn.Handshake(); err != nil {
+		logger.Debug("TLS handshake failed", "error", err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	// The certificate was issued by the CA, but may have been revoked since
+	peer := conn.ConnectionState().PeerCertificates[0]
+	c := &client{
+		serial: peer.SerialNumber.String(),
+	}
+	if _, err := s.authenticate(c); err != nil {
+		logger.Debug("client rejected", "serial_number", c.serial, "error", err)
+		return
+	}
+
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+
+		response := s.handleMessage(c, msg)
+		encoded, err := response.encode()
+		if err != nil {
+			logger.Error("unable to encode response", "error", err)
+			return
+		}
+		if _, err := conn.Write(encoded); err != nil {
+			return
+		}
+	}
+}
+
+// authenticate looks up the scope and role of the client certificate, and
+// returns the role. It is called for every message, so that revoking the
+// certificate or changing the role applies to open connections.
+func (s *server) authenticate(c *client) (*roleEntry, error) {
+	entry, err := readSerial(s.ctx, s.storage, c.serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, newError(resultReasonPermissionDenied, "the client certificate has been revoked")
+	}
+	role, err := readRole(s.ctx, s.storage, entry.Scope, entry.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, newError(resultReasonPermissionDenied, "the role of the client certificate does not exist")
+	}
+
+	c.scope = entry.Scope
+	c.role = entry.Role
+	return role, nil
+}
@@ -0,0 +1,360 @@
+package kmip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// itemType is the type of a TTLV item
+type itemType byte
+
+const (
+	typeStructure   itemType = 0x01
+	typeInteger     itemType = 0x02
+	typeLongInteger itemType = 0x03
+	typeBigInteger  itemType = 0x04
+	typeEnumeration itemType = 0x05
+	typeBoolean     itemType = 0x06
+	typeTextString  itemType = 0x07
+	typeByteString  itemType = 0x08
+	typeDateTime    itemType = 0x09
+	typeInterval    itemType = 0x0A
+)
+
+// maxMessageLength bounds the size of the messages read from clients
+const maxMessageLength = 1 << 20
+
+// item is a TTLV encoded value. The Go type of Value depends on Type:
+// []*item for structures, int32 for integers and enumerations, int64 for
+// long integers, *big.Int for big integers, bool for booleans, string for
+// text strings, []byte for byte strings, time.Time for date-times, and
+// time.Duration for intervals.
+type item struct {
+	Tag   tag
+	Type  itemType
+	Value interface{}
+}
+
+func structure(t tag, children ...*item) *item {
+	return &item{Tag: t, Type: typeStructure, Value: children}
+}
+
+func integer(t tag, v int32) *item {
+	return &item{Tag: t, Type: typeInteger, Value: v}
+}
+
+func enumeration(t tag, v uint32) *item {
+	return &item{Tag: t, Type: typeEnumeration, Value: int32(v)}
+}
+
+func boolean(t tag, v bool) *item {
+	return &item{Tag: t, Type: typeBoolean, Value: v}
+}
+
+func textString(t tag, v string) *item {
+	return &item{Tag: t, Type: typeTextString, Value: v}
+}
+
+func byteString(t tag, v []byte) *item {
+	return &item{Tag: t, Type: typeByteString, Value: v}
+}
+
+func dateTime(t tag, v time.Time) *item {
+	return &item{Tag: t, Type: typeDateTime, Value: v}
+}
+
+// children returns the items of a structure
+func (i *item) children() []*item {
+	if i == nil {
+		return nil
+	}
+	children, _ := i.Value.([]*item)
+	return children
+}
+
+// child returns the first item of the structure with the tag
+func (i *item) child(t tag) *item {
+	for _, c := range i.children() {
+		if c.Tag == t {
+			return c
+		}
+	}
+	return nil
+}
+
+// all returns the items of the structure with the tag
+func (i *item) all(t tag) []*item {
+	var result []*item
+	for _, c := range i.children() {
+		if c.Tag == t {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// add appends items to a structure
+func (i *item) add(children ...*item) {
+	i.Value = append(i.children(), children...)
+}
+
+func (i *item) int() (int32, bool) {
+	if i == nil {
+		return 0, false
+	}
+	v, ok := i.Value.(int32)
+	return v, ok
+}
+
+func (i *item) enum() (uint32, bool) {
+	v, ok := i.int()
+	return uint32(v), ok
+}
+
+func (i *item) text() (string, bool) {
+	if i == nil {
+		return "", false
+	}
+	v, ok := i.Value.(string)
+	return v, ok
+}
+
+func (i *item) bytes() ([]byte, bool) {
+	if i == nil {
+		return nil, false
+	}
+	v, ok := i.Value.([]byte)
+	return v, ok
+}
+
+// encode returns the TTLV encoding of the item
+func (i *item) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := i.encodeTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (i *item) encodeTo(buf *bytes.Buffer) error {
+	var value []byte
+	switch i.Type {
+	case typeStructure:
+		var children bytes.Buffer
+		for _, c := range i.children() {
+			if err := c.encodeTo(&children); err != nil {
+				return err
+			}
+		}
+		value = children.Bytes()
+	case typeInteger, typeEnumeration:
+		v, ok := i.Value.(int32)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(v))
+	case typeLongInteger:
+		v, ok := i.Value.(int64)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(v))
+	case typeBigInteger:
+		v, ok := i.Value.(*big.Int)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = encodeBigInteger(v)
+	case typeBoolean:
+		v, ok := i.Value.(bool)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 8)
+		if v {
+			value[7] = 1
+		}
+	case typeTextString:
+		v, ok := i.Value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = []byte(v)
+	case typeByteString:
+		v, ok := i.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = v
+	case typeDateTime:
+		v, ok := i.Value.(time.Time)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(v.Unix()))
+	case typeInterval:
+		v, ok := i.Value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("invalid value for tag %s: %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(v/time.Second))
+	default:
+		return fmt.Errorf("invalid type for tag %s: %d", i.Tag, i.Type)
+	}
+
+	var header [8]byte
+	header[0] = byte(i.Tag >> 16)
+	header[1] = byte(i.Tag >> 8)
+	header[2] = byte(i.Tag)
+	header[3] = byte(i.Type)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(value)))
+	buf.Write(header[:])
+	buf.Write(value)
+	if pad := padding(len(value)); pad > 0 {
+		buf.Write(make([]byte, pad))
+	}
+	return nil
+}
+
+// encodeBigInteger returns the two's complement encoding of v, sign extended
+// to a multiple of 8 bytes
+func encodeBigInteger(v *big.Int) []byte {
+	length := (v.BitLen()/8 + 1 + 7) / 8 * 8
+	if v.Sign() >= 0 {
+		out := make([]byte, length)
+		b := v.Bytes()
+		copy(out[length-len(b):], b)
+		return out
+	}
+
+	// Two's complement of negative values: 2^(8*length) + v
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(8*length))
+	b := new(big.Int).Add(modulus, v).Bytes()
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = 0xFF
+	}
+	copy(out[length-len(b):], b)
+	return out
+}
+
+func decodeBigInteger(b []byte) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return v
+}
+
+func padding(length int) int {
+	return (8 - length%8) % 8
+}
+
+// readMessage reads a TTLV encoded item from r
+func readMessage(r io.Reader) (*item, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length > maxMessageLength {
+		return nil, fmt.Errorf("message too large: %d bytes", length)
+	}
+
+	msg := make([]byte, 8+int(length)+padding(int(length)))
+	copy(msg, header[:])
+	if _, err := io.ReadFull(r, msg[8:8+length]); err != nil {
+		return nil, err
+	}
+
+	i, _, err := decode(msg[:8+length])
+	return i, err
+}
+
+// decode decodes the first item of b, and returns the number of bytes read
+func decode(b []byte) (*item, int, error) {
+	if len(b) < 8 {
+		return nil, 0, fmt.Errorf("truncated item header")
+	}
+	i := &item{
+		Tag:  tag(uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])),
+		Type: itemType(b[3]),
+	}
+	length := int(binary.BigEndian.Uint32(b[4:8]))
+	if length > len(b)-8 {
+		return nil, 0, fmt.Errorf("truncated value for tag %s", i.Tag)
+	}
+	value := b[8 : 8+length]
+	read := 8 + length + padding(length)
+	if read > len(b) {
+		// The padding of the last item of a message may be omitted
+		read = len(b)
+	}
+
+	fixedLength := func(expected int) error {
+		if length != expected {
+			return fmt.Errorf("invalid length for tag %s: %d", i.Tag, length)
+		}
+		return nil
+	}
+
+	switch i.Type {
+	case typeStructure:
+		children := []*item{}
+		for len(value) > 0 {
+			c, n, err := decode(value)
+			if err != nil {
+				return nil, 0, err
+			}
+			children = append(children, c)
+			value = value[n:]
+		}
+		i.Value = children
+	case typeInteger, typeEnumeration:
+		if err := fixedLength(4); err != nil {
+			return nil, 0, err
+		}
+		i.Value = int32(binary.BigEndian.Uint32(value))
+	case typeLongInteger:
+		if err := fixedLength(8); err != nil {
+			return nil, 0, err
+		}
+		i.Value = int64(binary.BigEndian.Uint64(value))
+	case typeBigInteger:
+		if length%8 != 0 {
+			return nil, 0, fmt.Errorf("invalid length for tag %s: %d", i.Tag, length)
+		}
+		i.Value = decodeBigInteger(value)
+	case typeBoolean:
+		if err := fixedLength(8); err != nil {
+			return nil, 0, err
+		}
+		i.Value = binary.BigEndian.Uint64(value) != 0
+	case typeTextString:
+		i.Value = string(value)
+	case typeByteString:
+		i.Value = append([]byte(nil), value...)
+	case typeDateTime:
+		if err := fixedLength(8); err != nil {
+			return nil, 0, err
+		}
+		i.Value = time.Unix(int64(binary.BigEndian.Uint64(value)), 0).UTC()
+	case typeInterval:
+		if err := fixedLength(4); err != nil {
+			return nil, 0, err
+		}
+		i.Value = time.Duration(binary.BigEndian.Uint32(value)) * time.Second
+	default:
+		return nil, 0, fmt.Errorf("invalid type for tag %s: %d", i.Tag, i.Type)
+	}
+
+	return i, read, nil
+}
@@ -0,0 +1,128 @@
+package kmip
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTLV_Encode(t *testing.T) {
+	// Examples from section 9.1.2 of the KMIP 1.4 specification
+	cases := map[string]struct {
+		item     *item
+		expected string
+	}{
+		"integer": {
+			integer(0x420020, 8),
+			"42 00 20 | 02 | 00 00 00 04 | 00 00 00 08 00 00 00 00",
+		},
+		"long integer": {
+			&item{Tag: 0x420020, Type: typeLongInteger, Value: int64(123456789000000000)},
+			"42 00 20 | 03 | 00 00 00 08 | 01 B6 9B 4B A5 74 92 00",
+		},
+		"big integer": {
+			&item{Tag: 0x420020, Type: typeBigInteger, Value: bigInt("1234567890000000000000000000")},
+			"42 00 20 | 04 | 00 00 00 10 | 00 00 00 00 03 FD 35 EB 6B C2 DF 46 18 08 00 00",
+		},
+		"enumeration": {
+			enumeration(0x420020, 255),
+			"42 00 20 | 05 | 00 00 00 04 | 00 00 00 FF 00 00 00 00",
+		},
+		"boolean": {
+			boolean(0x420020, true),
+			"42 00 20 | 06 | 00 00 00 08 | 00 00 00 00 00 00 00 01",
+		},
+		"text string": {
+			textString(0x420020, "Hello World"),
+			"42 00 20 | 07 | 00 00 00 0B | 48 65 6C 6C 6F 20 57 6F 72 6C 64 00 00 00 00 00",
+		},
+		"byte string": {
+			byteString(0x420020, []byte{1, 2, 3}),
+			"42 00 20 | 08 | 00 00 00 03 | 01 02 03 00 00 00 00 00",
+		},
+		"date-time": {
+			dateTime(0x420020, time.Date(2008, time.March, 14, 11, 56, 40, 0, time.UTC)),
+			"42 00 20 | 09 | 00 00 00 08 | 00 00 00 00 47 DA 67 F8",
+		},
+		"interval": {
+			&item{Tag: 0x420020, Type: typeInterval, Value: 10 * 24 * time.Hour},
+			"42 00 20 | 0A | 00 00 00 04 | 00 0D 2F 00 00 00 00 00",
+		},
+		"structure": {
+			structure(0x420020,
+				enumeration(0x420004, 254),
+				integer(0x420005, 255),
+			),
+			"42 00 20 | 01 | 00 00 00 20 | 42 00 04 | 05 | 00 00 00 04 | 00 00 00 FE 00 00 00 00 | 42 00 05 | 02 | 00 00 00 04 | 00 00 00 FF 00 00 00 00",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			expected, err := hex.DecodeString(strings.NewReplacer(" ", "", "|", "").Replace(tc.expected))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encoded, err := tc.item.encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, expected) {
+				t.Fatalf("bad encoding\nexpected: %X\ngot:      %X", expected, encoded)
+			}
+
+			decoded, err := readMessage(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tc.item) {
+				t.Fatalf("bad decoding\nexpected: %#v\ngot:      %#v", tc.item, decoded)
+			}
+		})
+	}
+}
+
+func bigInt(s string) *big.Int {
+	v, _ := new(big.Int).SetString(s, 10)
+	return v
+}
+
+func TestTTLV_BigInteger(t *testing.T) {
+	for _, v := range []int64{0, 1, -1, 127, 128, -128, -129, 1 << 62, -(1 << 62)} {
+		expected := big.NewInt(v)
+		encoded := encodeBigInteger(expected)
+		if len(encoded)%8 != 0 {
+			t.Fatalf("%d: bad encoding length %d", v, len(encoded))
+		}
+		if decoded := decodeBigInteger(encoded); decoded.Cmp(expected) != 0 {
+			t.Fatalf("%d: decoded %s", v, decoded)
+		}
+	}
+}
+
+func TestTTLV_DecodeErrors(t *testing.T) {
+	cases := map[string]string{
+		"truncated header":   "42 00 20 02",
+		"truncated value":    "42 00 20 02 00 00 00 04 00 00",
+		"bad integer length": "42 00 20 02 00 00 00 08 00 00 00 00 00 00 00 08",
+		"bad type":           "42 00 20 0F 00 00 00 00",
+		"bad child":          "42 00 20 01 00 00 00 04 42 00 20 02",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := hex.DecodeString(strings.Replace(input, " ", "", -1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := decode(b); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Factory returns a Kubernetes backend that satisfies the logical.Backend
+// interface
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend returns the configured Kubernetes backend
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				configPath,
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretToken(&b),
+		},
+		BackendType: logical.TypeLogical,
+	}
+
+	b.localTokenPath = localTokenPath
+	b.localCACertPath = localCACertPath
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// localTokenPath and localCACertPath are where the credentials of the pod
+	// running Vault are read from, when they aren't configured explicitly
+	localTokenPath  string
+	localCACertPath string
+}
+
+const backendHelp = `
+The Kubernetes secrets engine generates Kubernetes service account tokens.
+The tokens are either issued for an existing service account, or for a
+service account created for each request and bound to a Kubernetes role.
+
+After mounting this secrets engine, configure how to reach the cluster
+with the "config" path, and create roles with the "roles/" path.
+Credentials are then generated with the "creds/" path.
+`
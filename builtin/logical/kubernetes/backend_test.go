@@ -0,0 +1,258 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// fakeKubernetes serves the subset of the Kubernetes API used by the backend
+type fakeKubernetes struct {
+	sync.Mutex
+
+	serviceAccounts map[string]serviceAccount
+	bindings        map[string]roleBinding
+	tokenRequests   map[string]tokenRequestSpec
+}
+
+func newFakeKubernetes(t *testing.T) (*fakeKubernetes, *httptest.Server) {
+	k := &fakeKubernetes{
+		serviceAccounts: map[string]serviceAccount{},
+		bindings:        map[string]roleBinding{},
+		tokenRequests:   map[string]tokenRequestSpec{},
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer vault-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		k.Lock()
+		defer k.Unlock()
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		// /api/v1/namespaces/<ns>/serviceaccounts/<name>/token
+		case len(parts) == 7 && parts[6] == "token" && r.Method == http.MethodPost:
+			key := parts[3] + "/" + parts[5]
+			if _, ok := k.serviceAccounts[key]; !ok && parts[5] != "existing" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var req tokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			k.tokenRequests[key] = req.Spec
+			req.Status = &tokenRequestStatus{
+				Token:               "token-" + parts[5],
+				ExpirationTimestamp: time.Now().Add(time.Duration(req.Spec.ExpirationSeconds) * time.Second),
+			}
+			json.NewEncoder(w).Encode(req)
+
+		// /api/v1/namespaces/<ns>/serviceaccounts[/<name>]
+		case len(parts) >= 5 && parts[4] == "serviceaccounts":
+			switch r.Method {
+			case http.MethodPost:
+				var sa serviceAccount
+				if err := json.NewDecoder(r.Body).Decode(&sa); err != nil {
+					t.Fatal(err)
+				}
+				k.serviceAccounts[parts[3]+"/"+sa.Metadata.Name] = sa
+			case http.MethodDelete:
+				delete(k.serviceAccounts, parts[3]+"/"+parts[5])
+			}
+
+		// /apis/rbac.authorization.k8s.io/v1/[namespaces/<ns>/]<kind>[/<name>]
+		case parts[0] == "apis":
+			key := filepath.Join(parts[3:]...)
+			switch r.Method {
+			case http.MethodPost:
+				var binding roleBinding
+				if err := json.NewDecoder(r.Body).Decode(&binding); err != nil {
+					t.Fatal(err)
+				}
+				k.bindings[key+"/"+binding.Metadata.Name] = binding
+			case http.MethodDelete:
+				delete(k.bindings, key)
+			}
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return k, server
+}
+
+func TestBackend_Creds(t *testing.T) {
+	k, server := newFakeKubernetes(t)
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	b.localTokenPath = "/nonexistent"
+	b.localCACertPath = "/nonexistent"
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Storage:     config.StorageView,
+			Data:        data,
+			DisplayName: "token-Test",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error: %s: %#v", path, resp)
+		}
+	}
+	revoke := func(secret *logical.Secret) {
+		t.Helper()
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Storage:   config.StorageView,
+			Secret:    secret,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_ca_cert": caCert,
+	})
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":     server.URL,
+		"kubernetes_ca_cert":  caCert,
+		"service_account_jwt": "vault-token",
+	})
+	resp := request(logical.ReadOperation, "config", nil)
+	if resp.Data["kubernetes_host"] != server.URL {
+		t.Fatalf("bad config: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["service_account_jwt"]; ok {
+		t.Fatal("expected the service account JWT not to be returned")
+	}
+
+	// Roles issue tokens for one of an existing service account or a
+	// Kubernetes role
+	expectError(logical.UpdateOperation, "roles/bad", map[string]interface{}{
+		"allowed_kubernetes_namespaces": "app",
+	})
+	expectError(logical.UpdateOperation, "roles/bad", map[string]interface{}{
+		"allowed_kubernetes_namespaces": "app",
+		"service_account_name":          "existing",
+		"kubernetes_role_name":          "reader",
+	})
+	request(logical.UpdateOperation, "roles/existing", map[string]interface{}{
+		"allowed_kubernetes_namespaces": "app",
+		"service_account_name":          "existing",
+		"token_default_audiences":       "vault",
+	})
+	request(logical.UpdateOperation, "roles/reader", map[string]interface{}{
+		"allowed_kubernetes_namespaces": "*",
+		"kubernetes_role_name":          "reader",
+		"kubernetes_role_type":          "ClusterRole",
+		"token_default_ttl":             "1h",
+		"token_max_ttl":                 "2h",
+		"extra_labels":                  "team=platform",
+	})
+	resp = request(logical.ListOperation, "roles/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad roles: %#v", keys)
+	}
+
+	// Tokens of existing service accounts aren't tied to any object
+	expectError(logical.UpdateOperation, "creds/existing", map[string]interface{}{
+		"kubernetes_namespace": "other",
+	})
+	resp = request(logical.UpdateOperation, "creds/existing", map[string]interface{}{
+		"kubernetes_namespace": "app",
+	})
+	if resp.Data["service_account_token"] != "token-existing" || resp.Secret.TTL < 24*time.Hour-time.Minute {
+		t.Fatalf("bad creds: %#v %#v", resp.Data, resp.Secret)
+	}
+	if spec := k.tokenRequests["app/existing"]; len(spec.Audiences) != 1 || spec.Audiences[0] != "vault" {
+		t.Fatalf("bad token request: %#v", spec)
+	}
+	revoke(resp.Secret)
+
+	// Otherwise a service account is created and bound to the role
+	resp = request(logical.UpdateOperation, "creds/reader", map[string]interface{}{
+		"kubernetes_namespace": "dev",
+		"ttl":                  "3h",
+	})
+	name := resp.Data["service_account_name"].(string)
+	if !strings.HasPrefix(name, "v-reader-token-test-") || len(name) > maxNameLength {
+		t.Fatalf("bad service account name: %q", name)
+	}
+	if resp.Secret.TTL > 2*time.Hour || resp.Secret.TTL < 2*time.Hour-time.Minute || resp.Secret.Renewable {
+		t.Fatalf("bad secret: %#v", resp.Secret)
+	}
+	sa, ok := k.serviceAccounts["dev/"+name]
+	if !ok || sa.Metadata.Labels[managedByLabel] != managedByValue || sa.Metadata.Labels["team"] != "platform" {
+		t.Fatalf("bad service account: %#v", sa)
+	}
+	binding, ok := k.bindings[fmt.Sprintf("namespaces/dev/rolebindings/%s", name)]
+	if !ok || binding.Kind != "RoleBinding" || binding.RoleRef.Kind != "ClusterRole" || binding.Subjects[0].Name != name {
+		t.Fatalf("bad role binding: %#v", k.bindings)
+	}
+
+	revoke(resp.Secret)
+	if len(k.serviceAccounts) != 0 || len(k.bindings) != 0 {
+		t.Fatalf("expected the objects to be deleted: %#v %#v", k.serviceAccounts, k.bindings)
+	}
+
+	// ClusterRoleBindings are only allowed for ClusterRoles
+	expectError(logical.UpdateOperation, "creds/existing", map[string]interface{}{
+		"kubernetes_namespace": "app",
+		"cluster_role_binding": true,
+	})
+	resp = request(logical.UpdateOperation, "creds/reader", map[string]interface{}{
+		"kubernetes_namespace": "dev",
+		"cluster_role_binding": true,
+	})
+	name = resp.Data["service_account_name"].(string)
+	if binding, ok := k.bindings["clusterrolebindings/"+name]; !ok || binding.Kind != "ClusterRoleBinding" {
+		t.Fatalf("bad role binding: %#v", k.bindings)
+	}
+}
+
+func TestGenerateName(t *testing.T) {
+	name, err := generateName("My_Role", strings.Repeat("display.name", 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(name) > maxNameLength || !strings.HasPrefix(name, "v-my-role-display-name") || invalidNameChars.MatchString(name) {
+		t.Fatalf("bad name: %q", name)
+	}
+}
@@ -0,0 +1,246 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+const rbacAPIGroup = "rbac.authorization.k8s.io"
+
+// kubeClient is a minimal client for the parts of the Kubernetes API used by
+// this backend
+type kubeClient struct {
+	host       string
+	token      string
+	httpClient *http.Client
+}
+
+func newKubeClient(host, caCert, token string) (*kubeClient, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caCert != "" {
+		certPool := x509.NewCertPool()
+		if ok := certPool.AppendCertsFromPEM([]byte(caCert)); !ok {
+			return nil, fmt.Errorf("kubernetes_ca_cert could not be parsed")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+
+	return &kubeClient{
+		host:  host,
+		token: token,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+	}, nil
+}
+
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type serviceAccount struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+}
+
+type roleRef struct {
+	APIGroup string `json:"apiGroup"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+}
+
+type subject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// roleBinding is either a RoleBinding or a ClusterRoleBinding
+type roleBinding struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	RoleRef    roleRef    `json:"roleRef"`
+	Subjects   []subject  `json:"subjects"`
+}
+
+type tokenRequestSpec struct {
+	Audiences         []string `json:"audiences,omitempty"`
+	ExpirationSeconds int64    `json:"expirationSeconds"`
+}
+
+type tokenRequestStatus struct {
+	Token               string    `json:"token"`
+	ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+}
+
+type tokenRequest struct {
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Spec       tokenRequestSpec    `json:"spec"`
+	Status     *tokenRequestStatus `json:"status,omitempty"`
+}
+
+// status is the body of the API server's error responses
+type status struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+}
+
+// kubeError is returned for error responses of the API server
+type kubeError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *kubeError) Error() string {
+	return fmt.Sprintf("kubernetes API request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	kerr, ok := err.(*kubeError)
+	return ok && kerr.StatusCode == http.StatusNotFound
+}
+
+func (c *kubeClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.host+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var s status
+		if err := json.Unmarshal(respBody, &s); err != nil || s.Message == "" {
+			s.Message = http.StatusText(resp.StatusCode)
+		}
+		return &kubeError{
+			StatusCode: resp.StatusCode,
+			Message:    s.Message,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *kubeClient) createServiceAccount(ctx context.Context, meta objectMeta) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts", url.PathEscape(meta.Namespace)), &serviceAccount{
+		APIVersion: "v1",
+		Kind:       "ServiceAccount",
+		Metadata:   meta,
+	}, nil)
+}
+
+func (c *kubeClient) deleteServiceAccount(ctx context.Context, namespace, name string) error {
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts/%s", url.PathEscape(namespace), url.PathEscape(name)), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// bindingPath returns the API path of the RoleBindings in the namespace, or
+// of the ClusterRoleBindings if the namespace is empty
+func bindingPath(namespace string) string {
+	if namespace == "" {
+		return fmt.Sprintf("/apis/%s/v1/clusterrolebindings", rbacAPIGroup)
+	}
+	return fmt.Sprintf("/apis/%s/v1/namespaces/%s/rolebindings", rbacAPIGroup, url.PathEscape(namespace))
+}
+
+// createRoleBinding binds the role to the service account, with a
+// ClusterRoleBinding if meta has no namespace
+func (c *kubeClient) createRoleBinding(ctx context.Context, meta objectMeta, role roleRef, sa subject) error {
+	kind := "RoleBinding"
+	if meta.Namespace == "" {
+		kind = "ClusterRoleBinding"
+	}
+	return c.do(ctx, http.MethodPost, bindingPath(meta.Namespace), &roleBinding{
+		APIVersion: rbacAPIGroup + "/v1",
+		Kind:       kind,
+		Metadata:   meta,
+		RoleRef:    role,
+		Subjects:   []subject{sa},
+	}, nil)
+}
+
+func (c *kubeClient) deleteRoleBinding(ctx context.Context, namespace, name string) error {
+	err := c.do(ctx, http.MethodDelete, bindingPath(namespace)+"/"+url.PathEscape(name), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// createToken requests a token for the service account with the TokenRequest
+// API
+func (c *kubeClient) createToken(ctx context.Context, namespace, name string, ttl time.Duration, audiences []string) (*tokenRequestStatus, error) {
+	var out tokenRequest
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts/%s/token", url.PathEscape(namespace), url.PathEscape(name)), &tokenRequest{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenRequest",
+		Spec: tokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: int64(ttl.Seconds()),
+		},
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	if out.Status == nil || out.Status.Token == "" {
+		return nil, fmt.Errorf("no token in the kubernetes API response")
+	}
+
+	return out.Status, nil
+}
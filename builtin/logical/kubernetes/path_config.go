@@ -0,0 +1,209 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configPath = "config"
+
+	localTokenPath  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	localCACertPath = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// kubeConfig holds how Vault reaches the Kubernetes API server
+type kubeConfig struct {
+	Host              string `json:"kubernetes_host"`
+	CACert            string `json:"kubernetes_ca_cert"`
+	ServiceAccountJWT string `json:"service_account_jwt"`
+	DisableLocalCAJWT bool   `json:"disable_local_ca_jwt"`
+}
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: configPath,
+		Fields: map[string]*framework.FieldSchema{
+			"kubernetes_host": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Address of the Kubernetes API server, e.g. https://10.0.0.1:443.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Kubernetes Host",
+				},
+			},
+			"kubernetes_ca_cert": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM encoded CA certificate used to verify the Kubernetes API
+server certificate. Defaults to the CA certificate of the pod Vault runs in,
+unless disable_local_ca_jwt is set.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Kubernetes CA Certificate",
+				},
+			},
+			"service_account_jwt": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Token Vault uses to call the Kubernetes API. Defaults to the
+service account token of the pod Vault runs in, unless disable_local_ca_jwt
+is set.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:      "Service Account JWT",
+					Sensitive: true,
+				},
+			},
+			"disable_local_ca_jwt": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Disable defaulting to the credentials of the pod Vault runs in.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Disable Local CA and JWT",
+				},
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+			logical.DeleteOperation: b.pathConfigDelete,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) readConfig(ctx context.Context, s logical.Storage) (*kubeConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	config := &kubeConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("error reading kubernetes configuration: {{err}}", err)
+	}
+
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The service account JWT is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"kubernetes_host":      config.Host,
+			"kubernetes_ca_cert":   config.CACert,
+			"disable_local_ca_jwt": config.DisableLocalCAJWT,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &kubeConfig{}
+	}
+
+	if hostRaw, ok := d.GetOk("kubernetes_host"); ok {
+		config.Host = strings.TrimSuffix(hostRaw.(string), "/")
+	}
+	if caCertRaw, ok := d.GetOk("kubernetes_ca_cert"); ok {
+		config.CACert = caCertRaw.(string)
+	}
+	if jwtRaw, ok := d.GetOk("service_account_jwt"); ok {
+		config.ServiceAccountJWT = jwtRaw.(string)
+	}
+	if disableRaw, ok := d.GetOk("disable_local_ca_jwt"); ok {
+		config.DisableLocalCAJWT = disableRaw.(bool)
+	}
+
+	if config.Host == "" {
+		return logical.ErrorResponse("kubernetes_host is required"), nil
+	}
+	if config.CACert != "" {
+		if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(config.CACert)); !ok {
+			return logical.ErrorResponse("kubernetes_ca_cert could not be parsed"), nil
+		}
+	}
+	if config.DisableLocalCAJWT && config.ServiceAccountJWT == "" {
+		return logical.ErrorResponse("service_account_jwt is required when disable_local_ca_jwt is set"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configPath); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// client returns a Kubernetes API client for the configuration. The
+// credentials of the local pod are read on every call as they're rotated by
+// Kubernetes.
+func (b *backend) client(ctx context.Context, s logical.Storage) (*kubeClient, error) {
+	config, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("the kubernetes secrets engine is not configured")
+	}
+
+	caCert := config.CACert
+	token := config.ServiceAccountJWT
+	if !config.DisableLocalCAJWT {
+		if caCert == "" {
+			if contents, err := ioutil.ReadFile(b.localCACertPath); err == nil {
+				caCert = string(contents)
+			}
+		}
+		if token == "" {
+			contents, err := ioutil.ReadFile(b.localTokenPath)
+			if err != nil {
+				return nil, errwrap.Wrapf("no service_account_jwt configured, and the local service account token could not be read: {{err}}", err)
+			}
+			token = strings.TrimSpace(string(contents))
+		}
+	}
+
+	return newKubeClient(config.Host, caCert, token)
+}
+
+const pathConfigHelpSyn = `
+Configure how to reach the Kubernetes API server.
+`
+
+const pathConfigHelpDesc = `
+This path configures the address of the Kubernetes API server, and the
+credentials Vault uses to manage service accounts and role bindings. When
+Vault runs in a Kubernetes pod, the CA certificate and token of the pod's
+service account are used unless they are configured explicitly.
+`
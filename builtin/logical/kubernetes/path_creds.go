@@ -0,0 +1,238 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// minTokenTTL is the shortest lifetime the TokenRequest API accepts
+	minTokenTTL = 10 * time.Minute
+
+	// maxNameLength is the maximum length of the names of the objects
+	// created by Vault, so that they're valid DNS labels
+	maxNameLength = 63
+
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "HashiCorp-Vault"
+)
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+			"kubernetes_namespace": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Kubernetes namespace of the service account. Must be allowed by the role.",
+			},
+			"cluster_role_binding": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If true, bind the role with a ClusterRoleBinding
+rather than with a RoleBinding in kubernetes_namespace. Only valid for
+roles with kubernetes_role_type set to "ClusterRole".`,
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "TTL of the generated token. Defaults to the role's token_default_ttl.",
+			},
+			"audiences": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Audiences of the generated token. Defaults to the role's token_default_audiences.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCredsCreate,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsCreate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("name").(string)
+	role, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q does not exist", roleName)), nil
+	}
+
+	namespace := d.Get("kubernetes_namespace").(string)
+	if namespace == "" {
+		return logical.ErrorResponse("kubernetes_namespace is required"), nil
+	}
+	if !role.namespaceAllowed(namespace) {
+		return logical.ErrorResponse(fmt.Sprintf("kubernetes_namespace %q is not allowed by role %q", namespace, roleName)), nil
+	}
+	clusterRoleBinding := d.Get("cluster_role_binding").(bool)
+	if clusterRoleBinding && (role.KubernetesRoleName == "" || role.KubernetesRoleType != kindClusterRole) {
+		return logical.ErrorResponse(`cluster_role_binding is only valid for roles with kubernetes_role_type set to "ClusterRole"`), nil
+	}
+
+	ttl := b.tokenTTL(role, time.Duration(d.Get("ttl").(int))*time.Second)
+	audiences := role.TokenDefaultAudiences
+	if audiencesRaw, ok := d.GetOk("audiences"); ok {
+		audiences = audiencesRaw.([]string)
+	}
+
+	c, err := b.client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	internalData := map[string]interface{}{
+		"kubernetes_namespace":    namespace,
+		"created_service_account": role.ServiceAccountName == "",
+	}
+
+	saName := role.ServiceAccountName
+	if saName == "" {
+		saName, err = generateName(roleName, req.DisplayName)
+		if err != nil {
+			return nil, err
+		}
+		meta := role.objectMeta(saName, namespace)
+		if err := c.createServiceAccount(ctx, meta); err != nil {
+			return nil, errwrap.Wrapf("error creating the service account: {{err}}", err)
+		}
+
+		if clusterRoleBinding {
+			meta.Namespace = ""
+		}
+		err := c.createRoleBinding(ctx, meta, roleRef{
+			APIGroup: rbacAPIGroup,
+			Kind:     role.KubernetesRoleType,
+			Name:     role.KubernetesRoleName,
+		}, subject{
+			Kind:      "ServiceAccount",
+			Name:      saName,
+			Namespace: namespace,
+		})
+		if err != nil {
+			err = errwrap.Wrapf("error creating the role binding: {{err}}", err)
+			if cleanupErr := c.deleteServiceAccount(ctx, namespace, saName); cleanupErr != nil {
+				err = multierror.Append(err, cleanupErr)
+			}
+			return nil, err
+		}
+		internalData["role_binding_name"] = saName
+		internalData["role_binding_namespace"] = meta.Namespace
+	}
+	internalData["service_account_name"] = saName
+
+	token, err := c.createToken(ctx, namespace, saName, ttl, audiences)
+	if err != nil {
+		err = errwrap.Wrapf("error creating the service account token: {{err}}", err)
+		if cleanupErr := b.cleanupCreds(ctx, c, internalData); cleanupErr != nil {
+			err = multierror.Append(err, cleanupErr)
+		}
+		return nil, err
+	}
+
+	resp := b.Secret(SecretTokenType).Response(map[string]interface{}{
+		"service_account_token":     token.Token,
+		"service_account_name":      saName,
+		"service_account_namespace": namespace,
+	}, internalData)
+
+	// The API server may shorten the lifetime of the token
+	resp.Secret.TTL = ttl
+	if !token.ExpirationTimestamp.IsZero() {
+		if remaining := time.Until(token.ExpirationTimestamp); remaining < ttl {
+			resp.Secret.TTL = remaining
+		}
+	}
+	resp.Secret.MaxTTL = resp.Secret.TTL
+	resp.Secret.Renewable = false
+
+	return resp, nil
+}
+
+// tokenTTL resolves the lifetime of a token from the requested TTL, the
+// role's and the mount's settings
+func (b *backend) tokenTTL(role *roleEntry, requested time.Duration) time.Duration {
+	ttl := requested
+	if ttl == 0 {
+		ttl = role.TokenDefaultTTL
+	}
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+
+	if role.TokenMaxTTL > 0 && ttl > role.TokenMaxTTL {
+		ttl = role.TokenMaxTTL
+	}
+	if maxTTL := b.System().MaxLeaseTTL(); maxTTL > 0 && ttl > maxTTL {
+		ttl = maxTTL
+	}
+	if ttl < minTokenTTL {
+		ttl = minTokenTTL
+	}
+
+	return ttl
+}
+
+// objectMeta returns the metadata of the objects created for the role
+func (r *roleEntry) objectMeta(name, namespace string) objectMeta {
+	labels := map[string]string{}
+	for k, v := range r.ExtraLabels {
+		labels[k] = v
+	}
+	labels[managedByLabel] = managedByValue
+
+	return objectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      labels,
+		Annotations: r.ExtraAnnotations,
+	}
+}
+
+// generateName returns a unique name for the objects created for a request,
+// which is a valid DNS label
+func generateName(roleName, displayName string) (string, error) {
+	suffix, err := base62.Random(8)
+	if err != nil {
+		return "", err
+	}
+	suffix = fmt.Sprintf("%d-%s", time.Now().Unix(), strings.ToLower(suffix))
+
+	prefix := "v-" + roleName
+	if displayName != "" {
+		prefix += "-" + displayName
+	}
+	prefix = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(prefix), "-"), "-")
+	if max := maxNameLength - len(suffix) - 1; len(prefix) > max {
+		prefix = strings.TrimRight(prefix[:max], "-")
+	}
+
+	return prefix + "-" + suffix, nil
+}
+
+const pathCredsHelpSyn = `
+Request a Kubernetes service account token for a role.
+`
+
+const pathCredsHelpDesc = `
+This path generates a service account token in the requested namespace for
+the role. If the role binds a Kubernetes role, a service account and its
+binding are created first, and deleted when the lease is revoked.
+`
@@ -0,0 +1,264 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	rolesPath = "roles/"
+
+	kindRole        = "Role"
+	kindClusterRole = "ClusterRole"
+)
+
+// roleEntry describes the service accounts whose tokens a role issues
+type roleEntry struct {
+	AllowedNamespaces     []string          `json:"allowed_kubernetes_namespaces"`
+	ServiceAccountName    string            `json:"service_account_name"`
+	KubernetesRoleName    string            `json:"kubernetes_role_name"`
+	KubernetesRoleType    string            `json:"kubernetes_role_type"`
+	TokenDefaultTTL       time.Duration     `json:"token_default_ttl"`
+	TokenMaxTTL           time.Duration     `json:"token_max_ttl"`
+	TokenDefaultAudiences []string          `json:"token_default_audiences"`
+	ExtraLabels           map[string]string `json:"extra_labels"`
+	ExtraAnnotations      map[string]string `json:"extra_annotations"`
+}
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: rolesPath + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+			"allowed_kubernetes_namespaces": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Kubernetes namespaces in which credentials can be
+generated. If "*", all namespaces are allowed.`,
+			},
+			"service_account_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Existing service account to generate tokens for.
+Mutually exclusive with kubernetes_role_name.`,
+			},
+			"kubernetes_role_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Existing Kubernetes Role or ClusterRole to bind to
+a service account created for each request. Mutually exclusive with
+service_account_name.`,
+			},
+			"kubernetes_role_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     kindRole,
+				Description: `Type of kubernetes_role_name, either "Role" or "ClusterRole". Defaults to "Role".`,
+			},
+			"token_default_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default TTL of the generated tokens. Defaults to the mount's default TTL.",
+			},
+			"token_max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum TTL of the generated tokens. Defaults to the mount's maximum TTL.",
+			},
+			"token_default_audiences": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Audiences of the generated tokens when none are requested. Defaults to the API server's audiences.",
+			},
+			"extra_labels": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: "Additional labels to set on the service accounts and role bindings created by Vault.",
+			},
+			"extra_annotations": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: "Additional annotations to set on the service accounts and role bindings created by Vault.",
+			},
+		},
+
+		ExistenceCheck: b.roleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRolesHelpSyn,
+		HelpDescription: pathRolesHelpDesc,
+	}
+}
+
+func (b *backend) roleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) getRole(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, rolesPath+name)
+	if err != nil {
+		return nil, errwrap.Wrapf("error retrieving role: {{err}}", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	role := &roleEntry{}
+	if err := entry.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, rolesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed_kubernetes_namespaces": role.AllowedNamespaces,
+			"service_account_name":          role.ServiceAccountName,
+			"kubernetes_role_name":          role.KubernetesRoleName,
+			"kubernetes_role_type":          role.KubernetesRoleType,
+			"token_default_ttl":             int64(role.TokenDefaultTTL.Seconds()),
+			"token_max_ttl":                 int64(role.TokenMaxTTL.Seconds()),
+			"token_default_audiences":       role.TokenDefaultAudiences,
+			"extra_labels":                  role.ExtraLabels,
+			"extra_annotations":             role.ExtraAnnotations,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{
+			KubernetesRoleType: kindRole,
+		}
+	}
+
+	if namespacesRaw, ok := d.GetOk("allowed_kubernetes_namespaces"); ok {
+		role.AllowedNamespaces = namespacesRaw.([]string)
+	}
+	if saNameRaw, ok := d.GetOk("service_account_name"); ok {
+		role.ServiceAccountName = saNameRaw.(string)
+	}
+	if roleNameRaw, ok := d.GetOk("kubernetes_role_name"); ok {
+		role.KubernetesRoleName = roleNameRaw.(string)
+	}
+	if roleTypeRaw, ok := d.GetOk("kubernetes_role_type"); ok {
+		role.KubernetesRoleType = roleTypeRaw.(string)
+	}
+	if ttlRaw, ok := d.GetOk("token_default_ttl"); ok {
+		role.TokenDefaultTTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("token_max_ttl"); ok {
+		role.TokenMaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if audiencesRaw, ok := d.GetOk("token_default_audiences"); ok {
+		role.TokenDefaultAudiences = audiencesRaw.([]string)
+	}
+	if labelsRaw, ok := d.GetOk("extra_labels"); ok {
+		role.ExtraLabels = labelsRaw.(map[string]string)
+	}
+	if annotationsRaw, ok := d.GetOk("extra_annotations"); ok {
+		role.ExtraAnnotations = annotationsRaw.(map[string]string)
+	}
+
+	if len(role.AllowedNamespaces) == 0 {
+		return logical.ErrorResponse("allowed_kubernetes_namespaces is required"), nil
+	}
+	switch {
+	case role.ServiceAccountName == "" && role.KubernetesRoleName == "":
+		return logical.ErrorResponse("one of service_account_name and kubernetes_role_name is required"), nil
+	case role.ServiceAccountName != "" && role.KubernetesRoleName != "":
+		return logical.ErrorResponse("only one of service_account_name and kubernetes_role_name can be set"), nil
+	}
+	if role.KubernetesRoleType != kindRole && role.KubernetesRoleType != kindClusterRole {
+		return logical.ErrorResponse(fmt.Sprintf("kubernetes_role_type must be %q or %q", kindRole, kindClusterRole)), nil
+	}
+	if role.TokenMaxTTL > 0 && role.TokenDefaultTTL > role.TokenMaxTTL {
+		return logical.ErrorResponse("token_default_ttl must be less than or equal to token_max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(rolesPath+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, rolesPath+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// namespaceAllowed reports whether credentials can be generated in the
+// namespace
+func (r *roleEntry) namespaceAllowed(namespace string) bool {
+	return strutil.StrListContains(r.AllowedNamespaces, "*") || strutil.StrListContains(r.AllowedNamespaces, namespace)
+}
+
+const pathListRolesHelpSyn = `List the existing roles in this backend.`
+
+const pathListRolesHelpDesc = `Roles will be listed by the role name.`
+
+const pathRolesHelpSyn = `
+Manage the roles that can be used to generate Kubernetes credentials.
+`
+
+const pathRolesHelpDesc = `
+This path lets you manage the roles used to generate service account tokens.
+A role either issues tokens for an existing service account, set with
+"service_account_name", or creates a service account for each request and
+binds it to the Kubernetes Role or ClusterRole set with
+"kubernetes_role_name". The created service account and its binding are
+deleted when the lease expires or is revoked.
+`
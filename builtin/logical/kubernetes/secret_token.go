@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	SecretTokenType = "service_account_token"
+)
+
+func secretToken(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretTokenType,
+		Fields: map[string]*framework.FieldSchema{
+			"service_account_token": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Service account token",
+			},
+		},
+
+		Revoke: b.secretTokenRevoke,
+	}
+}
+
+// secretTokenRevoke deletes the service account and role binding created for
+// the lease. Tokens of existing service accounts can't be revoked and expire
+// on their own.
+func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	created, _ := req.Secret.InternalData["created_service_account"].(bool)
+	if !created {
+		return nil, nil
+	}
+
+	c, err := b.client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, b.cleanupCreds(ctx, c, req.Secret.InternalData)
+}
+
+// cleanupCreds deletes the objects recorded in the internal data of a lease
+func (b *backend) cleanupCreds(ctx context.Context, c *kubeClient, internalData map[string]interface{}) error {
+	var result *multierror.Error
+
+	if bindingName, ok := internalData["role_binding_name"].(string); ok && bindingName != "" {
+		bindingNamespace, _ := internalData["role_binding_namespace"].(string)
+		if err := c.deleteRoleBinding(ctx, bindingNamespace, bindingName); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	if created, _ := internalData["created_service_account"].(bool); created {
+		namespace, _ := internalData["kubernetes_namespace"].(string)
+		name, _ := internalData["service_account_name"].(string)
+		if namespace != "" && name != "" {
+			if err := c.deleteServiceAccount(ctx, namespace, name); err != nil {
+				result = multierror.Append(result, err)
+			}
+		}
+	}
+
+	return result.ErrorOrNil()
+}
@@ -0,0 +1,563 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeAccountPrefix       = "acme/accounts/"
+	acmeAccountOrdersPrefix = "acme/account-orders/"
+	acmeOrderPrefix         = "acme/orders/"
+	acmeAuthzPrefix         = "acme/authorizations/"
+	acmeCertPrefix          = "acme/certs/"
+
+	// acmeOrderLifetime is how long clients have to fulfill the challenges
+	// of an order and finalize it
+	acmeOrderLifetime = 24 * time.Hour
+
+	// acmeNonceLifetime is how long a nonce can be used after being issued,
+	// acmeMaxNonces how many can be outstanding at once
+	acmeNonceLifetime = 30 * time.Minute
+	acmeMaxNonces     = 100000
+
+	// acmeValidationTimeout bounds the time spent fetching the response to
+	// a challenge
+	acmeValidationTimeout = 10 * time.Second
+)
+
+const (
+	acmeStatusPending     = "pending"
+	acmeStatusProcessing  = "processing"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+)
+
+const (
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+)
+
+// acmeError is an error reported to ACME clients as a problem document, as
+// described in RFC 8555 section 6.7
+type acmeError struct {
+	Status int    `json:"status"`
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+func (e *acmeError) Error() string {
+	return e.Detail
+}
+
+func acmeErrorf(status int, typ string, format string, args ...interface{}) *acmeError {
+	return &acmeError{
+		Status: status,
+		Type:   "urn:ietf:params:acme:error:" + typ,
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+type acmeAccount struct {
+	ID        string           `json:"id"`
+	Key       *jose.JSONWebKey `json:"key"`
+	Status    string           `json:"status"`
+	Contact   []string         `json:"contact"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeOrder struct {
+	ID               string           `json:"id"`
+	AccountID        string           `json:"account_id"`
+	Role             string           `json:"role"`
+	Status           string           `json:"status"`
+	Expires          time.Time        `json:"expires"`
+	Identifiers      []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs []string         `json:"authorization_ids"`
+	SerialNumber     string           `json:"serial_number"`
+	CertificateChain string           `json:"certificate_chain"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+type acmeChallenge struct {
+	Type      string     `json:"type"`
+	Token     string     `json:"token"`
+	Status    string     `json:"status"`
+	Validated time.Time  `json:"validated"`
+	Error     *acmeError `json:"error"`
+}
+
+// acmeCertEntry records which account obtained a certificate, so that it
+// can revoke it
+type acmeCertEntry struct {
+	AccountID string `json:"account_id"`
+	OrderID   string `json:"order_id"`
+}
+
+// acmeContext holds what is needed to serve the ACME directory a request
+// was sent to
+type acmeContext struct {
+	config   *acmeConfig
+	roleName string
+	role     *roleEntry
+	dirURL   string
+}
+
+func (ac *acmeContext) accountURL(id string) string {
+	return ac.dirURL + "/account/" + id
+}
+
+func (ac *acmeContext) orderURL(id string) string {
+	return ac.dirURL + "/order/" + id
+}
+
+func (ac *acmeContext) authzURL(id string) string {
+	return ac.dirURL + "/authorization/" + id
+}
+
+func (ac *acmeContext) challengeURL(authzID, typ string) string {
+	return ac.dirURL + "/challenge/" + authzID + "/" + typ
+}
+
+// getACMEContext returns the context of the directory of the request, or an
+// acmeError when ACME clients can't use it
+func (b *backend) getACMEContext(ctx context.Context, req *logical.Request, data *framework.FieldData) (*acmeContext, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "the ACME server is not enabled")
+	}
+
+	ac := &acmeContext{
+		config:   config,
+		roleName: data.Get("role").(string),
+		dirURL:   config.BaseURL + "/acme",
+	}
+	if ac.roleName == "" {
+		if config.DefaultRole == "" {
+			return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "no default role is configured, use the directory of a role")
+		}
+		ac.roleName = config.DefaultRole
+	} else {
+		if !config.roleAllowed(ac.roleName) {
+			return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "role %q is not allowed for ACME", ac.roleName)
+		}
+		ac.dirURL += "/roles/" + ac.roleName
+	}
+
+	ac.role, err = b.getRole(ctx, req.Storage, ac.roleName)
+	if err != nil {
+		return nil, err
+	}
+	if ac.role == nil {
+		return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "unknown role %q", ac.roleName)
+	}
+
+	return ac, nil
+}
+
+// acmeNonceStore keeps track of the nonces handed to clients, which can only
+// be used once
+type acmeNonceStore struct {
+	lock   sync.Mutex
+	nonces map[string]time.Time
+}
+
+func newACMENonceStore() *acmeNonceStore {
+	return &acmeNonceStore{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+func (s *acmeNonceStore) issue() (string, error) {
+	nonce, err := acmeRandomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.nonces) >= acmeMaxNonces {
+		for n, expires := range s.nonces {
+			if now.After(expires) {
+				delete(s.nonces, n)
+			}
+		}
+	}
+	if len(s.nonces) >= acmeMaxNonces {
+		for n := range s.nonces {
+			delete(s.nonces, n)
+			break
+		}
+	}
+	s.nonces[nonce] = now.Add(acmeNonceLifetime)
+
+	return nonce, nil
+}
+
+func (s *acmeNonceStore) redeem(nonce string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expires, ok := s.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(s.nonces, nonce)
+
+	return time.Now().Before(expires)
+}
+
+func acmeRandomID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Modes of authentication of ACME requests, as described in RFC 8555
+// section 6.2
+const (
+	acmeAuthNone = iota
+	acmeAuthJWK
+	acmeAuthKID
+	acmeAuthAny
+)
+
+// acmeJWS is the verified content of an ACME request
+type acmeJWS struct {
+	payload []byte
+	key     *jose.JSONWebKey
+
+	// account is the account that signed the request, when it was
+	// signed with its kid
+	account *acmeAccount
+}
+
+// postAsGet returns whether the request is a POST-as-GET one
+func (j *acmeJWS) postAsGet() bool {
+	return len(j.payload) == 0
+}
+
+func (j *acmeJWS) decode(out interface{}) error {
+	if j.postAsGet() {
+		return nil
+	}
+	if err := json.Unmarshal(j.payload, out); err != nil {
+		return acmeErrorf(http.StatusBadRequest, "malformed", "error decoding the payload: %s", err)
+	}
+	return nil
+}
+
+func acmeThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// verifyACMEJWS checks the signature, nonce and URL of the JWS an ACME
+// request is made of
+func (b *backend) verifyACMEJWS(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, auth int) (*acmeJWS, error) {
+	raw, err := json.Marshal(map[string]string{
+		"protected": data.Get("protected").(string),
+		"payload":   data.Get("payload").(string),
+		"signature": data.Get("signature").(string),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sig, err := jose.ParseSigned(string(raw))
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "error parsing the JWS: %s", err)
+	}
+	if len(sig.Signatures) != 1 {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "the JWS must have a single signature")
+	}
+	header := sig.Signatures[0].Protected
+
+	switch header.Algorithm {
+	case string(jose.RS256), string(jose.ES256), string(jose.ES384), string(jose.ES512), string(jose.EdDSA):
+	default:
+		return nil, acmeErrorf(http.StatusBadRequest, "badSignatureAlgorithm", "unsupported signature algorithm %q", header.Algorithm)
+	}
+	if !b.acmeNonces.redeem(header.Nonce) {
+		return nil, acmeErrorf(http.StatusBadRequest, "badNonce", "invalid nonce")
+	}
+	if url, _ := header.ExtraHeaders["url"].(string); url != ac.config.BaseURL+"/"+req.Path {
+		return nil, acmeErrorf(http.StatusUnauthorized, "unauthorized", "the url of the JWS doesn't match the one of the request")
+	}
+	if header.JSONWebKey != nil && header.KeyID != "" {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "the JWS can't have both a jwk and a kid")
+	}
+
+	jws := &acmeJWS{}
+	switch {
+	case header.JSONWebKey != nil && (auth == acmeAuthJWK || auth == acmeAuthAny):
+		if !header.JSONWebKey.Valid() || !header.JSONWebKey.IsPublic() {
+			return nil, acmeErrorf(http.StatusBadRequest, "badPublicKey", "invalid jwk")
+		}
+		jws.key = header.JSONWebKey
+
+	case header.KeyID != "" && (auth == acmeAuthKID || auth == acmeAuthAny):
+		id := strings.TrimPrefix(header.KeyID, ac.dirURL+"/account/")
+		if id == header.KeyID {
+			return nil, acmeErrorf(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID)
+		}
+		account, err := getACMEAccount(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, acmeErrorf(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID)
+		}
+		if account.Status != acmeStatusValid {
+			return nil, acmeErrorf(http.StatusUnauthorized, "unauthorized", "the account is %s", account.Status)
+		}
+		jws.key = account.Key
+		jws.account = account
+
+	case auth == acmeAuthJWK:
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "the JWS must be signed with a jwk")
+
+	default:
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "the JWS must be signed with the kid of an account")
+	}
+
+	jws.payload, err = sig.Verify(jws.key)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "invalid signature")
+	}
+
+	return jws, nil
+}
+
+func getACMEEntry(ctx context.Context, s logical.Storage, key string, out interface{}) (bool, error) {
+	entry, err := s.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func putACMEEntry(ctx context.Context, s logical.Storage, key string, in interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, in)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func getACMEAccount(ctx context.Context, s logical.Storage, id string) (*acmeAccount, error) {
+	var account acmeAccount
+	if ok, err := getACMEEntry(ctx, s, acmeAccountPrefix+id, &account); !ok || err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func getACMEOrder(ctx context.Context, s logical.Storage, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	if ok, err := getACMEEntry(ctx, s, acmeOrderPrefix+id, &order); !ok || err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func getACMEAuthorization(ctx context.Context, s logical.Storage, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	if ok, err := getACMEEntry(ctx, s, acmeAuthzPrefix+id, &authz); !ok || err != nil {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+// refreshACMEAuthorization expires the authorization once past its
+// lifetime
+func refreshACMEAuthorization(authz *acmeAuthorization) bool {
+	if authz.Status != acmeStatusPending && authz.Status != acmeStatusValid {
+		return false
+	}
+	if time.Now().Before(authz.Expires) {
+		return false
+	}
+	authz.Status = acmeStatusInvalid
+	return true
+}
+
+// refreshACMEOrder updates the status of the order from the ones of its
+// authorizations, and stores it when it changed
+func refreshACMEOrder(ctx context.Context, s logical.Storage, order *acmeOrder) error {
+	status := order.Status
+	switch {
+	case order.Status != acmeStatusPending && order.Status != acmeStatusReady:
+	case time.Now().After(order.Expires):
+		order.Status = acmeStatusInvalid
+	case order.Status == acmeStatusPending:
+		ready := true
+		for _, id := range order.AuthorizationIDs {
+			authz, err := getACMEAuthorization(ctx, s, id)
+			if err != nil {
+				return err
+			}
+			if authz == nil {
+				order.Status = acmeStatusInvalid
+				break
+			}
+			refreshACMEAuthorization(authz)
+			switch authz.Status {
+			case acmeStatusValid:
+			case acmeStatusPending, acmeStatusProcessing:
+				ready = false
+			default:
+				order.Status = acmeStatusInvalid
+			}
+		}
+		if ready && order.Status == acmeStatusPending {
+			order.Status = acmeStatusReady
+		}
+	}
+
+	if order.Status == status {
+		return nil
+	}
+	return putACMEEntry(ctx, s, acmeOrderPrefix+order.ID, order)
+}
+
+// validateACMEChallenge checks that the response to the challenge was
+// provisioned, returning an acmeError describing the failure otherwise
+func (b *backend) validateACMEChallenge(ctx context.Context, ac *acmeContext, authz *acmeAuthorization, chal *acmeChallenge, thumbprint string) error {
+	keyAuthorization := chal.Token + "." + thumbprint
+
+	switch chal.Type {
+	case acmeChallengeHTTP01:
+		return b.validateACMEHTTP01(ctx, authz.Identifier.Value, chal.Token, keyAuthorization)
+	case acmeChallengeDNS01:
+		return b.validateACMEDNS01(ctx, ac.config, authz.Identifier.Value, keyAuthorization)
+	default:
+		return acmeErrorf(http.StatusBadRequest, "malformed", "unsupported challenge type %q", chal.Type)
+	}
+}
+
+func (b *backend) validateACMEHTTP01(ctx context.Context, domain, token, keyAuthorization string) error {
+	client := b.acmeHTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: acmeValidationTimeout}
+	}
+
+	url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", domain, token)
+	httpReq, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return acmeErrorf(http.StatusBadRequest, "malformed", "invalid challenge URL: %s", err)
+	}
+	resp, err := client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return acmeErrorf(http.StatusBadRequest, "connection", "error fetching %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return acmeErrorf(http.StatusForbidden, "unauthorized", "fetching %s returned status %d", url, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return acmeErrorf(http.StatusBadRequest, "connection", "error fetching %s: %s", url, err)
+	}
+	if string(bytes.TrimSpace(body)) != keyAuthorization {
+		return acmeErrorf(http.StatusForbidden, "incorrectResponse", "the key authorization served at %s is invalid", url)
+	}
+
+	return nil
+}
+
+func (b *backend) validateACMEDNS01(ctx context.Context, config *acmeConfig, domain, keyAuthorization string) error {
+	lookupTXT := b.acmeLookupTXT
+	if lookupTXT == nil {
+		resolver := net.DefaultResolver
+		if config.DNSResolver != "" {
+			resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, config.DNSResolver)
+				},
+			}
+		}
+		lookupTXT = resolver.LookupTXT
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, acmeValidationTimeout)
+	defer cancel()
+
+	name := "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		return acmeErrorf(http.StatusBadRequest, "dns", "error looking up the TXT records of %s: %s", name, err)
+	}
+
+	sum := sha256.Sum256([]byte(keyAuthorization))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	for _, record := range records {
+		if record == expected {
+			return nil
+		}
+	}
+
+	return acmeErrorf(http.StatusForbidden, "incorrectResponse", "no TXT record of %s matches the key authorization", name)
+}
+
+// jwkMatchesCertificate returns whether the JWK is the key of the certificate
+func jwkMatchesCertificate(key *jose.JSONWebKey, cert *x509.Certificate) bool {
+	keyBytes, err := x509.MarshalPKIXPublicKey(key.Key)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(keyBytes, cert.RawSubjectPublicKeyInfo)
+}
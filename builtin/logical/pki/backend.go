@@ -2,11 +2,14 @@ package pki
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -33,12 +36,19 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"crl/delta",
+				"crl/delta/pem",
+				"ocsp",
+				"ocsp/*",
+				"acme/*",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
+				"delta-crl",
 				"certs/",
+				"acme/",
 			},
 
 			Root: []string{
@@ -48,10 +58,11 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 			SealWrapStorage: []string{
 				"config/ca_bundle",
+				"issuers/",
 			},
 		},
 
-		Paths: []*framework.Path{
+		Paths: framework.PathAppend([]*framework.Path{
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathGenerateRoot(&b),
@@ -60,6 +71,11 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathDeleteRoot(&b),
 			pathGenerateIntermediate(&b),
 			pathSetSignedIntermediate(&b),
+			pathCrossSignIntermediate(&b),
+			pathListIssuers(&b),
+			pathIssuer(&b),
+			pathImportIssuer(&b),
+			pathConfigIssuers(&b),
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
@@ -70,23 +86,36 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathFetchCA(&b),
 			pathFetchCAChain(&b),
 			pathFetchCRL(&b),
+			pathFetchDeltaCRL(&b),
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValid(&b),
 			pathFetchListCerts(&b),
 			pathRevoke(&b),
 			pathTidy(&b),
+			pathTidyStatus(&b),
+			pathTidyPause(&b),
+			pathTidyResume(&b),
+			pathConfigAutoTidy(&b),
+			pathOCSP(&b),
+			pathOCSPGet(&b),
+			pathConfigACME(&b),
 		},
+			pathsACME(&b),
+		),
 
 		Secrets: []*framework.Secret{
 			secretCerts(&b),
 		},
 
-		BackendType: logical.TypeLogical,
+		BackendType:  logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
 	}
 
 	b.crlLifetime = time.Hour * 72
 	b.tidyCASGuard = new(uint32)
+	b.lastTidy = time.Now()
 	b.storage = conf.StorageView
+	b.acmeNonces = newACMENonceStore()
 
 	return &b
 }
@@ -98,6 +127,75 @@ type backend struct {
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
 	tidyCASGuard      *uint32
+
+	// tidyStatus reports the progress of the last tidy operation, and
+	// lastTidy when it finished; both are guarded by tidyStatusLock.
+	// tidyPaused is set while tidy operations are paused.
+	tidyStatusLock sync.RWMutex
+	tidyStatus     *tidyStatus
+	lastTidy       time.Time
+	tidyPaused     uint32
+
+	// issuersLock serializes the changes to the issuers and their
+	// configuration
+	issuersLock sync.Mutex
+
+	// acmeNonces holds the nonces handed to ACME clients, and acmeLock
+	// serializes the changes to ACME accounts, orders and authorizations
+	acmeNonces *acmeNonceStore
+	acmeLock   sync.Mutex
+
+	// acmeHTTPClient and acmeLookupTXT override how http-01 and dns-01
+	// challenges are validated, in tests
+	acmeHTTPClient *http.Client
+	acmeLookupTXT  func(ctx context.Context, name string) ([]string, error)
+}
+
+// periodicFunc rebuilds the CRLs and starts tidy operations when they are
+// configured to happen automatically
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	var result error
+	if err := b.autoRebuildCRL(ctx, req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	if err := b.autoTidy(ctx, req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
+
+// autoRebuildCRL rebuilds the CRL when it is about to expire, and the delta
+// CRL at its interval, when auto_rebuild is set
+func (b *backend) autoRebuildCRL(ctx context.Context, req *logical.Request) error {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if crlInfo == nil || !crlInfo.AutoRebuild || crlInfo.Disable {
+		return nil
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch {
+	case now.Add(crlInfo.autoRebuildGracePeriod()).After(state.NextUpdate):
+		err = buildCRL(ctx, b, req, false)
+	case crlInfo.EnableDelta && now.Sub(state.LastDeltaBuild) >= crlInfo.deltaRebuildInterval():
+		err = buildDeltaCRL(ctx, b, req)
+	}
+
+	// Mounts without a CA have no CRL to build
+	if _, ok := err.(errutil.UserError); ok {
+		return nil
+	}
+	return err
 }
 
 const backendHelp = `
@@ -1,6 +1,7 @@
 package pki
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
@@ -83,6 +84,12 @@ func fetchCAInfo(ctx context.Context, req *logical.Request) (*certutil.CAInfoBun
 		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
 	}
 
+	return caInfoFromCertBundle(ctx, req, &bundle)
+}
+
+// caInfoFromCertBundle returns the CA info of the given CA bundle, along
+// with the configured URLs
+func caInfoFromCertBundle(ctx context.Context, req *logical.Request, bundle *certutil.CertBundle) (*certutil.CAInfoBundle, error) {
 	parsedBundle, err := bundle.ToParsedCertBundle()
 	if err != nil {
 		return nil, errutil.InternalError{Err: err.Error()}
@@ -110,6 +117,37 @@ func fetchCAInfo(ctx context.Context, req *logical.Request) (*certutil.CAInfoBun
 	return caInfo, nil
 }
 
+// fullCAChain returns the chain of the given CA, starting with its own
+// certificate and, unlike GetCAChain, including self-signed roots
+func fullCAChain(caInfo *certutil.CAInfoBundle) []*certutil.CertBlock {
+	chain := []*certutil.CertBlock{
+		&certutil.CertBlock{
+			Certificate: caInfo.Certificate,
+			Bytes:       caInfo.CertificateBytes,
+		},
+	}
+	for _, cert := range caInfo.CAChain {
+		if !bytes.Equal(cert.Bytes, caInfo.CertificateBytes) {
+			chain = append(chain, cert)
+		}
+	}
+
+	return chain
+}
+
+// caChainPEM returns the chain of the given CA as PEM certificates
+func caChainPEM(caInfo *certutil.CAInfoBundle) []string {
+	var chain []string
+	for _, cert := range fullCAChain(caInfo) {
+		chain = append(chain, strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Bytes,
+		}))))
+	}
+
+	return chain
+}
+
 // Allows fetching certificates from the backend; it handles the slightly
 // separate pathing for CA, CRL, and revoked certificates.
 func fetchCertBySerial(ctx context.Context, req *logical.Request, prefix, serial string) (*logical.StorageEntry, error) {
@@ -130,6 +168,8 @@ func fetchCertBySerial(ctx context.Context, req *logical.Request, prefix, serial
 		path = "ca"
 	case serial == "crl":
 		path = "crl"
+	case serial == deltaCRLPath:
+		path = deltaCRLPath
 	default:
 		legacyPath = "certs/" + colonSerial
 		path = "certs/" + hyphenSerial
@@ -495,7 +535,7 @@ func generateCert(ctx context.Context,
 		}
 	}
 
-	parsedBundle, err := certutil.CreateCertificate(data)
+	parsedBundle, err := certutil.CreateCertificateWithRandomSource(data, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
@@ -515,7 +555,7 @@ func generateIntermediateCSR(b *backend, input *inputBundle) (*certutil.ParsedCS
 	}
 
 	addBasicConstraints := input.apiData != nil && input.apiData.Get("add_basic_constraints").(bool)
-	parsedBundle, err := certutil.CreateCSR(creation, addBasicConstraints)
+	parsedBundle, err := certutil.CreateCSRWithRandomSource(creation, addBasicConstraints, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
@@ -627,7 +667,7 @@ func signCert(b *backend,
 		creation.Params.PermittedDNSDomains = data.apiData.Get("permitted_dns_domains").([]string)
 	}
 
-	parsedBundle, err := certutil.SignCertificate(creation)
+	parsedBundle, err := certutil.SignCertificateWithRandomSource(creation, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
@@ -1,13 +1,22 @@
 package pki
 
 import (
+	"bytes"
+	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
+	"golang.org/x/crypto/ocsp"
 )
 
 func TestBackend_CRL_EnableDisable(t *testing.T) {
@@ -122,3 +131,175 @@ func TestBackend_CRL_EnableDisable(t *testing.T) {
 	toggle(false)
 	test(6)
 }
+
+func TestBackend_CRL_DeltaAndOCSP(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	parseCRL := func(path string) *pkix.CertificateList {
+		t.Helper()
+		resp := request(logical.ReadOperation, path, nil)
+		crl, err := x509.ParseCRL([]byte(resp.Data["certificate"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+	crlExtension := func(crl *pkix.CertificateList, oid asn1.ObjectIdentifier) int64 {
+		t.Helper()
+		for _, ext := range crl.TBSCertList.Extensions {
+			if ext.Id.Equal(oid) {
+				var number int64
+				if _, err := asn1.Unmarshal(ext.Value, &number); err != nil {
+					t.Fatal(err)
+				}
+				return number
+			}
+		}
+		return 0
+	}
+
+	resp := request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "40h",
+	})
+	block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "foobar.com",
+		"allow_subdomains": true,
+	})
+	var certs []*x509.Certificate
+	var serials []string
+	for i := 0; i < 2; i++ {
+		resp := request(logical.UpdateOperation, "issue/test", map[string]interface{}{
+			"common_name": "test.foobar.com",
+			"ttl":         "1h",
+		})
+		block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+		serials = append(serials, resp.Data["serial_number"].(string))
+	}
+
+	request(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"auto_rebuild":           true,
+		"enable_delta":           true,
+		"delta_rebuild_interval": "1ms",
+	})
+	base := parseCRL("cert/crl")
+	baseNumber := crlExtension(base, oidExtensionCRLNumber)
+	if baseNumber == 0 {
+		t.Fatalf("expected the CRL to be numbered")
+	}
+
+	// With auto_rebuild, revocations only show up in the delta CRL until the
+	// CRL is rebuilt
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serials[0],
+	})
+	time.Sleep(10 * time.Millisecond)
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	if crl := parseCRL("cert/crl"); len(crl.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("expected the CRL not to be rebuilt, got %d revoked certificates", len(crl.TBSCertList.RevokedCertificates))
+	}
+	delta := parseCRL("cert/delta-crl")
+	if len(delta.TBSCertList.RevokedCertificates) != 1 || delta.TBSCertList.RevokedCertificates[0].SerialNumber.Cmp(certs[0].SerialNumber) != 0 {
+		t.Fatalf("bad delta CRL entries: %#v", delta.TBSCertList.RevokedCertificates)
+	}
+	if crlExtension(delta, oidExtensionDeltaCRLIndicator) != baseNumber || crlExtension(delta, oidExtensionCRLNumber) <= baseNumber {
+		t.Fatalf("bad delta CRL numbers")
+	}
+	if err := caCert.CheckCRLSignature(delta); err != nil {
+		t.Fatal(err)
+	}
+
+	request(logical.ReadOperation, "crl/rotate", nil)
+	if crl := parseCRL("cert/crl"); len(crl.TBSCertList.RevokedCertificates) != 1 {
+		t.Fatalf("expected the rotated CRL to list the revoked certificate")
+	}
+	if delta := parseCRL("cert/delta-crl"); len(delta.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("expected the delta CRL to be reset")
+	}
+
+	// The OCSP responder answers over GET and POST
+	ocspStatus := func(cert *x509.Certificate, post bool) (int, *ocsp.Response) {
+		t.Helper()
+		ocspReq, err := ocsp.CreateRequest(cert, caCert, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "ocsp/" + base64.StdEncoding.EncodeToString(ocspReq),
+			Storage:   s,
+		}
+		if post {
+			req.Operation = logical.UpdateOperation
+			req.Path = "ocsp"
+			req.HTTPRequest, err = http.NewRequest("POST", "/v1/pki/ocsp", bytes.NewReader(ocspReq))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status := resp.Data[logical.HTTPStatusCode].(int)
+		if status != http.StatusOK {
+			return status, nil
+		}
+		ocspResp, err := ocsp.ParseResponseForCert(resp.Data[logical.HTTPRawBody].([]byte), cert, caCert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status, ocspResp
+	}
+	if _, ocspResp := ocspStatus(certs[0], false); ocspResp.Status != ocsp.Revoked {
+		t.Fatalf("expected the certificate to be revoked, got %d", ocspResp.Status)
+	}
+	if _, ocspResp := ocspStatus(certs[1], true); ocspResp.Status != ocsp.Good {
+		t.Fatalf("expected the certificate to be good, got %d", ocspResp.Status)
+	}
+
+	request(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"ocsp_disable": true,
+	})
+	if status, _ := ocspStatus(certs[1], false); status != http.StatusUnauthorized {
+		t.Fatalf("expected the OCSP responder to be disabled, got status %d", status)
+	}
+
+	// Delta CRLs need the CRL to be rebuilt periodically
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/crl",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"auto_rebuild": false,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected enable_delta without auto_rebuild to be rejected: %#v", resp)
+	}
+}
@@ -2,9 +2,14 @@ package pki
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"strings"
@@ -127,12 +132,20 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 
 	}
 
-	crlErr := buildCRL(ctx, b, req, false)
-	switch crlErr.(type) {
-	case errutil.UserError:
-		return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
-	case errutil.InternalError:
-		return nil, errwrap.Wrapf("error encountered during CRL building: {{err}}", crlErr)
+	// With auto_rebuild, the CRL is rebuilt periodically instead, and the
+	// revocation shows up in the next delta CRL
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return nil, errwrap.Wrapf("error fetching CRL config information: {{err}}", err)
+	}
+	if crlInfo == nil || !crlInfo.AutoRebuild {
+		crlErr := buildCRL(ctx, b, req, false)
+		switch crlErr.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
+		case errutil.InternalError:
+			return nil, errwrap.Wrapf("error encountered during CRL building: {{err}}", crlErr)
+		}
 	}
 
 	resp := &logical.Response{
@@ -156,8 +169,6 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request, forceNew bo
 
 	crlLifetime := b.crlLifetime
 	var revokedCerts []pkix.RevokedCertificate
-	var revInfo revocationInfo
-	var revokedSerials []string
 
 	if crlInfo != nil {
 		if crlInfo.Expiry != "" {
@@ -176,34 +187,156 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request, forceNew bo
 		}
 	}
 
-	revokedSerials, err = req.Storage.List(ctx, "revoked/")
+	revokedCerts, err = fetchRevokedCerts(ctx, req, time.Time{})
+	if err != nil {
+		return err
+	}
+
+WRITE:
+	signingBundle, caErr := fetchCAInfo(ctx, req)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL state: %s", err)}
+	}
+
+	now := time.Now()
+	state.LastCRLNumber++
+	crlBytes, err := createCRL(signingBundle, revokedCerts, now, now.Add(crlLifetime), state.LastCRLNumber, 0)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error creating new CRL: %s", err)}
+	}
+
+	err = req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   "crl",
+		Value: crlBytes,
+	})
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL: %s", err)}
+	}
+
+	state.CompleteCRLNumber = state.LastCRLNumber
+	state.LastCompleteBuild = now
+	state.NextUpdate = now.Add(crlLifetime)
+	if err := storeCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL state: %s", err)}
+	}
+
+	// The previous delta CRL refers to the previous complete CRL, so replace
+	// it with one on top of the new CRL
+	if crlInfo != nil && crlInfo.EnableDelta && !crlInfo.Disable {
+		return buildDeltaCRL(ctx, b, req)
+	}
+	if err := req.Storage.Delete(ctx, deltaCRLPath); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error deleting delta CRL: %s", err)}
+	}
+
+	return nil
+}
+
+// buildDeltaCRL builds a delta CRL listing the certificates revoked since the
+// last complete CRL was built.
+func buildDeltaCRL(ctx context.Context, b *backend, req *logical.Request) error {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL config information: %s", err)}
+	}
+	if crlInfo == nil || !crlInfo.EnableDelta || crlInfo.Disable {
+		return nil
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL state: %s", err)}
+	}
+	if state.CompleteCRLNumber == 0 {
+		return errutil.UserError{Err: "a complete CRL must be built before delta CRLs"}
+	}
+
+	revokedCerts, err := fetchRevokedCerts(ctx, req, state.LastCompleteBuild)
+	if err != nil {
+		return err
+	}
+
+	signingBundle, caErr := fetchCAInfo(ctx, req)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
+	}
+
+	// The delta CRL is valid until the next one is built, and at most as long
+	// as the complete CRL
+	now := time.Now()
+	nextUpdate := now.Add(crlInfo.deltaRebuildInterval())
+	if nextUpdate.After(state.NextUpdate) {
+		nextUpdate = state.NextUpdate
+	}
+
+	state.LastCRLNumber++
+	crlBytes, err := createCRL(signingBundle, revokedCerts, now, nextUpdate, state.LastCRLNumber, state.CompleteCRLNumber)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error creating new delta CRL: %s", err)}
+	}
+
+	err = req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   deltaCRLPath,
+		Value: crlBytes,
+	})
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing delta CRL: %s", err)}
+	}
+
+	state.LastDeltaBuild = now
+	if err := storeCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL state: %s", err)}
+	}
+
+	return nil
+}
+
+// fetchRevokedCerts returns the revoked certificates, limited to the ones
+// revoked after since unless it is zero
+func fetchRevokedCerts(ctx context.Context, req *logical.Request, since time.Time) ([]pkix.RevokedCertificate, error) {
+	var revokedCerts []pkix.RevokedCertificate
+
+	revokedSerials, err := req.Storage.List(ctx, "revoked/")
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("error fetching list of revoked certs: %s", err)}
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error fetching list of revoked certs: %s", err)}
 	}
 
 	for _, serial := range revokedSerials {
+		var revInfo revocationInfo
+
 		revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("unable to fetch revoked cert with serial %s: %s", serial, err)}
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch revoked cert with serial %s: %s", serial, err)}
 		}
 		if revokedEntry == nil {
-			return errutil.InternalError{Err: fmt.Sprintf("revoked certificate entry for serial %s is nil", serial)}
+			return nil, errutil.InternalError{Err: fmt.Sprintf("revoked certificate entry for serial %s is nil", serial)}
 		}
 		if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 			// TODO: In this case, remove it and continue? How likely is this to
 			// happen? Alternately, could skip it entirely, or could implement a
 			// delete function so that there is a way to remove these
-			return errutil.InternalError{Err: fmt.Sprintf("found revoked serial but actual certificate is empty")}
+			return nil, errutil.InternalError{Err: fmt.Sprintf("found revoked serial but actual certificate is empty")}
 		}
 
 		err = revokedEntry.DecodeJSON(&revInfo)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("error decoding revocation entry for serial %s: %s", serial, err)}
+			return nil, errutil.InternalError{Err: fmt.Sprintf("error decoding revocation entry for serial %s: %s", serial, err)}
 		}
 
 		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse stored revoked certificate with serial %s: %s", serial, err)}
 		}
 
 		// NOTE: We have to change this to UTC time because the CRL standard
@@ -216,30 +349,161 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request, forceNew bo
 		} else {
 			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
 		}
+		if !since.IsZero() && !newRevCert.RevocationTime.After(since) {
+			continue
+		}
 		revokedCerts = append(revokedCerts, newRevCert)
 	}
 
-WRITE:
-	signingBundle, caErr := fetchCAInfo(ctx, req)
-	switch caErr.(type) {
-	case errutil.UserError:
-		return errutil.UserError{Err: fmt.Sprintf("could not fetch the CA certificate: %s", caErr)}
-	case errutil.InternalError:
-		return errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
+	return revokedCerts, nil
+}
+
+const (
+	crlStatePath = "crl-state"
+	deltaCRLPath = "delta-crl"
+)
+
+// crlState tracks the numbering of the CRLs and when they were built.
+// Complete and delta CRLs share one numbering sequence, see RFC 5280 5.2.3.
+type crlState struct {
+	LastCRLNumber     int64     `json:"last_crl_number"`
+	CompleteCRLNumber int64     `json:"complete_crl_number"`
+	LastCompleteBuild time.Time `json:"last_complete_build"`
+	NextUpdate        time.Time `json:"next_update"`
+	LastDeltaBuild    time.Time `json:"last_delta_build"`
+}
+
+func getCRLState(ctx context.Context, s logical.Storage) (*crlState, error) {
+	entry, err := s.Get(ctx, crlStatePath)
+	if err != nil {
+		return nil, err
 	}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+	var state crlState
+	if entry == nil {
+		return &state, nil
+	}
+	if err := entry.DecodeJSON(&state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func storeCRLState(ctx context.Context, s logical.Storage, state *crlState) error {
+	entry, err := logical.StorageEntryJSON(crlStatePath, state)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("error creating new CRL: %s", err)}
+		return err
 	}
+	return s.Put(ctx, entry)
+}
 
-	err = req.Storage.Put(ctx, &logical.StorageEntry{
-		Key:   "crl",
-		Value: crlBytes,
+var (
+	oidExtensionAuthorityKeyID    = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber         = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+// createCRL creates a CRL like x509.Certificate.CreateCRL, along with the
+// CRL number extension and, when baseCRLNumber is set, the delta CRL
+// indicator referring to that complete CRL.
+func createCRL(caInfo *certutil.CAInfoBundle, revokedCerts []pkix.RevokedCertificate, now, nextUpdate time.Time, number, baseCRLNumber int64) ([]byte, error) {
+	sigAlg, hashFunc, err := crlSignatureAlgorithm(caInfo.PrivateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	var extensions []pkix.Extension
+	if len(caInfo.Certificate.SubjectKeyId) > 0 {
+		value, err := asn1.Marshal(authorityKeyID{ID: caInfo.Certificate.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{
+			Id:    oidExtensionAuthorityKeyID,
+			Value: value,
+		})
+	}
+
+	value, err := asn1.Marshal(number)
+	if err != nil {
+		return nil, err
+	}
+	extensions = append(extensions, pkix.Extension{
+		Id:    oidExtensionCRLNumber,
+		Value: value,
 	})
+
+	if baseCRLNumber > 0 {
+		value, err := asn1.Marshal(baseCRLNumber)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{
+			Id:       oidExtensionDeltaCRLIndicator,
+			Critical: true,
+			Value:    value,
+		})
+	}
+
+	tbsCertList := pkix.TBSCertificateList{
+		Version:             1,
+		Signature:           sigAlg,
+		Issuer:              caInfo.Certificate.Subject.ToRDNSequence(),
+		ThisUpdate:          now.UTC(),
+		NextUpdate:          nextUpdate.UTC(),
+		RevokedCertificates: revokedCerts,
+		Extensions:          extensions,
+	}
+
+	tbsCertListContents, err := asn1.Marshal(tbsCertList)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL: %s", err)}
+		return nil, err
 	}
 
-	return nil
+	h := hashFunc.New()
+	h.Write(tbsCertListContents)
+	signature, err := caInfo.PrivateKey.Sign(rand.Reader, h.Sum(nil), hashFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: sigAlg,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// crlSignatureAlgorithm returns the signature algorithm x509 uses by default
+// for the given public key
+func crlSignatureAlgorithm(pub crypto.PublicKey) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{
+			Algorithm:  oidSignatureSHA256WithRSA,
+			Parameters: asn1.NullRawValue,
+		}, crypto.SHA256, nil
+
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P224(), elliptic.P256():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256}, crypto.SHA256, nil
+		case elliptic.P384():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA384}, crypto.SHA384, nil
+		case elliptic.P521():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA512}, crypto.SHA512, nil
+		}
+	}
+
+	return pkix.AlgorithmIdentifier{}, 0, errors.New("unsupported CA key type for signing CRLs")
 }
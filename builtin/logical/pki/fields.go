@@ -232,6 +232,19 @@ the alt_names map using OID 2.5.4.5.`,
 	return fields
 }
 
+// addIssuerRefField adds the field selecting the issuer to sign with
+func addIssuerRefField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: defaultIssuerRef,
+		Description: `Reference to the issuer to sign with: its ID,
+its name, or "default" for the default issuer.
+Defaults to "default".`,
+	}
+
+	return fields
+}
+
 // addCAKeyGenerationFields adds fields with help text specific to CA key
 // generation and exporting
 func addCAKeyGenerationFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
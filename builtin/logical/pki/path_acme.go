@@ -0,0 +1,818 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// acmeIDRegex matches the identifiers of ACME resources, which are
+// base64url-encoded
+const acmeIDRegex = `(?P<id>[\w-]+)`
+
+// acmeOperation handles an ACME request, whose JWS was verified unless the
+// path doesn't expect one
+type acmeOperation func(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error)
+
+func pathsACME(b *backend) []*framework.Path {
+	return []*framework.Path{
+		acmePath(b, "directory", map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.acmeHandler(acmeAuthNone, b.pathACMEDirectory),
+		}),
+		acmePath(b, "new-nonce", map[logical.Operation]framework.OperationFunc{
+			logical.HeaderOperation: b.acmeHandler(acmeAuthNone, b.pathACMENewNonce),
+			logical.ReadOperation:   b.acmeHandler(acmeAuthNone, b.pathACMENewNonce),
+		}),
+		acmePath(b, "new-account", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthJWK, b.pathACMENewAccount),
+		}),
+		acmePath(b, "account/"+acmeIDRegex, map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAccount),
+		}),
+		acmePath(b, "account/"+acmeIDRegex+"/orders", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAccountOrders),
+		}),
+		acmePath(b, "new-order", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMENewOrder),
+		}),
+		acmePath(b, "order/"+acmeIDRegex, map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEOrder),
+		}),
+		acmePath(b, "order/"+acmeIDRegex+"/finalize", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEFinalize),
+		}),
+		acmePath(b, "order/"+acmeIDRegex+"/cert", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMECert),
+		}),
+		acmePath(b, "authorization/"+acmeIDRegex, map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAuthorization),
+		}),
+		acmePath(b, "challenge/"+acmeIDRegex+"/"+framework.GenericNameRegex("type"), map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEChallenge),
+		}),
+		acmePath(b, "revoke-cert", map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthAny, b.pathACMERevokeCert),
+		}),
+	}
+}
+
+// acmePath returns the path of an ACME endpoint, served both by the
+// directory of the default role and by the ones of each role
+func acmePath(b *backend, pattern string, callbacks map[logical.Operation]framework.OperationFunc) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/(roles/" + framework.GenericNameRegex("role") + "/)?" + pattern,
+
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Role the certificates are issued through.`,
+			},
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Identifier of the ACME resource.`,
+			},
+			"type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Type of the challenge.`,
+			},
+			"protected": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Protected header of the JWS.`,
+			},
+			"payload": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Payload of the JWS.`,
+			},
+			"signature": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Signature of the JWS.`,
+			},
+		},
+
+		Callbacks: callbacks,
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+// acmeHandler verifies the JWS of the request before calling the operation,
+// reports errors as problem documents and hands a fresh nonce with every
+// response
+func (b *backend) acmeHandler(auth int, op acmeOperation) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ac, err := b.getACMEContext(ctx, req, data)
+
+		var resp *logical.Response
+		if err == nil {
+			var jws *acmeJWS
+			if auth != acmeAuthNone {
+				jws, err = b.verifyACMEJWS(ctx, req, data, ac, auth)
+			}
+			if err == nil {
+				resp, err = op(ctx, req, data, ac, jws)
+			}
+		}
+
+		if err != nil {
+			problem, ok := err.(*acmeError)
+			if !ok {
+				b.Logger().Error("error handling ACME request", "path", req.Path, "error", err)
+				problem = acmeErrorf(http.StatusInternalServerError, "serverInternal", "internal error")
+			}
+			resp, err = acmeJSONResponse(problem.Status, problem)
+			if err != nil {
+				return nil, err
+			}
+			resp.Data[logical.HTTPContentType] = "application/problem+json"
+		}
+
+		nonce, err := b.acmeNonces.issue()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Headers == nil {
+			resp.Headers = make(map[string][]string)
+		}
+		resp.Headers["Replay-Nonce"] = []string{nonce}
+		resp.Headers["Cache-Control"] = []string{"no-store"}
+		if ac != nil {
+			resp.Headers["Link"] = append(resp.Headers["Link"], fmt.Sprintf(`<%s/directory>;rel="index"`, ac.dirURL))
+		}
+
+		return resp, nil
+	}
+}
+
+func acmeJSONResponse(status int, body interface{}) (*logical.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     raw,
+			logical.HTTPStatusCode:  status,
+		},
+		Headers: make(map[string][]string),
+	}, nil
+}
+
+func (b *backend) pathACMEDirectory(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	return acmeJSONResponse(http.StatusOK, map[string]interface{}{
+		"newNonce":   ac.dirURL + "/new-nonce",
+		"newAccount": ac.dirURL + "/new-account",
+		"newOrder":   ac.dirURL + "/new-order",
+		"revokeCert": ac.dirURL + "/revoke-cert",
+		"meta": map[string]interface{}{
+			"externalAccountRequired": false,
+		},
+	})
+}
+
+func (b *backend) pathACMENewNonce(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	status := http.StatusOK
+	if req.Operation == logical.ReadOperation {
+		status = http.StatusNoContent
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     []byte{},
+			logical.HTTPStatusCode:  status,
+		},
+	}, nil
+}
+
+func (b *backend) acmeAccountResponse(ac *acmeContext, account *acmeAccount, status int) (*logical.Response, error) {
+	resp, err := acmeJSONResponse(status, map[string]interface{}{
+		"status":  account.Status,
+		"contact": account.Contact,
+		"orders":  ac.accountURL(account.ID) + "/orders",
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers["Location"] = []string{ac.accountURL(account.ID)}
+	return resp, nil
+}
+
+func validateACMEContact(contact []string) error {
+	for _, c := range contact {
+		if !strings.HasPrefix(c, "mailto:") {
+			return acmeErrorf(http.StatusBadRequest, "unsupportedContact", "unsupported contact %q, only mailto: is supported", c)
+		}
+	}
+	return nil
+}
+
+func (b *backend) pathACMENewAccount(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Contact            []string `json:"contact"`
+		OnlyReturnExisting bool     `json:"onlyReturnExisting"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+
+	id, err := acmeThumbprint(jws.key)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "badPublicKey", "error computing the thumbprint of the key: %s", err)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account, err := getACMEAccount(ctx, req.Storage, id)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		return b.acmeAccountResponse(ac, account, http.StatusOK)
+	}
+	if payload.OnlyReturnExisting {
+		return nil, acmeErrorf(http.StatusBadRequest, "accountDoesNotExist", "no account exists with this key")
+	}
+	if err := validateACMEContact(payload.Contact); err != nil {
+		return nil, err
+	}
+
+	account = &acmeAccount{
+		ID:        id,
+		Key:       jws.key,
+		Status:    acmeStatusValid,
+		Contact:   payload.Contact,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := putACMEEntry(ctx, req.Storage, acmeAccountPrefix+id, account); err != nil {
+		return nil, err
+	}
+
+	return b.acmeAccountResponse(ac, account, http.StatusCreated)
+}
+
+func (b *backend) pathACMEAccount(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	if data.Get("id").(string) != jws.account.ID {
+		return nil, acmeErrorf(http.StatusUnauthorized, "unauthorized", "the request isn't signed by the key of the account")
+	}
+
+	var payload struct {
+		Contact []string `json:"contact"`
+		Status  string   `json:"status"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+	if jws.postAsGet() {
+		return b.acmeAccountResponse(ac, jws.account, http.StatusOK)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account := jws.account
+	if payload.Contact != nil {
+		if err := validateACMEContact(payload.Contact); err != nil {
+			return nil, err
+		}
+		account.Contact = payload.Contact
+	}
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		account.Status = acmeStatusDeactivated
+	default:
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "invalid status %q", payload.Status)
+	}
+	if err := putACMEEntry(ctx, req.Storage, acmeAccountPrefix+account.ID, account); err != nil {
+		return nil, err
+	}
+
+	return b.acmeAccountResponse(ac, account, http.StatusOK)
+}
+
+func (b *backend) pathACMEAccountOrders(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	if data.Get("id").(string) != jws.account.ID {
+		return nil, acmeErrorf(http.StatusUnauthorized, "unauthorized", "the request isn't signed by the key of the account")
+	}
+
+	ids, err := req.Storage.List(ctx, acmeAccountOrdersPrefix+jws.account.ID+"/")
+	if err != nil {
+		return nil, err
+	}
+	orders := []string{}
+	for _, id := range ids {
+		orders = append(orders, ac.orderURL(id))
+	}
+
+	return acmeJSONResponse(http.StatusOK, map[string]interface{}{
+		"orders": orders,
+	})
+}
+
+func (b *backend) acmeOrderResponse(ac *acmeContext, order *acmeOrder, status int) (*logical.Response, error) {
+	authorizations := []string{}
+	for _, id := range order.AuthorizationIDs {
+		authorizations = append(authorizations, ac.authzURL(id))
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       ac.orderURL(order.ID) + "/finalize",
+	}
+	if order.Status == acmeStatusValid {
+		body["certificate"] = ac.orderURL(order.ID) + "/cert"
+	}
+
+	resp, err := acmeJSONResponse(status, body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers["Location"] = []string{ac.orderURL(order.ID)}
+	return resp, nil
+}
+
+// fetchACMEOrder returns the order of the request after checking it belongs
+// to the account that signed it and refreshing its status
+func fetchACMEOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*acmeOrder, error) {
+	order, err := getACMEOrder(ctx, req.Storage, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.AccountID != jws.account.ID || order.Role != ac.roleName {
+		return nil, acmeErrorf(http.StatusNotFound, "malformed", "unknown order")
+	}
+	if err := refreshACMEOrder(ctx, req.Storage, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (b *backend) pathACMENewOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Identifiers) == 0 {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "no identifiers were requested")
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "notBefore and notAfter are not supported, the validity of certificates is set by the role")
+	}
+
+	var names []string
+	for i, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return nil, acmeErrorf(http.StatusBadRequest, "unsupportedIdentifier", "unsupported identifier type %q", identifier.Type)
+		}
+		name := strings.ToLower(identifier.Value)
+		if name == "" || strings.Contains(strings.TrimPrefix(name, "*."), "*") {
+			return nil, acmeErrorf(http.StatusBadRequest, "rejectedIdentifier", "invalid identifier %q", identifier.Value)
+		}
+		if strutil.StrListContains(names, name) {
+			return nil, acmeErrorf(http.StatusBadRequest, "malformed", "duplicate identifier %q", identifier.Value)
+		}
+		payload.Identifiers[i].Value = name
+		names = append(names, name)
+	}
+	if badName := validateNames(b, &inputBundle{req: req, role: ac.role}, names); badName != "" {
+		return nil, acmeErrorf(http.StatusBadRequest, "rejectedIdentifier", "name %q is not allowed by role %q", badName, ac.roleName)
+	}
+
+	orderID, err := acmeRandomID()
+	if err != nil {
+		return nil, err
+	}
+	expires := time.Now().Add(acmeOrderLifetime).UTC()
+	order := &acmeOrder{
+		ID:          orderID,
+		AccountID:   jws.account.ID,
+		Role:        ac.roleName,
+		Status:      acmeStatusPending,
+		Expires:     expires,
+		Identifiers: payload.Identifiers,
+	}
+
+	for _, identifier := range payload.Identifiers {
+		authzID, err := acmeRandomID()
+		if err != nil {
+			return nil, err
+		}
+		authz := &acmeAuthorization{
+			ID:         authzID,
+			AccountID:  jws.account.ID,
+			Status:     acmeStatusPending,
+			Expires:    expires,
+			Identifier: identifier,
+			Wildcard:   strings.HasPrefix(identifier.Value, "*."),
+		}
+
+		// Control over wildcard domains can only be proven with DNS
+		types := []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+		if authz.Wildcard {
+			types = []string{acmeChallengeDNS01}
+		}
+		for _, typ := range types {
+			token, err := acmeRandomID()
+			if err != nil {
+				return nil, err
+			}
+			authz.Challenges = append(authz.Challenges, &acmeChallenge{
+				Type:   typ,
+				Token:  token,
+				Status: acmeStatusPending,
+			})
+		}
+
+		if err := putACMEEntry(ctx, req.Storage, acmeAuthzPrefix+authzID, authz); err != nil {
+			return nil, err
+		}
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authzID)
+	}
+
+	if err := putACMEEntry(ctx, req.Storage, acmeOrderPrefix+orderID, order); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, &logical.StorageEntry{
+		Key: acmeAccountOrdersPrefix + jws.account.ID + "/" + orderID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderResponse(ac, order, http.StatusCreated)
+}
+
+func (b *backend) pathACMEOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	order, err := fetchACMEOrder(ctx, req, data, ac, jws)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderResponse(ac, order, http.StatusOK)
+}
+
+func (b *backend) pathACMEFinalize(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := fetchACMEOrder(ctx, req, data, ac, jws)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, acmeErrorf(http.StatusForbidden, "orderNotReady", "the order is %s", order.Status)
+	}
+
+	csrBytes, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "badCSR", "error decoding the CSR: %s", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "badCSR", "error parsing the CSR: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "badCSR", "invalid CSR signature: %s", err)
+	}
+
+	// The CSR must request exactly the names of the order
+	var names []string
+	for _, identifier := range order.Identifiers {
+		names = append(names, identifier.Value)
+	}
+	csrNames := strutil.RemoveDuplicates(append(csr.DNSNames, csr.Subject.CommonName), true)
+	sort.Strings(names)
+	if !strutil.EquivalentSlices(names, csrNames) || len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return nil, acmeErrorf(http.StatusBadRequest, "badCSR", "the CSR must request exactly the identifiers of the order")
+	}
+
+	commonName := strings.ToLower(csr.Subject.CommonName)
+	if commonName == "" {
+		commonName = names[0]
+	}
+
+	// The names were checked above, and leases can't be attached to
+	// unauthenticated requests
+	role := *ac.role
+	role.UseCSRCommonName = false
+	role.UseCSRSANs = false
+	generateLease := false
+	role.GenerateLease = &generateLease
+
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: csrBytes,
+			})),
+			"common_name": commonName,
+			"alt_names":   strings.Join(names, ","),
+			"format":      "pem",
+		},
+		Schema: pathSign(b).Fields,
+	}
+	resp, err := b.pathIssueSignCert(ctx, req, signData, &role, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, acmeErrorf(http.StatusBadRequest, "badCSR", "%s", resp.Error())
+	}
+
+	chain := []string{resp.Data["certificate"].(string)}
+	chain = append(chain, resp.Data["ca_chain"].([]string)...)
+	order.Status = acmeStatusValid
+	order.SerialNumber = resp.Data["serial_number"].(string)
+	order.CertificateChain = strings.Join(chain, "\n") + "\n"
+
+	if err := putACMEEntry(ctx, req.Storage, acmeCertPrefix+normalizeSerial(order.SerialNumber), &acmeCertEntry{
+		AccountID: order.AccountID,
+		OrderID:   order.ID,
+	}); err != nil {
+		return nil, err
+	}
+	if err := putACMEEntry(ctx, req.Storage, acmeOrderPrefix+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderResponse(ac, order, http.StatusOK)
+}
+
+func (b *backend) pathACMECert(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	order, err := fetchACMEOrder(ctx, req, data, ac, jws)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusValid {
+		return nil, acmeErrorf(http.StatusNotFound, "malformed", "the order has no certificate")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/pem-certificate-chain",
+			logical.HTTPRawBody:     []byte(order.CertificateChain),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+func (b *backend) acmeAuthorizationResponse(ac *acmeContext, authz *acmeAuthorization) (*logical.Response, error) {
+	var challenges []map[string]interface{}
+	for _, chal := range authz.Challenges {
+		challenges = append(challenges, acmeChallengeBody(ac, authz, chal))
+	}
+
+	body := map[string]interface{}{
+		"status":     authz.Status,
+		"expires":    authz.Expires.Format(time.RFC3339),
+		"identifier": acmeIdentifier{Type: "dns", Value: strings.TrimPrefix(authz.Identifier.Value, "*.")},
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		body["wildcard"] = true
+	}
+
+	return acmeJSONResponse(http.StatusOK, body)
+}
+
+func acmeChallengeBody(ac *acmeContext, authz *acmeAuthorization, chal *acmeChallenge) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":   chal.Type,
+		"url":    ac.challengeURL(authz.ID, chal.Type),
+		"status": chal.Status,
+		"token":  chal.Token,
+	}
+	if !chal.Validated.IsZero() {
+		body["validated"] = chal.Validated.Format(time.RFC3339)
+	}
+	if chal.Error != nil {
+		body["error"] = chal.Error
+	}
+	return body
+}
+
+// fetchACMEAuthorization returns the authorization of the request after
+// checking it belongs to the account that signed it
+func fetchACMEAuthorization(ctx context.Context, req *logical.Request, data *framework.FieldData, jws *acmeJWS) (*acmeAuthorization, error) {
+	authz, err := getACMEAuthorization(ctx, req.Storage, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if authz == nil || authz.AccountID != jws.account.ID {
+		return nil, acmeErrorf(http.StatusNotFound, "malformed", "unknown authorization")
+	}
+	if refreshACMEAuthorization(authz) {
+		if err := putACMEEntry(ctx, req.Storage, acmeAuthzPrefix+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	}
+	return authz, nil
+}
+
+func (b *backend) pathACMEAuthorization(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Status string `json:"status"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err := fetchACMEAuthorization(ctx, req, data, jws)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		if authz.Status != acmeStatusPending && authz.Status != acmeStatusValid {
+			return nil, acmeErrorf(http.StatusBadRequest, "malformed", "the authorization is %s", authz.Status)
+		}
+		authz.Status = acmeStatusDeactivated
+		if err := putACMEEntry(ctx, req.Storage, acmeAuthzPrefix+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "invalid status %q", payload.Status)
+	}
+
+	return b.acmeAuthorizationResponse(ac, authz)
+}
+
+func (b *backend) pathACMEChallenge(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	// Validation can take a while, so the authorization is marked as being
+	// processed rather than kept locked in the meantime
+	b.acmeLock.Lock()
+	authz, err := fetchACMEAuthorization(ctx, req, data, jws)
+	if err != nil {
+		b.acmeLock.Unlock()
+		return nil, err
+	}
+	var chal *acmeChallenge
+	for _, c := range authz.Challenges {
+		if c.Type == data.Get("type").(string) {
+			chal = c
+		}
+	}
+	if chal == nil {
+		b.acmeLock.Unlock()
+		return nil, acmeErrorf(http.StatusNotFound, "malformed", "unknown challenge")
+	}
+	validate := authz.Status == acmeStatusPending && chal.Status == acmeStatusPending
+	if validate {
+		chal.Status = acmeStatusProcessing
+		authz.Status = acmeStatusProcessing
+		err = putACMEEntry(ctx, req.Storage, acmeAuthzPrefix+authz.ID, authz)
+	}
+	b.acmeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if validate {
+		thumbprint, err := acmeThumbprint(jws.account.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		err = b.validateACMEChallenge(ctx, ac, authz, chal, thumbprint)
+		switch err := err.(type) {
+		case nil:
+			chal.Status = acmeStatusValid
+			chal.Validated = time.Now().UTC()
+			authz.Status = acmeStatusValid
+		case *acmeError:
+			chal.Status = acmeStatusInvalid
+			chal.Error = err
+			authz.Status = acmeStatusInvalid
+		default:
+			return nil, err
+		}
+
+		b.acmeLock.Lock()
+		err = putACMEEntry(ctx, req.Storage, acmeAuthzPrefix+authz.ID, authz)
+		b.acmeLock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := acmeJSONResponse(http.StatusOK, acmeChallengeBody(ac, authz, chal))
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers["Link"] = []string{fmt.Sprintf(`<%s>;rel="up"`, ac.authzURL(authz.ID))}
+	return resp, nil
+}
+
+func (b *backend) pathACMERevokeCert(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Certificate string `json:"certificate"`
+	}
+	if err := jws.decode(&payload); err != nil {
+		return nil, err
+	}
+
+	certBytes, err := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "error decoding the certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "malformed", "error parsing the certificate: %s", err)
+	}
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+
+	// Certificates can be revoked by the account that obtained them, or
+	// with their key
+	if jws.account != nil {
+		var entry acmeCertEntry
+		ok, err := getACMEEntry(ctx, req.Storage, acmeCertPrefix+normalizeSerial(serial), &entry)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || entry.AccountID != jws.account.ID {
+			return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "the certificate wasn't issued to this account")
+		}
+	} else if !jwkMatchesCertificate(jws.key, cert) {
+		return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "the request isn't signed by the key of the certificate")
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	certEntry, err := fetchCertBySerial(ctx, req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil || string(certEntry.Value) != string(certBytes) {
+		return nil, acmeErrorf(http.StatusNotFound, "malformed", "unknown certificate")
+	}
+	revokedEntry, err := fetchCertBySerial(ctx, req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		return nil, acmeErrorf(http.StatusBadRequest, "alreadyRevoked", "the certificate is already revoked")
+	}
+
+	resp, err := revokeCert(ctx, b, req, serial, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, acmeErrorf(http.StatusForbidden, "unauthorized", "%s", resp.Error())
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     []byte{},
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+const pathACMEHelpSyn = `
+ACME server endpoints.
+`
+
+const pathACMEHelpDesc = `
+These paths implement the ACME protocol, as described in RFC 8555, for the
+default role at "acme/directory" and for each allowed role at
+"acme/roles/<role>/directory". They are meant to be used by ACME clients,
+which prove control over the requested domains with http-01 or dns-01
+challenges, and are configured with the "config/acme" endpoint.
+`
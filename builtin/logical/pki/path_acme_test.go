@@ -0,0 +1,265 @@
+package pki
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"golang.org/x/crypto/acme"
+)
+
+func TestPki_ACME(t *testing.T) {
+	// The responses to the challenges are served by the test rather than by
+	// the requested domains
+	var lock sync.Mutex
+	httpTokens := map[string]string{}
+	txtRecords := map[string][]string{}
+	challengeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		keyAuth, ok := httpTokens[strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(keyAuth))
+	}))
+	defer challengeServer.Close()
+
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"pki": func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+				b := Backend(conf)
+				b.acmeHTTPClient = &http.Client{
+					Transport: &http.Transport{
+						DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+							var d net.Dialer
+							return d.DialContext(ctx, network, challengeServer.Listener.Addr().String())
+						},
+					},
+				}
+				b.acmeLookupTXT = func(ctx context.Context, name string) ([]string, error) {
+					lock.Lock()
+					defer lock.Unlock()
+					return txtRecords[name], nil
+				}
+				if err := b.Setup(ctx, conf); err != nil {
+					return nil, err
+				}
+				return b, nil
+			},
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	err := client.Sys().Mount("pki", &api.MountInput{
+		Type: "pki",
+		Config: api.MountConfigInput{
+			DefaultLeaseTTL: "16h",
+			MaxLeaseTTL:     "32h",
+			// ACME clients rely on these headers
+			AllowedResponseHeaders: []string{"Replay-Nonce", "Link", "Location"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Logical().Write("pki/roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "any",
+		"ttl":              "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseURL := client.Address() + "/v1/pki"
+	ctx := context.Background()
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeClient := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: baseURL + "/acme/directory",
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: cluster.RootCAs},
+			},
+		},
+	}
+
+	// The directory is only served once the ACME server is enabled
+	if _, err := acmeClient.Discover(ctx); err == nil {
+		t.Fatal("expected the directory to be unavailable")
+	}
+	_, err = client.Logical().Write("pki/config/acme", map[string]interface{}{
+		"enabled":      true,
+		"base_url":     baseURL,
+		"default_role": "example",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := acmeClient.Register(ctx, &acme.Account{Contact: []string{"mailto:admin@example.com"}}, acme.AcceptTOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Status != acme.StatusValid || !strings.HasPrefix(account.URI, baseURL+"/acme/account/") {
+		t.Fatalf("bad account: %#v", account)
+	}
+
+	// Names that the role doesn't allow are rejected upfront
+	_, err = acmeClient.AuthorizeOrder(ctx, []acme.AuthzID{{Type: "dns", Value: "www.example.org"}})
+	if acmeErr, ok := err.(*acme.Error); !ok || acmeErr.ProblemType != "urn:ietf:params:acme:error:rejectedIdentifier" {
+		t.Fatalf("expected the identifier to be rejected, got: %v", err)
+	}
+
+	order, err := acmeClient.AuthorizeOrder(ctx, []acme.AuthzID{
+		{Type: "dns", Value: "www.example.com"},
+		{Type: "dns", Value: "*.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != acme.StatusPending || len(order.AuthzURLs) != 2 {
+		t.Fatalf("bad order: %#v", order)
+	}
+
+	// Wildcards are validated with dns-01, and the other names with http-01
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := acmeClient.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "http-01" || (chal == nil && c.Type == "dns-01") {
+				chal = c
+			}
+		}
+		if authz.Wildcard && chal.Type != "dns-01" {
+			t.Fatalf("expected wildcards to only be validated with dns-01: %#v", authz.Challenges)
+		}
+
+		lock.Lock()
+		switch chal.Type {
+		case "http-01":
+			httpTokens[chal.Token], err = acmeClient.HTTP01ChallengeResponse(chal.Token)
+		case "dns-01":
+			var record string
+			record, err = acmeClient.DNS01ChallengeRecord(chal.Token)
+			txtRecords["_acme-challenge."+authz.Identifier.Value] = append(txtRecords["_acme-challenge."+authz.Identifier.Value], record)
+		}
+		lock.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := acmeClient.Accept(ctx, chal); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := acmeClient.WaitAuthorization(ctx, authzURL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order, err = acmeClient.WaitOrder(ctx, order.URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != acme.StatusReady {
+		t.Fatalf("bad order: %#v", order)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		DNSNames: []string{"www.example.com", "*.example.com"},
+	}, certKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, _, err := acmeClient.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 {
+		t.Fatalf("expected the certificate and its CA, got %d certificates", len(chain))
+	}
+	cert, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("foo.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A challenge whose response wasn't provisioned fails
+	order, err = acmeClient.AuthorizeOrder(ctx, []acme.AuthzID{{Type: "dns", Value: "bad.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authz, err := acmeClient.GetAuthorization(ctx, order.AuthzURLs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range authz.Challenges {
+		if c.Type != "http-01" {
+			continue
+		}
+		if _, err := acmeClient.Accept(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := acmeClient.WaitAuthorization(ctx, order.AuthzURLs[0]); err == nil {
+		t.Fatal("expected the authorization to fail")
+	}
+	if _, err := acmeClient.WaitOrder(ctx, order.URI); err == nil {
+		t.Fatal("expected the order to be invalid")
+	}
+
+	// The account can revoke its certificate
+	if err := acmeClient.RevokeCert(ctx, nil, chain[0], acme.CRLReasonUnspecified); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("pki/cert/" + certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revocationTime, _ := secret.Data["revocation_time"].(interface{ String() string }); revocationTime == nil || revocationTime.String() == "0" {
+		t.Fatalf("expected the certificate to be revoked: %#v", secret.Data)
+	}
+}
@@ -0,0 +1,177 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const acmeConfigPath = "config/acme"
+
+// acmeConfig holds the configuration of the ACME server
+type acmeConfig struct {
+	Enabled      bool     `json:"enabled"`
+	BaseURL      string   `json:"base_url"`
+	AllowedRoles []string `json:"allowed_roles"`
+	DefaultRole  string   `json:"default_role"`
+	DNSResolver  string   `json:"dns_resolver"`
+}
+
+// roleAllowed returns whether ACME clients can obtain certificates through
+// the given role
+func (c *acmeConfig) roleAllowed(name string) bool {
+	return strutil.StrListContains(c.AllowedRoles, "*") || strutil.StrListContains(c.AllowedRoles, name)
+}
+
+func pathConfigACME(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `If set to true, enables the ACME server.`,
+			},
+
+			"base_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `URL of this mount as reached by ACME clients,
+such as "https://vault.example.com/v1/pki". The
+ACME directory is then served under "acme/directory".`,
+			},
+
+			"allowed_roles": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of roles ACME clients can
+obtain certificates through, under
+"acme/roles/<role>/directory". "*" allows all
+roles. Defaults to none.`,
+			},
+
+			"default_role": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Role used by the ACME directory at
+"acme/directory". When unset, only the
+directories of the allowed roles are served.`,
+			},
+
+			"dns_resolver": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Address, as host:port, of the DNS resolver used
+to validate dns-01 challenges. Defaults to the
+resolver of the system.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathReadACMEConfig,
+			logical.UpdateOperation: b.pathWriteACMEConfig,
+		},
+
+		HelpSynopsis:    pathConfigACMEHelpSyn,
+		HelpDescription: pathConfigACMEHelpDesc,
+	}
+}
+
+func getACMEConfig(ctx context.Context, s logical.Storage) (*acmeConfig, error) {
+	entry, err := s.Get(ctx, acmeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config acmeConfig
+	if entry == nil {
+		return &config, nil
+	}
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (b *backend) pathReadACMEConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":       config.Enabled,
+			"base_url":      config.BaseURL,
+			"allowed_roles": config.AllowedRoles,
+			"default_role":  config.DefaultRole,
+			"dns_resolver":  config.DNSResolver,
+		},
+	}, nil
+}
+
+func (b *backend) pathWriteACMEConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if baseURLRaw, ok := data.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+	}
+	if allowedRolesRaw, ok := data.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = allowedRolesRaw.([]string)
+	}
+	if defaultRoleRaw, ok := data.GetOk("default_role"); ok {
+		config.DefaultRole = defaultRoleRaw.(string)
+	}
+	if dnsResolverRaw, ok := data.GetOk("dns_resolver"); ok {
+		config.DNSResolver = dnsResolverRaw.(string)
+	}
+
+	if config.Enabled && config.BaseURL == "" {
+		return logical.ErrorResponse("base_url is required to enable the ACME server"), nil
+	}
+	if config.BaseURL != "" && !govalidator.IsURL(config.BaseURL) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid base_url %q", config.BaseURL)), nil
+	}
+	if config.DNSResolver != "" {
+		if _, _, err := net.SplitHostPort(config.DNSResolver); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid dns_resolver: %s", err)), nil
+		}
+	}
+	if config.DefaultRole != "" {
+		role, err := b.getRole(ctx, req.Storage, config.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown default_role %q", config.DefaultRole)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(acmeConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigACMEHelpSyn = `
+Configure the ACME server.
+`
+
+const pathConfigACMEHelpDesc = `
+This endpoint enables the ACME server, which lets ACME clients such as
+certbot obtain certificates through the roles of this mount once they
+proved control of the requested domains with http-01 or dns-01 challenges.
+`
@@ -0,0 +1,191 @@
+package pki
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const autoTidyConfigPath = "config/auto-tidy"
+
+// tidyConfig holds the parameters of a tidy operation, and when it is run
+// automatically
+type tidyConfig struct {
+	Enabled          bool `json:"enabled"`
+	Interval         int  `json:"interval_duration"`
+	TidyCertStore    bool `json:"tidy_cert_store"`
+	TidyRevokedCerts bool `json:"tidy_revoked_certs"`
+	SafetyBuffer     int  `json:"safety_buffer"`
+}
+
+var defaultTidyConfig = tidyConfig{
+	Interval:     int((12 * time.Hour).Seconds()),
+	SafetyBuffer: int((72 * time.Hour).Seconds()),
+}
+
+func pathConfigAutoTidy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/auto-tidy",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to enable automatic tidy operations.`,
+			},
+
+			"interval_duration": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Interval at which tidy operations are started
+automatically. Defaults to 12 hours.`,
+			},
+
+			"tidy_cert_store": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to tidy up the certificate store
+automatically.`,
+			},
+
+			"tidy_revoked_certs": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to tidy up expired revoked
+certificates automatically.`,
+			},
+
+			"safety_buffer": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The amount of extra time that must have passed
+beyond certificate expiration before it is removed
+from the backend storage and/or revocation list.
+Defaults to 72 hours.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigAutoTidyRead,
+			logical.UpdateOperation: b.pathConfigAutoTidyWrite,
+		},
+
+		HelpSynopsis:    pathConfigAutoTidyHelpSyn,
+		HelpDescription: pathConfigAutoTidyHelpDesc,
+	}
+}
+
+func getAutoTidyConfig(ctx context.Context, s logical.Storage) (*tidyConfig, error) {
+	entry, err := s.Get(ctx, autoTidyConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultTidyConfig
+	if entry == nil {
+		return &config, nil
+	}
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (b *backend) pathConfigAutoTidyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":            config.Enabled,
+			"interval_duration":  config.Interval,
+			"tidy_cert_store":    config.TidyCertStore,
+			"tidy_revoked_certs": config.TidyRevokedCerts,
+			"safety_buffer":      config.SafetyBuffer,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigAutoTidyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if intervalRaw, ok := d.GetOk("interval_duration"); ok {
+		config.Interval = intervalRaw.(int)
+	}
+	if tidyCertStoreRaw, ok := d.GetOk("tidy_cert_store"); ok {
+		config.TidyCertStore = tidyCertStoreRaw.(bool)
+	}
+	if tidyRevokedCertsRaw, ok := d.GetOk("tidy_revoked_certs"); ok {
+		config.TidyRevokedCerts = tidyRevokedCertsRaw.(bool)
+	}
+	if safetyBufferRaw, ok := d.GetOk("safety_buffer"); ok {
+		config.SafetyBuffer = safetyBufferRaw.(int)
+	}
+
+	if config.Interval < 1 {
+		return logical.ErrorResponse("interval_duration must be greater than zero"), nil
+	}
+	if config.SafetyBuffer < 1 {
+		return logical.ErrorResponse("safety_buffer must be greater than zero"), nil
+	}
+	if config.Enabled && !config.TidyCertStore && !config.TidyRevokedCerts {
+		return logical.ErrorResponse("at least one of tidy_cert_store and tidy_revoked_certs must be set to enable automatic tidy"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(autoTidyConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// autoTidy starts a tidy operation when the last one finished more than the
+// configured interval ago
+func (b *backend) autoTidy(ctx context.Context, req *logical.Request) error {
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby | consts.ReplicationDRSecondary) {
+		return nil
+	}
+	if atomic.LoadUint32(&b.tidyPaused) == 1 {
+		return nil
+	}
+
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		return nil
+	}
+
+	b.tidyStatusLock.RLock()
+	lastTidy := b.lastTidy
+	b.tidyStatusLock.RUnlock()
+	if time.Since(lastTidy) < time.Duration(config.Interval)*time.Second {
+		return nil
+	}
+
+	b.startTidy(req, config)
+	return nil
+}
+
+const pathConfigAutoTidyHelpSyn = `
+Configure automatic tidy operations.
+`
+
+const pathConfigAutoTidyHelpDesc = `
+This endpoint configures tidy operations to be started periodically, with
+the same parameters as the "tidy" endpoint, so that expired certificates
+and revocation information don't pile up in storage. Their progress is
+reported by the "tidy-status" endpoint.
+`
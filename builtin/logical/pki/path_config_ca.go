@@ -68,6 +68,14 @@ func (b *backend) pathCAWrite(ctx context.Context, req *logical.Request, data *f
 		return nil, errwrap.Wrapf("error converting raw values into cert bundle: {{err}}", err)
 	}
 
+	// Keep the current CA as an issuer before replacing it
+	b.issuersLock.Lock()
+	err = b.migrateLegacyCA(ctx, req.Storage)
+	b.issuersLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
 	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
 	if err != nil {
 		return nil, err
@@ -77,6 +85,10 @@ func (b *backend) pathCAWrite(ctx context.Context, req *logical.Request, data *f
 		return nil, err
 	}
 
+	if err := b.registerDefaultIssuer(ctx, req.Storage, cb); err != nil {
+		return nil, err
+	}
+
 	// For ease of later use, also store just the certificate at a known
 	// location, plus a fresh CRL
 	entry.Key = "ca"
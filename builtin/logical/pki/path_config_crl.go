@@ -13,8 +13,58 @@ import (
 
 // CRLConfig holds basic CRL configuration information
 type crlConfig struct {
-	Expiry  string `json:"expiry" mapstructure:"expiry"`
-	Disable bool   `json:"disable"`
+	Expiry                 string `json:"expiry" mapstructure:"expiry"`
+	Disable                bool   `json:"disable"`
+	AutoRebuild            bool   `json:"auto_rebuild"`
+	AutoRebuildGracePeriod string `json:"auto_rebuild_grace_period"`
+	EnableDelta            bool   `json:"enable_delta"`
+	DeltaRebuildInterval   string `json:"delta_rebuild_interval"`
+	OCSPDisable            bool   `json:"ocsp_disable"`
+	OCSPExpiry             string `json:"ocsp_expiry"`
+}
+
+const (
+	defaultAutoRebuildGracePeriod = 12 * time.Hour
+	defaultDeltaRebuildInterval   = 15 * time.Minute
+	defaultOCSPExpiry             = 12 * time.Hour
+)
+
+// autoRebuildGracePeriod returns how long before its expiry the CRL is
+// rebuilt when auto_rebuild is set
+func (c *crlConfig) autoRebuildGracePeriod() time.Duration {
+	if c == nil || c.AutoRebuildGracePeriod == "" {
+		return defaultAutoRebuildGracePeriod
+	}
+	d, err := time.ParseDuration(c.AutoRebuildGracePeriod)
+	if err != nil {
+		return defaultAutoRebuildGracePeriod
+	}
+	return d
+}
+
+// deltaRebuildInterval returns how often the delta CRL is rebuilt when
+// enable_delta is set
+func (c *crlConfig) deltaRebuildInterval() time.Duration {
+	if c == nil || c.DeltaRebuildInterval == "" {
+		return defaultDeltaRebuildInterval
+	}
+	d, err := time.ParseDuration(c.DeltaRebuildInterval)
+	if err != nil {
+		return defaultDeltaRebuildInterval
+	}
+	return d
+}
+
+// ocspExpiry returns how long OCSP responses are valid
+func (c *crlConfig) ocspExpiry() time.Duration {
+	if c == nil || c.OCSPExpiry == "" {
+		return defaultOCSPExpiry
+	}
+	d, err := time.ParseDuration(c.OCSPExpiry)
+	if err != nil {
+		return defaultOCSPExpiry
+	}
+	return d
 }
 
 func pathConfigCRL(b *backend) *framework.Path {
@@ -31,6 +81,39 @@ valid; defaults to 72 hours`,
 				Type:        framework.TypeBool,
 				Description: `If set to true, disables generating the CRL entirely.`,
 			},
+			"auto_rebuild": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set to true, the CRL is rebuilt periodically
+before it expires instead of on every revocation.`,
+			},
+			"auto_rebuild_grace_period": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How long before its expiry the CRL is rebuilt
+when auto_rebuild is set; defaults to 12 hours`,
+				Default: "12h",
+			},
+			"enable_delta": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set to true, a delta CRL listing the
+certificates revoked since the last complete
+CRL is built periodically. Requires auto_rebuild.`,
+			},
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How often the delta CRL is rebuilt; defaults
+to 15 minutes`,
+				Default: "15m",
+			},
+			"ocsp_disable": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `If set to true, disables the OCSP responder.`,
+			},
+			"ocsp_expiry": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The amount of time OCSP responses are valid;
+defaults to 12 hours`,
+				Default: "12h",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -71,8 +154,14 @@ func (b *backend) pathCRLRead(ctx context.Context, req *logical.Request, data *f
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"expiry":  config.Expiry,
-			"disable": config.Disable,
+			"expiry":                    config.Expiry,
+			"disable":                   config.Disable,
+			"auto_rebuild":              config.AutoRebuild,
+			"auto_rebuild_grace_period": config.autoRebuildGracePeriod().String(),
+			"enable_delta":              config.EnableDelta,
+			"delta_rebuild_interval":    config.deltaRebuildInterval().String(),
+			"ocsp_disable":              config.OCSPDisable,
+			"ocsp_expiry":               config.ocspExpiry().String(),
 		},
 	}, nil
 }
@@ -85,6 +174,7 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 	if config == nil {
 		config = &crlConfig{}
 	}
+	oldEnableDelta := config.EnableDelta
 
 	if expiryRaw, ok := d.GetOk("expiry"); ok {
 		expiry := expiryRaw.(string)
@@ -101,6 +191,59 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 		config.Disable = disableRaw.(bool)
 	}
 
+	if autoRebuildRaw, ok := d.GetOk("auto_rebuild"); ok {
+		config.AutoRebuild = autoRebuildRaw.(bool)
+	}
+
+	if gracePeriodRaw, ok := d.GetOk("auto_rebuild_grace_period"); ok {
+		gracePeriod := gracePeriodRaw.(string)
+		if _, err := time.ParseDuration(gracePeriod); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("given auto_rebuild_grace_period could not be decoded: %s", err)), nil
+		}
+		config.AutoRebuildGracePeriod = gracePeriod
+	}
+
+	if enableDeltaRaw, ok := d.GetOk("enable_delta"); ok {
+		config.EnableDelta = enableDeltaRaw.(bool)
+	}
+
+	if intervalRaw, ok := d.GetOk("delta_rebuild_interval"); ok {
+		interval := intervalRaw.(string)
+		dur, err := time.ParseDuration(interval)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("given delta_rebuild_interval could not be decoded: %s", err)), nil
+		}
+		if dur <= 0 {
+			return logical.ErrorResponse("delta_rebuild_interval must be positive"), nil
+		}
+		config.DeltaRebuildInterval = interval
+	}
+
+	if ocspDisableRaw, ok := d.GetOk("ocsp_disable"); ok {
+		config.OCSPDisable = ocspDisableRaw.(bool)
+	}
+
+	if ocspExpiryRaw, ok := d.GetOk("ocsp_expiry"); ok {
+		ocspExpiry := ocspExpiryRaw.(string)
+		if _, err := time.ParseDuration(ocspExpiry); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("given ocsp_expiry could not be decoded: %s", err)), nil
+		}
+		config.OCSPExpiry = ocspExpiry
+	}
+
+	if config.EnableDelta && !config.AutoRebuild {
+		return logical.ErrorResponse("enable_delta requires auto_rebuild to be set"), nil
+	}
+	if config.AutoRebuild {
+		expiry := b.crlLifetime
+		if config.Expiry != "" {
+			expiry, _ = time.ParseDuration(config.Expiry)
+		}
+		if config.autoRebuildGracePeriod() >= expiry {
+			return logical.ErrorResponse("auto_rebuild_grace_period must be shorter than the CRL expiry"), nil
+		}
+	}
+
 	entry, err := logical.StorageEntryJSON("config/crl", config)
 	if err != nil {
 		return nil, err
@@ -110,8 +253,9 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 		return nil, err
 	}
 
-	if oldDisable != config.Disable {
-		// It wasn't disabled but now it is, rotate
+	if oldDisable != config.Disable || (config.EnableDelta && !oldEnableDelta) {
+		// It wasn't disabled but now it is, or the delta CRL needs a
+		// numbered base CRL, rotate
 		crlErr := buildCRL(ctx, b, req, true)
 		switch crlErr.(type) {
 		case errutil.UserError:
@@ -125,9 +269,10 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 }
 
 const pathConfigCRLHelpSyn = `
-Configure the CRL expiration.
+Configure the CRL expiration, rebuilding and the OCSP responder.
 `
 
 const pathConfigCRLHelpDesc = `
-This endpoint allows configuration of the CRL lifetime.
+This endpoint allows configuration of the CRL lifetime, of its periodic
+rebuilding along with delta CRLs, and of the OCSP responder.
 `
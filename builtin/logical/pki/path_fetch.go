@@ -53,6 +53,20 @@ func pathFetchCRL(b *backend) *framework.Path {
 	}
 }
 
+// Returns the delta CRL in raw format
+func pathFetchDeltaCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/delta(/pem)?`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchRead,
+		},
+
+		HelpSynopsis:    pathFetchHelpSyn,
+		HelpDescription: pathFetchHelpDesc,
+	}
+}
+
 // Returns any valid (non-revoked) cert. Since "ca" fits the pattern, this path
 // also handles returning the CA cert in a non-raw format.
 func pathFetchValid(b *backend) *framework.Path {
@@ -75,10 +89,10 @@ hyphen-separated octal`,
 	}
 }
 
-// This returns the CRL in a non-raw format
+// This returns the CRL or the delta CRL in a non-raw format
 func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: `cert/crl`,
+		Pattern: `cert/(delta-)?crl`,
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.pathFetchRead,
@@ -148,6 +162,15 @@ func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data
 	case req.Path == "cert/crl":
 		serial = "crl"
 		pemType = "X509 CRL"
+	case req.Path == "crl/delta" || req.Path == "crl/delta/pem":
+		serial = deltaCRLPath
+		contentType = "application/pkix-crl"
+		if req.Path == "crl/delta/pem" {
+			pemType = "X509 CRL"
+		}
+	case req.Path == "cert/delta-crl":
+		serial = deltaCRLPath
+		pemType = "X509 CRL"
 	default:
 		serial = data.Get("serial").(string)
 		pemType = "CERTIFICATE"
@@ -270,7 +293,7 @@ Fetch a CA, CRL, CA Chain, or non-revoked certificate.
 const pathFetchHelpDesc = `
 This allows certificates to be fetched. If using the fetch/ prefix any non-revoked certificate can be fetched.
 
-Using "ca" or "crl" as the value fetches the appropriate information in DER encoding. Add "/pem" to either to get PEM encoding.
+Using "ca" or "crl" as the value fetches the appropriate information in DER encoding. Add "/pem" to either to get PEM encoding. Using "crl/delta" fetches the delta CRL the same way.
 
 Using "ca_chain" as the value fetches the certificate authority trust chain in PEM encoding.
 `
@@ -2,8 +2,12 @@ package pki
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -62,6 +66,32 @@ endpoint.`,
 	return ret
 }
 
+func pathCrossSignIntermediate(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "intermediate/cross-sign",
+
+		Fields: map[string]*framework.FieldSchema{
+			"format": &framework.FieldSchema{
+				Type:          framework.TypeString,
+				Default:       "pem",
+				Description:   `Format for returned data. Can be "pem" or "der". Defaults to "pem".`,
+				AllowedValues: []interface{}{"pem", "der"},
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCrossSignIntermediate,
+		},
+
+		HelpSynopsis:    pathCrossSignIntermediateHelpSyn,
+		HelpDescription: pathCrossSignIntermediateHelpDesc,
+	}
+
+	ret.Fields = addIssuerRefField(ret.Fields)
+
+	return ret
+}
+
 func (b *backend) pathGenerateIntermediate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	var err error
 
@@ -126,6 +156,15 @@ func (b *backend) pathGenerateIntermediate(ctx context.Context, req *logical.Req
 		}
 	}
 
+	// Keep the current CA as an issuer before replacing it with the pending
+	// key
+	b.issuersLock.Lock()
+	err = b.migrateLegacyCA(ctx, req.Storage)
+	b.issuersLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
 	cb := &certutil.CertBundle{}
 	cb.PrivateKey = csrb.PrivateKey
 	cb.PrivateKeyType = csrb.PrivateKeyType
@@ -215,6 +254,10 @@ func (b *backend) pathSetSignedIntermediate(ctx context.Context, req *logical.Re
 		return nil, err
 	}
 
+	if err := b.registerDefaultIssuer(ctx, req.Storage, cb); err != nil {
+		return nil, err
+	}
+
 	entry.Key = "certs/" + normalizeSerial(cb.SerialNumber)
 	entry.Value = inputBundle.CertificateBytes
 	err = req.Storage.Put(ctx, entry)
@@ -237,6 +280,55 @@ func (b *backend) pathSetSignedIntermediate(ctx context.Context, req *logical.Re
 	return nil, err
 }
 
+func (b *backend) pathCrossSignIntermediate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	format := getFormat(data)
+	if format != "pem" && format != "der" {
+		return logical.ErrorResponse(`the "format" parameter must be "pem" or "der"`), nil
+	}
+
+	caInfo, err := b.fetchCAInfoByIssuerRef(ctx, req, data.Get("issuer_ref").(string))
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+	if caInfo.PrivateKey == nil {
+		return logical.ErrorResponse("the issuer has no private key"), nil
+	}
+
+	// The CSR keeps the subject and key of the issuer, so that the
+	// certificate signed by another CA can be used in its place
+	cert := caInfo.Certificate
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		RawSubject:     cert.RawSubject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+	}, caInfo.PrivateKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("error creating CSR: {{err}}", err)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{},
+	}
+	switch format {
+	case "pem":
+		resp.Data["csr"] = strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE REQUEST",
+			Bytes: csrBytes,
+		})))
+	case "der":
+		resp.Data["csr"] = base64.StdEncoding.EncodeToString(csrBytes)
+	}
+
+	return resp, nil
+}
+
 const pathGenerateIntermediateHelpSyn = `
 Generate a new CSR and private key used for signing.
 `
@@ -252,3 +344,13 @@ Provide the signed intermediate CA cert.
 const pathSetSignedIntermediateHelpDesc = `
 See the API documentation for more information.
 `
+
+const pathCrossSignIntermediateHelpSyn = `
+Generate a CSR to have an issuer cross-signed by another CA.
+`
+
+const pathCrossSignIntermediateHelpDesc = `
+This path generates a CSR with the subject and private key of an existing
+issuer. Once signed by another CA, the resulting certificate can be imported
+with the "issuers/import/bundle" path without its private key.
+`
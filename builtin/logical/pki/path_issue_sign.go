@@ -27,6 +27,7 @@ func pathIssue(b *backend) *framework.Path {
 	}
 
 	ret.Fields = addNonCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addIssuerRefField(ret.Fields)
 	return ret
 }
 
@@ -43,6 +44,7 @@ func pathSign(b *backend) *framework.Path {
 	}
 
 	ret.Fields = addNonCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addIssuerRefField(ret.Fields)
 
 	ret.Fields["csr"] = &framework.FieldSchema{
 		Type:        framework.TypeString,
@@ -66,6 +68,7 @@ func pathSignVerbatim(b *backend) *framework.Path {
 	}
 
 	ret.Fields = addNonCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addIssuerRefField(ret.Fields)
 
 	ret.Fields["csr"] = &framework.FieldSchema{
 		Type:    framework.TypeString,
@@ -201,7 +204,7 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(ctx, req)
+	signingBundle, caErr := b.fetchCAInfoByIssuerRef(ctx, req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -253,9 +256,7 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	case "pem":
 		respData["issuing_ca"] = signingCB.Certificate
 		respData["certificate"] = cb.Certificate
-		if cb.CAChain != nil && len(cb.CAChain) > 0 {
-			respData["ca_chain"] = cb.CAChain
-		}
+		respData["ca_chain"] = caChainPEM(signingBundle)
 		if !useCSR {
 			respData["private_key"] = cb.PrivateKey
 			respData["private_key_type"] = cb.PrivateKeyType
@@ -264,9 +265,7 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	case "pem_bundle":
 		respData["issuing_ca"] = signingCB.Certificate
 		respData["certificate"] = cb.ToPEMBundle()
-		if cb.CAChain != nil && len(cb.CAChain) > 0 {
-			respData["ca_chain"] = cb.CAChain
-		}
+		respData["ca_chain"] = caChainPEM(signingBundle)
 		if !useCSR {
 			respData["private_key"] = cb.PrivateKey
 			respData["private_key_type"] = cb.PrivateKeyType
@@ -277,12 +276,10 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 		respData["issuing_ca"] = base64.StdEncoding.EncodeToString(signingBundle.CertificateBytes)
 
 		var caChain []string
-		for _, caCert := range parsedBundle.CAChain {
+		for _, caCert := range fullCAChain(signingBundle) {
 			caChain = append(caChain, base64.StdEncoding.EncodeToString(caCert.Bytes))
 		}
-		if caChain != nil && len(caChain) > 0 {
-			respData["ca_chain"] = caChain
-		}
+		respData["ca_chain"] = caChain
 
 		if !useCSR {
 			respData["private_key"] = base64.StdEncoding.EncodeToString(parsedBundle.PrivateKeyBytes)
@@ -0,0 +1,739 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	issuerStoragePrefix = "issuers/"
+	issuersConfigPath   = "config/issuers"
+
+	// defaultIssuerRef refers to the default issuer, whose bundle is also
+	// stored at config/ca_bundle for the paths predating multiple issuers
+	defaultIssuerRef = "default"
+)
+
+var issuerNameRegex = regexp.MustCompile(`^\w(([\w-.]+)?\w)?$`)
+
+type issuerEntry struct {
+	ID     string               `json:"id"`
+	Name   string               `json:"name"`
+	Bundle *certutil.CertBundle `json:"bundle"`
+}
+
+type issuersConfig struct {
+	DefaultIssuerID string `json:"default"`
+}
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuersList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Reference to the issuer: its ID, its name,
+or "default" for the default issuer.`,
+			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of the issuer, which can be used in place
+of its ID. Must be unique, and can't be "default".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerWrite,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuerHelpSyn,
+		HelpDescription: pathIssuerHelpDesc,
+	}
+}
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+
+		Fields: map[string]*framework.FieldSchema{
+			"default": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Reference to the issuer to use by default.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigIssuersRead,
+			logical.UpdateOperation: b.pathConfigIssuersWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func pathImportIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/import/bundle",
+
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format CA certificate, optionally followed
+by its chain and preceded by its unencrypted
+private key. When the key is omitted, the key
+of an existing issuer with the same public key
+is used.`,
+			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the imported issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportIssuer,
+		},
+
+		HelpSynopsis:    pathImportIssuerHelpSyn,
+		HelpDescription: pathImportIssuerHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuersList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	issuers, err := listIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	keyInfo := make(map[string]interface{}, len(issuers))
+	for _, issuer := range issuers {
+		keys = append(keys, issuer.ID)
+		keyInfo[issuer.ID] = map[string]interface{}{
+			"issuer_name": issuer.Name,
+			"is_default":  issuer.ID == config.DefaultIssuerID,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *backend) pathIssuerRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuerRef(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	caChain := []string{issuer.Bundle.Certificate}
+	for _, cert := range issuer.Bundle.CAChain {
+		if cert != issuer.Bundle.Certificate {
+			caChain = append(caChain, cert)
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     issuer.ID,
+			"issuer_name":   issuer.Name,
+			"certificate":   issuer.Bundle.Certificate,
+			"ca_chain":      caChain,
+			"serial_number": issuer.Bundle.SerialNumber,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuerRef(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return logical.ErrorResponse("unknown issuer"), nil
+	}
+
+	if nameRaw, ok := data.GetOk("issuer_name"); ok {
+		name := nameRaw.(string)
+		if err := validateIssuerName(ctx, req.Storage, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		issuer.Name = name
+	}
+
+	if err := storeIssuer(ctx, req.Storage, issuer); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuerRef(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if issuer.ID == config.DefaultIssuerID {
+		return logical.ErrorResponse("the default issuer can't be deleted; set another default issuer first"), nil
+	}
+
+	return nil, req.Storage.Delete(ctx, issuerStoragePrefix+issuer.ID)
+}
+
+func (b *backend) pathConfigIssuersRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": config.DefaultIssuerID,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigIssuersWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	ref := data.Get("default").(string)
+	if ref == "" {
+		return logical.ErrorResponse("missing default issuer"), nil
+	}
+	issuer, err := resolveIssuerRef(ctx, req.Storage, ref)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown issuer %q", ref)), nil
+	}
+
+	if err := b.setDefaultIssuer(ctx, req, issuer); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathImportIssuer(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pemBundle := data.Get("pem_bundle").(string)
+	if pemBundle == "" {
+		return logical.ErrorResponse("'pem_bundle' was empty"), nil
+	}
+
+	parsedBundle, err := certutil.ParsePEMBundle(pemBundle)
+	if err != nil {
+		switch err.(type) {
+		case errutil.InternalError:
+			return nil, err
+		default:
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	if parsedBundle.Certificate == nil {
+		return logical.ErrorResponse("no certificate found in the PEM bundle"), nil
+	}
+	if !parsedBundle.Certificate.IsCA {
+		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuers, err := listIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{}
+	for _, issuer := range issuers {
+		existing, err := issuer.Bundle.ToParsedCertBundle()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(existing.CertificateBytes, parsedBundle.CertificateBytes) {
+			resp.AddWarning("the certificate was already imported")
+			resp.Data = map[string]interface{}{
+				"issuer_id": issuer.ID,
+			}
+			return resp, nil
+		}
+
+		// Cross-signed certificates share the key of an existing issuer
+		if parsedBundle.PrivateKey == nil {
+			same, err := samePublicKey(existing.Certificate, parsedBundle.Certificate)
+			if err != nil {
+				return nil, err
+			}
+			if same && existing.PrivateKey != nil {
+				parsedBundle.PrivateKey = existing.PrivateKey
+				parsedBundle.PrivateKeyType = existing.PrivateKeyType
+				parsedBundle.PrivateKeyBytes = existing.PrivateKeyBytes
+			}
+		}
+	}
+
+	if parsedBundle.PrivateKey == nil {
+		return logical.ErrorResponse("private key not found in the PEM bundle nor in the existing issuers"), nil
+	}
+	if parsedBundle.PrivateKeyType == certutil.UnknownPrivateKey {
+		return logical.ErrorResponse("unknown private key found in the PEM bundle"), nil
+	}
+	if err := parsedBundle.Verify(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("verification of the PEM bundle failed: %v", err)), nil
+	}
+
+	name := data.Get("issuer_name").(string)
+	if err := validateIssuerName(ctx, req.Storage, name, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, errwrap.Wrapf("error converting raw values into cert bundle: {{err}}", err)
+	}
+
+	issuer, err := b.addIssuer(ctx, req.Storage, cb, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the issuer by default when the mount doesn't have a CA yet
+	legacyEntry, err := req.Storage.Get(ctx, "config/ca_bundle")
+	if err != nil {
+		return nil, err
+	}
+	if legacyEntry == nil {
+		if err := b.setDefaultIssuer(ctx, req, issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	resp.Data = map[string]interface{}{
+		"issuer_id": issuer.ID,
+	}
+	return resp, nil
+}
+
+// fetchCAInfoByIssuerRef fetches the CA info of the referenced issuer, which
+// is the default one when the reference is empty
+func (b *backend) fetchCAInfoByIssuerRef(ctx context.Context, req *logical.Request, ref string) (*certutil.CAInfoBundle, error) {
+	if ref == "" || ref == defaultIssuerRef {
+		return fetchCAInfo(ctx, req)
+	}
+
+	issuer, err := resolveIssuerRef(ctx, req.Storage, ref)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch issuer: %v", err)}
+	}
+	if issuer == nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", ref)}
+	}
+
+	return caInfoFromCertBundle(ctx, req, issuer.Bundle)
+}
+
+// migrateLegacyCA registers the CA set before multiple issuers were
+// supported as the default issuer. It must be called with the issuers lock
+// held.
+func (b *backend) migrateLegacyCA(ctx context.Context, s logical.Storage) error {
+	configEntry, err := s.Get(ctx, issuersConfigPath)
+	if err != nil {
+		return err
+	}
+	if configEntry != nil {
+		return nil
+	}
+
+	entry, err := s.Get(ctx, "config/ca_bundle")
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+	var cb certutil.CertBundle
+	if err := entry.DecodeJSON(&cb); err != nil {
+		return err
+	}
+
+	// A pending intermediate only has a key and isn't an issuer yet
+	if cb.Certificate == "" {
+		return nil
+	}
+
+	_, err = b.addIssuer(ctx, s, &cb, "", true)
+	return err
+}
+
+// addIssuer stores a new issuer for the given bundle. When makeDefault is
+// set, the issuer becomes the default one; the caller is then responsible
+// for storing its bundle at config/ca_bundle. It must be called with the
+// issuers lock held.
+func (b *backend) addIssuer(ctx context.Context, s logical.Storage, cb *certutil.CertBundle, name string, makeDefault bool) (*issuerEntry, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	issuer := &issuerEntry{
+		ID:     id,
+		Name:   name,
+		Bundle: cb,
+	}
+	if err := storeIssuer(ctx, s, issuer); err != nil {
+		return nil, err
+	}
+
+	if makeDefault {
+		if err := storeIssuersConfig(ctx, s, &issuersConfig{DefaultIssuerID: id}); err != nil {
+			return nil, err
+		}
+	}
+
+	return issuer, nil
+}
+
+// registerDefaultIssuer registers a CA set through the paths predating
+// multiple issuers, which already stored it at config/ca_bundle, as the
+// default issuer. An existing issuer with the same certificate is reused.
+func (b *backend) registerDefaultIssuer(ctx context.Context, s logical.Storage, cb *certutil.CertBundle) error {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, issuer := range issuers {
+		if issuer.Bundle.Certificate == cb.Certificate {
+			issuer.Bundle = cb
+			if err := storeIssuer(ctx, s, issuer); err != nil {
+				return err
+			}
+			return storeIssuersConfig(ctx, s, &issuersConfig{DefaultIssuerID: issuer.ID})
+		}
+	}
+
+	_, err = b.addIssuer(ctx, s, cb, "", true)
+	return err
+}
+
+// setDefaultIssuer makes the issuer the default one, storing its bundle at
+// config/ca_bundle and building a fresh CRL. It must be called with the
+// issuers lock held.
+func (b *backend) setDefaultIssuer(ctx context.Context, req *logical.Request, issuer *issuerEntry) error {
+	parsedBundle, err := issuer.Bundle.ToParsedCertBundle()
+	if err != nil {
+		return err
+	}
+
+	if err := storeIssuersConfig(ctx, req.Storage, &issuersConfig{DefaultIssuerID: issuer.ID}); err != nil {
+		return err
+	}
+
+	entry, err := logical.StorageEntryJSON("config/ca_bundle", issuer.Bundle)
+	if err != nil {
+		return err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	err = req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   "ca",
+		Value: parsedBundle.CertificateBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return buildCRL(ctx, b, req, true)
+}
+
+// deleteIssuers removes all the issuers
+func (b *backend) deleteIssuers(ctx context.Context, s logical.Storage) error {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	ids, err := s.List(ctx, issuerStoragePrefix)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Delete(ctx, issuerStoragePrefix+id); err != nil {
+			return err
+		}
+	}
+
+	return s.Delete(ctx, issuersConfigPath)
+}
+
+func getIssuersConfig(ctx context.Context, s logical.Storage) (*issuersConfig, error) {
+	entry, err := s.Get(ctx, issuersConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config issuersConfig
+	if entry != nil {
+		if err := entry.DecodeJSON(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+func storeIssuersConfig(ctx context.Context, s logical.Storage, config *issuersConfig) error {
+	entry, err := logical.StorageEntryJSON(issuersConfigPath, config)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func fetchIssuer(ctx context.Context, s logical.Storage, id string) (*issuerEntry, error) {
+	entry, err := s.Get(ctx, issuerStoragePrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer issuerEntry
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, err
+	}
+
+	return &issuer, nil
+}
+
+func storeIssuer(ctx context.Context, s logical.Storage, issuer *issuerEntry) error {
+	entry, err := logical.StorageEntryJSON(issuerStoragePrefix+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func listIssuers(ctx context.Context, s logical.Storage) ([]*issuerEntry, error) {
+	ids, err := s.List(ctx, issuerStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	issuers := make([]*issuerEntry, 0, len(ids))
+	for _, id := range ids {
+		issuer, err := fetchIssuer(ctx, s, id)
+		if err != nil {
+			return nil, err
+		}
+		if issuer != nil {
+			issuers = append(issuers, issuer)
+		}
+	}
+
+	return issuers, nil
+}
+
+// resolveIssuerRef returns the issuer referenced by ID, by name, or as
+// "default"
+func resolveIssuerRef(ctx context.Context, s logical.Storage, ref string) (*issuerEntry, error) {
+	if ref == defaultIssuerRef {
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if config.DefaultIssuerID == "" {
+			return nil, nil
+		}
+		ref = config.DefaultIssuerID
+	}
+
+	issuer, err := fetchIssuer(ctx, s, ref)
+	if err != nil || issuer != nil {
+		return issuer, err
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range issuers {
+		if issuer.Name != "" && issuer.Name == ref {
+			return issuer, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// validateIssuerName checks that the name can be given to the issuer with
+// the given ID, which is empty for new issuers
+func validateIssuerName(ctx context.Context, s logical.Storage, name, id string) error {
+	if name == "" {
+		return nil
+	}
+	if name == defaultIssuerRef {
+		return fmt.Errorf("%q is reserved and can't be used as an issuer name", defaultIssuerRef)
+	}
+	if !issuerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid issuer name %q", name)
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, issuer := range issuers {
+		if issuer.ID == id {
+			continue
+		}
+		if issuer.Name == name || issuer.ID == name {
+			return fmt.Errorf("issuer name %q is already in use", name)
+		}
+	}
+
+	return nil
+}
+
+func samePublicKey(a, b *x509.Certificate) (bool, error) {
+	aKey, err := x509.MarshalPKIXPublicKey(a.PublicKey)
+	if err != nil {
+		return false, err
+	}
+	bKey, err := x509.MarshalPKIXPublicKey(b.PublicKey)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aKey, bKey), nil
+}
+
+const pathListIssuersHelpSyn = `
+List the issuers of this mount.
+`
+
+const pathListIssuersHelpDesc = `
+This path lists the IDs of the CA certificates, or issuers, this mount can
+sign certificates with, along with their names and whether they are used by
+default.
+`
+
+const pathIssuerHelpSyn = `
+Read, rename or delete an issuer.
+`
+
+const pathIssuerHelpDesc = `
+This path returns the certificate and CA chain of the referenced issuer. It
+can also be used to name the issuer, or to delete it if it isn't the default
+one. Issuers can be referenced by ID, by name, or as "default".
+`
+
+const pathConfigIssuersHelpSyn = `
+Read or set the default issuer.
+`
+
+const pathConfigIssuersHelpDesc = `
+This path sets the issuer used when no issuer is referenced, including by the
+paths predating multiple issuers such as "ca" and "crl".
+`
+
+const pathImportIssuerHelpSyn = `
+Import a CA certificate as a new issuer.
+`
+
+const pathImportIssuerHelpDesc = `
+This path imports a CA certificate, with its chain and private key, as a new
+issuer that doesn't become the default one unless the mount has no CA yet.
+
+The private key can be omitted when the certificate shares its key with an
+existing issuer, as is the case for a cross-signed certificate obtained by
+having another CA sign a CSR from "intermediate/cross-sign".
+`
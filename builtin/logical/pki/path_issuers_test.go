@@ -0,0 +1,189 @@
+package pki
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPki_Issuers(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	otherB, otherS := createBackendWithStorage(t)
+
+	request := func(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	mustRequest := func(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(b, s, op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := mustRequest(b, s, logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "root.example.com",
+		"ttl":         "40h",
+	})
+	rootCert := resp.Data["certificate"].(string)
+	mustRequest(otherB, otherS, logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "other.example.com",
+		"ttl":         "40h",
+	})
+
+	// The generated root is registered as the default issuer
+	resp = mustRequest(b, s, logical.ListOperation, "issuers/", nil)
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 {
+		t.Fatalf("expected a single issuer, got: %#v", keys)
+	}
+	rootID := keys[0]
+	resp = mustRequest(b, s, logical.ReadOperation, "issuer/default", nil)
+	if resp.Data["issuer_id"] != rootID || resp.Data["certificate"] != rootCert {
+		t.Fatalf("bad default issuer: %#v", resp.Data)
+	}
+	mustRequest(b, s, logical.UpdateOperation, "issuer/"+rootID, map[string]interface{}{
+		"issuer_name": "root",
+	})
+
+	// Have the root cross-signed by the other mount
+	resp = mustRequest(b, s, logical.UpdateOperation, "intermediate/cross-sign", map[string]interface{}{
+		"issuer_ref": "root",
+	})
+	csr := resp.Data["csr"].(string)
+	resp = mustRequest(otherB, otherS, logical.UpdateOperation, "root/sign-intermediate", map[string]interface{}{
+		"csr":            csr,
+		"use_csr_values": true,
+		"ttl":            "20h",
+	})
+	crossCert := resp.Data["certificate"].(string)
+	otherRoot := resp.Data["issuing_ca"].(string)
+
+	// The cross-signed certificate is imported with the key of the root
+	resp = mustRequest(b, s, logical.UpdateOperation, "issuers/import/bundle", map[string]interface{}{
+		"pem_bundle":  crossCert + "\n" + otherRoot,
+		"issuer_name": "cross",
+	})
+	crossID := resp.Data["issuer_id"].(string)
+	if crossID == "" || crossID == rootID {
+		t.Fatalf("bad imported issuer: %#v", resp.Data)
+	}
+	resp = mustRequest(b, s, logical.UpdateOperation, "issuers/import/bundle", map[string]interface{}{
+		"pem_bundle": crossCert + "\n" + otherRoot,
+	})
+	if resp.Data["issuer_id"] != crossID || len(resp.Warnings) == 0 {
+		t.Fatalf("expected the existing issuer to be returned with a warning: %#v", resp)
+	}
+	if resp, err := request(b, s, logical.UpdateOperation, "issuers/import/bundle", map[string]interface{}{
+		"pem_bundle": otherRoot,
+	}); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an import without a known key to fail: %#v", resp)
+	}
+
+	mustRequest(b, s, logical.UpdateOperation, "roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	issue := func(ref string) []string {
+		t.Helper()
+		resp := mustRequest(b, s, logical.UpdateOperation, "issue/example", map[string]interface{}{
+			"common_name": "test.example.com",
+			"issuer_ref":  ref,
+			"ttl":         "1h",
+		})
+		return resp.Data["ca_chain"].([]string)
+	}
+
+	// Certificates issued by a root carry it as their chain, and those issued
+	// by the cross-signed issuer the whole chain up to the other root
+	if chain := issue(""); len(chain) != 1 || chain[0] != rootCert {
+		t.Fatalf("bad chain: %#v", chain)
+	}
+	if chain := issue("cross"); len(chain) != 2 || chain[0] != crossCert || chain[1] != otherRoot {
+		t.Fatalf("bad chain: %#v", chain)
+	}
+	if resp, err := request(b, s, logical.UpdateOperation, "issue/example", map[string]interface{}{
+		"common_name": "test.example.com",
+		"issuer_ref":  "unknown",
+	}); err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an unknown issuer to be rejected: %#v", resp)
+	}
+
+	// Switching the default issuer updates the CA of the mount
+	mustRequest(b, s, logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "cross",
+	})
+	resp = mustRequest(b, s, logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != crossID {
+		t.Fatalf("bad default issuer: %#v", resp.Data)
+	}
+	resp = mustRequest(b, s, logical.ReadOperation, "cert/ca", nil)
+	if strings.TrimSpace(resp.Data["certificate"].(string)) != crossCert {
+		t.Fatalf("expected the CA to be the cross-signed certificate, got: %s", resp.Data["certificate"])
+	}
+	if chain := issue(""); len(chain) != 2 || chain[0] != crossCert {
+		t.Fatalf("bad chain: %#v", chain)
+	}
+
+	// The default issuer can't be deleted
+	if resp, err := request(b, s, logical.DeleteOperation, "issuer/cross", nil); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected deleting the default issuer to fail: %#v", resp)
+	}
+	mustRequest(b, s, logical.DeleteOperation, "issuer/root", nil)
+	resp = mustRequest(b, s, logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != crossID {
+		t.Fatalf("bad issuers: %#v", keys)
+	}
+}
+
+func TestPki_IssuersLegacyCA(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/internal",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"common_name": "root.example.com",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	rootCert := resp.Data["certificate"].(string)
+
+	// Drop the issuers to get the storage layout predating them
+	ids, err := s.List(context.Background(), issuerStoragePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if err := s.Delete(context.Background(), issuerStoragePrefix+id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete(context.Background(), issuersConfigPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The CA is then registered as the default issuer on first use
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issuer/default",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["certificate"] != rootCert {
+		t.Fatalf("bad default issuer: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,263 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPRequestSize bounds the size of the OCSP requests read from POST
+// bodies, which are only a few hundred bytes
+const maxOCSPRequestSize = 64 * 1024
+
+func pathOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathOCSPPost,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+func pathOCSPGet(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp/" + framework.MatchAllRegex("req"),
+
+		Fields: map[string]*framework.FieldSchema{
+			"req": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Base64 encoding of the DER-encoded OCSP request.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathOCSPGet,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+func (b *backend) pathOCSPPost(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+		return ocspRawResponse(http.StatusBadRequest, ocsp.MalformedRequestErrorResponse), nil
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(req.HTTPRequest.Body, maxOCSPRequestSize))
+	if err != nil {
+		return ocspRawResponse(http.StatusBadRequest, ocsp.MalformedRequestErrorResponse), nil
+	}
+
+	return b.ocspResponse(ctx, req, raw), nil
+}
+
+func (b *backend) pathOCSPGet(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raw, err := base64.StdEncoding.DecodeString(data.Get("req").(string))
+	if err != nil {
+		return ocspRawResponse(http.StatusBadRequest, ocsp.MalformedRequestErrorResponse), nil
+	}
+
+	return b.ocspResponse(ctx, req, raw), nil
+}
+
+// ocspResponse answers the given DER-encoded OCSP request. Failures are
+// reported with the OCSP error responses, as clients can't handle others.
+func (b *backend) ocspResponse(ctx context.Context, req *logical.Request, raw []byte) *logical.Response {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		b.Logger().Error("error fetching CRL config information", "error", err)
+		return ocspRawResponse(http.StatusInternalServerError, ocsp.InternalErrorErrorResponse)
+	}
+	if crlInfo != nil && crlInfo.OCSPDisable {
+		return ocspRawResponse(http.StatusUnauthorized, ocsp.UnauthorizedErrorResponse)
+	}
+
+	ocspReq, err := ocsp.ParseRequest(raw)
+	if err != nil {
+		return ocspRawResponse(http.StatusBadRequest, ocsp.MalformedRequestErrorResponse)
+	}
+
+	caInfo, err := lookupOCSPIssuer(ctx, req, ocspReq)
+	if err != nil {
+		b.Logger().Error("error looking up the issuer of an OCSP request", "error", err)
+		return ocspRawResponse(http.StatusInternalServerError, ocsp.InternalErrorErrorResponse)
+	}
+	if caInfo == nil {
+		return ocspRawResponse(http.StatusUnauthorized, ocsp.UnauthorizedErrorResponse)
+	}
+
+	template, err := ocspCertStatus(ctx, req, ocspReq.SerialNumber.Bytes(), caInfo.Certificate)
+	if err != nil {
+		b.Logger().Error("error fetching the status of a certificate for OCSP", "error", err)
+		return ocspRawResponse(http.StatusInternalServerError, ocsp.InternalErrorErrorResponse)
+	}
+
+	now := time.Now()
+	template.SerialNumber = ocspReq.SerialNumber
+	template.IssuerHash = ocspReq.HashAlgorithm
+	template.ThisUpdate = now
+	template.NextUpdate = now.Add(crlInfo.ocspExpiry())
+
+	respBytes, err := ocsp.CreateResponse(caInfo.Certificate, caInfo.Certificate, *template, caInfo.PrivateKey)
+	if err != nil {
+		b.Logger().Error("error creating OCSP response", "error", err)
+		return ocspRawResponse(http.StatusInternalServerError, ocsp.InternalErrorErrorResponse)
+	}
+
+	return ocspRawResponse(http.StatusOK, respBytes)
+}
+
+// lookupOCSPIssuer returns the issuer whose name and key hashes are the ones
+// of the request, or nil when none matches
+func lookupOCSPIssuer(ctx context.Context, req *logical.Request, ocspReq *ocsp.Request) (*certutil.CAInfoBundle, error) {
+	if !ocspReq.HashAlgorithm.Available() {
+		return nil, nil
+	}
+
+	// The CA set before multiple issuers were supported may not have been
+	// registered as an issuer yet
+	var bundles []*certutil.CertBundle
+	entry, err := req.Storage.Get(ctx, "config/ca_bundle")
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		var cb certutil.CertBundle
+		if err := entry.DecodeJSON(&cb); err != nil {
+			return nil, err
+		}
+		if cb.Certificate != "" {
+			bundles = append(bundles, &cb)
+		}
+	}
+	issuers, err := listIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range issuers {
+		bundles = append(bundles, issuer.Bundle)
+	}
+
+	for _, bundle := range bundles {
+		caInfo, err := caInfoFromCertBundle(ctx, req, bundle)
+		if err != nil {
+			return nil, err
+		}
+		if caInfo.PrivateKey == nil {
+			continue
+		}
+
+		var publicKeyInfo struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}
+		if _, err := asn1.Unmarshal(caInfo.Certificate.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+			return nil, err
+		}
+
+		h := ocspReq.HashAlgorithm.New()
+		h.Write(caInfo.Certificate.RawSubject)
+		nameHash := h.Sum(nil)
+
+		h.Reset()
+		h.Write(publicKeyInfo.PublicKey.RightAlign())
+		keyHash := h.Sum(nil)
+
+		if bytes.Equal(nameHash, ocspReq.IssuerNameHash) && bytes.Equal(keyHash, ocspReq.IssuerKeyHash) {
+			return caInfo, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ocspCertStatus returns the status of the certificate with the given serial
+// number, which is unknown unless it was issued by the given issuer
+func ocspCertStatus(ctx context.Context, req *logical.Request, serial []byte, issuer *x509.Certificate) (*ocsp.Response, error) {
+	serialStr := certutil.GetHexFormatted(serial, ":")
+
+	revokedEntry, err := fetchCertBySerial(ctx, req, "revoked/", serialStr)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(revInfo.CertificateBytes)
+		if err != nil {
+			return nil, err
+		}
+		if cert.CheckSignatureFrom(issuer) != nil {
+			return &ocsp.Response{Status: ocsp.Unknown}, nil
+		}
+
+		revokedAt := revInfo.RevocationTimeUTC
+		if revokedAt.IsZero() {
+			revokedAt = time.Unix(revInfo.RevocationTime, 0).UTC()
+		}
+		return &ocsp.Response{
+			Status:           ocsp.Revoked,
+			RevokedAt:        revokedAt,
+			RevocationReason: ocsp.Unspecified,
+		}, nil
+	}
+
+	certEntry, err := fetchCertBySerial(ctx, req, "certs/", serialStr)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return &ocsp.Response{Status: ocsp.Unknown}, nil
+	}
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if cert.CheckSignatureFrom(issuer) != nil {
+		return &ocsp.Response{Status: ocsp.Unknown}, nil
+	}
+
+	return &ocsp.Response{Status: ocsp.Good}, nil
+}
+
+func ocspRawResponse(status int, body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/ocsp-response",
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  status,
+		},
+	}
+}
+
+const pathOCSPHelpSyn = `
+Query the revocation status of certificates with OCSP.
+`
+
+const pathOCSPHelpDesc = `
+This path is an OCSP responder, as described in RFC 6960. DER-encoded
+requests can be sent with POST, or base64-encoded at the end of the path
+with GET. Responses are signed by the issuer of the certificate.
+
+The responder can be disabled and the lifetime of its responses configured
+with the "config/crl" endpoint.
+`
@@ -9,11 +9,43 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+type tidyStatusState string
+
+const (
+	tidyStatusInactive tidyStatusState = "Inactive"
+	tidyStatusRunning  tidyStatusState = "Running"
+	tidyStatusPaused   tidyStatusState = "Paused"
+	tidyStatusFinished tidyStatusState = "Finished"
+	tidyStatusError    tidyStatusState = "Error"
+)
+
+// tidyPausePollInterval is how often a paused tidy operation checks whether
+// it was resumed
+const tidyPausePollInterval = time.Second
+
+// tidyStatus tracks the progress of the last tidy operation
+type tidyStatus struct {
+	config       tidyConfig
+	state        tidyStatusState
+	err          error
+	timeStarted  time.Time
+	timeFinished time.Time
+	message      string
+
+	certStoreTotalCount       uint
+	certStoreProcessedCount   uint
+	certStoreDeletedCount     uint
+	revokedCertTotalCount     uint
+	revokedCertProcessedCount uint
+	revokedCertDeletedCount   uint
+}
+
 func pathTidy(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "tidy",
@@ -61,23 +93,45 @@ func (b *backend) pathTidyWrite(ctx context.Context, req *logical.Request, d *fr
 		return nil, logical.ErrReadOnly
 	}
 
-	safetyBuffer := d.Get("safety_buffer").(int)
-	tidyCertStore := d.Get("tidy_cert_store").(bool)
-	tidyRevokedCerts := d.Get("tidy_revoked_certs").(bool)
-	tidyRevocationList := d.Get("tidy_revocation_list").(bool)
+	config := &tidyConfig{
+		SafetyBuffer:     d.Get("safety_buffer").(int),
+		TidyCertStore:    d.Get("tidy_cert_store").(bool),
+		TidyRevokedCerts: d.Get("tidy_revoked_certs").(bool) || d.Get("tidy_revocation_list").(bool),
+	}
 
-	if safetyBuffer < 1 {
+	if config.SafetyBuffer < 1 {
 		return logical.ErrorResponse("safety_buffer must be greater than zero"), nil
 	}
+	if atomic.LoadUint32(&b.tidyPaused) == 1 {
+		return logical.ErrorResponse("tidy is paused, resume it with the tidy-resume endpoint first"), nil
+	}
 
-	bufferDuration := time.Duration(safetyBuffer) * time.Second
-
-	if !atomic.CompareAndSwapUint32(b.tidyCASGuard, 0, 1) {
+	if !b.startTidy(req, config) {
 		resp := &logical.Response{}
 		resp.AddWarning("Tidy operation already in progress.")
 		return resp, nil
 	}
 
+	resp := &logical.Response{}
+	resp.AddWarning("Tidy operation successfully started. Its progress is reported by the tidy-status endpoint, and any information from the operation will be printed to Vault's server logs.")
+	return logical.RespondWithStatusCode(resp, req, http.StatusAccepted)
+}
+
+// startTidy runs a tidy operation in the background, unless one is already
+// running
+func (b *backend) startTidy(req *logical.Request, config *tidyConfig) bool {
+	if !atomic.CompareAndSwapUint32(b.tidyCASGuard, 0, 1) {
+		return false
+	}
+
+	b.tidyStatusLock.Lock()
+	b.tidyStatus = &tidyStatus{
+		config:      *config,
+		state:       tidyStatusRunning,
+		timeStarted: time.Now(),
+	}
+	b.tidyStatusLock.Unlock()
+
 	// Tests using framework will screw up the storage so make a locally
 	// scoped req to hold a reference
 	req = &logical.Request{
@@ -88,132 +142,331 @@ func (b *backend) pathTidyWrite(ctx context.Context, req *logical.Request, d *fr
 		defer atomic.StoreUint32(b.tidyCASGuard, 0)
 
 		// Don't cancel when the original client request goes away
-		ctx = context.Background()
+		ctx := context.Background()
 
 		logger := b.Logger().Named("tidy")
 
-		doTidy := func() error {
-			if tidyCertStore {
-				serials, err := req.Storage.List(ctx, "certs/")
-				if err != nil {
-					return errwrap.Wrapf("error fetching list of certs: {{err}}", err)
-				}
+		err := b.doTidy(ctx, req, logger, config)
+		if err != nil {
+			logger.Error("error running tidy", "error", err)
+		}
 
-				for _, serial := range serials {
-					certEntry, err := req.Storage.Get(ctx, "certs/"+serial)
-					if err != nil {
-						return errwrap.Wrapf(fmt.Sprintf("error fetching certificate %q: {{err}}", serial), err)
-					}
-
-					if certEntry == nil {
-						logger.Warn("certificate entry is nil; tidying up since it is no longer useful for any server operations", "serial", serial)
-						if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting nil entry with serial %s: {{err}}", serial), err)
-						}
-						continue
-					}
-
-					if certEntry.Value == nil || len(certEntry.Value) == 0 {
-						logger.Warn("certificate entry has no value; tidying up since it is no longer useful for any server operations", "serial", serial)
-						if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting entry with nil value with serial %s: {{err}}", serial), err)
-						}
-						continue
-					}
-
-					cert, err := x509.ParseCertificate(certEntry.Value)
-					if err != nil {
-						return errwrap.Wrapf(fmt.Sprintf("unable to parse stored certificate with serial %q: {{err}}", serial), err)
-					}
-
-					if time.Now().After(cert.NotAfter.Add(bufferDuration)) {
-						if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from storage: {{err}}", serial), err)
-						}
-					}
-				}
-			}
+		b.tidyStatusLock.Lock()
+		defer b.tidyStatusLock.Unlock()
+		b.lastTidy = time.Now()
+		b.tidyStatus.timeFinished = b.lastTidy
+		if err != nil {
+			b.tidyStatus.state = tidyStatusError
+			b.tidyStatus.err = err
+			return
+		}
+		b.tidyStatus.state = tidyStatusFinished
+		b.tidyStatus.message = ""
+	}()
 
-			if tidyRevokedCerts || tidyRevocationList {
-				b.revokeStorageLock.Lock()
-				defer b.revokeStorageLock.Unlock()
+	return true
+}
 
-				tidiedRevoked := false
+func (b *backend) doTidy(ctx context.Context, req *logical.Request, logger hclog.Logger, config *tidyConfig) error {
+	bufferDuration := time.Duration(config.SafetyBuffer) * time.Second
 
-				revokedSerials, err := req.Storage.List(ctx, "revoked/")
-				if err != nil {
-					return errwrap.Wrapf("error fetching list of revoked certs: {{err}}", err)
-				}
+	if config.TidyCertStore {
+		b.tidyStatusMessage("Tidying certificate store")
+
+		serials, err := req.Storage.List(ctx, "certs/")
+		if err != nil {
+			return errwrap.Wrapf("error fetching list of certs: {{err}}", err)
+		}
+		b.tidyStatusUpdate(func(status *tidyStatus) {
+			status.certStoreTotalCount = uint(len(serials))
+		})
 
-				var revInfo revocationInfo
-				for _, serial := range revokedSerials {
-					revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
-					if err != nil {
-						return errwrap.Wrapf(fmt.Sprintf("unable to fetch revoked cert with serial %q: {{err}}", serial), err)
-					}
-
-					if revokedEntry == nil {
-						logger.Warn("revoked entry is nil; tidying up since it is no longer useful for any server operations", "serial", serial)
-						if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting nil revoked entry with serial %s: {{err}}", serial), err)
-						}
-						continue
-					}
-
-					if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
-						logger.Warn("revoked entry has nil value; tidying up since it is no longer useful for any server operations", "serial", serial)
-						if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting revoked entry with nil value with serial %s: {{err}}", serial), err)
-						}
-						continue
-					}
-
-					err = revokedEntry.DecodeJSON(&revInfo)
-					if err != nil {
-						return errwrap.Wrapf(fmt.Sprintf("error decoding revocation entry for serial %q: {{err}}", serial), err)
-					}
-
-					revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
-					if err != nil {
-						return errwrap.Wrapf(fmt.Sprintf("unable to parse stored revoked certificate with serial %q: {{err}}", serial), err)
-					}
-
-					// Remove the matched certificate entries from revoked/ and
-					// cert/ paths. We compare against both the NotAfter time
-					// within the cert itself and the time from the revocation
-					// entry, and perform tidy if either one tells us that the
-					// certificate has already been revoked.
-					now := time.Now()
-					if now.After(revokedCert.NotAfter.Add(bufferDuration)) || now.After(revInfo.RevocationTimeUTC.Add(bufferDuration)) {
-						if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from revoked list: {{err}}", serial), err)
-						}
-						if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
-							return errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from store when tidying revoked: {{err}}", serial), err)
-						}
-						tidiedRevoked = true
-					}
+		for _, serial := range serials {
+			b.tidyWaitWhilePaused()
+
+			deleted, err := b.tidyCertEntry(ctx, req, logger, serial, bufferDuration)
+			if err != nil {
+				return err
+			}
+			b.tidyStatusUpdate(func(status *tidyStatus) {
+				status.certStoreProcessedCount++
+				if deleted {
+					status.certStoreDeletedCount++
 				}
+			})
+		}
+	}
 
-				if tidiedRevoked {
-					if err := buildCRL(ctx, b, req, false); err != nil {
-						return err
-					}
+	if config.TidyRevokedCerts {
+		b.tidyStatusMessage("Tidying revoked certificates")
+
+		revokedSerials, err := req.Storage.List(ctx, "revoked/")
+		if err != nil {
+			return errwrap.Wrapf("error fetching list of revoked certs: {{err}}", err)
+		}
+		b.tidyStatusUpdate(func(status *tidyStatus) {
+			status.revokedCertTotalCount = uint(len(revokedSerials))
+		})
+
+		tidiedRevoked := false
+		for _, serial := range revokedSerials {
+			b.tidyWaitWhilePaused()
+
+			deleted, err := b.tidyRevokedEntry(ctx, req, logger, serial, bufferDuration)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				tidiedRevoked = true
+			}
+			b.tidyStatusUpdate(func(status *tidyStatus) {
+				status.revokedCertProcessedCount++
+				if deleted {
+					status.revokedCertDeletedCount++
 				}
+			})
+		}
+
+		if tidiedRevoked {
+			b.tidyStatusMessage("Rebuilding the CRL")
+
+			b.revokeStorageLock.Lock()
+			defer b.revokeStorageLock.Unlock()
+			if err := buildCRL(ctx, b, req, false); err != nil {
+				return err
 			}
+		}
+	}
+
+	return nil
+}
 
-			return nil
+// tidyCertEntry removes the certificate with the given serial from the
+// certificate store if it expired more than bufferDuration ago
+func (b *backend) tidyCertEntry(ctx context.Context, req *logical.Request, logger hclog.Logger, serial string, bufferDuration time.Duration) (bool, error) {
+	certEntry, err := req.Storage.Get(ctx, "certs/"+serial)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("error fetching certificate %q: {{err}}", serial), err)
+	}
+
+	if certEntry == nil {
+		logger.Warn("certificate entry is nil; tidying up since it is no longer useful for any server operations", "serial", serial)
+		if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting nil entry with serial %s: {{err}}", serial), err)
 		}
+		return true, nil
+	}
 
-		if err := doTidy(); err != nil {
-			logger.Error("error running tidy", "error", err)
-			return
+	if certEntry.Value == nil || len(certEntry.Value) == 0 {
+		logger.Warn("certificate entry has no value; tidying up since it is no longer useful for any server operations", "serial", serial)
+		if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting entry with nil value with serial %s: {{err}}", serial), err)
 		}
-	}()
+		return true, nil
+	}
 
-	resp := &logical.Response{}
-	resp.AddWarning("Tidy operation successfully started. Any information from the operation will be printed to Vault's server logs.")
-	return logical.RespondWithStatusCode(resp, req, http.StatusAccepted)
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("unable to parse stored certificate with serial %q: {{err}}", serial), err)
+	}
+
+	if time.Now().After(cert.NotAfter.Add(bufferDuration)) {
+		if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from storage: {{err}}", serial), err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// tidyRevokedEntry removes the revocation entry of the given serial, and the
+// certificate itself, once the certificate expired or was revoked more than
+// bufferDuration ago
+func (b *backend) tidyRevokedEntry(ctx context.Context, req *logical.Request, logger hclog.Logger, serial string, bufferDuration time.Duration) (bool, error) {
+	// The lock is held for each entry rather than for the whole run, so that
+	// revocations aren't blocked while tidying large stores
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("unable to fetch revoked cert with serial %q: {{err}}", serial), err)
+	}
+
+	if revokedEntry == nil {
+		logger.Warn("revoked entry is nil; tidying up since it is no longer useful for any server operations", "serial", serial)
+		if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting nil revoked entry with serial %s: {{err}}", serial), err)
+		}
+		return true, nil
+	}
+
+	if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
+		logger.Warn("revoked entry has nil value; tidying up since it is no longer useful for any server operations", "serial", serial)
+		if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting revoked entry with nil value with serial %s: {{err}}", serial), err)
+		}
+		return true, nil
+	}
+
+	var revInfo revocationInfo
+	err = revokedEntry.DecodeJSON(&revInfo)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("error decoding revocation entry for serial %q: {{err}}", serial), err)
+	}
+
+	revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("unable to parse stored revoked certificate with serial %q: {{err}}", serial), err)
+	}
+
+	// Remove the matched certificate entries from revoked/ and
+	// cert/ paths. We compare against both the NotAfter time
+	// within the cert itself and the time from the revocation
+	// entry, and perform tidy if either one tells us that the
+	// certificate has already been revoked.
+	now := time.Now()
+	if now.After(revokedCert.NotAfter.Add(bufferDuration)) || now.After(revInfo.RevocationTimeUTC.Add(bufferDuration)) {
+		if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from revoked list: {{err}}", serial), err)
+		}
+		if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("error deleting serial %q from store when tidying revoked: {{err}}", serial), err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// tidyWaitWhilePaused blocks the running tidy operation until it is resumed
+func (b *backend) tidyWaitWhilePaused() {
+	if atomic.LoadUint32(&b.tidyPaused) == 0 {
+		return
+	}
+
+	b.tidyStatusUpdate(func(status *tidyStatus) {
+		status.state = tidyStatusPaused
+	})
+	for atomic.LoadUint32(&b.tidyPaused) == 1 {
+		time.Sleep(tidyPausePollInterval)
+	}
+	b.tidyStatusUpdate(func(status *tidyStatus) {
+		status.state = tidyStatusRunning
+	})
+}
+
+func (b *backend) tidyStatusUpdate(update func(*tidyStatus)) {
+	b.tidyStatusLock.Lock()
+	defer b.tidyStatusLock.Unlock()
+	update(b.tidyStatus)
+}
+
+func (b *backend) tidyStatusMessage(message string) {
+	b.tidyStatusUpdate(func(status *tidyStatus) {
+		status.message = message
+	})
+}
+
+func pathTidyStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-status",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathTidyStatusRead,
+		},
+
+		HelpSynopsis:    pathTidyStatusHelpSyn,
+		HelpDescription: pathTidyStatusHelpDesc,
+	}
+}
+
+func (b *backend) pathTidyStatusRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.tidyStatusLock.RLock()
+	defer b.tidyStatusLock.RUnlock()
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"state":                        tidyStatusInactive,
+			"paused":                       atomic.LoadUint32(&b.tidyPaused) == 1,
+			"safety_buffer":                nil,
+			"tidy_cert_store":              nil,
+			"tidy_revoked_certs":           nil,
+			"time_started":                 nil,
+			"time_finished":                nil,
+			"message":                      nil,
+			"error":                        nil,
+			"cert_store_total_count":       nil,
+			"cert_store_processed_count":   nil,
+			"cert_store_deleted_count":     nil,
+			"revoked_cert_total_count":     nil,
+			"revoked_cert_processed_count": nil,
+			"revoked_cert_deleted_count":   nil,
+		},
+	}
+
+	status := b.tidyStatus
+	if status == nil {
+		return resp, nil
+	}
+
+	resp.Data["state"] = status.state
+	resp.Data["safety_buffer"] = status.config.SafetyBuffer
+	resp.Data["tidy_cert_store"] = status.config.TidyCertStore
+	resp.Data["tidy_revoked_certs"] = status.config.TidyRevokedCerts
+	resp.Data["time_started"] = status.timeStarted
+	resp.Data["message"] = status.message
+	resp.Data["cert_store_total_count"] = status.certStoreTotalCount
+	resp.Data["cert_store_processed_count"] = status.certStoreProcessedCount
+	resp.Data["cert_store_deleted_count"] = status.certStoreDeletedCount
+	resp.Data["revoked_cert_total_count"] = status.revokedCertTotalCount
+	resp.Data["revoked_cert_processed_count"] = status.revokedCertProcessedCount
+	resp.Data["revoked_cert_deleted_count"] = status.revokedCertDeletedCount
+	if !status.timeFinished.IsZero() {
+		resp.Data["time_finished"] = status.timeFinished
+	}
+	if status.err != nil {
+		resp.Data["error"] = status.err.Error()
+	}
+
+	return resp, nil
+}
+
+func pathTidyPause(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-pause",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTidyPauseWrite,
+		},
+
+		HelpSynopsis:    pathTidyPauseHelpSyn,
+		HelpDescription: pathTidyPauseHelpDesc,
+	}
+}
+
+func (b *backend) pathTidyPauseWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	atomic.StoreUint32(&b.tidyPaused, 1)
+	return nil, nil
+}
+
+func pathTidyResume(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-resume",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTidyResumeWrite,
+		},
+
+		HelpSynopsis:    pathTidyPauseHelpSyn,
+		HelpDescription: pathTidyPauseHelpDesc,
+	}
+}
+
+func (b *backend) pathTidyResumeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	atomic.StoreUint32(&b.tidyPaused, 0)
+	return nil, nil
 }
 
 const pathTidyHelpSyn = `
@@ -242,3 +495,24 @@ certificate storage or in revocation information will then be checked. If the
 current time, minus the value of 'safety_buffer', is greater than the
 expiration, it will be removed.
 `
+
+const pathTidyStatusHelpSyn = `
+Report the status of the last tidy operation.
+`
+
+const pathTidyStatusHelpDesc = `
+This endpoint reports the state of the last tidy operation, whether started
+with the "tidy" endpoint or automatically, along with its parameters and how
+many entries it processed and deleted so far.
+`
+
+const pathTidyPauseHelpSyn = `
+Pause or resume tidy operations.
+`
+
+const pathTidyPauseHelpDesc = `
+The "tidy-pause" endpoint pauses the running tidy operation after the entry
+it is processing, and prevents new ones from being started, until the
+"tidy-resume" endpoint is called. Pausing isn't persisted, and is lifted when
+the mount is reloaded.
+`
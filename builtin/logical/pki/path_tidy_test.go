@@ -0,0 +1,136 @@
+package pki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPki_AutoTidy(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	waitForTidy := func() *logical.Response {
+		t.Helper()
+		for i := 0; i < 50; i++ {
+			resp := request(logical.ReadOperation, "tidy-status", nil)
+			if resp.Data["state"] == tidyStatusFinished {
+				return resp
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("tidy didn't finish")
+		return nil
+	}
+
+	request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "40h",
+	})
+	request(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "foobar.com",
+		"allow_subdomains": true,
+	})
+	request(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "expired.foobar.com",
+		"ttl":         "1s",
+	})
+	request(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "valid.foobar.com",
+		"ttl":         "1h",
+	})
+
+	resp := request(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStatusInactive {
+		t.Fatalf("bad status: %#v", resp.Data)
+	}
+
+	// Automatic tidy requires something to tidy
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/auto-tidy",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"enabled": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+	request(logical.UpdateOperation, "config/auto-tidy", map[string]interface{}{
+		"enabled":           true,
+		"interval_duration": "1s",
+		"tidy_cert_store":   true,
+		"safety_buffer":     "1s",
+	})
+	resp = request(logical.ReadOperation, "config/auto-tidy", nil)
+	if resp.Data["interval_duration"] != 1 || resp.Data["safety_buffer"] != 1 || resp.Data["tidy_revoked_certs"] != false {
+		t.Fatalf("bad config: %#v", resp.Data)
+	}
+
+	// Nothing happens while paused, and tidy can't be started manually
+	time.Sleep(2 * time.Second)
+	request(logical.UpdateOperation, "tidy-pause", nil)
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"tidy_cert_store": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+	resp = request(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStatusInactive || resp.Data["paused"] != true {
+		t.Fatalf("bad status: %#v", resp.Data)
+	}
+
+	// Once resumed, the expired certificate is tidied on the next run
+	request(logical.UpdateOperation, "tidy-resume", nil)
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	resp = waitForTidy()
+	// The root is stored along with the issued certificates
+	if resp.Data["cert_store_total_count"] != uint(3) ||
+		resp.Data["cert_store_processed_count"] != uint(3) ||
+		resp.Data["cert_store_deleted_count"] != uint(1) ||
+		resp.Data["tidy_cert_store"] != true ||
+		resp.Data["time_finished"] == nil ||
+		resp.Data["error"] != nil {
+		t.Fatalf("bad status: %#v", resp.Data)
+	}
+	timeFinished := resp.Data["time_finished"]
+	resp = request(logical.ListOperation, "certs/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("expected the expired certificate to be removed, got: %#v", keys)
+	}
+
+	// The next run waits for the interval to pass
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	resp = request(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["time_finished"] != timeFinished {
+		t.Fatalf("expected no tidy to have run: %#v", resp.Data)
+	}
+}
@@ -35,6 +35,7 @@ func Backend() *backend {
 		Paths: []*framework.Path{
 			pathConfigConnection(&b),
 			pathConfigLease(&b),
+			pathConfigRotateRoot(&b),
 			pathListRoles(&b),
 			pathCreds(&b),
 			pathRoles(&b),
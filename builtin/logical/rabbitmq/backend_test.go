@@ -158,6 +158,68 @@ func TestBackend_roleWithPasswordPolicy(t *testing.T) {
 	})
 }
 
+func TestBackend_rotateRoot(t *testing.T) {
+	if os.Getenv(logicaltest.TestEnvVar) == "" {
+		t.Skip(fmt.Sprintf("Acceptance tests skipped unless env '%s' set", logicaltest.TestEnvVar))
+		return
+	}
+
+	b, _ := Factory(context.Background(), logical.TestBackendConfig())
+
+	cleanup, uri := prepareRabbitMQTestContainer(t)
+	defer cleanup()
+
+	// Rotate the password of a dedicated user rather than of the one used by
+	// the other tests
+	step := testAccStepConfig(t, uri, "")
+	adminClient, err := rabbithole.NewClient(uri, step.Data["username"].(string), step.Data["password"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const adminUsername, adminPassword = "vault-admin", "vault-admin-password"
+	if _, err := adminClient.PutUser(adminUsername, rabbithole.UserSettings{
+		Password: adminPassword,
+		Tags:     "administrator",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer adminClient.DeleteUser(adminUsername)
+	step.Data["username"] = adminUsername
+	step.Data["password"] = adminPassword
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		PreCheck:       testAccPreCheckFunc(t, uri),
+		LogicalBackend: b,
+		Steps: []logicaltest.TestStep{
+			step,
+			{
+				Operation: logical.UpdateOperation,
+				Path:      "config/rotate-root",
+				Check: func(resp *logical.Response) error {
+					client, err := rabbithole.NewClient(uri, adminUsername, adminPassword)
+					if err != nil {
+						return err
+					}
+					if _, err := client.Whoami(); err == nil {
+						return fmt.Errorf("expected the old password to be rejected")
+					}
+					user, err := adminClient.GetUser(adminUsername)
+					if err != nil {
+						return err
+					}
+					if user.Tags != "administrator" {
+						return fmt.Errorf("expected the tags to be kept, got %q", user.Tags)
+					}
+					return nil
+				},
+			},
+			// Vault keeps working with the new password
+			testAccStepRole(t),
+			testAccStepReadCreds(t, b, uri, roleName),
+		},
+	})
+}
+
 func testAccPreCheckFunc(t *testing.T, uri string) func() {
 	return func() {
 		if uri == "" {
@@ -0,0 +1,95 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	rabbithole "github.com/michaelklishin/rabbit-hole"
+)
+
+func pathConfigRotateRoot(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/rotate-root",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathConfigRotateRootUpdate,
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigRotateRootHelpSyn,
+		HelpDescription: pathConfigRotateRootHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigRotateRootUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.URI == "" || config.Username == "" {
+		return logical.ErrorResponse("the connection must be configured with config/connection before rotating its credentials"), nil
+	}
+
+	// Have to get the client first because that takes out the lock
+	client, err := b.Client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// The tags are replaced along with the password, so keep the current ones
+	user, err := client.GetUser(config.Username)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to read user %q: {{err}}", config.Username), err)
+	}
+
+	password, err := b.generatePassword(ctx, config.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.PutUser(config.Username, rabbithole.UserSettings{
+		Password: password,
+		Tags:     user.Tags,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to update the password of user %q: {{err}}", config.Username), err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			b.Logger().Error(fmt.Sprintf("unable to close response body: %s", err))
+		}
+	}()
+	if !isIn200s(resp.StatusCode) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error updating the password of user %s - %d: %s", config.Username, resp.StatusCode, body)
+	}
+
+	config.Password = password
+	if err := writeConfig(ctx, req.Storage, config); err != nil {
+		return nil, errwrap.Wrapf("error saving the new password, it must be reset on the RabbitMQ server: {{err}}", err)
+	}
+
+	b.client = nil
+
+	return nil, nil
+}
+
+const pathConfigRotateRootHelpSyn = `
+Request to rotate the password of the RabbitMQ management user used by Vault.
+`
+
+const pathConfigRotateRootHelpDesc = `
+This path generates a new password for the RabbitMQ management user
+configured with the "config/connection" path, and updates the configuration
+with it. The password is generated with the configured password policy, if
+any. After rotation, only Vault knows the password.
+`
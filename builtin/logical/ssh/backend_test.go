@@ -1167,6 +1167,86 @@ func TestBackend_OptionsOverrideDefaults(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
+func TestBackend_DefaultExtensionsTemplate(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.System.(*logical.StaticSystemView).EntityVal = &logical.Entity{
+		ID:   "entity-id",
+		Name: "tuber-entity",
+		Metadata: map[string]string{
+			"login": "tuber",
+		},
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	request := func(path, entityID string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			EntityID:  entityID,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	extensionsOf := func(resp *logical.Response) map[string]string {
+		t.Helper()
+		signedKey := strings.TrimSpace(resp.Data["signed_key"].(string))
+		key, _ := base64.StdEncoding.DecodeString(strings.Split(signedKey, " ")[1])
+		parsedKey, err := ssh.ParsePublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsedKey.(*ssh.Certificate).Extensions
+	}
+
+	request("config/ca", "", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	request("roles/testing", "", map[string]interface{}{
+		"key_type":                    "ca",
+		"allowed_users":               "tuber",
+		"default_user":                "tuber",
+		"allow_user_certificates":     true,
+		"default_extensions_template": true,
+		"default_extensions": map[string]interface{}{
+			"login@example.com": "{{identity.entity.metadata.login}}",
+			"permit-pty":        "",
+		},
+	})
+
+	resp := request("sign/testing", "entity-id", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	expected := map[string]string{
+		"login@example.com": "tuber",
+		"permit-pty":        "",
+	}
+	if extensions := extensionsOf(resp); !reflect.DeepEqual(extensions, expected) {
+		t.Fatalf("bad extensions: expected %#v, got %#v", expected, extensions)
+	}
+
+	// Templated values are left out when the request has no entity
+	resp = request("sign/testing", "", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	expected = map[string]string{
+		"permit-pty": "",
+	}
+	if extensions := extensionsOf(resp); !reflect.DeepEqual(extensions, expected) {
+		t.Fatalf("bad extensions: expected %#v, got %#v", expected, extensions)
+	}
+}
+
 func TestBackend_AllowedUserKeyLengths(t *testing.T) {
 	config := logical.TestBackendConfig()
 
@@ -156,7 +156,7 @@ func (b *backend) pathSignCertificate(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	extensions, err := b.calculateExtensions(data, role)
+	extensions, err := b.calculateExtensions(data, req, role)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
@@ -357,10 +357,34 @@ func (b *backend) calculateCriticalOptions(data *framework.FieldData, role *sshR
 	return criticalOptions, nil
 }
 
-func (b *backend) calculateExtensions(data *framework.FieldData, role *sshRole) (map[string]string, error) {
+func (b *backend) calculateExtensions(data *framework.FieldData, req *logical.Request, role *sshRole) (map[string]string, error) {
 	unparsedExtensions := data.Get("extensions").(map[string]interface{})
 	if len(unparsedExtensions) == 0 {
-		return role.DefaultExtensions, nil
+		if !role.DefaultExtensionsTemplate {
+			return role.DefaultExtensions, nil
+		}
+
+		defaultExtensions := make(map[string]string, len(role.DefaultExtensions))
+		for extensionKey, extensionValue := range role.DefaultExtensions {
+			// Look for templating markers {{ .* }}
+			matched, _ := regexp.MatchString(`^{{.+?}}$`, extensionValue)
+			if !matched {
+				// Static extension value
+				defaultExtensions[extensionKey] = extensionValue
+				continue
+			}
+			if req.EntityID == "" {
+				continue
+			}
+
+			// Retrieve extension value based on template + entityID from request.
+			templateExtensionValue, err := framework.PopulateIdentityTemplate(extensionValue, req.EntityID, b.System())
+			if err != nil {
+				return nil, fmt.Errorf("template '%s' could not be rendered -> %s", extensionValue, err)
+			}
+			defaultExtensions[extensionKey] = templateExtensionValue
+		}
+		return defaultExtensions, nil
 	}
 
 	extensions := convertMapToStringValue(unparsedExtensions)
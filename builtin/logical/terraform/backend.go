@@ -0,0 +1,59 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Factory returns a Terraform Cloud backend that satisfies the
+// logical.Backend interface
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend returns the configured Terraform Cloud backend
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				configPath,
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretToken(&b),
+		},
+		BackendType: logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+}
+
+const backendHelp = `
+The Terraform Cloud secrets engine generates API tokens for Terraform Cloud
+and Terraform Enterprise.
+
+After mounting this secrets engine, configure the address of the server and
+the token Vault uses with the "config" path, and create roles with the
+"role/" path. Tokens are then generated with the "creds/" path.
+`
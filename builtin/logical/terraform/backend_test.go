@@ -0,0 +1,198 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// fakeTerraform serves the authentication tokens API of Terraform Cloud
+type fakeTerraform struct {
+	sync.Mutex
+
+	// tokens holds the token IDs by owner
+	tokens  map[string][]string
+	counter int
+}
+
+func newFakeTerraform(t *testing.T) (*fakeTerraform, *httptest.Server) {
+	f := &fakeTerraform{
+		tokens: map[string][]string{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer vault-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		f.Lock()
+		defer f.Unlock()
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/v2/")
+		switch {
+		case r.Method == http.MethodPost:
+			var in tokenDocument
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Fatal(err)
+			}
+			f.counter++
+			id := fmt.Sprintf("at-%d", f.counter)
+			owner := strings.TrimSuffix(strings.TrimSuffix(path, "/authentication-tokens"), "/authentication-token")
+			if strings.HasPrefix(owner, "users/") {
+				f.tokens[owner] = append(f.tokens[owner], id)
+			} else {
+				// Organizations and teams have a single token
+				f.tokens[owner] = []string{id}
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&tokenDocument{
+				Data: tokenData{
+					ID:   id,
+					Type: "authentication-tokens",
+					Attributes: tokenAttributes{
+						Description: in.Data.Attributes.Description,
+						Token:       "token-" + id,
+					},
+				},
+			})
+
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "authentication-tokens/"):
+			id := strings.TrimPrefix(path, "authentication-tokens/")
+			for owner, ids := range f.tokens {
+				for i := range ids {
+					if ids[i] == id {
+						f.tokens[owner] = append(ids[:i], ids[i+1:]...)
+						w.WriteHeader(http.StatusNoContent)
+						return
+					}
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return f, server
+}
+
+func TestBackend_Creds(t *testing.T) {
+	f, server := newFakeTerraform(t)
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Storage:     config.StorageView,
+			Data:        data,
+			DisplayName: "test",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error: %s: %#v", path, resp)
+		}
+	}
+
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{
+		"address": server.URL,
+	})
+	request(logical.UpdateOperation, "config", map[string]interface{}{
+		"address": server.URL,
+		"token":   "vault-token",
+	})
+	resp := request(logical.ReadOperation, "config", nil)
+	if resp.Data["address"] != server.URL || resp.Data["base_path"] != defaultBasePath {
+		t.Fatalf("bad config: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["token"]; ok {
+		t.Fatal("expected the token not to be returned")
+	}
+
+	expectError(logical.UpdateOperation, "role/bad", map[string]interface{}{})
+	expectError(logical.UpdateOperation, "role/bad", map[string]interface{}{
+		"team_id": "team-1",
+		"user_id": "user-1",
+	})
+	request(logical.UpdateOperation, "role/org", map[string]interface{}{
+		"organization": "example",
+	})
+	request(logical.UpdateOperation, "role/team", map[string]interface{}{
+		"organization": "example",
+		"team_id":      "team-1",
+	})
+	request(logical.UpdateOperation, "role/user", map[string]interface{}{
+		"user_id": "user-1",
+		"ttl":     "1h",
+		"max_ttl": "2h",
+	})
+	resp = request(logical.ListOperation, "role/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 3 {
+		t.Fatalf("bad roles: %#v", keys)
+	}
+
+	// Organization and team tokens replace the previous one and aren't leased
+	resp = request(logical.ReadOperation, "creds/org", nil)
+	if resp.Data["token"] != "token-at-1" || resp.Data["organization"] != "example" || resp.Secret != nil {
+		t.Fatalf("bad creds: %#v", resp)
+	}
+	resp = request(logical.ReadOperation, "creds/team", nil)
+	if resp.Data["token_id"] != "at-2" || resp.Data["team_id"] != "team-1" || resp.Secret != nil {
+		t.Fatalf("bad creds: %#v", resp)
+	}
+	request(logical.ReadOperation, "creds/team", nil)
+	if ids := f.tokens["teams/team-1"]; len(ids) != 1 || ids[0] != "at-3" {
+		t.Fatalf("expected the team token to be replaced: %#v", ids)
+	}
+
+	// User tokens are leased, and deleted on revocation
+	first := request(logical.ReadOperation, "creds/user", nil)
+	second := request(logical.ReadOperation, "creds/user", nil)
+	if first.Secret == nil || first.Secret.TTL != time.Hour || first.Secret.MaxTTL != 2*time.Hour {
+		t.Fatalf("bad secret: %#v", first.Secret)
+	}
+	if len(f.tokens["users/user-1"]) != 2 {
+		t.Fatalf("bad tokens: %#v", f.tokens)
+	}
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   config.StorageView,
+		Secret:    first.Secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := f.tokens["users/user-1"]; len(ids) != 1 || ids[0] != second.Data["token_id"] {
+		t.Fatalf("expected only the revoked token to be deleted: %#v", ids)
+	}
+}
@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const jsonAPIContentType = "application/vnd.api+json"
+
+// tfClient is a minimal client of the authentication tokens API of Terraform
+// Cloud and Enterprise
+type tfClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// authToken is an authentication token, which is only returned in full when
+// it's created
+type authToken struct {
+	ID    string
+	Token string
+}
+
+type tokenDocument struct {
+	Data tokenData `json:"data"`
+}
+
+type tokenData struct {
+	ID         string          `json:"id,omitempty"`
+	Type       string          `json:"type"`
+	Attributes tokenAttributes `json:"attributes"`
+}
+
+type tokenAttributes struct {
+	Description string `json:"description,omitempty"`
+	Token       string `json:"token,omitempty"`
+}
+
+// errorDocument is the body of the API's error responses
+type errorDocument struct {
+	Errors []struct {
+		Status string `json:"status"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// apiError is returned for error responses of the API
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("terraform API request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(*apiError)
+	return ok && aerr.StatusCode == http.StatusNotFound
+}
+
+func (b *backend) client(ctx context.Context, s logical.Storage) (*tfClient, error) {
+	config, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("the terraform secrets engine is not configured")
+	}
+
+	return &tfClient{
+		baseURL:    strings.TrimRight(config.Address, "/") + "/" + strings.Trim(config.BasePath, "/") + "/",
+		token:      config.Token,
+		httpClient: cleanhttp.DefaultClient(),
+	}, nil
+}
+
+func (c *tfClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", jsonAPIContentType)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", jsonAPIContentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := http.StatusText(resp.StatusCode)
+		var errs errorDocument
+		if err := json.Unmarshal(respBody, &errs); err == nil && len(errs.Errors) > 0 {
+			message = errs.Errors[0].Title
+			if errs.Errors[0].Detail != "" {
+				message += ": " + errs.Errors[0].Detail
+			}
+		}
+		return &apiError{
+			StatusCode: resp.StatusCode,
+			Message:    message,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *tfClient) createToken(ctx context.Context, path, description string) (*authToken, error) {
+	in := &tokenDocument{
+		Data: tokenData{
+			Type: "authentication-tokens",
+			Attributes: tokenAttributes{
+				Description: description,
+			},
+		},
+	}
+	var out tokenDocument
+	if err := c.do(ctx, http.MethodPost, path, in, &out); err != nil {
+		return nil, err
+	}
+	if out.Data.ID == "" || out.Data.Attributes.Token == "" {
+		return nil, fmt.Errorf("no token in the terraform API response")
+	}
+
+	return &authToken{
+		ID:    out.Data.ID,
+		Token: out.Data.Attributes.Token,
+	}, nil
+}
+
+// createOrganizationToken replaces the token of the organization
+func (c *tfClient) createOrganizationToken(ctx context.Context, organization string) (*authToken, error) {
+	return c.createToken(ctx, fmt.Sprintf("organizations/%s/authentication-token", url.PathEscape(organization)), "")
+}
+
+// createTeamToken replaces the token of the team
+func (c *tfClient) createTeamToken(ctx context.Context, teamID string) (*authToken, error) {
+	return c.createToken(ctx, fmt.Sprintf("teams/%s/authentication-token", url.PathEscape(teamID)), "")
+}
+
+// createUserToken adds a token to the user
+func (c *tfClient) createUserToken(ctx context.Context, userID, description string) (*authToken, error) {
+	return c.createToken(ctx, fmt.Sprintf("users/%s/authentication-tokens", url.PathEscape(userID)), description)
+}
+
+func (c *tfClient) deleteToken(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, "authentication-tokens/"+url.PathEscape(id), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
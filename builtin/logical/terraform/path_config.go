@@ -0,0 +1,156 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configPath = "config"
+
+	defaultAddress  = "https://app.terraform.io"
+	defaultBasePath = "/api/v2/"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"address": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     defaultAddress,
+				Description: "Address of the Terraform Cloud or Enterprise instance. Defaults to https://app.terraform.io.",
+			},
+			"base_path": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     defaultBasePath,
+				Description: "Base path of the API. Defaults to /api/v2/.",
+			},
+			"token": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Token used to manage the API tokens, which must
+be allowed to create tokens for the organizations, teams and users of the
+roles.`,
+			},
+		},
+
+		ExistenceCheck: b.configExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.CreateOperation: b.pathConfigWrite,
+			logical.UpdateOperation: b.pathConfigWrite,
+			logical.DeleteOperation: b.pathConfigDelete,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type tfConfig struct {
+	Address  string `json:"address"`
+	BasePath string `json:"base_path"`
+	Token    string `json:"token"`
+}
+
+func (b *backend) configExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	config, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return false, err
+	}
+	return config != nil, nil
+}
+
+func (b *backend) readConfig(ctx context.Context, s logical.Storage) (*tfConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	config := &tfConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("error reading terraform configuration: {{err}}", err)
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The token is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"address":   config.Address,
+			"base_path": config.BasePath,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &tfConfig{
+			Address:  d.Get("address").(string),
+			BasePath: d.Get("base_path").(string),
+		}
+	}
+
+	if addressRaw, ok := d.GetOk("address"); ok {
+		config.Address = addressRaw.(string)
+	}
+	if basePathRaw, ok := d.GetOk("base_path"); ok {
+		config.BasePath = basePathRaw.(string)
+	}
+	if tokenRaw, ok := d.GetOk("token"); ok {
+		config.Token = tokenRaw.(string)
+	}
+
+	if config.Address == "" {
+		return logical.ErrorResponse("address is required"), nil
+	}
+	if config.Token == "" {
+		return logical.ErrorResponse("token is required"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, configPath); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure how to reach Terraform Cloud or Terraform Enterprise.
+`
+
+const pathConfigHelpDesc = `
+This path configures the address of the Terraform Cloud or Enterprise
+instance, and the token Vault uses to create and delete API tokens. The token
+is never returned once written.
+`
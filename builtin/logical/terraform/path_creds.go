@@ -0,0 +1,98 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	role, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q not found", name)), nil
+	}
+
+	c, err := b.client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var token *authToken
+	switch {
+	case role.UserID != "":
+		description := fmt.Sprintf("Vault %s %s %d", name, req.DisplayName, time.Now().UnixNano())
+		token, err = c.createUserToken(ctx, role.UserID, description)
+	case role.TeamID != "":
+		token, err = c.createTeamToken(ctx, role.TeamID)
+	default:
+		token, err = c.createOrganizationToken(ctx, role.Organization)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("error creating the token: {{err}}", err)
+	}
+
+	data := map[string]interface{}{
+		"token":    token.Token,
+		"token_id": token.ID,
+	}
+	if role.Organization != "" {
+		data["organization"] = role.Organization
+	}
+	if role.TeamID != "" {
+		data["team_id"] = role.TeamID
+	}
+
+	// Generating a new organization or team token invalidates the previous
+	// one, so revoking a lease could delete the token of a later one
+	if role.UserID == "" {
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+
+	resp := b.Secret(SecretTokenType).Response(data, map[string]interface{}{
+		"token_id": token.ID,
+		"role":     name,
+	})
+	resp.Secret.TTL = role.TTL
+	resp.Secret.MaxTTL = role.MaxTTL
+
+	return resp, nil
+}
+
+const pathCredsHelpSyn = `
+Generate a Terraform Cloud API token for a role.
+`
+
+const pathCredsHelpDesc = `
+This path generates an API token for the organization, team or user of the
+role. Only user tokens are leased: organization and team tokens are replaced
+the next time credentials are generated for the role.
+`
@@ -0,0 +1,204 @@
+package terraform
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const rolePath = "role/"
+
+// roleEntry maps a role to the organization, team or user whose tokens it
+// generates
+type roleEntry struct {
+	Organization string        `json:"organization"`
+	TeamID       string        `json:"team_id"`
+	UserID       string        `json:"user_id"`
+	TTL          time.Duration `json:"ttl"`
+	MaxTTL       time.Duration `json:"max_ttl"`
+}
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: rolePath + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+			"organization": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the organization. Generates organization tokens unless team_id or user_id is set.",
+			},
+			"team_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the team to generate team tokens for. Mutually exclusive with user_id.",
+			},
+			"user_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the user to generate user tokens for. Mutually exclusive with team_id.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease for user tokens. Defaults to the mount's default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease for user tokens. Defaults to the mount's maximum TTL.",
+			},
+		},
+
+		ExistenceCheck: b.roleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRolesHelpSyn,
+		HelpDescription: pathRolesHelpDesc,
+	}
+}
+
+func (b *backend) roleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) getRole(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, rolePath+name)
+	if err != nil {
+		return nil, errwrap.Wrapf("error retrieving role: {{err}}", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	role := &roleEntry{}
+	if err := entry.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, rolePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"organization": role.Organization,
+			"team_id":      role.TeamID,
+			"user_id":      role.UserID,
+			"ttl":          int64(role.TTL.Seconds()),
+			"max_ttl":      int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if organizationRaw, ok := d.GetOk("organization"); ok {
+		role.Organization = organizationRaw.(string)
+	}
+	if teamIDRaw, ok := d.GetOk("team_id"); ok {
+		role.TeamID = teamIDRaw.(string)
+	}
+	if userIDRaw, ok := d.GetOk("user_id"); ok {
+		role.UserID = userIDRaw.(string)
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+
+	if role.TeamID != "" && role.UserID != "" {
+		return logical.ErrorResponse("only one of team_id and user_id can be set"), nil
+	}
+	if role.Organization == "" && role.TeamID == "" && role.UserID == "" {
+		return logical.ErrorResponse("one of organization, team_id and user_id is required"), nil
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl must be less than or equal to max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePath+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, rolePath+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathListRolesHelpSyn = `List the existing roles in this backend.`
+
+const pathListRolesHelpDesc = `Roles will be listed by the role name.`
+
+const pathRolesHelpSyn = `
+Manage the roles that can be used to generate Terraform Cloud tokens.
+`
+
+const pathRolesHelpDesc = `
+This path lets you manage the roles used to generate API tokens. A role
+generates user tokens if "user_id" is set, team tokens if "team_id" is set,
+and organization tokens otherwise.
+
+User tokens are leased, and deleted when the lease expires or is revoked.
+Organizations and teams only have one token each, which is replaced every
+time credentials are generated, so these tokens aren't leased.
+`
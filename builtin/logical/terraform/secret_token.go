@@ -0,0 +1,62 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	SecretTokenType = "terraform_token"
+)
+
+func secretToken(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretTokenType,
+		Fields: map[string]*framework.FieldSchema{
+			"token": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Terraform Cloud API token",
+			},
+		},
+
+		Renew:  b.secretTokenRenew,
+		Revoke: b.secretTokenRevoke,
+	}
+}
+
+func (b *backend) secretTokenRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	resp := &logical.Response{Secret: req.Secret}
+	roleName, _ := req.Secret.InternalData["role"].(string)
+	if roleName == "" {
+		return resp, nil
+	}
+
+	role, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("issuing role %q not found", roleName)), nil
+	}
+
+	resp.Secret.TTL = role.TTL
+	resp.Secret.MaxTTL = role.MaxTTL
+	return resp, nil
+}
+
+func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	tokenID, _ := req.Secret.InternalData["token_id"].(string)
+	if tokenID == "" {
+		return nil, fmt.Errorf("token_id is missing on the lease")
+	}
+
+	c, err := b.client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, c.deleteToken(ctx, tokenID)
+}
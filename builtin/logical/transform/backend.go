@@ -0,0 +1,62 @@
+package transform
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				"transformation/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathListAlphabets(&b),
+			pathAlphabets(&b),
+			pathListTemplates(&b),
+			pathTemplates(&b),
+			pathListTransformations(&b),
+			pathTransformations(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathEncode(&b),
+			pathDecode(&b),
+		},
+
+		Secrets:     []*framework.Secret{},
+		BackendType: logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+}
+
+const backendHelp = `
+The transform backend encodes values while preserving their format, using
+format-preserving encryption (FF3-1) or masking.
+
+Alphabets define the characters values are made of, templates define the
+format of values and the parts of them that are transformed, and
+transformations tie a template to a key. Roles grant access to sets of
+transformations through the encode and decode paths.
+`
@@ -0,0 +1,247 @@
+package transform
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func TestTransform_EncodeDecode(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	mustWrite := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := write(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	mustFail := func(path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := write(path, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected %s to fail, got: %#v", path, resp)
+		}
+	}
+
+	mustWrite("transformation/ccn", map[string]interface{}{
+		"type":          "fpe",
+		"template":      "builtin/creditcardnumber",
+		"tweak_source":  "internal",
+		"allowed_roles": "payments",
+	})
+	mustWrite("transformation/ccn-generated", map[string]interface{}{
+		"type":          "fpe",
+		"template":      "builtin/creditcardnumber",
+		"tweak_source":  "generated",
+		"allowed_roles": "*",
+	})
+	mustWrite("transformation/ssn", map[string]interface{}{
+		"type":          "masking",
+		"template":      "builtin/socialsecuritynumber",
+		"allowed_roles": "payments",
+	})
+	mustWrite("role/payments", map[string]interface{}{
+		"transformations": "ccn,ccn-generated,ssn",
+	})
+
+	// The key and the format of a transformation can't be changed
+	mustFail("transformation/ccn", map[string]interface{}{
+		"template": "builtin/socialsecuritynumber",
+	})
+	mustFail("transformation/ccn", map[string]interface{}{
+		"tweak_source": "supplied",
+	})
+
+	// FPE preserves the format of values and can be reversed
+	value := "1111-2222-3333-4444"
+	resp := mustWrite("encode/payments", map[string]interface{}{
+		"value":          value,
+		"transformation": "ccn",
+	})
+	encoded := resp.Data["encoded_value"].(string)
+	if encoded == value || !regexp.MustCompile(`^\d{4}-\d{4}-\d{4}-\d{4}$`).MatchString(encoded) {
+		t.Fatalf("bad encoded value: %s", encoded)
+	}
+	resp = mustWrite("decode/payments", map[string]interface{}{
+		"value":          encoded,
+		"transformation": "ccn",
+	})
+	if decoded := resp.Data["decoded_value"].(string); decoded != value {
+		t.Fatalf("bad decoded value: expected %s, got %s", value, decoded)
+	}
+
+	// Generated tweaks are returned and required to decode
+	resp = mustWrite("encode/payments", map[string]interface{}{
+		"value":          value,
+		"transformation": "ccn-generated",
+	})
+	encoded = resp.Data["encoded_value"].(string)
+	tweak := resp.Data["tweak"].(string)
+	mustFail("decode/payments", map[string]interface{}{
+		"value":          encoded,
+		"transformation": "ccn-generated",
+	})
+	resp = mustWrite("decode/payments", map[string]interface{}{
+		"value":          encoded,
+		"transformation": "ccn-generated",
+		"tweak":          tweak,
+	})
+	if decoded := resp.Data["decoded_value"].(string); decoded != value {
+		t.Fatalf("bad decoded value: expected %s, got %s", value, decoded)
+	}
+
+	// Masked values can't be decoded
+	resp = mustWrite("encode/payments", map[string]interface{}{
+		"value":          "123-45-6789",
+		"transformation": "ssn",
+	})
+	if encoded := resp.Data["encoded_value"].(string); encoded != "***-**-****" {
+		t.Fatalf("bad masked value: %s", encoded)
+	}
+	mustFail("decode/payments", map[string]interface{}{
+		"value":          "***-**-****",
+		"transformation": "ssn",
+	})
+
+	// Values must match the template
+	mustFail("encode/payments", map[string]interface{}{
+		"value":          "1111-2222",
+		"transformation": "ccn",
+	})
+
+	// Roles can only use the transformations they list and that allow them
+	mustWrite("role/other", map[string]interface{}{
+		"transformations": "ccn,ccn-generated",
+	})
+	mustFail("encode/other", map[string]interface{}{
+		"value":          value,
+		"transformation": "ccn",
+	})
+	mustFail("encode/other", map[string]interface{}{
+		"value":          "123-45-6789",
+		"transformation": "ssn",
+	})
+	mustWrite("encode/other", map[string]interface{}{
+		"value":          value,
+		"transformation": "ccn-generated",
+	})
+
+	// Batch items fail independently
+	resp = mustWrite("encode/payments", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{
+				"value":          value,
+				"transformation": "ccn",
+				"reference":      "a",
+			},
+			map[string]interface{}{
+				"value":          "bad",
+				"transformation": "ccn",
+				"reference":      "b",
+			},
+		},
+	})
+	results := resp.Data["batch_results"].([]BatchResponseItem)
+	if len(results) != 2 || results[0].Error != "" || results[0].Reference != "a" || results[1].Error == "" || results[1].Reference != "b" {
+		t.Fatalf("bad batch results: %#v", results)
+	}
+}
+
+func TestTransform_CustomTemplate(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	// Alphabets need unique characters and templates capture groups
+	if resp, err := write("alphabet/hex", map[string]interface{}{"alphabet": "0123456789abcdeff"}); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected duplicate characters to be rejected: %#v", resp)
+	}
+	if resp, err := write("alphabet/hex", map[string]interface{}{"alphabet": "0123456789abcdef"}); err != nil || resp != nil {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp, err := write("template/id", map[string]interface{}{"pattern": `ID-[0-9a-f]{8}`, "alphabet": "hex"}); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a pattern without groups to be rejected: %#v", resp)
+	}
+	if resp, err := write("template/id", map[string]interface{}{"pattern": `ID-([0-9a-f]{8})`, "alphabet": "hex"}); err != nil || resp != nil {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp, err := write("transformation/id", map[string]interface{}{"type": "fpe", "template": "id", "allowed_roles": "app"}); err != nil || resp != nil {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp, err := write("role/app", map[string]interface{}{"transformations": "id"}); err != nil || resp != nil {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	// The transformation can be omitted when the role has a single one
+	tweak := "AAECAwQFBg=="
+	resp, err := write("encode/app", map[string]interface{}{"value": "ID-0badcafe", "tweak": tweak})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	encoded := resp.Data["encoded_value"].(string)
+	if encoded == "ID-0badcafe" || !regexp.MustCompile(`^ID-[0-9a-f]{8}$`).MatchString(encoded) {
+		t.Fatalf("bad encoded value: %s", encoded)
+	}
+
+	resp, err = write("decode/app", map[string]interface{}{"value": encoded, "tweak": tweak})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if decoded := resp.Data["decoded_value"].(string); decoded != "ID-0badcafe" {
+		t.Fatalf("bad decoded value: %s", decoded)
+	}
+
+	// Resources can't be deleted while in use, and are released in order
+	paths := []string{"role/app", "transformation/id", "template/id", "alphabet/hex"}
+	for i, path := range paths {
+		for _, inUse := range paths[i+1:] {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Storage:   s,
+				Operation: logical.DeleteOperation,
+				Path:      inUse,
+			})
+			if err != nil || resp == nil || !resp.IsError() {
+				t.Fatalf("expected deleting %s to fail, got: err: %v\nresp: %#v", inUse, err, resp)
+			}
+		}
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   s,
+			Operation: logical.DeleteOperation,
+			Path:      path,
+		})
+		if err != nil || resp != nil {
+			t.Fatalf("bad: %s: err: %v\nresp: %#v", path, err, resp)
+		}
+	}
+}
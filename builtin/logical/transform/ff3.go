@@ -0,0 +1,190 @@
+package transform
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	// ff3TweakLen is the length in bytes of FF3-1 tweaks
+	ff3TweakLen = 7
+
+	// ff3MaxRadix is the largest alphabet FF3-1 supports
+	ff3MaxRadix = 1 << 16
+
+	// ff3MinDomain is the minimum number of possible values of the input
+	ff3MinDomain = 1000000
+)
+
+// ff3Cipher implements the FF3-1 format-preserving encryption mode
+// described in NIST SP 800-38G Revision 1. Values are strings of numerals,
+// i.e. indexes in an alphabet of radix characters.
+type ff3Cipher struct {
+	block  cipher.Block
+	radix  int
+	minLen int
+	maxLen int
+}
+
+// newFF3Cipher returns an FF3-1 cipher using the given AES key and radix
+func newFF3Cipher(key []byte, radix int) (*ff3Cipher, error) {
+	if radix < 2 || radix > ff3MaxRadix {
+		return nil, fmt.Errorf("radix must be between 2 and %d", ff3MaxRadix)
+	}
+
+	// The key is used in reverse byte order
+	revKey := make([]byte, len(key))
+	for i := range key {
+		revKey[i] = key[len(key)-1-i]
+	}
+	block, err := aes.NewCipher(revKey)
+	if err != nil {
+		return nil, err
+	}
+
+	minLen := 2
+	for domain := radix * radix; domain < ff3MinDomain; domain *= radix {
+		minLen++
+	}
+	maxLen := 2 * int(math.Floor(96/math.Log2(float64(radix))))
+
+	return &ff3Cipher{
+		block:  block,
+		radix:  radix,
+		minLen: minLen,
+		maxLen: maxLen,
+	}, nil
+}
+
+// Encrypt returns the encryption of the numerals with the given 7-byte tweak
+func (c *ff3Cipher) Encrypt(numerals []uint16, tweak []byte) ([]uint16, error) {
+	return c.crypt(numerals, tweak, true)
+}
+
+// Decrypt returns the decryption of the numerals with the given 7-byte tweak
+func (c *ff3Cipher) Decrypt(numerals []uint16, tweak []byte) ([]uint16, error) {
+	return c.crypt(numerals, tweak, false)
+}
+
+func (c *ff3Cipher) crypt(numerals []uint16, tweak []byte, encrypt bool) ([]uint16, error) {
+	if len(tweak) != ff3TweakLen {
+		return nil, fmt.Errorf("tweak must be %d bytes", ff3TweakLen)
+	}
+
+	// FF3-1 tweaks are 56 bits; split them in two 32-bit halves as FF3 does
+	// with its 64-bit tweaks
+	return c.cryptFF3(numerals, []byte{
+		tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0,
+		tweak[4], tweak[5], tweak[6], tweak[3] << 4,
+	}, encrypt)
+}
+
+// cryptFF3 runs the FF3 Feistel network with the given 8-byte tweak
+func (c *ff3Cipher) cryptFF3(numerals []uint16, tweak []byte, encrypt bool) ([]uint16, error) {
+	n := len(numerals)
+	if n < c.minLen || n > c.maxLen {
+		return nil, fmt.Errorf("value must be between %d and %d characters long", c.minLen, c.maxLen)
+	}
+	for _, numeral := range numerals {
+		if int(numeral) >= c.radix {
+			return nil, errors.New("value contains characters outside of the alphabet")
+		}
+	}
+
+	u := (n + 1) / 2
+	v := n - u
+	a := append([]uint16(nil), numerals[:u]...)
+	b := append([]uint16(nil), numerals[u:]...)
+	tl, tr := tweak[:4], tweak[4:]
+
+	radix := big.NewInt(int64(c.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	var p, s [aes.BlockSize]byte
+	y := new(big.Int)
+	num := new(big.Int)
+	for r := 0; r < 8; r++ {
+		i := r
+		if !encrypt {
+			i = 7 - r
+		}
+
+		m, mod, w := u, modU, tr
+		if i%2 == 1 {
+			m, mod, w = v, modV, tl
+		}
+
+		// The round function is applied to B when encrypting and to A
+		// when decrypting
+		x := b
+		if !encrypt {
+			x = a
+		}
+
+		copy(p[:4], w)
+		p[3] ^= byte(i)
+		for j := 4; j < len(p); j++ {
+			p[j] = 0
+		}
+		numBytes := numRev(x, radix, num).Bytes()
+		copy(p[len(p)-len(numBytes):], numBytes)
+
+		reverseBytes(p[:])
+		c.block.Encrypt(s[:], p[:])
+		reverseBytes(s[:])
+		y.SetBytes(s[:])
+
+		if encrypt {
+			num = numRev(a, radix, num)
+			num.Add(num, y)
+		} else {
+			num = numRev(b, radix, num)
+			num.Sub(num, y)
+		}
+		num.Mod(num, mod)
+		out := strRev(num, radix, m)
+
+		if encrypt {
+			a, b = b, out
+		} else {
+			b, a = a, out
+		}
+	}
+
+	return append(a, b...), nil
+}
+
+// numRev returns the number the numerals represent in the given radix, least
+// significant numeral first
+func numRev(numerals []uint16, radix, out *big.Int) *big.Int {
+	out.SetInt64(0)
+	for i := len(numerals) - 1; i >= 0; i-- {
+		out.Mul(out, radix)
+		out.Add(out, big.NewInt(int64(numerals[i])))
+	}
+	return out
+}
+
+// strRev returns the m numerals representing x in the given radix, least
+// significant numeral first
+func strRev(x, radix *big.Int, m int) []uint16 {
+	x = new(big.Int).Set(x)
+	out := make([]uint16, m)
+	digit := new(big.Int)
+	for i := 0; i < m; i++ {
+		x.DivMod(x, radix, digit)
+		out[i] = uint16(digit.Int64())
+	}
+	return out
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,106 @@
+package transform
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestFF3_Vectors(t *testing.T) {
+	// Samples from NIST for FF3, which FF3-1 only differs from by its tweak
+	cases := []struct {
+		key        string
+		radix      int
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{
+			"EF4359D8D580AA4F7F036D6F04FC6A94",
+			10,
+			"D8E7920AFA330A73",
+			"890121234567890000",
+			"750918814058654607",
+		},
+		{
+			"EF4359D8D580AA4F7F036D6F04FC6A94",
+			10,
+			"9A768A92F60E12D8",
+			"890121234567890000",
+			"018989839189395384",
+		},
+		{
+			"EF4359D8D580AA4F7F036D6F04FC6A942B7E151628AED2A6ABF7158809CF4F3C",
+			10,
+			"D8E7920AFA330A73",
+			"890121234567890000",
+			"922011205562777495",
+		},
+	}
+
+	for _, tc := range cases {
+		key, _ := hex.DecodeString(tc.key)
+		tweak, _ := hex.DecodeString(tc.tweak)
+		c, err := newFF3Cipher(key, tc.radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plaintext := digits(tc.plaintext)
+		ciphertext, err := c.cryptFF3(plaintext, tweak, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ciphertext, digits(tc.ciphertext)) {
+			t.Fatalf("bad ciphertext: expected %s, got %v", tc.ciphertext, ciphertext)
+		}
+
+		decrypted, err := c.cryptFF3(ciphertext, tweak, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decrypted, plaintext) {
+			t.Fatalf("bad plaintext: expected %s, got %v", tc.plaintext, decrypted)
+		}
+	}
+}
+
+func TestFF3_1(t *testing.T) {
+	key, _ := hex.DecodeString("EF4359D8D580AA4F7F036D6F04FC6A942B7E151628AED2A6ABF7158809CF4F3C")
+	tweak, _ := hex.DecodeString("D8E7920AFA330A")
+	c, err := newFF3Cipher(key, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := digits("4111111111111111")
+	ciphertext, err := c.Encrypt(plaintext, tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertext) != len(plaintext) || reflect.DeepEqual(ciphertext, plaintext) {
+		t.Fatalf("bad ciphertext: %v", ciphertext)
+	}
+	decrypted, err := c.Decrypt(ciphertext, tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decrypted, plaintext) {
+		t.Fatalf("bad plaintext: %v", decrypted)
+	}
+
+	if _, err := c.Encrypt(digits("12345"), tweak); err == nil {
+		t.Fatal("expected an error for a value shorter than the minimum length")
+	}
+	if _, err := c.Encrypt(plaintext, tweak[:6]); err == nil {
+		t.Fatal("expected an error for a short tweak")
+	}
+}
+
+func digits(s string) []uint16 {
+	out := make([]uint16, len(s))
+	for i, r := range s {
+		out[i] = uint16(r - '0')
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// builtinPrefix is the prefix of the alphabets and templates provided by the
+// backend. Names can't contain slashes, so they can't clash with user-defined
+// ones.
+const builtinPrefix = "builtin/"
+
+const (
+	numericChars    = "0123456789"
+	alphaLowerChars = "abcdefghijklmnopqrstuvwxyz"
+	alphaUpperChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+var builtinAlphabets = map[string]string{
+	"builtin/numeric":           numericChars,
+	"builtin/alphalower":        alphaLowerChars,
+	"builtin/alphaupper":        alphaUpperChars,
+	"builtin/alphanumericlower": numericChars + alphaLowerChars,
+	"builtin/alphanumericupper": numericChars + alphaUpperChars,
+	"builtin/alphanumeric":      numericChars + alphaLowerChars + alphaUpperChars,
+}
+
+type alphabetEntry struct {
+	Alphabet string `json:"alphabet"`
+}
+
+func pathListAlphabets(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "alphabet/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathAlphabetList,
+		},
+
+		HelpSynopsis:    pathAlphabetHelpSyn,
+		HelpDescription: pathAlphabetHelpDesc,
+	}
+}
+
+func pathAlphabets(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "alphabet/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the alphabet.",
+			},
+
+			"alphabet": {
+				Type:        framework.TypeString,
+				Description: "The set of characters values are made of. Characters must be unique.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathAlphabetRead,
+			logical.UpdateOperation: b.pathAlphabetWrite,
+			logical.DeleteOperation: b.pathAlphabetDelete,
+		},
+
+		HelpSynopsis:    pathAlphabetHelpSyn,
+		HelpDescription: pathAlphabetHelpDesc,
+	}
+}
+
+// Alphabet returns the named alphabet, which may be a builtin one
+func (b *backend) Alphabet(ctx context.Context, s logical.Storage, n string) (*alphabetEntry, error) {
+	if strings.HasPrefix(n, builtinPrefix) {
+		alphabet, ok := builtinAlphabets[n]
+		if !ok {
+			return nil, nil
+		}
+		return &alphabetEntry{Alphabet: alphabet}, nil
+	}
+
+	entry, err := s.Get(ctx, "alphabet/"+n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result alphabetEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathAlphabetList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "alphabet/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathAlphabetRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	alphabet, err := b.Alphabet(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if alphabet == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"alphabet": alphabet.Alphabet,
+		},
+	}, nil
+}
+
+func (b *backend) pathAlphabetWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	alphabet := d.Get("alphabet").(string)
+
+	if err := validateAlphabet(alphabet); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("alphabet/"+name, &alphabetEntry{
+		Alphabet: alphabet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathAlphabetDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	// Deleting an alphabet in use would prevent decoding values
+	templates, err := req.Storage.List(ctx, "template/")
+	if err != nil {
+		return nil, err
+	}
+	for _, templateName := range templates {
+		template, err := b.Template(ctx, req.Storage, templateName)
+		if err != nil {
+			return nil, err
+		}
+		if template != nil && template.Alphabet == name {
+			return logical.ErrorResponse(fmt.Sprintf("alphabet is in use by template %q", templateName)), nil
+		}
+	}
+
+	err = req.Storage.Delete(ctx, "alphabet/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func validateAlphabet(alphabet string) error {
+	runes := []rune(alphabet)
+	if len(runes) < 2 {
+		return fmt.Errorf("alphabet must contain at least 2 characters")
+	}
+	if len(runes) > ff3MaxRadix {
+		return fmt.Errorf("alphabet must contain at most %d characters", ff3MaxRadix)
+	}
+
+	seen := make(map[rune]struct{}, len(runes))
+	for _, r := range runes {
+		if _, ok := seen[r]; ok {
+			return fmt.Errorf("alphabet contains duplicate character %q", r)
+		}
+		seen[r] = struct{}{}
+	}
+
+	return nil
+}
+
+const pathAlphabetHelpSyn = `
+Manage the alphabets used by transformations.
+`
+
+const pathAlphabetHelpDesc = `
+This path lets you manage the alphabets values are made of. The characters
+of an alphabet must be unique, and their order matters: changing the
+alphabet of a template that is in use prevents decoding the values it was
+used to encode. Alphabets can't be deleted while in use by a template.
+
+The following builtin alphabets are always available: builtin/numeric,
+builtin/alphalower, builtin/alphaupper, builtin/alphanumericlower,
+builtin/alphanumericupper and builtin/alphanumeric.
+`
@@ -0,0 +1,340 @@
+package transform
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// BatchRequestItem represents a request item for batch processing
+type BatchRequestItem struct {
+	// Value is the value to encode or decode
+	Value string `json:"value" structs:"value" mapstructure:"value"`
+
+	// Transformation is the name of the transformation to use
+	Transformation string `json:"transformation" structs:"transformation" mapstructure:"transformation"`
+
+	// Tweak is the base64-encoded tweak of FPE transformations
+	Tweak string `json:"tweak" structs:"tweak" mapstructure:"tweak"`
+
+	// Reference is an arbitrary caller supplied string value that will be
+	// placed on the batch response to ease correlation
+	Reference string `json:"reference" structs:"reference" mapstructure:"reference"`
+}
+
+// BatchResponseItem represents a response item for batch processing
+type BatchResponseItem struct {
+	// EncodedValue is the encoded value, on encode
+	EncodedValue string `json:"encoded_value,omitempty" structs:"encoded_value" mapstructure:"encoded_value"`
+
+	// DecodedValue is the decoded value, on decode
+	DecodedValue string `json:"decoded_value,omitempty" structs:"decoded_value" mapstructure:"decoded_value"`
+
+	// Tweak is the base64-encoded tweak generated on encode, for
+	// transformations with a generated tweak source
+	Tweak string `json:"tweak,omitempty" structs:"tweak" mapstructure:"tweak"`
+
+	// Reference is the caller supplied reference of the request item
+	Reference string `json:"reference" structs:"reference" mapstructure:"reference"`
+
+	// Error, if set represents a failure encountered while processing a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+func pathEncode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "encode/" + framework.GenericNameRegex("role_name"),
+		Fields:  transformFields(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathEncodeWrite,
+		},
+
+		HelpSynopsis:    pathEncodeHelpSyn,
+		HelpDescription: pathEncodeHelpDesc,
+	}
+}
+
+func pathDecode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "decode/" + framework.GenericNameRegex("role_name"),
+		Fields:  transformFields(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDecodeWrite,
+		},
+
+		HelpSynopsis:    pathDecodeHelpSyn,
+		HelpDescription: pathDecodeHelpDesc,
+	}
+}
+
+func transformFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"role_name": {
+			Type:        framework.TypeString,
+			Description: "Name of the role.",
+		},
+
+		"value": {
+			Type:        framework.TypeString,
+			Description: "The value to encode or decode.",
+		},
+
+		"transformation": {
+			Type:        framework.TypeString,
+			Description: "The name of the transformation to use. Optional if the role has a single transformation.",
+		},
+
+		"tweak": {
+			Type:        framework.TypeString,
+			Description: "The base64-encoded, 7-byte tweak of FPE transformations whose tweak is supplied or generated.",
+		},
+
+		"reference": {
+			Type:        framework.TypeString,
+			Description: "A caller supplied reference returned with the result.",
+		},
+
+		"batch_input": {
+			Type: framework.TypeSlice,
+			Description: `
+Specifies a list of items to be processed in a single batch. When this
+parameter is set, if the parameters 'value', 'transformation', 'tweak' and
+'reference' are also set, they will be ignored. Any batch output will
+preserve the order of the batch input.`,
+		},
+	}
+}
+
+func (b *backend) pathEncodeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.transformRequest(ctx, req, d, true)
+}
+
+func (b *backend) pathDecodeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.transformRequest(ctx, req, d, false)
+}
+
+func (b *backend) transformRequest(ctx context.Context, req *logical.Request, d *framework.FieldData, encode bool) (*logical.Response, error) {
+	roleName := d.Get("role_name").(string)
+	role, err := b.Role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q does not exist", roleName)), logical.ErrInvalidRequest
+	}
+
+	var batchInputItems []BatchRequestItem
+	batchInputRaw := d.Raw["batch_input"]
+	if batchInputRaw != nil {
+		if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to parse batch input: %v", err)), logical.ErrInvalidRequest
+		}
+		if len(batchInputItems) == 0 {
+			return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+	} else {
+		batchInputItems = []BatchRequestItem{{
+			Value:          d.Get("value").(string),
+			Transformation: d.Get("transformation").(string),
+			Tweak:          d.Get("tweak").(string),
+			Reference:      d.Get("reference").(string),
+		}}
+	}
+
+	batchResponseItems := make([]BatchResponseItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		result, err := b.transformItem(ctx, req.Storage, roleName, role, item, encode)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				if batchInputRaw == nil {
+					return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+				}
+				batchResponseItems[i].Error = err.Error()
+			default:
+				return nil, err
+			}
+		} else {
+			batchResponseItems[i] = *result
+		}
+		batchResponseItems[i].Reference = item.Reference
+	}
+
+	if batchInputRaw != nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"batch_results": batchResponseItems,
+			},
+		}, nil
+	}
+
+	result := batchResponseItems[0]
+	data := map[string]interface{}{}
+	if encode {
+		data["encoded_value"] = result.EncodedValue
+		if result.Tweak != "" {
+			data["tweak"] = result.Tweak
+		}
+	} else {
+		data["decoded_value"] = result.DecodedValue
+	}
+	if result.Reference != "" {
+		data["reference"] = result.Reference
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// transformItem encodes or decodes a single value. Errors caused by the
+// request are returned as errutil.UserError.
+func (b *backend) transformItem(ctx context.Context, s logical.Storage, roleName string, role *roleEntry, item BatchRequestItem, encode bool) (*BatchResponseItem, error) {
+	name := item.Transformation
+	if name == "" {
+		if len(role.Transformations) != 1 {
+			return nil, errutil.UserError{Err: "missing transformation"}
+		}
+		name = role.Transformations[0]
+	}
+	if !strutil.StrListContains(role.Transformations, name) {
+		return nil, errutil.UserError{Err: fmt.Sprintf("transformation %q is not allowed for role %q", name, roleName)}
+	}
+
+	transformation, err := b.Transformation(ctx, s, name)
+	if err != nil {
+		return nil, err
+	}
+	if transformation == nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("transformation %q does not exist", name)}
+	}
+	if !strutil.StrListContains(transformation.AllowedRoles, "*") && !strutil.StrListContains(transformation.AllowedRoles, roleName) {
+		return nil, errutil.UserError{Err: fmt.Sprintf("role %q is not allowed to use transformation %q", roleName, name)}
+	}
+
+	template, err := b.Template(ctx, s, transformation.Template)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template %q of transformation %q does not exist", transformation.Template, name)
+	}
+	re, err := template.compile()
+	if err != nil {
+		return nil, err
+	}
+	alphabet, err := b.Alphabet(ctx, s, template.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if alphabet == nil {
+		return nil, fmt.Errorf("alphabet %q of template %q does not exist", template.Alphabet, transformation.Template)
+	}
+
+	result := &BatchResponseItem{}
+
+	if transformation.Type == transformationTypeMasking {
+		if !encode {
+			return nil, errutil.UserError{Err: "masked values can't be decoded"}
+		}
+		mask := []rune(transformation.MaskingCharacter)[0]
+		result.EncodedValue, err = transformValue(re, item.Value, func(chars []rune) ([]rune, error) {
+			for i := range chars {
+				chars[i] = mask
+			}
+			return chars, nil
+		})
+		if err != nil {
+			return nil, errutil.UserError{Err: err.Error()}
+		}
+		return result, nil
+	}
+
+	var tweak []byte
+	switch {
+	case transformation.TweakSource == tweakSourceInternal:
+		if item.Tweak != "" {
+			return nil, errutil.UserError{Err: "a tweak can't be supplied for transformations with an internal tweak source"}
+		}
+		tweak = transformation.Tweak
+
+	case transformation.TweakSource == tweakSourceGenerated && encode:
+		if item.Tweak != "" {
+			return nil, errutil.UserError{Err: "a tweak can't be supplied when encoding with a generated tweak source"}
+		}
+		tweak = make([]byte, ff3TweakLen)
+		if _, err := io.ReadFull(b.GetRandomReader(), tweak); err != nil {
+			return nil, err
+		}
+		result.Tweak = base64.StdEncoding.EncodeToString(tweak)
+
+	default:
+		if item.Tweak == "" {
+			return nil, errutil.UserError{Err: "missing tweak"}
+		}
+		tweak, err = base64.StdEncoding.DecodeString(item.Tweak)
+		if err != nil {
+			return nil, errutil.UserError{Err: "failed to base64-decode tweak"}
+		}
+		if len(tweak) != ff3TweakLen {
+			return nil, errutil.UserError{Err: fmt.Sprintf("tweak must be %d bytes long", ff3TweakLen)}
+		}
+	}
+
+	chars := []rune(alphabet.Alphabet)
+	cipher, err := newFF3Cipher(transformation.Key, len(chars))
+	if err != nil {
+		return nil, err
+	}
+	out, err := transformValue(re, item.Value, func(in []rune) ([]rune, error) {
+		numerals, err := toNumerals(chars, in)
+		if err != nil {
+			return nil, err
+		}
+		if encode {
+			numerals, err = cipher.Encrypt(numerals, tweak)
+		} else {
+			numerals, err = cipher.Decrypt(numerals, tweak)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fromNumerals(chars, numerals), nil
+	})
+	if err != nil {
+		return nil, errutil.UserError{Err: err.Error()}
+	}
+	if encode {
+		result.EncodedValue = out
+	} else {
+		result.DecodedValue = out
+	}
+
+	return result, nil
+}
+
+const pathEncodeHelpSyn = `Encode values using a role`
+
+const pathEncodeHelpDesc = `
+This path uses the named role to encode values with one of its
+transformations. The format of encoded values matches the template of the
+transformation. When the tweak of the transformation is generated, it is
+returned along with the encoded value and must be supplied to decode it.
+`
+
+const pathDecodeHelpSyn = `Decode values using a role`
+
+const pathDecodeHelpDesc = `
+This path uses the named role to decode values previously encoded with one of
+its FPE transformations. Masked values can't be decoded.
+`
@@ -0,0 +1,225 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const templateTypeRegex = "regex"
+
+var builtinTemplates = map[string]*templateEntry{
+	"builtin/creditcardnumber": {
+		Type:     templateTypeRegex,
+		Pattern:  `(\d{4})[- ]?(\d{4})[- ]?(\d{4})[- ]?(\d{4})`,
+		Alphabet: "builtin/numeric",
+	},
+	"builtin/socialsecuritynumber": {
+		Type:     templateTypeRegex,
+		Pattern:  `(\d{3})[- ]?(\d{2})[- ]?(\d{4})`,
+		Alphabet: "builtin/numeric",
+	},
+}
+
+type templateEntry struct {
+	Type     string `json:"type"`
+	Pattern  string `json:"pattern"`
+	Alphabet string `json:"alphabet"`
+}
+
+// compile returns the regular expression matching whole values of the
+// template
+func (t *templateEntry) compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + t.Pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("pattern must contain at least one capture group")
+	}
+	return re, nil
+}
+
+func pathListTemplates(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "template/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathTemplateList,
+		},
+
+		HelpSynopsis:    pathTemplateHelpSyn,
+		HelpDescription: pathTemplateHelpDesc,
+	}
+}
+
+func pathTemplates(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "template/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the template.",
+			},
+
+			"type": {
+				Type:        framework.TypeString,
+				Default:     templateTypeRegex,
+				Description: `The type of the template. Only "regex" is currently supported.`,
+			},
+
+			"pattern": {
+				Type:        framework.TypeString,
+				Description: "The regular expression values must match. The characters matched by its capture groups are transformed, the others are kept as is.",
+			},
+
+			"alphabet": {
+				Type:        framework.TypeString,
+				Description: "The name of the alphabet the transformed characters belong to.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathTemplateRead,
+			logical.UpdateOperation: b.pathTemplateWrite,
+			logical.DeleteOperation: b.pathTemplateDelete,
+		},
+
+		HelpSynopsis:    pathTemplateHelpSyn,
+		HelpDescription: pathTemplateHelpDesc,
+	}
+}
+
+// Template returns the named template, which may be a builtin one
+func (b *backend) Template(ctx context.Context, s logical.Storage, n string) (*templateEntry, error) {
+	if strings.HasPrefix(n, builtinPrefix) {
+		return builtinTemplates[n], nil
+	}
+
+	entry, err := s.Get(ctx, "template/"+n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result templateEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathTemplateList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "template/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathTemplateRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	template, err := b.Template(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"type":     template.Type,
+			"pattern":  template.Pattern,
+			"alphabet": template.Alphabet,
+		},
+	}, nil
+}
+
+func (b *backend) pathTemplateWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	template := &templateEntry{
+		Type:     d.Get("type").(string),
+		Pattern:  d.Get("pattern").(string),
+		Alphabet: d.Get("alphabet").(string),
+	}
+
+	if template.Type != templateTypeRegex {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported template type %q", template.Type)), nil
+	}
+	if template.Pattern == "" {
+		return logical.ErrorResponse("missing pattern"), nil
+	}
+	if _, err := template.compile(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid pattern: %v", err)), nil
+	}
+
+	if template.Alphabet == "" {
+		return logical.ErrorResponse("missing alphabet"), nil
+	}
+	alphabet, err := b.Alphabet(ctx, req.Storage, template.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if alphabet == nil {
+		return logical.ErrorResponse(fmt.Sprintf("alphabet %q does not exist", template.Alphabet)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("template/"+name, template)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathTemplateDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	// Deleting a template in use would prevent decoding values
+	transformations, err := req.Storage.List(ctx, "transformation/")
+	if err != nil {
+		return nil, err
+	}
+	for _, transformationName := range transformations {
+		transformation, err := b.Transformation(ctx, req.Storage, transformationName)
+		if err != nil {
+			return nil, err
+		}
+		if transformation != nil && transformation.Template == name {
+			return logical.ErrorResponse(fmt.Sprintf("template is in use by transformation %q", transformationName)), nil
+		}
+	}
+
+	err = req.Storage.Delete(ctx, "template/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathTemplateHelpSyn = `
+Manage the templates used by transformations.
+`
+
+const pathTemplateHelpDesc = `
+This path lets you manage templates, which describe the format of values.
+The pattern of a template must match whole values; the characters matched by
+its capture groups are transformed, while the other characters, such as
+separators, are kept as is. Capture groups must not overlap. Templates can't
+be deleted while in use by a transformation.
+
+The following builtin templates are always available:
+builtin/creditcardnumber and builtin/socialsecuritynumber.
+`
@@ -0,0 +1,306 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	transformationTypeFPE     = "fpe"
+	transformationTypeMasking = "masking"
+
+	tweakSourceSupplied  = "supplied"
+	tweakSourceGenerated = "generated"
+	tweakSourceInternal  = "internal"
+
+	// transformationKeyLen is the length in bytes of the AES keys used by
+	// FPE transformations
+	transformationKeyLen = 32
+)
+
+type transformationEntry struct {
+	Type             string   `json:"type"`
+	Template         string   `json:"template"`
+	TweakSource      string   `json:"tweak_source,omitempty"`
+	MaskingCharacter string   `json:"masking_character,omitempty"`
+	AllowedRoles     []string `json:"allowed_roles"`
+
+	// Key and Tweak are only set for FPE transformations, Tweak only when
+	// its source is internal
+	Key   []byte `json:"key,omitempty"`
+	Tweak []byte `json:"tweak,omitempty"`
+}
+
+func pathListTransformations(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "transformation/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathTransformationList,
+		},
+
+		HelpSynopsis:    pathTransformationHelpSyn,
+		HelpDescription: pathTransformationHelpDesc,
+	}
+}
+
+func pathTransformations(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "transformation/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the transformation.",
+			},
+
+			"type": {
+				Type:        framework.TypeString,
+				Description: `The type of the transformation, either "fpe" or "masking". Can't be changed once set.`,
+			},
+
+			"template": {
+				Type:        framework.TypeString,
+				Description: "The name of the template describing the format of values. Can't be changed once set for FPE transformations.",
+			},
+
+			"tweak_source": {
+				Type:        framework.TypeString,
+				Description: `The source of the tweak of FPE transformations: "supplied" by the client, "generated" by Vault on encode, or "internal". Defaults to "supplied". Can't be changed once set.`,
+			},
+
+			"masking_character": {
+				Type:        framework.TypeString,
+				Description: `The character replacing the transformed characters of masking transformations. If multiple characters are given, only the first one is used. Defaults to "*".`,
+			},
+
+			"allowed_roles": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The roles allowed to use the transformation. "*" allows all roles.`,
+			},
+		},
+
+		ExistenceCheck: b.pathTransformationExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathTransformationRead,
+			logical.CreateOperation: b.pathTransformationWrite,
+			logical.UpdateOperation: b.pathTransformationWrite,
+			logical.DeleteOperation: b.pathTransformationDelete,
+		},
+
+		HelpSynopsis:    pathTransformationHelpSyn,
+		HelpDescription: pathTransformationHelpDesc,
+	}
+}
+
+func (b *backend) Transformation(ctx context.Context, s logical.Storage, n string) (*transformationEntry, error) {
+	entry, err := s.Get(ctx, "transformation/"+n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result transformationEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathTransformationExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	transformation, err := b.Transformation(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return transformation != nil, nil
+}
+
+func (b *backend) pathTransformationList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "transformation/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathTransformationRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	transformation, err := b.Transformation(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if transformation == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"type":          transformation.Type,
+		"templates":     []string{transformation.Template},
+		"allowed_roles": transformation.AllowedRoles,
+	}
+	switch transformation.Type {
+	case transformationTypeFPE:
+		data["tweak_source"] = transformation.TweakSource
+	case transformationTypeMasking:
+		data["masking_character"] = transformation.MaskingCharacter
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathTransformationWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	transformation, err := b.Transformation(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	create := transformation == nil
+	if create {
+		transformation = &transformationEntry{
+			Type:        d.Get("type").(string),
+			TweakSource: tweakSourceSupplied,
+		}
+		switch transformation.Type {
+		case transformationTypeFPE, transformationTypeMasking:
+		case "":
+			return logical.ErrorResponse("missing type"), nil
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("unsupported transformation type %q", transformation.Type)), nil
+		}
+	} else if typeRaw, ok := d.GetOk("type"); ok && typeRaw.(string) != transformation.Type {
+		return logical.ErrorResponse("the type of a transformation can't be changed"), nil
+	}
+	fpe := transformation.Type == transformationTypeFPE
+
+	if templateRaw, ok := d.GetOk("template"); ok {
+		if !create && fpe && templateRaw.(string) != transformation.Template {
+			return logical.ErrorResponse("the template of an FPE transformation can't be changed"), nil
+		}
+		transformation.Template = templateRaw.(string)
+	}
+	if transformation.Template == "" {
+		return logical.ErrorResponse("missing template"), nil
+	}
+	template, err := b.Template(ctx, req.Storage, transformation.Template)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return logical.ErrorResponse(fmt.Sprintf("template %q does not exist", transformation.Template)), nil
+	}
+
+	if allowedRolesRaw, ok := d.GetOk("allowed_roles"); ok {
+		transformation.AllowedRoles = allowedRolesRaw.([]string)
+	}
+
+	switch {
+	case fpe:
+		if tweakSourceRaw, ok := d.GetOk("tweak_source"); ok {
+			if !create && tweakSourceRaw.(string) != transformation.TweakSource {
+				return logical.ErrorResponse("the tweak source of a transformation can't be changed"), nil
+			}
+			transformation.TweakSource = tweakSourceRaw.(string)
+		}
+
+		if create {
+			switch transformation.TweakSource {
+			case tweakSourceSupplied, tweakSourceGenerated:
+			case tweakSourceInternal:
+				transformation.Tweak = make([]byte, ff3TweakLen)
+				if _, err := io.ReadFull(b.GetRandomReader(), transformation.Tweak); err != nil {
+					return nil, err
+				}
+			default:
+				return logical.ErrorResponse(fmt.Sprintf("unsupported tweak source %q", transformation.TweakSource)), nil
+			}
+
+			transformation.Key = make([]byte, transformationKeyLen)
+			if _, err := io.ReadFull(b.GetRandomReader(), transformation.Key); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		transformation.TweakSource = ""
+		if maskingCharacterRaw, ok := d.GetOk("masking_character"); ok {
+			// Only the first character is used
+			maskingCharacter := []rune(maskingCharacterRaw.(string))
+			if len(maskingCharacter) == 0 {
+				return logical.ErrorResponse("masking_character can't be empty"), nil
+			}
+			transformation.MaskingCharacter = string(maskingCharacter[0])
+		}
+		if transformation.MaskingCharacter == "" {
+			transformation.MaskingCharacter = "*"
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("transformation/"+name, transformation)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathTransformationDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	// Deleting a transformation in use would lose its key
+	roles, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+	for _, roleName := range roles {
+		role, err := b.Role(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && strutil.StrListContains(role.Transformations, name) {
+			return logical.ErrorResponse(fmt.Sprintf("transformation is in use by role %q", roleName)), nil
+		}
+	}
+
+	err = req.Storage.Delete(ctx, "transformation/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathTransformationHelpSyn = `
+Manage transformations.
+`
+
+const pathTransformationHelpDesc = `
+This path lets you manage transformations, which define how the values
+matching a template are encoded.
+
+FPE transformations encrypt values with FF3-1 format-preserving encryption,
+using a key generated when the transformation is created: encoded values
+match the same template and can be decoded. Each encoding also depends on a
+tweak, which is either supplied by the client, generated by Vault and
+returned on encode, or internal to the transformation. The same tweak must be
+given to decode a value.
+
+Masking transformations replace the transformed characters with a masking
+character; masked values can't be decoded.
+
+Transformations can only be used by the roles listed in allowed_roles, and
+can't be deleted while in use by a role.
+`
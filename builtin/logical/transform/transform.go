@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// transformValue applies fn to the characters of value matched by the capture
+// groups of re, and returns value with them replaced by the characters fn
+// returns. The other characters of value are kept as is.
+func transformValue(re *regexp.Regexp, value string, fn func([]rune) ([]rune, error)) (string, error) {
+	loc := re.FindStringSubmatchIndex(value)
+	if loc == nil {
+		return "", errors.New("value does not match the template of the transformation")
+	}
+
+	// Gather the characters of all groups, skipping the ones that didn't
+	// participate in the match
+	var chars []rune
+	var groups [][2]int
+	end := 0
+	for i := 2; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			continue
+		}
+		if loc[i] < end {
+			return "", errors.New("the capture groups of the template must not overlap")
+		}
+		groups = append(groups, [2]int{loc[i], loc[i+1]})
+		chars = append(chars, []rune(value[loc[i]:loc[i+1]])...)
+		end = loc[i+1]
+	}
+
+	out, err := fn(chars)
+	if err != nil {
+		return "", err
+	}
+	if len(out) != len(chars) {
+		return "", fmt.Errorf("transformed %d characters into %d", len(chars), len(out))
+	}
+
+	var result []rune
+	end = 0
+	for _, group := range groups {
+		n := len([]rune(value[group[0]:group[1]]))
+		result = append(result, []rune(value[end:group[0]])...)
+		result = append(result, out[:n]...)
+		out = out[n:]
+		end = group[1]
+	}
+	result = append(result, []rune(value[end:])...)
+
+	return string(result), nil
+}
+
+// toNumerals returns the indexes in the alphabet of the characters
+func toNumerals(alphabet, chars []rune) ([]uint16, error) {
+	index := make(map[rune]uint16, len(alphabet))
+	for i, r := range alphabet {
+		index[r] = uint16(i)
+	}
+
+	numerals := make([]uint16, len(chars))
+	for i, r := range chars {
+		numeral, ok := index[r]
+		if !ok {
+			return nil, fmt.Errorf("character %q is not part of the alphabet of the template", r)
+		}
+		numerals[i] = numeral
+	}
+	return numerals, nil
+}
+
+// fromNumerals returns the characters of the alphabet at the given indexes
+func fromNumerals(alphabet []rune, numerals []uint16) []rune {
+	chars := make([]rune, len(numerals))
+	for i, numeral := range numerals {
+		chars[i] = alphabet[numeral]
+	}
+	return chars
+}
@@ -2,14 +2,30 @@ package transit
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	metrics "github.com/armon/go-metrics"
 	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+const (
+	// minAutoRotatePeriod is the shortest period keys can be automatically
+	// rotated with
+	minAutoRotatePeriod = time.Hour
+
+	// autoRotateCheckInterval is how often keys are checked for automatic
+	// rotation
+	autoRotateCheckInterval = 10 * time.Minute
+)
+
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
 
 	b, err := Backend(ctx, conf)
@@ -37,7 +53,9 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			// as the handler is greedy
 			b.pathConfig(),
 			b.pathRotate(),
+			b.pathImport(),
 			b.pathRewrap(),
+			b.pathWrappingKey(),
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
@@ -55,9 +73,10 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathCacheConfig(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		BackendType:  logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
 	}
 
 	// determine cacheSize to use. Defaults to 0 which means unlimited
@@ -83,6 +102,10 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// nextAutoRotateCheck is when keys are next checked for automatic
+	// rotation
+	nextAutoRotateCheck time.Time
 }
 
 func GetCacheSizeFromStorage(ctx context.Context, s logical.Storage) (int, error) {
@@ -111,3 +134,74 @@ func (b *backend) invalidate(_ context.Context, key string) {
 		b.lm.InvalidatePolicy(name)
 	}
 }
+
+// periodicFunc is invoked by the RollbackManager about once a minute. Every
+// autoRotateCheckInterval, it rotates the keys whose latest version is older
+// than their auto_rotate_period.
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	if !b.nextAutoRotateCheck.IsZero() && time.Now().Before(b.nextAutoRotateCheck) {
+		return nil
+	}
+
+	// Rotating writes the key, so leave it to the nodes that own it
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary|consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+	b.nextAutoRotateCheck = time.Now().Add(autoRotateCheckInterval)
+
+	names, err := req.Storage.List(ctx, "policy/")
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, name := range names {
+		// Skip the keys used internally
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		if err := b.autoRotateKey(ctx, req.Storage, name); err != nil {
+			metrics.IncrCounter([]string{"secrets", "transit", "auto_rotate", "failure"}, 1)
+			errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("error auto-rotating key %q: {{err}}", name), err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// autoRotateKey rotates the named key if automatic rotation is enabled on it
+// and its latest version is older than its auto_rotate_period
+func (b *backend) autoRotateKey(ctx context.Context, storage logical.Storage, name string) error {
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return nil
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(true)
+	}
+	defer p.Unlock()
+
+	if p.AutoRotatePeriod == 0 || (p.Imported && !p.AllowImportedKeyRotation) {
+		return nil
+	}
+
+	latest, ok := p.Keys[strconv.Itoa(p.LatestVersion)]
+	if !ok {
+		return fmt.Errorf("latest version %d not found", p.LatestVersion)
+	}
+	if time.Now().Before(latest.CreationTime.Add(p.AutoRotatePeriod)) {
+		return nil
+	}
+
+	b.Logger().Info("automatically rotating key", "name", name, "auto_rotate_period", p.AutoRotatePeriod)
+	if err := p.Rotate(ctx, storage, b.GetRandomReader()); err != nil {
+		return err
+	}
+	metrics.IncrCounter([]string{"secrets", "transit", "auto_rotate", "success"}, 1)
+	return nil
+}
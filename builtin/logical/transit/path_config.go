@@ -3,6 +3,7 @@ package transit
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
@@ -33,6 +34,22 @@ to be used for signing. If set to zero, only
 the latest version of the key is allowed.`,
 			},
 
+			"bump_min_decryption_version": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the minimum version of the key allowed
+to be decrypted is set to the latest version,
+e.g. once all ciphertexts have been rewrapped.
+Can't be used together with min_decryption_version.`,
+			},
+
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `How long the latest version of the key is used
+before the key is automatically rotated. Must be
+at least one hour, or zero to disable automatic
+rotation.`,
+			},
+
 			"deletion_allowed": &framework.FieldSchema{
 				Type:        framework.TypeBool,
 				Description: "Whether to allow deletion of the key",
@@ -84,6 +101,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 	originalDeletionAllowed := p.DeletionAllowed
 	originalExportable := p.Exportable
 	originalAllowPlaintextBackup := p.AllowPlaintextBackup
+	originalAutoRotatePeriod := p.AutoRotatePeriod
 
 	defer func() {
 		if retErr != nil || (resp != nil && resp.IsError()) {
@@ -92,6 +110,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 			p.DeletionAllowed = originalDeletionAllowed
 			p.Exportable = originalExportable
 			p.AllowPlaintextBackup = originalAllowPlaintextBackup
+			p.AutoRotatePeriod = originalAutoRotatePeriod
 		}
 	}()
 
@@ -100,6 +119,12 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 	persistNeeded := false
 
 	minDecryptionVersionRaw, ok := d.GetOk("min_decryption_version")
+	if bump := d.Get("bump_min_decryption_version").(bool); bump {
+		if ok {
+			return logical.ErrorResponse("cannot set both min_decryption_version and bump_min_decryption_version"), nil
+		}
+		minDecryptionVersionRaw, ok = p.LatestVersion, true
+	}
 	if ok {
 		minDecryptionVersion := minDecryptionVersionRaw.(int)
 
@@ -185,6 +210,21 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		}
 	}
 
+	autoRotatePeriodRaw, ok := d.GetOk("auto_rotate_period")
+	if ok {
+		autoRotatePeriod := time.Duration(autoRotatePeriodRaw.(int)) * time.Second
+		if err := validateAutoRotatePeriod(autoRotatePeriod); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if autoRotatePeriod != 0 && p.Imported && !p.AllowImportedKeyRotation {
+			return logical.ErrorResponse("imported keys that don't allow rotation can't be rotated automatically"), nil
+		}
+		if autoRotatePeriod != p.AutoRotatePeriod {
+			p.AutoRotatePeriod = autoRotatePeriod
+			persistNeeded = true
+		}
+	}
+
 	if !persistNeeded {
 		return nil, nil
 	}
@@ -208,5 +248,7 @@ const pathConfigHelpSyn = `Configure a named encryption key`
 const pathConfigHelpDesc = `
 This path is used to configure the named key. Currently, this
 supports adjusting the minimum version of the key allowed to
-be used for decryption via the min_decryption_version parameter.
+be used for decryption via the min_decryption_version parameter,
+and automatic rotation of the key via the auto_rotate_period
+parameter.
 `
@@ -5,6 +5,9 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
 
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -283,3 +286,119 @@ func TestTransit_ConfigSettings(t *testing.T) {
 	testHMAC(3, true)
 	testHMAC(2, false)
 }
+
+func TestTransit_AutoRotate(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		return resp
+	}
+	doErrReq := func(op logical.Operation, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error; req:\n%#v\nresp:\n%#v", data, resp)
+		}
+	}
+
+	doErrReq(logical.UpdateOperation, "keys/aes", map[string]interface{}{
+		"auto_rotate_period": "10m",
+	})
+	doReq(logical.UpdateOperation, "keys/aes", map[string]interface{}{
+		"auto_rotate_period": "2h",
+	})
+	doReq(logical.UpdateOperation, "keys/other", nil)
+
+	resp := doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["auto_rotate_period"] != int64(7200) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Nothing is due for rotation yet
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["latest_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Age the key past its rotation period
+	p, _, err := b.lm.GetPolicy(context.Background(), keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    "aes",
+	}, b.GetRandomReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Lock(true)
+	entry := p.Keys["1"]
+	entry.CreationTime = entry.CreationTime.Add(-3 * time.Hour)
+	p.Keys["1"] = entry
+	if err := p.Persist(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+	p.Unlock()
+
+	// Checks are rate limited
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["latest_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	b.nextAutoRotateCheck = time.Time{}
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["latest_version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "keys/other", nil)
+	if resp.Data["latest_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Disable automatic rotation
+	doErrReq(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"auto_rotate_period": "30m",
+	})
+	doReq(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"auto_rotate_period": "0",
+	})
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["auto_rotate_period"] != int64(0) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Once everything has been rewrapped, older versions can be retired
+	doErrReq(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"bump_min_decryption_version": true,
+		"min_decryption_version":      1,
+	})
+	doReq(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{
+		"bump_min_decryption_version": true,
+	})
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["min_decryption_version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
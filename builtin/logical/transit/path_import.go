@@ -0,0 +1,266 @@
+package transit
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func (b *backend) pathImport() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "aes256-gcm96",
+				Description: `
+The type of key being imported. Currently, "aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric),
+"chacha20-poly1305" (symmetric), "ecdsa-p256" (asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric),
+"ed25519" (asymmetric), "rsa-2048" (asymmetric), "rsa-3072" (asymmetric), "rsa-4096" (asymmetric) are supported.
+Defaults to "aes256-gcm96".
+`,
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64-encoded ciphertext of the key being
+imported. It is made of an ephemeral AES-256 key
+wrapped with RSA-OAEP using the wrapping key, followed
+by the key being imported wrapped with the ephemeral
+key using AES key wrap with padding (RFC 5649).
+Symmetric keys are wrapped as raw bytes and
+asymmetric keys as PKCS #8, DER-encoded private keys.`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used by RSA-OAEP to wrap the
+ephemeral key. Currently, "SHA1", "SHA224", "SHA256",
+"SHA384" and "SHA512" are supported. Defaults to "SHA256".`,
+			},
+
+			"allow_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Allows rotating the key within Vault, in which
+case the new versions are generated by Vault.`,
+			},
+
+			"derived": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables key derivation mode. This
+allows for per-transaction unique
+keys for encryption operations.`,
+			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables keys to be exportable.
+This allows for all the valid keys
+in the key ring to be exported.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking a backup of the named
+key in plaintext format. Once set,
+this cannot be disabled.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportWrite,
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *backend) pathImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	keyType, err := parseKeyType(d.Get("type").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var hashFn hash.Hash
+	switch d.Get("hash_function").(string) {
+	case "SHA1":
+		hashFn = sha1.New()
+	case "SHA224":
+		hashFn = sha256.New224()
+	case "SHA256":
+		hashFn = sha256.New()
+	case "SHA384":
+		hashFn = sha512.New384()
+	case "SHA512":
+		hashFn = sha512.New()
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported hash function %q", d.Get("hash_function").(string))), logical.ErrInvalidRequest
+	}
+
+	ciphertextRaw := d.Get("ciphertext").(string)
+	if ciphertextRaw == "" {
+		return logical.ErrorResponse("missing ciphertext"), logical.ErrInvalidRequest
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextRaw)
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode ciphertext"), logical.ErrInvalidRequest
+	}
+
+	wrappingKey, err := b.getWrappingKey(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) <= wrappingKey.Size() {
+		return logical.ErrorResponse("ciphertext is too short"), logical.ErrInvalidRequest
+	}
+
+	ephemeralKey, err := rsa.DecryptOAEP(hashFn, b.GetRandomReader(), wrappingKey, ciphertext[:wrappingKey.Size()], nil)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to unwrap ephemeral key: %v", err)), logical.ErrInvalidRequest
+	}
+	key, err := kwpUnwrap(ephemeralKey, ciphertext[wrappingKey.Size():])
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to unwrap key: %v", err)), logical.ErrInvalidRequest
+	}
+
+	err = b.lm.ImportPolicy(ctx, keysutil.PolicyRequest{
+		Storage:                  req.Storage,
+		Name:                     name,
+		KeyType:                  keyType,
+		Derived:                  d.Get("derived").(bool),
+		Exportable:               d.Get("exportable").(bool),
+		AllowPlaintextBackup:     d.Get("allow_plaintext_backup").(bool),
+		AllowImportedKeyRotation: d.Get("allow_rotation").(bool),
+	}, key, b.GetRandomReader())
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// kwpIV is the alternative initial value of AES key wrap with padding
+var kwpIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+var errKWPIntegrity = errors.New("integrity check failed")
+
+// kwpWrap wraps the plaintext with the given key using AES key wrap with
+// padding, as described in RFC 5649
+func kwpWrap(kek, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) == 0 {
+		return nil, errors.New("nothing to wrap")
+	}
+
+	n := (len(plaintext) + 7) / 8
+	buf := make([]byte, 8+n*8)
+	copy(buf, kwpIV)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(plaintext)))
+	copy(buf[8:], plaintext)
+
+	if n == 1 {
+		block.Encrypt(buf, buf)
+		return buf, nil
+	}
+
+	var blk [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(blk[:8], buf[:8])
+			copy(blk[8:], buf[i*8:(i+1)*8])
+			block.Encrypt(blk[:], blk[:])
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(blk[:8])^uint64(n*j+i))
+			copy(buf[i*8:(i+1)*8], blk[8:])
+		}
+	}
+	return buf, nil
+}
+
+// kwpUnwrap unwraps the ciphertext with the given key using AES key wrap with
+// padding, as described in RFC 5649
+func kwpUnwrap(kek, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 16 || len(ciphertext)%8 != 0 {
+		return nil, errors.New("invalid wrapped key length")
+	}
+
+	n := len(ciphertext)/8 - 1
+	buf := make([]byte, len(ciphertext))
+	copy(buf, ciphertext)
+
+	if n == 1 {
+		block.Decrypt(buf, buf)
+	} else {
+		var blk [16]byte
+		for j := 5; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				binary.BigEndian.PutUint64(blk[:8], binary.BigEndian.Uint64(buf[:8])^uint64(n*j+i))
+				copy(blk[8:], buf[i*8:(i+1)*8])
+				block.Decrypt(blk[:], blk[:])
+				copy(buf[:8], blk[:8])
+				copy(buf[i*8:(i+1)*8], blk[8:])
+			}
+		}
+	}
+
+	if subtle.ConstantTimeCompare(buf[:4], kwpIV) != 1 {
+		return nil, errKWPIntegrity
+	}
+	mli := int(binary.BigEndian.Uint32(buf[4:8]))
+	if mli <= 8*(n-1) || mli > 8*n {
+		return nil, errKWPIntegrity
+	}
+	for _, c := range buf[8+mli:] {
+		if c != 0 {
+			return nil, errKWPIntegrity
+		}
+	}
+	return buf[8 : 8+mli], nil
+}
+
+const pathImportHelpSyn = `Imports an externally-generated key into a new transit key`
+
+const pathImportHelpDesc = `
+This path is used to import an externally-generated key into Vault. The key
+must be wrapped for transport: a random, ephemeral AES-256 key is wrapped
+with RSA-OAEP using the public key returned by the "wrapping_key" path, and
+the key being imported is wrapped with the ephemeral key using AES key wrap
+with padding (RFC 5649). The concatenation of both is base64-encoded and sent
+as the ciphertext.
+
+Imported keys can't be rotated within Vault unless allowed on import.
+`
@@ -0,0 +1,239 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_KWP(t *testing.T) {
+	// Test vectors from RFC 5649, section 6
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	cases := []struct {
+		key     string
+		wrapped string
+	}{
+		{
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for _, tc := range cases {
+		key, _ := hex.DecodeString(tc.key)
+		wrapped, _ := hex.DecodeString(tc.wrapped)
+
+		out, err := kwpWrap(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, wrapped) {
+			t.Fatalf("bad wrapped key: expected %x, got %x", wrapped, out)
+		}
+
+		out, err = kwpUnwrap(kek, wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, key) {
+			t.Fatalf("bad unwrapped key: expected %x, got %x", key, out)
+		}
+
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := kwpUnwrap(kek, wrapped); err == nil {
+			t.Fatal("expected an error for a tampered wrapped key")
+		}
+	}
+}
+
+func TestTransit_Import(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "wrapping_key",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	block, _ := pem.Decode([]byte(resp.Data["public_key"].(string)))
+	if block == nil {
+		t.Fatal("failed to decode wrapping key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappingKey := pub.(*rsa.PublicKey)
+
+	wrap := func(key []byte) string {
+		t.Helper()
+		ephemeralKey := make([]byte, 32)
+		if _, err := rand.Read(ephemeralKey); err != nil {
+			t.Fatal(err)
+		}
+		wrappedEphemeralKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, wrappingKey, ephemeralKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrappedKey, err := kwpWrap(ephemeralKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(append(wrappedEphemeralKey, wrappedKey...))
+	}
+
+	importKey := func(name string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name + "/import",
+			Data:      data,
+		})
+	}
+
+	// The wrapping key is internal and must not show up as a key
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.ListOperation,
+		Path:      "keys",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, ok := resp.Data["keys"]; ok {
+		t.Fatalf("expected no keys, got %v", keys)
+	}
+
+	// Import a symmetric key and check that it is the one used to encrypt
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = importKey("aes", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+		"exportable": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "export/encryption-key/aes/1",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if exported := resp.Data["keys"].(map[string]string)["1"]; exported != base64.StdEncoding.EncodeToString(aesKey) {
+		t.Fatalf("bad exported key: %s", exported)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "keys/aes",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["imported_key"] != true || resp.Data["imported_key_allow_rotation"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Imported keys can't be rotated unless allowed
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/aes/rotate",
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected rotation to fail, got: err: %v\nresp: %#v", err, resp)
+	}
+
+	// Keys can't be imported over existing keys
+	resp, err = importKey("aes", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected import to fail, got: err: %v\nresp: %#v", err, resp)
+	}
+
+	// The key material must match the type
+	resp, err = importKey("aes128", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+		"type":       "aes128-gcm96",
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected import to fail, got: err: %v\nresp: %#v", err, resp)
+	}
+
+	// Import an asymmetric key, allowing rotation, and check that it is the
+	// one used to sign
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKeyBytes, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = importKey("ec", map[string]interface{}{
+		"ciphertext":     wrap(ecKeyBytes),
+		"type":           "ecdsa-p256",
+		"allow_rotation": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "keys/ec",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	pemBlock, _ := pem.Decode([]byte(resp.Data["keys"].(map[string]map[string]interface{})["1"]["public_key"].(string)))
+	pub, err = x509.ParsePKIXPublicKey(pemBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ecPub := pub.(*ecdsa.PublicKey); ecPub.X.Cmp(ecKey.X) != 0 || ecPub.Y.Cmp(ecKey.Y) != 0 {
+		t.Fatal("public key doesn't match the imported key")
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/ec/rotate",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	// A key of the wrong type is rejected
+	resp, err = importKey("ed", map[string]interface{}{
+		"ciphertext": wrap(ecKeyBytes),
+		"type":       "ed25519",
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected import to fail, got: err: %v\nresp: %#v", err, resp)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ed25519"
@@ -88,6 +89,14 @@ key in plaintext format. Once set,
 this cannot be disabled.`,
 			},
 
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `How long the latest version of the key is used
+before the key is automatically rotated. Must be
+at least one hour, or zero to disable automatic
+rotation. Defaults to zero.`,
+			},
+
 			"context": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `Base64 encoded context for key derivation.
@@ -114,7 +123,16 @@ func (b *backend) pathKeysList(ctx context.Context, req *logical.Request, d *fra
 		return nil, err
 	}
 
-	return logical.ListResponse(entries), nil
+	// Keys used internally, such as the wrapping key for imports, are
+	// stored under a prefix which isn't a valid key name
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry, "/") {
+			keys = append(keys, entry)
+		}
+	}
+
+	return logical.ListResponse(keys), nil
 }
 
 func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -129,38 +147,26 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse("convergent encryption requires derivation to be enabled"), nil
 	}
 
+	polKeyType, err := parseKeyType(keyType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	autoRotatePeriod := time.Duration(d.Get("auto_rotate_period").(int)) * time.Second
+	if err := validateAutoRotatePeriod(autoRotatePeriod); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	polReq := keysutil.PolicyRequest{
 		Upsert:               true,
 		Storage:              req.Storage,
 		Name:                 name,
+		KeyType:              polKeyType,
 		Derived:              derived,
 		Convergent:           convergent,
 		Exportable:           exportable,
 		AllowPlaintextBackup: allowPlaintextBackup,
-	}
-	switch keyType {
-	case "aes128-gcm96":
-		polReq.KeyType = keysutil.KeyType_AES128_GCM96
-	case "aes256-gcm96":
-		polReq.KeyType = keysutil.KeyType_AES256_GCM96
-	case "chacha20-poly1305":
-		polReq.KeyType = keysutil.KeyType_ChaCha20_Poly1305
-	case "ecdsa-p256":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P256
-	case "ecdsa-p384":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P384
-	case "ecdsa-p521":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P521
-	case "ed25519":
-		polReq.KeyType = keysutil.KeyType_ED25519
-	case "rsa-2048":
-		polReq.KeyType = keysutil.KeyType_RSA2048
-	case "rsa-3072":
-		polReq.KeyType = keysutil.KeyType_RSA3072
-	case "rsa-4096":
-		polReq.KeyType = keysutil.KeyType_RSA4096
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
+		AutoRotatePeriod:     autoRotatePeriod,
 	}
 
 	p, upserted, err := b.lm.GetPolicy(ctx, polReq, b.GetRandomReader())
@@ -182,6 +188,43 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 	return nil, nil
 }
 
+// validateAutoRotatePeriod checks that keys aren't rotated more often than
+// the periodic function can keep up with
+func validateAutoRotatePeriod(period time.Duration) error {
+	if period != 0 && period < minAutoRotatePeriod {
+		return fmt.Errorf("auto_rotate_period must be 0 to disable automatic rotation or at least %s", minAutoRotatePeriod)
+	}
+	return nil
+}
+
+// parseKeyType returns the key type with the given name
+func parseKeyType(keyType string) (keysutil.KeyType, error) {
+	switch keyType {
+	case "aes128-gcm96":
+		return keysutil.KeyType_AES128_GCM96, nil
+	case "aes256-gcm96":
+		return keysutil.KeyType_AES256_GCM96, nil
+	case "chacha20-poly1305":
+		return keysutil.KeyType_ChaCha20_Poly1305, nil
+	case "ecdsa-p256":
+		return keysutil.KeyType_ECDSA_P256, nil
+	case "ecdsa-p384":
+		return keysutil.KeyType_ECDSA_P384, nil
+	case "ecdsa-p521":
+		return keysutil.KeyType_ECDSA_P521, nil
+	case "ed25519":
+		return keysutil.KeyType_ED25519, nil
+	case "rsa-2048":
+		return keysutil.KeyType_RSA2048, nil
+	case "rsa-3072":
+		return keysutil.KeyType_RSA3072, nil
+	case "rsa-4096":
+		return keysutil.KeyType_RSA4096, nil
+	default:
+		return 0, fmt.Errorf("unknown key type %v", keyType)
+	}
+}
+
 // Built-in helper type for returning asymmetric keys
 type asymKey struct {
 	Name         string    `json:"name" structs:"name" mapstructure:"name"`
@@ -220,6 +263,8 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 			"latest_version":         p.LatestVersion,
 			"exportable":             p.Exportable,
 			"allow_plaintext_backup": p.AllowPlaintextBackup,
+			"imported_key":           p.Imported,
+			"auto_rotate_period":     int64(p.AutoRotatePeriod.Seconds()),
 			"supports_encryption":    p.Type.EncryptionSupported(),
 			"supports_decryption":    p.Type.DecryptionSupported(),
 			"supports_signing":       p.Type.SigningSupported(),
@@ -240,6 +285,10 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 		}
 	}
 
+	if p.Imported {
+		resp.Data["imported_key_allow_rotation"] = p.AllowImportedKeyRotation
+	}
+
 	if p.Derived {
 		switch p.KDF {
 		case keysutil.Kdf_hmac_sha256_counter:
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -49,7 +50,15 @@ func (b *backend) pathRotateWrite(ctx context.Context, req *logical.Request, d *
 	err = p.Rotate(ctx, req.Storage, b.GetRandomReader())
 
 	p.Unlock()
-	return nil, err
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+	return nil, nil
 }
 
 const pathRotateHelpSyn = `Rotate named encryption key`
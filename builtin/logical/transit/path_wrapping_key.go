@@ -0,0 +1,89 @@
+package transit
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// wrappingKeyName is the name of the key used to wrap keys being imported.
+// The slash keeps it apart from the keys managed through the API.
+const wrappingKeyName = "import/wrapping-key"
+
+func (b *backend) pathWrappingKey() *framework.Path {
+	return &framework.Path{
+		Pattern: "wrapping_key",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathWrappingKeyRead,
+		},
+
+		HelpSynopsis:    pathWrappingKeyHelpSyn,
+		HelpDescription: pathWrappingKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathWrappingKeyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	wrappingKey, err := b.getWrappingKey(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	derBytes, err := x509.MarshalPKIXPublicKey(&wrappingKey.PublicKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("error marshaling public key: {{err}}", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	})
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": string(pemBytes),
+		},
+	}, nil
+}
+
+// getWrappingKey returns the private key used to wrap keys being imported,
+// generating it on first use
+func (b *backend) getWrappingKey(ctx context.Context, storage logical.Storage) (*rsa.PrivateKey, error) {
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Upsert:  true,
+		Storage: storage,
+		Name:    wrappingKeyName,
+		KeyType: keysutil.KeyType_RSA4096,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("error generating wrapping key: returned policy was nil")
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	keyEntry, ok := p.Keys[strconv.Itoa(p.LatestVersion)]
+	if !ok || keyEntry.RSAKey == nil {
+		return nil, fmt.Errorf("wrapping key not found")
+	}
+	return keyEntry.RSAKey, nil
+}
+
+const pathWrappingKeyHelpSyn = `Returns the public key to use for wrapping imported keys`
+
+const pathWrappingKeyHelpDesc = `
+This path is used to retrieve the RSA-4096 wrapping key for wrapping keys
+that are being imported into transit. The key is generated the first time
+it is requested.
+`
@@ -21,7 +21,7 @@ func (c *AuditCommand) Help() string {
 Usage: vault audit <subcommand> [options] [args]
 
   This command groups subcommands for interacting with Vault's audit devices.
-  Users can list, enable, and disable audit devices.
+  Users can list, enable, and disable audit devices, and verify audit logs.
 
   List all enabled audit devices:
 
@@ -31,6 +31,10 @@ Usage: vault audit <subcommand> [options] [args]
 
        $ vault audit enable file file_path=/var/log/audit.log
 
+  Verify an audit log written by a "file" device:
+
+      $ vault audit verify /var/log/audit.log
+
   Please see the individual subcommand help for detailed usage information.
 `
 
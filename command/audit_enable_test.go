@@ -188,6 +188,8 @@ func TestAuditEnableCommand_Run(t *testing.T) {
 			switch b {
 			case "file":
 				args = append(args, "file_path=discard")
+			case "otel":
+				args = append(args, "endpoint=127.0.0.1:4317", "insecure=true")
 			case "socket":
 				args = append(args, "address=127.0.0.1:8888")
 			case "syslog":
@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*AuditVerifyCommand)(nil)
+var _ cli.CommandAutocomplete = (*AuditVerifyCommand)(nil)
+
+type AuditVerifyCommand struct {
+	*BaseCommand
+
+	flagFormat string
+	flagPrefix string
+	flagDevice string
+	flagValues []string
+}
+
+func (c *AuditVerifyCommand) Synopsis() string {
+	return "Verifies an audit log and searches it for values"
+}
+
+func (c *AuditVerifyCommand) Help() string {
+	helpText := `
+Usage: vault audit verify [options] PATH...
+
+  Reads the audit log at the given local paths and checks that every entry is
+  well formed and that every response entry follows its request entry. A log
+  rotated into several files is verified as a whole by giving the files from
+  the oldest to the newest.
+
+  When one or more values are given, each is hashed by the audit device given
+  with -device, using the same HMAC the device applies when logging, and the
+  entries containing the hash are listed. This answers whether a given secret
+  or token was seen by Vault. Hashing requires a token with access to the
+  sys/audit-hash endpoint.
+
+  Verify the integrity of a log:
+
+      $ vault audit verify /var/log/vault/audit.log
+
+  Find entries that contain a given token:
+
+      $ vault audit verify -device=file -value=s.abcd1234 /var/log/vault/audit.log
+
+  Verify a rotated log:
+
+      $ vault audit verify /var/log/vault/audit-1.log /var/log/vault/audit.log
+
+  The command exits with a code of 2 if any problems were found in the log,
+  and 1 if the log couldn't be verified.
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AuditVerifyCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "log-format",
+		Target:     &c.flagFormat,
+		Default:    "json",
+		Completion: complete.PredictSet("json", "protobuf"),
+		Usage:      "Format the audit log was written in, either \"json\" or \"protobuf\".",
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "prefix",
+		Target:  &c.flagPrefix,
+		Default: "",
+		Usage:   "Prefix configured on the audit device, stripped from each entry.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "device",
+		Target:     &c.flagDevice,
+		Default:    "",
+		Completion: c.PredictVaultAudits(),
+		Usage:      "Path of the audit device whose salt is used to hash values.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "value",
+		Target: &c.flagValues,
+		Usage: "Plaintext value to search the log for. This can be specified " +
+			"multiple times.",
+	})
+
+	return set
+}
+
+func (c *AuditVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *AuditVerifyCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *AuditVerifyCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 1 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 1, got %d)", len(args)))
+		return 1
+	}
+
+	if len(c.flagValues) > 0 && c.flagDevice == "" {
+		c.UI.Error("An audit device must be given with -device to search for values")
+		return 1
+	}
+
+	config := &audit.VerifyConfig{
+		Format: c.flagFormat,
+		Prefix: c.flagPrefix,
+		Values: c.flagValues,
+	}
+
+	if len(c.flagValues) > 0 {
+		client, err := c.Client()
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		device := ensureTrailingSlash(sanitizePath(c.flagDevice))
+		config.HashFunc = func(v string) (string, error) {
+			return client.Sys().AuditHash(device, v)
+		}
+	}
+
+	verifier, err := audit.NewVerifier(config)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error verifying audit log: %s", err))
+		return 1
+	}
+
+	for _, path := range args {
+		file, err := os.Open(path)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error opening audit log: %s", err))
+			return 1
+		}
+		verifier.Verify(path, file)
+		file.Close()
+	}
+	result := verifier.Result()
+
+	code := 0
+	if len(result.Problems) > 0 {
+		code = 2
+	}
+
+	if Format(c.UI) != "table" {
+		if ret := OutputData(c.UI, result); ret != 0 {
+			return ret
+		}
+		return code
+	}
+
+	c.UI.Output(fmt.Sprintf("Read %d request and %d response entries.", result.Requests, result.Responses))
+
+	if len(c.flagValues) > 0 {
+		c.UI.Output("")
+		if len(result.Matches) == 0 {
+			c.UI.Output("No entries contain the given values.")
+		} else {
+			columns := []string{"File | Entry | Type | Time | Request ID | Operation | Path | Value"}
+			for _, m := range result.Matches {
+				columns = append(columns, fmt.Sprintf("%s | %d | %s | %s | %s | %s | %s | %s",
+					m.File, m.Entry, m.Type, m.Time, m.RequestID, m.Operation, m.Path, m.Value))
+			}
+			c.UI.Output(tableOutput(columns, nil))
+		}
+	}
+
+	if len(result.Problems) > 0 {
+		c.UI.Output("")
+		columns := []string{"File | Entry | Problem"}
+		for _, p := range result.Problems {
+			columns = append(columns, fmt.Sprintf("%s | %d | %s", p.File, p.Entry, p.Message))
+		}
+		c.UI.Output(tableOutput(columns, nil))
+	}
+
+	return code
+}
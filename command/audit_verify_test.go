@@ -0,0 +1,149 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testAuditVerifyCommand(tb testing.TB) (*cli.MockUi, *AuditVerifyCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &AuditVerifyCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestAuditVerifyCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			"Not enough arguments",
+			1,
+		},
+		{
+			"value_without_device",
+			[]string{"-value", "foo", "audit.log"},
+			"-device",
+			1,
+		},
+		{
+			"missing_file",
+			[]string{"does-not-exist.log"},
+			"Error opening audit log",
+			1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ui, cmd := testAuditVerifyCommand(t)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("integration", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "vault-audit-verify")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		logPath := filepath.Join(dir, "audit.log")
+
+		if err := client.Sys().EnableAuditWithOptions("file", &api.EnableAuditOptions{
+			Type: "file",
+			Options: map[string]string{
+				"file_path": logPath,
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+			"value": "s3cr3t",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testAuditVerifyCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-device", "file",
+			"-value", "s3cr3t",
+			logPath,
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d: %s", code, exp, ui.OutputWriter.String()+ui.ErrorWriter.String())
+		}
+
+		expected := "secret/foo"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		// A response without its request is a problem, unless the request
+		// is in a previous file of the log
+		rotatedPath := filepath.Join(dir, "audit-rotated.log")
+		if err := ioutil.WriteFile(rotatedPath, []byte(strings.Join([]string{
+			`{"time":"2020-01-01T00:00:00Z","type":"request","request":{"id":"one"}}`,
+			`{"time":"2020-01-01T00:00:01Z","type":"request","request":{"id":"two"}}`,
+			`{"time":"2020-01-01T00:00:01Z","type":"response","request":{"id":"two"}}`,
+		}, "\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		nextPath := filepath.Join(dir, "audit-next.log")
+		if err := ioutil.WriteFile(nextPath, []byte(
+			`{"time":"2020-01-01T00:00:02Z","type":"response","request":{"id":"one"}}`,
+		), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd = testAuditVerifyCommand(t)
+		code = cmd.Run([]string{rotatedPath, nextPath})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d: %s", code, exp, ui.OutputWriter.String()+ui.ErrorWriter.String())
+		}
+
+		ui, cmd = testAuditVerifyCommand(t)
+		code = cmd.Run([]string{rotatedPath, nextPath, nextPath})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d: %s", code, exp, ui.OutputWriter.String()+ui.ErrorWriter.String())
+		}
+	})
+}
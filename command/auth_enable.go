@@ -27,6 +27,7 @@ type AuthEnableCommand struct {
 	flagAuditNonHMACResponseKeys  []string
 	flagListingVisibility         string
 	flagPluginName                string
+	flagPluginVersion             string
 	flagPassthroughRequestHeaders []string
 	flagAllowedResponseHeaders    []string
 	flagOptions                   map[string]string
@@ -154,6 +155,14 @@ func (c *AuthEnableCommand) Flags() *FlagSets {
 			"exist in the Vault server's plugin catalog.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "plugin-version",
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Version of the auth method plugin to run. This version of the " +
+			"plugin must already exist in Vault's plugin catalog.",
+	})
+
 	f.StringMapVar(&StringMapVar{
 		Name:       "options",
 		Target:     &c.flagOptions,
@@ -264,6 +273,7 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		Local:                 c.flagLocal,
 		SealWrap:              c.flagSealWrap,
 		ExternalEntropyAccess: c.flagExternalEntropyAccess,
+		PluginVersion:         c.flagPluginVersion,
 		Config: api.AuthConfigInput{
 			DefaultLeaseTTL: c.flagDefaultLeaseTTL.String(),
 			MaxLeaseTTL:     c.flagMaxLeaseTTL.String(),
@@ -18,14 +18,20 @@ var _ cli.CommandAutocomplete = (*AuthTuneCommand)(nil)
 type AuthTuneCommand struct {
 	*BaseCommand
 
+	flagAllowedEntityAliases     []string
 	flagAuditNonHMACRequestKeys  []string
 	flagAuditNonHMACResponseKeys []string
 	flagDefaultLeaseTTL          time.Duration
 	flagDescription              string
+	flagExternalGroupSync        bool
 	flagListingVisibility        string
 	flagMaxLeaseTTL              time.Duration
+	flagMinLeaseTTL              time.Duration
 	flagOptions                  map[string]string
 	flagTokenType                string
+	flagUserLockoutThreshold     uint64
+	flagUserLockoutDuration      time.Duration
+	flagUserLockoutCounterReset  time.Duration
 	flagVersion                  int
 }
 
@@ -105,6 +111,22 @@ func (c *AuthTuneCommand) Flags() *FlagSets {
 			"or a previously configured value for the auth method.",
 	})
 
+	f.DurationVar(&DurationVar{
+		Name:       flagNameMinLeaseTTL,
+		Target:     &c.flagMinLeaseTTL,
+		Completion: complete.PredictAnything,
+		Usage: "The minimum TTL of the tokens issued by this auth method. Tokens " +
+			"requested with a lower TTL get this TTL, within their maximum TTL.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   flagNameAllowedEntityAliases,
+		Target: &c.flagAllowedEntityAliases,
+		Usage: "Glob pattern of the entity alias names allowed to log in with " +
+			"this auth method. This can be specified multiple times. Defaults to " +
+			"every alias.",
+	})
+
 	f.StringMapVar(&StringMapVar{
 		Name:       "options",
 		Target:     &c.flagOptions,
@@ -119,6 +141,39 @@ func (c *AuthTuneCommand) Flags() *FlagSets {
 		Usage:  "Sets a forced token type for the mount.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    flagNameExternalGroupSync,
+		Target:  &c.flagExternalGroupSync,
+		Default: false,
+		Usage: "Create external groups for the group aliases returned at login " +
+			"that don't map to a group yet, and delete them once they have no " +
+			"members left.",
+	})
+
+	f.Uint64Var(&Uint64Var{
+		Name:   flagNameUserLockoutThreshold,
+		Target: &c.flagUserLockoutThreshold,
+		Usage: "The number of failed logins after which a user is locked out of " +
+			"the auth method. A value of 0 disables the user lockout. Only the " +
+			"approle, ldap and userpass auth methods support the user lockout.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       flagNameUserLockoutDuration,
+		Target:     &c.flagUserLockoutDuration,
+		Completion: complete.PredictAnything,
+		Usage: "How long a user stays locked out after the last failed login. " +
+			"Defaults to 15 minutes.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       flagNameUserLockoutCounterReset,
+		Target:     &c.flagUserLockoutCounterReset,
+		Completion: complete.PredictAnything,
+		Usage: "The time after the last failed login at which the count of the " +
+			"failed logins of a user restarts. Defaults to 15 minutes.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "version",
 		Target:  &c.flagVersion,
@@ -175,6 +230,7 @@ func (c *AuthTuneCommand) Run(args []string) int {
 	}
 
 	// Set these values only if they are provided in the CLI
+	var userLockoutConfig api.UserLockoutConfigInput
 	f.Visit(func(fl *flag.Flag) {
 		if fl.Name == flagNameAuditNonHMACRequestKeys {
 			mountConfigInput.AuditNonHMACRequestKeys = c.flagAuditNonHMACRequestKeys
@@ -195,6 +251,30 @@ func (c *AuthTuneCommand) Run(args []string) int {
 		if fl.Name == flagNameTokenType {
 			mountConfigInput.TokenType = c.flagTokenType
 		}
+
+		if fl.Name == flagNameExternalGroupSync {
+			mountConfigInput.ExternalGroupSync = &c.flagExternalGroupSync
+		}
+
+		if fl.Name == flagNameMinLeaseTTL {
+			mountConfigInput.MinLeaseTTL = c.flagMinLeaseTTL.String()
+		}
+
+		if fl.Name == flagNameAllowedEntityAliases {
+			mountConfigInput.AllowedEntityAliases = c.flagAllowedEntityAliases
+		}
+
+		switch fl.Name {
+		case flagNameUserLockoutThreshold:
+			userLockoutConfig.LockoutThreshold = strconv.FormatUint(c.flagUserLockoutThreshold, 10)
+			mountConfigInput.UserLockoutConfig = &userLockoutConfig
+		case flagNameUserLockoutDuration:
+			userLockoutConfig.LockoutDuration = c.flagUserLockoutDuration.String()
+			mountConfigInput.UserLockoutConfig = &userLockoutConfig
+		case flagNameUserLockoutCounterReset:
+			userLockoutConfig.LockoutCounterReset = c.flagUserLockoutCounterReset.String()
+			mountConfigInput.UserLockoutConfig = &userLockoutConfig
+		}
 	})
 
 	// Append /auth (since that's where auths live) and a trailing slash to
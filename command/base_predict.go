@@ -85,11 +85,15 @@ func (b *BaseCommand) PredictVaultAvailableMounts() complete.Predictor {
 		"consul",
 		"database",
 		"generic",
+		"kmip",
+		"kubernetes",
 		"pki",
 		"plugin",
 		"rabbitmq",
 		"ssh",
+		"terraform",
 		"totp",
+		"transform",
 		"transit",
 	)
 }
@@ -390,6 +390,7 @@ func TestPredict_Plugins(t *testing.T) {
 				"radius",
 				"redshift-database-plugin",
 				"ssh",
+				"terraform",
 				"totp",
 				"transform",
 				"transit",
@@ -409,14 +410,6 @@ func TestPredict_Plugins(t *testing.T) {
 
 				act := p.plugins()
 
-				if !strutil.StrListContains(act, "kmip") {
-					for i, v := range tc.exp {
-						if v == "kmip" {
-							tc.exp = append(tc.exp[:i], tc.exp[i+1:]...)
-							break
-						}
-					}
-				}
 				if !strutil.StrListContains(act, "transform") {
 					for i, v := range tc.exp {
 						if v == "transform" {
@@ -20,6 +20,7 @@ import (
 	_ "github.com/hashicorp/vault/helper/builtinplugins"
 
 	auditFile "github.com/hashicorp/vault/builtin/audit/file"
+	auditOTel "github.com/hashicorp/vault/builtin/audit/otel"
 	auditSocket "github.com/hashicorp/vault/builtin/audit/socket"
 	auditSyslog "github.com/hashicorp/vault/builtin/audit/syslog"
 
@@ -110,11 +111,30 @@ const (
 	flagNameAllowedResponseHeaders = "allowed-response-headers"
 	// flagNameTokenType is the flag name used to force a specific token type
 	flagNameTokenType = "token-type"
+	// flagNameExternalGroupSync is the flag name used to toggle the sync of
+	// external groups at login
+	flagNameExternalGroupSync = "external-group-sync"
+	// flagNameUserLockoutThreshold is the flag name used to set the number of
+	// failed logins after which the users of an auth mount are locked out
+	flagNameUserLockoutThreshold = "user-lockout-threshold"
+	// flagNameUserLockoutDuration is the flag name used to set how long the
+	// users of an auth mount stay locked out
+	flagNameUserLockoutDuration = "user-lockout-duration"
+	// flagNameUserLockoutCounterReset is the flag name used to set when the
+	// count of the failed logins of a user restarts
+	flagNameUserLockoutCounterReset = "user-lockout-counter-reset"
+	// flagNameMinLeaseTTL is the flag name used to set the floor of the TTL of
+	// the tokens of an auth mount
+	flagNameMinLeaseTTL = "min-lease-ttl"
+	// flagNameAllowedEntityAliases is the flag name used to restrict the
+	// aliases allowed to log in with an auth mount
+	flagNameAllowedEntityAliases = "allowed-entity-aliases"
 )
 
 var (
 	auditBackends = map[string]audit.Factory{
 		"file":   auditFile.Factory,
+		"otel":   auditOTel.Factory,
 		"socket": auditSocket.Factory,
 		"syslog": auditSyslog.Factory,
 	}
@@ -232,6 +252,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"audit verify": func() (cli.Command, error) {
+			return &AuditVerifyCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"auth tune": func() (cli.Command, error) {
 			return &AuthTuneCommand{
 				BaseCommand: getBaseCommand(),
@@ -274,6 +299,26 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"identity": func() (cli.Command, error) {
+			return &IdentityCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"identity entity": func() (cli.Command, error) {
+			return &IdentityEntityCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"identity entity duplicates": func() (cli.Command, error) {
+			return &IdentityEntityDuplicatesCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"identity entity merge": func() (cli.Command, error) {
+			return &IdentityEntityMergeCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"lease": func() (cli.Command, error) {
 			return &LeaseCommand{
 				BaseCommand: getBaseCommand(),
@@ -330,6 +375,12 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator diagnose": func() (cli.Command, error) {
+			return &OperatorDiagnoseCommand{
+				BaseCommand:      getBaseCommand(),
+				PhysicalBackends: physicalBackends,
+			}, nil
+		},
 		"operator generate-root": func() (cli.Command, error) {
 			return &OperatorGenerateRootCommand{
 				BaseCommand: getBaseCommand(),
@@ -402,6 +453,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator seal migrate": func() (cli.Command, error) {
+			return &OperatorSealMigrateCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"operator step-down": func() (cli.Command, error) {
 			return &OperatorStepDownCommand{
 				BaseCommand: getBaseCommand(),
@@ -452,6 +508,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"plugin upgrade": func() (cli.Command, error) {
+			return &PluginUpgradeCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"policy": func() (cli.Command, error) {
 			return &PolicyCommand{
 				BaseCommand: getBaseCommand(),
@@ -602,6 +663,12 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"exec": func() (cli.Command, error) {
+			return &ExecCommand{
+				BaseCommand: getBaseCommand(),
+				ShutdownCh:  MakeShutdownCh(),
+			}, nil
+		},
 		"kv": func() (cli.Command, error) {
 			return &KVCommand{
 				BaseCommand: getBaseCommand(),
@@ -0,0 +1,318 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*ExecCommand)(nil)
+var _ cli.CommandAutocomplete = (*ExecCommand)(nil)
+
+type ExecCommand struct {
+	*BaseCommand
+
+	flagEnv    []string
+	flagFile   []string
+	flagRevoke bool
+
+	// ShutdownCh is used to forward the interrupts to the child process
+	ShutdownCh chan struct{}
+}
+
+// execSecretRef is a reference to the field of a secret, given as
+// NAME=PATH#FIELD, which is injected into the child process
+type execSecretRef struct {
+	Name  string
+	Path  string
+	Field string
+}
+
+func (c *ExecCommand) Synopsis() string {
+	return "Run a process with secrets in its environment"
+}
+
+func (c *ExecCommand) Help() string {
+	helpText := `
+Usage: vault exec [options] -- COMMAND [ARGS...]
+
+  Reads secrets from Vault and runs the given command with their fields in its
+  environment, either as the value of an environment variable or as the
+  contents of a temporary file whose path is in an environment variable.
+
+  The secrets are referenced as NAME=PATH#FIELD. The secrets at the same path
+  are read only once, so the fields of a dynamic secret come from the same
+  lease. K/V version 2 paths are supported with the same paths as the "kv"
+  commands.
+
+  Once the command exits, the temporary files are removed, the leases of the
+  secrets are revoked, and the exit code of the command is returned.
+
+  Run a command with a database password in its environment:
+
+      $ vault exec -env=DB_PASSWORD=secret/app#password -- ./run-tests.sh
+
+  Run a command with dynamic database credentials:
+
+      $ vault exec \
+          -env=DB_USERNAME=database/creds/readonly#username \
+          -env=DB_PASSWORD=database/creds/readonly#password \
+          -- ./run-tests.sh
+
+  Run a command with a TLS key in a temporary file:
+
+      $ vault exec -file=TLS_KEY_FILE=secret/tls#key -- ./server
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ExecCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:       "env",
+		Target:     &c.flagEnv,
+		Completion: complete.PredictAnything,
+		Usage: "Environment variable to set to the field of a secret, given " +
+			"as NAME=PATH#FIELD. This can be specified multiple times.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:       "file",
+		Target:     &c.flagFile,
+		Completion: complete.PredictAnything,
+		Usage: "Environment variable to set to the path of a temporary file " +
+			"holding the field of a secret, given as NAME=PATH#FIELD. The " +
+			"file is removed when the command exits. This can be specified " +
+			"multiple times.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "revoke",
+		Target:  &c.flagRevoke,
+		Default: true,
+		Usage: "Revoke the leases of the secrets when the command exits. " +
+			"Set this to false for the leases to expire on their own.",
+	})
+
+	return set
+}
+
+func (c *ExecCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *ExecCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *ExecCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 1 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 1, got %d)", len(args)))
+		return 1
+	}
+
+	envRefs, err := parseExecSecretRefs(c.flagEnv)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	fileRefs, err := parseExecSecretRefs(c.flagFile)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	// The secrets read so far are revoked even if a later one fails
+	secrets := make(map[string]*api.Secret)
+	defer c.revokeLeases(client, secrets)
+
+	env := os.Environ()
+	for _, ref := range envRefs {
+		value, err := c.resolve(client, secrets, ref)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+		env = append(env, ref.Name+"="+value)
+	}
+
+	if len(fileRefs) > 0 {
+		dir, err := ioutil.TempDir("", "vault-exec")
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating the temporary directory: %s", err))
+			return 2
+		}
+		defer os.RemoveAll(dir)
+
+		for _, ref := range fileRefs {
+			value, err := c.resolve(client, secrets, ref)
+			if err != nil {
+				c.UI.Error(err.Error())
+				return 2
+			}
+			path := filepath.Join(dir, ref.Name)
+			if err := ioutil.WriteFile(path, []byte(value), 0600); err != nil {
+				c.UI.Error(fmt.Sprintf("Error writing the temporary file of %s: %s", ref.Name, err))
+				return 2
+			}
+			env = append(env, ref.Name+"="+path)
+		}
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error starting %s: %s", args[0], err))
+		return 2
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		select {
+		case <-c.ShutdownCh:
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				cmd.Process.Kill()
+			}
+		case <-doneCh:
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			return exitErr.ExitCode()
+		}
+		c.UI.Error(fmt.Sprintf("Error running %s: %s", args[0], err))
+		return 2
+	}
+	return 0
+}
+
+// resolve returns the value of the field of a secret, reading the secret if it
+// wasn't read already
+func (c *ExecCommand) resolve(client *api.Client, secrets map[string]*api.Secret, ref *execSecretRef) (string, error) {
+	secret, ok := secrets[ref.Path]
+	if !ok {
+		var err error
+		secret, err = c.readSecret(client, ref.Path)
+		if err != nil {
+			return "", err
+		}
+		secrets[ref.Path] = secret
+	}
+
+	value, ok := secret.Data[ref.Field]
+	if !ok || value == nil {
+		return "", fmt.Errorf("Field %q not present in the secret at %s", ref.Field, ref.Path)
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("Error encoding field %q of the secret at %s: %s", ref.Field, ref.Path, err)
+		}
+		return string(encoded), nil
+	}
+}
+
+func (c *ExecCommand) readSecret(client *api.Client, path string) (*api.Secret, error) {
+	path = sanitizePath(path)
+	mountPath, v2, err := isKVv2(path, client)
+	if err != nil {
+		return nil, err
+	}
+	if v2 {
+		path = addPrefixToVKVPath(path, mountPath, "data")
+	}
+
+	secret, err := kvReadRequest(client, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("No value found at %s", path)
+	}
+
+	// The data of K/V version 2 secrets is nested along with their metadata
+	if v2 {
+		data, ok := secret.Data["data"].(map[string]interface{})
+		if !ok || data == nil {
+			return nil, fmt.Errorf("No data found at %s", path)
+		}
+		secret.Data = data
+	}
+	return secret, nil
+}
+
+func (c *ExecCommand) revokeLeases(client *api.Client, secrets map[string]*api.Secret) {
+	if !c.flagRevoke {
+		return
+	}
+	for path, secret := range secrets {
+		if secret.LeaseID == "" {
+			continue
+		}
+		if err := client.Sys().Revoke(secret.LeaseID); err != nil {
+			c.UI.Error(fmt.Sprintf("Error revoking the lease of %s: %s", path, err))
+		}
+	}
+}
+
+// parseExecSecretRefs parses the secret references given as NAME=PATH#FIELD
+func parseExecSecretRefs(raw []string) ([]*execSecretRef, error) {
+	refs := make([]*execSecretRef, 0, len(raw))
+	for _, r := range raw {
+		ref := &execSecretRef{}
+		if i := strings.Index(r, "="); i > 0 {
+			ref.Name = r[:i]
+			ref.Path = r[i+1:]
+		}
+		if i := strings.LastIndex(ref.Path, "#"); i >= 0 {
+			ref.Field = ref.Path[i+1:]
+			ref.Path = ref.Path[:i]
+		}
+		if ref.Name == "" || ref.Path == "" || ref.Field == "" {
+			return nil, fmt.Errorf("Invalid secret reference %q, expected NAME=PATH#FIELD", r)
+		}
+		if strings.ContainsAny(ref.Name, `/\`) {
+			return nil, fmt.Errorf("Invalid environment variable name %q", ref.Name)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*IdentityCommand)(nil)
+
+type IdentityCommand struct {
+	*BaseCommand
+}
+
+func (c *IdentityCommand) Synopsis() string {
+	return "Interact with the identity store"
+}
+
+func (c *IdentityCommand) Help() string {
+	helpText := `
+Usage: vault identity <subcommand> [options] [args]
+
+  This command groups subcommands for interacting with the identity store of
+  Vault. The entities, groups and aliases can also be managed with the "read"
+  and "write" commands on the paths of the identity secrets engine.
+
+  List the entities that are likely to belong to the same person:
+
+      $ vault identity entity duplicates
+
+  Merge entities into another one:
+
+      $ vault identity entity merge TO_ENTITY_ID FROM_ENTITY_ID
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
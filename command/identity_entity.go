@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*IdentityEntityCommand)(nil)
+
+type IdentityEntityCommand struct {
+	*BaseCommand
+}
+
+func (c *IdentityEntityCommand) Synopsis() string {
+	return "Interact with identity entities"
+}
+
+func (c *IdentityEntityCommand) Help() string {
+	helpText := `
+Usage: vault identity entity <subcommand> [options] [args]
+
+  This command groups subcommands for interacting with the entities of the
+  identity store.
+
+  List the entities that have aliases of the same name on different auth
+  mounts:
+
+      $ vault identity entity duplicates
+
+  Preview the merge of entities:
+
+      $ vault identity entity merge -preview TO_ENTITY_ID FROM_ENTITY_ID
+
+  Merge entities and their policies:
+
+      $ vault identity entity merge -merge-policies TO_ENTITY_ID FROM_ENTITY_ID
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityEntityCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*IdentityEntityDuplicatesCommand)(nil)
+var _ cli.CommandAutocomplete = (*IdentityEntityDuplicatesCommand)(nil)
+
+type IdentityEntityDuplicatesCommand struct {
+	*BaseCommand
+}
+
+func (c *IdentityEntityDuplicatesCommand) Synopsis() string {
+	return "List the entities that are likely duplicates"
+}
+
+func (c *IdentityEntityDuplicatesCommand) Help() string {
+	helpText := `
+Usage: vault identity entity duplicates [options]
+
+  Lists the entities that have aliases of the same name on different auth
+  mounts, which usually means that the same user has logged in through more
+  than one auth method. For each alias name, the oldest entity is suggested as
+  the entity to merge the others into, with the "vault identity entity merge"
+  command.
+
+  List the duplicate entities:
+
+      $ vault identity entity duplicates
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityEntityDuplicatesCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+}
+
+func (c *IdentityEntityDuplicatesCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *IdentityEntityDuplicatesCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *IdentityEntityDuplicatesCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	secret, err := client.Logical().Read("identity/entity/duplicates")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing duplicate entities: %s", err))
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error("No duplicate entities found")
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputData(c.UI, secret.Data["duplicates"])
+	}
+
+	duplicates, _ := secret.Data["duplicates"].([]interface{})
+	if len(duplicates) == 0 {
+		c.UI.Output("No duplicate entities found")
+		return 0
+	}
+
+	out := []string{"Alias Name | To Entity ID | From Entity IDs"}
+	for _, raw := range duplicates {
+		duplicate, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var fromEntityIDs []string
+		if ids, ok := duplicate["from_entity_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				fromEntityIDs = append(fromEntityIDs, fmt.Sprintf("%v", id))
+			}
+		}
+		out = append(out, fmt.Sprintf("%v | %v | %s",
+			duplicate["alias_name"],
+			duplicate["to_entity_id"],
+			strings.Join(fromEntityIDs, ",")))
+	}
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
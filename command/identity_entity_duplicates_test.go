@@ -0,0 +1,150 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testIdentityEntityDuplicatesCommand(tb testing.TB) (*cli.MockUi, *IdentityEntityDuplicatesCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &IdentityEntityDuplicatesCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+// testDuplicateEntities creates two entities with aliases of the same name on
+// two userpass mounts, and returns the IDs of the older and of the newer one
+func testDuplicateEntities(tb testing.TB, client *api.Client) (string, string) {
+	tb.Helper()
+
+	var entityIDs []string
+	for _, mount := range []string{"userpass1", "userpass2"} {
+		if err := client.Sys().EnableAuthWithOptions(mount, &api.EnableAuthOptions{
+			Type: "userpass",
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		auths, err := client.Sys().ListAuth()
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		secret, err := client.Logical().Write("identity/entity", map[string]interface{}{
+			"name":     "jane-" + mount,
+			"policies": mount,
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		entityID := secret.Data["id"].(string)
+		if _, err := client.Logical().Write("identity/entity-alias", map[string]interface{}{
+			"name":           "jane",
+			"canonical_id":   entityID,
+			"mount_accessor": auths[mount+"/"].Accessor,
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		entityIDs = append(entityIDs, entityID)
+	}
+	return entityIDs[0], entityIDs[1]
+}
+
+func TestIdentityEntityDuplicatesCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("too_many_args", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testIdentityEntityDuplicatesCommand(t)
+		code := cmd.Run([]string{"foo"})
+		if exp := 1; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Too many arguments"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		ui, cmd := testIdentityEntityDuplicatesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run(nil)
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "No duplicate entities found"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		toEntityID, fromEntityID := testDuplicateEntities(t, client)
+
+		ui, cmd := testIdentityEntityDuplicatesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run(nil)
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		for _, expected := range []string{"Alias Name", "jane", toEntityID, fromEntityID} {
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testIdentityEntityDuplicatesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error listing duplicate entities: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testIdentityEntityDuplicatesCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
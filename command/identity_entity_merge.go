@@ -0,0 +1,133 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*IdentityEntityMergeCommand)(nil)
+var _ cli.CommandAutocomplete = (*IdentityEntityMergeCommand)(nil)
+
+type IdentityEntityMergeCommand struct {
+	*BaseCommand
+
+	flagPreview       bool
+	flagMergePolicies bool
+	flagForce         bool
+}
+
+func (c *IdentityEntityMergeCommand) Synopsis() string {
+	return "Merge entities into another entity"
+}
+
+func (c *IdentityEntityMergeCommand) Help() string {
+	helpText := `
+Usage: vault identity entity merge [options] TO_ENTITY_ID FROM_ENTITY_ID...
+
+  Merges the entities with the given IDs into the entity with ID TO_ENTITY_ID.
+  The aliases of the merged entities are moved to the remaining entity, and
+  the merged entities are deleted. The merge is recorded in the audit log.
+
+  Preview the entity that the merge would produce:
+
+      $ vault identity entity merge -preview \
+          f2cdefbe-f510-a226-77fa-989a48ba6abc \
+          1ade80ec-ba5c-8eed-91e2-b9dcd41d6fff
+
+  Merge the entities along with their policies:
+
+      $ vault identity entity merge -merge-policies \
+          f2cdefbe-f510-a226-77fa-989a48ba6abc \
+          1ade80ec-ba5c-8eed-91e2-b9dcd41d6fff
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityEntityMergeCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.BoolVar(&BoolVar{
+		Name:    "preview",
+		Target:  &c.flagPreview,
+		Default: false,
+		Usage: "Print the entity that the merge would produce, along with the " +
+			"conflicting MFA secrets, without modifying any of the entities.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "merge-policies",
+		Target:  &c.flagMergePolicies,
+		Default: false,
+		Usage: "Add the policies of the merged entities to the remaining " +
+			"entity. The resulting policies are deduplicated and sorted.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "force",
+		Target:  &c.flagForce,
+		Default: false,
+		Usage: "Keep the MFA secrets of the remaining entity when the merged " +
+			"entities have secrets for the same MFA methods, instead of " +
+			"failing.",
+	})
+
+	return set
+}
+
+func (c *IdentityEntityMergeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictAnything
+}
+
+func (c *IdentityEntityMergeCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *IdentityEntityMergeCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 2 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 2, got %d)", len(args)))
+		return 1
+	}
+	toEntityID := strings.TrimSpace(args[0])
+	fromEntityIDs := args[1:]
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	secret, err := client.Logical().Write("identity/entity/merge", map[string]interface{}{
+		"to_entity_id":    toEntityID,
+		"from_entity_ids": fromEntityIDs,
+		"merge_policies":  c.flagMergePolicies,
+		"force":           c.flagForce,
+		"preview":         c.flagPreview,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error merging entities into %s: %s", toEntityID, err))
+		return 2
+	}
+
+	if !c.flagPreview && Format(c.UI) == "table" {
+		c.UI.Info(fmt.Sprintf("Success! Merged %s into %s", strings.Join(fromEntityIDs, ", "), toEntityID))
+	}
+	if secret == nil {
+		return 0
+	}
+	return OutputSecret(c.UI, secret)
+}
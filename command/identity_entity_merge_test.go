@@ -0,0 +1,124 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func testIdentityEntityMergeCommand(tb testing.TB) (*cli.MockUi, *IdentityEntityMergeCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &IdentityEntityMergeCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestIdentityEntityMergeCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not_enough_args", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testIdentityEntityMergeCommand(t)
+		code := cmd.Run([]string{"foo"})
+		if exp := 1; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Not enough arguments"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("integration", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		toEntityID, fromEntityID := testDuplicateEntities(t, client)
+
+		// The preview doesn't modify the entities
+		ui, cmd := testIdentityEntityMergeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-preview", "-merge-policies", toEntityID, fromEntityID})
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, combined)
+		}
+		for _, expected := range []string{"merged_entity_ids", fromEntityID, "[userpass1 userpass2]"} {
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+		if strings.Contains(combined, "Success!") {
+			t.Errorf("expected %q not to contain a success message", combined)
+		}
+
+		secret, err := client.Logical().Read("identity/entity/id/" + fromEntityID)
+		if err != nil || secret == nil {
+			t.Fatalf("expected entity %s to still exist: %v", fromEntityID, err)
+		}
+
+		ui, cmd = testIdentityEntityMergeCommand(t)
+		cmd.client = client
+
+		code = cmd.Run([]string{"-merge-policies", toEntityID, fromEntityID})
+		combined = ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, combined)
+		}
+		expected := "Success! Merged " + fromEntityID + " into " + toEntityID
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		secret, err = client.Logical().Read("identity/entity/id/" + fromEntityID)
+		if err != nil || secret != nil {
+			t.Fatalf("expected entity %s to be merged: %v %#v", fromEntityID, err, secret)
+		}
+		secret, err = client.Logical().Read("identity/entity/id/" + toEntityID)
+		if err != nil || secret == nil {
+			t.Fatal(err)
+		}
+		if policies := secret.Data["policies"].([]interface{}); len(policies) != 2 {
+			t.Errorf("bad: policies %v", policies)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testIdentityEntityMergeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"foo", "bar"})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error merging entities into foo: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testIdentityEntityMergeCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
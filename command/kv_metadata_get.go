@@ -37,7 +37,7 @@ Usage: vault kv metadata get [options] KEY
 }
 
 func (c *KVMetadataGetCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	return set
 }
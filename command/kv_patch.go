@@ -3,9 +3,11 @@ package command
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strings"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 )
@@ -16,6 +18,8 @@ var _ cli.CommandAutocomplete = (*KVPatchCommand)(nil)
 type KVPatchCommand struct {
 	*BaseCommand
 
+	flagMethod string
+
 	testStdin io.Reader // for tests
 }
 
@@ -43,6 +47,13 @@ Usage: vault kv patch [options] KEY [DATA]
 
       $ echo "abcd1234" | vault kv patch secret/foo bar=-
 
+  By default the patch is applied by the server with a single HTTP PATCH
+  request. If the token is not allowed to patch the secret, or the server does
+  not support it, the command falls back to reading the secret and writing it
+  back with check-and-set. The method can be selected explicitly:
+
+      $ vault kv patch -method=rw secret/foo bar=baz
+
   Additional flags and more advanced use cases are detailed below.
 
 ` + c.Flags().Help()
@@ -52,6 +63,19 @@ Usage: vault kv patch [options] KEY [DATA]
 func (c *KVPatchCommand) Flags() *FlagSets {
 	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.StringVar(&StringVar{
+		Name:    "method",
+		Target:  &c.flagMethod,
+		Default: "",
+		Usage: `Specifies which method of patching to use. If set to "patch",
+		an HTTP PATCH request is sent. If set to "rw", the secret is read and
+		written back with check-and-set. If not set, "patch" is tried first
+		and "rw" is used if the patch is denied or not supported.`,
+	})
+
 	return set
 }
 
@@ -119,6 +143,50 @@ func (c *KVPatchCommand) Run(args []string) int {
 		return 2
 	}
 
+	var secret *api.Secret
+	var code int
+	switch c.flagMethod {
+	case "", "patch":
+		secret, err = client.Logical().JSONMergePatch(path, map[string]interface{}{
+			"data": newData,
+		})
+		respErr, ok := err.(*api.ResponseError)
+		switch {
+		case c.flagMethod == "" && ok && (respErr.StatusCode == http.StatusForbidden || respErr.StatusCode == http.StatusMethodNotAllowed):
+			// Fall back to a read and a check-and-set write
+			secret, code = c.readThenWrite(client, path, newData)
+		case err != nil:
+			c.UI.Error(fmt.Sprintf("Error writing data to %s: %s", path, err))
+			return 2
+		}
+	case "rw":
+		secret, code = c.readThenWrite(client, path, newData)
+	default:
+		c.UI.Error(fmt.Sprintf("Unsupported method provided to -method flag: %s", c.flagMethod))
+		return 2
+	}
+	if code != 0 {
+		return code
+	}
+
+	if secret == nil {
+		// Don't output anything unless using the "table" format
+		if Format(c.UI) == "table" {
+			c.UI.Info(fmt.Sprintf("Success! Data written to: %s", path))
+		}
+		return 0
+	}
+
+	if c.flagField != "" {
+		return PrintRawField(c.UI, secret, c.flagField)
+	}
+
+	return OutputSecret(c.UI, secret)
+}
+
+// readThenWrite applies the data to the secret at the path by reading it and
+// writing it back with check-and-set
+func (c *KVPatchCommand) readThenWrite(client *api.Client, path string, newData map[string]interface{}) (*api.Secret, int) {
 	// First, do a read.
 	// Note that we don't want to see curl output for the read request.
 	curOutputCurl := client.OutputCurlString()
@@ -127,45 +195,45 @@ func (c *KVPatchCommand) Run(args []string) int {
 	client.SetOutputCurlString(curOutputCurl)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error doing pre-read at %s: %s", path, err))
-		return 2
+		return nil, 2
 	}
 
 	// Make sure a value already exists
 	if secret == nil || secret.Data == nil {
 		c.UI.Error(fmt.Sprintf("No value found at %s", path))
-		return 2
+		return nil, 2
 	}
 
 	// Verify metadata found
 	rawMeta, ok := secret.Data["metadata"]
 	if !ok || rawMeta == nil {
 		c.UI.Error(fmt.Sprintf("No metadata found at %s; patch only works on existing data", path))
-		return 2
+		return nil, 2
 	}
 	meta, ok := rawMeta.(map[string]interface{})
 	if !ok {
 		c.UI.Error(fmt.Sprintf("Metadata found at %s is not the expected type (JSON object)", path))
-		return 2
+		return nil, 2
 	}
 	if meta == nil {
 		c.UI.Error(fmt.Sprintf("No metadata found at %s; patch only works on existing data", path))
-		return 2
+		return nil, 2
 	}
 
 	// Verify old data found
 	rawData, ok := secret.Data["data"]
 	if !ok || rawData == nil {
 		c.UI.Error(fmt.Sprintf("No data found at %s; patch only works on existing data", path))
-		return 2
+		return nil, 2
 	}
 	data, ok := rawData.(map[string]interface{})
 	if !ok {
 		c.UI.Error(fmt.Sprintf("Data found at %s is not the expected type (JSON object)", path))
-		return 2
+		return nil, 2
 	}
 	if data == nil {
 		c.UI.Error(fmt.Sprintf("No data found at %s; patch only works on existing data", path))
-		return 2
+		return nil, 2
 	}
 
 	// Copy new data over
@@ -181,19 +249,7 @@ func (c *KVPatchCommand) Run(args []string) int {
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error writing data to %s: %s", path, err))
-		return 2
+		return nil, 2
 	}
-	if secret == nil {
-		// Don't output anything unless using the "table" format
-		if Format(c.UI) == "table" {
-			c.UI.Info(fmt.Sprintf("Success! Data written to: %s", path))
-		}
-		return 0
-	}
-
-	if c.flagField != "" {
-		return PrintRawField(c.UI, secret, c.flagField)
-	}
-
-	return OutputSecret(c.UI, secret)
+	return secret, 0
 }
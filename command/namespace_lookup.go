@@ -37,7 +37,7 @@ Usage: vault namespace lookup [options] PATH
 }
 
 func (c *NamespaceLookupCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *NamespaceLookupCommand) AutocompleteArgs() complete.Predictor {
@@ -84,5 +84,9 @@ func (c *NamespaceLookupCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.flagField != "" {
+		return PrintRawField(c.UI, secret, c.flagField)
+	}
+
 	return OutputSecret(c.UI, secret)
 }
@@ -0,0 +1,547 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/version"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorDiagnoseCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorDiagnoseCommand)(nil)
+
+const (
+	diagnoseStatusSuccess = "success"
+	diagnoseStatusWarning = "warning"
+	diagnoseStatusFailure = "failure"
+	diagnoseStatusSkipped = "skipped"
+
+	// diagnoseStorageKey is the prefix of the keys written and deleted by the
+	// storage probe
+	diagnoseStorageKey = "diagnose/"
+
+	// diagnoseCertExpiryWarning is how long before the expiry of a TLS
+	// certificate a warning is raised
+	diagnoseCertExpiryWarning = 30 * 24 * time.Hour
+
+	// diagnoseTimeSkewThreshold is the clock difference with the Vault server
+	// above which a warning is raised
+	diagnoseTimeSkewThreshold = 5 * time.Second
+)
+
+type OperatorDiagnoseCommand struct {
+	*BaseCommand
+
+	PhysicalBackends map[string]physical.Factory
+
+	flagConfigs []string
+	flagBundle  string
+	flagTimeout time.Duration
+
+	logger log.Logger
+}
+
+// diagnoseResult is the outcome of one of the checks of the diagnose command
+type diagnoseResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// diagnoseBundle is the support bundle written by the diagnose command. It only
+// holds the sanitized configuration, without the storage and seal parameters,
+// so it can be shared safely.
+type diagnoseBundle struct {
+	Version     string                 `json:"version"`
+	GeneratedAt string                 `json:"generated_at"`
+	OS          string                 `json:"os"`
+	Arch        string                 `json:"arch"`
+	Config      map[string]interface{} `json:"config"`
+	Results     []*diagnoseResult      `json:"results"`
+}
+
+func (c *OperatorDiagnoseCommand) Synopsis() string {
+	return "Troubleshoots the configuration of a Vault server"
+}
+
+func (c *OperatorDiagnoseCommand) Help() string {
+	helpText := `
+Usage: vault operator diagnose [options]
+
+  This command checks the configuration of a Vault server and the services it
+  depends on, without starting the server. It parses the configuration files,
+  checks the permissions of the configuration and TLS key files, checks the
+  expiry of the TLS certificates of the listeners, probes the storage backend
+  and the seal, and compares the local clock with the clock of the Vault
+  server.
+
+  The storage probe writes, reads back and deletes a key under "diagnose/".
+  Raft storage is only checked for a writable data directory, since it is
+  locked by a running server.
+
+  Diagnose the configuration of a server:
+
+      $ vault operator diagnose -config=/etc/vault/config.hcl
+
+  Write a redacted support bundle with the results:
+
+      $ vault operator diagnose -config=/etc/vault/config.hcl -bundle=diagnose.json
+
+  The command exits with code 2 if any check fails.
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorDiagnoseCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "config",
+		Target: &c.flagConfigs,
+		Completion: complete.PredictOr(
+			complete.PredictFiles("*.hcl"),
+			complete.PredictFiles("*.json"),
+			complete.PredictDirs("*"),
+		),
+		Usage: "Path to a configuration file or directory of configuration " +
+			"files of the Vault server. This flag can be specified multiple " +
+			"times to load multiple configurations.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "bundle",
+		Target:     &c.flagBundle,
+		Completion: complete.PredictFiles("*.json"),
+		Usage: "Path of a support bundle to write. The bundle holds the " +
+			"results of the checks and the configuration, with the storage " +
+			"and seal parameters and the other sensitive values removed.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "timeout",
+		Target:     &c.flagTimeout,
+		Default:    30 * time.Second,
+		Completion: complete.PredictAnything,
+		Usage:      "Timeout of the storage, seal and time skew checks.",
+	})
+
+	return set
+}
+
+func (c *OperatorDiagnoseCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorDiagnoseCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorDiagnoseCommand) Run(args []string) int {
+	c.logger = logging.NewVaultLogger(log.Error)
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.flagConfigs) == 0 {
+		c.UI.Error("Must specify at least one config path using -config")
+		return 1
+	}
+
+	var results []*diagnoseResult
+	config, result := c.parseConfig()
+	results = append(results, result)
+	if config != nil {
+		results = append(results, c.checkFilePermissions(config)...)
+		results = append(results, c.checkTLS(config)...)
+		results = append(results, c.checkStorage("storage", config.Storage))
+		if config.HAStorage != nil {
+			results = append(results, c.checkStorage("ha_storage", config.HAStorage))
+		}
+		results = append(results, c.checkSeals(config)...)
+		results = append(results, c.checkTimeSkew(config))
+	}
+
+	if c.flagBundle != "" {
+		if err := c.writeBundle(config, results); err != nil {
+			c.UI.Error(fmt.Sprintf("Error writing the support bundle: %s", err))
+			return 1
+		}
+	}
+
+	if Format(c.UI) == "table" {
+		for _, result := range results {
+			c.UI.Output(fmt.Sprintf("[ %s ] %s: %s", result.Status, result.Name, result.Message))
+		}
+	} else if code := OutputData(c.UI, results); code != 0 {
+		return code
+	}
+
+	for _, result := range results {
+		if result.Status == diagnoseStatusFailure {
+			return 2
+		}
+	}
+	return 0
+}
+
+func (c *OperatorDiagnoseCommand) parseConfig() (*server.Config, *diagnoseResult) {
+	result := &diagnoseResult{Name: "config"}
+
+	var config *server.Config
+	for _, path := range c.flagConfigs {
+		current, err := server.LoadConfig(path)
+		if err != nil {
+			result.Status = diagnoseStatusFailure
+			result.Message = fmt.Sprintf("error loading configuration from %s: %s", path, err)
+			return nil, result
+		}
+
+		if config == nil {
+			config = current
+		} else {
+			config = config.Merge(current)
+		}
+	}
+
+	switch {
+	case config.Storage == nil:
+		result.Status = diagnoseStatusFailure
+		result.Message = "no storage backend configured"
+		return nil, result
+	case len(config.Listeners) == 0:
+		result.Status = diagnoseStatusFailure
+		result.Message = "no listener configured"
+		return nil, result
+	}
+
+	result.Status = diagnoseStatusSuccess
+	result.Message = fmt.Sprintf("loaded %d configuration path(s)", len(c.flagConfigs))
+	return config, result
+}
+
+// checkFilePermissions checks that the configuration files aren't writable by
+// everyone, and that the TLS keys are only accessible by their owner and group
+func (c *OperatorDiagnoseCommand) checkFilePermissions(config *server.Config) []*diagnoseResult {
+	if runtime.GOOS == "windows" {
+		return []*diagnoseResult{{
+			Name:    "file_permissions",
+			Status:  diagnoseStatusSkipped,
+			Message: "file permissions are not checked on Windows",
+		}}
+	}
+
+	var results []*diagnoseResult
+	check := func(path string, mask os.FileMode, problem string) {
+		result := &diagnoseResult{Name: "file_permissions", Status: diagnoseStatusSuccess}
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			result.Status = diagnoseStatusFailure
+			result.Message = err.Error()
+		case info.Mode().Perm()&mask != 0:
+			result.Status = diagnoseStatusWarning
+			result.Message = fmt.Sprintf("%s has mode %s and is %s", path, info.Mode().Perm(), problem)
+		default:
+			result.Message = fmt.Sprintf("%s has mode %s", path, info.Mode().Perm())
+		}
+		results = append(results, result)
+	}
+
+	for _, path := range c.flagConfigs {
+		check(path, 0002, "writable by everyone")
+	}
+	for _, ln := range config.Listeners {
+		if ln.Type == "tcp" && !ln.TLSDisable && ln.TLSKeyFile != "" {
+			check(ln.TLSKeyFile, 0007, "accessible by everyone")
+		}
+	}
+	return results
+}
+
+// checkTLS checks that the TLS certificates of the listeners match their keys
+// and are valid
+func (c *OperatorDiagnoseCommand) checkTLS(config *server.Config) []*diagnoseResult {
+	var results []*diagnoseResult
+	now := time.Now()
+	for _, ln := range config.Listeners {
+		if ln.Type != "tcp" || ln.TLSDisable {
+			continue
+		}
+		result := &diagnoseResult{
+			Name:   fmt.Sprintf("tls (%s)", ln.Address),
+			Status: diagnoseStatusSuccess,
+		}
+		results = append(results, result)
+
+		keyPair, err := tls.LoadX509KeyPair(ln.TLSCertFile, ln.TLSKeyFile)
+		if err != nil {
+			result.Status = diagnoseStatusFailure
+			result.Message = fmt.Sprintf("error loading the certificate and key: %s", err)
+			continue
+		}
+
+		for i, raw := range keyPair.Certificate {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				result.Status = diagnoseStatusFailure
+				result.Message = fmt.Sprintf("error parsing certificate %d of %s: %s", i, ln.TLSCertFile, err)
+				break
+			}
+			switch {
+			case now.Before(cert.NotBefore):
+				result.Status = diagnoseStatusFailure
+				result.Message = fmt.Sprintf("certificate %q is not valid before %s", cert.Subject, cert.NotBefore.Format(time.RFC3339))
+			case now.After(cert.NotAfter):
+				result.Status = diagnoseStatusFailure
+				result.Message = fmt.Sprintf("certificate %q expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+			case now.Add(diagnoseCertExpiryWarning).After(cert.NotAfter):
+				result.Status = diagnoseStatusWarning
+				result.Message = fmt.Sprintf("certificate %q expires on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+			default:
+				continue
+			}
+			break
+		}
+		if result.Status == diagnoseStatusSuccess {
+			result.Message = fmt.Sprintf("%d certificate(s) valid", len(keyPair.Certificate))
+		}
+	}
+	return results
+}
+
+// checkStorage writes, reads back and deletes a key in the storage backend.
+// Raft storage is locked by a running server, so only its data directory is
+// checked.
+func (c *OperatorDiagnoseCommand) checkStorage(name string, storage *server.Storage) *diagnoseResult {
+	result := &diagnoseResult{
+		Name:   fmt.Sprintf("%s (%s)", name, storage.Type),
+		Status: diagnoseStatusSuccess,
+	}
+
+	var err error
+	if storage.Type == storageTypeRaft {
+		err = checkDirWritable(storage.Config["path"])
+		result.Message = "data directory is writable"
+	} else {
+		err = c.withTimeout(func(ctx context.Context) error {
+			return c.probeStorage(ctx, storage)
+		})
+		result.Message = "write, read and delete succeeded"
+	}
+	if err != nil {
+		result.Status = diagnoseStatusFailure
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func (c *OperatorDiagnoseCommand) probeStorage(ctx context.Context, storage *server.Storage) error {
+	factory, ok := c.PhysicalBackends[storage.Type]
+	if !ok {
+		return fmt.Errorf("unknown storage type %s", storage.Type)
+	}
+	backend, err := factory(storage.Config, c.logger)
+	if err != nil {
+		return errwrap.Wrapf("error initializing the storage backend: {{err}}", err)
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	entry := &physical.Entry{
+		Key:   diagnoseStorageKey + id,
+		Value: []byte(id),
+	}
+	if err := backend.Put(ctx, entry); err != nil {
+		return errwrap.Wrapf("error writing to the storage backend: {{err}}", err)
+	}
+	defer backend.Delete(ctx, entry.Key)
+
+	read, err := backend.Get(ctx, entry.Key)
+	if err != nil {
+		return errwrap.Wrapf("error reading from the storage backend: {{err}}", err)
+	}
+	if read == nil || !bytes.Equal(read.Value, entry.Value) {
+		return errors.New("the value read from the storage backend doesn't match the value written")
+	}
+	if err := backend.Delete(ctx, entry.Key); err != nil {
+		return errwrap.Wrapf("error deleting from the storage backend: {{err}}", err)
+	}
+	return nil
+}
+
+func checkDirWritable(path string) error {
+	if path == "" {
+		if path = os.Getenv("VAULT_RAFT_PATH"); path == "" {
+			return errors.New("'path' must be set")
+		}
+	}
+	file, err := ioutil.TempFile(path, ".diagnose")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// checkSeals encrypts and decrypts a value with each of the auto seals, to
+// check their configuration and connectivity
+func (c *OperatorDiagnoseCommand) checkSeals(config *server.Config) []*diagnoseResult {
+	if len(config.Seals) == 0 {
+		return []*diagnoseResult{{
+			Name:    "seal (shamir)",
+			Status:  diagnoseStatusSuccess,
+			Message: "shamir seal, nothing to check",
+		}}
+	}
+
+	var results []*diagnoseResult
+	for _, configSeal := range config.Seals {
+		result := &diagnoseResult{
+			Name:   fmt.Sprintf("seal (%s)", configSeal.Type),
+			Status: diagnoseStatusSuccess,
+		}
+		results = append(results, result)
+
+		wrapper, err := configureSealWrapper(configSeal, nil, nil, c.logger)
+		if err != nil {
+			result.Status = diagnoseStatusFailure
+			result.Message = fmt.Sprintf("error parsing the seal configuration: %s", err)
+			continue
+		}
+		if wrapper == nil {
+			result.Message = "shamir seal, nothing to check"
+			continue
+		}
+
+		err = c.withTimeout(func(ctx context.Context) error {
+			if err := wrapper.Init(ctx); err != nil {
+				return err
+			}
+			defer wrapper.Finalize(context.Background())
+
+			blob, err := wrapper.Encrypt(ctx, []byte(diagnoseStorageKey), nil)
+			if err != nil {
+				return errwrap.Wrapf("error encrypting with the seal: {{err}}", err)
+			}
+			plaintext, err := wrapper.Decrypt(ctx, blob, nil)
+			if err != nil {
+				return errwrap.Wrapf("error decrypting with the seal: {{err}}", err)
+			}
+			if string(plaintext) != diagnoseStorageKey {
+				return errors.New("the value decrypted by the seal doesn't match the value encrypted")
+			}
+			return nil
+		})
+		if err != nil {
+			result.Status = diagnoseStatusFailure
+			result.Message = err.Error()
+			continue
+		}
+		result.Message = "encrypt and decrypt succeeded"
+	}
+	return results
+}
+
+// checkTimeSkew compares the local clock with the clock of the Vault server at
+// the api_addr of the configuration, or at the address of the client
+func (c *OperatorDiagnoseCommand) checkTimeSkew(config *server.Config) *diagnoseResult {
+	result := &diagnoseResult{Name: "time_skew", Status: diagnoseStatusSuccess}
+
+	client, err := c.Client()
+	if err != nil {
+		result.Status = diagnoseStatusSkipped
+		result.Message = fmt.Sprintf("error creating the client: %s", err)
+		return result
+	}
+	if config.APIAddr != "" {
+		if err := client.SetAddress(config.APIAddr); err != nil {
+			result.Status = diagnoseStatusSkipped
+			result.Message = fmt.Sprintf("invalid api_addr: %s", err)
+			return result
+		}
+	}
+	client.SetClientTimeout(c.flagTimeout)
+
+	health, err := client.Sys().Health()
+	if err != nil {
+		result.Status = diagnoseStatusSkipped
+		result.Message = fmt.Sprintf("Vault server at %s is unreachable: %s", client.Address(), err)
+		return result
+	}
+
+	skew := time.Since(time.Unix(health.ServerTimeUTC, 0)).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	result.Message = fmt.Sprintf("clock is %s apart from the Vault server at %s", skew, client.Address())
+	if skew > diagnoseTimeSkewThreshold {
+		result.Status = diagnoseStatusWarning
+	}
+	return result
+}
+
+// withTimeout runs the given check, and fails it if it doesn't return before
+// the timeout, since some backends connect to their service without a context
+func (c *OperatorDiagnoseCommand) withTimeout(f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.flagTimeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", c.flagTimeout)
+	}
+}
+
+func (c *OperatorDiagnoseCommand) writeBundle(config *server.Config, results []*diagnoseResult) error {
+	bundle := &diagnoseBundle{
+		Version:     version.GetVersion().FullVersionNumber(false),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Results:     results,
+	}
+	if config != nil {
+		bundle.Config = config.Sanitized()
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Clean(c.flagBundle), data, 0600)
+}
@@ -0,0 +1,208 @@
+package command
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorDiagnoseCommand(tb testing.TB) (*cli.MockUi, *OperatorDiagnoseCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorDiagnoseCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		PhysicalBackends: physicalBackends,
+	}
+}
+
+// testDiagnoseCert writes a self-signed certificate valid until the given time,
+// and its key, to the given directory
+func testDiagnoseCert(t *testing.T, dir string, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func testDiagnoseConfig(t *testing.T, dir, listener string) string {
+	t.Helper()
+
+	config := fmt.Sprintf(`
+storage "file" {
+  path = %q
+}
+
+listener "tcp" {
+  address = "127.0.0.1:8200"
+  %s
+}
+`, filepath.Join(dir, "data"), listener)
+
+	path := filepath.Join(dir, "config.hcl")
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOperatorDiagnoseCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no_config", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testOperatorDiagnoseCommand(t)
+		code := cmd.Run(nil)
+		if code != 1 {
+			t.Errorf("expected %d to be %d", code, 1)
+		}
+		expected := "Must specify at least one config path"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("bad_config", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testOperatorDiagnoseCommand(t)
+		code := cmd.Run([]string{"-config", filepath.Join(os.TempDir(), "nonexistent.hcl")})
+		if code != 2 {
+			t.Errorf("expected %d to be %d", code, 2)
+		}
+		expected := "[ failure ] config: error loading configuration"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "diagnose")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		certFile, keyFile := testDiagnoseCert(t, dir, time.Now().Add(10*24*time.Hour))
+		config := testDiagnoseConfig(t, dir, fmt.Sprintf("tls_cert_file = %q\n  tls_key_file = %q", certFile, keyFile))
+		bundle := filepath.Join(dir, "bundle.json")
+
+		ui, cmd := testOperatorDiagnoseCommand(t)
+		cmd.client = client
+		code := cmd.Run([]string{"-config", config, "-bundle", bundle})
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if code != 0 {
+			t.Fatalf("expected %d to be %d: %s", code, 0, combined)
+		}
+		for _, expected := range []string{
+			"[ success ] config:",
+			"[ success ] file_permissions: " + config,
+			"[ warning ] tls (127.0.0.1:8200): certificate \"CN=localhost\" expires on",
+			"[ success ] storage (file): write, read and delete succeeded",
+			"[ success ] seal (shamir):",
+			"[ success ] time_skew:",
+		} {
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+
+		raw, err := ioutil.ReadFile(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result diagnoseBundle
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Results) != 7 {
+			t.Errorf("bad: results %#v", result.Results)
+		}
+		if strings.Contains(string(raw), filepath.Join(dir, "data")) {
+			t.Errorf("expected the storage path to be redacted from the bundle: %s", raw)
+		}
+	})
+
+	t.Run("expired_cert", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "diagnose")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		certFile, keyFile := testDiagnoseCert(t, dir, time.Now().Add(-time.Hour))
+		config := testDiagnoseConfig(t, dir, fmt.Sprintf("tls_cert_file = %q\n  tls_key_file = %q", certFile, keyFile))
+
+		ui, cmd := testOperatorDiagnoseCommand(t)
+		code := cmd.Run([]string{"-config", config, "-address", "http://127.0.0.1:1"})
+		if code != 2 {
+			t.Errorf("expected %d to be %d", code, 2)
+		}
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		for _, expected := range []string{
+			"[ failure ] tls (127.0.0.1:8200): certificate \"CN=localhost\" expired on",
+			"[ skipped ] time_skew:",
+		} {
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testOperatorDiagnoseCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorSealMigrateCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorSealMigrateCommand)(nil)
+
+type OperatorSealMigrateCommand struct {
+	*BaseCommand
+
+	flagKey   string
+	flagReset bool
+
+	testStdin io.Reader // For tests
+}
+
+func (c *OperatorSealMigrateCommand) Synopsis() string {
+	return "Migrates the Vault keys to a new seal while unsealed"
+}
+
+func (c *OperatorSealMigrateCommand) Help() string {
+	helpText := `
+Usage: vault operator seal migrate [options] [TYPE [K=V...]]
+
+  Migrates the master key and the recovery key to a new seal while Vault is
+  unsealed, so that the cluster keeps serving requests. The new seal is
+  configured with the same key-value pairs as its seal stanza.
+
+  Migrate from one auto seal to another:
+
+      $ vault operator seal migrate awskms region=us-east-1 kms_key_id=19ec80b0
+
+  Migrating from a Shamir seal requires a threshold of unseal key shares,
+  which become the recovery key shares. Provide them one at a time:
+
+      $ vault operator seal migrate -key=<share> awskms region=us-east-1 kms_key_id=19ec80b0
+      $ vault operator seal migrate -key=<share>
+
+  Once the migration is complete, update the seal stanza of each node's
+  configuration before restarting it.
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSealMigrateCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "key",
+		Target:     &c.flagKey,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage:      "Unseal key share to provide when migrating from a Shamir seal.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "reset",
+		Target:  &c.flagReset,
+		Default: false,
+		Usage:   "Cancel the migration in progress.",
+	})
+
+	return set
+}
+
+func (c *OperatorSealMigrateCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictAnything
+}
+
+func (c *OperatorSealMigrateCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorSealMigrateCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	data := map[string]interface{}{
+		"key":   c.flagKey,
+		"reset": c.flagReset,
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		// Pull our fake stdin if needed
+		stdin := (io.Reader)(os.Stdin)
+		if c.testStdin != nil {
+			stdin = c.testStdin
+		}
+
+		config, err := parseArgsDataString(stdin, args[1:])
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to parse K=V data: %s", err))
+			return 1
+		}
+		data["type"] = strings.TrimSpace(args[0])
+		data["config"] = config
+	} else if c.flagKey == "" && !c.flagReset {
+		c.UI.Error("Missing seal type or unseal key share")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	secret, err := client.Logical().Write("sys/seal/migrate", data)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error migrating seal: %s", err))
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	switch {
+	case secret == nil:
+		c.UI.Error("Error migrating seal: no response from server")
+		return 2
+	case secret.Data["complete"] == true:
+		c.UI.Output(fmt.Sprintf("Success! Vault keys are wrapped by the %v seal. Update the "+
+			"seal stanza of each node's configuration before restarting it.", secret.Data["type"]))
+	case c.flagReset:
+		c.UI.Output("Success! Seal migration canceled.")
+	default:
+		c.UI.Output(fmt.Sprintf("Seal migration to %v in progress: %v/%v unseal key shares provided.",
+			secret.Data["type"], secret.Data["progress"], secret.Data["required"]))
+	}
+
+	return 0
+}
@@ -15,6 +15,8 @@ var _ cli.CommandAutocomplete = (*PluginDeregisterCommand)(nil)
 
 type PluginDeregisterCommand struct {
 	*BaseCommand
+
+	flagVersion string
 }
 
 func (c *PluginDeregisterCommand) Synopsis() string {
@@ -39,7 +41,18 @@ Usage: vault plugin deregister [options] TYPE NAME
 }
 
 func (c *PluginDeregisterCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage:      "Semantic version of the plugin to deregister. If unspecified, the unversioned plugin is deregistered.",
+	})
+
+	return set
 }
 
 func (c *PluginDeregisterCommand) AutocompleteArgs() complete.Predictor {
@@ -91,8 +104,9 @@ func (c *PluginDeregisterCommand) Run(args []string) int {
 	pluginName := strings.TrimSpace(pluginNameRaw)
 
 	if err := client.Sys().DeregisterPlugin(&api.DeregisterPluginInput{
-		Name: pluginName,
-		Type: pluginType,
+		Name:    pluginName,
+		Type:    pluginType,
+		Version: c.flagVersion,
 	}); err != nil {
 		c.UI.Error(fmt.Sprintf("Error deregistering plugin named %s: %s", pluginName, err))
 		return 2
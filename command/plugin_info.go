@@ -15,6 +15,8 @@ var _ cli.CommandAutocomplete = (*PluginInfoCommand)(nil)
 
 type PluginInfoCommand struct {
 	*BaseCommand
+
+	flagVersion string
 }
 
 func (c *PluginInfoCommand) Synopsis() string {
@@ -39,7 +41,18 @@ Usage: vault plugin info [options] TYPE NAME
 }
 
 func (c *PluginInfoCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage:      "Semantic version of the plugin to read. If unspecified, the unversioned plugin is read.",
+	})
+
+	return set
 }
 
 func (c *PluginInfoCommand) AutocompleteArgs() complete.Predictor {
@@ -91,8 +104,9 @@ func (c *PluginInfoCommand) Run(args []string) int {
 	pluginName := strings.TrimSpace(pluginNameRaw)
 
 	resp, err := client.Sys().GetPlugin(&api.GetPluginInput{
-		Name: pluginName,
-		Type: pluginType,
+		Name:    pluginName,
+		Type:    pluginType,
+		Version: c.flagVersion,
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error reading plugin named %s: %s", pluginName, err))
@@ -105,11 +119,13 @@ func (c *PluginInfoCommand) Run(args []string) int {
 	}
 
 	data := map[string]interface{}{
-		"args":    resp.Args,
-		"builtin": resp.Builtin,
-		"command": resp.Command,
-		"name":    resp.Name,
-		"sha256":  resp.SHA256,
+		"args":     resp.Args,
+		"builtin":  resp.Builtin,
+		"command":  resp.Command,
+		"name":     resp.Name,
+		"sha256":   resp.SHA256,
+		"version":  resp.Version,
+		"versions": resp.Versions,
 	}
 
 	if c.flagField != "" {
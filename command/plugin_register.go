@@ -19,6 +19,7 @@ type PluginRegisterCommand struct {
 	flagArgs    []string
 	flagCommand string
 	flagSHA256  string
+	flagVersion string
 }
 
 func (c *PluginRegisterCommand) Synopsis() string {
@@ -44,6 +45,11 @@ Usage: vault plugin register [options] TYPE NAME
           -args=--with-glibc,--with-cgo \
           auth my-custom-plugin
 
+  Register a version of a plugin, alongside its other versions:
+
+      $ vault plugin register -sha256=d3f0a8b... -version=v1.0.0 \
+          auth my-custom-plugin
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -77,6 +83,14 @@ func (c *PluginRegisterCommand) Flags() *FlagSets {
 		Usage:      "SHA256 of the plugin binary. This is required for all plugins.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version of the plugin. Versioned plugins are registered " +
+			"alongside the other versions of the plugin.",
+	})
+
 	return set
 }
 
@@ -142,6 +156,7 @@ func (c *PluginRegisterCommand) Run(args []string) int {
 		Args:    c.flagArgs,
 		Command: command,
 		SHA256:  c.flagSHA256,
+		Version: c.flagVersion,
 	}); err != nil {
 		c.UI.Error(fmt.Sprintf("Error registering plugin %s: %s", pluginName, err))
 		return 2
@@ -33,7 +33,7 @@ Usage: vault plugin reload-status RELOAD_ID
 }
 
 func (c *PluginReloadStatusCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 }
 
 func (c *PluginReloadStatusCommand) AutocompleteArgs() complete.Predictor {
@@ -78,14 +78,20 @@ func (c *PluginReloadStatusCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error retrieving plugin reload status: %s", err))
 		return 2
 	}
-	out := []string{"Time | Participant | Success | Message "}
-	for i, s := range r.Results {
-		out = append(out, fmt.Sprintf("%s | %s | %t | %s ",
-			s.Timestamp.Format("15:04:05"),
-			i,
-			s.Error == "",
-			s.Error))
+
+	switch Format(c.UI) {
+	case "table":
+		out := []string{"Time | Participant | Success | Message "}
+		for i, s := range r.Results {
+			out = append(out, fmt.Sprintf("%s | %s | %t | %s ",
+				s.Timestamp.Format("15:04:05"),
+				i,
+				s.Error == "",
+				s.Error))
+		}
+		c.UI.Output(tableOutput(out, nil))
+		return 0
+	default:
+		return OutputData(c.UI, r)
 	}
-	c.UI.Output(tableOutput(out, nil))
-	return 0
 }
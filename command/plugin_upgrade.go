@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*PluginUpgradeCommand)(nil)
+var _ cli.CommandAutocomplete = (*PluginUpgradeCommand)(nil)
+
+type PluginUpgradeCommand struct {
+	*BaseCommand
+
+	flagVersion string
+	flagMounts  []string
+}
+
+func (c *PluginUpgradeCommand) Synopsis() string {
+	return "Upgrade mounted plugin backends to another plugin version"
+}
+
+func (c *PluginUpgradeCommand) Help() string {
+	helpText := `
+Usage: vault plugin upgrade [options] NAME
+
+  Upgrades the mounted backends of a plugin to another version of the plugin,
+  as registered in the plugin catalog. The mounts are pinned to the version
+  and reloaded. If any of the mounts fails to reload, all of them are rolled
+  back to their previous version.
+
+  Upgrade all the mounts of the plugin named "my-custom-plugin" to v1.1.0:
+
+      $ vault plugin upgrade -version=v1.1.0 my-custom-plugin
+
+  Upgrade only the mount at "my-secrets":
+
+      $ vault plugin upgrade -version=v1.1.0 -mounts=my-secrets my-custom-plugin
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginUpgradeCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage:      "Version of the plugin to upgrade the mounts to.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:       "mounts",
+		Target:     &c.flagMounts,
+		Completion: complete.PredictAnything,
+		Usage: "Array or comma-separated string mount paths of the plugin " +
+			"backends to upgrade. All the mounts of the plugin are upgraded " +
+			"if unset.",
+	})
+
+	return set
+}
+
+func (c *PluginUpgradeCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictVaultPlugins()
+}
+
+func (c *PluginUpgradeCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *PluginUpgradeCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	case c.flagVersion == "":
+		c.UI.Error("A plugin version is required, set it with -version")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	pluginName := strings.TrimSpace(args[0])
+
+	mounts, err := client.Sys().UpgradePlugin(&api.UpgradePluginInput{
+		Plugin:  pluginName,
+		Version: c.flagVersion,
+		Mounts:  c.flagMounts,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error upgrading plugin %s: %s", pluginName, err))
+		return 2
+	}
+
+	c.UI.Output(fmt.Sprintf("Success! Upgraded plugin %s to %s on mounts: %s", pluginName, c.flagVersion, strings.Join(mounts, ", ")))
+	return 0
+}
@@ -0,0 +1,126 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/mitchellh/cli"
+)
+
+func testPluginUpgradeCommand(tb testing.TB) (*cli.MockUi, *PluginUpgradeCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &PluginUpgradeCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestPluginUpgradeCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			[]string{"-version", "v1.0.0"},
+			"Not enough arguments",
+			1,
+		},
+		{
+			"too_many_args",
+			[]string{"-version", "v1.0.0", "foo", "bar"},
+			"Too many arguments",
+			1,
+		},
+		{
+			"missing_version",
+			[]string{"foo"},
+			"A plugin version is required",
+			1,
+		},
+		{
+			"no_mounts",
+			[]string{"-version", "v1.0.0", "foo"},
+			"no mounts of plugin",
+			2,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, closer := testVaultServer(t)
+			defer closer()
+
+			ui, cmd := testPluginUpgradeCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("other_plugin_mount", func(t *testing.T) {
+		t.Parallel()
+
+		pluginDir, cleanup := testPluginDir(t)
+		defer cleanup(t)
+
+		client, _, closer := testVaultServerPluginDir(t, pluginDir)
+		defer closer()
+
+		pluginName := "my-plugin"
+		_, sha256Sum := testPluginCreate(t, pluginDir, pluginName)
+		if err := client.Sys().RegisterPlugin(&api.RegisterPluginInput{
+			Name:    pluginName,
+			Type:    consts.PluginTypeCredential,
+			Command: pluginName,
+			SHA256:  sha256Sum,
+			Version: "v1.0.0",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Sys().EnableAuthWithOptions("my-plugin", &api.EnableAuthOptions{
+			Type: "approle",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testPluginUpgradeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-version", "v1.0.0",
+			"-mounts", "auth/my-plugin",
+			pluginName,
+		})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := `doesn't run plugin "my-plugin"`
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
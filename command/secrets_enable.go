@@ -30,6 +30,7 @@ type SecretsEnableCommand struct {
 	flagAllowedResponseHeaders    []string
 	flagForceNoCache              bool
 	flagPluginName                string
+	flagPluginVersion             string
 	flagOptions                   map[string]string
 	flagLocal                     bool
 	flagSealWrap                  bool
@@ -170,6 +171,14 @@ func (c *SecretsEnableCommand) Flags() *FlagSets {
 			"exist in Vault's plugin catalog.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "plugin-version",
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Version of the secrets engine plugin to run. This version of the " +
+			"plugin must already exist in Vault's plugin catalog.",
+	})
+
 	f.StringMapVar(&StringMapVar{
 		Name:       "options",
 		Target:     &c.flagOptions,
@@ -276,6 +285,7 @@ func (c *SecretsEnableCommand) Run(args []string) int {
 		Local:                 c.flagLocal,
 		SealWrap:              c.flagSealWrap,
 		ExternalEntropyAccess: c.flagExternalEntropyAccess,
+		PluginVersion:         c.flagPluginVersion,
 		Config: api.MountConfigInput{
 			DefaultLeaseTTL: c.flagDefaultLeaseTTL.String(),
 			MaxLeaseTTL:     c.flagMaxLeaseTTL.String(),
@@ -3,6 +3,8 @@ package command
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -20,10 +22,14 @@ import (
 	"sync"
 	"time"
 
+	metrics "github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/datadog"
+	"github.com/armon/go-metrics/prometheus"
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-hclog"
 	log "github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-kms-wrapping/entropy"
 	aeadwrapper "github.com/hashicorp/go-kms-wrapping/wrappers/aead"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/shared-secure-libs/configutil"
@@ -34,11 +40,15 @@ import (
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/builtinplugins"
+	serverentropy "github.com/hashicorp/vault/helper/entropy"
+	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/helper/mlock"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/helper/useragent"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/physical"
@@ -524,7 +534,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 		}),
 	})
 	sealLogger := c.logger.ResetNamed(fmt.Sprintf("seal.%s", sealType))
-	wrapper, sealConfigError = configutil.ConfigureWrapper(configSeal, &infoKeys, &info, sealLogger)
+	wrapper, sealConfigError = configureSealWrapper(configSeal, &infoKeys, &info, sealLogger)
 	if sealConfigError != nil {
 		if !errwrap.ContainsType(sealConfigError, new(logical.KeyNotFoundError)) {
 			c.UI.Error(fmt.Sprintf(
@@ -636,12 +646,19 @@ func (c *ServerCommand) runRecoveryMode() int {
 	c.UI.Output("")
 
 	for _, ln := range lns {
+		customHeaders, err := server.ParseCustomResponseHeaders(ln.Config)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error parsing listener custom response headers: %s", err))
+			return 1
+		}
+
 		handler := vaulthttp.Handler(&vault.HandlerProperties{
 			Core:                  core,
 			ListenerConfig:        ln.Config,
 			DisablePrintableCheck: config.DisablePrintableCheck,
 			RecoveryMode:          c.flagRecovery,
 			RecoveryToken:         atomic.NewString(""),
+			CustomResponseHeaders: customHeaders,
 		})
 
 		server := &http.Server{
@@ -986,6 +1003,51 @@ func (c *ServerCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
+
+	if config.TelemetryFilter != nil {
+		if err := vaultmetrics.ApplyGlobalFilter(metricSink, metricsFilter(config)); err != nil {
+			c.UI.Error(fmt.Sprintf("Error initializing telemetry filter: %s", err))
+			return 1
+		}
+	}
+
+	// Sinks configured outside of the shared telemetry options are sent
+	// metrics through a single fanout, which keeps sending metrics to the
+	// other sinks when one fails
+	extraSinks := vaultmetrics.NewFanoutSink(c.logger.Named("metrics"))
+
+	if config.OTLPMetrics != nil {
+		otlpSink, err := c.setupOTLPMetrics(config, extraSinks)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error initializing OpenTelemetry metrics: %s", err))
+			return 1
+		}
+		defer otlpSink.Shutdown()
+	}
+
+	if len(config.TelemetrySinks) > 0 {
+		sinksPrometheus, closeSinks := c.setupTelemetrySinks(config, extraSinks)
+		defer closeSinks()
+		prometheusEnabled = prometheusEnabled || sinksPrometheus
+	}
+
+	// Keep metrics in memory for the rolling windows of sys/metrics
+	rollingSink, err := vaultmetrics.NewRollingSink(config.MetricsRollingWindows)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
+		return 1
+	}
+	rollingPrefix := "vault"
+	if config.Telemetry != nil && config.Telemetry.MetricsPrefix != "" {
+		rollingPrefix = config.Telemetry.MetricsPrefix
+	}
+	extraSinks.AddSink("rolling", vaultmetrics.NewPrefixSink(rollingSink, rollingPrefix))
+
+	if err := vaultmetrics.AddGlobalSink(metricSink, extraSinks); err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing telemetry sinks: %s", err))
+		return 1
+	}
+
 	metricsHelper := metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
 	// Initialize the backend
@@ -1069,6 +1131,7 @@ func (c *ServerCommand) Run(args []string) int {
 	var sealConfigError error
 	var wrapper wrapping.Wrapper
 	var barrierWrapper wrapping.Wrapper
+	var enabledWrappers []wrapping.Wrapper
 	if c.flagDevAutoSeal {
 		barrierSeal = vault.NewAutoSeal(vaultseal.NewTestSeal(nil))
 	} else {
@@ -1100,7 +1163,7 @@ func (c *ServerCommand) Run(args []string) int {
 					Logger: c.logger.Named("shamir"),
 				}),
 			})
-			wrapper, sealConfigError = configutil.ConfigureWrapper(configSeal, &infoKeys, &info, sealLogger)
+			wrapper, sealConfigError = configureSealWrapper(configSeal, &infoKeys, &info, sealLogger)
 			if sealConfigError != nil {
 				if !errwrap.ContainsType(sealConfigError, new(logical.KeyNotFoundError)) {
 					c.UI.Error(fmt.Sprintf(
@@ -1121,6 +1184,9 @@ func (c *ServerCommand) Run(args []string) int {
 			} else {
 				barrierSeal = seal
 				barrierWrapper = wrapper
+				if wrapper != nil {
+					enabledWrappers = append(enabledWrappers, wrapper)
+				}
 			}
 
 			// Ensure that the seal finalizer is called, even if using verify-only
@@ -1132,6 +1198,25 @@ func (c *ServerCommand) Run(args []string) int {
 			}()
 
 		}
+
+		// With several enabled seals, the keys are wrapped by all of them so
+		// that Vault can be unsealed as long as any of them is reachable
+		if len(enabledWrappers) > 1 {
+			barrierWrapper, err = vaultseal.NewMultiWrapper(c.logger.ResetNamed("seal.multi"), enabledWrappers...)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error configuring seals: %s", err))
+				return 1
+			}
+			barrierSeal = vault.NewAutoSeal(&vaultseal.Access{
+				Wrapper: barrierWrapper,
+			})
+			sealTypes := make([]string, 0, len(enabledWrappers))
+			for _, w := range enabledWrappers {
+				sealTypes = append(sealTypes, w.Type())
+			}
+			info["Seals"] = strings.Join(sealTypes, ", ")
+			infoKeys = append(infoKeys, "Seals")
+		}
 	}
 
 	if barrierSeal == nil {
@@ -1146,6 +1231,23 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Mix the external source of entropy, if any, into the key material
+	var entropySourcer *serverentropy.MixedSourcer
+	if config.EntropySource != nil {
+		source, err := serverentropy.NewSource(config.EntropySource.Type, config.EntropySource.Config, barrierWrapper)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error configuring entropy source %q: %s", config.EntropySource.Type, err))
+			return 1
+		}
+		defer source.Close()
+
+		entropySourcer = serverentropy.NewMixedSourcer(source)
+		secureRandomReader = entropy.NewReader(entropySourcer)
+
+		info["Entropy Source"] = config.EntropySource.Type
+		infoKeys = append(infoKeys, "Entropy Source")
+	}
+
 	coreConfig := &vault.CoreConfig{
 		RawConfig:                 config,
 		Physical:                  backend,
@@ -1155,6 +1257,7 @@ func (c *ServerCommand) Run(args []string) int {
 		ServiceRegistration:       configSR,
 		Seal:                      barrierSeal,
 		UnwrapSeal:                unwrapSeal,
+		SealFactory:               c.sealFactory,
 		AuditBackends:             c.AuditBackends,
 		CredentialBackends:        c.CredentialBackends,
 		LogicalBackends:           c.LogicalBackends,
@@ -1170,6 +1273,7 @@ func (c *ServerCommand) Run(args []string) int {
 		EnableUI:                  config.EnableUI,
 		EnableRaw:                 config.EnableRawEndpoint,
 		DisableSealWrap:           config.DisableSealWrap,
+		EnablePerformanceStandby:  config.EnablePerformanceStandby,
 		DisablePerformanceStandby: config.DisablePerformanceStandby,
 		DisableIndexing:           config.DisableIndexing,
 		AllLoggers:                allLoggers,
@@ -1177,7 +1281,15 @@ func (c *ServerCommand) Run(args []string) int {
 		DisableKeyEncodingChecks:  config.DisablePrintableCheck,
 		MetricsHelper:             metricsHelper,
 		MetricSink:                metricSink,
+		MetricsRollingSink:        rollingSink,
+		RuntimeMetricsInterval:    runtimeMetricsInterval(config),
 		SecureRandomReader:        secureRandomReader,
+		DrainTimeout:              config.DrainTimeout,
+		ForwardingCompression:     config.RequestForwardingCompression,
+		ForwardingConnections:     config.RequestForwardingConnections,
+	}
+	if entropySourcer != nil {
+		coreConfig.EntropySourcer = entropySourcer
 	}
 	if c.flagDev {
 		coreConfig.EnableRaw = true
@@ -1732,16 +1844,33 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	}
 
 	// Initialize the HTTP servers
+	servers := make([]*http.Server, 0, len(lns))
 	for _, ln := range lns {
 		if ln.Config == nil {
 			c.UI.Error("Found nil listener config after parsing")
 			return 1
 		}
+		customHeaders, err := server.ParseCustomResponseHeaders(ln.Config)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error parsing listener custom response headers: %s", err))
+			return 1
+		}
+		unauthAccess, err := server.ParseUnauthenticatedAccess(ln.Config)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error parsing listener unauthenticated access: %s", err))
+			return 1
+		}
+
 		handler := vaulthttp.Handler(&vault.HandlerProperties{
-			Core:                  core,
-			ListenerConfig:        ln.Config,
-			DisablePrintableCheck: config.DisablePrintableCheck,
-			RecoveryMode:          c.flagRecovery,
+			Core:                            core,
+			ListenerConfig:                  ln.Config,
+			DisablePrintableCheck:           config.DisablePrintableCheck,
+			RecoveryMode:                    c.flagRecovery,
+			CustomResponseHeaders:           customHeaders,
+			DisableHealthDetails:            !unauthAccess.HealthDetails,
+			DisableSealStatusDetails:        !unauthAccess.SealStatusDetails,
+			DisableUI:                       !unauthAccess.UI,
+			UnauthenticatedInFlightRequests: unauthAccess.InFlightRequests,
 		})
 
 		if len(ln.Config.XForwardedForAuthorizedAddrs) > 0 {
@@ -1776,6 +1905,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 			continue
 		}
 
+		servers = append(servers, server)
 		go server.Serve(ln.Listener)
 	}
 
@@ -1906,6 +2036,10 @@ CLUSTER_SYNTHESIS_COMPLETE:
 		}
 	}
 
+	// Stop accepting new requests, and give the requests being handled a
+	// chance to complete before sealing.
+	c.drainServers(servers, config.DrainTimeout)
+
 	// Stop the listeners so that we don't process further client requests.
 	c.cleanupGuard.Do(listenerCloseFunc)
 
@@ -1921,6 +2055,33 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	return retCode
 }
 
+// drainServers stops the HTTP servers from accepting new requests and waits
+// for the requests being handled to complete, for at most the given timeout.
+// The connections still active at the deadline are closed.
+func (c *ServerCommand) drainServers(servers []*http.Server, timeout time.Duration) {
+	if timeout <= 0 || len(servers) == 0 {
+		return
+	}
+
+	c.logger.Info("draining in-flight requests", "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				c.logger.Warn("drain timeout reached, closing remaining connections", "error", err)
+				srv.Close()
+			}
+		}(srv)
+	}
+	wg.Wait()
+}
+
 func (c *ServerCommand) enableDev(core *vault.Core, coreConfig *vault.CoreConfig) (*vault.InitResult, error) {
 	ctx := namespace.ContextWithNamespace(context.Background(), namespace.RootNamespace)
 
@@ -2402,6 +2563,201 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 	return reloadErrors.ErrorOrNil()
 }
 
+// setupOTLPMetrics starts pushing metrics to the OpenTelemetry collector set
+// in the telemetry stanza, alongside the other configured sinks.
+func (c *ServerCommand) setupOTLPMetrics(config *server.Config, fanout *vaultmetrics.FanoutSink) (*vaultmetrics.OTLPSink, error) {
+	var tlsConfig *tls.Config
+	if !config.OTLPMetrics.Insecure {
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+		if config.OTLPMetrics.CACert != "" {
+			pem, err := ioutil.ReadFile(config.OTLPMetrics.CACert)
+			if err != nil {
+				return nil, errwrap.Wrapf("error reading CA certificate: {{err}}", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %q", config.OTLPMetrics.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	prefix := "vault"
+	if config.Telemetry != nil && config.Telemetry.MetricsPrefix != "" {
+		prefix = config.Telemetry.MetricsPrefix
+	}
+
+	sink, err := vaultmetrics.NewOTLPSink(&vaultmetrics.OTLPSinkConfig{
+		Endpoint:           config.OTLPMetrics.Endpoint,
+		TLSConfig:          tlsConfig,
+		Headers:            config.OTLPMetrics.Headers,
+		ResourceAttributes: config.OTLPMetrics.ResourceAttributes,
+		Temporality:        config.OTLPMetrics.Temporality,
+		ExportInterval:     config.OTLPMetrics.ExportInterval,
+		Prefix:             prefix,
+		Logger:             c.logger.Named("metrics.otlp"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The sink is added after the global instance is filtered, so the filter
+	// is applied to it separately
+	var fanoutSink metrics.MetricSink = sink
+	if config.TelemetryFilter != nil {
+		fanoutSink, err = vaultmetrics.NewFilterSink(sink, prefix, metricsFilter(config))
+		if err != nil {
+			return nil, err
+		}
+	}
+	fanout.AddSink("otlp", fanoutSink)
+
+	sink.Run()
+	return sink, nil
+}
+
+// setupTelemetrySinks adds the sinks set with sink blocks in the telemetry
+// stanza to the fanout. Each sink prepends its own prefix to metric names, and
+// uses its own filter if one is set, or else the filter of the stanza. A sink
+// that can't be created is skipped, so that the others still receive
+// metrics. It returns whether a Prometheus sink was added, and a function
+// closing the sinks that write to files.
+func (c *ServerCommand) setupTelemetrySinks(config *server.Config, fanout *vaultmetrics.FanoutSink) (bool, func()) {
+	defaultPrefix := "vault"
+	if config.Telemetry != nil && config.Telemetry.MetricsPrefix != "" {
+		defaultPrefix = config.Telemetry.MetricsPrefix
+	}
+
+	var prometheusEnabled bool
+	var fileSinks []*vaultmetrics.FileSink
+	for i, sinkConfig := range config.TelemetrySinks {
+		name := fmt.Sprintf("%s.%d", sinkConfig.Type, i)
+
+		var sink metrics.MetricSink
+		var err error
+		switch sinkConfig.Type {
+		case "statsd":
+			sink, err = metrics.NewStatsdSink(sinkConfig.Config["address"])
+		case "statsite":
+			sink, err = metrics.NewStatsiteSink(sinkConfig.Config["address"])
+		case "dogstatsd":
+			sink, err = datadog.NewDogStatsdSink(sinkConfig.Config["address"], "")
+		case "prometheus":
+			retention := configutil.PrometheusDefaultRetentionTime
+			if raw := sinkConfig.Config["retention_time"]; raw != "" {
+				retention, err = parseutil.ParseDurationSecond(raw)
+				if err != nil {
+					break
+				}
+			}
+			sink, err = prometheus.NewPrometheusSinkFrom(prometheus.PrometheusOpts{
+				Expiration: retention,
+			})
+			if err == nil {
+				prometheusEnabled = true
+			}
+		case "file":
+			var fileSink *vaultmetrics.FileSink
+			fileSink, err = vaultmetrics.NewFileSink(sinkConfig.Config["path"])
+			if err == nil {
+				fileSinks = append(fileSinks, fileSink)
+				sink = fileSink
+			}
+		default:
+			err = fmt.Errorf("unknown sink type")
+		}
+		if err != nil {
+			c.logger.Warn("skipping telemetry sink that could not be created", "sink", name, "error", err)
+			continue
+		}
+
+		prefix := defaultPrefix
+		if p, ok := sinkConfig.Config["metrics_prefix"]; ok {
+			prefix = p
+		}
+		sink = vaultmetrics.NewPrefixSink(sink, prefix)
+
+		filter, err := telemetrySinkFilter(config, sinkConfig)
+		if err != nil {
+			c.logger.Warn("skipping telemetry sink with invalid filter", "sink", name, "error", err)
+			continue
+		}
+		if filter != nil {
+			sink, err = vaultmetrics.NewFilterSink(sink, prefix, filter)
+			if err != nil {
+				c.logger.Warn("skipping telemetry sink with invalid filter", "sink", name, "error", err)
+				continue
+			}
+		}
+
+		fanout.AddSink(name, sink)
+	}
+
+	return prometheusEnabled, func() {
+		for _, s := range fileSinks {
+			s.Close()
+		}
+	}
+}
+
+// telemetrySinkFilter returns the filter of a telemetry sink, falling back to
+// the filter of the telemetry stanza.
+func telemetrySinkFilter(config *server.Config, sinkConfig *server.TelemetrySink) (*vaultmetrics.MetricsFilter, error) {
+	var set bool
+	for _, k := range []string{"prefix_filter", "filter_default", "allowed_labels", "blocked_labels"} {
+		if _, ok := sinkConfig.Config[k]; ok {
+			set = true
+		}
+	}
+	if !set {
+		if config.TelemetryFilter == nil {
+			return nil, nil
+		}
+		return metricsFilter(config), nil
+	}
+
+	filter := &vaultmetrics.MetricsFilter{
+		PrefixFilter:  strutil.ParseStringSlice(sinkConfig.Config["prefix_filter"], ","),
+		FilterDefault: true,
+		BlockedLabels: strutil.ParseStringSlice(sinkConfig.Config["blocked_labels"], ","),
+	}
+	if raw, ok := sinkConfig.Config["filter_default"]; ok {
+		var err error
+		if filter.FilterDefault, err = parseutil.ParseBool(raw); err != nil {
+			return nil, err
+		}
+	}
+	if raw, ok := sinkConfig.Config["allowed_labels"]; ok {
+		filter.AllowedLabels = strutil.ParseStringSlice(raw, ",")
+	}
+	return filter, nil
+}
+
+// runtimeMetricsInterval returns the interval at which runtime metrics are
+// collected, which is zero if they are disabled.
+func runtimeMetricsInterval(config *server.Config) time.Duration {
+	switch {
+	case config.RuntimeMetricsInterval < 0:
+		return 0
+	case config.RuntimeMetricsInterval == 0:
+		return vault.DefaultRuntimeMetricsInterval
+	default:
+		return config.RuntimeMetricsInterval
+	}
+}
+
+// metricsFilter returns the metric filter set in the telemetry stanza.
+func metricsFilter(config *server.Config) *vaultmetrics.MetricsFilter {
+	return &vaultmetrics.MetricsFilter{
+		PrefixFilter:  config.TelemetryFilter.PrefixFilter,
+		FilterDefault: config.TelemetryFilter.FilterDefault,
+		AllowedLabels: config.TelemetryFilter.AllowedLabels,
+		BlockedLabels: config.TelemetryFilter.BlockedLabels,
+	}
+}
+
 // storePidFile is used to write out our PID to a file if necessary
 func (c *ServerCommand) storePidFile(pidPath string) error {
 	// Quit fast if no pidfile
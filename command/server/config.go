@@ -13,11 +13,13 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/shared-secure-libs/configutil"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 )
 
 // Config is the configuration for the vault server.
@@ -31,6 +33,18 @@ type Config struct {
 
 	ServiceRegistration *ServiceRegistration `hcl:"-"`
 
+	EntropySource *EntropySource `hcl:"-"`
+
+	OTLPMetrics *OTLPMetrics `hcl:"-"`
+
+	TelemetryFilter *TelemetryFilter `hcl:"-"`
+
+	TelemetrySinks []*TelemetrySink `hcl:"-"`
+
+	MetricsRollingWindows []time.Duration `hcl:"-"`
+
+	RuntimeMetricsInterval time.Duration `hcl:"-"`
+
 	CacheSize                int         `hcl:"cache_size"`
 	DisableCache             bool        `hcl:"-"`
 	DisableCacheRaw          interface{} `hcl:"disable_cache"`
@@ -57,6 +71,9 @@ type Config struct {
 	DisableClustering    bool        `hcl:"-"`
 	DisableClusteringRaw interface{} `hcl:"disable_clustering"`
 
+	EnablePerformanceStandby    bool        `hcl:"-"`
+	EnablePerformanceStandbyRaw interface{} `hcl:"enable_performance_standby"`
+
 	DisablePerformanceStandby    bool        `hcl:"-"`
 	DisablePerformanceStandbyRaw interface{} `hcl:"disable_performance_standby"`
 
@@ -68,6 +85,15 @@ type Config struct {
 
 	DisableSentinelTrace    bool        `hcl:"-"`
 	DisableSentinelTraceRaw interface{} `hcl:"disable_sentinel_trace"`
+
+	// DrainTimeout is how long the requests being handled are given to
+	// complete on shutdown and step-down. Zero disables draining.
+	DrainTimeout    time.Duration `hcl:"-"`
+	DrainTimeoutRaw interface{}   `hcl:"drain_timeout"`
+
+	RequestForwardingCompression    bool        `hcl:"-"`
+	RequestForwardingCompressionRaw interface{} `hcl:"request_forwarding_compression"`
+	RequestForwardingConnections    int         `hcl:"request_forwarding_connections"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -126,6 +152,60 @@ func (b *ServiceRegistration) GoString() string {
 	return fmt.Sprintf("*%#v", *b)
 }
 
+// EntropySource is the optional external source of entropy mixed into the
+// key material generated by the server, configured with an entropy stanza.
+type EntropySource struct {
+	Type   string
+	Config map[string]string
+}
+
+func (e *EntropySource) GoString() string {
+	return fmt.Sprintf("*%#v", *e)
+}
+
+// OTLPMetrics is the optional configuration, read from the telemetry stanza,
+// for pushing metrics to an OpenTelemetry collector.
+type OTLPMetrics struct {
+	Endpoint           string            `hcl:"otlp_metrics_endpoint"`
+	Insecure           bool              `hcl:"-"`
+	InsecureRaw        interface{}       `hcl:"otlp_metrics_insecure"`
+	CACert             string            `hcl:"otlp_metrics_ca_cert"`
+	Headers            map[string]string `hcl:"otlp_metrics_headers"`
+	ResourceAttributes map[string]string `hcl:"otlp_metrics_resource_attributes"`
+	Temporality        string            `hcl:"otlp_metrics_temporality"`
+	ExportInterval     time.Duration     `hcl:"-"`
+	ExportIntervalRaw  interface{}       `hcl:"otlp_metrics_export_interval"`
+}
+
+func (o *OTLPMetrics) GoString() string {
+	return fmt.Sprintf("*%#v", *o)
+}
+
+// TelemetryFilter is the optional configuration, read from the telemetry
+// stanza, for filtering the metric names and labels sent to the sinks.
+type TelemetryFilter struct {
+	PrefixFilter     []string    `hcl:"prefix_filter"`
+	FilterDefault    bool        `hcl:"-"`
+	FilterDefaultRaw interface{} `hcl:"filter_default"`
+	AllowedLabels    []string    `hcl:"allowed_labels"`
+	BlockedLabels    []string    `hcl:"blocked_labels"`
+}
+
+func (f *TelemetryFilter) GoString() string {
+	return fmt.Sprintf("*%#v", *f)
+}
+
+// TelemetrySink is an additional metrics sink, configured with a sink block
+// in the telemetry stanza.
+type TelemetrySink struct {
+	Type   string
+	Config map[string]string
+}
+
+func (s *TelemetrySink) GoString() string {
+	return fmt.Sprintf("*%#v", *s)
+}
+
 func NewConfig() *Config {
 	return &Config{
 		SharedConfig: new(configutil.SharedConfig),
@@ -160,6 +240,36 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.ServiceRegistration = c2.ServiceRegistration
 	}
 
+	result.EntropySource = c.EntropySource
+	if c2.EntropySource != nil {
+		result.EntropySource = c2.EntropySource
+	}
+
+	result.OTLPMetrics = c.OTLPMetrics
+	if c2.OTLPMetrics != nil {
+		result.OTLPMetrics = c2.OTLPMetrics
+	}
+
+	result.TelemetryFilter = c.TelemetryFilter
+	if c2.TelemetryFilter != nil {
+		result.TelemetryFilter = c2.TelemetryFilter
+	}
+
+	result.TelemetrySinks = c.TelemetrySinks
+	if c2.TelemetrySinks != nil {
+		result.TelemetrySinks = c2.TelemetrySinks
+	}
+
+	result.MetricsRollingWindows = c.MetricsRollingWindows
+	if c2.MetricsRollingWindows != nil {
+		result.MetricsRollingWindows = c2.MetricsRollingWindows
+	}
+
+	result.RuntimeMetricsInterval = c.RuntimeMetricsInterval
+	if c2.RuntimeMetricsInterval != 0 {
+		result.RuntimeMetricsInterval = c2.RuntimeMetricsInterval
+	}
+
 	result.CacheSize = c.CacheSize
 	if c2.CacheSize != 0 {
 		result.CacheSize = c2.CacheSize
@@ -192,6 +302,21 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DefaultLeaseTTL = c2.DefaultLeaseTTL
 	}
 
+	result.DrainTimeout = c.DrainTimeout
+	if c2.DrainTimeoutRaw != nil {
+		result.DrainTimeout = c2.DrainTimeout
+	}
+
+	result.RequestForwardingCompression = c.RequestForwardingCompression
+	if c2.RequestForwardingCompressionRaw != nil {
+		result.RequestForwardingCompression = c2.RequestForwardingCompression
+	}
+
+	result.RequestForwardingConnections = c.RequestForwardingConnections
+	if c2.RequestForwardingConnections != 0 {
+		result.RequestForwardingConnections = c2.RequestForwardingConnections
+	}
+
 	result.ClusterCipherSuites = c.ClusterCipherSuites
 	if c2.ClusterCipherSuites != "" {
 		result.ClusterCipherSuites = c2.ClusterCipherSuites
@@ -230,6 +355,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PluginDirectory = c2.PluginDirectory
 	}
 
+	result.EnablePerformanceStandby = c.EnablePerformanceStandby
+	if c2.EnablePerformanceStandby {
+		result.EnablePerformanceStandby = c2.EnablePerformanceStandby
+	}
+
 	result.DisablePerformanceStandby = c.DisablePerformanceStandby
 	if c2.DisablePerformanceStandby {
 		result.DisablePerformanceStandby = c2.DisablePerformanceStandby
@@ -292,12 +422,24 @@ func CheckConfig(c *Config, e error) (*Config, error) {
 		return c, e
 	}
 
-	if len(c.Seals) == 2 {
+	// Several enabled auto seals wrap the keys together, and one disabled
+	// seal can be provided to migrate from it
+	if len(c.Seals) > 1 {
+		var disabled int
+		for _, seal := range c.Seals {
+			switch {
+			case seal.Disabled:
+				disabled++
+			case seal.Type == wrapping.Shamir:
+				return nil, errors.New("seals: shamir seal can't be combined with other enabled seals")
+			}
+		}
+
 		switch {
-		case c.Seals[0].Disabled && c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but both are disabled")
-		case !c.Seals[0].Disabled && !c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but neither is disabled")
+		case disabled == len(c.Seals):
+			return nil, fmt.Errorf("seals: %d seals provided but all are disabled", len(c.Seals))
+		case disabled > 1:
+			return nil, errors.New("seals: only one seal can be disabled for migration")
 		}
 	}
 
@@ -349,6 +491,24 @@ func ParseConfig(d string) (*Config, error) {
 			return nil, err
 		}
 	}
+	if result.DrainTimeoutRaw != nil {
+		if result.DrainTimeout, err = parseutil.ParseDurationSecond(result.DrainTimeoutRaw); err != nil {
+			return nil, err
+		}
+		if result.DrainTimeout < 0 {
+			return nil, errors.New("drain_timeout can't be negative")
+		}
+	}
+
+	if result.RequestForwardingCompressionRaw != nil {
+		if result.RequestForwardingCompression, err = parseutil.ParseBool(result.RequestForwardingCompressionRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.RequestForwardingConnections < 0 {
+		return nil, errors.New("request_forwarding_connections can't be negative")
+	}
 
 	if result.EnableUIRaw != nil {
 		if result.EnableUI, err = parseutil.ParseBool(result.EnableUIRaw); err != nil {
@@ -386,6 +546,12 @@ func ParseConfig(d string) (*Config, error) {
 		}
 	}
 
+	if result.EnablePerformanceStandbyRaw != nil {
+		if result.EnablePerformanceStandby, err = parseutil.ParseBool(result.EnablePerformanceStandbyRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	if result.DisablePerformanceStandbyRaw != nil {
 		if result.DisablePerformanceStandby, err = parseutil.ParseBool(result.DisablePerformanceStandbyRaw); err != nil {
 			return nil, err
@@ -441,6 +607,39 @@ func ParseConfig(d string) (*Config, error) {
 		}
 	}
 
+	for i, l := range result.Listeners {
+		if _, err := ParseCustomResponseHeaders(l); err != nil {
+			return nil, fmt.Errorf("listeners.%d: %w", i, err)
+		}
+		if _, err := ParseUnauthenticatedAccess(l); err != nil {
+			return nil, fmt.Errorf("listeners.%d: %w", i, err)
+		}
+	}
+
+	if o := list.Filter("entropy"); len(o.Items) > 0 {
+		if err := parseEntropySource(result, o, "entropy"); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'entropy': {{err}}", err)
+		}
+	}
+
+	if o := list.Filter("telemetry"); len(o.Items) > 0 {
+		if err := parseOTLPMetrics(result, o); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'telemetry': {{err}}", err)
+		}
+		if err := parseTelemetryFilter(result, o); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'telemetry': {{err}}", err)
+		}
+		if err := parseTelemetrySinks(result, o); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'telemetry': {{err}}", err)
+		}
+		if err := parseMetricsRollingWindows(result, o); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'telemetry': {{err}}", err)
+		}
+		if err := parseRuntimeMetricsInterval(result, o); err != nil {
+			return nil, errwrap.Wrapf("error parsing 'telemetry': {{err}}", err)
+		}
+	}
+
 	entConfig := &(result.entConfig)
 	if err := entConfig.parseConfig(list); err != nil {
 		return nil, errwrap.Wrapf("error parsing enterprise config: {{err}}", err)
@@ -698,6 +897,216 @@ func parseServiceRegistration(result *Config, list *ast.ObjectList, name string)
 	return nil
 }
 
+// parseEntropySource reads the external source of entropy. The only supported
+// mode is augmentation, in which the source is mixed with the system's
+// randomness.
+func parseEntropySource(result *Config, list *ast.ObjectList, name string) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one %q block is permitted", name)
+	}
+
+	// Get our item
+	item := list.Items[0]
+	if len(item.Keys) == 0 {
+		return fmt.Errorf("%s: the type of external entropy is required", name)
+	}
+	key := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+	}
+
+	switch mode := m["mode"]; mode {
+	case "", "augmentation":
+	default:
+		return fmt.Errorf("the specified entropy mode %q is not supported", mode)
+	}
+	delete(m, "mode")
+
+	switch key {
+	case "seal", "device", "egd":
+	default:
+		return fmt.Errorf("the %q type of external entropy is not supported", key)
+	}
+
+	result.EntropySource = &EntropySource{
+		Type:   key,
+		Config: m,
+	}
+	result.Entropy = &configutil.Entropy{
+		Mode: configutil.EntropyAugmentation,
+	}
+	return nil
+}
+
+// parseOTLPMetrics reads the OpenTelemetry metrics options from the telemetry
+// stanza. The rest of the stanza is parsed with the shared configuration.
+func parseOTLPMetrics(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'telemetry' block is permitted")
+	}
+
+	var o OTLPMetrics
+	if err := hcl.DecodeObject(&o, list.Items[0].Val); err != nil {
+		return err
+	}
+	if o.Endpoint == "" {
+		return nil
+	}
+
+	if o.InsecureRaw != nil {
+		var err error
+		if o.Insecure, err = parseutil.ParseBool(o.InsecureRaw); err != nil {
+			return err
+		}
+		o.InsecureRaw = nil
+	}
+
+	if o.ExportIntervalRaw != nil {
+		var err error
+		if o.ExportInterval, err = parseutil.ParseDurationSecond(o.ExportIntervalRaw); err != nil {
+			return err
+		}
+		o.ExportIntervalRaw = nil
+	}
+
+	switch o.Temporality {
+	case "", "cumulative", "delta":
+	default:
+		return fmt.Errorf("otlp_metrics_temporality must be \"cumulative\" or \"delta\"")
+	}
+
+	result.OTLPMetrics = &o
+	return nil
+}
+
+// parseTelemetryFilter reads the metric filtering options from the telemetry
+// stanza. Metrics are allowed by default.
+func parseTelemetryFilter(result *Config, list *ast.ObjectList) error {
+	var f TelemetryFilter
+	if err := hcl.DecodeObject(&f, list.Items[0].Val); err != nil {
+		return err
+	}
+	if f.PrefixFilter == nil && f.FilterDefaultRaw == nil && f.AllowedLabels == nil && f.BlockedLabels == nil {
+		return nil
+	}
+
+	f.FilterDefault = true
+	if f.FilterDefaultRaw != nil {
+		var err error
+		if f.FilterDefault, err = parseutil.ParseBool(f.FilterDefaultRaw); err != nil {
+			return err
+		}
+		f.FilterDefaultRaw = nil
+	}
+
+	for _, rule := range f.PrefixFilter {
+		if !strings.HasPrefix(rule, "+") && !strings.HasPrefix(rule, "-") {
+			return fmt.Errorf("prefix_filter entry %q must start with '+' or '-'", rule)
+		}
+	}
+
+	result.TelemetryFilter = &f
+	return nil
+}
+
+// parseTelemetrySinks reads the sink blocks of the telemetry stanza. Each
+// block is labeled with the type of the sink.
+func parseTelemetrySinks(result *Config, list *ast.ObjectList) error {
+	ot, ok := list.Items[0].Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("telemetry: should be an object")
+	}
+
+	for _, item := range ot.List.Filter("sink").Items {
+		if len(item.Keys) == 0 {
+			return fmt.Errorf("sink: type must be specified")
+		}
+		key := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+		var m map[string]string
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("sink.%s:", key))
+		}
+
+		var required string
+		switch key {
+		case "statsd", "statsite", "dogstatsd":
+			required = "address"
+		case "file":
+			required = "path"
+		case "prometheus":
+		default:
+			return fmt.Errorf("sink.%s: unknown sink type", key)
+		}
+		if required != "" && m[required] == "" {
+			return fmt.Errorf("sink.%s: %s is required", key, required)
+		}
+		for _, rule := range strutil.ParseStringSlice(m["prefix_filter"], ",") {
+			if !strings.HasPrefix(rule, "+") && !strings.HasPrefix(rule, "-") {
+				return fmt.Errorf("sink.%s: prefix_filter entry %q must start with '+' or '-'", key, rule)
+			}
+		}
+
+		result.TelemetrySinks = append(result.TelemetrySinks, &TelemetrySink{
+			Type:   key,
+			Config: m,
+		})
+	}
+
+	return nil
+}
+
+// parseMetricsRollingWindows reads the windows the in-memory metrics served in
+// the json format of sys/metrics are aggregated over.
+func parseMetricsRollingWindows(result *Config, list *ast.ObjectList) error {
+	var w struct {
+		RollingWindows []string `hcl:"rolling_windows"`
+	}
+	if err := hcl.DecodeObject(&w, list.Items[0].Val); err != nil {
+		return err
+	}
+
+	for _, raw := range w.RollingWindows {
+		d, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return errwrap.Wrapf("invalid rolling_windows: {{err}}", err)
+		}
+		result.MetricsRollingWindows = append(result.MetricsRollingWindows, d)
+	}
+	return nil
+}
+
+// parseRuntimeMetricsInterval reads the interval at which runtime metrics
+// labeled with the cluster are collected. A value of "none" disables them,
+// which is stored as a negative interval.
+func parseRuntimeMetricsInterval(result *Config, list *ast.ObjectList) error {
+	var r struct {
+		Raw interface{} `hcl:"runtime_metrics_interval"`
+	}
+	if err := hcl.DecodeObject(&r, list.Items[0].Val); err != nil {
+		return err
+	}
+	if r.Raw == nil {
+		return nil
+	}
+
+	if raw, ok := r.Raw.(string); ok && raw == "none" {
+		result.RuntimeMetricsInterval = -1
+		return nil
+	}
+	d, err := parseutil.ParseDurationSecond(r.Raw)
+	if err != nil {
+		return errwrap.Wrapf("invalid runtime_metrics_interval: {{err}}", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("runtime_metrics_interval must be positive")
+	}
+	result.RuntimeMetricsInterval = d
+	return nil
+}
+
 // Sanitized returns a copy of the config with all values that are considered
 // sensitive stripped. It also strips all `*Raw` values that are mainly
 // used for parsing.
@@ -707,6 +1116,7 @@ func parseServiceRegistration(result *Config, list *ast.ObjectList, name string)
 // - HAStorage.Config
 // - Seals.Config
 // - Telemetry.CirconusAPIToken
+// - OTLPMetrics.Headers
 func (c *Config) Sanitized() map[string]interface{} {
 	// Create shared config if it doesn't exist (e.g. in tests) so that map
 	// keys are actually populated
@@ -725,6 +1135,11 @@ func (c *Config) Sanitized() map[string]interface{} {
 		"max_lease_ttl":     c.MaxLeaseTTL,
 		"default_lease_ttl": c.DefaultLeaseTTL,
 
+		"drain_timeout": c.DrainTimeout,
+
+		"request_forwarding_compression": c.RequestForwardingCompression,
+		"request_forwarding_connections": c.RequestForwardingConnections,
+
 		"cluster_cipher_suites": c.ClusterCipherSuites,
 
 		"plugin_directory": c.PluginDirectory,
@@ -735,6 +1150,8 @@ func (c *Config) Sanitized() map[string]interface{} {
 		"cluster_addr":       c.ClusterAddr,
 		"disable_clustering": c.DisableClustering,
 
+		"enable_performance_standby": c.EnablePerformanceStandby,
+
 		"disable_performance_standby": c.DisablePerformanceStandby,
 
 		"disable_sealwrap": c.DisableSealWrap,
@@ -767,6 +1184,61 @@ func (c *Config) Sanitized() map[string]interface{} {
 		result["ha_storage"] = sanitizedHAStorage
 	}
 
+	// Sanitize OpenTelemetry metrics options, which are part of the
+	// telemetry stanza
+	if c.OTLPMetrics != nil {
+		if telemetry, ok := result["telemetry"].(map[string]interface{}); ok {
+			telemetry["otlp_metrics_endpoint"] = c.OTLPMetrics.Endpoint
+			telemetry["otlp_metrics_insecure"] = c.OTLPMetrics.Insecure
+			telemetry["otlp_metrics_ca_cert"] = c.OTLPMetrics.CACert
+			telemetry["otlp_metrics_resource_attributes"] = c.OTLPMetrics.ResourceAttributes
+			telemetry["otlp_metrics_temporality"] = c.OTLPMetrics.Temporality
+			telemetry["otlp_metrics_export_interval"] = c.OTLPMetrics.ExportInterval
+		}
+	}
+
+	if c.TelemetryFilter != nil {
+		if telemetry, ok := result["telemetry"].(map[string]interface{}); ok {
+			telemetry["prefix_filter"] = c.TelemetryFilter.PrefixFilter
+			telemetry["filter_default"] = c.TelemetryFilter.FilterDefault
+			telemetry["allowed_labels"] = c.TelemetryFilter.AllowedLabels
+			telemetry["blocked_labels"] = c.TelemetryFilter.BlockedLabels
+		}
+	}
+
+	if len(c.MetricsRollingWindows) > 0 {
+		if telemetry, ok := result["telemetry"].(map[string]interface{}); ok {
+			telemetry["rolling_windows"] = c.MetricsRollingWindows
+		}
+	}
+	if c.RuntimeMetricsInterval != 0 {
+		if telemetry, ok := result["telemetry"].(map[string]interface{}); ok {
+			telemetry["runtime_metrics_interval"] = c.RuntimeMetricsInterval
+		}
+	}
+
+	// Sanitize telemetry sinks, whose options may include credentials in
+	// their addresses
+	if len(c.TelemetrySinks) > 0 {
+		if telemetry, ok := result["telemetry"].(map[string]interface{}); ok {
+			var sinks []interface{}
+			for _, sink := range c.TelemetrySinks {
+				sinks = append(sinks, map[string]interface{}{
+					"type": sink.Type,
+				})
+			}
+			telemetry["sinks"] = sinks
+		}
+	}
+
+	// Sanitize entropy stanza
+	if c.EntropySource != nil {
+		result["entropy"] = map[string]interface{}{
+			"type": c.EntropySource.Type,
+			"mode": "augmentation",
+		}
+	}
+
 	// Sanitize service_registration stanza
 	if c.ServiceRegistration != nil {
 		sanitizedServiceRegistration := map[string]interface{}{
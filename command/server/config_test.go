@@ -4,6 +4,8 @@ package server
 
 import (
 	"testing"
+
+	"github.com/hashicorp/shared-secure-libs/configutil"
 )
 
 func TestLoadConfigFile(t *testing.T) {
@@ -11,7 +13,7 @@ func TestLoadConfigFile(t *testing.T) {
 }
 
 func TestLoadConfigFile_topLevel(t *testing.T) {
-	testLoadConfigFile_topLevel(t, nil)
+	testLoadConfigFile_topLevel(t, &configutil.Entropy{Mode: configutil.EntropyAugmentation})
 }
 
 func TestLoadConfigFile_json(t *testing.T) {
@@ -19,7 +21,7 @@ func TestLoadConfigFile_json(t *testing.T) {
 }
 
 func TestLoadConfigFile_json2(t *testing.T) {
-	testLoadConfigFile_json2(t, nil)
+	testLoadConfigFile_json2(t, &configutil.Entropy{Mode: configutil.EntropyAugmentation})
 }
 
 func TestLoadConfigFileIntegerAndBooleanValues(t *testing.T) {
@@ -53,3 +55,35 @@ func TestConfigRaftRetryJoin(t *testing.T) {
 func TestParseSeals(t *testing.T) {
 	testParseSeals(t)
 }
+
+func TestParseCustomResponseHeaders(t *testing.T) {
+	testParseCustomResponseHeaders(t)
+}
+
+func TestParseUnauthenticatedAccess(t *testing.T) {
+	testParseUnauthenticatedAccess(t)
+}
+
+func TestParseEntropySource(t *testing.T) {
+	testParseEntropySource(t)
+}
+
+func TestParseOTLPMetrics(t *testing.T) {
+	testParseOTLPMetrics(t)
+}
+
+func TestParseTelemetryFilter(t *testing.T) {
+	testParseTelemetryFilter(t)
+}
+
+func TestParseTelemetrySinks(t *testing.T) {
+	testParseTelemetrySinks(t)
+}
+
+func TestParseMetricsRollingWindows(t *testing.T) {
+	testParseMetricsRollingWindows(t)
+}
+
+func TestParseRuntimeMetricsInterval(t *testing.T) {
+	testParseRuntimeMetricsInterval(t)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -145,6 +146,10 @@ func testLoadConfigFile_topLevel(t *testing.T, entropy *configutil.Entropy) {
 	if entropy != nil {
 		expected.Entropy = entropy
 	}
+	expected.EntropySource = &EntropySource{
+		Type:   "seal",
+		Config: map[string]string{},
+	}
 	config.Listeners[0].RawConfig = nil
 	if diff := deep.Equal(config, expected); diff != nil {
 		t.Fatal(diff)
@@ -229,6 +234,10 @@ func testLoadConfigFile_json2(t *testing.T, entropy *configutil.Entropy) {
 	if entropy != nil {
 		expected.Entropy = entropy
 	}
+	expected.EntropySource = &EntropySource{
+		Type:   "seal",
+		Config: map[string]string{},
+	}
 	config.Listeners[0].RawConfig = nil
 	config.Listeners[1].RawConfig = nil
 	if diff := deep.Equal(config, expected); diff != nil {
@@ -589,11 +598,13 @@ func testConfig_Sanitized(t *testing.T) {
 		"disable_clustering":           false,
 		"disable_indexing":             false,
 		"disable_mlock":                true,
+		"enable_performance_standby":   false,
 		"disable_performance_standby":  false,
 		"disable_printable_check":      false,
 		"disable_sealwrap":             true,
 		"raw_storage_endpoint":         true,
 		"disable_sentinel_trace":       true,
+		"drain_timeout":                30 * time.Second,
 		"enable_ui":                    true,
 		"ha_storage": map[string]interface{}{
 			"cluster_addr":       "top_level_cluster_addr",
@@ -619,6 +630,8 @@ func testConfig_Sanitized(t *testing.T) {
 				"type":     "awskms",
 			},
 		},
+		"request_forwarding_compression": true,
+		"request_forwarding_connections": 4,
 		"storage": map[string]interface{}{
 			"cluster_addr":       "top_level_cluster_addr",
 			"disable_clustering": false,
@@ -771,3 +784,372 @@ func testParseSeals(t *testing.T) {
 	}
 	require.Equal(t, config, expected)
 }
+
+func testParseOTLPMetrics(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config_otlp_metrics.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &OTLPMetrics{
+		Endpoint:       "otel-collector:4317",
+		Insecure:       true,
+		Temporality:    "delta",
+		ExportInterval: 30 * time.Second,
+		Headers: map[string]string{
+			"x-api-key": "secret",
+		},
+		ResourceAttributes: map[string]string{
+			"deployment.environment": "production",
+		},
+	}
+	if diff := deep.Equal(config.OTLPMetrics, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	sanitized := config.Sanitized()["telemetry"].(map[string]interface{})
+	if sanitized["otlp_metrics_endpoint"] != "otel-collector:4317" {
+		t.Fatalf("bad sanitized telemetry: %v", sanitized)
+	}
+	if _, ok := sanitized["otlp_metrics_headers"]; ok {
+		t.Fatal("expected headers to be removed from sanitized config")
+	}
+
+	_, err = ParseConfig(`
+telemetry {
+	otlp_metrics_endpoint = "otel-collector:4317"
+	otlp_metrics_temporality = "sometimes"
+}`)
+	if err == nil {
+		t.Fatal("expected error with bad temporality")
+	}
+}
+
+func testParseCustomResponseHeaders(t *testing.T) {
+	config, err := ParseConfig(`
+listener "tcp" {
+	address = "127.0.0.1:8200"
+	custom_response_headers {
+		"default" = {
+			"Strict-Transport-Security" = ["max-age=31536000", "includeSubDomains"]
+			"x-frame-options" = "deny"
+		}
+		"4XX" = {
+			"Content-Security-Policy" = ["default-src 'none'"]
+		}
+		"307" = {
+			"X-Custom" = ["redirect"]
+		}
+	}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := ParseCustomResponseHeaders(config.Listeners[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]http.Header{
+		"default": {
+			"Strict-Transport-Security": {"max-age=31536000", "includeSubDomains"},
+			"X-Frame-Options":           {"deny"},
+		},
+		"4xx": {
+			"Content-Security-Policy": {"default-src 'none'"},
+		},
+		"307": {
+			"X-Custom": {"redirect"},
+		},
+	}
+	if diff := deep.Equal(headers, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	for _, bad := range []string{
+		`"6xx" = { "X-Custom" = "foo" }`,
+		`"abc" = { "X-Custom" = "foo" }`,
+		`"default" = { "X-Vault-Index" = "foo" }`,
+		`"default" = { "X-Custom" = 1 }`,
+	} {
+		_, err := ParseConfig(fmt.Sprintf(`
+listener "tcp" {
+	address = "127.0.0.1:8200"
+	custom_response_headers {
+		%s
+	}
+}`, bad))
+		if err == nil {
+			t.Fatalf("expected error parsing %s", bad)
+		}
+	}
+}
+
+func testParseUnauthenticatedAccess(t *testing.T) {
+	config, err := ParseConfig(`
+listener "tcp" {
+	address = "127.0.0.1:8200"
+	unauthenticated_access {
+		health_details = false
+		seal_status_details = "false"
+		in_flight_requests = true
+	}
+}
+
+listener "tcp" {
+	address = "127.0.0.1:8201"
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := ParseUnauthenticatedAccess(config.Listeners[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &UnauthenticatedAccess{
+		HealthDetails:     false,
+		SealStatusDetails: false,
+		UI:                true,
+		InFlightRequests:  true,
+	}
+	if diff := deep.Equal(access, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	access, err = ParseUnauthenticatedAccess(config.Listeners[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(access, DefaultUnauthenticatedAccess()); diff != nil {
+		t.Fatal(diff)
+	}
+
+	for _, bad := range []string{
+		`ui = "maybe"`,
+		`metrics = true`,
+	} {
+		_, err := ParseConfig(fmt.Sprintf(`
+listener "tcp" {
+	address = "127.0.0.1:8200"
+	unauthenticated_access {
+		%s
+	}
+}`, bad))
+		if err == nil {
+			t.Fatalf("expected error parsing %s", bad)
+		}
+	}
+}
+
+func testParseEntropySource(t *testing.T) {
+	cases := map[string]struct {
+		config   string
+		expected *EntropySource
+		wantErr  bool
+	}{
+		"seal": {
+			config: `entropy "seal" {
+				mode = "augmentation"
+			}`,
+			expected: &EntropySource{Type: "seal", Config: map[string]string{}},
+		},
+		"device without mode": {
+			config: `entropy "device" {
+				path = "/dev/hwrng"
+			}`,
+			expected: &EntropySource{Type: "device", Config: map[string]string{"path": "/dev/hwrng"}},
+		},
+		"egd": {
+			config: `entropy "egd" {
+				mode = "augmentation"
+				address = "unix:///var/run/egd-pool"
+			}`,
+			expected: &EntropySource{Type: "egd", Config: map[string]string{"address": "unix:///var/run/egd-pool"}},
+		},
+		"unsupported mode": {
+			config: `entropy "seal" {
+				mode = "a_mode_that_is_not_supported"
+			}`,
+			wantErr: true,
+		},
+		"unsupported type": {
+			config: `entropy "device_that_is_not_supported" {
+				mode = "augmentation"
+			}`,
+			wantErr: true,
+		},
+		"multiple blocks": {
+			config: `entropy "seal" {}
+			entropy "device" {}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config, err := ParseConfig(tc.config)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(config.EntropySource, tc.expected); diff != nil {
+				t.Fatal(diff)
+			}
+			if config.Entropy == nil || config.Entropy.Mode != configutil.EntropyAugmentation {
+				t.Fatalf("bad entropy mode: %#v", config.Entropy)
+			}
+		})
+	}
+}
+
+func testParseTelemetryFilter(t *testing.T) {
+	config, err := ParseConfig(`
+telemetry {
+	prefix_filter = ["+vault.core", "-vault.core.request"]
+	filter_default = false
+	blocked_labels = ["mount_point"]
+}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &TelemetryFilter{
+		PrefixFilter:  []string{"+vault.core", "-vault.core.request"},
+		FilterDefault: false,
+		BlockedLabels: []string{"mount_point"},
+	}
+	if diff := deep.Equal(config.TelemetryFilter, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Metrics are allowed by default
+	config, err = ParseConfig(`
+telemetry {
+	blocked_labels = ["mount_point"]
+}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !config.TelemetryFilter.FilterDefault {
+		t.Fatal("expected filter_default to default to true")
+	}
+
+	_, err = ParseConfig(`
+telemetry {
+	prefix_filter = ["vault.core"]
+}`)
+	if err == nil {
+		t.Fatal("expected error with a prefix missing '+' or '-'")
+	}
+}
+
+func testParseTelemetrySinks(t *testing.T) {
+	config, err := ParseConfig(`
+telemetry {
+	statsd_address = "statsd:8125"
+
+	sink "statsd" {
+		address = "statsd-b:8125"
+		metrics_prefix = "vault-b"
+		prefix_filter = "+vault-b.core,-vault-b.core.request"
+	}
+
+	sink "file" {
+		path = "/var/log/vault-metrics.log"
+		blocked_labels = "mount_point"
+	}
+}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.Telemetry.StatsdAddr != "statsd:8125" {
+		t.Fatalf("bad statsd address: %q", config.Telemetry.StatsdAddr)
+	}
+
+	expected := []*TelemetrySink{
+		{
+			Type: "statsd",
+			Config: map[string]string{
+				"address":        "statsd-b:8125",
+				"metrics_prefix": "vault-b",
+				"prefix_filter":  "+vault-b.core,-vault-b.core.request",
+			},
+		},
+		{
+			Type: "file",
+			Config: map[string]string{
+				"path":           "/var/log/vault-metrics.log",
+				"blocked_labels": "mount_point",
+			},
+		},
+	}
+	if diff := deep.Equal(config.TelemetrySinks, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	for name, hcl := range map[string]string{
+		"unknown type":    `telemetry { sink "carrier-pigeon" {} }`,
+		"missing address": `telemetry { sink "statsd" {} }`,
+		"bad prefix rule": `telemetry { sink "file" { path = "/tmp/m" prefix_filter = "vault" } }`,
+	} {
+		if _, err := ParseConfig(hcl); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func testParseMetricsRollingWindows(t *testing.T) {
+	config, err := ParseConfig(`
+telemetry {
+	rolling_windows = ["30s", "10m"]
+}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []time.Duration{30 * time.Second, 10 * time.Minute}
+	if diff := deep.Equal(config.MetricsRollingWindows, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	_, err = ParseConfig(`
+telemetry {
+	rolling_windows = ["soon"]
+}`)
+	if err == nil {
+		t.Fatal("expected error with an invalid window")
+	}
+}
+
+func testParseRuntimeMetricsInterval(t *testing.T) {
+	for raw, expected := range map[string]time.Duration{
+		`"30s"`:  30 * time.Second,
+		`"none"`: -1,
+	} {
+		config, err := ParseConfig(`
+telemetry {
+	runtime_metrics_interval = ` + raw + `
+}`)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if config.RuntimeMetricsInterval != expected {
+			t.Fatalf("bad interval for %s: %s", raw, config.RuntimeMetricsInterval)
+		}
+	}
+
+	_, err := ParseConfig(`
+telemetry {
+	runtime_metrics_interval = "0s"
+}`)
+	if err == nil {
+		t.Fatal("expected error with a zero interval")
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/shared-secure-libs/configutil"
+)
+
+// CustomResponseHeadersDefault is the key of the custom response headers
+// added to the responses of any status.
+const CustomResponseHeadersDefault = "default"
+
+// ParseCustomResponseHeaders parses the custom_response_headers block of a
+// listener stanza. The headers are keyed by status code ("307"), status class
+// ("4xx") or "default":
+//
+//	custom_response_headers {
+//	  "default" = {
+//	    "Strict-Transport-Security" = ["max-age=31536000", "includeSubDomains"]
+//	  }
+//	  "4xx" = {
+//	    "Content-Security-Policy" = ["default-src 'none'"]
+//	  }
+//	}
+func ParseCustomResponseHeaders(l *configutil.Listener) (map[string]http.Header, error) {
+	raw, ok := l.RawConfig["custom_response_headers"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	statuses, err := flattenRawObject(raw)
+	if err != nil {
+		return nil, fmt.Errorf("custom_response_headers: %w", err)
+	}
+
+	result := make(map[string]http.Header, len(statuses))
+	for status, rawHeaders := range statuses {
+		if err := validateCustomResponseHeadersStatus(status); err != nil {
+			return nil, fmt.Errorf("custom_response_headers: %w", err)
+		}
+
+		headers, err := flattenRawObject(rawHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("custom_response_headers.%s: %w", status, err)
+		}
+
+		h := make(http.Header, len(headers))
+		for name, rawValues := range headers {
+			name = textproto.CanonicalMIMEHeaderKey(name)
+			if strings.HasPrefix(name, "X-Vault-") {
+				return nil, fmt.Errorf("custom_response_headers.%s: header %q can't be set, X-Vault- headers are reserved", status, name)
+			}
+
+			values, err := rawHeaderValues(rawValues)
+			if err != nil {
+				return nil, fmt.Errorf("custom_response_headers.%s.%s: %w", status, name, err)
+			}
+			h[name] = values
+		}
+		result[strings.ToLower(status)] = h
+	}
+
+	return result, nil
+}
+
+func validateCustomResponseHeadersStatus(status string) error {
+	status = strings.ToLower(status)
+	switch {
+	case status == CustomResponseHeadersDefault:
+		return nil
+	case len(status) == 3 && strings.HasSuffix(status, "xx") && status[0] >= '1' && status[0] <= '5':
+		return nil
+	}
+
+	if code, err := strconv.Atoi(status); err == nil && code >= 100 && code <= 599 {
+		return nil
+	}
+	return fmt.Errorf("invalid status %q, expected a status code, a status class like \"4xx\", or %q", status, CustomResponseHeadersDefault)
+}
+
+// flattenRawObject returns the object decoded from the configuration, which
+// HCL represents as a list of objects.
+func flattenRawObject(raw interface{}) (map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		result := make(map[string]interface{})
+		for _, m := range v {
+			for k, val := range m {
+				result[k] = val
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := make(map[string]interface{})
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected an object, got %T", item)
+			}
+			for k, val := range m {
+				result[k] = val
+			}
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected an object, got %T", raw)
+	}
+}
+
+func rawHeaderValues(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", item)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("expected a string or a list of strings, got %T", raw)
+	}
+}
@@ -6,6 +6,10 @@ ui = true
 api_addr = "top_level_api_addr"
 cluster_addr = "top_level_cluster_addr"
 
+drain_timeout = "30s"
+request_forwarding_compression = true
+request_forwarding_connections = 4
+
 listener "tcp" {
   address = "127.0.0.1:443"
 }
@@ -0,0 +1,17 @@
+storage "inmem" {}
+
+telemetry {
+	disable_hostname = true
+	otlp_metrics_endpoint = "otel-collector:4317"
+	otlp_metrics_insecure = true
+	otlp_metrics_temporality = "delta"
+	otlp_metrics_export_interval = "30s"
+
+	otlp_metrics_headers = {
+		"x-api-key" = "secret"
+	}
+
+	otlp_metrics_resource_attributes = {
+		"deployment.environment" = "production"
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/shared-secure-libs/configutil"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+)
+
+// UnauthenticatedAccess controls what a listener exposes to the clients
+// without a valid token.
+type UnauthenticatedAccess struct {
+	// HealthDetails includes the version, cluster and replication details in
+	// the sys/health responses
+	HealthDetails bool
+
+	// SealStatusDetails includes the version, cluster and storage details in
+	// the sys/seal-status responses
+	SealStatusDetails bool
+
+	// UI serves the web UI on the listener
+	UI bool
+
+	// InFlightRequests allows reading sys/in-flight-req without a token
+	InFlightRequests bool
+}
+
+// DefaultUnauthenticatedAccess returns the unauthenticated access of the
+// listeners without an unauthenticated_access block.
+func DefaultUnauthenticatedAccess() *UnauthenticatedAccess {
+	return &UnauthenticatedAccess{
+		HealthDetails:     true,
+		SealStatusDetails: true,
+		UI:                true,
+	}
+}
+
+// ParseUnauthenticatedAccess parses the unauthenticated_access block of a
+// listener stanza:
+//
+//	unauthenticated_access {
+//	  health_details      = false
+//	  seal_status_details = false
+//	  ui                  = false
+//	  in_flight_requests  = true
+//	}
+func ParseUnauthenticatedAccess(l *configutil.Listener) (*UnauthenticatedAccess, error) {
+	result := DefaultUnauthenticatedAccess()
+
+	raw, ok := l.RawConfig["unauthenticated_access"]
+	if !ok || raw == nil {
+		return result, nil
+	}
+
+	settings, err := flattenRawObject(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unauthenticated_access: %w", err)
+	}
+
+	for key, rawValue := range settings {
+		var field *bool
+		switch key {
+		case "health_details":
+			field = &result.HealthDetails
+		case "seal_status_details":
+			field = &result.SealStatusDetails
+		case "ui":
+			field = &result.UI
+		case "in_flight_requests":
+			field = &result.InFlightRequests
+		default:
+			return nil, fmt.Errorf("unauthenticated_access: invalid key %q", key)
+		}
+
+		value, err := parseutil.ParseBool(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("unauthenticated_access.%s: %w", key, err)
+		}
+		*field = value
+	}
+
+	return result, nil
+}
@@ -1,8 +1,16 @@
 package command
 
 import (
+	"fmt"
+
+	log "github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/shared-secure-libs/configutil"
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/vault"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+	"github.com/hashicorp/vault/vault/seal/azurekeyvault"
+	"github.com/hashicorp/vault/vault/seal/pkcs11"
 )
 
 var (
@@ -11,3 +19,96 @@ var (
 
 func adjustCoreConfigForEntNoop(config *server.Config, coreConfig *vault.CoreConfig) {
 }
+
+// configureSealWrapper creates the wrapper of a seal. Azure Key Vault seals use
+// Vault's own wrapper, which supports pinning the version of the key, as do
+// PKCS#11 seals; the other seals are created by configutil.
+func configureSealWrapper(configSeal *configutil.KMS, infoKeys *[]string, info *map[string]string, logger log.Logger) (wrapping.Wrapper, error) {
+	var wrapper wrapping.Wrapper
+	var kmsInfo map[string]string
+	var err error
+
+	switch configSeal.Type {
+	case wrapping.AzureKeyVault:
+		wrapper, kmsInfo, err = configureAzureKeyVaultWrapper(configSeal, logger)
+	case wrapping.PKCS11:
+		wrapper, kmsInfo, err = configurePKCS11Wrapper(configSeal, logger)
+	default:
+		return configutil.ConfigureWrapper(configSeal, infoKeys, info, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if infoKeys != nil && info != nil {
+		for k, v := range kmsInfo {
+			*infoKeys = append(*infoKeys, k)
+			(*info)[k] = v
+		}
+	}
+
+	return wrapper, nil
+}
+
+func configureAzureKeyVaultWrapper(configSeal *configutil.KMS, logger log.Logger) (wrapping.Wrapper, map[string]string, error) {
+	wrapper := azurekeyvault.NewWrapper(&wrapping.WrapperOptions{
+		Logger: logger,
+	})
+	wrapperInfo, err := wrapper.SetConfig(configSeal.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kmsInfo := map[string]string{
+		"Azure Environment": wrapperInfo["environment"],
+		"Azure Vault Name":  wrapperInfo["vault_name"],
+		"Azure Key Name":    wrapperInfo["key_name"],
+	}
+	if wrapperInfo["key_version"] != "" {
+		kmsInfo["Azure Key Version"] = wrapperInfo["key_version"]
+	}
+
+	return wrapper, kmsInfo, nil
+}
+
+func configurePKCS11Wrapper(configSeal *configutil.KMS, logger log.Logger) (wrapping.Wrapper, map[string]string, error) {
+	wrapper := pkcs11.NewWrapper(&wrapping.WrapperOptions{
+		Logger: logger,
+	})
+	wrapperInfo, err := wrapper.SetConfig(configSeal.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kmsInfo := map[string]string{
+		"PKCS#11 Library":   wrapperInfo["lib"],
+		"PKCS#11 Key Label": wrapperInfo["key_label"],
+		"PKCS#11 Mechanism": wrapperInfo["mechanism"],
+	}
+	if wrapperInfo["slot"] != "" {
+		kmsInfo["PKCS#11 Slot"] = wrapperInfo["slot"]
+	} else {
+		kmsInfo["PKCS#11 Token Label"] = wrapperInfo["token_label"]
+	}
+
+	return wrapper, kmsInfo, nil
+}
+
+// sealFactory creates the seals that Vault migrates to while unsealed.
+func (c *ServerCommand) sealFactory(sealType string, config map[string]string) (vault.Seal, error) {
+	sealLogger := c.logger.ResetNamed(fmt.Sprintf("seal.%s", sealType))
+	wrapper, err := configureSealWrapper(&configutil.KMS{
+		Type:   sealType,
+		Config: config,
+	}, nil, nil, sealLogger)
+	if err != nil {
+		return nil, err
+	}
+	if wrapper == nil {
+		return nil, fmt.Errorf("seal type %q can't be migrated to while unsealed", sealType)
+	}
+
+	return vault.NewAutoSeal(&vaultseal.Access{
+		Wrapper: wrapper,
+	}), nil
+}
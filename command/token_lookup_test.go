@@ -100,6 +100,31 @@ func TestTokenLookupCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("field", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		token, accessor := testTokenAndAccessor(t, client)
+
+		ui, cmd := testTokenLookupCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-field", "accessor",
+			token,
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if strings.TrimSpace(combined) != accessor {
+			t.Errorf("expected %q to be %q", combined, accessor)
+		}
+	})
+
 	t.Run("self", func(t *testing.T) {
 		t.Parallel()
 
@@ -44,6 +44,8 @@ import (
 	logicalAws "github.com/hashicorp/vault/builtin/logical/aws"
 	logicalCass "github.com/hashicorp/vault/builtin/logical/cassandra"
 	logicalConsul "github.com/hashicorp/vault/builtin/logical/consul"
+	logicalKmip "github.com/hashicorp/vault/builtin/logical/kmip"
+	logicalKubernetes "github.com/hashicorp/vault/builtin/logical/kubernetes"
 	logicalMongo "github.com/hashicorp/vault/builtin/logical/mongodb"
 	logicalMssql "github.com/hashicorp/vault/builtin/logical/mssql"
 	logicalMysql "github.com/hashicorp/vault/builtin/logical/mysql"
@@ -52,7 +54,9 @@ import (
 	logicalPostgres "github.com/hashicorp/vault/builtin/logical/postgresql"
 	logicalRabbit "github.com/hashicorp/vault/builtin/logical/rabbitmq"
 	logicalSsh "github.com/hashicorp/vault/builtin/logical/ssh"
+	logicalTerraform "github.com/hashicorp/vault/builtin/logical/terraform"
 	logicalTotp "github.com/hashicorp/vault/builtin/logical/totp"
+	logicalTransform "github.com/hashicorp/vault/builtin/logical/transform"
 	logicalTransit "github.com/hashicorp/vault/builtin/logical/transit"
 )
 
@@ -118,6 +122,8 @@ func newRegistry() *registry {
 			"consul":       logicalConsul.Factory,
 			"gcp":          logicalGcp.Factory,
 			"gcpkms":       logicalGcpKms.Factory,
+			"kmip":         logicalKmip.Factory,
+			"kubernetes":   logicalKubernetes.Factory,
 			"kv":           logicalKv.Factory,
 			"mongodb":      logicalMongo.Factory, // Deprecated
 			"mongodbatlas": logicalMongoAtlas.Factory,
@@ -129,7 +135,9 @@ func newRegistry() *registry {
 			"postgresql":   logicalPostgres.Factory, // Deprecated
 			"rabbitmq":     logicalRabbit.Factory,
 			"ssh":          logicalSsh.Factory,
+			"terraform":    logicalTerraform.Factory,
 			"totp":         logicalTotp.Factory,
+			"transform":    logicalTransform.Factory,
 			"transit":      logicalTransit.Factory,
 		},
 	}
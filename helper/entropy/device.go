@@ -0,0 +1,66 @@
+package entropy
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// DeviceSource reads random bytes from a device or a file, e.g. the hardware
+// random number generator of a TPM exposed as /dev/hwrng, or a named pipe
+// written to by an entropy daemon.
+type DeviceSource struct {
+	path string
+
+	l    sync.Mutex
+	file *os.File
+}
+
+// NewDeviceSource opens the device at the given path.
+func NewDeviceSource(path string) (*DeviceSource, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errwrap.Wrapf("error opening entropy device: {{err}}", err)
+	}
+
+	return &DeviceSource{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// GetRandom reads the given number of bytes from the device.
+func (d *DeviceSource) GetRandom(bytes int) ([]byte, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if d.file == nil {
+		return nil, errors.New("entropy device is closed")
+	}
+
+	buf := make([]byte, bytes)
+	if _, err := io.ReadFull(d.file, buf); err != nil {
+		return nil, errwrap.Wrapf("error reading from entropy device: {{err}}", err)
+	}
+	return buf, nil
+}
+
+// Close closes the device.
+func (d *DeviceSource) Close() error {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
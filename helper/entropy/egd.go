@@ -0,0 +1,85 @@
+package entropy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// egdReadBlocking is the EGD command to read bytes, blocking until enough
+	// entropy is available.
+	egdReadBlocking = 0x02
+
+	// egdMaxRead is the maximum number of bytes read by a single command.
+	egdMaxRead = 255
+
+	egdTimeout = 10 * time.Second
+)
+
+// EGDSource reads random bytes from a daemon speaking the Entropy Gathering
+// Daemon protocol, such as egd or prngd, over a Unix or TCP socket.
+type EGDSource struct {
+	network string
+	address string
+}
+
+// NewEGDSource creates a source for the daemon at the given address. Addresses
+// starting with "unix://" or containing no port are Unix sockets; the others
+// are TCP addresses.
+func NewEGDSource(address string) (*EGDSource, error) {
+	if address == "" {
+		return nil, errors.New("address is required")
+	}
+
+	e := &EGDSource{
+		network: "unix",
+		address: strings.TrimPrefix(address, "unix://"),
+	}
+	if !strings.HasPrefix(address, "unix://") {
+		if _, _, err := net.SplitHostPort(address); err == nil {
+			e.network = "tcp"
+		}
+	}
+
+	return e, nil
+}
+
+// GetRandom reads the given number of bytes from the daemon.
+func (e *EGDSource) GetRandom(bytes int) ([]byte, error) {
+	conn, err := net.DialTimeout(e.network, e.address, egdTimeout)
+	if err != nil {
+		return nil, errwrap.Wrapf("error connecting to entropy daemon: {{err}}", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(egdTimeout)); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, bytes)
+	for read := 0; read < bytes; {
+		n := bytes - read
+		if n > egdMaxRead {
+			n = egdMaxRead
+		}
+		if _, err := conn.Write([]byte{egdReadBlocking, byte(n)}); err != nil {
+			return nil, errwrap.Wrapf("error writing to entropy daemon: {{err}}", err)
+		}
+		if _, err := io.ReadFull(conn, buf[read:read+n]); err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("error reading %d bytes from entropy daemon: {{err}}", n), err)
+		}
+		read += n
+	}
+
+	return buf, nil
+}
+
+// Close is a no-op, since a connection is opened for each read.
+func (e *EGDSource) Close() error {
+	return nil
+}
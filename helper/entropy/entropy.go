@@ -0,0 +1,101 @@
+// Package entropy provides the external sources of entropy that Vault mixes
+// into the key material it generates.
+package entropy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/errwrap"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-kms-wrapping/entropy"
+	"github.com/hashicorp/vault/helper/xor"
+)
+
+const (
+	SourceTypeSeal   = "seal"
+	SourceTypeDevice = "device"
+	SourceTypeEGD    = "egd"
+)
+
+// Source is an external source of entropy, such as an HSM, a TPM, or an
+// entropy daemon.
+type Source interface {
+	entropy.Sourcer
+	io.Closer
+}
+
+// NewSource creates the external source of entropy of the given type. Seal
+// sources use the seal's wrapper, which must be able to generate random bytes.
+func NewSource(sourceType string, config map[string]string, seal wrapping.Wrapper) (Source, error) {
+	switch sourceType {
+	case SourceTypeSeal:
+		if seal == nil {
+			return nil, fmt.Errorf("no seal is configured to provide entropy")
+		}
+		sourcer, ok := seal.(entropy.Sourcer)
+		if !ok {
+			return nil, fmt.Errorf("seal type %q can't provide entropy", seal.Type())
+		}
+		return nopCloser{sourcer}, nil
+	case SourceTypeDevice:
+		return NewDeviceSource(config["path"])
+	case SourceTypeEGD:
+		return NewEGDSource(config["address"])
+	default:
+		return nil, fmt.Errorf("unknown entropy source type %q", sourceType)
+	}
+}
+
+type nopCloser struct {
+	entropy.Sourcer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// MixedSourcer is a Sourcer that XORs the random bytes of an external source
+// with bytes from crypto/rand. As long as the sources are independent, the
+// output is at least as unpredictable as the best of them, so a broken or
+// compromised external source never weakens the key material.
+type MixedSourcer struct {
+	source entropy.Sourcer
+}
+
+// Ensure that we are implementing Sourcer
+var _ entropy.Sourcer = (*MixedSourcer)(nil)
+
+// NewMixedSourcer creates a Sourcer that mixes the given external source with
+// crypto/rand.
+func NewMixedSourcer(source entropy.Sourcer) *MixedSourcer {
+	return &MixedSourcer{
+		source: source,
+	}
+}
+
+// GetRandom returns the given number of random bytes. It fails if the external
+// source can't provide them, rather than silently using crypto/rand alone.
+func (m *MixedSourcer) GetRandom(bytes int) ([]byte, error) {
+	external, err := m.source.GetRandom(bytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading from external entropy source: {{err}}", err)
+	}
+	if len(external) != bytes {
+		return nil, fmt.Errorf("external entropy source returned %d bytes instead of %d", len(external), bytes)
+	}
+
+	local := make([]byte, bytes)
+	if _, err := io.ReadFull(rand.Reader, local); err != nil {
+		return nil, err
+	}
+
+	return xor.XORBytes(local, external)
+}
+
+// NewMixedReader creates a reader that mixes the given external source with
+// crypto/rand.
+func NewMixedReader(source entropy.Sourcer) io.Reader {
+	return entropy.NewReader(NewMixedSourcer(source))
+}
@@ -0,0 +1,201 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testSourcer struct {
+	err  error
+	fill byte
+}
+
+func (s *testSourcer) GetRandom(n int) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return bytes.Repeat([]byte{s.fill}, n), nil
+}
+
+func TestMixedSourcer(t *testing.T) {
+	m := NewMixedSourcer(&testSourcer{})
+
+	// With an all-zero external source the output is crypto/rand alone, so
+	// two reads must differ.
+	a, err := m.GetRandom(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.GetRandom(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 32 || len(b) != 32 {
+		t.Fatalf("bad lengths: %d, %d", len(a), len(b))
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("expected different random bytes")
+	}
+
+	// A failing external source must fail the read.
+	m = NewMixedSourcer(&testSourcer{err: errors.New("broken")})
+	if _, err := m.GetRandom(32); err == nil {
+		t.Fatal("expected error")
+	}
+
+	r := NewMixedReader(&testSourcer{fill: 0xff})
+	buf := make([]byte, 64)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeviceSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "entropy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hwrng")
+	data := []byte("0123456789abcdef")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDeviceSource(""); err == nil {
+		t.Fatal("expected error without a path")
+	}
+
+	d, err := NewDeviceSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := d.GetRandom(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data[:10]) {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// Only 6 bytes are left
+	if _, err := d.GetRandom(10); err == nil {
+		t.Fatal("expected error on short read")
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.GetRandom(1); err == nil {
+		t.Fatal("expected error after close")
+	}
+}
+
+func TestEGDSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "entropy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "egd.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A fake daemon answering blocking reads with a counter
+	go func() {
+		var counter byte
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				cmd := make([]byte, 2)
+				for {
+					if _, err := conn.Read(cmd); err != nil {
+						return
+					}
+					if cmd[0] != egdReadBlocking {
+						return
+					}
+					resp := make([]byte, cmd[1])
+					for i := range resp {
+						resp[i] = counter
+						counter++
+					}
+					if _, err := conn.Write(resp); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	if _, err := NewEGDSource(""); err == nil {
+		t.Fatal("expected error without an address")
+	}
+
+	e, err := NewEGDSource("unix://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.network != "unix" || e.address != path {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// More than a single command can read
+	out, err := e.GetRandom(600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 600 {
+		t.Fatalf("bad length: %d", len(out))
+	}
+	for i := range out {
+		if out[i] != byte(i) {
+			t.Fatalf("bad byte %d: %d", i, out[i])
+		}
+	}
+
+	tcp, err := NewEGDSource("127.0.0.1:8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcp.network != "tcp" {
+		t.Fatalf("bad: %#v", tcp)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	if _, err := NewSource(SourceTypeSeal, nil, nil); err == nil {
+		t.Fatal("expected error without a seal")
+	}
+	if _, err := NewSource(SourceTypeDevice, map[string]string{}, nil); err == nil {
+		t.Fatal("expected error without a path")
+	}
+	if _, err := NewSource(SourceTypeEGD, map[string]string{}, nil); err == nil {
+		t.Fatal("expected error without an address")
+	}
+	if _, err := NewSource("bogus", nil, nil); err == nil {
+		t.Fatal("expected error for an unknown type")
+	}
+	s, err := NewSource(SourceTypeEGD, map[string]string{"address": "/tmp/egd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package fairshare runs jobs on a bounded pool of workers. Jobs are kept in
+// named queues which are served in turn, so that a queue filling up with jobs
+// can't starve the other queues.
+package fairshare
+
+import (
+	"container/list"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+)
+
+// Job is a unit of work run by the JobManager
+type Job interface {
+	// Execute runs the job
+	Execute() error
+
+	// OnFailure is called with the error returned by Execute, if any
+	OnFailure(err error)
+}
+
+// JobManager dispatches the jobs of its queues to its workers. The queues are
+// served round robin, one job at a time. Jobs are only handed to a worker once
+// one is free, the others wait in their queue.
+type JobManager struct {
+	name       string
+	numWorkers int
+	logger     log.Logger
+
+	l           sync.Mutex
+	queues      map[string]*list.List
+	queuesIndex []string
+	lastQueue   int
+	totalJobs   int
+
+	newJobCh chan struct{}
+	workCh   chan Job
+	quitCh   chan struct{}
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewJobManager creates a JobManager running its jobs on numWorkers workers
+func NewJobManager(name string, numWorkers int, logger log.Logger) *JobManager {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
+	return &JobManager{
+		name:       name,
+		numWorkers: numWorkers,
+		logger:     logger,
+		queues:     make(map[string]*list.List),
+		lastQueue:  -1,
+		newJobCh:   make(chan struct{}, 1),
+		workCh:     make(chan Job),
+		quitCh:     make(chan struct{}),
+	}
+}
+
+// Start starts the workers and the dispatcher
+func (j *JobManager) Start() {
+	j.startOnce.Do(func() {
+		for i := 0; i < j.numWorkers; i++ {
+			go j.work()
+		}
+
+		go j.dispatch()
+	})
+}
+
+// Stop stops the dispatcher and the workers, which exit once their running
+// job is done. Stop doesn't wait for them. The jobs still waiting in the
+// queues are dropped.
+func (j *JobManager) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.quitCh)
+
+		j.l.Lock()
+		defer j.l.Unlock()
+		if j.totalJobs > 0 {
+			j.logger.Debug("dropping pending jobs", "job_manager", j.name, "count", j.totalJobs)
+		}
+		j.queues = make(map[string]*list.List)
+		j.queuesIndex = nil
+		j.lastQueue = -1
+		j.totalJobs = 0
+	})
+}
+
+// AddJob adds the job to the queue named queueID, creating the queue if needed
+func (j *JobManager) AddJob(job Job, queueID string) {
+	j.l.Lock()
+	queue, ok := j.queues[queueID]
+	if !ok {
+		queue = list.New()
+		j.queues[queueID] = queue
+		j.queuesIndex = append(j.queuesIndex, queueID)
+	}
+	queue.PushBack(job)
+	j.totalJobs++
+	j.l.Unlock()
+
+	metrics.IncrCounter([]string{j.name, "job_manager", "queued"}, 1)
+
+	// Wake up the dispatcher, unless it's already been signaled
+	select {
+	case j.newJobCh <- struct{}{}:
+	default:
+	}
+}
+
+// GetPendingJobCount returns the number of jobs waiting in the queues
+func (j *JobManager) GetPendingJobCount() int {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	return j.totalJobs
+}
+
+// GetPendingJobCountByQueue returns the number of jobs waiting in each queue
+func (j *JobManager) GetPendingJobCountByQueue() map[string]int {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	counts := make(map[string]int, len(j.queues))
+	for queueID, queue := range j.queues {
+		counts[queueID] = queue.Len()
+	}
+	return counts
+}
+
+// nextJob pops the next job of the queue following the last queue served.
+// Queues are removed once empty.
+func (j *JobManager) nextJob() Job {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	if j.totalJobs == 0 {
+		return nil
+	}
+
+	i := (j.lastQueue + 1) % len(j.queuesIndex)
+	queueID := j.queuesIndex[i]
+	queue := j.queues[queueID]
+	job := queue.Remove(queue.Front()).(Job)
+	j.totalJobs--
+
+	if queue.Len() == 0 {
+		delete(j.queues, queueID)
+		j.queuesIndex = append(j.queuesIndex[:i], j.queuesIndex[i+1:]...)
+		// The queue following the removed one now sits at its index
+		i--
+	}
+	j.lastQueue = i
+
+	return job
+}
+
+// dispatch hands the jobs to the workers as they become free
+func (j *JobManager) dispatch() {
+	for {
+		job := j.nextJob()
+		if job == nil {
+			select {
+			case <-j.quitCh:
+				return
+			case <-j.newJobCh:
+				continue
+			}
+		}
+
+		select {
+		case <-j.quitCh:
+			return
+		case j.workCh <- job:
+		}
+	}
+}
+
+func (j *JobManager) work() {
+	for {
+		select {
+		case <-j.quitCh:
+			return
+		case job := <-j.workCh:
+			if err := job.Execute(); err != nil {
+				job.OnFailure(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package fairshare
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testJob struct {
+	id        string
+	err       error
+	executed  func(id string)
+	onFailure func(id string, err error)
+}
+
+func (t *testJob) Execute() error {
+	t.executed(t.id)
+	return t.err
+}
+
+func (t *testJob) OnFailure(err error) {
+	t.onFailure(t.id, err)
+}
+
+func TestJobManager_fairQueueing(t *testing.T) {
+	var l sync.Mutex
+	var order []string
+	executed := func(id string) {
+		l.Lock()
+		defer l.Unlock()
+		order = append(order, id)
+	}
+
+	j := NewJobManager("test", 1, nil)
+
+	// Queue all the jobs before starting so the order is deterministic
+	for i := 0; i < 3; i++ {
+		j.AddJob(&testJob{id: fmt.Sprintf("a%d", i), executed: executed}, "a")
+	}
+	j.AddJob(&testJob{id: "b0", executed: executed}, "b")
+	for i := 0; i < 2; i++ {
+		j.AddJob(&testJob{id: fmt.Sprintf("c%d", i), executed: executed}, "c")
+	}
+
+	if count := j.GetPendingJobCount(); count != 6 {
+		t.Fatalf("expected 6 pending jobs, got %d", count)
+	}
+	expectedCounts := map[string]int{"a": 3, "b": 1, "c": 2}
+	if counts := j.GetPendingJobCountByQueue(); !reflect.DeepEqual(counts, expectedCounts) {
+		t.Fatalf("bad: %#v", counts)
+	}
+
+	j.Start()
+	defer j.Stop()
+
+	numExecuted := func() int {
+		l.Lock()
+		defer l.Unlock()
+		return len(order)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for numExecuted() < 6 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the jobs")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	expected := []string{"a0", "b0", "c0", "a1", "c1", "a2"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestJobManager_onFailure(t *testing.T) {
+	failedCh := make(chan error, 1)
+	jobErr := errors.New("failed")
+
+	j := NewJobManager("test", 2, nil)
+	j.Start()
+	defer j.Stop()
+
+	j.AddJob(&testJob{
+		id:       "job",
+		err:      jobErr,
+		executed: func(string) {},
+		onFailure: func(id string, err error) {
+			failedCh <- err
+		},
+	}, "a")
+
+	select {
+	case err := <-failedCh:
+		if err != jobErr {
+			t.Fatalf("bad: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the failure")
+	}
+}
+
+func TestJobManager_boundedWorkers(t *testing.T) {
+	const numWorkers = 3
+
+	var l sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	executed := func(string) {
+		l.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		l.Unlock()
+
+		<-release
+
+		l.Lock()
+		running--
+		l.Unlock()
+	}
+
+	j := NewJobManager("test", numWorkers, nil)
+	j.Start()
+	defer j.Stop()
+
+	for i := 0; i < 10; i++ {
+		j.AddJob(&testJob{id: fmt.Sprintf("%d", i), executed: executed}, fmt.Sprintf("queue-%d", i%2))
+	}
+
+	// Give the workers the time to pick up the jobs
+	time.Sleep(100 * time.Millisecond)
+	if count := j.GetPendingJobCount(); count < 10-numWorkers-1 {
+		t.Fatalf("expected the jobs to wait for a free worker, got %d pending", count)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for j.GetPendingJobCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the jobs")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if maxRunning > numWorkers {
+		t.Fatalf("expected at most %d concurrent jobs, got %d", numWorkers, maxRunning)
+	}
+}
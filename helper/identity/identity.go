@@ -75,13 +75,19 @@ func ToSDKAlias(a *Alias) *logical.Alias {
 		metadata[k] = v
 	}
 
+	customMetadata := make(map[string]string, len(a.CustomMetadata))
+	for k, v := range a.CustomMetadata {
+		customMetadata[k] = v
+	}
+
 	return &logical.Alias{
-		Name:          a.Name,
-		ID:            a.ID,
-		MountAccessor: a.MountAccessor,
-		MountType:     a.MountType,
-		Metadata:      metadata,
-		NamespaceID:   a.NamespaceID,
+		Name:           a.Name,
+		ID:             a.ID,
+		MountAccessor:  a.MountAccessor,
+		MountType:      a.MountType,
+		Metadata:       metadata,
+		CustomMetadata: customMetadata,
+		NamespaceID:    a.NamespaceID,
 	}
 }
 
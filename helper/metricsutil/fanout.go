@@ -0,0 +1,155 @@
+package metricsutil
+
+import (
+	"sync/atomic"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+)
+
+// FanoutSink sends every metric to several sinks. Unlike the go-metrics
+// fanout, a sink that panics doesn't prevent the others from receiving the
+// metric; the failure is logged the first time it happens, and counted.
+type FanoutSink struct {
+	sinks  []*fanoutEntry
+	logger log.Logger
+}
+
+type fanoutEntry struct {
+	name     string
+	sink     metrics.MetricSink
+	failures uint64
+}
+
+var _ metrics.MetricSink = (*FanoutSink)(nil)
+
+// NewFanoutSink returns an empty fanout; sinks are added with AddSink.
+func NewFanoutSink(logger log.Logger) *FanoutSink {
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+	return &FanoutSink{
+		logger: logger,
+	}
+}
+
+// AddSink adds a sink to the fanout. The name identifies the sink in logs. It
+// must not be called once metrics are being sent to the fanout.
+func (f *FanoutSink) AddSink(name string, sink metrics.MetricSink) {
+	f.sinks = append(f.sinks, &fanoutEntry{
+		name: name,
+		sink: sink,
+	})
+}
+
+// Len returns the number of sinks in the fanout.
+func (f *FanoutSink) Len() int {
+	return len(f.sinks)
+}
+
+// Failures returns the number of metrics a sink failed to receive.
+func (f *FanoutSink) Failures(name string) uint64 {
+	for _, e := range f.sinks {
+		if e.name == name {
+			return atomic.LoadUint64(&e.failures)
+		}
+	}
+	return 0
+}
+
+func (f *FanoutSink) each(fn func(metrics.MetricSink)) {
+	for _, e := range f.sinks {
+		f.call(e, fn)
+	}
+}
+
+func (f *FanoutSink) call(e *fanoutEntry, fn func(metrics.MetricSink)) {
+	defer func() {
+		if r := recover(); r != nil {
+			if atomic.AddUint64(&e.failures, 1) == 1 {
+				f.logger.Error("metrics sink failed, further failures will not be logged", "sink", e.name, "error", r)
+			}
+		}
+	}()
+	fn(e.sink)
+}
+
+func (f *FanoutSink) SetGauge(key []string, val float32) {
+	f.each(func(s metrics.MetricSink) { s.SetGauge(key, val) })
+}
+
+func (f *FanoutSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	f.each(func(s metrics.MetricSink) { s.SetGaugeWithLabels(key, val, labels) })
+}
+
+func (f *FanoutSink) EmitKey(key []string, val float32) {
+	f.each(func(s metrics.MetricSink) { s.EmitKey(key, val) })
+}
+
+func (f *FanoutSink) IncrCounter(key []string, val float32) {
+	f.each(func(s metrics.MetricSink) { s.IncrCounter(key, val) })
+}
+
+func (f *FanoutSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	f.each(func(s metrics.MetricSink) { s.IncrCounterWithLabels(key, val, labels) })
+}
+
+func (f *FanoutSink) AddSample(key []string, val float32) {
+	f.each(func(s metrics.MetricSink) { s.AddSample(key, val) })
+}
+
+func (f *FanoutSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	f.each(func(s metrics.MetricSink) { s.AddSampleWithLabels(key, val, labels) })
+}
+
+// PrefixSink prepends a prefix to the keys of the metrics sent to a sink.
+type PrefixSink struct {
+	sink   metrics.MetricSink
+	prefix string
+}
+
+var _ metrics.MetricSink = (*PrefixSink)(nil)
+
+// NewPrefixSink returns a sink that prepends the prefix to keys before
+// forwarding metrics to the given sink.
+func NewPrefixSink(sink metrics.MetricSink, prefix string) *PrefixSink {
+	return &PrefixSink{
+		sink:   sink,
+		prefix: prefix,
+	}
+}
+
+func (p *PrefixSink) key(key []string) []string {
+	if p.prefix == "" {
+		return key
+	}
+	return append([]string{p.prefix}, key...)
+}
+
+func (p *PrefixSink) SetGauge(key []string, val float32) {
+	p.sink.SetGauge(p.key(key), val)
+}
+
+func (p *PrefixSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.sink.SetGaugeWithLabels(p.key(key), val, labels)
+}
+
+func (p *PrefixSink) EmitKey(key []string, val float32) {
+	p.sink.EmitKey(p.key(key), val)
+}
+
+func (p *PrefixSink) IncrCounter(key []string, val float32) {
+	p.sink.IncrCounter(p.key(key), val)
+}
+
+func (p *PrefixSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.sink.IncrCounterWithLabels(p.key(key), val, labels)
+}
+
+func (p *PrefixSink) AddSample(key []string, val float32) {
+	p.sink.AddSample(p.key(key), val)
+}
+
+func (p *PrefixSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.sink.AddSampleWithLabels(p.key(key), val, labels)
+}
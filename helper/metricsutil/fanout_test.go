@@ -0,0 +1,85 @@
+package metricsutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+)
+
+type panicSink struct {
+	metrics.BlackholeSink
+}
+
+func (panicSink) IncrCounterWithLabels([]string, float32, []metrics.Label) {
+	panic("sink failed")
+}
+
+func TestFanoutSink(t *testing.T) {
+	inm := metrics.NewInmemSink(time.Minute, time.Minute)
+
+	fanout := NewFanoutSink(logging.NewVaultLogger(0))
+	fanout.AddSink("failing", &panicSink{})
+	fanout.AddSink("inmem", NewPrefixSink(inm, "vault"))
+
+	labels := []metrics.Label{{Name: "namespace", Value: "root"}}
+	fanout.IncrCounterWithLabels([]string{"core", "request"}, 1, labels)
+	fanout.IncrCounterWithLabels([]string{"core", "request"}, 1, labels)
+
+	c, ok := inm.Data()[0].Counters["vault.core.request;namespace=root"]
+	if !ok {
+		t.Fatalf("expected the counter to reach the other sink, got %v", inm.Data()[0].Counters)
+	}
+	if c.Count != 2 {
+		t.Fatalf("bad count: %d", c.Count)
+	}
+	if failures := fanout.Failures("failing"); failures != 2 {
+		t.Fatalf("expected 2 failures, got %d", failures)
+	}
+	if failures := fanout.Failures("inmem"); failures != 0 {
+		t.Fatalf("expected no failures, got %d", failures)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.SetGaugeWithLabels([]string{"vault", "core", "unsealed"}, 1, []metrics.Label{{Name: "cluster", Value: "c1"}})
+	sink.IncrCounter([]string{"vault", "core", "check_token"}, 1)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []fileSinkEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry fileSinkEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Type != "gauge" || entries[0].Name != "vault.core.unsealed" || entries[0].Labels["cluster"] != "c1" {
+		t.Fatalf("bad entry: %#v", entries[0])
+	}
+	if entries[1].Type != "counter" || entries[1].Name != "vault.core.check_token" || entries[1].Value != 1 {
+		t.Fatalf("bad entry: %#v", entries[1])
+	}
+}
@@ -0,0 +1,96 @@
+package metricsutil
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// FileSink writes each metric to a file as a line of JSON.
+type FileSink struct {
+	l   sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+var _ metrics.MetricSink = (*FileSink)(nil)
+
+type fileSinkEntry struct {
+	Time   time.Time         `json:"time"`
+	Type   string            `json:"type"`
+	Name   string            `json:"name"`
+	Value  float32           `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NewFileSink opens the file at path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{
+		w:   f,
+		enc: json.NewEncoder(f),
+	}, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.w.Close()
+}
+
+func (s *FileSink) write(metricType string, key []string, val float32, labels []metrics.Label) {
+	entry := &fileSinkEntry{
+		Time:  time.Now().UTC(),
+		Type:  metricType,
+		Name:  strings.Join(key, "."),
+		Value: val,
+	}
+	if len(labels) > 0 {
+		entry.Labels = make(map[string]string, len(labels))
+		for _, l := range labels {
+			entry.Labels[l.Name] = l.Value
+		}
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	// Metrics are best effort, so a failed write is dropped
+	s.enc.Encode(entry)
+}
+
+func (s *FileSink) SetGauge(key []string, val float32) {
+	s.write("gauge", key, val, nil)
+}
+
+func (s *FileSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.write("gauge", key, val, labels)
+}
+
+func (s *FileSink) EmitKey(key []string, val float32) {
+	s.write("key", key, val, nil)
+}
+
+func (s *FileSink) IncrCounter(key []string, val float32) {
+	s.write("counter", key, val, nil)
+}
+
+func (s *FileSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.write("counter", key, val, labels)
+}
+
+func (s *FileSink) AddSample(key []string, val float32) {
+	s.write("sample", key, val, nil)
+}
+
+func (s *FileSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.write("sample", key, val, labels)
+}
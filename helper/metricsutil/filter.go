@@ -0,0 +1,179 @@
+package metricsutil
+
+import (
+	"fmt"
+	"strings"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
+)
+
+// MetricsFilter selects the metrics, and the labels of each metric, that
+// reach the sinks.
+type MetricsFilter struct {
+	// PrefixFilter is a list of metric name prefixes, with '.' as the
+	// separator. Prefixes starting with '+' are allowed and prefixes starting
+	// with '-' are blocked; the longest matching prefix wins.
+	PrefixFilter []string
+
+	// FilterDefault is whether metrics that don't match any prefix are
+	// allowed.
+	FilterDefault bool
+
+	// AllowedLabels, when not nil, is the list of the only labels kept on
+	// metrics. BlockedLabels is a list of labels dropped from metrics.
+	AllowedLabels []string
+	BlockedLabels []string
+}
+
+// Prefixes returns the allowed and blocked prefixes of the filter. When
+// metrics are blocked by default, the empty prefix is blocked so that only
+// allowed prefixes match.
+func (f *MetricsFilter) Prefixes() (allow, block []string, err error) {
+	for _, rule := range f.PrefixFilter {
+		switch {
+		case strings.HasPrefix(rule, "+"):
+			allow = append(allow, rule[1:])
+		case strings.HasPrefix(rule, "-"):
+			block = append(block, rule[1:])
+		default:
+			return nil, nil, fmt.Errorf("prefix filter %q must start with '+' or '-'", rule)
+		}
+	}
+	if !f.FilterDefault {
+		block = append(block, "")
+	}
+	return allow, block, nil
+}
+
+// ApplyGlobalFilter filters the metrics sent to the global go-metrics
+// instance wrapped by the cluster metric sink, which covers metrics emitted
+// through either.
+func ApplyGlobalFilter(cms *metricsutil.ClusterMetricSink, filter *MetricsFilter) error {
+	global, ok := cms.Sink.(*metrics.Metrics)
+	if !ok {
+		return fmt.Errorf("unexpected metric sink type %T", cms.Sink)
+	}
+
+	allow, block, err := filter.Prefixes()
+	if err != nil {
+		return err
+	}
+	global.UpdateFilterAndLabels(allow, block, filter.AllowedLabels, filter.BlockedLabels)
+	return nil
+}
+
+// FilterSink applies a MetricsFilter to the metrics sent to a sink. The
+// prefix is the service name prepended to keys when matching them against
+// the filter; keys are passed to the sink unchanged.
+type FilterSink struct {
+	sink          metrics.MetricSink
+	prefix        string
+	rules         map[string]bool
+	allowedLabels map[string]bool
+	blockedLabels map[string]bool
+}
+
+var _ metrics.MetricSink = (*FilterSink)(nil)
+
+// NewFilterSink returns a sink that forwards the metrics allowed by the filter
+// to the given sink.
+func NewFilterSink(sink metrics.MetricSink, prefix string, filter *MetricsFilter) (*FilterSink, error) {
+	allow, block, err := filter.Prefixes()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FilterSink{
+		sink:          sink,
+		prefix:        prefix,
+		rules:         make(map[string]bool),
+		blockedLabels: make(map[string]bool),
+	}
+	for _, p := range allow {
+		s.rules[p] = true
+	}
+	for _, p := range block {
+		s.rules[p] = false
+	}
+	if filter.AllowedLabels != nil {
+		s.allowedLabels = make(map[string]bool)
+		for _, l := range filter.AllowedLabels {
+			s.allowedLabels[l] = true
+		}
+	}
+	for _, l := range filter.BlockedLabels {
+		s.blockedLabels[l] = true
+	}
+
+	return s, nil
+}
+
+func (s *FilterSink) allowMetric(key []string) bool {
+	name := strings.Join(key, ".")
+	if s.prefix != "" {
+		name = s.prefix + "." + name
+	}
+
+	// The longest matching prefix wins
+	allowed, longest := true, -1
+	for p, allow := range s.rules {
+		if len(p) > longest && strings.HasPrefix(name, p) {
+			allowed, longest = allow, len(p)
+		}
+	}
+	return allowed
+}
+
+func (s *FilterSink) filterLabels(labels []metrics.Label) []metrics.Label {
+	if labels == nil {
+		return nil
+	}
+	ret := make([]metrics.Label, 0, len(labels))
+	for _, l := range labels {
+		if s.blockedLabels[l.Name] {
+			continue
+		}
+		if s.allowedLabels != nil && !s.allowedLabels[l.Name] {
+			continue
+		}
+		ret = append(ret, l)
+	}
+	return ret
+}
+
+func (s *FilterSink) SetGauge(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *FilterSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	if s.allowMetric(key) {
+		s.sink.SetGaugeWithLabels(key, val, s.filterLabels(labels))
+	}
+}
+
+func (s *FilterSink) EmitKey(key []string, val float32) {
+	if s.allowMetric(key) {
+		s.sink.EmitKey(key, val)
+	}
+}
+
+func (s *FilterSink) IncrCounter(key []string, val float32) {
+	s.IncrCounterWithLabels(key, val, nil)
+}
+
+func (s *FilterSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	if s.allowMetric(key) {
+		s.sink.IncrCounterWithLabels(key, val, s.filterLabels(labels))
+	}
+}
+
+func (s *FilterSink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+func (s *FilterSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	if s.allowMetric(key) {
+		s.sink.AddSampleWithLabels(key, val, s.filterLabels(labels))
+	}
+}
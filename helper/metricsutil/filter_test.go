@@ -0,0 +1,73 @@
+package metricsutil
+
+import (
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestFilterSink(t *testing.T) {
+	inm := metrics.NewInmemSink(time.Minute, time.Minute)
+	sink, err := NewFilterSink(inm, "vault", &MetricsFilter{
+		PrefixFilter:  []string{"+vault.core", "-vault.core.request"},
+		FilterDefault: true,
+		BlockedLabels: []string{"mount_point"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := []metrics.Label{
+		{Name: "namespace", Value: "root"},
+		{Name: "mount_point", Value: "secret/"},
+	}
+	sink.IncrCounterWithLabels([]string{"core", "check_token"}, 1, labels)
+	sink.IncrCounterWithLabels([]string{"core", "request"}, 1, labels)
+	sink.IncrCounterWithLabels([]string{"expire", "revoke"}, 1, labels)
+
+	counters := inm.Data()[0].Counters
+	if len(counters) != 2 {
+		t.Fatalf("expected 2 counters, got %v", counters)
+	}
+	c, ok := counters["core.check_token;namespace=root"]
+	if !ok {
+		t.Fatalf("expected the mount_point label to be dropped, got %v", counters)
+	}
+	if c.Count != 1 {
+		t.Fatalf("bad count: %d", c.Count)
+	}
+	if _, ok := counters["expire.revoke;namespace=root"]; !ok {
+		t.Fatalf("expected metrics not matching a prefix to be allowed, got %v", counters)
+	}
+}
+
+func TestFilterSink_blockByDefault(t *testing.T) {
+	inm := metrics.NewInmemSink(time.Minute, time.Minute)
+	sink, err := NewFilterSink(inm, "vault", &MetricsFilter{
+		PrefixFilter:  []string{"+vault.core"},
+		AllowedLabels: []string{"cluster"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := []metrics.Label{
+		{Name: "cluster", Value: "c1"},
+		{Name: "namespace", Value: "root"},
+	}
+	sink.SetGaugeWithLabels([]string{"core", "unsealed"}, 1, labels)
+	sink.SetGaugeWithLabels([]string{"expire", "num_leases"}, 1, labels)
+
+	gauges := inm.Data()[0].Gauges
+	if len(gauges) != 1 {
+		t.Fatalf("expected 1 gauge, got %v", gauges)
+	}
+	if _, ok := gauges["core.unsealed;cluster=c1"]; !ok {
+		t.Fatalf("expected only the allowed label, got %v", gauges)
+	}
+
+	if _, err := NewFilterSink(inm, "vault", &MetricsFilter{PrefixFilter: []string{"vault"}}); err == nil {
+		t.Fatal("expected error with a prefix missing '+' or '-'")
+	}
+}
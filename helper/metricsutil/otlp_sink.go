@@ -0,0 +1,440 @@
+package metricsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/metricsutil/otlp"
+	"github.com/hashicorp/vault/sdk/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TemporalityCumulative reports counters and histograms as totals since
+	// the sink was started.
+	TemporalityCumulative = "cumulative"
+
+	// TemporalityDelta reports counters and histograms as the change since
+	// the previous export.
+	TemporalityDelta = "delta"
+
+	// otlpScopeName identifies the instrumentation scope of exported metrics.
+	otlpScopeName = "github.com/hashicorp/vault"
+)
+
+// otlpHistogramBounds are the upper bounds, in milliseconds, of the buckets
+// that samples are counted in. Samples are mostly timings, so the bounds
+// cover the range from a millisecond to ten seconds.
+var otlpHistogramBounds = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// OTLPSinkConfig is the configuration of an OTLPSink.
+type OTLPSinkConfig struct {
+	// Endpoint is the address of the OpenTelemetry collector.
+	Endpoint string
+
+	// TLSConfig is used to connect to the collector. If nil, the connection
+	// is not encrypted.
+	TLSConfig *tls.Config
+
+	// Headers are sent with every export request.
+	Headers map[string]string
+
+	// ResourceAttributes are added to the attributes identifying Vault.
+	ResourceAttributes map[string]string
+
+	// Temporality is either TemporalityCumulative, the default, or
+	// TemporalityDelta.
+	Temporality string
+
+	// ExportInterval is how often metrics are pushed to the collector.
+	ExportInterval time.Duration
+
+	// Timeout bounds each export request.
+	Timeout time.Duration
+
+	// Prefix is prepended to the name of every metric.
+	Prefix string
+
+	Logger log.Logger
+}
+
+// OTLPSink is a go-metrics sink that aggregates metrics in memory and
+// periodically pushes them to an OpenTelemetry collector over OTLP/gRPC.
+type OTLPSink struct {
+	config OTLPSinkConfig
+	conn   *grpc.ClientConn
+	client otlp.MetricsServiceClient
+
+	l          sync.Mutex
+	start      time.Time
+	gauges     map[string]*otlpNumber
+	counters   map[string]*otlpNumber
+	histograms map[string]*otlpHistogram
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+type otlpSeries struct {
+	name   string
+	labels []metrics.Label
+}
+
+type otlpNumber struct {
+	otlpSeries
+	value float64
+}
+
+type otlpHistogram struct {
+	otlpSeries
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+var _ metrics.MetricSink = (*OTLPSink)(nil)
+
+// NewOTLPSink creates a sink exporting to the collector at the configured
+// endpoint. Run must be called to start exporting.
+func NewOTLPSink(config *OTLPSinkConfig) (*OTLPSink, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	switch config.Temporality {
+	case "":
+		config.Temporality = TemporalityCumulative
+	case TemporalityCumulative, TemporalityDelta:
+	default:
+		return nil, fmt.Errorf("temporality must be %q or %q", TemporalityCumulative, TemporalityDelta)
+	}
+
+	if config.ExportInterval <= 0 {
+		config.ExportInterval = 10 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Logger == nil {
+		config.Logger = log.NewNullLogger()
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if config.TLSConfig != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(config.TLSConfig))}
+	}
+	conn, err := grpc.Dial(config.Endpoint, opts...)
+	if err != nil {
+		return nil, errwrap.Wrapf("error connecting to OpenTelemetry collector: {{err}}", err)
+	}
+
+	return &OTLPSink{
+		config:     *config,
+		conn:       conn,
+		client:     otlp.NewMetricsServiceClient(conn),
+		start:      time.Now(),
+		gauges:     make(map[string]*otlpNumber),
+		counters:   make(map[string]*otlpNumber),
+		histograms: make(map[string]*otlpHistogram),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}, nil
+}
+
+// Run exports metrics every export interval until Shutdown is called.
+func (s *OTLPSink) Run() {
+	go func() {
+		defer close(s.doneCh)
+
+		ticker := time.NewTicker(s.config.ExportInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Export(context.Background()); err != nil {
+					s.config.Logger.Warn("failed to export metrics", "error", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops exporting, pushes the metrics collected since the last
+// export and closes the connection to the collector.
+func (s *OTLPSink) Shutdown() {
+	close(s.stopCh)
+	<-s.doneCh
+
+	if err := s.Export(context.Background()); err != nil {
+		s.config.Logger.Warn("failed to export metrics", "error", err)
+	}
+	s.conn.Close()
+}
+
+func (s *OTLPSink) SetGauge(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	series := s.series(key, labels)
+	id := series.id()
+	g, ok := s.gauges[id]
+	if !ok {
+		g = &otlpNumber{otlpSeries: series}
+		s.gauges[id] = g
+	}
+	g.value = float64(val)
+}
+
+func (s *OTLPSink) EmitKey(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) IncrCounter(key []string, val float32) {
+	s.IncrCounterWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	series := s.series(key, labels)
+	id := series.id()
+	c, ok := s.counters[id]
+	if !ok {
+		c = &otlpNumber{otlpSeries: series}
+		s.counters[id] = c
+	}
+	c.value += float64(val)
+}
+
+func (s *OTLPSink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	series := s.series(key, labels)
+	id := series.id()
+	h, ok := s.histograms[id]
+	if !ok {
+		h = &otlpHistogram{
+			otlpSeries: series,
+			buckets:    make([]uint64, len(otlpHistogramBounds)+1),
+		}
+		s.histograms[id] = h
+	}
+	h.count++
+	h.sum += float64(val)
+	h.buckets[sort.SearchFloat64s(otlpHistogramBounds, float64(val))]++
+}
+
+// Export pushes the current value of every metric to the collector. With
+// delta temporality, counters and histograms are reset once they have been
+// collected, whether or not the export succeeds.
+func (s *OTLPSink) Export(ctx context.Context) error {
+	req := s.collect(time.Now())
+	if len(req.ResourceMetrics[0].ScopeMetrics[0].Metrics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	if len(s.config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.config.Headers))
+	}
+
+	resp, err := s.client.Export(ctx, req)
+	if err != nil {
+		return errwrap.Wrapf("error exporting metrics: {{err}}", err)
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.RejectedDataPoints > 0 {
+		return fmt.Errorf("collector rejected %d data points: %s", ps.RejectedDataPoints, ps.ErrorMessage)
+	}
+	return nil
+}
+
+// collect builds an export request from the metrics collected so far.
+func (s *OTLPSink) collect(now time.Time) *otlp.ExportMetricsServiceRequest {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	start := uint64(s.start.UnixNano())
+	end := uint64(now.UnixNano())
+	temporality := otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if s.config.Temporality == TemporalityDelta {
+		temporality = otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+
+	var ms []*otlp.Metric
+	for _, id := range sortedIDs(s.gauges) {
+		g := s.gauges[id]
+		ms = append(ms, &otlp.Metric{
+			Name: g.name,
+			Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+				DataPoints: []*otlp.NumberDataPoint{g.dataPoint(0, end)},
+			}},
+		})
+	}
+	for _, id := range sortedIDs(s.counters) {
+		c := s.counters[id]
+		ms = append(ms, &otlp.Metric{
+			Name: c.name,
+			Data: &otlp.Metric_Sum{Sum: &otlp.Sum{
+				DataPoints:             []*otlp.NumberDataPoint{c.dataPoint(start, end)},
+				AggregationTemporality: temporality,
+				IsMonotonic:            true,
+			}},
+		})
+	}
+	for _, id := range sortedIDs(s.histograms) {
+		h := s.histograms[id]
+		ms = append(ms, &otlp.Metric{
+			Name: h.name,
+			Unit: "ms",
+			Data: &otlp.Metric_Histogram{Histogram: &otlp.Histogram{
+				DataPoints: []*otlp.HistogramDataPoint{
+					{
+						Attributes:        otlpAttributes(h.labels),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      end,
+						Count:             h.count,
+						Sum:               h.sum,
+						BucketCounts:      append([]uint64(nil), h.buckets...),
+						ExplicitBounds:    otlpHistogramBounds,
+					},
+				},
+				AggregationTemporality: temporality,
+			}},
+		})
+	}
+
+	if s.config.Temporality == TemporalityDelta {
+		s.start = now
+		s.counters = make(map[string]*otlpNumber)
+		s.histograms = make(map[string]*otlpHistogram)
+	}
+
+	return &otlp.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlp.ResourceMetrics{
+			{
+				Resource: &otlp.Resource{
+					Attributes: s.resourceAttributes(),
+				},
+				ScopeMetrics: []*otlp.ScopeMetrics{
+					{
+						Scope: &otlp.InstrumentationScope{
+							Name: otlpScopeName,
+						},
+						Metrics: ms,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *OTLPSink) resourceAttributes() []*otlp.KeyValue {
+	attrs := []*otlp.KeyValue{
+		otlpStringAttribute("service.name", "vault"),
+		otlpStringAttribute("service.version", version.GetVersion().VersionNumber()),
+	}
+	keys := make([]string, 0, len(s.config.ResourceAttributes))
+	for k := range s.config.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, otlpStringAttribute(k, s.config.ResourceAttributes[k]))
+	}
+	return attrs
+}
+
+// series returns the name and labels a metric is reported with. Labels are
+// copied, since callers may reuse the slice.
+func (s *OTLPSink) series(key []string, labels []metrics.Label) otlpSeries {
+	name := strings.Join(key, ".")
+	if s.config.Prefix != "" {
+		name = s.config.Prefix + "." + name
+	}
+	copied := append([]metrics.Label(nil), labels...)
+	sort.Slice(copied, func(i, j int) bool {
+		return copied[i].Name < copied[j].Name
+	})
+	return otlpSeries{name: name, labels: copied}
+}
+
+// id uniquely identifies a series by its name and labels.
+func (s otlpSeries) id() string {
+	var b strings.Builder
+	b.WriteString(s.name)
+	for _, l := range s.labels {
+		b.WriteString(";")
+		b.WriteString(l.Name)
+		b.WriteString("=")
+		b.WriteString(l.Value)
+	}
+	return b.String()
+}
+
+func (n *otlpNumber) dataPoint(start, end uint64) *otlp.NumberDataPoint {
+	return &otlp.NumberDataPoint{
+		Attributes:        otlpAttributes(n.labels),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      end,
+		Value:             &otlp.NumberDataPoint_AsDouble{AsDouble: n.value},
+	}
+}
+
+func otlpAttributes(labels []metrics.Label) []*otlp.KeyValue {
+	attrs := make([]*otlp.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, otlpStringAttribute(l.Name, l.Value))
+	}
+	return attrs
+}
+
+func otlpStringAttribute(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{
+		Key: key,
+		Value: &otlp.AnyValue{
+			Value: &otlp.AnyValue_StringValue{StringValue: value},
+		},
+	}
+}
+
+// sortedIDs returns the keys of a map of series in order, so that exports
+// are built deterministically.
+func sortedIDs(m interface{}) []string {
+	var ids []string
+	switch m := m.(type) {
+	case map[string]*otlpNumber:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[string]*otlpHistogram:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
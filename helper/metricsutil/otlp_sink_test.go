@@ -0,0 +1,183 @@
+package metricsutil
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/metricsutil/otlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testCollector struct {
+	otlp.UnimplementedMetricsServiceServer
+
+	l        sync.Mutex
+	requests []*otlp.ExportMetricsServiceRequest
+	md       []metadata.MD
+}
+
+func (c *testCollector) Export(ctx context.Context, req *otlp.ExportMetricsServiceRequest) (*otlp.ExportMetricsServiceResponse, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.requests = append(c.requests, req)
+	c.md = append(c.md, md)
+	return &otlp.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *testCollector) last() map[string]*otlp.Metric {
+	c.l.Lock()
+	defer c.l.Unlock()
+	ret := make(map[string]*otlp.Metric)
+	for _, m := range c.requests[len(c.requests)-1].ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		ret[m.Name] = m
+	}
+	return ret
+}
+
+func testOTLPSink(t *testing.T, temporality string) (*OTLPSink, *testCollector) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &testCollector{}
+	server := grpc.NewServer()
+	otlp.RegisterMetricsServiceServer(server, collector)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	sink, err := NewOTLPSink(&OTLPSinkConfig{
+		Endpoint:           ln.Addr().String(),
+		Headers:            map[string]string{"x-api-key": "secret"},
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+		Temporality:        temporality,
+		ExportInterval:     time.Hour,
+		Prefix:             "vault",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Run()
+	t.Cleanup(sink.Shutdown)
+
+	return sink, collector
+}
+
+func TestOTLPSink_cumulative(t *testing.T) {
+	sink, collector := testOTLPSink(t, "")
+
+	labels := []metrics.Label{{Name: "cluster", Value: "test-cluster"}}
+	sink.IncrCounterWithLabels([]string{"core", "request", "error"}, 1, labels)
+	sink.IncrCounterWithLabels([]string{"core", "request", "error"}, 2, labels)
+	sink.SetGaugeWithLabels([]string{"core", "unsealed"}, 1, labels)
+	sink.AddSampleWithLabels([]string{"core", "request"}, 3, labels)
+	sink.AddSampleWithLabels([]string{"core", "request"}, 30000, labels)
+
+	if err := sink.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.l.Lock()
+	if v := collector.md[0].Get("x-api-key"); len(v) != 1 || v[0] != "secret" {
+		t.Fatalf("bad headers: %v", collector.md[0])
+	}
+	resource := collector.requests[0].ResourceMetrics[0].Resource
+	var found bool
+	for _, kv := range resource.Attributes {
+		if kv.Key == "deployment.environment" && kv.Value.GetStringValue() == "test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("resource attribute missing: %v", resource.Attributes)
+	}
+	collector.l.Unlock()
+
+	ms := collector.last()
+	counter := ms["vault.core.request.error"].GetSum()
+	if counter == nil || !counter.IsMonotonic || counter.AggregationTemporality != otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("bad counter: %v", ms["vault.core.request.error"])
+	}
+	if v := counter.DataPoints[0].GetAsDouble(); v != 3 {
+		t.Fatalf("expected counter to be 3, got %v", v)
+	}
+	if attrs := counter.DataPoints[0].Attributes; len(attrs) != 1 || attrs[0].Key != "cluster" || attrs[0].Value.GetStringValue() != "test-cluster" {
+		t.Fatalf("bad attributes: %v", attrs)
+	}
+
+	if v := ms["vault.core.unsealed"].GetGauge().DataPoints[0].GetAsDouble(); v != 1 {
+		t.Fatalf("expected gauge to be 1, got %v", v)
+	}
+
+	histogram := ms["vault.core.request"].GetHistogram().DataPoints[0]
+	if histogram.Count != 2 || histogram.Sum != 30003 {
+		t.Fatalf("bad histogram: %v", histogram)
+	}
+	// 3ms falls in the (2.5, 5] bucket, and 30s in the overflow bucket
+	if histogram.BucketCounts[2] != 1 || histogram.BucketCounts[len(otlpHistogramBounds)] != 1 {
+		t.Fatalf("bad buckets: %v", histogram.BucketCounts)
+	}
+
+	// Cumulative counters keep adding up across exports
+	sink.IncrCounterWithLabels([]string{"core", "request", "error"}, 1, labels)
+	if err := sink.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ms = collector.last()
+	if v := ms["vault.core.request.error"].GetSum().DataPoints[0].GetAsDouble(); v != 4 {
+		t.Fatalf("expected counter to be 4, got %v", v)
+	}
+}
+
+func TestOTLPSink_delta(t *testing.T) {
+	sink, collector := testOTLPSink(t, TemporalityDelta)
+
+	sink.IncrCounter([]string{"token", "creation"}, 2)
+	sink.SetGauge([]string{"core", "unsealed"}, 1)
+	if err := sink.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ms := collector.last()
+	counter := ms["vault.token.creation"].GetSum()
+	if counter.AggregationTemporality != otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		t.Fatalf("bad temporality: %v", counter.AggregationTemporality)
+	}
+	if v := counter.DataPoints[0].GetAsDouble(); v != 2 {
+		t.Fatalf("expected counter to be 2, got %v", v)
+	}
+	firstEnd := counter.DataPoints[0].TimeUnixNano
+
+	// Counters restart from zero after each export, but gauges are kept
+	sink.IncrCounter([]string{"token", "creation"}, 1)
+	if err := sink.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ms = collector.last()
+	point := ms["vault.token.creation"].GetSum().DataPoints[0]
+	if v := point.GetAsDouble(); v != 1 {
+		t.Fatalf("expected counter to be 1, got %v", v)
+	}
+	if point.StartTimeUnixNano != firstEnd {
+		t.Fatalf("expected window to start at the previous export")
+	}
+	if _, ok := ms["vault.core.unsealed"]; !ok {
+		t.Fatal("expected gauge to be exported again")
+	}
+}
+
+func TestOTLPSink_config(t *testing.T) {
+	if _, err := NewOTLPSink(&OTLPSinkConfig{}); err == nil {
+		t.Fatal("expected error without endpoint")
+	}
+	if _, err := NewOTLPSink(&OTLPSinkConfig{Endpoint: "localhost:4317", Temporality: "sometimes"}); err == nil {
+		t.Fatal("expected error with bad temporality")
+	}
+}
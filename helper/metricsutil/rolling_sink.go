@@ -0,0 +1,180 @@
+package metricsutil
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// RollingSinkInterval is the granularity at which the rolling sink aggregates
+// metrics.
+const RollingSinkInterval = 10 * time.Second
+
+// DefaultRollingWindows are the windows metrics are aggregated over when
+// none are configured.
+var DefaultRollingWindows = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// RollingSink keeps metrics in memory for the longest of a set of windows, so
+// that they can be aggregated over any of the windows on request. It's meant
+// for environments without an external metrics stack.
+type RollingSink struct {
+	*metrics.InmemSink
+	windows []time.Duration
+}
+
+// NewRollingSink returns a sink that can aggregate metrics over the given
+// windows, which must be multiples of RollingSinkInterval.
+func NewRollingSink(windows []time.Duration) (*RollingSink, error) {
+	if len(windows) == 0 {
+		windows = DefaultRollingWindows
+	}
+	windows = append([]time.Duration(nil), windows...)
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	for _, w := range windows {
+		if w <= 0 || w%RollingSinkInterval != 0 {
+			return nil, fmt.Errorf("window %s is not a multiple of %s", w, RollingSinkInterval)
+		}
+	}
+
+	return &RollingSink{
+		InmemSink: metrics.NewInmemSink(RollingSinkInterval, windows[len(windows)-1]),
+		windows:   windows,
+	}, nil
+}
+
+// Windows returns the windows metrics can be aggregated over, shortest first.
+func (r *RollingSink) Windows() []time.Duration {
+	return r.windows
+}
+
+// RollingSummary is the aggregation of metrics over a window. Gauges hold
+// their last value, and counters and samples are aggregated over the window.
+type RollingSummary struct {
+	Window   string
+	Start    time.Time
+	End      time.Time
+	Gauges   []metrics.GaugeValue
+	Counters []metrics.SampledValue
+	Samples  []metrics.SampledValue
+}
+
+// Summary aggregates the metrics emitted during the window, which must be
+// one of the windows of the sink.
+func (r *RollingSink) Summary(window time.Duration, now time.Time) (*RollingSummary, error) {
+	var found bool
+	for _, w := range r.windows {
+		if w == window {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown window %s", window)
+	}
+
+	start := now.Add(-window)
+	gauges := make(map[string]metrics.GaugeValue)
+	counters := make(map[string]*metrics.SampledValue)
+	samples := make(map[string]*metrics.SampledValue)
+
+	// Intervals are ordered oldest first, so later gauge values replace the
+	// earlier ones
+	for _, interval := range r.Data() {
+		if !interval.Interval.Add(RollingSinkInterval).After(start) {
+			continue
+		}
+
+		interval.RLock()
+		for k, v := range interval.Gauges {
+			gauges[k] = v
+		}
+		mergeSampledValues(counters, interval.Counters)
+		mergeSampledValues(samples, interval.Samples)
+		interval.RUnlock()
+	}
+
+	summary := &RollingSummary{
+		Window:   window.String(),
+		Start:    start.UTC(),
+		End:      now.UTC(),
+		Gauges:   make([]metrics.GaugeValue, 0, len(gauges)),
+		Counters: sampledValues(counters, window),
+		Samples:  sampledValues(samples, window),
+	}
+	for _, k := range sortedMapKeys(gauges) {
+		v := gauges[k]
+		v.DisplayLabels = displayLabels(v.Labels)
+		summary.Gauges = append(summary.Gauges, v)
+	}
+
+	return summary, nil
+}
+
+func mergeSampledValues(dst map[string]*metrics.SampledValue, src map[string]metrics.SampledValue) {
+	for k, v := range src {
+		if v.AggregateSample == nil {
+			continue
+		}
+		existing, ok := dst[k]
+		if !ok {
+			agg := *v.AggregateSample
+			v.AggregateSample = &agg
+			dst[k] = &v
+			continue
+		}
+
+		agg := existing.AggregateSample
+		if v.Min < agg.Min {
+			agg.Min = v.Min
+		}
+		if v.Max > agg.Max {
+			agg.Max = v.Max
+		}
+		agg.Count += v.Count
+		agg.Sum += v.Sum
+		agg.SumSq += v.SumSq
+		if v.LastUpdated.After(agg.LastUpdated) {
+			agg.LastUpdated = v.LastUpdated
+		}
+	}
+}
+
+func sampledValues(m map[string]*metrics.SampledValue, window time.Duration) []metrics.SampledValue {
+	ret := make([]metrics.SampledValue, 0, len(m))
+	for _, k := range sortedMapKeys(m) {
+		v := *m[k]
+		v.Rate = v.Sum / window.Seconds()
+		v.Mean = v.AggregateSample.Mean()
+		v.Stddev = v.AggregateSample.Stddev()
+		v.DisplayLabels = displayLabels(v.Labels)
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func displayLabels(labels []metrics.Label) map[string]string {
+	ret := make(map[string]string, len(labels))
+	for _, l := range labels {
+		ret[l.Name] = l.Value
+	}
+	return ret
+}
+
+func sortedMapKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]metrics.GaugeValue:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*metrics.SampledValue:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package metricsutil
+
+import (
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestRollingSink_Summary(t *testing.T) {
+	sink, err := NewRollingSink(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := sink.Windows(); len(w) != 3 || w[0] != time.Minute || w[2] != time.Hour {
+		t.Fatalf("bad default windows: %v", w)
+	}
+
+	labels := []metrics.Label{{Name: "cluster", Value: "c1"}}
+	sink.SetGaugeWithLabels([]string{"vault", "core", "unsealed"}, 0, labels)
+	sink.SetGaugeWithLabels([]string{"vault", "core", "unsealed"}, 1, labels)
+	sink.IncrCounterWithLabels([]string{"vault", "core", "check_token"}, 1, labels)
+	sink.IncrCounterWithLabels([]string{"vault", "core", "check_token"}, 2, labels)
+	sink.AddSample([]string{"vault", "core", "handle_request"}, 10)
+	sink.AddSample([]string{"vault", "core", "handle_request"}, 30)
+
+	summary, err := sink.Summary(5*time.Minute, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Window != "5m0s" {
+		t.Fatalf("bad window: %q", summary.Window)
+	}
+
+	if len(summary.Gauges) != 1 || summary.Gauges[0].Value != 1 || summary.Gauges[0].DisplayLabels["cluster"] != "c1" {
+		t.Fatalf("bad gauges: %#v", summary.Gauges)
+	}
+	if len(summary.Counters) != 1 || summary.Counters[0].Count != 2 || summary.Counters[0].Sum != 3 {
+		t.Fatalf("bad counters: %#v", summary.Counters)
+	}
+	if rate := summary.Counters[0].Rate; rate != 3.0/300 {
+		t.Fatalf("bad rate: %v", rate)
+	}
+	if len(summary.Samples) != 1 || summary.Samples[0].Mean != 20 || summary.Samples[0].Min != 10 || summary.Samples[0].Max != 30 {
+		t.Fatalf("bad samples: %#v", summary.Samples)
+	}
+
+	// Metrics emitted before the window are left out
+	summary, err = sink.Summary(time.Minute, time.Now().Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Gauges) != 0 || len(summary.Counters) != 0 || len(summary.Samples) != 0 {
+		t.Fatalf("expected an empty summary, got %#v", summary)
+	}
+
+	if _, err := sink.Summary(2*time.Minute, time.Now()); err == nil {
+		t.Fatal("expected error with an unknown window")
+	}
+	if _, err := NewRollingSink([]time.Duration{15 * time.Second}); err == nil {
+		t.Fatal("expected error with a window that isn't a multiple of the interval")
+	}
+}
+
+func TestRollingSink_mergesIntervals(t *testing.T) {
+	dst := make(map[string]*metrics.SampledValue)
+	for _, v := range []float64{5, 1, 9} {
+		agg := &metrics.AggregateSample{}
+		agg.Ingest(v, 1)
+		mergeSampledValues(dst, map[string]metrics.SampledValue{
+			"request": {Name: "request", AggregateSample: agg},
+		})
+	}
+
+	values := sampledValues(dst, time.Minute)
+	if len(values) != 1 {
+		t.Fatalf("bad values: %#v", values)
+	}
+	v := values[0]
+	if v.Count != 3 || v.Sum != 15 || v.Min != 1 || v.Max != 9 || v.Mean != 5 {
+		t.Fatalf("bad merged sample: %v", v.AggregateSample)
+	}
+}
@@ -0,0 +1,26 @@
+package metricsutil
+
+import (
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
+)
+
+// AddGlobalSink sends every metric to an additional sink. The global
+// go-metrics instance is replaced by one that forwards to both the sink of
+// the cluster metric sink, which is expected to be the previous global
+// instance, and the given sink. The cluster metric sink is pointed at the new
+// instance, so that metrics emitted through either reach the new sink. The
+// new sink receives keys without the service prefix and hostname, which the
+// previous instance still adds for its own sinks.
+func AddGlobalSink(cms *metricsutil.ClusterMetricSink, sink metrics.MetricSink) error {
+	conf := metrics.DefaultConfig("")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+
+	global, err := metrics.NewGlobal(conf, metrics.FanoutSink{cms.Sink, sink})
+	if err != nil {
+		return err
+	}
+	cms.Sink = global
+	return nil
+}
@@ -0,0 +1,119 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/fxamacker/cbor/v2"
+	testing "github.com/mitchellh/go-testing-interface"
+)
+
+// TestAuthenticator is a software authenticator with a P-256 credential,
+// used to test the ceremonies
+type TestAuthenticator struct {
+	t         testing.T
+	rpID      string
+	origin    string
+	key       *ecdsa.PrivateKey
+	id        []byte
+	signCount uint32
+}
+
+// NewTestAuthenticator returns an authenticator for the relying party ID,
+// whose client runs on the origin
+func NewTestAuthenticator(t testing.T, rpID, origin string) *TestAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &TestAuthenticator{
+		t:      t,
+		rpID:   rpID,
+		origin: origin,
+		key:    key,
+		id:     id,
+	}
+}
+
+// ID returns the ID of the credential of the authenticator
+func (a *TestAuthenticator) ID() []byte {
+	return a.id
+}
+
+// Register returns the client data and the attestation object of the creation
+// of the credential for the challenge
+func (a *TestAuthenticator) Register(challenge []byte) ([]byte, []byte) {
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  coseKeyTypeEC2,
+		3:  coseAlgES256,
+		-1: coseCurveP256,
+		-2: padTo32(a.key.X.Bytes()),
+		-3: padTo32(a.key.Y.Bytes()),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified | flagAttestedCredData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, byte(len(a.id)>>8), byte(len(a.id)))
+	authData = append(authData, a.id...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return a.clientData("webauthn.create", challenge), attestation
+}
+
+// Assert returns the client data, the authenticator data and the signature of
+// the authentication with the credential for the challenge
+func (a *TestAuthenticator) Assert(challenge []byte) ([]byte, []byte, []byte) {
+	a.signCount++
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return clientDataJSON, authData, signature
+}
+
+func (a *TestAuthenticator) clientData(ceremonyType string, challenge []byte) []byte {
+	clientDataJSON, err := json.Marshal(map[string]interface{}{
+		"type":      ceremonyType,
+		"challenge": EncodeBase64(challenge),
+		"origin":    a.origin,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return clientDataJSON
+}
+
+func (a *TestAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	authData := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(authData[33:], a.signCount)
+	return authData
+}
+
+func padTo32(b []byte) []byte {
+	return append(make([]byte, 32-len(b)), b...)
+}
@@ -0,0 +1,326 @@
+// Package webauthn verifies the registration and authentication ceremonies of
+// WebAuthn authenticators, as specified by the W3C Web Authentication
+// recommendation. Attestation statements are not verified, which is
+// equivalent to the relying party requesting "none" attestation.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// ChallengeSize is the size in bytes of the challenges generated by
+	// NewChallenge
+	ChallengeSize = 32
+
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+
+	// The COSE key types and algorithms of the supported public keys
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseAlgES256   = -7
+	coseAlgEdDSA   = -8
+	coseAlgRS256   = -257
+	coseCurveP256  = 1
+	coseCurveEd25  = 6
+)
+
+// Algorithms are the COSE algorithms of the public keys that can be
+// registered, in order of preference
+var Algorithms = []int{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// Options is the relying party against which the ceremonies are verified
+type Options struct {
+	// RPID is the relying party ID, which is the domain the credentials are
+	// scoped to
+	RPID string
+
+	// Origins are the origins the ceremonies are allowed to come from
+	Origins []string
+
+	// UserVerification requires the authenticators to verify the user, with
+	// a PIN or biometrics, in addition to testing their presence
+	UserVerification bool
+}
+
+// Credential is a registered public key credential
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// NewChallenge returns a random challenge for a ceremony
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// EncodeBase64 encodes the binary values of the ceremonies, such as the
+// challenges and the credential IDs, the way the client data does
+func EncodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64 decodes the binary values of the ceremonies, with or without
+// padding
+func DecodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// VerifyRegistration verifies the response of an authenticator to the
+// creation of a credential for the given challenge, and returns the
+// credential
+func VerifyRegistration(opts *Options, challenge, clientDataJSON, attestationObjectCBOR []byte) (*Credential, error) {
+	if err := verifyClientData(opts, "webauthn.create", challenge, clientDataJSON); err != nil {
+		return nil, err
+	}
+
+	var att attestationObject
+	if err := cbor.Unmarshal(attestationObjectCBOR, &att); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	authData, err := parseAuthenticatorData(att.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAuthenticatorData(opts, authData); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, errors.New("the authenticator data has no attested credential")
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of an authenticator to the
+// authentication challenge with the credential, and returns the signature
+// counter of the authenticator
+func VerifyAssertion(opts *Options, credential *Credential, challenge, clientDataJSON, authenticatorDataRaw, signature []byte) (uint32, error) {
+	if err := verifyClientData(opts, "webauthn.get", challenge, clientDataJSON); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(authenticatorDataRaw)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyAuthenticatorData(opts, authData); err != nil {
+		return 0, err
+	}
+
+	publicKey, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authenticatorDataRaw...), clientDataHash[:]...)
+	if err := verifySignature(publicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that doesn't increase denotes a cloned authenticator, unless
+	// the authenticator doesn't implement it
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, fmt.Errorf("the signature counter %d of the authenticator is not greater than %d", authData.signCount, credential.SignCount)
+	}
+	return authData.signCount, nil
+}
+
+func verifyClientData(opts *Options, ceremonyType string, challenge, clientDataJSON []byte) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+	if data.Type != ceremonyType {
+		return fmt.Errorf("invalid client data type %q, expected %q", data.Type, ceremonyType)
+	}
+	dataChallenge, err := DecodeBase64(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(dataChallenge, challenge) != 1 {
+		return errors.New("the challenge of the client data does not match")
+	}
+	for _, origin := range opts.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", data.Origin)
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errors.New("the authenticator data is too short")
+	}
+	authData := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return authData, nil
+	}
+
+	// The attested credential data is the AAGUID of the authenticator, the
+	// length of the credential ID, the credential ID and the public key
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("the attested credential data is too short")
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || len(rest) < idLen {
+		return nil, errors.New("invalid credential ID length")
+	}
+	authData.credentialID = rest[:idLen]
+
+	// The public key may be followed by extensions
+	var publicKey cbor.RawMessage
+	if err := cbor.NewDecoder(bytes.NewReader(rest[idLen:])).Decode(&publicKey); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	authData.publicKey = publicKey
+	return authData, nil
+}
+
+func verifyAuthenticatorData(opts *Options, authData *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(opts.RPID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return errors.New("the relying party ID hash of the authenticator data does not match")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return errors.New("the authenticator did not test the presence of the user")
+	}
+	if opts.UserVerification && authData.flags&flagUserVerified == 0 {
+		return errors.New("the authenticator did not verify the user")
+	}
+	return nil
+}
+
+// parsePublicKey returns the public key of a COSE_Key of a supported type
+func parsePublicKey(raw []byte) (crypto.PublicKey, error) {
+	var key struct {
+		Kty int `cbor:"1,keyasint"`
+		Alg int `cbor:"3,keyasint"`
+	}
+	if err := cbor.Unmarshal(raw, &key); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+
+	switch {
+	case key.Kty == coseKeyTypeEC2 && key.Alg == coseAlgES256:
+		var ec2 struct {
+			Crv int    `cbor:"-1,keyasint"`
+			X   []byte `cbor:"-2,keyasint"`
+			Y   []byte `cbor:"-3,keyasint"`
+		}
+		if err := cbor.Unmarshal(raw, &ec2); err != nil {
+			return nil, fmt.Errorf("invalid EC2 public key: %w", err)
+		}
+		if ec2.Crv != coseCurveP256 {
+			return nil, fmt.Errorf("unsupported EC2 curve %d", ec2.Crv)
+		}
+		x, y := new(big.Int).SetBytes(ec2.X), new(big.Int).SetBytes(ec2.Y)
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC2 public key: the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+
+	case key.Kty == coseKeyTypeOKP && key.Alg == coseAlgEdDSA:
+		var okp struct {
+			Crv int    `cbor:"-1,keyasint"`
+			X   []byte `cbor:"-2,keyasint"`
+		}
+		if err := cbor.Unmarshal(raw, &okp); err != nil {
+			return nil, fmt.Errorf("invalid OKP public key: %w", err)
+		}
+		if okp.Crv != coseCurveEd25 || len(okp.X) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid OKP public key")
+		}
+		return ed25519.PublicKey(okp.X), nil
+
+	case key.Kty == coseKeyTypeRSA && key.Alg == coseAlgRS256:
+		var rsaKey struct {
+			N []byte `cbor:"-1,keyasint"`
+			E []byte `cbor:"-2,keyasint"`
+		}
+		if err := cbor.Unmarshal(raw, &rsaKey); err != nil {
+			return nil, fmt.Errorf("invalid RSA public key: %w", err)
+		}
+		e := new(big.Int).SetBytes(rsaKey.E)
+		if len(rsaKey.N) < 256 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA public key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(rsaKey.N), E: int(e.Int64())}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported public key type %d with algorithm %d", key.Kty, key.Alg)
+	}
+}
+
+func verifySignature(publicKey crypto.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	valid := false
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, signed, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
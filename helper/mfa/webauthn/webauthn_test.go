@@ -0,0 +1,141 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWebAuthn_Ceremonies(t *testing.T) {
+	opts := &Options{
+		RPID:             "example.com",
+		Origins:          []string{"https://vault.example.com"},
+		UserVerification: true,
+	}
+	authenticator := NewTestAuthenticator(t, "example.com", "https://vault.example.com")
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, attestation := authenticator.Register(challenge)
+	credential, err := VerifyRegistration(opts, challenge, clientDataJSON, attestation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(credential.ID) != string(authenticator.ID()) {
+		t.Fatalf("bad: credential ID %x", credential.ID)
+	}
+
+	challenge, err = NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, authData, signature := authenticator.Assert(challenge)
+	signCount, err := VerifyAssertion(opts, credential, challenge, clientDataJSON, authData, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signCount != 1 {
+		t.Fatalf("bad: sign count %d", signCount)
+	}
+	credential.SignCount = signCount
+
+	// A replayed assertion doesn't increase the counter
+	if _, err := VerifyAssertion(opts, credential, challenge, clientDataJSON, authData, signature); err == nil {
+		t.Fatal("expected an error replaying the assertion")
+	}
+}
+
+func TestWebAuthn_VerifyAssertion_Errors(t *testing.T) {
+	opts := &Options{
+		RPID:    "example.com",
+		Origins: []string{"https://vault.example.com"},
+	}
+	authenticator := NewTestAuthenticator(t, "example.com", "https://vault.example.com")
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, attestation := authenticator.Register(challenge)
+	credential, err := VerifyRegistration(opts, challenge, clientDataJSON, attestation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherChallenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewTestAuthenticator(t, "example.com", "https://vault.example.com")
+	wrongRPID := NewTestAuthenticator(t, "example.org", "https://vault.example.com")
+	wrongOrigin := NewTestAuthenticator(t, "example.com", "https://evil.example.com")
+
+	cases := map[string]struct {
+		authenticator *TestAuthenticator
+		challenge     []byte
+		modify        func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte)
+		expected      string
+	}{
+		"wrong challenge": {
+			authenticator: authenticator,
+			challenge:     otherChallenge,
+			expected:      "challenge",
+		},
+		"wrong key": {
+			authenticator: other,
+			challenge:     challenge,
+			expected:      "invalid signature",
+		},
+		"wrong rp id": {
+			authenticator: wrongRPID,
+			challenge:     challenge,
+			expected:      "relying party ID",
+		},
+		"wrong origin": {
+			authenticator: wrongOrigin,
+			challenge:     challenge,
+			expected:      "origin",
+		},
+		"wrong type": {
+			authenticator: authenticator,
+			challenge:     challenge,
+			modify: func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte) {
+				var data map[string]interface{}
+				if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+					t.Fatal(err)
+				}
+				data["type"] = "webauthn.create"
+				clientDataJSON, err := json.Marshal(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return clientDataJSON, authData, signature
+			},
+			expected: "type",
+		},
+		"user not present": {
+			authenticator: authenticator,
+			challenge:     challenge,
+			modify: func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte) {
+				authData[32] &^= flagUserPresent
+				return clientDataJSON, authData, signature
+			},
+			expected: "presence",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			clientDataJSON, authData, signature := tc.authenticator.Assert(challenge)
+			if tc.modify != nil {
+				clientDataJSON, authData, signature = tc.modify(clientDataJSON, authData, signature)
+			}
+			_, err := VerifyAssertion(opts, credential, tc.challenge, clientDataJSON, authData, signature)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// wrapCustomResponseHeaders adds the custom headers configured on the
+// listener to all the responses. The headers configured for the status code
+// of a response take precedence over the ones configured for its status class,
+// which take precedence over the default ones. Headers set by Vault itself
+// are never overridden.
+func wrapCustomResponseHeaders(h http.Handler, headers map[string]http.Header) http.Handler {
+	if len(headers) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&customHeadersResponseWriter{
+			ResponseWriter: w,
+			headers:        headers,
+		}, r)
+	})
+}
+
+// customResponseHeaders returns the custom headers to add to a response of
+// the given status.
+func customResponseHeaders(headers map[string]http.Header, status int) http.Header {
+	result := make(http.Header)
+	for _, key := range []string{"default", fmt.Sprintf("%dxx", status/100), strconv.Itoa(status)} {
+		for name, values := range headers[key] {
+			result[name] = values
+		}
+	}
+	return result
+}
+
+type customHeadersResponseWriter struct {
+	http.ResponseWriter
+	headers     map[string]http.Header
+	wroteHeader bool
+}
+
+func (w *customHeadersResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.ResponseWriter.Header()
+		for name, values := range customResponseHeaders(w.headers, status) {
+			if _, ok := header[name]; !ok {
+				header[name] = values
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *customHeadersResponseWriter) Write(buf []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(buf)
+}
+
+func (w *customHeadersResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
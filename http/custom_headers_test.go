@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestHandler_CustomResponseHeaders(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core: core,
+		CustomResponseHeaders: map[string]http.Header{
+			"default": {
+				"Strict-Transport-Security": {"max-age=31536000"},
+				"X-Frame-Options":           {"deny"},
+				"Cache-Control":             {"public"},
+			},
+			"4xx": {
+				"X-Frame-Options": {"sameorigin"},
+				"X-Custom":        {"client-error"},
+			},
+			"404": {
+				"X-Custom": {"not-found"},
+			},
+		},
+	})
+
+	cases := map[string]struct {
+		path     string
+		token    string
+		status   int
+		expected map[string]string
+	}{
+		"default": {
+			path:   "/v1/sys/mounts",
+			status: http.StatusOK,
+			expected: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000",
+				"X-Frame-Options":           "deny",
+				"X-Custom":                  "",
+				// Headers set by Vault aren't overridden
+				"Cache-Control": "no-store",
+			},
+		},
+		"status class": {
+			path:   "/v1/sys/mounts",
+			token:  "bogus",
+			status: http.StatusForbidden,
+			expected: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000",
+				"X-Frame-Options":           "sameorigin",
+				"X-Custom":                  "client-error",
+			},
+		},
+		"status code": {
+			path:   "/v1/secret/missing",
+			status: http.StatusNotFound,
+			expected: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000",
+				"X-Frame-Options":           "sameorigin",
+				"X-Custom":                  "not-found",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", addr+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.token == "" {
+				tc.token = token
+			}
+			req.Header.Set(consts.AuthHeaderName, tc.token)
+
+			resp, err := cleanhttp.DefaultClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("bad status: expected %d, got %d", tc.status, resp.StatusCode)
+			}
+			for name, value := range tc.expected {
+				if actual := resp.Header.Get(name); actual != value {
+					t.Fatalf("bad %s header: expected %q, got %q", name, value, actual)
+				}
+			}
+		})
+	}
+}
@@ -603,3 +603,90 @@ func TestHTTP_Forwarding_LocalOnly(t *testing.T) {
 	testLocalOnly(cores[1].Client)
 	testLocalOnly(cores[2].Client)
 }
+
+func TestHTTP_Forwarding_CompressionPool(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		ForwardingCompression: true,
+		ForwardingConnections: 3,
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	// A large, compressible payload, forwarded over each connection of the
+	// pool in turn
+	value := strings.Repeat("forwarded", 100000)
+	for i := 0; i < 6; i++ {
+		path := fmt.Sprintf("cubbyhole/large-%d", i)
+		if _, err := cores[1].Client.Logical().Write(path, map[string]interface{}{"value": value}); err != nil {
+			t.Fatal(err)
+		}
+
+		secret, err := cores[2].Client.Logical().Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Data["value"] != value {
+			t.Fatalf("bad value read back from %s", path)
+		}
+	}
+}
+
+func TestHTTP_Forwarding_PerfStandby(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		EnablePerformanceStandby: true,
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+	for i := 0; !cores[1].PerfStandby(); i++ {
+		if i > 100 {
+			t.Fatal("timed out waiting for the performance standby")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The write is forwarded to the active node, and the read is served
+	// by the performance standby
+	client := cores[1].Client
+	if _, err := client.Logical().Write("cubbyhole/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("cubbyhole/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// So are the token lookups
+	secret, err = client.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["id"] != cluster.RootToken {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// The requests which can't be served locally are forwarded
+	secret, err = client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"default"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
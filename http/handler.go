@@ -22,6 +22,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	sockaddr "github.com/hashicorp/go-sockaddr"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/shared-secure-libs/configutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -103,6 +104,12 @@ func init() {
 		"sys/storage/raft/snapshot",
 		"sys/storage/raft/snapshot-force",
 	})
+	perfStandbyAlwaysForwardPaths.AddPaths([]string{
+		"sys/step-down",
+		"sys/generate-root/",
+		"sys/rekey/",
+		"sys/rekey-recovery-key/",
+	})
 }
 
 // Handler returns an http.Handler for the API. This can be used on
@@ -127,13 +134,14 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 		mux.Handle("/v1/sys/pprof/", handleLogicalNoForward(core))
 
 		mux.Handle("/v1/sys/init", handleSysInit(core))
-		mux.Handle("/v1/sys/seal-status", handleSysSealStatus(core))
+		mux.Handle("/v1/sys/seal-status", handleSysSealStatus(core, props.DisableSealStatusDetails))
 		mux.Handle("/v1/sys/seal", handleSysSeal(core))
 		mux.Handle("/v1/sys/step-down", handleRequestForwarding(core, handleSysStepDown(core)))
 		mux.Handle("/v1/sys/unseal", handleSysUnseal(core))
 		mux.Handle("/v1/sys/leader", handleSysLeader(core))
-		mux.Handle("/v1/sys/health", handleSysHealth(core))
+		mux.Handle("/v1/sys/health", handleSysHealth(core, props.DisableHealthDetails))
 		mux.Handle("/v1/sys/monitor", handleLogicalNoForward(core))
+		mux.Handle("/v1/sys/audit/tail", handleLogicalNoForward(core))
 		mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core,
 			handleAuditNonLogical(core, handleSysGenerateRootAttempt(core, vault.GenerateStandardRootTokenStrategy))))
 		mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core,
@@ -151,7 +159,7 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 		}
 		mux.Handle("/v1/sys/", handleRequestForwarding(core, handleLogical(core)))
 		mux.Handle("/v1/", handleRequestForwarding(core, handleLogical(core)))
-		if core.UIEnabled() == true {
+		if core.UIEnabled() && !props.DisableUI {
 			if uiBuiltIn {
 				mux.Handle("/ui/", http.StripPrefix("/ui/", gziphandler.GzipHandler(handleUIHeaders(core, handleUI(http.FileServer(&UIAssetWrapper{FileSystem: assetFS()}))))))
 				mux.Handle("/robots.txt", gziphandler.GzipHandler(handleUIHeaders(core, handleUI(http.FileServer(&UIAssetWrapper{FileSystem: assetFS()})))))
@@ -170,6 +178,14 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 			mux.Handle("/v1/sys/metrics", handleLogicalNoForward(core))
 		}
 
+		// Register the in-flight requests path without authentication if
+		// enabled
+		if props.UnauthenticatedInFlightRequests {
+			mux.Handle("/v1/sys/in-flight-req", handleInFlightRequestsUnauthenticated(core))
+		} else {
+			mux.Handle("/v1/sys/in-flight-req", handleLogicalNoForward(core))
+		}
+
 		additionalRoutes(mux, core)
 	}
 
@@ -186,7 +202,7 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 		printablePathCheckHandler = cleanhttp.PrintablePathCheckHandler(genericWrappedHandler, nil)
 	}
 
-	return printablePathCheckHandler
+	return wrapCustomResponseHeaders(printablePathCheckHandler, props.CustomResponseHeaders)
 }
 
 type copyResponseWriter struct {
@@ -219,6 +235,37 @@ func (w *copyResponseWriter) WriteHeader(code int) {
 	w.wrapped.WriteHeader(code)
 }
 
+// sizeResponseWriter counts the bytes of the response body written to the
+// wrapped writer.
+type sizeResponseWriter struct {
+	http.ResponseWriter
+	size int64
+}
+
+func (w *sizeResponseWriter) Write(buf []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(buf)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *sizeResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// sizeReader counts the bytes of the request body read by handlers.
+type sizeReader struct {
+	io.ReadCloser
+	size int64
+}
+
+func (r *sizeReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.size += int64(n)
+	return n, err
+}
+
 func handleAuditNonLogical(core *vault.Core, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origBody := new(bytes.Buffer)
@@ -281,8 +328,10 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 		// Start with the request context
 		ctx := r.Context()
 		var cancelFunc context.CancelFunc
-		// Add our timeout, but not for the monitor endpoint, as it's streaming
-		if strings.HasSuffix(r.URL.Path, "sys/monitor") {
+		// Add our timeout, but not for the monitor and audit tail endpoints,
+		// as they're streaming
+		streaming := strings.HasSuffix(r.URL.Path, "sys/monitor") || strings.HasSuffix(r.URL.Path, "sys/audit/tail")
+		if streaming {
 			ctx, cancelFunc = context.WithCancel(ctx)
 		} else {
 			ctx, cancelFunc = context.WithTimeout(ctx, maxRequestDuration)
@@ -305,6 +354,30 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 			}
 			r = newR
 
+			// Record the size of the request and response payloads, except
+			// for streaming endpoints
+			if !streaming && r.Body != nil {
+				body := &sizeReader{ReadCloser: r.Body}
+				r.Body = body
+				sw := &sizeResponseWriter{ResponseWriter: w}
+				w = sw
+				defer func(ctx context.Context, path string) {
+					core.EmitRequestSizeMetrics(ctx, path, body.size, sw.size)
+				}(r.Context(), strings.TrimPrefix(r.URL.Path, "/v1/"))
+			}
+
+			// Track the request until it's handled, so that it can be
+			// listed by sys/in-flight-req
+			if reqID, err := uuid.GenerateUUID(); err == nil {
+				core.StoreInFlightRequest(reqID, vault.InFlightRequest{
+					StartTime:        time.Now(),
+					ClientRemoteAddr: r.RemoteAddr,
+					ReqPath:          r.URL.Path,
+					ReqMethod:        r.Method,
+				})
+				defer core.FinalizeInFlightRequest(reqID)
+			}
+
 		case strings.HasPrefix(r.URL.Path, "/ui"), r.URL.Path == "/robots.txt", r.URL.Path == "/":
 		default:
 			respondError(w, http.StatusNotFound, nil)
@@ -606,6 +679,11 @@ func parseJSONRequest(perfStandby bool, r *http.Request, w http.ResponseWriter,
 			return nil, errors.New("could not parse max_request_size from request context")
 		}
 		if max > 0 {
+			// The server's own writer must be used so that it closes the
+			// connection once the limit is exceeded
+			if sw, ok := w.(*sizeResponseWriter); ok {
+				w = sw.ResponseWriter
+			}
 			reader = http.MaxBytesReader(w, r.Body, max)
 		}
 	}
@@ -678,12 +756,12 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 			path := ns.TrimmedPath(r.URL.Path[len("/v1/"):])
 			switch {
 			case !perfStandbyAlwaysForwardPaths.HasPath(path) && !alwaysRedirectPaths.HasPath(path):
-				handler.ServeHTTP(w, r)
+				serveWithRequiredState(core, handler, w, r)
 				return
 			case strings.HasPrefix(path, "auth/token/create/"):
 				isBatch, err := core.IsBatchTokenCreationRequest(r.Context(), path)
 				if err == nil && isBatch {
-					handler.ServeHTTP(w, r)
+					serveWithRequiredState(core, handler, w, r)
 					return
 				}
 			}
@@ -696,7 +774,7 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 		if err != nil {
 			if err == vault.ErrHANotEnabled {
 				// Standalone node, serve request normally
-				handler.ServeHTTP(w, r)
+				serveWithRequiredState(core, handler, w, r)
 				return
 			}
 			// Some internal error occurred
@@ -705,7 +783,7 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 		}
 		if isLeader {
 			// No forwarding needed, we're leader
-			handler.ServeHTTP(w, r)
+			serveWithRequiredState(core, handler, w, r)
 			return
 		}
 		if leaderAddr == "" {
@@ -718,6 +796,45 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 	})
 }
 
+// serveWithRequiredState serves the request locally once the node has applied
+// the storage writes required by its X-Vault-Index header. A performance
+// standby that can't catch up in time forwards the request to the active node
+// if the client asked for it with the X-Vault-Inconsistent header, and any
+// other node fails the request with a 412.
+func serveWithRequiredState(core *vault.Core, handler http.Handler, w http.ResponseWriter, r *http.Request) {
+	raw := r.Header.Get(consts.IndexHeaderName)
+	if raw == "" {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	inconsistent := r.Header.Get(consts.InconsistentHeaderName)
+	switch inconsistent {
+	case "", vault.InconsistentFail, vault.InconsistentForwardActiveNode:
+	default:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid %s header %q", consts.InconsistentHeaderName, inconsistent))
+		return
+	}
+
+	required, err := vault.ParseRequiredState(raw)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = core.WaitForRequiredState(r.Context(), required)
+	switch {
+	case err == nil:
+		handler.ServeHTTP(w, r)
+	case err == vault.ErrStaleState && inconsistent == vault.InconsistentForwardActiveNode && core.PerfStandby():
+		forwardRequest(core, w, r)
+	case err == vault.ErrStaleState:
+		respondError(w, http.StatusPreconditionFailed, err)
+	default:
+		respondError(w, http.StatusInternalServerError, err)
+	}
+}
+
 func forwardRequest(core *vault.Core, w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get(vault.IntNoForwardingHeaderName) != "" {
 		respondStandby(core, w, r.URL)
@@ -786,6 +903,12 @@ func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *l
 		return nil, false, true
 	}
 
+	// Convey the storage index including the writes of this request, so
+	// that the client can require it in its next requests
+	if state := core.ResponseState(); state != "" {
+		w.Header().Set(consts.IndexHeaderName, state)
+	}
+
 	if resp != nil && len(resp.Headers) > 0 {
 		// Set this here so it will take effect regardless of any other type of
 		// response processing
@@ -899,6 +1022,17 @@ func getTokenFromReq(r *http.Request) (string, bool) {
 	return "", false
 }
 
+// requestHasValidToken returns whether the request carries a valid token.
+func requestHasValidToken(core *vault.Core, r *http.Request) bool {
+	token, _ := getTokenFromReq(r)
+	if token == "" {
+		return false
+	}
+
+	te, err := core.LookupToken(r.Context(), token)
+	return err == nil && te != nil
+}
+
 // requestAuth adds the token to the logical.Request if it exists.
 func requestAuth(core *vault.Core, r *http.Request, req *logical.Request) (*logical.Request, error) {
 	// Attach the header value if we have it
@@ -916,6 +1050,10 @@ func requestAuth(core *vault.Core, r *http.Request, req *logical.Request) (*logi
 		// a token from a Vault version pre-accessors. We ignore errors for
 		// JWTs.
 		te, err := core.LookupToken(r.Context(), token)
+		if err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()) {
+			// The request is forwarded once the core looks the token up
+			return req, nil
+		}
 		if err != nil {
 			dotCount := strings.Count(token, ".")
 			// If we have two dots but the second char is a dot it's a vault
@@ -1060,6 +1198,13 @@ func isForm(head []byte, contentType string) bool {
 	return true
 }
 
+// isOCSPRequest returns whether the content type is the one of OCSP requests
+// sent over POST, see RFC 6960 A.1
+func isOCSPRequest(contentType string) bool {
+	contentType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && contentType == "application/ocsp-request"
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	logical.RespondError(w, status, err)
 }
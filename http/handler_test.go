@@ -224,6 +224,66 @@ func TestHandler_CacheControlNoStore(t *testing.T) {
 	}
 }
 
+func TestHandler_RequiredStateHeaders(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	cases := map[string]struct {
+		index        string
+		inconsistent string
+		status       int
+	}{
+		"no index": {
+			status: http.StatusOK,
+		},
+		// The inmem storage doesn't number its writes, so any index is
+		// satisfied
+		"valid index": {
+			index:        "djE6MTA=",
+			inconsistent: vault.InconsistentForwardActiveNode,
+			status:       http.StatusOK,
+		},
+		"invalid index": {
+			index:  "bogus",
+			status: http.StatusBadRequest,
+		},
+		"invalid inconsistent": {
+			index:        "djE6MTA=",
+			inconsistent: "bogus",
+			status:       http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", addr+"/v1/sys/mounts", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(consts.AuthHeaderName, token)
+			if tc.index != "" {
+				req.Header.Set(consts.IndexHeaderName, tc.index)
+			}
+			if tc.inconsistent != "" {
+				req.Header.Set(consts.InconsistentHeaderName, tc.inconsistent)
+			}
+
+			resp, err := cleanhttp.DefaultClient().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("bad status: expected %d, got %d", tc.status, resp.StatusCode)
+			}
+			if resp.Header.Get(consts.IndexHeaderName) != "" {
+				t.Fatal("unexpected index header without indexed storage")
+			}
+		})
+	}
+}
+
 func TestHandler_Accepted(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -505,6 +565,22 @@ func TestHandler_ui_enabled(t *testing.T) {
 	testResponseStatus(t, resp, 200)
 }
 
+func TestHandler_ui_disabledOnListener(t *testing.T) {
+	core := vault.TestCoreUI(t, true)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core:      core,
+		DisableUI: true,
+	})
+
+	resp, err := http.Get(addr + "/ui/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 404)
+}
+
 func TestHandler_error(t *testing.T) {
 	w := httptest.NewRecorder()
 
@@ -739,3 +815,40 @@ func TestHandler_Parse_Form(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestHandler_RequestSizeMetrics(t *testing.T) {
+	core, _, token, sink := vault.TestCoreUnsealedWithMetrics(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": strings.Repeat("a", 1024),
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 200)
+
+	intervals := sink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	samples := make(map[string]float64)
+	for _, s := range intervals[0].Samples {
+		labels := make(map[string]string)
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+		if labels["mount_point"] == "secret/" && labels["mount_type"] == "kv" {
+			samples[s.Name] = s.Max
+		}
+	}
+	if samples["core.request_size"] < 1024 {
+		t.Fatalf("expected the request size of the write, got %v", samples)
+	}
+	if samples["core.response_size"] < 1024 {
+		t.Fatalf("expected the response size of the read, got %v", samples)
+	}
+}
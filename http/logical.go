@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"strconv"
@@ -86,7 +87,7 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 			responseWriter = w
 		case path == "sys/storage/raft/snapshot":
 			responseWriter = w
-		case path == "sys/monitor":
+		case path == "sys/monitor", path == "sys/audit/tail":
 			passHTTPReq = true
 			responseWriter = w
 		}
@@ -99,10 +100,10 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		bufferedBody := newBufferedReader(r.Body)
 		r.Body = bufferedBody
 
-		// If we are uploading a snapshot we don't want to parse it. Instead
-		// we will simply add the HTTP request to the logical request object
-		// for later consumption.
-		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" {
+		// If we are uploading a snapshot or an OCSP request, which is DER
+		// encoded, we don't want to parse it. Instead we will simply add the
+		// HTTP request to the logical request object for later consumption.
+		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isOCSPRequest(r.Header.Get("Content-Type")) {
 			passHTTPReq = true
 			origBody = r.Body
 		} else {
@@ -134,13 +135,36 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 			}
 		}
 
+	case "PATCH":
+		op = logical.PatchOperation
+
+		// Patches are applied with JSON merge patch semantics, so require
+		// the matching content type rather than guessing the format
+		contentType := r.Header.Get("Content-Type")
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/merge-patch+json" {
+			return nil, nil, http.StatusUnsupportedMediaType, fmt.Errorf("PATCH requires Content-Type of application/merge-patch+json, got %q", contentType)
+		}
+
+		origBody, err = parseJSONRequest(perfStandby, r, w, &data)
+		if err == io.EOF {
+			data = nil
+			err = nil
+		}
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, err
+		}
+
 	case "LIST":
 		op = logical.ListOperation
 		if !strings.HasSuffix(path, "/") {
 			path += "/"
 		}
 
-	case "OPTIONS", "HEAD":
+	case "HEAD":
+		op = logical.HeaderOperation
+		data = parseQuery(r.URL.Query())
+
+	case "OPTIONS":
 	default:
 		return nil, nil, http.StatusMethodNotAllowed, nil
 	}
@@ -16,6 +16,8 @@ import (
 
 	"github.com/go-test/deep"
 	log "github.com/hashicorp/go-hclog"
+	kv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/api"
 
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/namespace"
@@ -472,3 +474,227 @@ func TestLogical_ShouldParseForm(t *testing.T) {
 	}
 
 }
+
+func TestLogical_KVPatch(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Logical().Write("kv/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "b",
+			"c": map[string]interface{}{"d": "e", "f": "g"},
+			"h": "i",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only allow patching, so that reading and writing on behalf of the
+	// client doesn't depend on its own read and update capabilities
+	err = client.Sys().PutPolicy("patcher", `path "kv/data/foo" { capabilities = ["patch"] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"patcher"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchClient.SetToken(secret.Auth.ClientToken)
+
+	secret, err = patchClient.Logical().JSONMergePatch("kv/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "z",
+			"c": map[string]interface{}{"f": nil},
+			"h": nil,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["version"] != json.Number("2") {
+		t.Fatalf("bad: patch response: %#v", secret)
+	}
+
+	secret, err = client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"a": "z",
+		"c": map[string]interface{}{"d": "e"},
+	}
+	if diff := deep.Equal(secret.Data["data"], expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// A stale check-and-set value is rejected
+	_, err = client.Logical().JSONMergePatch("kv/data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"a": "y"},
+		"options": map[string]interface{}{"cas": 1},
+	})
+	if err == nil {
+		t.Fatal("expected an error patching with a stale check-and-set value")
+	}
+
+	// Patching requires existing data
+	_, err = client.Logical().JSONMergePatch("kv/data/bar", map[string]interface{}{
+		"data": map[string]interface{}{"a": "b"},
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a not found error, got: %v", err)
+	}
+
+	// Patching requires the merge patch content type
+	req := client.NewRequest("PATCH", "/v1/kv/data/foo")
+	if err := req.SetJSONBody(map[string]interface{}{"data": map[string]interface{}{"a": "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.RawRequest(req)
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected an unsupported media type error, got: %v", err)
+	}
+}
+
+func TestLogical_KVDeletionProtection(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Sys().TuneMount("kv", api.MountConfigInput{
+		Options: map[string]string{"deletion_protection": "yes"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid deletion_protection value")
+	}
+	err = client.Sys().TuneMount("kv", api.MountConfigInput{
+		Options: map[string]string{"deletion_protection": "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Logical().Write("kv/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"a": "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first request only hands out a confirmation
+	secret, err := client.Logical().Write("kv/destroy/foo", map[string]interface{}{
+		"versions": []int{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, ok := secret.Data["confirm"].(string)
+	if !ok || confirm == "" {
+		t.Fatalf("expected a confirmation, got %#v", secret.Data)
+	}
+	if len(secret.Warnings) != 1 {
+		t.Fatalf("expected a warning, got %#v", secret.Warnings)
+	}
+	secret, err = client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["data"] == nil {
+		t.Fatal("expected the secret not to be destroyed")
+	}
+
+	// The confirmation is bound to the operation and path
+	_, err = client.Logical().DeleteWithData("kv/metadata/foo", map[string][]string{
+		"confirm": {confirm},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a confirmation of another request")
+	}
+
+	_, err = client.Logical().Write("kv/destroy/foo", map[string]interface{}{
+		"versions": []int{1},
+		"confirm":  confirm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil && secret.Data["data"] != nil {
+		t.Fatalf("expected the secret to be destroyed, got %#v", secret.Data)
+	}
+
+	// Confirmations can only be used once
+	_, err = client.Logical().Write("kv/destroy/foo", map[string]interface{}{
+		"versions": []int{1},
+		"confirm":  confirm,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a confirmation that was already used")
+	}
+
+	secret, err = client.Logical().Delete("kv/metadata/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm = secret.Data["confirm"].(string)
+	_, err = client.Logical().DeleteWithData("kv/metadata/foo", map[string][]string{
+		"confirm": {confirm},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Logical().Read("kv/metadata/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected the metadata to be deleted, got %#v", secret.Data)
+	}
+}
@@ -15,11 +15,11 @@ import (
 	"github.com/hashicorp/vault/vault"
 )
 
-func handleSysHealth(core *vault.Core) http.Handler {
+func handleSysHealth(core *vault.Core, disableDetails bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
-			handleSysHealthGet(core, w, r)
+			handleSysHealthGet(core, w, r, disableDetails)
 		case "HEAD":
 			handleSysHealthHead(core, w, r)
 		default:
@@ -41,7 +41,7 @@ func fetchStatusCode(r *http.Request, field string) (int, bool, bool) {
 	return statusCode, false, true
 }
 
-func handleSysHealthGet(core *vault.Core, w http.ResponseWriter, r *http.Request) {
+func handleSysHealthGet(core *vault.Core, w http.ResponseWriter, r *http.Request, disableDetails bool) {
 	code, body, err := getSysHealth(core, r)
 
 	if err != nil {
@@ -55,6 +55,10 @@ func handleSysHealthGet(core *vault.Core, w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if disableDetails && !requestHasValidToken(core, r) {
+		body.redact()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
@@ -210,11 +214,23 @@ type HealthResponse struct {
 	Sealed                     bool   `json:"sealed"`
 	Standby                    bool   `json:"standby"`
 	PerformanceStandby         bool   `json:"performance_standby"`
-	ReplicationPerformanceMode string `json:"replication_performance_mode"`
-	ReplicationDRMode          string `json:"replication_dr_mode"`
-	ServerTimeUTC              int64  `json:"server_time_utc"`
-	Version                    string `json:"version"`
+	ReplicationPerformanceMode string `json:"replication_performance_mode,omitempty"`
+	ReplicationDRMode          string `json:"replication_dr_mode,omitempty"`
+	ServerTimeUTC              int64  `json:"server_time_utc,omitempty"`
+	Version                    string `json:"version,omitempty"`
 	ClusterName                string `json:"cluster_name,omitempty"`
 	ClusterID                  string `json:"cluster_id,omitempty"`
 	LastWAL                    uint64 `json:"last_wal,omitempty"`
 }
+
+// redact removes the version, cluster and replication details of the health
+// status returned to the clients without a valid token.
+func (h *HealthResponse) redact() {
+	h.ReplicationPerformanceMode = ""
+	h.ReplicationDRMode = ""
+	h.ServerTimeUTC = 0
+	h.Version = ""
+	h.ClusterName = ""
+	h.ClusterID = ""
+	h.LastWAL = 0
+}
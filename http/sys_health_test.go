@@ -272,3 +272,38 @@ func TestSysHealth_head(t *testing.T) {
 		}
 	}
 }
+
+func TestSysHealth_disableDetails(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core:                 core,
+		DisableHealthDetails: true,
+	})
+
+	resp := testHttpGet(t, "", addr+"/v1/sys/health")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	expected := map[string]interface{}{
+		"initialized":         true,
+		"sealed":              false,
+		"standby":             false,
+		"performance_standby": false,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, actual)
+	}
+
+	// The details are returned to the clients with a valid token
+	resp = testHttpGet(t, token, addr+"/v1/sys/health")
+	actual = map[string]interface{}{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	for _, field := range []string{"version", "cluster_name", "cluster_id", "server_time_utc", "replication_dr_mode"} {
+		if actual[field] == nil {
+			t.Fatalf("expected %q in the response: %#v", field, actual)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleInFlightRequestsUnauthenticated(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		respondOk(w, logical.LogicalResponseToHTTPResponse(&logical.Response{
+			Data: core.InFlightRequestsResponse(),
+		}))
+	})
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestInFlightRequests(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// The endpoint requires a token by default
+	resp := testHttpGet(t, "", addr+"/v1/sys/in-flight-req")
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/in-flight-req")
+	testResponseStatus(t, resp, 200)
+	testInFlightRequestsBody(t, resp)
+}
+
+func TestInFlightRequests_unauthenticated(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core:                            core,
+		UnauthenticatedInFlightRequests: true,
+	})
+
+	resp := testHttpGet(t, "", addr+"/v1/sys/in-flight-req")
+	testResponseStatus(t, resp, 200)
+	testInFlightRequestsBody(t, resp)
+
+	if reqs := core.InFlightRequests(); len(reqs) != 0 {
+		t.Fatalf("expected no in-flight request once handled, got %#v", reqs)
+	}
+}
+
+// testInFlightRequestsBody checks that the response lists the request to
+// sys/in-flight-req itself.
+func testInFlightRequestsBody(t *testing.T, resp *http.Response) {
+	t.Helper()
+
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	data, ok := actual["data"].(map[string]interface{})
+	if !ok || len(data) != 1 {
+		t.Fatalf("expected one in-flight request, got %#v", actual)
+	}
+	for _, v := range data {
+		req := v.(map[string]interface{})
+		if req["request_path"] != "/v1/sys/in-flight-req" || req["request_method"] != "GET" {
+			t.Fatalf("bad: %#v", req)
+		}
+		if req["client_remote_address"] == "" || req["start_time"] == "" {
+			t.Fatalf("bad: %#v", req)
+		}
+	}
+}
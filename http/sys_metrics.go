@@ -32,7 +32,7 @@ func handleMetricsUnauthenticated(core *vault.Core) http.Handler {
 		}
 
 		// Define response
-		resp := core.MetricsHelper().ResponseForFormat(format)
+		resp := core.MetricsResponse(format, r.Form.Get("window"))
 
 		// Manually extract the logical response and send back the information
 		w.WriteHeader(resp.Data[logical.HTTPStatusCode].(int))
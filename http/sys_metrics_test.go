@@ -7,6 +7,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/shared-secure-libs/configutil"
 	"github.com/hashicorp/shared-secure-libs/metricsutil"
+	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/vault"
 )
 
@@ -56,3 +57,45 @@ func TestSysMetricsUnauthenticated(t *testing.T) {
 	resp = testHttpGet(t, "", addr+"/v1/sys/metrics?format=prometheus")
 	testResponseStatus(t, resp, 200)
 }
+
+func TestSysMetrics_json(t *testing.T) {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	rolling, err := vaultmetrics.NewRollingSink(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &vault.CoreConfig{
+		BuiltinRegistry:    vault.NewMockBuiltinRegistry(),
+		MetricsHelper:      metricsutil.NewMetricsHelper(inm, false),
+		MetricsRollingSink: rolling,
+	}
+	core, _, token := vault.TestCoreUnsealedWithConfig(t, conf)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	rolling.IncrCounter([]string{"vault", "core", "check_token"}, 1)
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/metrics?format=json&window=5m")
+	testResponseStatus(t, resp, 200)
+
+	var summary vaultmetrics.RollingSummary
+	testResponseBody(t, resp, &summary)
+	if summary.Window != "5m0s" {
+		t.Fatalf("bad window: %q", summary.Window)
+	}
+	if len(summary.Counters) != 1 || summary.Counters[0].Name != "vault.core.check_token" {
+		t.Fatalf("bad counters: %#v", summary.Counters)
+	}
+
+	// The shortest window is used by default
+	resp = testHttpGet(t, token, addr+"/v1/sys/metrics?format=json")
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &summary)
+	if summary.Window != "1m0s" {
+		t.Fatalf("bad window: %q", summary.Window)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/metrics?format=json&window=2m")
+	testResponseStatus(t, resp, 400)
+}
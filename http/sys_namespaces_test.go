@@ -0,0 +1,95 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysNamespaces_header(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	config := api.DefaultConfig()
+	config.Address = addr
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+
+	if _, err := client.Logical().Write("sys/namespaces/ns1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ns1/sys/namespaces/ns2", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The namespace is given by the header, the path, or both
+	nsClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsClient.SetToken(token)
+	nsClient.SetNamespace("ns1")
+	if err := nsClient.Sys().Mount("kv", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nsClient.Logical().Write("kv/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("ns1/kv/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	secret, err = nsClient.Logical().List("sys/namespaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || len(secret.Data["keys"].([]interface{})) != 1 {
+		t.Fatalf("bad: %#v", secret)
+	}
+	nsClient.SetNamespace("ns1/ns2")
+	if err := nsClient.Sys().Mount("kv", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nsClient.Logical().Write("kv/foo", map[string]interface{}{"value": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+	nsClient.SetNamespace("ns1")
+	secret, err = nsClient.Logical().Read("ns2/kv/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// The mounts of the root namespace aren't reachable from the namespace
+	if secret, err := client.Logical().Read("kv/foo"); err == nil && secret != nil {
+		t.Fatalf("expected no secret in the root namespace, got: %#v", secret)
+	}
+
+	// The cluster-wide system paths ignore the header
+	status, err := nsClient.Sys().SealStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Sealed {
+		t.Fatal("expected vault to be unsealed")
+	}
+
+	// Unknown namespaces aren't found
+	nsClient.SetNamespace("missing")
+	if _, err := nsClient.Sys().ListMounts(); err == nil {
+		t.Fatal("expected error listing the mounts of an unknown namespace")
+	} else if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != 404 {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+}
@@ -43,6 +43,13 @@ func TestSysRotate(t *testing.T) {
 	expected["data"].(map[string]interface{})["install_time"] = actualInstallTime
 	expected["install_time"] = actualInstallTime
 
+	actualEncryptions, ok := actual["data"].(map[string]interface{})["encryptions"]
+	if !ok {
+		t.Fatal("encryptions missing in data")
+	}
+	expected["data"].(map[string]interface{})["encryptions"] = actualEncryptions
+	expected["encryptions"] = actualEncryptions
+
 	expected["request_id"] = actual["request_id"]
 
 	if diff := deep.Equal(actual, expected); diff != nil {
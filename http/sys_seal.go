@@ -164,18 +164,38 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 	})
 }
 
-func handleSysSealStatus(core *vault.Core) http.Handler {
+func handleSysSealStatus(core *vault.Core, disableDetails bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
 			respondError(w, http.StatusMethodNotAllowed, nil)
 			return
 		}
 
-		handleSysSealStatusRaw(core, w, r)
+		status, err := getSealStatus(core)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if disableDetails && !requestHasValidToken(core, r) {
+			status.redact()
+		}
+
+		respondOk(w, status)
 	})
 }
 
 func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Request) {
+	status, err := getSealStatus(core)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondOk(w, status)
+}
+
+func getSealStatus(core *vault.Core) (*SealStatusResponse, error) {
 	ctx := context.Background()
 
 	sealed := core.Sealed()
@@ -188,20 +208,18 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		sealConfig, err = core.SealAccess().BarrierConfig(ctx)
 	}
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err)
-		return
+		return nil, err
 	}
 
 	if sealConfig == nil {
-		respondOk(w, &SealStatusResponse{
+		return &SealStatusResponse{
 			Type:         core.SealAccess().BarrierType(),
 			Initialized:  false,
 			Sealed:       true,
 			RecoverySeal: core.SealAccess().RecoveryKeySupported(),
 			StorageType:  core.StorageType(),
 			Version:      version.GetVersion().VersionNumber(),
-		})
-		return
+		}, nil
 	}
 
 	// Fetch the local cluster name and identifier
@@ -209,12 +227,10 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 	if !sealed {
 		cluster, err := core.Cluster(ctx)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, err)
-			return
+			return nil, err
 		}
 		if cluster == nil {
-			respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch cluster details"))
-			return
+			return nil, fmt.Errorf("failed to fetch cluster details")
 		}
 		clusterName = cluster.Name
 		clusterID = cluster.ID
@@ -222,7 +238,7 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 
 	progress, nonce := core.SecretProgress()
 
-	respondOk(w, &SealStatusResponse{
+	return &SealStatusResponse{
 		Type:         sealConfig.Type,
 		Initialized:  true,
 		Sealed:       sealed,
@@ -236,7 +252,7 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		ClusterID:    clusterID,
 		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
 		StorageType:  core.StorageType(),
-	})
+	}, nil
 }
 
 type SealStatusResponse struct {
@@ -247,7 +263,7 @@ type SealStatusResponse struct {
 	N            int    `json:"n"`
 	Progress     int    `json:"progress"`
 	Nonce        string `json:"nonce"`
-	Version      string `json:"version"`
+	Version      string `json:"version,omitempty"`
 	Migration    bool   `json:"migration"`
 	ClusterName  string `json:"cluster_name,omitempty"`
 	ClusterID    string `json:"cluster_id,omitempty"`
@@ -255,6 +271,15 @@ type SealStatusResponse struct {
 	StorageType  string `json:"storage_type,omitempty"`
 }
 
+// redact removes the version, cluster and storage details of the seal status
+// returned to the clients without a valid token.
+func (s *SealStatusResponse) redact() {
+	s.Version = ""
+	s.ClusterName = ""
+	s.ClusterID = ""
+	s.StorageType = ""
+}
+
 // Note: because we didn't provide explicit tagging in the past we can't do it
 // now because if it then no longer accepts capitalized versions it could break
 // clients
@@ -58,6 +58,40 @@ func TestSysSealStatus(t *testing.T) {
 	}
 }
 
+func TestSysSealStatus_disableDetails(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	defer ln.Close()
+	TestServerWithListenerAndProperties(t, ln, addr, core, &vault.HandlerProperties{
+		Core:                     core,
+		DisableSealStatusDetails: true,
+	})
+
+	resp := testHttpGet(t, "", addr+"/v1/sys/seal-status")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	for _, field := range []string{"version", "cluster_name", "cluster_id", "storage_type"} {
+		if actual[field] != nil {
+			t.Fatalf("unexpected %q in the response: %#v", field, actual)
+		}
+	}
+	if actual["sealed"] != false || actual["initialized"] != true {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The details are returned to the clients with a valid token
+	resp = testHttpGet(t, token, addr+"/v1/sys/seal-status")
+	actual = map[string]interface{}{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	for _, field := range []string{"version", "cluster_name", "cluster_id"} {
+		if actual[field] == nil {
+			t.Fatalf("expected %q in the response: %#v", field, actual)
+		}
+	}
+}
+
 func TestSysSealStatus_uninit(t *testing.T) {
 	core := vault.TestCore(t)
 	ln, addr := TestServer(t, core)
@@ -10,14 +10,13 @@ import (
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/vault"
 	"github.com/hashicorp/vault/vault/quotas"
 )
 
 var (
-	adjustRequest = func(c *vault.Core, r *http.Request) (*http.Request, int) {
-		return r, 0
-	}
+	adjustRequest = adjustNamespaceRequest
 
 	genericWrapping = func(core *vault.Core, in http.Handler, props *vault.HandlerProperties) http.Handler {
 		// Wrap the help wrapped handler with another layer with a generic
@@ -98,6 +97,43 @@ func rateLimitQuotaWrapping(handler http.Handler, core *vault.Core) http.Handler
 	})
 }
 
+// adjustNamespaceRequest sets the namespace of the request, which is given by
+// the X-Vault-Namespace header and the path of the request. The path is
+// rewritten to include the namespace of the header.
+func adjustNamespaceRequest(core *vault.Core, r *http.Request) (*http.Request, int) {
+	// The namespaces are only known once unsealed, a standby forwards the
+	// request as is
+	if core.Sealed() {
+		return r, 0
+	}
+	if standby, _ := core.Standby(); standby && !core.PerfStandby() {
+		return r, 0
+	}
+
+	reqPath := strings.TrimPrefix(r.URL.Path, "/v1/")
+	nsHeader := namespace.Canonicalize(r.Header.Get(consts.NamespaceHeaderName))
+
+	// The system paths configuring the whole cluster are always served from
+	// the root namespace
+	if strings.HasPrefix(reqPath, "sys/") && !vault.NamespaceSystemPath(reqPath) {
+		nsHeader = ""
+	}
+	r.Header.Del(consts.NamespaceHeaderName)
+
+	reqPath = nsHeader + reqPath
+	ns, _ := core.NamespaceByPath(reqPath)
+	if !strings.HasPrefix(ns.Path, nsHeader) {
+		// The namespace of the header doesn't exist
+		return r, http.StatusNotFound
+	}
+	if ns.ID == namespace.RootNamespaceID {
+		return r, 0
+	}
+
+	r.URL.Path = "/v1/" + reqPath
+	return r.WithContext(namespace.ContextWithNamespace(r.Context(), ns)), 0
+}
+
 func parseRemoteIPAddress(r *http.Request) string {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
 	"github.com/hashicorp/vault/sdk/database/newdbplugin"
+	"github.com/hashicorp/vault/sdk/helper/template"
 	"github.com/mitchellh/mapstructure"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -19,11 +20,20 @@ import (
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
-const mongoDBTypeName = "mongodb"
+const (
+	mongoDBTypeName = "mongodb"
+
+	// maxUsernameLength is the maximum length of a user name in MongoDB
+	maxUsernameLength = 100
+)
 
 // MongoDB is an implementation of Database interface
 type MongoDB struct {
 	*mongoDBConnectionProducer
+
+	// usernameProducer renders the username_template set in the connection
+	// configuration, if any
+	usernameProducer *template.StringTemplate
 }
 
 var _ newdbplugin.Database = &MongoDB{}
@@ -77,7 +87,13 @@ func (m *MongoDB) Initialize(ctx context.Context, req newdbplugin.InitializeRequ
 
 	m.RawConfig = req.Config
 
-	err := mapstructure.WeakDecode(req.Config, m.mongoDBConnectionProducer)
+	usernameProducer, err := credsutil.UsernameTemplateFromConfig(req.Config)
+	if err != nil {
+		return newdbplugin.InitializeResponse{}, err
+	}
+	m.usernameProducer = usernameProducer
+
+	err = mapstructure.WeakDecode(req.Config, m.mongoDBConnectionProducer)
 	if err != nil {
 		return newdbplugin.InitializeResponse{}, err
 	}
@@ -120,6 +136,28 @@ func (m *MongoDB) Initialize(ctx context.Context, req newdbplugin.InitializeRequ
 	return resp, nil
 }
 
+func (m *MongoDB) generateUsername(usernameConfig newdbplugin.UsernameMetadata) (string, error) {
+	if m.usernameProducer != nil {
+		username, err := m.usernameProducer.Generate(usernameConfig)
+		if err != nil {
+			return "", err
+		}
+		// Truncating would cut the random part of the username off, so the
+		// template has to keep it short enough itself
+		if len(username) > maxUsernameLength {
+			return "", fmt.Errorf("username %q generated by the username template is longer than the maximum of %d characters", username, maxUsernameLength)
+		}
+		return username, nil
+	}
+
+	return credsutil.GenerateUsername(
+		credsutil.DisplayName(usernameConfig.DisplayName, 15),
+		credsutil.RoleName(usernameConfig.RoleName, 15),
+		credsutil.MaxLength(maxUsernameLength),
+		credsutil.Separator("-"),
+	)
+}
+
 func (m *MongoDB) NewUser(ctx context.Context, req newdbplugin.NewUserRequest) (newdbplugin.NewUserResponse, error) {
 	// Grab the lock
 	m.Lock()
@@ -129,12 +167,7 @@ func (m *MongoDB) NewUser(ctx context.Context, req newdbplugin.NewUserRequest) (
 		return newdbplugin.NewUserResponse{}, dbutil.ErrEmptyCreationStatement
 	}
 
-	username, err := credsutil.GenerateUsername(
-		credsutil.DisplayName(req.UsernameConfig.DisplayName, 15),
-		credsutil.RoleName(req.UsernameConfig.RoleName, 15),
-		credsutil.MaxLength(100),
-		credsutil.Separator("-"),
-	)
+	username, err := m.generateUsername(req.UsernameConfig)
 	if err != nil {
 		return newdbplugin.NewUserResponse{}, err
 	}
@@ -51,6 +51,47 @@ func TestMongoDB_Initialize(t *testing.T) {
 	}
 }
 
+func TestMongoDB_UsernameTemplate(t *testing.T) {
+	usernameConfig := newdbplugin.UsernameMetadata{
+		DisplayName: "token-displayname",
+		RoleName:    "readonly-role",
+	}
+
+	db := new()
+	// The connection isn't verified, so no database is needed
+	_, err := db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url":    "mongodb://localhost:27017/admin",
+			"username_template": `{{.RoleName | replace "-" "_"}}_{{random 8}}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err := db.generateUsername(usernameConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(username, "readonly_role_") || len(username) != len("readonly_role_")+8 {
+		t.Fatalf("bad templated username: %q", username)
+	}
+
+	db = new()
+	_, err = db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url":    "mongodb://localhost:27017/admin",
+			"username_template": "{{.DisplayName}}_{{.RoleName}}_{{random 80}}",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.generateUsername(usernameConfig)
+	if err == nil || !strings.Contains(err.Error(), "longer than the maximum of 100 characters") {
+		t.Fatalf("expected a username over the length limit to be rejected, got: %v", err)
+	}
+}
+
 func TestMongoDB_CreateUser(t *testing.T) {
 	cleanup, connURL := mongodb.PrepareTestContainer(t, "latest")
 	defer cleanup()
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
 	"github.com/hashicorp/vault/sdk/database/newdbplugin"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
 
 	stdmysql "github.com/go-sql-driver/mysql"
 )
@@ -42,6 +43,10 @@ var _ newdbplugin.Database = (*MySQL)(nil)
 type MySQL struct {
 	*mySQLConnectionProducer
 	legacy bool
+
+	// usernameProducer renders the username_template set in the connection
+	// configuration, if any
+	usernameProducer *template.StringTemplate
 }
 
 // New implements builtinplugins.BuiltinFactory
@@ -101,10 +106,18 @@ func (m *MySQL) getConnection(ctx context.Context) (*sql.DB, error) {
 }
 
 func (m *MySQL) Initialize(ctx context.Context, req newdbplugin.InitializeRequest) (newdbplugin.InitializeResponse, error) {
-	err := m.mySQLConnectionProducer.Initialize(ctx, req.Config, req.VerifyConnection)
+	usernameProducer, err := credsutil.UsernameTemplateFromConfig(req.Config)
+	if err != nil {
+		return newdbplugin.InitializeResponse{}, err
+	}
+
+	err = m.mySQLConnectionProducer.Initialize(ctx, req.Config, req.VerifyConnection)
 	if err != nil {
 		return newdbplugin.InitializeResponse{}, err
 	}
+	m.Lock()
+	m.usernameProducer = usernameProducer
+	m.Unlock()
 	resp := newdbplugin.InitializeResponse{
 		Config: req.Config,
 	}
@@ -155,6 +168,22 @@ func (m *MySQL) generateUsername(req newdbplugin.NewUserRequest) (string, error)
 		maxLen = UsernameLen
 	}
 
+	m.Lock()
+	usernameProducer := m.usernameProducer
+	m.Unlock()
+	if usernameProducer != nil {
+		username, err := usernameProducer.Generate(req.UsernameConfig)
+		if err != nil {
+			return "", errwrap.Wrapf("error generating username: {{err}}", err)
+		}
+		// Truncating would cut the random part of the username off, so the
+		// template has to keep it short enough itself
+		if len(username) > maxLen {
+			return "", fmt.Errorf("username %q generated by the username template is longer than the maximum of %d characters", username, maxLen)
+		}
+		return username, nil
+	}
+
 	username, err := credsutil.GenerateUsername(
 		credsutil.DisplayName(req.UsernameConfig.DisplayName, dispNameLen),
 		credsutil.RoleName(req.UsernameConfig.RoleName, roleNameLen),
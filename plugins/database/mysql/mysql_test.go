@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -222,6 +223,70 @@ func testCreateUser(t *testing.T, db *MySQL, connURL string) {
 	}
 }
 
+func TestMySQL_UsernameTemplate(t *testing.T) {
+	req := newdbplugin.NewUserRequest{
+		UsernameConfig: newdbplugin.UsernameMetadata{
+			DisplayName: "token-displayname",
+			RoleName:    "readonly-role",
+		},
+	}
+
+	type testCase struct {
+		legacy      bool
+		template    string
+		expected    string
+		expectedErr bool
+	}
+
+	tests := map[string]testCase{
+		"short enough": {
+			template: `{{.RoleName}}_{{random 8 | lowercase}}`,
+			expected: "^readonly-role_[a-z0-9]{8}$",
+		},
+		"too long": {
+			template:    `{{.DisplayName}}_{{.RoleName}}_{{random 20}}`,
+			expectedErr: true,
+		},
+		"legacy short enough": {
+			legacy:   true,
+			template: `{{.RoleName | truncate 8}}_{{random 7}}`,
+			expected: "^readonly_[a-zA-Z0-9]{7}$",
+		},
+		"legacy too long": {
+			legacy:      true,
+			template:    `{{.RoleName}}_{{random 8}}`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := new(test.legacy)
+			// The connection isn't verified, so no database is needed
+			_, err := db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+				Config: map[string]interface{}{
+					"connection_url":    "root:secret@tcp(localhost:3306)/",
+					"username_template": test.template,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			username, err := db.generateUsername(req)
+			if test.expectedErr && err == nil {
+				t.Fatalf("err expected, got username %q", username)
+			}
+			if !test.expectedErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+			if test.expected != "" && !regexp.MustCompile(test.expected).MatchString(username) {
+				t.Fatalf("bad username: %q", username)
+			}
+		})
+	}
+}
+
 func TestMySQL_RotateRootCredentials(t *testing.T) {
 	type testCase struct {
 		statements []string
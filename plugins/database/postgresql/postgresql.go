@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/vault/sdk/database/newdbplugin"
 	"github.com/hashicorp/vault/sdk/helper/dbtxn"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
 	"github.com/lib/pq"
 )
 
@@ -29,6 +30,9 @@ ALTER ROLE "{{username}}" WITH PASSWORD '{{password}}';
 `
 
 	expirationFormat = "2006-01-02 15:04:05-0700"
+
+	// maxUsernameLength is the maximum length of an identifier in PostgreSQL
+	maxUsernameLength = 63
 )
 
 var (
@@ -81,13 +85,25 @@ func Run(apiTLSConfig *api.TLSConfig) error {
 
 type PostgreSQL struct {
 	*connutil.SQLConnectionProducer
+
+	// usernameProducer renders the username_template set in the connection
+	// configuration, if any
+	usernameProducer *template.StringTemplate
 }
 
 func (p *PostgreSQL) Initialize(ctx context.Context, req newdbplugin.InitializeRequest) (newdbplugin.InitializeResponse, error) {
+	usernameProducer, err := credsutil.UsernameTemplateFromConfig(req.Config)
+	if err != nil {
+		return newdbplugin.InitializeResponse{}, err
+	}
+
 	newConf, err := p.SQLConnectionProducer.Init(ctx, req.Config, req.VerifyConnection)
 	if err != nil {
 		return newdbplugin.InitializeResponse{}, err
 	}
+	p.Lock()
+	p.usernameProducer = usernameProducer
+	p.Unlock()
 	resp := newdbplugin.InitializeResponse{
 		Config: newConf,
 	}
@@ -229,6 +245,28 @@ func (p *PostgreSQL) changeUserExpiration(ctx context.Context, username string,
 	return tx.Commit()
 }
 
+func (p *PostgreSQL) generateUsername(usernameConfig newdbplugin.UsernameMetadata) (string, error) {
+	if p.usernameProducer != nil {
+		username, err := p.usernameProducer.Generate(usernameConfig)
+		if err != nil {
+			return "", err
+		}
+		// PostgreSQL would silently truncate the name, so the template has to
+		// keep it short enough itself
+		if len(username) > maxUsernameLength {
+			return "", fmt.Errorf("username %q generated by the username template is longer than the maximum of %d characters", username, maxUsernameLength)
+		}
+		return username, nil
+	}
+
+	return credsutil.GenerateUsername(
+		credsutil.DisplayName(usernameConfig.DisplayName, 8),
+		credsutil.RoleName(usernameConfig.RoleName, 8),
+		credsutil.Separator("-"),
+		credsutil.MaxLength(maxUsernameLength),
+	)
+}
+
 func (p *PostgreSQL) NewUser(ctx context.Context, req newdbplugin.NewUserRequest) (newdbplugin.NewUserResponse, error) {
 	if len(req.Statements.Commands) == 0 {
 		return newdbplugin.NewUserResponse{}, dbutil.ErrEmptyCreationStatement
@@ -237,12 +275,7 @@ func (p *PostgreSQL) NewUser(ctx context.Context, req newdbplugin.NewUserRequest
 	p.Lock()
 	defer p.Unlock()
 
-	username, err := credsutil.GenerateUsername(
-		credsutil.DisplayName(req.UsernameConfig.DisplayName, 8),
-		credsutil.RoleName(req.UsernameConfig.RoleName, 8),
-		credsutil.Separator("-"),
-		credsutil.MaxLength(63),
-	)
+	username, err := p.generateUsername(req.UsernameConfig)
 	if err != nil {
 		return newdbplugin.NewUserResponse{}, err
 	}
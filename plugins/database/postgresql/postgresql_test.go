@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -668,6 +669,75 @@ $$
 	`GRANT CONNECT ON DATABASE "postgres" TO "{{name}}";`,
 }
 
+func TestPostgreSQL_UsernameTemplate(t *testing.T) {
+	usernameConfig := newdbplugin.UsernameMetadata{
+		DisplayName: "token-displayname",
+		RoleName:    "readonly-role",
+	}
+
+	db := new()
+	// The connection isn't verified, so no database is needed
+	_, err := db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url": "postgres://localhost:5432/postgres",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err := db.generateUsername(usernameConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile("^v-token-di-readonly-[a-zA-Z0-9]{20}-[0-9]+$").MatchString(username) {
+		t.Fatalf("bad default username: %q", username)
+	}
+
+	db = new()
+	_, err = db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url":    "postgres://localhost:5432/postgres",
+			"username_template": `{{.RoleName | replace "-" "_"}}_{{random 8 | lowercase}}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err = db.generateUsername(usernameConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile("^readonly_role_[a-z0-9]{8}$").MatchString(username) {
+		t.Fatalf("bad templated username: %q", username)
+	}
+
+	db = new()
+	_, err = db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url":    "postgres://localhost:5432/postgres",
+			"username_template": "{{.Unknown}}",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an invalid template to be rejected")
+	}
+
+	db = new()
+	_, err = db.Initialize(context.Background(), newdbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url":    "postgres://localhost:5432/postgres",
+			"username_template": "{{.DisplayName}}_{{.RoleName}}_{{random 50}}",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.generateUsername(usernameConfig)
+	if err == nil || !strings.Contains(err.Error(), "longer than the maximum of 63 characters") {
+		t.Fatalf("expected a username over the length limit to be rejected, got: %v", err)
+	}
+}
+
 func TestContainsMultilineStatement(t *testing.T) {
 	type testCase struct {
 		Input    string
@@ -4,8 +4,15 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/template"
 )
 
+// UsernameTemplateKey is the plugin configuration entry holding a custom
+// template for generated usernames
+const UsernameTemplateKey = "username_template"
+
 type CaseOp int
 
 const (
@@ -102,6 +109,47 @@ func GenerateUsername(opts ...UsernameOpt) (string, error) {
 	return b.makeUsername()
 }
 
+// UsernameTemplateFromConfig parses the username template set in a plugin's
+// configuration. It returns nil when no template is set, in which case the
+// plugin should keep generating usernames with GenerateUsername. The template
+// is rendered with the DisplayName and RoleName of the request.
+func UsernameTemplateFromConfig(config map[string]interface{}) (*template.StringTemplate, error) {
+	raw, ok := config[UsernameTemplateKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	rawTemplate, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", UsernameTemplateKey)
+	}
+	if rawTemplate == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.NewTemplate(template.Template(rawTemplate))
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("invalid %s: {{err}}", UsernameTemplateKey), err)
+	}
+
+	// Render the template once so that mistakes are reported when the
+	// connection is configured rather than when credentials are requested
+	username, err := tmpl.Generate(struct {
+		DisplayName string
+		RoleName    string
+	}{
+		DisplayName: "display-name",
+		RoleName:    "role-name",
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("invalid %s: {{err}}", UsernameTemplateKey), err)
+	}
+	if username == "" {
+		return nil, fmt.Errorf("%s must not render an empty username", UsernameTemplateKey)
+	}
+
+	return &tmpl, nil
+}
+
 func trunc(str string, l int) string {
 	switch {
 	case l > 0:
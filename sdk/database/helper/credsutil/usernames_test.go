@@ -142,3 +142,45 @@ func TestGenerateUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestUsernameTemplateFromConfig(t *testing.T) {
+	tmpl, err := UsernameTemplateFromConfig(map[string]interface{}{})
+	if err != nil || tmpl != nil {
+		t.Fatalf("expected no template: %v %v", tmpl, err)
+	}
+	tmpl, err = UsernameTemplateFromConfig(map[string]interface{}{UsernameTemplateKey: ""})
+	if err != nil || tmpl != nil {
+		t.Fatalf("expected no template: %v %v", tmpl, err)
+	}
+
+	for _, invalid := range []interface{}{
+		42,
+		"{{.DisplayName",
+		"{{.Missing}}",
+		"{{.RoleName | truncate 0}}",
+		"   ",
+	} {
+		if _, err := UsernameTemplateFromConfig(map[string]interface{}{UsernameTemplateKey: invalid}); err == nil {
+			t.Fatalf("expected an error for %#v", invalid)
+		}
+	}
+
+	tmpl, err = UsernameTemplateFromConfig(map[string]interface{}{
+		UsernameTemplateKey: `{{printf "v_%s_%s" (.RoleName | truncate 4) (random 8) | uppercase}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err := tmpl.Generate(struct {
+		DisplayName string
+		RoleName    string
+	}{
+		RoleName: "readonly",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile("^V_READ_[A-Z0-9]{8}$").MatchString(username) {
+		t.Fatalf("bad username: %q", username)
+	}
+}
@@ -193,6 +193,12 @@ func ParsePEMBundle(pemBundle string) (*ParsedCertBundle, error) {
 
 // GeneratePrivateKey generates a private key with the specified type and key bits
 func GeneratePrivateKey(keyType string, keyBits int, container ParsedPrivateKeyContainer) error {
+	return GeneratePrivateKeyWithRandomSource(keyType, keyBits, container, rand.Reader)
+}
+
+// GeneratePrivateKeyWithRandomSource generates a private key with the
+// specified type and key bits, using the given source of randomness
+func GeneratePrivateKeyWithRandomSource(keyType string, keyBits int, container ParsedPrivateKeyContainer, randReader io.Reader) error {
 	var err error
 	var privateKeyType PrivateKeyType
 	var privateKeyBytes []byte
@@ -201,7 +207,7 @@ func GeneratePrivateKey(keyType string, keyBits int, container ParsedPrivateKeyC
 	switch keyType {
 	case "rsa":
 		privateKeyType = RSAPrivateKey
-		privateKey, err = rsa.GenerateKey(rand.Reader, keyBits)
+		privateKey, err = rsa.GenerateKey(randReader, keyBits)
 		if err != nil {
 			return errutil.InternalError{Err: fmt.Sprintf("error generating RSA private key: %v", err)}
 		}
@@ -221,7 +227,7 @@ func GeneratePrivateKey(keyType string, keyBits int, container ParsedPrivateKeyC
 		default:
 			return errutil.UserError{Err: fmt.Sprintf("unsupported bit length for EC key: %d", keyBits)}
 		}
-		privateKey, err = ecdsa.GenerateKey(curve, rand.Reader)
+		privateKey, err = ecdsa.GenerateKey(curve, randReader)
 		if err != nil {
 			return errutil.InternalError{Err: fmt.Sprintf("error generating EC private key: %v", err)}
 		}
@@ -239,7 +245,13 @@ func GeneratePrivateKey(keyType string, keyBits int, container ParsedPrivateKeyC
 
 // GenerateSerialNumber generates a serial number suitable for a certificate
 func GenerateSerialNumber() (*big.Int, error) {
-	serial, err := rand.Int(rand.Reader, (&big.Int{}).Exp(big.NewInt(2), big.NewInt(159), nil))
+	return GenerateSerialNumberWithRandomSource(rand.Reader)
+}
+
+// GenerateSerialNumberWithRandomSource generates a serial number suitable for
+// a certificate, using the given source of randomness
+func GenerateSerialNumberWithRandomSource(randReader io.Reader) (*big.Int, error) {
+	serial, err := rand.Int(randReader, (&big.Int{}).Exp(big.NewInt(2), big.NewInt(159), nil))
 	if err != nil {
 		return nil, errutil.InternalError{Err: fmt.Sprintf("error generating serial number: %v", err)}
 	}
@@ -494,17 +506,23 @@ func ValidateKeyTypeLength(keyType string, keyBits int) error {
 // Performs the heavy lifting of creating a certificate. Returns
 // a fully-filled-in ParsedCertBundle.
 func CreateCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
+	return CreateCertificateWithRandomSource(data, rand.Reader)
+}
+
+// CreateCertificateWithRandomSource is CreateCertificate, using the given
+// source of randomness to generate the key and the certificate
+func CreateCertificateWithRandomSource(data *CreationBundle, randReader io.Reader) (*ParsedCertBundle, error) {
 	var err error
 	result := &ParsedCertBundle{}
 
-	serialNumber, err := GenerateSerialNumber()
+	serialNumber, err := GenerateSerialNumberWithRandomSource(randReader)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := GeneratePrivateKey(data.Params.KeyType,
+	if err := GeneratePrivateKeyWithRandomSource(data.Params.KeyType,
 		data.Params.KeyBits,
-		result); err != nil {
+		result, randReader); err != nil {
 		return nil, err
 	}
 
@@ -569,7 +587,7 @@ func CreateCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
 		caCert := data.SigningBundle.Certificate
 		certTemplate.AuthorityKeyId = caCert.SubjectKeyId
 
-		certBytes, err = x509.CreateCertificate(rand.Reader, certTemplate, caCert, result.PrivateKey.Public(), data.SigningBundle.PrivateKey)
+		certBytes, err = x509.CreateCertificate(randReader, certTemplate, caCert, result.PrivateKey.Public(), data.SigningBundle.PrivateKey)
 	} else {
 		// Creating a self-signed root
 		if data.Params.MaxPathLength == 0 {
@@ -588,7 +606,7 @@ func CreateCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
 
 		certTemplate.AuthorityKeyId = subjKeyID
 		certTemplate.BasicConstraintsValid = true
-		certBytes, err = x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, result.PrivateKey.Public(), result.PrivateKey)
+		certBytes, err = x509.CreateCertificate(randReader, certTemplate, certTemplate, result.PrivateKey.Public(), result.PrivateKey)
 	}
 
 	if err != nil {
@@ -623,12 +641,18 @@ var oidExtensionBasicConstraints = []int{2, 5, 29, 19}
 // Creates a CSR. This is currently only meant for use when
 // generating an intermediate certificate.
 func CreateCSR(data *CreationBundle, addBasicConstraints bool) (*ParsedCSRBundle, error) {
+	return CreateCSRWithRandomSource(data, addBasicConstraints, rand.Reader)
+}
+
+// CreateCSRWithRandomSource is CreateCSR, using the given source of
+// randomness to generate the key and the CSR
+func CreateCSRWithRandomSource(data *CreationBundle, addBasicConstraints bool, randReader io.Reader) (*ParsedCSRBundle, error) {
 	var err error
 	result := &ParsedCSRBundle{}
 
-	if err := GeneratePrivateKey(data.Params.KeyType,
+	if err := GeneratePrivateKeyWithRandomSource(data.Params.KeyType,
 		data.Params.KeyBits,
-		result); err != nil {
+		result, randReader); err != nil {
 		return nil, err
 	}
 
@@ -669,7 +693,7 @@ func CreateCSR(data *CreationBundle, addBasicConstraints bool) (*ParsedCSRBundle
 		csrTemplate.SignatureAlgorithm = x509.ECDSAWithSHA256
 	}
 
-	csr, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, result.PrivateKey)
+	csr, err := x509.CreateCertificateRequest(randReader, csrTemplate, result.PrivateKey)
 	if err != nil {
 		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to create certificate: %s", err)}
 	}
@@ -686,6 +710,12 @@ func CreateCSR(data *CreationBundle, addBasicConstraints bool) (*ParsedCSRBundle
 // Performs the heavy lifting of generating a certificate from a CSR.
 // Returns a ParsedCertBundle sans private keys.
 func SignCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
+	return SignCertificateWithRandomSource(data, rand.Reader)
+}
+
+// SignCertificateWithRandomSource is SignCertificate, using the given source
+// of randomness to generate the certificate
+func SignCertificateWithRandomSource(data *CreationBundle, randReader io.Reader) (*ParsedCertBundle, error) {
 	switch {
 	case data == nil:
 		return nil, errutil.UserError{Err: "nil data bundle given to signCertificate"}
@@ -704,7 +734,7 @@ func SignCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
 
 	result := &ParsedCertBundle{}
 
-	serialNumber, err := GenerateSerialNumber()
+	serialNumber, err := GenerateSerialNumberWithRandomSource(randReader)
 	if err != nil {
 		return nil, err
 	}
@@ -797,7 +827,7 @@ func SignCertificate(data *CreationBundle) (*ParsedCertBundle, error) {
 		certTemplate.PermittedDNSDomainsCritical = true
 	}
 
-	certBytes, err = x509.CreateCertificate(rand.Reader, certTemplate, caCert, data.CSR.PublicKey, data.SigningBundle.PrivateKey)
+	certBytes, err = x509.CreateCertificate(randReader, certTemplate, caCert, data.CSR.PublicKey, data.SigningBundle.PrivateKey)
 
 	if err != nil {
 		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to create certificate: %s", err)}
@@ -16,6 +16,15 @@ const (
 	// SSRF protection.
 	RequestHeaderName = "X-Vault-Request"
 
+	// IndexHeaderName is the name of the header conveying the storage write
+	// index of a response, and the minimum index required by a request for
+	// read-after-write consistency.
+	IndexHeaderName = "X-Vault-Index"
+
+	// InconsistentHeaderName is the name of the header used to select what a
+	// node does with a request requiring an index it hasn't reached yet.
+	InconsistentHeaderName = "X-Vault-Inconsistent"
+
 	// PerformanceReplicationALPN is the negotiated protocol used for
 	// performance replication.
 	PerformanceReplicationALPN = "replication_v1"
@@ -30,6 +30,7 @@ func TestPopulate_Basic(t *testing.T) {
 		nilEntity         bool
 		validityCheckOnly bool
 		aliasMetadata     map[string]string
+		aliasCustomMeta   map[string]string
 		groupName         string
 		groupMetadata     map[string]string
 		groupMemberships  []string
@@ -192,6 +193,21 @@ func TestPopulate_Basic(t *testing.T) {
 			metadata: map[string]string{"foo": "bar"},
 			err:      ErrTemplateValueNotFound,
 		},
+		{
+			name:            "alias_custom_metadata",
+			input:           "path \"secret/data/{{identity.entity.aliases.foomount.custom_metadata.team}}/*\" {\n\tcapabilities = [\"read\"]\n}",
+			aliasAccessor:   "foomount",
+			aliasMetadata:   map[string]string{"team": "login"},
+			aliasCustomMeta: map[string]string{"team": "payments"},
+			output:          "path \"secret/data/payments/*\" {\n\tcapabilities = [\"read\"]\n}",
+		},
+		{
+			name:          "alias_custom_metadata_not_found",
+			input:         "path \"secret/data/{{identity.entity.aliases.foomount.custom_metadata.team}}/*\" {}",
+			aliasAccessor: "foomount",
+			aliasMetadata: map[string]string{"team": "login"},
+			err:           ErrTemplateValueNotFound,
+		},
 		{
 			name:          "alias_metadata_object_disallowed",
 			input:         "{{identity.entity.aliases.foomount.metadata}}",
@@ -321,6 +337,14 @@ func TestPopulate_Basic(t *testing.T) {
 			aliasAccessor: "aws_123",
 			output:        `{}`,
 		},
+		{
+			mode:            JSONTemplating,
+			name:            "all alias custom metadata",
+			input:           "{{identity.entity.aliases.aws_123.custom_metadata}}",
+			aliasAccessor:   "aws_123",
+			aliasCustomMeta: map[string]string{"team": "payments"},
+			output:          `{"team":"payments"}`,
+		},
 		{
 			mode:          JSONTemplating,
 			name:          "all alias metadata, accessor not found",
@@ -343,10 +367,11 @@ func TestPopulate_Basic(t *testing.T) {
 		if test.aliasAccessor != "" {
 			entity.Aliases = []*logical.Alias{
 				{
-					MountAccessor: test.aliasAccessor,
-					ID:            test.aliasID,
-					Name:          test.aliasName,
-					Metadata:      test.aliasMetadata,
+					MountAccessor:  test.aliasAccessor,
+					ID:             test.aliasID,
+					Name:           test.aliasName,
+					Metadata:       test.aliasMetadata,
+					CustomMetadata: test.aliasCustomMeta,
 				},
 			}
 		}
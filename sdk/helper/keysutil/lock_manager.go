@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -52,6 +53,13 @@ type PolicyRequest struct {
 
 	// Whether to allow plaintext backup
 	AllowPlaintextBackup bool
+
+	// Whether to allow rotation of an imported key
+	AllowImportedKeyRotation bool
+
+	// How long the latest version of the key is used before the key is
+	// automatically rotated
+	AutoRotatePeriod time.Duration
 }
 
 type LockManager struct {
@@ -327,57 +335,12 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 		// to the user to let them know that their request can't be satisfied
 		// because we don't know if the parameters match.
 
-		switch req.KeyType {
-		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
-			if req.Convergent && !req.Derived {
-				cleanup()
-				return nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
-			}
-
-		case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
-			if req.Derived || req.Convergent {
-				cleanup()
-				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		case KeyType_ED25519:
-			if req.Convergent {
-				cleanup()
-				return nil, false, fmt.Errorf("convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
-			if req.Derived || req.Convergent {
-				cleanup()
-				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		default:
+		if err := validatePolicyRequest(req); err != nil {
 			cleanup()
-			return nil, false, fmt.Errorf("unsupported key type %v", req.KeyType)
-		}
-
-		p = &Policy{
-			l:                    new(sync.RWMutex),
-			Name:                 req.Name,
-			Type:                 req.KeyType,
-			Derived:              req.Derived,
-			Exportable:           req.Exportable,
-			AllowPlaintextBackup: req.AllowPlaintextBackup,
+			return nil, false, err
 		}
 
-		if req.Derived {
-			p.KDF = Kdf_hkdf_sha256
-			if req.Convergent {
-				p.ConvergentEncryption = true
-				// As of version 3 we store the version within each key, so we
-				// set to -1 to indicate that the value in the policy has no
-				// meaning. We still, for backwards compatibility, fall back to
-				// this value if the key doesn't have one, which means it will
-				// only be -1 in the case where every key version is >= 3
-				p.ConvergentVersion = -1
-			}
-		}
+		p = policyFromRequest(req)
 
 		// Performs the actual persist and does setup
 		err = p.Rotate(ctx, req.Storage, rand)
@@ -419,6 +382,46 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 	return
 }
 
+// ImportPolicy acquires an exclusive lock on the policy name and creates a
+// new policy with the given key material as its first version. It errors out
+// if a policy with that name already exists.
+func (lm *LockManager) ImportPolicy(ctx context.Context, req PolicyRequest, key []byte, rand io.Reader) error {
+	lock := locksutil.LockForKey(lm.keyLocks, req.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if lm.useCache {
+		if _, ok := lm.cache.Load(req.Name); ok {
+			return errutil.UserError{Err: fmt.Sprintf("key %q already exists", req.Name)}
+		}
+	}
+
+	p, err := lm.getPolicyFromStorage(ctx, req.Storage, req.Name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("key %q already exists", req.Name)}
+	}
+
+	if err := validatePolicyRequest(req); err != nil {
+		return errutil.UserError{Err: err.Error()}
+	}
+
+	p = policyFromRequest(req)
+	p.AllowImportedKeyRotation = req.AllowImportedKeyRotation
+
+	// Performs the actual persist
+	if err := p.Import(ctx, req.Storage, key, rand); err != nil {
+		return err
+	}
+
+	if lm.useCache {
+		lm.cache.Store(req.Name, p)
+	}
+	return nil
+}
+
 func (lm *LockManager) DeletePolicy(ctx context.Context, storage logical.Storage, name string) error {
 	var p *Policy
 	var err error
@@ -477,3 +480,63 @@ func (lm *LockManager) DeletePolicy(ctx context.Context, storage logical.Storage
 func (lm *LockManager) getPolicyFromStorage(ctx context.Context, storage logical.Storage, name string) (*Policy, error) {
 	return LoadPolicy(ctx, storage, "policy/"+name)
 }
+
+// validatePolicyRequest checks that the options requested for a new policy
+// are supported by its key type
+func validatePolicyRequest(req PolicyRequest) error {
+	switch req.KeyType {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		if req.Convergent && !req.Derived {
+			return fmt.Errorf("convergent encryption requires derivation to be enabled")
+		}
+
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		if req.Derived || req.Convergent {
+			return fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	case KeyType_ED25519:
+		if req.Convergent {
+			return fmt.Errorf("convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+		if req.Derived || req.Convergent {
+			return fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	default:
+		return fmt.Errorf("unsupported key type %v", req.KeyType)
+	}
+
+	return nil
+}
+
+// policyFromRequest returns a new policy, without any keys, with the options
+// of the request
+func policyFromRequest(req PolicyRequest) *Policy {
+	p := &Policy{
+		l:                    new(sync.RWMutex),
+		Name:                 req.Name,
+		Type:                 req.KeyType,
+		Derived:              req.Derived,
+		Exportable:           req.Exportable,
+		AllowPlaintextBackup: req.AllowPlaintextBackup,
+		AutoRotatePeriod:     req.AutoRotatePeriod,
+	}
+
+	if req.Derived {
+		p.KDF = Kdf_hkdf_sha256
+		if req.Convergent {
+			p.ConvergentEncryption = true
+			// As of version 3 we store the version within each key, so we
+			// set to -1 to indicate that the value in the policy has no
+			// meaning. We still, for backwards compatibility, fall back to
+			// this value if the key doesn't have one, which means it will
+			// only be -1 in the case where every key version is >= 3
+			p.ConvergentVersion = -1
+		}
+	}
+
+	return p
+}
@@ -374,6 +374,19 @@ type Policy struct {
 	// policy object.
 	StoragePrefix string `json:"storage_prefix"`
 
+	// Imported indicates whether the key material was imported rather than
+	// generated by Vault
+	Imported bool `json:"imported"`
+
+	// AllowImportedKeyRotation allows rotating an imported key, in which case
+	// the new versions are generated by Vault
+	AllowImportedKeyRotation bool `json:"allow_imported_key_rotation"`
+
+	// AutoRotatePeriod is how long the latest version of the key is used
+	// before the key is automatically rotated. Zero disables automatic
+	// rotation.
+	AutoRotatePeriod time.Duration `json:"auto_rotate_period"`
+
 	// versionPrefixCache stores caches of version prefix strings and the split
 	// version template.
 	versionPrefixCache sync.Map
@@ -1336,6 +1349,10 @@ func (p *Policy) VerifySignature(context, input []byte, hashAlgorithm HashType,
 }
 
 func (p *Policy) Rotate(ctx context.Context, storage logical.Storage, randReader io.Reader) (retErr error) {
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return errutil.UserError{Err: fmt.Sprintf("imported key %q does not allow rotation within Vault", p.Name)}
+	}
+
 	priorLatestVersion := p.LatestVersion
 	priorMinDecryptionVersion := p.MinDecryptionVersion
 	var priorKeys keyEntryMap
@@ -1406,19 +1423,10 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage, randReader
 		entry.EC_D = privKey.D
 		entry.EC_X = privKey.X
 		entry.EC_Y = privKey.Y
-		derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+		entry.FormattedPublicKey, err = formatECDSAPublicKey(&privKey.PublicKey)
 		if err != nil {
-			return errwrap.Wrapf("error marshaling public key: {{err}}", err)
-		}
-		pemBlock := &pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: derBytes,
-		}
-		pemBytes := pem.EncodeToMemory(pemBlock)
-		if pemBytes == nil || len(pemBytes) == 0 {
-			return fmt.Errorf("error PEM-encoding public key")
+			return err
 		}
-		entry.FormattedPublicKey = string(pemBytes)
 
 	case KeyType_ED25519:
 		pub, pri, err := ed25519.GenerateKey(randReader)
@@ -1461,6 +1469,123 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage, randReader
 	return p.Persist(ctx, storage)
 }
 
+// Import sets the given key material as the first version of a new policy.
+// Symmetric keys are given as raw bytes and asymmetric keys as PKCS #8,
+// DER-encoded private keys.
+func (p *Policy) Import(ctx context.Context, storage logical.Storage, key []byte, randReader io.Reader) error {
+	now := time.Now()
+	entry := KeyEntry{
+		CreationTime:           now,
+		DeprecatedCreationTime: now.Unix(),
+	}
+
+	hmacKey, err := uuid.GenerateRandomBytesWithReader(32, randReader)
+	if err != nil {
+		return err
+	}
+	entry.HMACKey = hmacKey
+
+	switch p.Type {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		numBytes := 32
+		if p.Type == KeyType_AES128_GCM96 {
+			numBytes = 16
+		}
+		if len(key) != numBytes {
+			return errutil.UserError{Err: fmt.Sprintf("invalid key size %d bytes for key type %v", len(key), p.Type)}
+		}
+		entry.Key = key
+
+	default:
+		parsedKey, err := x509.ParsePKCS8PrivateKey(key)
+		if err != nil {
+			return errutil.UserError{Err: fmt.Sprintf("error parsing asymmetric key: %v", err)}
+		}
+
+		switch p.Type {
+		case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+			curve := elliptic.P256()
+			switch p.Type {
+			case KeyType_ECDSA_P384:
+				curve = elliptic.P384()
+			case KeyType_ECDSA_P521:
+				curve = elliptic.P521()
+			}
+
+			privKey, ok := parsedKey.(*ecdsa.PrivateKey)
+			if !ok || privKey.Curve != curve {
+				return errutil.UserError{Err: fmt.Sprintf("invalid key for key type %v", p.Type)}
+			}
+			entry.EC_D = privKey.D
+			entry.EC_X = privKey.X
+			entry.EC_Y = privKey.Y
+			entry.FormattedPublicKey, err = formatECDSAPublicKey(&privKey.PublicKey)
+			if err != nil {
+				return err
+			}
+
+		case KeyType_ED25519:
+			privKey, ok := parsedKey.(ed25519.PrivateKey)
+			if !ok {
+				return errutil.UserError{Err: fmt.Sprintf("invalid key for key type %v", p.Type)}
+			}
+			entry.Key = privKey
+			entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(privKey.Public().(ed25519.PublicKey))
+
+		case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+			bitSize := 2048
+			if p.Type == KeyType_RSA3072 {
+				bitSize = 3072
+			}
+			if p.Type == KeyType_RSA4096 {
+				bitSize = 4096
+			}
+
+			privKey, ok := parsedKey.(*rsa.PrivateKey)
+			if !ok || privKey.N.BitLen() != bitSize {
+				return errutil.UserError{Err: fmt.Sprintf("invalid key for key type %v", p.Type)}
+			}
+			entry.RSAKey = privKey
+
+		default:
+			return fmt.Errorf("unsupported key type %v", p.Type)
+		}
+	}
+
+	if p.ConvergentEncryption {
+		if p.ConvergentVersion == -1 || p.ConvergentVersion > 1 {
+			entry.ConvergentVersion = currentConvergentVersion
+		}
+	}
+
+	p.Imported = true
+	p.LatestVersion = 1
+	p.MinDecryptionVersion = 1
+	p.Keys = keyEntryMap{
+		strconv.Itoa(p.LatestVersion): entry,
+	}
+
+	return p.Persist(ctx, storage)
+}
+
+// formatECDSAPublicKey returns the PEM-encoded public key stored with ECDSA
+// key entries
+func formatECDSAPublicKey(pub *ecdsa.PublicKey) (string, error) {
+	derBytes, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", errwrap.Wrapf("error marshaling public key: {{err}}", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+	pemBytes := pem.EncodeToMemory(pemBlock)
+	if pemBytes == nil || len(pemBytes) == 0 {
+		return "", fmt.Errorf("error PEM-encoding public key")
+	}
+	return string(pemBytes), nil
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	now := time.Now()
 	p.Keys = keyEntryMap{
@@ -17,9 +17,14 @@ import (
 	"github.com/hashicorp/errwrap"
 	hclog "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/helper/tlsutil"
 )
 
+// maxReferralDepth is the maximum number of referrals followed in a row, to
+// avoid referral loops
+const maxReferralDepth = 5
+
 type Client struct {
 	Logger hclog.Logger
 	LDAP   LDAP
@@ -30,50 +35,8 @@ func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 	var conn Connection
 	urls := strings.Split(cfg.Url, ",")
 	for _, uut := range urls {
-		u, err := url.Parse(uut)
-		if err != nil {
-			retErr = multierror.Append(retErr, errwrap.Wrapf(fmt.Sprintf("error parsing url %q: {{err}}", uut), err))
-			continue
-		}
-		host, port, err := net.SplitHostPort(u.Host)
-		if err != nil {
-			host = u.Host
-		}
-
-		var tlsConfig *tls.Config
-		switch u.Scheme {
-		case "ldap":
-			if port == "" {
-				port = "389"
-			}
-			conn, err = c.LDAP.Dial("tcp", net.JoinHostPort(host, port))
-			if err != nil {
-				break
-			}
-			if conn == nil {
-				err = fmt.Errorf("empty connection after dialing")
-				break
-			}
-			if cfg.StartTLS {
-				tlsConfig, err = getTLSConfig(cfg, host)
-				if err != nil {
-					break
-				}
-				err = conn.StartTLS(tlsConfig)
-			}
-		case "ldaps":
-			if port == "" {
-				port = "636"
-			}
-			tlsConfig, err = getTLSConfig(cfg, host)
-			if err != nil {
-				break
-			}
-			conn, err = c.LDAP.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
-		default:
-			retErr = multierror.Append(retErr, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port)))
-			continue
-		}
+		var err error
+		conn, err = c.dialURL(cfg, uut)
 		if err == nil {
 			if retErr != nil {
 				if c.Logger.IsDebug() {
@@ -83,7 +46,7 @@ func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 			retErr = nil
 			break
 		}
-		retErr = multierror.Append(retErr, errwrap.Wrapf(fmt.Sprintf("error connecting to host %q: {{err}}", uut), err))
+		retErr = multierror.Append(retErr, err)
 	}
 	if retErr != nil {
 		return nil, retErr
@@ -94,6 +57,176 @@ func (c *Client) DialLDAP(cfg *ConfigEntry) (Connection, error) {
 	return conn, nil
 }
 
+// dialURL connects to the server of a single LDAP URL
+func (c *Client) dialURL(cfg *ConfigEntry, uut string) (Connection, error) {
+	u, err := url.Parse(uut)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error parsing url %q: {{err}}", uut), err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+
+	var conn Connection
+	var tlsConfig *tls.Config
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+		conn, err = c.LDAP.Dial("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			break
+		}
+		if conn == nil {
+			err = fmt.Errorf("empty connection after dialing")
+			break
+		}
+		if cfg.StartTLS {
+			tlsConfig, err = getTLSConfig(cfg, host)
+			if err == nil {
+				err = conn.StartTLS(tlsConfig)
+			}
+			if err != nil {
+				conn.Close()
+			}
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		tlsConfig, err = getTLSConfig(cfg, host)
+		if err != nil {
+			break
+		}
+		conn, err = c.LDAP.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
+	default:
+		return nil, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port))
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error connecting to host %q: {{err}}", uut), err)
+	}
+	return conn, nil
+}
+
+// search runs the search request, requesting the results in pages when
+// cfg.MaxPageSize is set, and following the returned referrals when
+// cfg.ChaseReferrals is set
+func (c *Client) search(cfg *ConfigEntry, conn Connection, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return c.searchReferrals(cfg, conn, req, 0)
+}
+
+func (c *Client) searchReferrals(cfg *ConfigEntry, conn Connection, req *ldap.SearchRequest, depth int) (*ldap.SearchResult, error) {
+	var result *ldap.SearchResult
+	var err error
+	if cfg.MaxPageSize > 0 {
+		// The paging control is added to the request, which is reused when
+		// chasing referrals
+		pagedReq := *req
+		pagedReq.Controls = append([]ldap.Control(nil), req.Controls...)
+		result, err = conn.SearchWithPaging(&pagedReq, uint32(cfg.MaxPageSize))
+	} else {
+		result, err = conn.Search(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.ChaseReferrals || len(result.Referrals) == 0 {
+		return result, nil
+	}
+
+	if depth >= maxReferralDepth {
+		c.Logger.Warn("maximum referral depth reached, ignoring referrals", "referrals", result.Referrals)
+		return result, nil
+	}
+	for _, referral := range result.Referrals {
+		entries, err := c.chaseReferral(cfg, req, referral, depth+1)
+		if err != nil {
+			// Referrals often point to partitions that are not relevant or
+			// not reachable from Vault, such as DomainDnsZones in Active
+			// Directory, so they do not fail the search
+			c.Logger.Warn("unable to follow referral", "referral", referral, "error", err)
+			continue
+		}
+		result.Entries = append(result.Entries, entries...)
+	}
+	result.Referrals = nil
+	return result, nil
+}
+
+// chaseReferral runs the search request against the server and base DN of an
+// LDAP URL returned as a referral
+func (c *Client) chaseReferral(cfg *ConfigEntry, req *ldap.SearchRequest, referral string, depth int) ([]*ldap.Entry, error) {
+	u, err := url.Parse(referral)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing referral: {{err}}", err)
+	}
+	if err := checkReferral(cfg, u); err != nil {
+		return nil, err
+	}
+
+	referralCfg := *cfg
+	referralCfg.Url = (&url.URL{Scheme: strings.ToLower(u.Scheme), Host: u.Host}).String()
+	conn, err := c.DialLDAP(&referralCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if cfg.BindDN != "" && cfg.BindPassword != "" {
+		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind(cfg.BindDN)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("LDAP bind (service) failed: {{err}}", err)
+	}
+
+	referralReq := *req
+	if baseDN := strings.TrimPrefix(u.Path, "/"); baseDN != "" {
+		referralReq.BaseDN = baseDN
+	}
+	result, err := c.searchReferrals(&referralCfg, conn, &referralReq, depth)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// checkReferral returns an error if following the referral could send the
+// bind credentials to a server they are not meant for: the referral must point
+// to one of the hosts of cfg.Url or cfg.ReferralHosts, and must not use ldap
+// without StartTLS when cfg.Url uses ldaps.
+func checkReferral(cfg *ConfigEntry, u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+
+	secure := false
+	allowedHosts := make([]string, 0, len(cfg.ReferralHosts))
+	for _, referralHost := range cfg.ReferralHosts {
+		allowedHosts = append(allowedHosts, strings.ToLower(referralHost))
+	}
+	for _, uut := range strings.Split(cfg.Url, ",") {
+		configured, err := url.Parse(strings.TrimSpace(uut))
+		if err != nil {
+			continue
+		}
+		if strings.ToLower(configured.Scheme) == "ldaps" {
+			secure = true
+		}
+		allowedHosts = append(allowedHosts, strings.ToLower(configured.Hostname()))
+	}
+
+	if secure && scheme != "ldaps" && !cfg.StartTLS {
+		return fmt.Errorf("referral scheme %q is weaker than the ldaps scheme of url", u.Scheme)
+	}
+	if !strutil.StrListContains(allowedHosts, host) {
+		return fmt.Errorf("referral host %q is neither a host of url nor in referral_hosts", u.Hostname())
+	}
+	return nil
+}
+
 /*
  * Discover and return the bind string for the user attempting to authenticate.
  * This is handled in one of several ways:
@@ -127,7 +260,7 @@ func (c *Client) GetUserBindDN(cfg *ConfigEntry, conn Connection, username strin
 		if c.Logger.IsDebug() {
 			c.Logger.Debug("discovering user", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := conn.Search(&ldap.SearchRequest{
+		result, err := c.search(cfg, conn, &ldap.SearchRequest{
 			BaseDN:    cfg.UserDN,
 			Scope:     ldap.ScopeWholeSubtree,
 			Filter:    filter,
@@ -162,7 +295,7 @@ func (c *Client) GetUserDN(cfg *ConfigEntry, conn Connection, bindDN, username s
 		if c.Logger.IsDebug() {
 			c.Logger.Debug("searching upn", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := conn.Search(&ldap.SearchRequest{
+		result, err := c.search(cfg, conn, &ldap.SearchRequest{
 			BaseDN:    cfg.UserDN,
 			Scope:     ldap.ScopeWholeSubtree,
 			Filter:    filter,
@@ -223,7 +356,7 @@ func (c *Client) performLdapFilterGroupsSearch(cfg *ConfigEntry, conn Connection
 		c.Logger.Debug("searching", "groupdn", cfg.GroupDN, "rendered_query", renderedQuery.String())
 	}
 
-	result, err := conn.Search(&ldap.SearchRequest{
+	result, err := c.search(cfg, conn, &ldap.SearchRequest{
 		BaseDN: cfg.GroupDN,
 		Scope:  ldap.ScopeWholeSubtree,
 		Filter: renderedQuery.String(),
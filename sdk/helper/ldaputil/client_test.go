@@ -1,8 +1,12 @@
 package ldaputil
 
 import (
+	"crypto/tls"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/go-ldap/ldap/v3"
 	"github.com/hashicorp/go-hclog"
 )
 
@@ -82,3 +86,222 @@ func TestSIDBytesToString(t *testing.T) {
 		}
 	}
 }
+
+// fakeLDAP returns the fake connection of the dialed address
+type fakeLDAP map[string]*fakeConnection
+
+func (f fakeLDAP) Dial(network, addr string) (Connection, error) {
+	return f[addr], nil
+}
+
+func (f fakeLDAP) DialTLS(network, addr string, config *tls.Config) (Connection, error) {
+	return f[addr], nil
+}
+
+// fakeConnection returns the same result to every search
+type fakeConnection struct {
+	result   *ldap.SearchResult
+	bindDN   string
+	baseDN   string
+	pageSize uint32
+	searches int
+	closed   bool
+}
+
+func (f *fakeConnection) Bind(username, password string) error {
+	f.bindDN = username
+	return nil
+}
+
+func (f *fakeConnection) Close() {
+	f.closed = true
+}
+
+func (f *fakeConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
+	return nil
+}
+
+func (f *fakeConnection) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.searches++
+	f.baseDN = searchRequest.BaseDN
+	result := *f.result
+	return &result, nil
+}
+
+func (f *fakeConnection) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	f.pageSize = pagingSize
+	return f.Search(searchRequest)
+}
+
+func (f *fakeConnection) StartTLS(config *tls.Config) error {
+	return nil
+}
+
+func (f *fakeConnection) SetTimeout(timeout time.Duration) {}
+
+func (f *fakeConnection) UnauthenticatedBind(username string) error {
+	f.bindDN = username
+	return nil
+}
+
+func TestSearch_PagingAndReferrals(t *testing.T) {
+	primary := &fakeConnection{
+		result: &ldap.SearchResult{
+			Entries:   []*ldap.Entry{ldap.NewEntry("cn=admins,dc=example,dc=com", nil)},
+			Referrals: []string{"ldap://child.example.com/DC=child,DC=example,DC=com"},
+		},
+	}
+	child := &fakeConnection{
+		result: &ldap.SearchResult{
+			Entries:   []*ldap.Entry{ldap.NewEntry("cn=devs,dc=child,dc=example,dc=com", nil)},
+			Referrals: []string{"ldap://loop.example.com/dc=loop"},
+		},
+	}
+	loop := &fakeConnection{
+		result: &ldap.SearchResult{
+			Referrals: []string{"ldap://loop.example.com/dc=loop"},
+		},
+	}
+	client := Client{
+		Logger: hclog.NewNullLogger(),
+		LDAP: fakeLDAP{
+			"child.example.com:389": child,
+			"loop.example.com:389":  loop,
+		},
+	}
+	cfg := &ConfigEntry{
+		Url:           "ldap://primary.example.com",
+		BindDN:        "cn=vault,dc=example,dc=com",
+		BindPassword:  "secret",
+		ReferralHosts: []string{"child.example.com", "loop.example.com"},
+	}
+	req := &ldap.SearchRequest{
+		BaseDN: "dc=example,dc=com",
+		Filter: "(member=cn=alice,dc=example,dc=com)",
+	}
+
+	// By default, the result of the server is returned as is
+	result, err := client.search(cfg, primary, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 1 || len(result.Referrals) != 1 || primary.pageSize != 0 || child.searches != 0 {
+		t.Fatalf("bad result: %#v", result)
+	}
+
+	cfg.MaxPageSize = 500
+	cfg.ChaseReferrals = true
+	result, err = client.search(cfg, primary, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dns []string
+	for _, entry := range result.Entries {
+		dns = append(dns, entry.DN)
+	}
+	sort.Strings(dns)
+	if len(dns) != 2 || dns[0] != "cn=admins,dc=example,dc=com" || dns[1] != "cn=devs,dc=child,dc=example,dc=com" {
+		t.Fatalf("bad entries: %v", dns)
+	}
+	if len(result.Referrals) != 0 {
+		t.Fatalf("expected the referrals to be followed: %v", result.Referrals)
+	}
+	if primary.pageSize != 500 || child.pageSize != 500 {
+		t.Fatalf("expected paged searches, got %d and %d", primary.pageSize, child.pageSize)
+	}
+	if child.baseDN != "DC=child,DC=example,DC=com" || child.bindDN != cfg.BindDN || !child.closed {
+		t.Fatalf("bad referral search: %#v", child)
+	}
+	if len(req.Controls) != 0 {
+		t.Fatalf("expected the request not to be modified: %#v", req.Controls)
+	}
+
+	// Referral loops are bounded
+	if loop.searches != maxReferralDepth-1 {
+		t.Fatalf("expected %d searches of the looping referral, got %d", maxReferralDepth-1, loop.searches)
+	}
+}
+
+func TestSearch_ReferralChecks(t *testing.T) {
+	testCases := map[string]struct {
+		url           string
+		startTLS      bool
+		referralHosts []string
+		referral      string
+		followed      bool
+	}{
+		"host of url": {
+			url:      "ldap://primary.example.com,ldap://child.example.com",
+			referral: "ldap://child.example.com/dc=child",
+			followed: true,
+		},
+		"referral host": {
+			url:           "ldaps://primary.example.com",
+			referralHosts: []string{"CHILD.example.com"},
+			referral:      "ldaps://child.example.com/dc=child",
+			followed:      true,
+		},
+		"unknown host": {
+			url:      "ldaps://primary.example.com",
+			referral: "ldaps://child.example.com/dc=child",
+		},
+		"downgrade to ldap": {
+			url:           "ldaps://primary.example.com",
+			referralHosts: []string{"child.example.com"},
+			referral:      "ldap://child.example.com/dc=child",
+		},
+		"ldap with starttls": {
+			url:           "ldaps://primary.example.com",
+			startTLS:      true,
+			referralHosts: []string{"child.example.com"},
+			referral:      "ldap://child.example.com/dc=child",
+			followed:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			primary := &fakeConnection{
+				result: &ldap.SearchResult{
+					Entries:   []*ldap.Entry{ldap.NewEntry("cn=admins,dc=example,dc=com", nil)},
+					Referrals: []string{tc.referral},
+				},
+			}
+			child := &fakeConnection{
+				result: &ldap.SearchResult{
+					Entries: []*ldap.Entry{ldap.NewEntry("cn=devs,dc=child", nil)},
+				},
+			}
+			client := Client{
+				Logger: hclog.NewNullLogger(),
+				LDAP: fakeLDAP{
+					"child.example.com:389": child,
+					"child.example.com:636": child,
+				},
+			}
+			cfg := &ConfigEntry{
+				Url:            tc.url,
+				StartTLS:       tc.startTLS,
+				BindDN:         "cn=vault,dc=example,dc=com",
+				BindPassword:   "secret",
+				ChaseReferrals: true,
+				ReferralHosts:  tc.referralHosts,
+			}
+
+			result, err := client.search(cfg, primary, &ldap.SearchRequest{BaseDN: "dc=example,dc=com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.followed {
+				if len(result.Entries) != 2 || child.bindDN != cfg.BindDN {
+					t.Fatalf("expected the referral to be followed: %#v", result.Entries)
+				}
+				return
+			}
+			// The credentials must not be sent to the referral server
+			if len(result.Entries) != 1 || child.searches != 0 || child.bindDN != "" {
+				t.Fatalf("expected the referral to be rejected: %#v", child)
+			}
+		})
+	}
+}
@@ -209,6 +209,32 @@ Default: cn`,
 			Description: "Timeout, in seconds, for the connection when making requests against the server before returning back an error.",
 			Default:     "90s",
 		},
+
+		"max_page_size": {
+			Type:        framework.TypeInt,
+			Default:     0,
+			Description: "If greater than 0, search results are requested from the server in pages of this size, allowing searches returning more entries than the server size limit, such as the groups of users in more than 1500 Active Directory groups.",
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name: "Maximum page size",
+			},
+		},
+
+		"chase_referrals": {
+			Type:        framework.TypeBool,
+			Default:     false,
+			Description: "If true, follow the referrals returned by searches to other servers, binding to them with binddn and bindpass.",
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name: "Chase referrals",
+			},
+		},
+
+		"referral_hosts": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: "Comma-separated list of the hosts, besides those of url, that followed referrals may point to.",
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name: "Referral hosts",
+			},
+		},
 	}
 }
 
@@ -358,32 +384,51 @@ func NewConfigEntry(existing *ConfigEntry, d *framework.FieldData) (*ConfigEntry
 		cfg.RequestTimeout = d.Get("request_timeout").(int)
 	}
 
+	if _, ok := d.Raw["max_page_size"]; ok || !hadExisting {
+		maxPageSize := d.Get("max_page_size").(int)
+		if maxPageSize < 0 {
+			return nil, errors.New("'max_page_size' must not be negative")
+		}
+		cfg.MaxPageSize = maxPageSize
+	}
+
+	if _, ok := d.Raw["chase_referrals"]; ok || !hadExisting {
+		cfg.ChaseReferrals = d.Get("chase_referrals").(bool)
+	}
+
+	if _, ok := d.Raw["referral_hosts"]; ok || !hadExisting {
+		cfg.ReferralHosts = d.Get("referral_hosts").([]string)
+	}
+
 	return cfg, nil
 }
 
 type ConfigEntry struct {
-	Url                      string `json:"url"`
-	UserDN                   string `json:"userdn"`
-	AnonymousGroupSearch     bool   `json:"anonymous_group_search"`
-	GroupDN                  string `json:"groupdn"`
-	GroupFilter              string `json:"groupfilter"`
-	GroupAttr                string `json:"groupattr"`
-	UPNDomain                string `json:"upndomain"`
-	UserAttr                 string `json:"userattr"`
-	Certificate              string `json:"certificate"`
-	ClientTLSCert            string `json:"client_tls_cert`
-	ClientTLSKey             string `json:"client_tls_key`
-	InsecureTLS              bool   `json:"insecure_tls"`
-	StartTLS                 bool   `json:"starttls"`
-	BindDN                   string `json:"binddn"`
-	BindPassword             string `json:"bindpass"`
-	DenyNullBind             bool   `json:"deny_null_bind"`
-	DiscoverDN               bool   `json:"discoverdn"`
-	TLSMinVersion            string `json:"tls_min_version"`
-	TLSMaxVersion            string `json:"tls_max_version"`
-	UseTokenGroups           bool   `json:"use_token_groups"`
-	UsePre111GroupCNBehavior *bool  `json:"use_pre111_group_cn_behavior"`
-	RequestTimeout           int    `json:"request_timeout"`
+	Url                      string   `json:"url"`
+	UserDN                   string   `json:"userdn"`
+	AnonymousGroupSearch     bool     `json:"anonymous_group_search"`
+	GroupDN                  string   `json:"groupdn"`
+	GroupFilter              string   `json:"groupfilter"`
+	GroupAttr                string   `json:"groupattr"`
+	UPNDomain                string   `json:"upndomain"`
+	UserAttr                 string   `json:"userattr"`
+	Certificate              string   `json:"certificate"`
+	ClientTLSCert            string   `json:"client_tls_cert`
+	ClientTLSKey             string   `json:"client_tls_key`
+	InsecureTLS              bool     `json:"insecure_tls"`
+	StartTLS                 bool     `json:"starttls"`
+	BindDN                   string   `json:"binddn"`
+	BindPassword             string   `json:"bindpass"`
+	DenyNullBind             bool     `json:"deny_null_bind"`
+	DiscoverDN               bool     `json:"discoverdn"`
+	TLSMinVersion            string   `json:"tls_min_version"`
+	TLSMaxVersion            string   `json:"tls_max_version"`
+	UseTokenGroups           bool     `json:"use_token_groups"`
+	UsePre111GroupCNBehavior *bool    `json:"use_pre111_group_cn_behavior"`
+	RequestTimeout           int      `json:"request_timeout"`
+	MaxPageSize              int      `json:"max_page_size"`
+	ChaseReferrals           bool     `json:"chase_referrals"`
+	ReferralHosts            []string `json:"referral_hosts"`
 
 	// This json tag deviates from snake case because there was a past issue
 	// where the tag was being ignored, causing it to be jsonified as "CaseSensitiveNames".
@@ -417,6 +462,9 @@ func (c *ConfigEntry) PasswordlessMap() map[string]interface{} {
 		"tls_max_version":        c.TLSMaxVersion,
 		"use_token_groups":       c.UseTokenGroups,
 		"anonymous_group_search": c.AnonymousGroupSearch,
+		"max_page_size":          c.MaxPageSize,
+		"chase_referrals":        c.ChaseReferrals,
+		"referral_hosts":         c.ReferralHosts,
 	}
 	if c.CaseSensitiveNames != nil {
 		m["case_sensitive_names"] = *c.CaseSensitiveNames
@@ -20,6 +20,17 @@ var (
 	// to bootstrap mounting plugins.
 	PluginMetadataModeEnv = "VAULT_PLUGIN_METADATA_MODE"
 
+	// PluginAutoMTLSEnv is the ENV name used to tell the plugin that vault
+	// passes the generated TLS certificate directly, in which case no unwrap
+	// token is passed.
+	PluginAutoMTLSEnv = "VAULT_PLUGIN_AUTOMTLS_ENABLED"
+
+	// PluginTLSCertEnv and PluginTLSKeyEnv are the ENV names used to pass the
+	// base64 encoded certificate and private key to the plugin in automatic
+	// mTLS mode.
+	PluginTLSCertEnv = "VAULT_PLUGIN_TLS_CERT"
+	PluginTLSKeyEnv  = "VAULT_PLUGIN_TLS_KEY"
+
 	// PluginUnwrapTokenEnv is the ENV name used to pass unwrap tokens to the
 	// plugin.
 	PluginUnwrapTokenEnv = "VAULT_UNWRAP_TOKEN"
@@ -63,6 +74,12 @@ func GRPCSupport() bool {
 	return true
 }
 
+// InAutoMTLSMode returns true if the plugin calling this function negotiates
+// mTLS with vault automatically.
+func InAutoMTLSMode() bool {
+	return os.Getenv(PluginAutoMTLSEnv) == "true"
+}
+
 // InMetadataMode returns true if the plugin calling this function is running in metadata mode.
 func InMetadataMode() bool {
 	return os.Getenv(PluginMetadataModeEnv) == "true"
@@ -0,0 +1,34 @@
+package pluginutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// MultiplexingCtxKey is the gRPC metadata key carrying the ID of the backend
+// instance a request is meant for, when several mounts share the same plugin
+// process.
+const MultiplexingCtxKey = "multiplex_id"
+
+// GetMultiplexIDFromContext returns the multiplexing ID set in the incoming
+// gRPC metadata of the context.
+func GetMultiplexIDFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errors.New("missing plugin multiplexing metadata")
+	}
+
+	multiplexIDs := md[MultiplexingCtxKey]
+	if len(multiplexIDs) != 1 {
+		return "", fmt.Errorf("unexpected number of IDs in metadata: (%d)", len(multiplexIDs))
+	}
+
+	if multiplexIDs[0] == "" {
+		return "", errors.New("empty multiplex ID in metadata")
+	}
+
+	return multiplexIDs[0], nil
+}
@@ -45,8 +45,11 @@ func (rc runConfig) makeConfig(ctx context.Context) (*plugin.ClientConfig, error
 	metadataEnv := fmt.Sprintf("%s=%t", PluginMetadataModeEnv, rc.isMetadataMode)
 	cmd.Env = append(cmd.Env, metadataEnv)
 
+	autoMTLSEnv := fmt.Sprintf("%s=%t", PluginAutoMTLSEnv, rc.autoMTLS)
+	cmd.Env = append(cmd.Env, autoMTLSEnv)
+
 	var clientTLSConfig *tls.Config
-	if !rc.autoMTLS && !rc.isMetadataMode {
+	if !rc.isMetadataMode {
 		// Get a CA TLS Certificate
 		certBytes, key, err := generateCert()
 		if err != nil {
@@ -59,15 +62,25 @@ func (rc runConfig) makeConfig(ctx context.Context) (*plugin.ClientConfig, error
 			return nil, err
 		}
 
-		// Use CA to sign a server cert and wrap the values in a response wrapped
-		// token.
-		wrapToken, err := wrapServerConfig(ctx, rc.wrapper, certBytes, key)
-		if err != nil {
-			return nil, err
+		if rc.autoMTLS {
+			// Pass the server cert to the plugin directly, sparing it the
+			// round trip to the vault API
+			serverEnv, err := autoMTLSServerEnv(certBytes, key)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Env = append(cmd.Env, serverEnv...)
+		} else {
+			// Use CA to sign a server cert and wrap the values in a response
+			// wrapped token.
+			wrapToken, err := wrapServerConfig(ctx, rc.wrapper, certBytes, key)
+			if err != nil {
+				return nil, err
+			}
+
+			// Add the response wrap token to the ENV of the plugin
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", PluginUnwrapTokenEnv, wrapToken))
 		}
-
-		// Add the response wrap token to the ENV of the plugin
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", PluginUnwrapTokenEnv, wrapToken))
 	}
 
 	secureConfig := &plugin.SecureConfig{
@@ -86,7 +99,6 @@ func (rc runConfig) makeConfig(ctx context.Context) (*plugin.ClientConfig, error
 			plugin.ProtocolNetRPC,
 			plugin.ProtocolGRPC,
 		},
-		AutoMTLS: rc.autoMTLS,
 	}
 	return clientConfig, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,6 +77,7 @@ func TestNameMakeConfig(t *testing.T) {
 						"initial=true",
 						fmt.Sprintf("%s=%s", PluginVaultVersionEnv, version.GetVersion().Version),
 						fmt.Sprintf("%s=%t", PluginMetadataModeEnv, true),
+						fmt.Sprintf("%s=%t", PluginAutoMTLSEnv, false),
 					},
 				),
 				SecureConfig: &plugin.SecureConfig{
@@ -139,6 +141,7 @@ func TestNameMakeConfig(t *testing.T) {
 						fmt.Sprintf("%s=%t", PluginMlockEnabled, true),
 						fmt.Sprintf("%s=%s", PluginVaultVersionEnv, version.GetVersion().Version),
 						fmt.Sprintf("%s=%t", PluginMetadataModeEnv, false),
+						fmt.Sprintf("%s=%t", PluginAutoMTLSEnv, false),
 						fmt.Sprintf("%s=%s", PluginUnwrapTokenEnv, "testtoken"),
 					},
 				),
@@ -199,6 +202,7 @@ func TestNameMakeConfig(t *testing.T) {
 						"initial=true",
 						fmt.Sprintf("%s=%s", PluginVaultVersionEnv, version.GetVersion().Version),
 						fmt.Sprintf("%s=%t", PluginMetadataModeEnv, true),
+						fmt.Sprintf("%s=%t", PluginAutoMTLSEnv, true),
 					},
 				),
 				SecureConfig: &plugin.SecureConfig{
@@ -209,8 +213,7 @@ func TestNameMakeConfig(t *testing.T) {
 					plugin.ProtocolNetRPC,
 					plugin.ProtocolGRPC,
 				},
-				Logger:   hclog.NewNullLogger(),
-				AutoMTLS: true,
+				Logger: hclog.NewNullLogger(),
 			},
 			expectTLSConfig: false,
 		},
@@ -258,6 +261,7 @@ func TestNameMakeConfig(t *testing.T) {
 						"initial=true",
 						fmt.Sprintf("%s=%s", PluginVaultVersionEnv, version.GetVersion().Version),
 						fmt.Sprintf("%s=%t", PluginMetadataModeEnv, false),
+						fmt.Sprintf("%s=%t", PluginAutoMTLSEnv, true),
 					},
 				),
 				SecureConfig: &plugin.SecureConfig{
@@ -268,10 +272,9 @@ func TestNameMakeConfig(t *testing.T) {
 					plugin.ProtocolNetRPC,
 					plugin.ProtocolGRPC,
 				},
-				Logger:   hclog.NewNullLogger(),
-				AutoMTLS: true,
+				Logger: hclog.NewNullLogger(),
 			},
-			expectTLSConfig: false,
+			expectTLSConfig: true,
 		},
 	}
 
@@ -311,6 +314,23 @@ func TestNameMakeConfig(t *testing.T) {
 			}
 			config.TLSConfig = nil
 
+			// The generated server cert is passed directly in automatic mTLS mode
+			if test.rc.autoMTLS && !test.rc.isMetadataMode {
+				var env []string
+				var found int
+				for _, e := range config.Cmd.Env {
+					if strings.HasPrefix(e, PluginTLSCertEnv+"=") || strings.HasPrefix(e, PluginTLSKeyEnv+"=") {
+						found++
+						continue
+					}
+					env = append(env, e)
+				}
+				if found != 2 {
+					t.Fatalf("expected the server cert and key in the env, got: %v", config.Cmd.Env)
+				}
+				config.Cmd.Env = env
+			}
+
 			if !reflect.DeepEqual(config, test.expectedConfig) {
 				t.Fatalf("Actual config: %#v\nExpected config: %#v", config, test.expectedConfig)
 			}
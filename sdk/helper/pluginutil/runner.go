@@ -16,6 +16,13 @@ type Looker interface {
 	LookupPlugin(context.Context, string, consts.PluginType) (*PluginRunner, error)
 }
 
+// VersionedLooker defines the plugin LookupPluginVersion function that looks
+// into the plugin catalog for a given version of an external plugin and
+// returns a PluginRunner
+type VersionedLooker interface {
+	LookupPluginVersion(context.Context, string, consts.PluginType, string) (*PluginRunner, error)
+}
+
 // RunnerUtil interface defines the functions needed by the runner to wrap the
 // metadata needed to run a plugin process. This includes looking up Mlock
 // configuration and wrapping data in a response wrapped token.
@@ -36,6 +43,7 @@ type LookRunnerUtil interface {
 type PluginRunner struct {
 	Name           string                      `json:"name" structs:"name"`
 	Type           consts.PluginType           `json:"type" structs:"type"`
+	Version        string                      `json:"version,omitempty" structs:"version"`
 	Command        string                      `json:"command" structs:"command"`
 	Args           []string                    `json:"args" structs:"args"`
 	Env            []string                    `json:"env" structs:"env"`
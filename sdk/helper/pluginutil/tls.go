@@ -8,6 +8,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/errwrap"
@@ -106,3 +108,18 @@ func wrapServerConfig(ctx context.Context, sys RunnerUtil, certBytes []byte, key
 
 	return wrapInfo.Token, nil
 }
+
+// autoMTLSServerEnv returns the ENV entries passing the server certificate and
+// private key to the plugin directly, instead of through a response wrapped
+// token.
+func autoMTLSServerEnv(certBytes []byte, key *ecdsa.PrivateKey) ([]string, error) {
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("%s=%s", PluginTLSCertEnv, base64.StdEncoding.EncodeToString(certBytes)),
+		fmt.Sprintf("%s=%s", PluginTLSKeyEnv, base64.StdEncoding.EncodeToString(rawKey)),
+	}, nil
+}
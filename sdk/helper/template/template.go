@@ -0,0 +1,110 @@
+// Package template renders strings, such as usernames, from user supplied Go
+// templates extended with a set of helper functions.
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/errwrap"
+)
+
+type Opt func(*StringTemplate) error
+
+// Template sets the template to render. It is required.
+func Template(rawTemplate string) Opt {
+	return func(up *StringTemplate) error {
+		up.rawTemplate = rawTemplate
+		return nil
+	}
+}
+
+// Function adds a function to the ones available in the template, replacing
+// any existing function with the same name.
+func Function(name string, f interface{}) Opt {
+	return func(up *StringTemplate) error {
+		if name == "" {
+			return fmt.Errorf("missing function name")
+		}
+		if f == nil {
+			return fmt.Errorf("missing function")
+		}
+		up.funcMap[name] = f
+		return nil
+	}
+}
+
+// StringTemplate renders a template into a string. On top of the standard
+// template functions, the following are available:
+//
+//	random <length>                    random base62 string
+//	truncate <length> <str>            keep the first length characters
+//	truncate_sha256 <length> <str>     truncate, ending with a hash of the cut off part
+//	uppercase <str>, lowercase <str>   change case
+//	replace <find> <replace> <str>     replace all occurrences
+//	sha256 <str>, base64 <str>         hash or encode
+//	uuid                               random UUID
+//	unix_time, unix_time_millis        current time since the epoch
+//	timestamp <format>                 current UTC time in the given layout
+type StringTemplate struct {
+	rawTemplate string
+	tmpl        *template.Template
+	funcMap     template.FuncMap
+}
+
+// NewTemplate parses the template set with the Template option.
+func NewTemplate(opts ...Opt) (up StringTemplate, err error) {
+	up = StringTemplate{
+		funcMap: map[string]interface{}{
+			"random":          base62Random,
+			"truncate":        truncate,
+			"truncate_sha256": truncateSHA256,
+			"uppercase":       uppercase,
+			"lowercase":       lowercase,
+			"replace":         replace,
+			"sha256":          hashSHA256,
+			"base64":          encodeBase64,
+
+			"unix_time":        unixTime,
+			"unix_time_millis": unixTimeMillis,
+			"timestamp":        timestamp,
+			"uuid":             uuid,
+		},
+	}
+
+	for _, opt := range opts {
+		if err := opt(&up); err != nil {
+			return StringTemplate{}, errwrap.Wrapf("unable to apply option: {{err}}", err)
+		}
+	}
+
+	if up.rawTemplate == "" {
+		return StringTemplate{}, fmt.Errorf("missing template")
+	}
+
+	tmpl, err := template.New("template").
+		Funcs(up.funcMap).
+		Parse(up.rawTemplate)
+	if err != nil {
+		return StringTemplate{}, errwrap.Wrapf("unable to parse template: {{err}}", err)
+	}
+	up.tmpl = tmpl
+
+	return up, nil
+}
+
+// Generate renders the template with the given data. Leading and trailing
+// whitespace is removed from the result.
+func (up StringTemplate) Generate(data interface{}) (string, error) {
+	if up.tmpl == nil || up.rawTemplate == "" {
+		return "", fmt.Errorf("template not initialized")
+	}
+
+	str := &strings.Builder{}
+	if err := up.tmpl.Execute(str, data); err != nil {
+		return "", errwrap.Wrapf("unable to apply template: {{err}}", err)
+	}
+
+	return strings.TrimSpace(str.String()), nil
+}
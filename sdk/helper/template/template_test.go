@@ -0,0 +1,119 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestStringTemplate(t *testing.T) {
+	type metadata struct {
+		DisplayName string
+		RoleName    string
+	}
+	data := metadata{
+		DisplayName: "token-Display-Name",
+		RoleName:    "my-role",
+	}
+
+	tests := map[string]struct {
+		template string
+		expected string
+		pattern  string
+	}{
+		"plain": {
+			template: "{{.DisplayName}}",
+			expected: "token-Display-Name",
+		},
+		"truncate and lowercase": {
+			template: "v_{{.DisplayName | truncate 8 | lowercase}}_{{.RoleName | uppercase}}",
+			expected: "v_token-di_MY-ROLE",
+		},
+		"replace": {
+			template: `{{.RoleName | replace "-" "_"}}`,
+			expected: "my_role",
+		},
+		"truncate_sha256 leaves short strings alone": {
+			template: "{{.RoleName | truncate_sha256 10}}",
+			expected: "my-role",
+		},
+		"truncate_sha256": {
+			template: "{{.DisplayName | truncate_sha256 12}}",
+			expected: "toke" + hashSHA256("n-Display-Name")[:8],
+		},
+		"whitespace is trimmed": {
+			template: "  {{.RoleName}}\n",
+			expected: "my-role",
+		},
+		"random": {
+			template: "{{random 20}}",
+			pattern:  "^[a-zA-Z0-9]{20}$",
+		},
+		"unix_time": {
+			template: "{{.RoleName}}-{{unix_time}}",
+			pattern:  "^my-role-[0-9]+$",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			st, err := NewTemplate(Template(test.template))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := st.Generate(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.pattern != "" {
+				if !regexp.MustCompile(test.pattern).MatchString(actual) {
+					t.Fatalf("%q doesn't match %q", actual, test.pattern)
+				}
+				return
+			}
+			if actual != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStringTemplate_Errors(t *testing.T) {
+	if _, err := NewTemplate(); err == nil {
+		t.Fatal("expected an error without a template")
+	}
+	if _, err := NewTemplate(Template("{{.Foo")); err == nil {
+		t.Fatal("expected an error for an invalid template")
+	}
+
+	st, err := NewTemplate(Template("{{.RoleName | truncate 0}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Generate(map[string]string{"RoleName": "role"}); err == nil {
+		t.Fatal("expected an error when truncating to nothing")
+	}
+
+	if _, err := (StringTemplate{}).Generate(nil); err == nil {
+		t.Fatal("expected an error from an uninitialized template")
+	}
+}
+
+func TestStringTemplate_Function(t *testing.T) {
+	st, err := NewTemplate(
+		Template("{{.RoleName | shout}}"),
+		Function("shout", func(str string) string {
+			return strings.ToUpper(str) + "!"
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := st.Generate(map[string]string{"RoleName": "role"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != "ROLE!" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
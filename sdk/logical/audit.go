@@ -1,5 +1,7 @@
 package logical
 
+import "time"
+
 type LogInput struct {
 	Type                string
 	Auth                *Auth
@@ -8,6 +10,10 @@ type LogInput struct {
 	OuterErr            error
 	NonHMACReqDataKeys  []string
 	NonHMACRespDataKeys []string
+
+	// Duration is the time taken to handle the request. It is only set when
+	// logging responses.
+	Duration time.Duration
 }
 
 type MarshalOptions struct {
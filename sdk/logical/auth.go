@@ -100,6 +100,24 @@ type Auth struct {
 
 	// Orphan is set if the token does not have a parent
 	Orphan bool `json:"orphan"`
+
+	// PolicyResults is the set of policies that grant the token access to the
+	// requesting path. This is populated by core for audit purposes and is
+	// not returned by backends.
+	PolicyResults *PolicyResults `json:"policy_results"`
+}
+
+// PolicyResults is the result of the policy evaluation for a request
+type PolicyResults struct {
+	Allowed          bool         `json:"allowed"`
+	GrantingPolicies []PolicyInfo `json:"granting_policies"`
+}
+
+// PolicyInfo identifies a single policy that took part in a decision
+type PolicyInfo struct {
+	Name        string `json:"name"`
+	NamespaceID string `json:"namespace_id"`
+	Type        string `json:"type"`
 }
 
 func (a *Auth) GoString() string {
@@ -14,4 +14,5 @@ type ControlGroup struct {
 type Authz struct {
 	Token             string    `json:"token"`
 	AuthorizationTime time.Time `json:"authorization_time"`
+	EntityID          string    `json:"entity_id"`
 }
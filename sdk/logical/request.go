@@ -301,6 +301,8 @@ const (
 	UpdateOperation                   = "update"
 	DeleteOperation                   = "delete"
 	ListOperation                     = "list"
+	PatchOperation                    = "patch"
+	HeaderOperation                   = "header"
 	HelpOperation                     = "help"
 	AliasLookaheadOperation           = "alias-lookahead"
 
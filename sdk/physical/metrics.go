@@ -0,0 +1,103 @@
+package physical
+
+import (
+	"context"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// MetricSink is the subset of a metrics sink used to instrument a physical
+// backend.
+type MetricSink interface {
+	IncrCounterWithLabels(key []string, val float32, labels []metrics.Label)
+	MeasureSinceWithLabels(key []string, start time.Time, labels []metrics.Label)
+}
+
+// MetricsBackend is used to record the latency and errors of the requests
+// made to an underlying physical backend
+type MetricsBackend struct {
+	backend Backend
+	sink    MetricSink
+	labels  []metrics.Label
+}
+
+// TransactionalMetricsBackend is the transactional version of the metrics
+// backend
+type TransactionalMetricsBackend struct {
+	*MetricsBackend
+	Transactional
+}
+
+// Verify MetricsBackend satisfies the correct interfaces
+var _ Backend = (*MetricsBackend)(nil)
+var _ Transactional = (*TransactionalMetricsBackend)(nil)
+
+// NewMetricsBackend returns a wrapped physical backend that records a latency
+// sample for each request, and counts the requests that fail. Metrics are
+// labeled with the type of the backend.
+func NewMetricsBackend(b Backend, backendType string, sink MetricSink) *MetricsBackend {
+	if backendType == "" {
+		backendType = "unknown"
+	}
+	return &MetricsBackend{
+		backend: b,
+		sink:    sink,
+		labels:  []metrics.Label{{Name: "type", Value: backendType}},
+	}
+}
+
+// NewTransactionalMetricsBackend creates a new transactional MetricsBackend
+func NewTransactionalMetricsBackend(b Backend, backendType string, sink MetricSink) *TransactionalMetricsBackend {
+	return &TransactionalMetricsBackend{
+		MetricsBackend: NewMetricsBackend(b, backendType, sink),
+		Transactional:  b.(Transactional),
+	}
+}
+
+func (m *MetricsBackend) record(op string, start time.Time, err error) {
+	m.sink.MeasureSinceWithLabels([]string{"storage", op}, start, m.labels)
+	if err != nil {
+		m.sink.IncrCounterWithLabels([]string{"storage", op, "error"}, 1, m.labels)
+	}
+}
+
+// Put is a measured put request
+func (m *MetricsBackend) Put(ctx context.Context, entry *Entry) error {
+	start := time.Now()
+	err := m.backend.Put(ctx, entry)
+	m.record("put", start, err)
+	return err
+}
+
+// Get is a measured get request
+func (m *MetricsBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	start := time.Now()
+	entry, err := m.backend.Get(ctx, key)
+	m.record("get", start, err)
+	return entry, err
+}
+
+// Delete is a measured delete request
+func (m *MetricsBackend) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := m.backend.Delete(ctx, key)
+	m.record("delete", start, err)
+	return err
+}
+
+// List is a measured list request
+func (m *MetricsBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	start := time.Now()
+	keys, err := m.backend.List(ctx, prefix)
+	m.record("list", start, err)
+	return keys, err
+}
+
+// Transaction is a measured transaction request
+func (m *TransactionalMetricsBackend) Transaction(ctx context.Context, txns []*TxnEntry) error {
+	start := time.Now()
+	err := m.Transactional.Transaction(ctx, txns)
+	m.record("transaction", start, err)
+	return err
+}
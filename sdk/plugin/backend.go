@@ -22,18 +22,26 @@ type GRPCBackendPlugin struct {
 	MetadataMode bool
 	Logger       log.Logger
 
+	// MultiplexingSupport makes the plugin server host a backend instance per
+	// mount, selected by the multiplexing ID of each request.
+	MultiplexingSupport bool
+
 	// Embeding this will disable the netRPC protocol
 	plugin.NetRPCUnsupportedPlugin
 }
 
 func (b GRPCBackendPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
-	pb.RegisterBackendServer(s, &backendGRPCPluginServer{
+	server := &backendGRPCPluginServer{
 		broker:  broker,
 		factory: b.Factory,
 		// We pass the logger down into the backend so go-plugin will forward
 		// logs for us.
 		logger: b.Logger,
-	})
+	}
+	if b.MultiplexingSupport {
+		server.instances = make(map[string]backendInstance)
+	}
+	pb.RegisterBackendServer(s, server)
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	log "github.com/hashicorp/go-hclog"
@@ -44,6 +45,19 @@ type backendGRPCPluginClient struct {
 	// so it can be cleaned up.
 	clientConn *grpc.ClientConn
 	doneCtx    context.Context
+
+	// multiplexingID identifies the backend instance of this client when the
+	// plugin process is multiplexed. It is empty otherwise.
+	multiplexingID string
+}
+
+// outgoingContext returns the context to make calls to the plugin with,
+// carrying the multiplexing ID of the client if the plugin is multiplexed.
+func (b *backendGRPCPluginClient) outgoingContext(ctx context.Context) context.Context {
+	if b.multiplexingID == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pluginutil.MultiplexingCtxKey, b.multiplexingID)
 }
 
 func (b *backendGRPCPluginClient) Initialize(ctx context.Context, _ *logical.InitializationRequest) error {
@@ -56,7 +70,7 @@ func (b *backendGRPCPluginClient) Initialize(ctx context.Context, _ *logical.Ini
 	defer close(quitCh)
 	defer cancel()
 
-	reply, err := b.client.Initialize(ctx, &pb.InitializeArgs{}, largeMsgGRPCCallOpts...)
+	reply, err := b.client.Initialize(b.outgoingContext(ctx), &pb.InitializeArgs{}, largeMsgGRPCCallOpts...)
 	if err != nil {
 		if b.doneCtx.Err() != nil {
 			return ErrPluginShutdown
@@ -93,7 +107,7 @@ func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logica
 		return nil, err
 	}
 
-	reply, err := b.client.HandleRequest(ctx, &pb.HandleRequestArgs{
+	reply, err := b.client.HandleRequest(b.outgoingContext(ctx), &pb.HandleRequestArgs{
 		Request: protoReq,
 	}, largeMsgGRPCCallOpts...)
 	if err != nil {
@@ -115,7 +129,7 @@ func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logica
 }
 
 func (b *backendGRPCPluginClient) SpecialPaths() *logical.Paths {
-	reply, err := b.client.SpecialPaths(b.doneCtx, &pb.Empty{})
+	reply, err := b.client.SpecialPaths(b.outgoingContext(b.doneCtx), &pb.Empty{})
 	if err != nil {
 		return nil
 	}
@@ -158,7 +172,7 @@ func (b *backendGRPCPluginClient) HandleExistenceCheck(ctx context.Context, req
 	quitCh := pluginutil.CtxCancelIfCanceled(cancel, b.doneCtx)
 	defer close(quitCh)
 	defer cancel()
-	reply, err := b.client.HandleExistenceCheck(ctx, &pb.HandleExistenceCheckArgs{
+	reply, err := b.client.HandleExistenceCheck(b.outgoingContext(ctx), &pb.HandleExistenceCheckArgs{
 		Request: protoReq,
 	}, largeMsgGRPCCallOpts...)
 	if err != nil {
@@ -180,7 +194,7 @@ func (b *backendGRPCPluginClient) Cleanup(ctx context.Context) {
 	defer close(quitCh)
 	defer cancel()
 
-	b.client.Cleanup(ctx, &pb.Empty{})
+	b.client.Cleanup(b.outgoingContext(ctx), &pb.Empty{})
 
 	// This will block until Setup has run the function to create a new server
 	// in b.server. If we stop here before it has a chance to actually start
@@ -192,7 +206,12 @@ func (b *backendGRPCPluginClient) Cleanup(ctx context.Context) {
 	if server != nil {
 		server.(*grpc.Server).GracefulStop()
 	}
-	b.clientConn.Close()
+
+	// The connection of a multiplexed plugin is shared with the other mounts
+	// and is closed when the plugin process is killed
+	if b.multiplexingID == "" {
+		b.clientConn.Close()
+	}
 }
 
 func (b *backendGRPCPluginClient) InvalidateKey(ctx context.Context, key string) {
@@ -205,7 +224,7 @@ func (b *backendGRPCPluginClient) InvalidateKey(ctx context.Context, key string)
 	defer close(quitCh)
 	defer cancel()
 
-	b.client.InvalidateKey(ctx, &pb.InvalidateKeyArgs{
+	b.client.InvalidateKey(b.outgoingContext(ctx), &pb.InvalidateKeyArgs{
 		Key: key,
 	})
 }
@@ -255,7 +274,7 @@ func (b *backendGRPCPluginClient) Setup(ctx context.Context, config *logical.Bac
 	defer close(quitCh)
 	defer cancel()
 
-	reply, err := b.client.Setup(ctx, args)
+	reply, err := b.client.Setup(b.outgoingContext(ctx), args)
 	if err != nil {
 		return err
 	}
@@ -271,7 +290,7 @@ func (b *backendGRPCPluginClient) Setup(ctx context.Context, config *logical.Bac
 }
 
 func (b *backendGRPCPluginClient) Type() logical.BackendType {
-	reply, err := b.client.Type(b.doneCtx, &pb.Empty{})
+	reply, err := b.client.Type(b.outgoingContext(b.doneCtx), &pb.Empty{})
 	if err != nil {
 		return logical.TypeUnknown
 	}
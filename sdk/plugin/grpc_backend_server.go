@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"errors"
+	"sync"
 
 	log "github.com/hashicorp/go-hclog"
 	plugin "github.com/hashicorp/go-plugin"
@@ -12,7 +13,10 @@ import (
 	"google.golang.org/grpc"
 )
 
-var ErrServerInMetadataMode = errors.New("plugin server can not perform action while in metadata mode")
+var (
+	ErrServerInMetadataMode = errors.New("plugin server can not perform action while in metadata mode")
+	ErrNoInstance           = errors.New("no backend instance found for the multiplexing ID")
+)
 
 type backendGRPCPluginServer struct {
 	broker  *plugin.GRPCBroker
@@ -22,9 +26,42 @@ type backendGRPCPluginServer struct {
 
 	brokeredClient *grpc.ClientConn
 
+	// instances holds the backends of a multiplexed plugin, keyed by the
+	// multiplexing ID of the mount they serve. It is nil if the plugin isn't
+	// multiplexed.
+	instances     map[string]backendInstance
+	instancesLock sync.RWMutex
+
 	logger log.Logger
 }
 
+type backendInstance struct {
+	backend        logical.Backend
+	brokeredClient *grpc.ClientConn
+}
+
+// getBackendAndBrokeredClient returns the backend and the brokered client
+// serving the request of the context.
+func (b *backendGRPCPluginServer) getBackendAndBrokeredClient(ctx context.Context) (logical.Backend, *grpc.ClientConn, error) {
+	if b.instances == nil {
+		return b.backend, b.brokeredClient, nil
+	}
+
+	id, err := pluginutil.GetMultiplexIDFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b.instancesLock.RLock()
+	defer b.instancesLock.RUnlock()
+
+	instance, ok := b.instances[id]
+	if !ok {
+		return nil, nil, ErrNoInstance
+	}
+	return instance.backend, instance.brokeredClient, nil
+}
+
 // Setup dials into the plugin's broker to get a shimmed storage, logger, and
 // system view of the backend. This method also instantiates the underlying
 // backend through its factory func for the server side of the plugin.
@@ -34,7 +71,9 @@ func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs)
 	if err != nil {
 		return &pb.SetupReply{}, err
 	}
-	b.brokeredClient = brokeredClient
+	if b.instances == nil {
+		b.brokeredClient = brokeredClient
+	}
 	storage := newGRPCStorageClient(brokeredClient)
 	sysView := newGRPCSystemView(brokeredClient)
 
@@ -50,11 +89,31 @@ func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs)
 	// to set b.backend
 	backend, err := b.factory(ctx, config)
 	if err != nil {
+		if b.instances != nil {
+			brokeredClient.Close()
+		}
 		return &pb.SetupReply{
 			Err: pb.ErrToString(err),
 		}, nil
 	}
-	b.backend = backend
+
+	if b.instances == nil {
+		b.backend = backend
+		return &pb.SetupReply{}, nil
+	}
+
+	id, err := pluginutil.GetMultiplexIDFromContext(ctx)
+	if err != nil {
+		brokeredClient.Close()
+		return &pb.SetupReply{}, err
+	}
+
+	b.instancesLock.Lock()
+	b.instances[id] = backendInstance{
+		backend:        backend,
+		brokeredClient: brokeredClient,
+	}
+	b.instancesLock.Unlock()
 
 	return &pb.SetupReply{}, nil
 }
@@ -64,14 +123,19 @@ func (b *backendGRPCPluginServer) HandleRequest(ctx context.Context, args *pb.Ha
 		return &pb.HandleRequestReply{}, ErrServerInMetadataMode
 	}
 
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.HandleRequestReply{}, err
+	}
+
 	logicalReq, err := pb.ProtoRequestToLogicalRequest(args.Request)
 	if err != nil {
 		return &pb.HandleRequestReply{}, err
 	}
 
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	logicalReq.Storage = newGRPCStorageClient(brokeredClient)
 
-	resp, respErr := b.backend.HandleRequest(ctx, logicalReq)
+	resp, respErr := backend.HandleRequest(ctx, logicalReq)
 
 	pbResp, err := pb.LogicalResponseToProtoResponse(resp)
 	if err != nil {
@@ -89,11 +153,16 @@ func (b *backendGRPCPluginServer) Initialize(ctx context.Context, _ *pb.Initiali
 		return &pb.InitializeReply{}, ErrServerInMetadataMode
 	}
 
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.InitializeReply{}, err
+	}
+
 	req := &logical.InitializationRequest{
-		Storage: newGRPCStorageClient(b.brokeredClient),
+		Storage: newGRPCStorageClient(brokeredClient),
 	}
 
-	respErr := b.backend.Initialize(ctx, req)
+	respErr := backend.Initialize(ctx, req)
 
 	return &pb.InitializeReply{
 		Err: pb.ErrToProtoErr(respErr),
@@ -101,7 +170,12 @@ func (b *backendGRPCPluginServer) Initialize(ctx context.Context, _ *pb.Initiali
 }
 
 func (b *backendGRPCPluginServer) SpecialPaths(ctx context.Context, args *pb.Empty) (*pb.SpecialPathsReply, error) {
-	paths := b.backend.SpecialPaths()
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.SpecialPathsReply{}, err
+	}
+
+	paths := backend.SpecialPaths()
 	if paths == nil {
 		return &pb.SpecialPathsReply{
 			Paths: nil,
@@ -123,13 +197,18 @@ func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args
 		return &pb.HandleExistenceCheckReply{}, ErrServerInMetadataMode
 	}
 
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.HandleExistenceCheckReply{}, err
+	}
+
 	logicalReq, err := pb.ProtoRequestToLogicalRequest(args.Request)
 	if err != nil {
 		return &pb.HandleExistenceCheckReply{}, err
 	}
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	logicalReq.Storage = newGRPCStorageClient(brokeredClient)
 
-	checkFound, exists, err := b.backend.HandleExistenceCheck(ctx, logicalReq)
+	checkFound, exists, err := backend.HandleExistenceCheck(ctx, logicalReq)
 	return &pb.HandleExistenceCheckReply{
 		CheckFound: checkFound,
 		Exists:     exists,
@@ -138,10 +217,27 @@ func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args
 }
 
 func (b *backendGRPCPluginServer) Cleanup(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
-	b.backend.Cleanup(ctx)
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.Empty{}, err
+	}
+
+	backend.Cleanup(ctx)
 
 	// Close rpc clients
-	b.brokeredClient.Close()
+	brokeredClient.Close()
+
+	if b.instances != nil {
+		id, err := pluginutil.GetMultiplexIDFromContext(ctx)
+		if err != nil {
+			return &pb.Empty{}, err
+		}
+
+		b.instancesLock.Lock()
+		delete(b.instances, id)
+		b.instancesLock.Unlock()
+	}
+
 	return &pb.Empty{}, nil
 }
 
@@ -150,12 +246,22 @@ func (b *backendGRPCPluginServer) InvalidateKey(ctx context.Context, args *pb.In
 		return &pb.Empty{}, ErrServerInMetadataMode
 	}
 
-	b.backend.InvalidateKey(ctx, args.Key)
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.Empty{}, err
+	}
+
+	backend.InvalidateKey(ctx, args.Key)
 	return &pb.Empty{}, nil
 }
 
 func (b *backendGRPCPluginServer) Type(ctx context.Context, _ *pb.Empty) (*pb.TypeReply, error) {
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.TypeReply{}, err
+	}
+
 	return &pb.TypeReply{
-		Type: uint32(b.backend.Type()),
+		Type: uint32(backend.Type()),
 	}, nil
 }
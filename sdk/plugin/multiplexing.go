@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+)
+
+// multiplexingProtocolVersion is the protocol version served by plugins able
+// to host the backends of several mounts in the same process.
+const multiplexingProtocolVersion = 5
+
+var (
+	// multiplexedClients holds the running processes of multiplexed plugins,
+	// keyed by the catalog entry they were started from.
+	multiplexedClients     = make(map[string]*multiplexedClient)
+	multiplexedClientsLock sync.Mutex
+)
+
+// multiplexedClient is a plugin process shared by the mounts of a multiplexed
+// plugin.
+type multiplexedClient struct {
+	key       string
+	client    *plugin.Client
+	rpcClient plugin.ClientProtocol
+
+	// refs is the number of mounts using the process; it is protected by
+	// multiplexedClientsLock.
+	refs int
+}
+
+// release is called when a mount stops using the process, which is killed
+// once no mount uses it anymore.
+func (m *multiplexedClient) release() {
+	multiplexedClientsLock.Lock()
+	defer multiplexedClientsLock.Unlock()
+
+	m.refs--
+	if m.refs > 0 {
+		return
+	}
+
+	if multiplexedClients[m.key] == m {
+		delete(multiplexedClients, m.key)
+	}
+	m.client.Kill()
+}
+
+// multiplexingKey returns the key identifying the process of a plugin, which
+// changes whenever the plugin is registered again in the catalog.
+func multiplexingKey(pluginRunner *pluginutil.PluginRunner) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%q/%q/%x", pluginRunner.Type, pluginRunner.Name, pluginRunner.Version,
+		pluginRunner.Command, pluginRunner.Args, pluginRunner.Env, pluginRunner.Sha256)
+}
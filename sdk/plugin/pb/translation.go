@@ -23,6 +23,7 @@ const (
 	ErrTypeInvalidRequest
 	ErrTypePermissionDenied
 	ErrTypeMultiAuthzPending
+	ErrTypeInvalidCredentials
 )
 
 func ProtoErrToErr(e *ProtoError) error {
@@ -52,6 +53,8 @@ func ProtoErrToErr(e *ProtoError) error {
 		err = logical.ErrPermissionDenied
 	case ErrTypeMultiAuthzPending:
 		err = logical.ErrMultiAuthzPending
+	case ErrTypeInvalidCredentials:
+		err = logical.ErrInvalidCredentials
 	}
 
 	return err
@@ -89,6 +92,8 @@ func ErrToProtoErr(e error) *ProtoError {
 		pbErr.ErrType = ErrTypePermissionDenied
 	case e == logical.ErrMultiAuthzPending:
 		pbErr.ErrType = ErrTypeMultiAuthzPending
+	case e == logical.ErrInvalidCredentials:
+		pbErr.ErrType = ErrTypeInvalidCredentials
 	}
 
 	return pbErr
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	plugin "github.com/hashicorp/go-plugin"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -21,6 +22,10 @@ type BackendPluginClient struct {
 	client *plugin.Client
 	sync.Mutex
 
+	// multiplexed is set if the plugin process is shared with other mounts,
+	// in which case it is only killed once all of them are cleaned up
+	multiplexed *multiplexedClient
+
 	logical.Backend
 }
 
@@ -28,16 +33,31 @@ type BackendPluginClient struct {
 // the go-plugin's client Kill() func
 func (b *BackendPluginClient) Cleanup(ctx context.Context) {
 	b.Backend.Cleanup(ctx)
+	if b.multiplexed != nil {
+		b.multiplexed.release()
+		return
+	}
 	b.client.Kill()
 }
 
 // NewBackend will return an instance of an RPC-based client implementation of the backend for
 // external plugins, or a concrete implementation of the backend if it is a builtin backend.
 // The backend is returned as a logical.Backend interface. The isMetadataMode param determines whether
-// the plugin should run in metadata mode.
+// the plugin should run in metadata mode. If the plugin_version config is set, that version of the
+// external plugin is looked up instead.
 func NewBackend(ctx context.Context, pluginName string, pluginType consts.PluginType, sys pluginutil.LookRunnerUtil, conf *logical.BackendConfig, isMetadataMode bool) (logical.Backend, error) {
 	// Look for plugin in the plugin catalog
-	pluginRunner, err := sys.LookupPlugin(ctx, pluginName, pluginType)
+	var pluginRunner *pluginutil.PluginRunner
+	var err error
+	if pluginVersion := conf.Config["plugin_version"]; pluginVersion != "" {
+		looker, ok := sys.(pluginutil.VersionedLooker)
+		if !ok {
+			return nil, fmt.Errorf("unable to look up version %q of plugin %q", pluginVersion, pluginName)
+		}
+		pluginRunner, err = looker.LookupPluginVersion(ctx, pluginName, pluginType, pluginVersion)
+	} else {
+		pluginRunner, err = sys.LookupPlugin(ctx, pluginName, pluginType)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -86,30 +106,49 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 				MetadataMode: isMetadataMode,
 			},
 		},
+		// Version 5 is served by multiplexed plugins, which host the backends
+		// of several mounts in the same process.
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				MetadataMode: isMetadataMode,
+			},
+		},
 	}
 
 	namedLogger := logger.Named(pluginRunner.Name)
 
-	var client *plugin.Client
-	var err error
-	if isMetadataMode {
-		client, err = pluginRunner.RunMetadataMode(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
-	} else {
-		client, err = pluginRunner.Run(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
-	}
-	if err != nil {
-		return nil, err
+	// Plugins in metadata mode are short-lived, so they don't share their
+	// process with other mounts
+	var multiplexed *multiplexedClient
+	if !isMetadataMode {
+		multiplexedClientsLock.Lock()
+		defer multiplexedClientsLock.Unlock()
+
+		multiplexed = multiplexedClients[multiplexingKey(pluginRunner)]
+		if multiplexed != nil && multiplexed.client.Exited() {
+			multiplexed = nil
+		}
 	}
 
-	// Connect via RPC
-	rpcClient, err := client.Client()
-	if err != nil {
-		return nil, err
+	var client *plugin.Client
+	var rpcClient plugin.ClientProtocol
+	if multiplexed != nil {
+		client = multiplexed.client
+		rpcClient = multiplexed.rpcClient
+	} else {
+		var err error
+		client, rpcClient, err = runPluginClient(ctx, sys, pluginRunner, pluginSet, namedLogger, isMetadataMode)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Request the plugin
 	raw, err := rpcClient.Dispense("backend")
 	if err != nil {
+		if multiplexed == nil {
+			client.Kill()
+		}
 		return nil, err
 	}
 
@@ -119,12 +158,39 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 	// implementation but is in fact over an RPC connection.
 	switch raw.(type) {
 	case *backendGRPCPluginClient:
-		backend = raw.(*backendGRPCPluginClient)
+		grpcClient := raw.(*backendGRPCPluginClient)
+		if client.NegotiatedVersion() >= multiplexingProtocolVersion {
+			grpcClient.multiplexingID, err = uuid.GenerateUUID()
+			if err != nil {
+				if multiplexed == nil {
+					client.Kill()
+				}
+				return nil, err
+			}
+		}
+		backend = grpcClient
 		transport = "gRPC"
 	default:
+		if multiplexed == nil {
+			client.Kill()
+		}
 		return nil, errors.New("unsupported plugin client type")
 	}
 
+	// Keep track of the processes of multiplexed plugins so that the next
+	// mounts of the plugin reuse them
+	if multiplexed == nil && !isMetadataMode && client.NegotiatedVersion() >= multiplexingProtocolVersion {
+		multiplexed = &multiplexedClient{
+			key:       multiplexingKey(pluginRunner),
+			client:    client,
+			rpcClient: rpcClient,
+		}
+		multiplexedClients[multiplexed.key] = multiplexed
+	}
+	if multiplexed != nil {
+		multiplexed.refs++
+	}
+
 	// Wrap the backend in a tracing middleware
 	if namedLogger.IsTrace() {
 		backend = &backendTracingMiddleware{
@@ -134,11 +200,49 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 	}
 
 	return &BackendPluginClient{
-		client:  client,
-		Backend: backend,
+		client:      client,
+		multiplexed: multiplexed,
+		Backend:     backend,
 	}, nil
 }
 
+// runPluginClient starts the plugin process and connects to it. The client
+// and the plugin first try to negotiate mTLS automatically; plugins built
+// against an older SDK need the TLS configuration to be passed through a
+// response wrapped token instead, so the plugin is started again with one if
+// that fails.
+func runPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginRunner, pluginSet map[int]plugin.PluginSet, logger log.Logger, isMetadataMode bool) (*plugin.Client, plugin.ClientProtocol, error) {
+	run := func(autoMTLS bool) (*plugin.Client, plugin.ClientProtocol, error) {
+		client, err := pluginRunner.RunConfig(ctx,
+			pluginutil.Runner(sys),
+			pluginutil.PluginSets(pluginSet),
+			pluginutil.HandshakeConfig(handshakeConfig),
+			pluginutil.Logger(logger),
+			pluginutil.MetadataMode(isMetadataMode),
+			pluginutil.AutoMTLS(autoMTLS),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Connect via RPC
+		rpcClient, err := client.Client()
+		if err != nil {
+			client.Kill()
+			return nil, nil, err
+		}
+		return client, rpcClient, nil
+	}
+
+	client, rpcClient, err := run(true)
+	if err == nil {
+		return client, rpcClient, nil
+	}
+	logger.Debug("failed to start plugin with automatic mTLS, retrying with a wrapping token", "error", err)
+
+	return run(false)
+}
+
 // wrapError takes a generic error type and makes it usable with the plugin
 // interface. Only errors which have exported fields and have been registered
 // with gob can be unwrapped and transported. This checks error types and, if
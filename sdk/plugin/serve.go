@@ -57,6 +57,50 @@ func Serve(opts *ServeOpts) error {
 		},
 	}
 
+	return serve(opts, logger, pluginSets)
+}
+
+// ServeMultiplex is a helper function used to serve a backend plugin able to
+// host the backends of several mounts in the same process. Vault starts a
+// single process for all the mounts of the plugin, each request carrying the
+// ID of the backend instance it is meant for. This should be ran on the
+// plugin's main process.
+func ServeMultiplex(opts *ServeOpts) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.New(&log.LoggerOptions{
+			Level:      log.Trace,
+			Output:     os.Stderr,
+			JSONFormat: true,
+		})
+	}
+
+	pluginSets := map[int]plugin.PluginSet{
+		3: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				Factory: opts.BackendFactoryFunc,
+				Logger:  logger,
+			},
+		},
+		4: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				Factory: opts.BackendFactoryFunc,
+				Logger:  logger,
+			},
+		},
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				Factory:             opts.BackendFactoryFunc,
+				Logger:              logger,
+				MultiplexingSupport: true,
+			},
+		},
+	}
+
+	return serve(opts, logger, pluginSets)
+}
+
+func serve(opts *ServeOpts, logger log.Logger, pluginSets map[int]plugin.PluginSet) error {
 	err := pluginutil.OptionallyEnableMlock()
 	if err != nil {
 		return err
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/errwrap"
@@ -55,6 +56,7 @@ type ACLResults struct {
 	MFAMethods         []string
 	ControlGroup       *ControlGroup
 	CapabilitiesBitmap uint32
+	GrantingPolicies   []logical.PolicyInfo
 }
 
 // NewACL is used to construct a policy based ACL from a set of policies.
@@ -103,6 +105,15 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 			a.root = true
 		}
 
+		pi := logical.PolicyInfo{
+			Name:        policy.Name,
+			NamespaceID: ns.ID,
+			Type:        "acl",
+		}
+		if policy.namespace != nil {
+			pi.NamespaceID = policy.namespace.ID
+		}
+
 		for _, pc := range policy.Paths {
 			var raw interface{}
 			var ok bool
@@ -127,6 +138,10 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				if err != nil {
 					return nil, errwrap.Wrapf("error cloning ACL permissions: {{err}}", err)
 				}
+				addGrantingPolicyToMap(clonedPerms, pi, pc.Permissions.CapabilitiesBitmap)
+				clonedPerms.BoundCIDRs = nil
+				clonedPerms.AllowedTimeOfDay = nil
+				addConditionsToMap(clonedPerms, pc.Permissions, 0)
 				switch {
 				case pc.HasSegmentWildcards:
 					a.segmentWildcardPaths[pc.Path] = clonedPerms
@@ -150,12 +165,16 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.GrantingPoliciesMap = nil
+				existingPerms.ConditionsMap = nil
 				goto INSERT
 
 			default:
 				// Insert the capabilities in this new policy into the existing
 				// value
+				addConditionsToMap(existingPerms, pc.Permissions, existingPerms.CapabilitiesBitmap)
 				existingPerms.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | pc.Permissions.CapabilitiesBitmap
+				addGrantingPolicyToMap(existingPerms, pi, pc.Permissions.CapabilitiesBitmap)
 			}
 
 			// Note: In these stanzas, we're preferring minimum lifetimes. So
@@ -272,6 +291,31 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 	return a, nil
 }
 
+// addGrantingPolicyToMap records the given policy as granting each of the
+// capabilities set in the bitmap
+func addGrantingPolicyToMap(perms *ACLPermissions, policy logical.PolicyInfo, capabilities uint32) {
+	if capabilities&DenyCapabilityInt > 0 {
+		return
+	}
+	if perms.GrantingPoliciesMap == nil {
+		perms.GrantingPoliciesMap = make(map[uint32][]logical.PolicyInfo)
+	}
+	for _, capability := range []uint32{
+		CreateCapabilityInt,
+		ReadCapabilityInt,
+		UpdateCapabilityInt,
+		DeleteCapabilityInt,
+		ListCapabilityInt,
+		SudoCapabilityInt,
+		PatchCapabilityInt,
+	} {
+		if capabilities&capability == 0 {
+			continue
+		}
+		perms.GrantingPoliciesMap[capability] = append(perms.GrantingPoliciesMap[capability], policy)
+	}
+}
+
 func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities []string) {
 	req := &logical.Request{
 		Path: path,
@@ -305,6 +349,9 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 	if capabilities&CreateCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, CreateCapability)
 	}
+	if capabilities&PatchCapabilityInt > 0 {
+		pathCapabilities = append(pathCapabilities, PatchCapability)
+	}
 
 	// If "deny" is explicitly set or if the path has no capabilities at all,
 	// set the path capabilities to "deny"
@@ -323,6 +370,11 @@ func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheck
 		ret.Allowed = true
 		ret.RootPrivs = true
 		ret.IsRoot = true
+		ret.GrantingPolicies = []logical.PolicyInfo{{
+			Name:        "root",
+			NamespaceID: namespace.RootNamespaceID,
+			Type:        "acl",
+		}}
 		return
 	}
 	op := req.Operation
@@ -394,32 +446,43 @@ CHECK:
 	ret.MFAMethods = permissions.MFAMethods
 	ret.ControlGroup = permissions.ControlGroup
 
-	operationAllowed := false
+	var grantingCapability uint32
 	switch op {
-	case logical.ReadOperation:
-		operationAllowed = capabilities&ReadCapabilityInt > 0
+	// HEAD requests are reads without a body
+	case logical.ReadOperation, logical.HeaderOperation:
+		grantingCapability = ReadCapabilityInt
 	case logical.ListOperation:
-		operationAllowed = capabilities&ListCapabilityInt > 0
+		grantingCapability = ListCapabilityInt
 	case logical.UpdateOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+		grantingCapability = UpdateCapabilityInt
 	case logical.DeleteOperation:
-		operationAllowed = capabilities&DeleteCapabilityInt > 0
+		grantingCapability = DeleteCapabilityInt
 	case logical.CreateOperation:
-		operationAllowed = capabilities&CreateCapabilityInt > 0
+		grantingCapability = CreateCapabilityInt
+	case logical.PatchOperation:
+		grantingCapability = PatchCapabilityInt
 
 	// These three re-use UpdateCapabilityInt since that's the most appropriate
 	// capability/operation mapping
 	case logical.RevokeOperation, logical.RenewOperation, logical.RollbackOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+		grantingCapability = UpdateCapabilityInt
 
 	default:
 		return
 	}
 
-	if !operationAllowed {
+	if capabilities&grantingCapability == 0 {
 		return
 	}
 
+	// Record which policies granted the operation once all the parameter and
+	// wrapping checks below have passed
+	defer func() {
+		if ret.Allowed {
+			ret.GrantingPolicies = permissions.GrantingPoliciesMap[grantingCapability]
+		}
+	}()
+
 	if permissions.MaxWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL > permissions.MaxWrappingTTL {
 			return
@@ -438,9 +501,13 @@ CHECK:
 		return
 	}
 
+	if !conditionsAllowed(permissions.ConditionsMap[grantingCapability], req, time.Now()) {
+		return
+	}
+
 	// Only check parameter permissions for operations that can modify
 	// parameters.
-	if op == logical.ReadOperation || op == logical.UpdateOperation || op == logical.CreateOperation {
+	if op == logical.ReadOperation || op == logical.UpdateOperation || op == logical.CreateOperation || op == logical.PatchOperation {
 		for _, parameter := range permissions.RequiredParameters {
 			if _, ok := req.Data[strings.ToLower(parameter)]; !ok {
 				return
@@ -466,7 +533,7 @@ CHECK:
 			// Check if parameter has been explicitly denied
 			if valueSlice, ok := permissions.DeniedParameters[strings.ToLower(parameter)]; ok {
 				// If the value exists in denied values slice, deny
-				if valueInParameterList(value, valueSlice) {
+				if valueInParameterList(value, valueSlice, true) {
 					return
 				}
 			}
@@ -494,7 +561,7 @@ CHECK:
 
 			// If the value doesn't exists in the allowed values slice,
 			// deny
-			if ok && !valueInParameterList(value, valueSlice) {
+			if ok && !valueInParameterList(value, valueSlice, false) {
 				return
 			}
 		}
@@ -698,18 +765,24 @@ func (c *Core) performPolicyChecks(ctx context.Context, acl *ACL, te *logical.To
 	return ret
 }
 
-func valueInParameterList(v interface{}, list []interface{}) bool {
+// valueInParameterList returns whether the value is in the allowed or, when
+// denied is set, the denied values of a parameter
+func valueInParameterList(v interface{}, list []interface{}, denied bool) bool {
 	// Empty list is equivalent to the item always existing in the list
 	if len(list) == 0 {
 		return true
 	}
 
-	return valueInSlice(v, list)
+	return valueInSlice(v, list, denied)
 }
 
-func valueInSlice(v interface{}, list []interface{}) bool {
+func valueInSlice(v interface{}, list []interface{}, denied bool) bool {
 	for _, el := range list {
-		if el == nil || v == nil {
+		if comparison, ok := el.(parameterComparison); ok {
+			if comparison.matches(v, denied) {
+				return true
+			}
+		} else if el == nil || v == nil {
 			// It doesn't seem possible to set up a nil entry in the list, but it is possible
 			// to pass in a null entry in the API request being checked. Just in case,
 			// nil will match nil.
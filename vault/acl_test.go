@@ -2,6 +2,8 @@ package vault
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"sync"
 	"testing"
@@ -359,6 +361,53 @@ func testLayeredACL(t *testing.T, acl *ACL, ns *namespace.Namespace) {
 	}
 }
 
+func TestACL_GrantingPolicies(t *testing.T) {
+	policy1, err := ParseACLPolicy(namespace.RootNamespace, aclPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := ParseACLPolicy(namespace.RootNamespace, aclPolicy2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL(namespace.RootContext(nil), []*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		op       logical.Operation
+		path     string
+		policies []string
+	}
+	tcases := []tcase{
+		{logical.ReadOperation, "prod/foo", []string{"DeV", "OpS"}},
+		{logical.DeleteOperation, "prod/foo", []string{"OpS"}},
+		{logical.UpdateOperation, "dev/foo", []string{"DeV"}},
+		{logical.ReadOperation, "dev/hide/foo", nil},
+		{logical.ReadOperation, "foo/bar", nil},
+	}
+
+	for _, tc := range tcases {
+		request := &logical.Request{
+			Operation: tc.op,
+			Path:      tc.path,
+		}
+		authResults := acl.AllowOperation(namespace.RootContext(nil), request, false)
+
+		var names []string
+		for _, pi := range authResults.GrantingPolicies {
+			if pi.NamespaceID != namespace.RootNamespaceID || pi.Type != "acl" {
+				t.Fatalf("bad: case %#v: unexpected policy info %#v", tc, pi)
+			}
+			names = append(names, pi.Name)
+		}
+		if !reflect.DeepEqual(names, tc.policies) {
+			t.Fatalf("bad: case %#v: expected %v, got %v", tc, tc.policies, names)
+		}
+	}
+}
+
 func TestACL_PolicyMerge(t *testing.T) {
 	t.Run("root-ns", func(t *testing.T) {
 		t.Parallel()
@@ -581,6 +630,27 @@ func testACLValuePermissions(t *testing.T, ns *namespace.Namespace) {
 		{"test/star", []string{"foo"}, []interface{}{true}, true},
 		{"test/star", []string{"bar"}, []interface{}{false}, true},
 		{"test/star", []string{"bar"}, []interface{}{true}, false},
+		{"test/numbers", []string{"ttl"}, []interface{}{3600}, true},
+		{"test/numbers", []string{"ttl"}, []interface{}{json.Number("60")}, true},
+		{"test/numbers", []string{"ttl"}, []interface{}{"1800"}, true},
+		{"test/numbers", []string{"ttl"}, []interface{}{3601}, false},
+		{"test/numbers", []string{"ttl"}, []interface{}{59.5}, false},
+		{"test/numbers", []string{"ttl"}, []interface{}{"1h"}, true},
+		{"test/numbers", []string{"ttl"}, []interface{}{" 30m "}, true},
+		{"test/numbers", []string{"ttl"}, []interface{}{"30s"}, false},
+		{"test/numbers", []string{"ttl"}, []interface{}{"2h"}, false},
+		{"test/numbers", []string{"ttl"}, []interface{}{"default"}, false},
+		{"test/numbers", []string{"count"}, []interface{}{5}, true},
+		{"test/numbers", []string{"count"}, []interface{}{-1}, false},
+		{"test/numbers", []string{"count"}, []interface{}{10}, false},
+		{"test/numbers", []string{"count"}, []interface{}{"many"}, false},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{"3600"}, true},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{"59m"}, true},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{"48h"}, false},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{"100000s"}, false},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{" 1e9 "}, false},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{json.Number("1e9")}, false},
+		{"test/denied-numbers", []string{"ttl"}, []interface{}{"2d"}, false},
 	}
 
 	for _, tc := range tcases {
@@ -603,6 +673,132 @@ func testACLValuePermissions(t *testing.T, ns *namespace.Namespace) {
 	}
 }
 
+func TestACL_PathConditions(t *testing.T) {
+	ns := namespace.RootNamespace
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+
+	now := time.Now().UTC()
+	window := func(from, to time.Duration) string {
+		return now.Add(from).Format("15:04") + "-" + now.Add(to).Format("15:04")
+	}
+	policy, err := ParseACLPolicy(ns, fmt.Sprintf(`
+path "cidr/*" {
+	capabilities = ["read"]
+	bound_cidrs = ["127.0.0.1/32", "10.0.0.0/8"]
+}
+path "open/*" {
+	capabilities = ["read"]
+	allowed_time_of_day = ["%s"]
+}
+path "closed/*" {
+	capabilities = ["read"]
+	allowed_time_of_day = ["%s"]
+}
+`, window(-time.Hour, time.Hour), window(2*time.Hour, 3*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := NewACL(ctx, []*Policy{policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		path       string
+		remoteAddr string
+		allowed    bool
+	}{
+		{"cidr/foo", "127.0.0.1", true},
+		{"cidr/foo", "10.1.2.3", true},
+		{"cidr/foo", "192.168.1.1", false},
+		{"cidr/foo", "", false},
+		{"open/foo", "192.168.1.1", true},
+		{"closed/foo", "192.168.1.1", false},
+	}
+	for _, tc := range tcases {
+		req := &logical.Request{
+			Operation:  logical.ReadOperation,
+			Path:       tc.path,
+			Connection: &logical.Connection{RemoteAddr: tc.remoteAddr},
+		}
+		if allowed := acl.AllowOperation(ctx, req, false).Allowed; allowed != tc.allowed {
+			t.Fatalf("bad: case %#v: %v", tc, allowed)
+		}
+	}
+}
+
+func TestACL_PathConditions_Merge(t *testing.T) {
+	ns := namespace.RootNamespace
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+
+	restricted, err := ParseACLPolicy(ns, `
+path "merged/*" {
+	capabilities = ["read", "update"]
+	bound_cidrs = ["127.0.0.1/32"]
+}
+path "both/*" {
+	capabilities = ["read"]
+	bound_cidrs = ["127.0.0.1/32"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.Name = "restricted"
+	unrestricted, err := ParseACLPolicy(ns, `
+path "merged/*" {
+	capabilities = ["read"]
+}
+path "both/*" {
+	capabilities = ["read"]
+	bound_cidrs = ["10.0.0.0/8"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrestricted.Name = "unrestricted"
+
+	tcases := []struct {
+		op         logical.Operation
+		path       string
+		remoteAddr string
+		allowed    bool
+	}{
+		// Read is granted without restrictions by one of the policies
+		{logical.ReadOperation, "merged/foo", "127.0.0.1", true},
+		{logical.ReadOperation, "merged/foo", "192.168.1.1", true},
+		// Update is only granted by the restricted policy
+		{logical.UpdateOperation, "merged/foo", "127.0.0.1", true},
+		{logical.UpdateOperation, "merged/foo", "192.168.1.1", false},
+		// Either policy's restrictions allow read
+		{logical.ReadOperation, "both/foo", "127.0.0.1", true},
+		{logical.ReadOperation, "both/foo", "10.1.2.3", true},
+		{logical.ReadOperation, "both/foo", "192.168.1.1", false},
+	}
+
+	// The result doesn't depend on the order of the policies
+	for _, policies := range [][]*Policy{
+		{restricted, unrestricted},
+		{unrestricted, restricted},
+	} {
+		acl, err := NewACL(ctx, policies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range tcases {
+			req := &logical.Request{
+				Operation:  tc.op,
+				Path:       tc.path,
+				Connection: &logical.Connection{RemoteAddr: tc.remoteAddr},
+			}
+			if allowed := acl.AllowOperation(ctx, req, false).Allowed; allowed != tc.allowed {
+				t.Fatalf("bad: policies %s, %s: case %#v: %v", policies[0].Name, policies[1].Name, tc, allowed)
+			}
+		}
+	}
+}
+
 func TestACL_SegmentWildcardPriority(t *testing.T) {
 	ns := namespace.RootNamespace
 	ctx := namespace.ContextWithNamespace(context.Background(), ns)
@@ -1174,4 +1370,21 @@ path "test/star" {
 	denied_parameters = {
 	}
 }
+path "test/numbers" {
+	policy = "write"
+	allowed_parameters = {
+		"ttl" = ["<=3600", "default"]
+		"count" = []
+	}
+	denied_parameters = {
+		"ttl" = ["< 60"]
+		"count" = ["< 0", ">= 10"]
+	}
+}
+path "test/denied-numbers" {
+	policy = "write"
+	denied_parameters = {
+		"ttl" = ["> 3600"]
+	}
+}
 `
@@ -5,10 +5,29 @@ package vault
 import (
 	"context"
 
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 func (c *Core) performEntPolicyChecks(ctx context.Context, acl *ACL, te *logical.TokenEntry, req *logical.Request, inEntity *identity.Entity, opts *PolicyCheckOpts, ret *AuthResults) {
 	ret.Allowed = true
+
+	// Requests on paths with MFA methods must validate all of them
+	if ret.ACLResults != nil && len(ret.ACLResults.MFAMethods) > 0 {
+		if err := c.validatePathMFA(ctx, req, inEntity, ret.ACLResults.MFAMethods); err != nil {
+			ret.Allowed = false
+			ret.DeniedError = true
+			ret.Error = multierror.Append(ret.Error, err)
+			return
+		}
+	}
+
+	// Requests on paths with a control group wait for its authorizations
+	if ret.ACLResults != nil && ret.ACLResults.ControlGroup != nil {
+		if err := c.checkControlGroup(ctx, req, ret.ACLResults.ControlGroup); err != nil {
+			ret.Allowed = false
+			ret.Error = multierror.Append(ret.Error, err)
+		}
+	}
 }
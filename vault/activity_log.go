@@ -2,10 +2,17 @@ package vault
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault/activity"
@@ -15,6 +22,12 @@ const (
 	// activitySubPath is the directory under the system view where
 	// the log will be stored.
 	activitySubPath = "activity/"
+
+	// activityMonthsSubPath is the directory under the activity log view
+	// where the monthly client and request counts are stored, keyed by
+	// activityMonthPathFormat.
+	activityMonthsSubPath   = "months/"
+	activityMonthPathFormat = "2006/01"
 )
 
 // ActivityLog tracks unique entity counts and non-entity token counts.
@@ -37,6 +50,56 @@ type ActivityLog struct {
 	fragmentLock     sync.RWMutex
 	fragment         *activity.LogFragment
 	fragmentCreation time.Time
+
+	// month holds the clients and requests seen this month. When a new month
+	// starts, the previous one is kept in pendingMonth until it is written
+	// to storage.
+	monthLock    sync.Mutex
+	month        *ActivityMonth
+	pendingMonth *ActivityMonth
+	monthDirty   bool
+}
+
+// ActivityMonth holds the distinct clients and the number of requests seen
+// in each namespace during a month.
+type ActivityMonth struct {
+	StartTime  time.Time                     `json:"start_time"`
+	Namespaces map[string]*ActivityNamespace `json:"namespaces"`
+}
+
+// ActivityNamespace holds the distinct clients and the number of requests
+// seen in a namespace. Clients are entities, or tokens without an entity,
+// which are identified by their accessor, or a hash of the token for batch
+// tokens.
+type ActivityNamespace struct {
+	Entities        map[string]bool `json:"entities"`
+	NonEntityTokens map[string]bool `json:"non_entity_tokens"`
+	Requests        uint64          `json:"requests"`
+}
+
+func newActivityMonth(t time.Time) *ActivityMonth {
+	return &ActivityMonth{
+		StartTime:  activityMonthStart(t),
+		Namespaces: make(map[string]*ActivityNamespace),
+	}
+}
+
+// activityMonthStart returns the start of the month of t, in UTC.
+func activityMonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (m *ActivityMonth) namespace(namespaceID string) *ActivityNamespace {
+	ns, ok := m.Namespaces[namespaceID]
+	if !ok {
+		ns = &ActivityNamespace{
+			Entities:        make(map[string]bool),
+			NonEntityTokens: make(map[string]bool),
+		}
+		m.Namespaces[namespaceID] = ns
+	}
+	return ns
 }
 
 // NewActivityLog creates an activity log.
@@ -63,10 +126,24 @@ func (c *Core) setupActivityLog(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := manager.loadCurrentMonth(ctx, time.Now()); err != nil {
+		return err
+	}
 	c.activityLog = manager
 	return nil
 }
 
+// teardownActivityLog writes the counts of the current month to storage and
+// unhooks the ActivityLog from Core.
+func (c *Core) teardownActivityLog(ctx context.Context) error {
+	if c.activityLog == nil {
+		return nil
+	}
+	err := c.activityLog.saveCurrentMonth(ctx)
+	c.activityLog = nil
+	return err
+}
+
 func (a *ActivityLog) AddEntityToFragment(entityID string, namespaceID string, timestamp time.Time) {
 	a.fragmentLock.Lock()
 	defer a.fragmentLock.Unlock()
@@ -106,3 +183,325 @@ func (a *ActivityLog) createCurrentFragment() {
 		// TODO: start a timer to send it, if we're a performance standby
 	}
 }
+
+// HandleTokenUsage records the client behind a token that was used for a
+// request: its entity if it has one, or the token itself otherwise. Clients
+// are counted in the namespace of the token.
+func (a *ActivityLog) HandleTokenUsage(te *logical.TokenEntry, now time.Time) {
+	if te == nil {
+		return
+	}
+
+	a.monthLock.Lock()
+	defer a.monthLock.Unlock()
+
+	ns := a.currentMonth(now).namespace(te.NamespaceID)
+	switch {
+	case te.EntityID != "":
+		if ns.Entities[te.EntityID] {
+			return
+		}
+		ns.Entities[te.EntityID] = true
+	default:
+		id := te.Accessor
+		if id == "" {
+			// Batch tokens have no accessor
+			sum := sha256.Sum256([]byte(te.ID))
+			id = base64.RawStdEncoding.EncodeToString(sum[:])
+		}
+		if ns.NonEntityTokens[id] {
+			return
+		}
+		ns.NonEntityTokens[id] = true
+	}
+	a.monthDirty = true
+}
+
+// AddRequest counts a request made in the namespace.
+func (a *ActivityLog) AddRequest(namespaceID string, now time.Time) {
+	a.monthLock.Lock()
+	defer a.monthLock.Unlock()
+
+	a.currentMonth(now).namespace(namespaceID).Requests++
+	a.monthDirty = true
+}
+
+// currentMonth returns the counts of the month of now, starting a new month
+// if needed. Must be called with the month lock held.
+func (a *ActivityLog) currentMonth(now time.Time) *ActivityMonth {
+	start := activityMonthStart(now)
+	if a.month == nil {
+		a.month = newActivityMonth(start)
+	}
+	if start.After(a.month.StartTime) {
+		if a.monthDirty {
+			a.pendingMonth = a.month
+		}
+		a.month = newActivityMonth(start)
+		a.monthDirty = false
+	}
+	return a.month
+}
+
+func (a *ActivityLog) loadMonth(ctx context.Context, key string) (*ActivityMonth, error) {
+	out, err := a.view.Get(ctx, activityMonthsSubPath+key)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read activity log: {{err}}", err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	month := &ActivityMonth{}
+	if err := out.DecodeJSON(month); err != nil {
+		return nil, err
+	}
+	return month, nil
+}
+
+// loadCurrentMonth primes the counts of the current month from storage, if
+// they were written before.
+func (a *ActivityLog) loadCurrentMonth(ctx context.Context, now time.Time) error {
+	month, err := a.loadMonth(ctx, now.UTC().Format(activityMonthPathFormat))
+	if err != nil {
+		return err
+	}
+
+	a.monthLock.Lock()
+	defer a.monthLock.Unlock()
+	a.month = month
+	return nil
+}
+
+// saveCurrentMonth writes the counts of the current month, and of the
+// previous month if it wasn't written yet, to storage.
+func (a *ActivityLog) saveCurrentMonth(ctx context.Context) error {
+	a.monthLock.Lock()
+	var entries []*logical.StorageEntry
+	for _, month := range []*ActivityMonth{a.pendingMonth, a.month} {
+		if month == nil || (month == a.month && !a.monthDirty) {
+			continue
+		}
+		entry, err := logical.StorageEntryJSON(activityMonthsSubPath+month.StartTime.Format(activityMonthPathFormat), month)
+		if err != nil {
+			a.monthLock.Unlock()
+			return errwrap.Wrapf("failed to create activity log entry: {{err}}", err)
+		}
+		entries = append(entries, entry)
+	}
+	a.pendingMonth = nil
+	a.monthDirty = false
+	a.monthLock.Unlock()
+
+	for _, entry := range entries {
+		if err := a.view.Put(ctx, entry); err != nil {
+			return errwrap.Wrapf("failed to save activity log: {{err}}", err)
+		}
+	}
+	return nil
+}
+
+// queryMonths returns the counts of the months overlapping the range from
+// start to end, oldest first. The months not yet written to storage are
+// taken from memory.
+func (a *ActivityLog) queryMonths(ctx context.Context, start, end time.Time) ([]*ActivityMonth, error) {
+	found := make(map[time.Time]*ActivityMonth)
+
+	years, err := a.view.List(ctx, activityMonthsSubPath)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read activity log: {{err}}", err)
+	}
+	for _, year := range years {
+		months, err := a.view.List(ctx, activityMonthsSubPath+year)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to read activity log: {{err}}", err)
+		}
+		for _, m := range months {
+			t, err := time.Parse(activityMonthPathFormat, year+m)
+			if err != nil {
+				return nil, err
+			}
+			if t.After(end) || !t.AddDate(0, 1, 0).After(start) {
+				continue
+			}
+			month, err := a.loadMonth(ctx, year+m)
+			if err != nil {
+				return nil, err
+			}
+			if month != nil {
+				found[t] = month
+			}
+		}
+	}
+
+	// Encode the in-memory months so that the copies don't share their maps
+	a.monthLock.Lock()
+	for _, month := range []*ActivityMonth{a.pendingMonth, a.month} {
+		if month == nil || month.StartTime.After(end) || !month.StartTime.AddDate(0, 1, 0).After(start) {
+			continue
+		}
+		entry, err := logical.StorageEntryJSON("", month)
+		if err != nil {
+			a.monthLock.Unlock()
+			return nil, err
+		}
+		copied := &ActivityMonth{}
+		if err := entry.DecodeJSON(copied); err != nil {
+			a.monthLock.Unlock()
+			return nil, err
+		}
+		found[month.StartTime] = copied
+	}
+	a.monthLock.Unlock()
+
+	ret := make([]*ActivityMonth, 0, len(found))
+	for _, month := range found {
+		ret = append(ret, month)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].StartTime.Before(ret[j].StartTime) })
+	return ret, nil
+}
+
+// ActivityCounts are the distinct clients and requests seen over a period.
+type ActivityCounts struct {
+	DistinctEntities int    `json:"distinct_entities"`
+	NonEntityTokens  int    `json:"non_entity_tokens"`
+	Clients          int    `json:"clients"`
+	Requests         uint64 `json:"requests"`
+}
+
+// ActivityNamespaceRecord holds the counts of a namespace over a period.
+type ActivityNamespaceRecord struct {
+	NamespaceID   string         `json:"namespace_id"`
+	NamespacePath string         `json:"namespace_path"`
+	Counts        ActivityCounts `json:"counts"`
+}
+
+// ActivityMonthRecord holds the counts of a month, in total and by namespace.
+type ActivityMonthRecord struct {
+	Month      string                     `json:"month"`
+	Counts     ActivityCounts             `json:"counts"`
+	Namespaces []*ActivityNamespaceRecord `json:"by_namespace"`
+}
+
+// ActivitySummary holds the counts of a range of months. Clients seen in
+// several months are only counted once in the totals of the range.
+type ActivitySummary struct {
+	StartTime  time.Time                  `json:"start_time"`
+	EndTime    time.Time                  `json:"end_time"`
+	Total      ActivityCounts             `json:"total"`
+	Namespaces []*ActivityNamespaceRecord `json:"by_namespace"`
+	Months     []*ActivityMonthRecord     `json:"months"`
+}
+
+// activityTally accumulates the clients and requests of namespaces.
+type activityTally map[string]*ActivityNamespace
+
+func (t activityTally) add(namespaceID string, ns *ActivityNamespace) {
+	acc, ok := t[namespaceID]
+	if !ok {
+		acc = &ActivityNamespace{
+			Entities:        make(map[string]bool),
+			NonEntityTokens: make(map[string]bool),
+		}
+		t[namespaceID] = acc
+	}
+	for id := range ns.Entities {
+		acc.Entities[id] = true
+	}
+	for id := range ns.NonEntityTokens {
+		acc.NonEntityTokens[id] = true
+	}
+	acc.Requests += ns.Requests
+}
+
+// records returns the counts of each namespace, sorted by namespace path, and
+// their total.
+func (t activityTally) records(pathFunc func(string) string) ([]*ActivityNamespaceRecord, ActivityCounts) {
+	var total ActivityCounts
+	ret := make([]*ActivityNamespaceRecord, 0, len(t))
+	for id, ns := range t {
+		counts := ActivityCounts{
+			DistinctEntities: len(ns.Entities),
+			NonEntityTokens:  len(ns.NonEntityTokens),
+			Clients:          len(ns.Entities) + len(ns.NonEntityTokens),
+			Requests:         ns.Requests,
+		}
+		total.DistinctEntities += counts.DistinctEntities
+		total.NonEntityTokens += counts.NonEntityTokens
+		total.Clients += counts.Clients
+		total.Requests += counts.Requests
+
+		ret = append(ret, &ActivityNamespaceRecord{
+			NamespaceID:   id,
+			NamespacePath: pathFunc(id),
+			Counts:        counts,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].NamespacePath != ret[j].NamespacePath {
+			return ret[i].NamespacePath < ret[j].NamespacePath
+		}
+		return ret[i].NamespaceID < ret[j].NamespaceID
+	})
+	return ret, total
+}
+
+// Summary returns the counts of the months overlapping the range from start
+// to end. pathFunc returns the path of a namespace from its ID.
+func (a *ActivityLog) Summary(ctx context.Context, start, end time.Time, pathFunc func(string) string) (*ActivitySummary, error) {
+	months, err := a.queryMonths(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ActivitySummary{
+		StartTime: start.UTC(),
+		EndTime:   end.UTC(),
+		Months:    make([]*ActivityMonthRecord, 0, len(months)),
+	}
+	rangeTally := make(activityTally)
+	for _, month := range months {
+		monthTally := make(activityTally)
+		for id, ns := range month.Namespaces {
+			monthTally.add(id, ns)
+			rangeTally.add(id, ns)
+		}
+
+		record := &ActivityMonthRecord{
+			Month: month.StartTime.Format("2006-01"),
+		}
+		record.Namespaces, record.Counts = monthTally.records(pathFunc)
+		summary.Months = append(summary.Months, record)
+	}
+	summary.Namespaces, summary.Total = rangeTally.records(pathFunc)
+
+	return summary, nil
+}
+
+// WriteCSV writes the counts of each namespace for each month of the summary
+// as CSV, with a header row.
+func (s *ActivitySummary) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"month", "namespace_id", "namespace_path", "distinct_entities", "non_entity_tokens", "clients", "requests"}); err != nil {
+		return err
+	}
+	for _, month := range s.Months {
+		for _, ns := range month.Namespaces {
+			err := cw.Write([]string{
+				month.Month,
+				ns.NamespaceID,
+				ns.NamespacePath,
+				strconv.Itoa(ns.Counts.DistinctEntities),
+				strconv.Itoa(ns.Counts.NonEntityTokens),
+				strconv.Itoa(ns.Counts.Clients),
+				strconv.FormatUint(ns.Counts.Requests, 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
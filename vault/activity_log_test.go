@@ -1,8 +1,13 @@
 package vault
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
 )
 
 func TestActivityLog_Creation(t *testing.T) {
@@ -74,3 +79,133 @@ func TestActivityLog_Creation(t *testing.T) {
 	}
 
 }
+
+func TestActivityLog_MonthlyCounts(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+	a := core.activityLog
+	ctx := context.Background()
+
+	october := time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)
+	november := time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC)
+	pathFunc := func(id string) string { return id + "/" }
+
+	// The same entity is only counted once per month, tokens without an
+	// entity are counted by accessor or hash
+	a.month = nil
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "t1", Accessor: "a1", EntityID: "e1", NamespaceID: "ns1"}, october)
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "t2", Accessor: "a2", EntityID: "e1", NamespaceID: "ns1"}, october)
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "t3", Accessor: "a3", NamespaceID: "ns1"}, october)
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "batch", NamespaceID: "ns2"}, october)
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "batch", NamespaceID: "ns2"}, october)
+	a.AddRequest("ns1", october)
+	a.AddRequest("ns1", october)
+
+	// A new month keeps the previous one until it's written
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "t4", Accessor: "a4", EntityID: "e1", NamespaceID: "ns1"}, november)
+	a.HandleTokenUsage(&logical.TokenEntry{ID: "t5", Accessor: "a5", EntityID: "e2", NamespaceID: "ns1"}, november)
+	a.AddRequest("ns1", november)
+	if a.pendingMonth == nil || !a.pendingMonth.StartTime.Equal(activityMonthStart(october)) {
+		t.Fatalf("expected october to be pending, got %#v", a.pendingMonth)
+	}
+	if err := a.saveCurrentMonth(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if a.pendingMonth != nil {
+		t.Fatal("expected pending month to be written")
+	}
+
+	// Both months are read back from storage
+	a.month = nil
+	summary, err := a.Summary(ctx, october, november, pathFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Months) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(summary.Months))
+	}
+
+	oct := summary.Months[0]
+	if oct.Month != "2020-10" {
+		t.Fatalf("bad month: %s", oct.Month)
+	}
+	expected := ActivityCounts{DistinctEntities: 1, NonEntityTokens: 2, Clients: 3, Requests: 2}
+	if oct.Counts != expected {
+		t.Fatalf("bad october counts: %#v", oct.Counts)
+	}
+	if len(oct.Namespaces) != 2 || oct.Namespaces[0].NamespacePath != "ns1/" || oct.Namespaces[1].Counts.NonEntityTokens != 1 {
+		t.Fatalf("bad october namespaces: %#v", oct.Namespaces)
+	}
+
+	// e1 is counted once over the range
+	expected = ActivityCounts{DistinctEntities: 2, NonEntityTokens: 2, Clients: 4, Requests: 3}
+	if summary.Total != expected {
+		t.Fatalf("bad total: %#v", summary.Total)
+	}
+
+	// Months outside of the range are left out
+	summary, err = a.Summary(ctx, november, november, pathFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Months) != 1 || summary.Months[0].Month != "2020-11" {
+		t.Fatalf("bad months: %#v", summary.Months)
+	}
+}
+
+func TestActivityLog_Endpoint(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	// Requests made with the root token are metered
+	resp, err := core.HandleRequest(ctx, &logical.Request{
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+		Path:        "sys/internal/counters/activity",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\n err: %v", resp, err)
+	}
+	total := resp.Data["total"].(ActivityCounts)
+	if total.NonEntityTokens != 1 || total.Clients != 1 || total.Requests < 1 {
+		t.Fatalf("bad total: %#v", total)
+	}
+	byNamespace := resp.Data["by_namespace"].([]*ActivityNamespaceRecord)
+	if len(byNamespace) != 1 || byNamespace[0].NamespaceID != namespace.RootNamespaceID {
+		t.Fatalf("bad namespaces: %#v", byNamespace)
+	}
+
+	resp, err = core.HandleRequest(ctx, &logical.Request{
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+		Path:        "sys/internal/counters/activity",
+		Data: map[string]interface{}{
+			"format": "csv",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\n err: %v", resp, err)
+	}
+	if resp.Data[logical.HTTPContentType] != "text/csv" {
+		t.Fatalf("bad content type: %v", resp.Data[logical.HTTPContentType])
+	}
+	lines := strings.Split(strings.TrimSpace(string(resp.Data[logical.HTTPRawBody].([]byte))), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "month,namespace_id,") {
+		t.Fatalf("bad csv: %q", lines)
+	}
+	if !strings.Contains(lines[1], ","+namespace.RootNamespaceID+",") {
+		t.Fatalf("bad csv row: %q", lines[1])
+	}
+
+	resp, err = core.HandleRequest(ctx, &logical.Request{
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+		Path:        "sys/internal/counters/activity",
+		Data: map[string]interface{}{
+			"start_time": "2020-11-01T00:00:00Z",
+			"end_time":   "2020-10-01T00:00:00Z",
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected error with an inverted range, got %#v", resp)
+	}
+}
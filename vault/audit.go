@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/errwrap"
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -38,6 +41,11 @@ const (
 var (
 	// loadAuditFailed if loading audit tables encounters an error
 	errLoadAuditFailed = errors.New("failed to setup audit table")
+
+	// rootOnlyAuditTypes are the audit device types writing to a destination
+	// picked in their options, such as a path on the Vault host or a network
+	// address, so only operators of the root namespace may enable them
+	rootOnlyAuditTypes = []string{"file", "socket", "otel"}
 )
 
 // enableAudit is used to enable a new audit backend
@@ -52,12 +60,45 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	// The device couldn't be managed, as sys/audit/tail streams entries
+	if entry.Path == "tail/" {
+		return fmt.Errorf(`"tail" is reserved and can't be used as an audit device path`)
+	}
+
 	// Update the audit table
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
 
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	fallback, err := isFallbackAudit(entry)
+	if err != nil {
+		return err
+	}
+	if fallback && ns.ID != namespace.RootNamespaceID {
+		return fmt.Errorf("a fallback audit device can only be enabled in the root namespace")
+	}
+	if ns.ID != namespace.RootNamespaceID && strutil.StrListContains(rootOnlyAuditTypes, entry.Type) {
+		return fmt.Errorf("audit devices of type %q can only be enabled in the root namespace", entry.Type)
+	}
+	filter, err := parseAuditFilter(entry.Options)
+	if err != nil {
+		return err
+	}
+	entry.NamespaceID = ns.ID
+	entry.namespace = ns
+
 	// Look for matching name
 	for _, ent := range c.audit.Entries {
+		// Devices in other namespaces are registered under their full path
+		// so they can't conflict
+		if ent.NamespaceID != ns.ID {
+			continue
+		}
+
 		switch {
 		// Existing is sql/mysql/ new is sql/ or
 		// existing is sql/ and new is sql/mysql/
@@ -66,6 +107,12 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 		case strings.HasPrefix(entry.Path, ent.Path):
 			return fmt.Errorf("path already in use")
 		}
+
+		if fallback {
+			if entFallback, _ := isFallbackAudit(ent); entFallback {
+				return fmt.Errorf("a fallback audit device is already enabled at %q", ent.Path)
+			}
+		}
 	}
 
 	// Generate a new UUID and view
@@ -106,13 +153,6 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	newTable := c.audit.shallowClone()
 	newTable.Entries = append(newTable.Entries, entry)
 
-	ns, err := namespace.FromContext(ctx)
-	if err != nil {
-		return err
-	}
-	entry.NamespaceID = ns.ID
-	entry.namespace = ns
-
 	if updateStorage {
 		if err := c.persistAudit(ctx, newTable, entry.Local); err != nil {
 			return errors.New("failed to update audit table")
@@ -122,9 +162,9 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(auditBrokerKey(entry), backend, view, ns, filter, entry.Local, fallback)
 	if c.logger.IsInfo() {
-		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
+		c.logger.Info("enabled audit backend", "namespace", ns.Path, "path", entry.Path, "type", entry.Type)
 	}
 
 	return nil
@@ -175,9 +215,9 @@ func (c *Core) disableAudit(ctx context.Context, path string, updateStorage bool
 	c.audit = newTable
 
 	// Unmount the backend
-	c.auditBroker.Deregister(path)
+	c.auditBroker.Deregister(auditBrokerKey(entry))
 	if c.logger.IsInfo() {
-		c.logger.Info("disabled audit backend", "path", path)
+		c.logger.Info("disabled audit backend", "namespace", entry.Namespace().Path, "path", path)
 	}
 
 	removeAuditPathChecker(c, entry)
@@ -352,6 +392,7 @@ func (c *Core) setupAudits(ctx context.Context) error {
 	brokerLogger := c.baseLogger.Named("audit")
 	c.AddLogger(brokerLogger)
 	broker := NewAuditBroker(brokerLogger)
+	broker.metricSink = c.metricSink
 
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
@@ -384,8 +425,20 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			continue
 		}
 
+		fallback, err := isFallbackAudit(entry)
+		if err != nil {
+			c.logger.Error("failed to parse fallback option", "path", entry.Path, "error", err)
+			continue
+		}
+
+		filter, err := parseAuditFilter(entry.Options)
+		if err != nil {
+			c.logger.Error("failed to parse sampling options", "path", entry.Path, "error", err)
+			continue
+		}
+
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(auditBrokerKey(entry), backend, view, entry.Namespace(), filter, entry.Local, fallback)
 
 		successCount++
 	}
@@ -404,6 +457,14 @@ func (c *Core) teardownAudits() error {
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
 
+	// Persist the entries buffered by the backends before they're dropped
+	var result error
+	if c.auditBroker != nil {
+		if err := c.auditBroker.Flush(context.Background()); err != nil {
+			result = err
+		}
+	}
+
 	if c.audit != nil {
 		for _, entry := range c.audit.Entries {
 			c.removeAuditReloadFunc(entry)
@@ -413,7 +474,7 @@ func (c *Core) teardownAudits() error {
 
 	c.audit = nil
 	c.auditBroker = nil
-	return nil
+	return result
 }
 
 // removeAuditReloadFunc removes the reload func from the working set. The
@@ -421,7 +482,7 @@ func (c *Core) teardownAudits() error {
 func (c *Core) removeAuditReloadFunc(entry *MountEntry) {
 	switch entry.Type {
 	case "file":
-		key := "audit_file|" + entry.Path
+		key := "audit_file|" + auditBrokerKey(entry)
 		c.reloadFuncsLock.Lock()
 
 		if c.logger.IsDebug() {
@@ -434,6 +495,30 @@ func (c *Core) removeAuditReloadFunc(entry *MountEntry) {
 	}
 }
 
+// auditBrokerKey returns the name the entry is registered under in the audit
+// broker, which is its path qualified by the path of its namespace.
+func auditBrokerKey(entry *MountEntry) string {
+	if entry.namespace == nil {
+		return entry.Path
+	}
+	return entry.namespace.Path + entry.Path
+}
+
+// isFallbackAudit returns whether the entry is configured as the fallback
+// device, which only receives entries once all other devices have failed.
+func isFallbackAudit(entry *MountEntry) (bool, error) {
+	raw, ok := entry.Options["fallback"]
+	if !ok {
+		return false, nil
+	}
+
+	fallback, err := parseutil.ParseBool(raw)
+	if err != nil {
+		return false, errwrap.Wrapf("invalid value for fallback: {{err}}", err)
+	}
+	return fallback, nil
+}
+
 // newAuditBackend is used to create and configure a new audit backend by name
 func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logical.Storage, conf map[string]string) (audit.Backend, error) {
 	f, ok := c.auditBackends[entry.Type]
@@ -446,10 +531,18 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		Location: salt.DefaultLocation,
 	}
 
+	// The cluster name is only informational, so failing to read it doesn't
+	// prevent the device from being set up
+	var clusterName string
+	if cluster, err := c.Cluster(ctx); err == nil {
+		clusterName = cluster.Name
+	}
+
 	be, err := f(ctx, &audit.BackendConfig{
-		SaltView:   view,
-		SaltConfig: saltConfig,
-		Config:     conf,
+		SaltView:    view,
+		SaltConfig:  saltConfig,
+		Config:      conf,
+		ClusterName: clusterName,
 	})
 	if err != nil {
 		return nil, err
@@ -463,7 +556,7 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 
 	switch entry.Type {
 	case "file":
-		key := "audit_file|" + entry.Path
+		key := "audit_file|" + auditBrokerKey(entry)
 
 		c.reloadFuncsLock.Lock()
 
@@ -488,6 +581,12 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 				auditLogger.Debug("socket backend options", "path", entry.Path, "address", entry.Options["address"], "socket type", entry.Options["socket_type"])
 			}
 		}
+	case "otel":
+		if auditLogger.IsDebug() {
+			if entry.Options != nil {
+				auditLogger.Debug("otel backend options", "path", entry.Path, "endpoint", entry.Options["endpoint"])
+			}
+		}
 	case "syslog":
 		if auditLogger.IsDebug() {
 			if entry.Options != nil {
@@ -9,41 +9,85 @@ import (
 	metrics "github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 type backendEntry struct {
-	backend audit.Backend
-	view    *BarrierView
-	local   bool
+	backend   audit.Backend
+	view      *BarrierView
+	namespace *namespace.Namespace
+	filter    *auditFilter
+	local     bool
+	fallback  bool
+}
+
+// appliesTo returns whether the backend should receive entries for requests
+// made in the given namespace. Backends enabled in the root namespace receive
+// every entry; others only receive entries for their namespace and its
+// children.
+func (be backendEntry) appliesTo(ns *namespace.Namespace) bool {
+	if be.namespace == nil || be.namespace.ID == namespace.RootNamespaceID {
+		return true
+	}
+	return ns.ID == be.namespace.ID || ns.HasParent(be.namespace)
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
 // events given that multiple backends may be configured.
 type AuditBroker struct {
 	sync.RWMutex
-	backends map[string]backendEntry
-	logger   log.Logger
+	backends    map[string]backendEntry
+	subscribers map[*AuditSubscriber]struct{}
+	logger      log.Logger
+
+	// metricSink receives the per-device metrics, labeled with the device
+	metricSink *metricsutil.ClusterMetricSink
 }
 
 // NewAuditBroker creates a new audit broker
 func NewAuditBroker(log log.Logger) *AuditBroker {
 	b := &AuditBroker{
-		backends: make(map[string]backendEntry),
-		logger:   log,
+		backends:    make(map[string]backendEntry),
+		subscribers: make(map[*AuditSubscriber]struct{}),
+		logger:      log,
+		metricSink:  metricsutil.BlackholeSink(),
 	}
 	return b
 }
 
-// Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool) {
+// emitDeviceMetrics records how long a device took to write an entry, how long
+// the request was held up by the device, which also covers hashing the audited
+// headers, and whether the device failed.
+func (a *AuditBroker) emitDeviceMetrics(op, name string, start, logStart time.Time, logged bool) {
+	labels := []metrics.Label{{"device", name}}
+	if !logStart.IsZero() {
+		a.metricSink.MeasureSinceWithLabels([]string{"audit", "device", op}, logStart, labels)
+	}
+	a.metricSink.MeasureSinceWithLabels([]string{"audit", "device", "blocked_time"}, start, labels)
+
+	failure := float32(0.0)
+	if !logged {
+		failure = 1.0
+	}
+	a.metricSink.IncrCounterWithLabels([]string{"audit", "device", op + "_failure"}, failure, labels)
+}
+
+// Register is used to add new audit backend to the broker. A fallback backend
+// is only written to when every other backend has failed. A nil namespace is
+// treated as the root namespace, and a nil filter logs every entry.
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, ns *namespace.Namespace, filter *auditFilter, local, fallback bool) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
-		backend: b,
-		view:    v,
-		local:   local,
+		backend:   b,
+		view:      v,
+		namespace: ns,
+		filter:    filter,
+		local:     local,
+		fallback:  fallback,
 	}
 }
 
@@ -120,30 +164,53 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
-	anyLogged := false
-	for name, be := range a.backends {
+	logRequest := func(name string, be backendEntry) (logged bool) {
+		if be.filter != nil {
+			// Entries the device is configured to sample out count as
+			// logged, but the ones over its budget don't
+			if be.filter.skip(name, in.Request) {
+				return true
+			}
+			if !be.filter.withinBudget(name, in.Request, false, a.logger) {
+				return false
+			}
+		}
+
+		var logStart time.Time
+		start := time.Now()
+		defer func() {
+			a.emitDeviceMetrics("log_request", name, start, logStart, logged)
+		}()
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
 			a.logger.Error("backend failed to include headers", "backend", name, "error", thErr)
-			continue
+			return false
 		}
 		in.Request.Headers = transHeaders
 
-		start := time.Now()
+		logStart = time.Now()
 		lrErr := be.backend.LogRequest(ctx, in)
-		metrics.MeasureSince([]string{"audit", name, "log_request"}, start)
+		metrics.MeasureSince([]string{"audit", name, "log_request"}, logStart)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log request", "backend", name, "error", lrErr)
-		} else {
-			anyLogged = true
+			return false
 		}
+		return true
 	}
-	if !anyLogged && len(a.backends) > 0 {
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		ns = namespace.RootNamespace
+	}
+
+	if !a.logToBackends("log_request", ns, logRequest) {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
+	a.publish(ctx, ns, "request", in, headers, headersConfig)
+
 	return retErr.ErrorOrNil()
 }
 
@@ -176,33 +243,118 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
-	anyLogged := false
-	for name, be := range a.backends {
+	logResponse := func(name string, be backendEntry) (logged bool) {
+		if be.filter != nil {
+			// Entries the device is configured to sample out count as
+			// logged, but the ones over its budget don't
+			if be.filter.skip(name, in.Request) {
+				return true
+			}
+			if !be.filter.withinBudget(name, in.Request, true, a.logger) {
+				return false
+			}
+		}
+
+		var logStart time.Time
+		start := time.Now()
+		defer func() {
+			a.emitDeviceMetrics("log_response", name, start, logStart, logged)
+		}()
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
 			a.logger.Error("backend failed to include headers", "backend", name, "error", thErr)
-			continue
+			return false
 		}
 		in.Request.Headers = transHeaders
 
-		start := time.Now()
+		logStart = time.Now()
 		lrErr := be.backend.LogResponse(ctx, in)
-		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
+		metrics.MeasureSince([]string{"audit", name, "log_response"}, logStart)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log response", "backend", name, "error", lrErr)
-		} else {
-			anyLogged = true
+			return false
 		}
+		return true
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		ns = namespace.RootNamespace
 	}
-	if !anyLogged && len(a.backends) > 0 {
+
+	if !a.logToBackends("log_response", ns, logResponse) {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
+	a.publish(ctx, ns, "response", in, headers, headersConfig)
+
 	return retErr.ErrorOrNil()
 }
 
+// logToBackends writes an entry for a request made in the given namespace to
+// every backend that applies to it, and returns whether the entry was audited.
+// When backends are enabled in the root namespace, at least one of them must
+// log the entry, so that the backends enabled by a namespace can't stand in
+// for the ones of the operator. Otherwise at least one of the backends of the
+// namespace must log it. An entry without any applicable backend counts as
+// audited. The broker lock must be held.
+func (a *AuditBroker) logToBackends(op string, ns *namespace.Namespace, logFunc func(string, backendEntry) bool) bool {
+	var rootEligible, nsEligible int
+	var rootLogged, nsLogged bool
+	for name, be := range a.backends {
+		if be.fallback || !be.appliesTo(ns) {
+			continue
+		}
+		logged := logFunc(name, be)
+		if be.namespace == nil || be.namespace.ID == namespace.RootNamespaceID {
+			rootEligible++
+			rootLogged = rootLogged || logged
+		} else {
+			nsEligible++
+			nsLogged = nsLogged || logged
+		}
+	}
+
+	eligible := rootEligible + nsEligible
+	logged := rootLogged
+	if rootEligible == 0 {
+		logged = nsLogged
+	}
+	if logged {
+		return true
+	}
+
+	found, logged := a.logToFallback(op, eligible, logFunc)
+	return logged || (!found && eligible == 0)
+}
+
+// logToFallback writes to the fallback backend, if one is registered, once
+// all the primary backends have failed. It returns whether a fallback backend
+// was found and whether it logged the entry. The broker lock must be held.
+func (a *AuditBroker) logToFallback(op string, primaries int, logFunc func(string, backendEntry) bool) (bool, bool) {
+	for name, be := range a.backends {
+		if !be.fallback {
+			continue
+		}
+
+		// Only warn if there were primary backends that should have taken the
+		// entry; a lone fallback device is just a regular device.
+		if primaries > 0 {
+			a.logger.Warn("all audit backends failed, writing to fallback backend", "backend", name)
+			metrics.IncrCounter([]string{"audit", "fallback", op}, 1)
+		}
+
+		if logFunc(name, be) {
+			return true, true
+		}
+		metrics.IncrCounter([]string{"audit", "fallback", op + "_failure"}, 1)
+		return true, false
+	}
+	return false, false
+}
+
 func (a *AuditBroker) Invalidate(ctx context.Context, key string) {
 	// For now we ignore the key as this would only apply to salts. We just
 	// sort of brute force it on each one.
@@ -212,3 +364,22 @@ func (a *AuditBroker) Invalidate(ctx context.Context, key string) {
 		be.backend.Invalidate(ctx)
 	}
 }
+
+// Flush flushes the entries buffered by the backends implementing
+// audit.Flusher.
+func (a *AuditBroker) Flush(ctx context.Context) error {
+	a.RLock()
+	defer a.RUnlock()
+
+	var retErr *multierror.Error
+	for name, be := range a.backends {
+		flusher, ok := be.backend.(audit.Flusher)
+		if !ok {
+			continue
+		}
+		if err := flusher.Flush(ctx); err != nil {
+			retErr = multierror.Append(retErr, fmt.Errorf("error flushing audit backend %q: %w", name, err))
+		}
+	}
+	return retErr.ErrorOrNil()
+}
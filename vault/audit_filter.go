@@ -0,0 +1,203 @@
+package vault
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// auditOverflowReportInterval is how often a device that is over its entry
+	// budget reports the number of entries it has dropped.
+	auditOverflowReportInterval = time.Minute
+
+	// auditBudgetPairTimeout is how long the budget remembers the requests it
+	// admitted, so that their responses are logged as well
+	auditBudgetPairTimeout = time.Minute
+)
+
+// auditFilter decides which entries are written to an audit device. It
+// implements sampling of read operations and a budget of entries per second
+// for them.
+type auditFilter struct {
+	// sampleRate is N when only 1 of every N read operations is logged
+	sampleRate uint32
+	// samplePaths restricts sampling to requests under these prefixes
+	samplePaths []string
+	// maxPerSecond is the maximum number of entries logged each second
+	maxPerSecond int
+
+	l      sync.Mutex
+	window int64
+	count  int
+	// admitted holds the IDs of the requests admitted by the budget, with
+	// the second they were admitted in
+	admitted    map[string]int64
+	dropped     uint64
+	lastReport  time.Time
+	reportTimer *time.Timer
+}
+
+// parseAuditFilter builds an audit filter from the options of an audit device.
+// It returns nil if neither sampling nor a budget is configured.
+func parseAuditFilter(options map[string]string) (*auditFilter, error) {
+	f := &auditFilter{}
+
+	if raw, ok := options["sample_rate"]; ok {
+		rate, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || rate == 0 {
+			return nil, fmt.Errorf("invalid value for sample_rate: must be a positive integer")
+		}
+		f.sampleRate = uint32(rate)
+	}
+
+	if raw, ok := options["sample_paths"]; ok {
+		if f.sampleRate == 0 {
+			return nil, fmt.Errorf("sample_paths requires sample_rate to be set")
+		}
+		f.samplePaths = strutil.ParseDedupAndSortStrings(raw, ",")
+	}
+
+	if raw, ok := options["max_entries_per_second"]; ok {
+		max, err := strconv.Atoi(raw)
+		if err != nil || max <= 0 {
+			return nil, fmt.Errorf("invalid value for max_entries_per_second: must be a positive integer")
+		}
+		f.maxPerSecond = max
+	}
+
+	if f.sampleRate <= 1 && f.maxPerSecond == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// skip returns whether an entry for the given request is sampled out of the
+// device. Sampling is decided from the request ID so that a request and its
+// response are either both logged or both skipped.
+func (f *auditFilter) skip(name string, req *logical.Request) bool {
+	if f.sampleRate > 1 && f.sampledOut(req) {
+		metrics.IncrCounter([]string{"audit", name, "sampled_out"}, 1)
+		return true
+	}
+	return false
+}
+
+// withinBudget returns whether an entry for the given request fits in the
+// budget of the device. Like sampling, the budget only applies to read and
+// list operations, and the response of a request is logged if and only if the
+// request was.
+func (f *auditFilter) withinBudget(name string, req *logical.Request, response bool, logger log.Logger) bool {
+	if f.maxPerSecond == 0 {
+		return true
+	}
+
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation:
+	default:
+		return true
+	}
+
+	if !f.allow(name, req.ID, response, time.Now(), logger) {
+		metrics.IncrCounter([]string{"audit", name, "overflow"}, 1)
+		return false
+	}
+	return true
+}
+
+func (f *auditFilter) sampledOut(req *logical.Request) bool {
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation:
+	default:
+		return false
+	}
+
+	if req.ID == "" {
+		return false
+	}
+
+	if len(f.samplePaths) > 0 {
+		var found bool
+		for _, prefix := range f.samplePaths {
+			if strings.HasPrefix(req.Path, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(req.ID))
+	return h.Sum32()%f.sampleRate != 0
+}
+
+// allow consumes an entry from the budget of the current second for a
+// request, returning false if the budget is exhausted. Responses don't consume
+// the budget: they are allowed if their request was admitted. Dropped entries
+// are reported at most once per auditOverflowReportInterval.
+func (f *auditFilter) allow(name, id string, response bool, now time.Time, logger log.Logger) bool {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if sec := now.Unix(); sec != f.window {
+		f.window = sec
+		f.count = 0
+		for admittedID, admittedAt := range f.admitted {
+			if sec-admittedAt >= int64(auditBudgetPairTimeout/time.Second) {
+				delete(f.admitted, admittedID)
+			}
+		}
+	}
+
+	if id != "" && response {
+		if _, ok := f.admitted[id]; ok {
+			delete(f.admitted, id)
+			return true
+		}
+	} else if f.count < f.maxPerSecond {
+		f.count++
+		if id != "" {
+			if f.admitted == nil {
+				f.admitted = make(map[string]int64)
+			}
+			f.admitted[id] = f.window
+		}
+		return true
+	}
+
+	f.dropped++
+	if f.reportTimer == nil {
+		delay := auditOverflowReportInterval - now.Sub(f.lastReport)
+		if delay < 0 {
+			delay = 0
+		}
+		f.reportTimer = time.AfterFunc(delay, func() {
+			f.report(name, logger)
+		})
+	}
+	return false
+}
+
+// report logs the number of entries dropped since the last report
+func (f *auditFilter) report(name string, logger log.Logger) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.dropped > 0 {
+		logger.Warn("audit device exceeded its entry budget, entries were dropped", "backend", name, "max_entries_per_second", f.maxPerSecond, "dropped", f.dropped)
+	}
+	f.dropped = 0
+	f.lastReport = time.Now()
+	f.reportTimer = nil
+}
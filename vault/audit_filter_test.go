@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestAuditFilter_parse(t *testing.T) {
+	cases := map[string]struct {
+		options map[string]string
+		isNil   bool
+		isErr   bool
+	}{
+		"none":             {map[string]string{"file_path": "stdout"}, true, false},
+		"rate of one":      {map[string]string{"sample_rate": "1"}, true, false},
+		"sampling":         {map[string]string{"sample_rate": "10", "sample_paths": "sys/health,secret/"}, false, false},
+		"budget":           {map[string]string{"max_entries_per_second": "100"}, false, false},
+		"zero rate":        {map[string]string{"sample_rate": "0"}, false, true},
+		"bad rate":         {map[string]string{"sample_rate": "often"}, false, true},
+		"paths no rate":    {map[string]string{"sample_paths": "secret/"}, false, true},
+		"negative budget":  {map[string]string{"max_entries_per_second": "-1"}, false, true},
+		"malformed budget": {map[string]string{"max_entries_per_second": "1.5"}, false, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseAuditFilter(tc.options)
+			if (err != nil) != tc.isErr {
+				t.Fatalf("bad err: %v", err)
+			}
+			if !tc.isErr && (f == nil) != tc.isNil {
+				t.Fatalf("bad filter: %#v", f)
+			}
+		})
+	}
+}
+
+func TestAuditFilter_sampling(t *testing.T) {
+	f, err := parseAuditFilter(map[string]string{
+		"sample_rate":  "4",
+		"sample_paths": "sys/health",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logged int
+	for i := 0; i < 1000; i++ {
+		req := &logical.Request{
+			ID:        fmt.Sprintf("request-%d", i),
+			Operation: logical.ReadOperation,
+			Path:      "sys/health",
+		}
+		first := f.skip("test", req)
+		if first != f.skip("test", req) {
+			t.Fatalf("sampling was not consistent for %q", req.ID)
+		}
+		if !first {
+			logged++
+		}
+	}
+	if logged < 150 || logged > 350 {
+		t.Fatalf("expected roughly a quarter of requests to be logged, got %d", logged)
+	}
+
+	// Writes and paths outside the sampled prefixes are always logged
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("request-%d", i)
+		if f.skip("test", &logical.Request{ID: id, Operation: logical.UpdateOperation, Path: "sys/health"}) {
+			t.Fatal("expected write to be logged")
+		}
+		if f.skip("test", &logical.Request{ID: id, Operation: logical.ReadOperation, Path: "secret/foo"}) {
+			t.Fatal("expected read outside sampled paths to be logged")
+		}
+	}
+}
+
+func TestAuditFilter_budget(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Trace)
+	f, err := parseAuditFilter(map[string]string{
+		"max_entries_per_second": "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		if !f.allow("test", fmt.Sprintf("request-%d", i), false, now, logger) {
+			t.Fatalf("expected entry %d to be allowed", i)
+		}
+	}
+	if f.allow("test", "request-3", false, now.Add(500*time.Millisecond), logger) {
+		t.Fatal("expected entry over budget to be dropped")
+	}
+	if f.allow("test", "", false, now.Add(600*time.Millisecond), logger) {
+		t.Fatal("expected entry over budget to be dropped")
+	}
+	if f.dropped != 2 {
+		t.Fatalf("expected 2 dropped entries pending report, got %d", f.dropped)
+	}
+	if f.reportTimer == nil {
+		t.Fatal("expected the overflow report to be scheduled")
+	}
+
+	// Responses are logged if and only if their request was
+	if !f.allow("test", "request-0", true, now.Add(700*time.Millisecond), logger) {
+		t.Fatal("expected response of an admitted request to be allowed")
+	}
+	if f.allow("test", "request-3", true, now.Add(700*time.Millisecond), logger) {
+		t.Fatal("expected response of a dropped request to be dropped")
+	}
+
+	// The budget resets every second
+	if !f.allow("test", "request-4", false, now.Add(time.Second), logger) {
+		t.Fatal("expected entry in the next second to be allowed")
+	}
+	if !f.allow("test", "request-1", true, now.Add(time.Second), logger) {
+		t.Fatal("expected response of a request admitted in a previous second to be allowed")
+	}
+
+	// Admitted requests are forgotten after a while
+	if f.allow("test", "request-2", true, now.Add(auditBudgetPairTimeout), logger) {
+		t.Fatal("expected response of a request admitted long ago to be dropped")
+	}
+	if _, ok := f.admitted["request-2"]; ok {
+		t.Fatal("expected request admitted long ago to be forgotten")
+	}
+
+	// Dropped entries are reported even when no more entries come in
+	f.reportTimer.Stop()
+	f.report("test", logger)
+	if f.dropped != 0 || f.reportTimer != nil {
+		t.Fatalf("expected dropped entries to be reported, got %d pending", f.dropped)
+	}
+}
+
+func TestAuditFilter_budgetWrites(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Trace)
+	f, err := parseAuditFilter(map[string]string{
+		"max_entries_per_second": "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		req := &logical.Request{
+			ID:        fmt.Sprintf("request-%d", i),
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+		}
+		if !f.withinBudget("test", req, false, logger) || !f.withinBudget("test", req, true, logger) {
+			t.Fatal("expected writes to ignore the budget")
+		}
+	}
+}
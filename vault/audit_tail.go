@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// auditTailBufferSize is the number of formatted entries buffered for each
+// subscriber before entries are dropped.
+const auditTailBufferSize = 512
+
+// saltedAuditBackend is implemented by audit backends that expose their salt,
+// which is needed to hash entries streamed to subscribers the same way the
+// backend does.
+type saltedAuditBackend interface {
+	Salt(context.Context) (*salt.Salt, error)
+}
+
+// AuditTailFilter selects the entries streamed to an audit subscriber. Empty
+// fields match every entry.
+type AuditTailFilter struct {
+	// Type is either "request" or "response"
+	Type       string
+	PathPrefix string
+	Operations []logical.Operation
+	ErrorsOnly bool
+}
+
+func (f *AuditTailFilter) matches(entryType string, in *logical.LogInput) bool {
+	if f.Type != "" && f.Type != entryType {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(in.Request.Path, f.PathPrefix) {
+		return false
+	}
+	if len(f.Operations) > 0 {
+		var found bool
+		for _, op := range f.Operations {
+			if op == in.Request.Operation {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.ErrorsOnly && in.OuterErr == nil && !in.Response.IsError() {
+		return false
+	}
+	return true
+}
+
+// AuditSubscriber receives the entries logged by the audit broker, formatted
+// as JSON and hashed with the salt of an audit device.
+type AuditSubscriber struct {
+	namespace *namespace.Namespace
+	filter    *AuditTailFilter
+	formatter audit.AuditFormatter
+	config    audit.FormatterConfig
+	hashFunc  func(context.Context, string) (string, error)
+
+	ch      chan []byte
+	dropped uint64
+}
+
+// Entries returns the channel formatted entries are delivered on.
+func (s *AuditSubscriber) Entries() <-chan []byte {
+	return s.ch
+}
+
+// Dropped returns the number of entries that were dropped, and resets the
+// count. Entries are dropped when the subscriber doesn't keep up.
+func (s *AuditSubscriber) Dropped() uint64 {
+	return atomic.SwapUint64(&s.dropped, 0)
+}
+
+// Subscribe registers a subscriber for the entries of requests made in the
+// given namespace and its children. Entries are hashed using the salt of the
+// named device; if no device is named, the first device enabled in the
+// namespace is used, in order of path.
+func (a *AuditBroker) Subscribe(ns *namespace.Namespace, device string, filter *AuditTailFilter) (*AuditSubscriber, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	var be backendEntry
+	if device != "" {
+		var ok bool
+		be, ok = a.backends[device]
+		if !ok {
+			return nil, fmt.Errorf("unknown audit backend %q", device)
+		}
+	} else {
+		names := make([]string, 0, len(a.backends))
+		for name, be := range a.backends {
+			if be.namespace == nil || be.namespace.ID == ns.ID {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no audit devices are enabled")
+		}
+		sort.Strings(names)
+		be = a.backends[names[0]]
+	}
+
+	salted, ok := be.backend.(saltedAuditBackend)
+	if !ok {
+		return nil, fmt.Errorf("audit backend does not support streaming entries")
+	}
+
+	if filter == nil {
+		filter = &AuditTailFilter{}
+	}
+
+	s := &AuditSubscriber{
+		namespace: ns,
+		filter:    filter,
+		formatter: audit.AuditFormatter{
+			AuditFormatWriter: &audit.JSONFormatWriter{
+				SaltFunc: salted.Salt,
+			},
+		},
+		config: audit.FormatterConfig{
+			HMACAccessor: true,
+		},
+		hashFunc: be.backend.GetHash,
+		ch:       make(chan []byte, auditTailBufferSize),
+	}
+	a.subscribers[s] = struct{}{}
+
+	return s, nil
+}
+
+// Unsubscribe removes a subscriber from the broker. No more entries are sent
+// on its channel once this returns.
+func (a *AuditBroker) Unsubscribe(s *AuditSubscriber) {
+	a.Lock()
+	defer a.Unlock()
+	delete(a.subscribers, s)
+}
+
+// publish sends an entry to the matching subscribers without blocking. The
+// broker lock must be held. Failing to stream an entry never fails the
+// request, so errors are only logged.
+func (a *AuditBroker) publish(ctx context.Context, ns *namespace.Namespace, entryType string, in *logical.LogInput, headers map[string][]string, headersConfig *AuditedHeadersConfig) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Error("panic streaming audit entry", "request_path", in.Request.Path, "error", r)
+		}
+	}()
+
+	for s := range a.subscribers {
+		if ns.ID != s.namespace.ID && !ns.HasParent(s.namespace) {
+			continue
+		}
+		if !s.filter.matches(entryType, in) {
+			continue
+		}
+
+		transHeaders, err := headersConfig.ApplyConfig(ctx, headers, s.hashFunc)
+		if err != nil {
+			a.logger.Error("failed to include headers in streamed audit entry", "error", err)
+			continue
+		}
+		in.Request.Headers = transHeaders
+
+		var buf bytes.Buffer
+		switch entryType {
+		case "request":
+			err = s.formatter.FormatRequest(ctx, &buf, s.config, in)
+		default:
+			err = s.formatter.FormatResponse(ctx, &buf, s.config, in)
+		}
+		if err != nil {
+			a.logger.Error("failed to format streamed audit entry", "error", err)
+			continue
+		}
+
+		select {
+		case s.ch <- buf.Bytes():
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestAuditBroker_Subscribe(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	ns1 := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+
+	newDevice := func() *NoopAudit {
+		return &NoopAudit{
+			Config: &audit.BackendConfig{
+				SaltConfig: &salt.Config{},
+				SaltView:   &logical.InmemStorage{},
+			},
+		}
+	}
+	b.Register("root/", newDevice(), nil, namespace.RootNamespace, nil, false, false)
+	b.Register("ns1/tenant/", newDevice(), nil, ns1, nil, false, false)
+
+	if _, err := b.Subscribe(namespace.RootNamespace, "missing/", nil); err == nil {
+		t.Fatal("expected error subscribing with unknown device")
+	}
+
+	all, err := b.Subscribe(namespace.RootNamespace, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errors, err := b.Subscribe(namespace.RootNamespace, "root/", &AuditTailFilter{
+		Type:       "response",
+		ErrorsOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := b.Subscribe(ns1, "", &AuditTailFilter{
+		PathPrefix: "secret/",
+		Operations: []logical.Operation{logical.ReadOperation},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	logRequest := func(ns *namespace.Namespace, op logical.Operation, path string, outerErr error) {
+		t.Helper()
+		ctx := namespace.ContextWithNamespace(context.Background(), ns)
+		in := &logical.LogInput{
+			Auth: &logical.Auth{
+				ClientToken: "foo",
+			},
+			Request: &logical.Request{
+				Operation: op,
+				Path:      path,
+			},
+			OuterErr: outerErr,
+		}
+		if err := b.LogRequest(ctx, in, headersConf); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.LogResponse(ctx, in, headersConf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logRequest(namespace.RootNamespace, logical.ReadOperation, "secret/foo", nil)
+	logRequest(ns1, logical.ReadOperation, "secret/foo", nil)
+	logRequest(ns1, logical.UpdateOperation, "secret/foo", nil)
+	logRequest(ns1, logical.ReadOperation, "sys/mounts", logical.ErrPermissionDenied)
+
+	if len(all.Entries()) != 8 {
+		t.Fatalf("expected 8 entries for root subscriber, got %d", len(all.Entries()))
+	}
+	if len(errors.Entries()) != 1 {
+		t.Fatalf("expected 1 error entry, got %d", len(errors.Entries()))
+	}
+	if len(tenant.Entries()) != 2 {
+		t.Fatalf("expected 2 entries for tenant subscriber, got %d", len(tenant.Entries()))
+	}
+
+	// Entries are formatted as JSON with sensitive values hashed
+	var entry map[string]interface{}
+	if err := json.Unmarshal(<-tenant.Entries(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["type"] != "request" {
+		t.Fatalf("bad entry: %v", entry)
+	}
+	token := entry["auth"].(map[string]interface{})["client_token"].(string)
+	if token == "foo" || token == "" {
+		t.Fatalf("expected client token to be hashed, got %q", token)
+	}
+
+	// Subscribers that don't keep up drop entries instead of blocking
+	for i := 0; i < auditTailBufferSize; i++ {
+		logRequest(namespace.RootNamespace, logical.ReadOperation, fmt.Sprintf("secret/%d", i), nil)
+	}
+	if dropped := all.Dropped(); dropped != auditTailBufferSize+8 {
+		t.Fatalf("expected %d dropped entries, got %d", auditTailBufferSize+8, dropped)
+	}
+	if dropped := all.Dropped(); dropped != 0 {
+		t.Fatalf("expected dropped count to be reset, got %d", dropped)
+	}
+
+	b.Unsubscribe(all)
+	b.Unsubscribe(errors)
+	b.Unsubscribe(tenant)
+	if len(b.subscribers) != 0 {
+		t.Fatalf("expected no subscribers, got %d", len(b.subscribers))
+	}
+}
@@ -10,9 +10,11 @@ import (
 
 	"errors"
 
+	metrics "github.com/armon/go-metrics"
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
@@ -247,6 +249,57 @@ func TestCore_EnableAudit_Local(t *testing.T) {
 	}
 }
 
+func TestCore_EnableAudit_Namespace(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+	c.auditBackends["file"] = c.auditBackends["noop"]
+	c.auditBackends["socket"] = c.auditBackends["noop"]
+
+	ns := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+
+	for _, typ := range []string{"file", "socket"} {
+		me := &MountEntry{
+			Table: auditTableType,
+			Path:  typ,
+			Type:  typ,
+			Options: map[string]string{
+				"file_path": "/etc/passwd",
+				"address":   "127.0.0.1:9090",
+			},
+		}
+		err := c.enableAudit(ctx, me, true)
+		if err == nil || !strings.Contains(err.Error(), "can only be enabled in the root namespace") {
+			t.Fatalf("expected %s device to be rejected outside the root namespace, got: %v", typ, err)
+		}
+		if c.auditBroker.IsRegistered(ns.Path + typ + "/") {
+			t.Fatalf("%s device should not be registered", typ)
+		}
+	}
+
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "noop",
+		Type:  "noop",
+	}
+	if err := c.enableAudit(ctx, me, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	me = &MountEntry{
+		Table: auditTableType,
+		Path:  "file",
+		Type:  "file",
+	}
+	if err := c.enableAudit(namespace.RootContext(nil), me, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestCore_DisableAudit(t *testing.T) {
 	c, keys, _ := TestCoreUnsealed(t)
 	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
@@ -357,8 +410,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, nil, nil, false, false)
+	b.Register("bar", a2, nil, nil, nil, false, false)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -438,13 +491,180 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	}
 }
 
+func TestAuditBroker_Fallback(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	primary := &NoopAudit{}
+	fallback := &NoopAudit{}
+	b.Register("primary", primary, nil, nil, nil, false, false)
+	b.Register("fallback", fallback, nil, nil, nil, false, true)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	logInput := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/mounts",
+		},
+	}
+
+	// The fallback should not be written to while the primary works
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogResponse(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(primary.Req) != 1 || len(primary.Resp) != 1 {
+		t.Fatalf("expected primary to log, got %d requests and %d responses", len(primary.Req), len(primary.Resp))
+	}
+	if len(fallback.Req) != 0 || len(fallback.Resp) != 0 {
+		t.Fatalf("expected fallback to be unused, got %d requests and %d responses", len(fallback.Req), len(fallback.Resp))
+	}
+
+	// Once the primary fails the fallback should take over
+	primary.ReqErr = fmt.Errorf("failed")
+	primary.RespErr = fmt.Errorf("failed")
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogResponse(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(fallback.Req) != 1 || len(fallback.Resp) != 1 {
+		t.Fatalf("expected fallback to log, got %d requests and %d responses", len(fallback.Req), len(fallback.Resp))
+	}
+
+	// If both fail the request must be rejected
+	fallback.ReqErr = fmt.Errorf("failed")
+	if err := b.LogRequest(context.Background(), logInput, headersConf); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAuditBroker_DeviceMetrics(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	inmemSink := metrics.NewInmemSink(1000000*time.Hour, 2000000*time.Hour)
+	b := NewAuditBroker(l)
+	b.metricSink = metricsutil.NewClusterMetricSink("test-cluster", inmemSink)
+
+	working := &NoopAudit{}
+	failing := &NoopAudit{ReqErr: fmt.Errorf("failed")}
+	b.Register("working/", working, nil, nil, nil, false, false)
+	b.Register("failing/", failing, nil, nil, nil, false, false)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	logInput := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/mounts",
+		},
+	}
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	intervals := inmemSink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+	for _, device := range []string{"working/", "failing/"} {
+		for _, name := range []string{"audit.device.log_request", "audit.device.blocked_time"} {
+			if _, ok := intervals[0].Samples[name+";device="+device+";cluster=test-cluster"]; !ok {
+				t.Fatalf("missing %s for %s: %v", name, device, intervals[0].Samples)
+			}
+		}
+	}
+	for device, expected := range map[string]float64{"working/": 0, "failing/": 1} {
+		failures, ok := intervals[0].Counters["audit.device.log_request_failure;device="+device+";cluster=test-cluster"]
+		if !ok {
+			t.Fatalf("missing failure counter for %s: %v", device, intervals[0].Counters)
+		}
+		if failures.Sum != expected {
+			t.Fatalf("expected %v failures for %s, got %v", expected, device, failures.Sum)
+		}
+	}
+}
+
+func TestAuditBroker_Namespaces(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	ns1 := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+	ns2 := &namespace.Namespace{ID: "ns2", Path: "ns2/"}
+	child := &namespace.Namespace{ID: "child", Path: "ns1/child/"}
+
+	root := &NoopAudit{}
+	tenant1 := &NoopAudit{}
+	tenant2 := &NoopAudit{}
+	b.Register("root/", root, nil, namespace.RootNamespace, nil, false, false)
+	b.Register("ns1/tenant/", tenant1, nil, ns1, nil, false, false)
+	b.Register("ns2/tenant/", tenant2, nil, ns2, nil, false, false)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	logRequest := func(ns *namespace.Namespace) error {
+		return b.LogRequest(namespace.ContextWithNamespace(context.Background(), ns), &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "secret/foo",
+			},
+		}, headersConf)
+	}
+
+	for _, ns := range []*namespace.Namespace{namespace.RootNamespace, ns1, child} {
+		if err := logRequest(ns); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// The root device sees everything, tenants only their own namespace
+	// and its children
+	if len(root.Req) != 3 {
+		t.Fatalf("expected root device to log 3 requests, got %d", len(root.Req))
+	}
+	if len(tenant1.Req) != 2 {
+		t.Fatalf("expected ns1 device to log 2 requests, got %d", len(tenant1.Req))
+	}
+	if len(tenant2.Req) != 0 {
+		t.Fatalf("expected ns2 device to log no requests, got %d", len(tenant2.Req))
+	}
+
+	// A working tenant device doesn't stand in for the root device
+	root.ReqErr = fmt.Errorf("failed")
+	for _, ns := range []*namespace.Namespace{ns1, ns2} {
+		if err := logRequest(ns); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Without a root device, a working tenant device is enough for requests
+	// in its namespace
+	b.Deregister("root/")
+	if err := logRequest(ns1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tenant2.ReqErr = fmt.Errorf("failed")
+	if err := logRequest(ns2); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests with no applicable device don't fail
+	if err := logRequest(namespace.RootNamespace); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestAuditBroker_LogResponse(t *testing.T) {
 	l := logging.NewVaultLogger(log.Trace)
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, nil, nil, false, false)
+	b.Register("bar", a2, nil, nil, nil, false, false)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -549,8 +769,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, nil, nil, false, false)
+	b.Register("bar", a2, nil, nil, nil, false, false)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -624,3 +844,41 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+// flushingAudit is a NoopAudit buffering its entries
+type flushingAudit struct {
+	*NoopAudit
+	flushes  int
+	flushErr error
+}
+
+func (a *flushingAudit) Flush(_ context.Context) error {
+	a.flushes++
+	return a.flushErr
+}
+
+func TestAuditBroker_Flush(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+
+	buffered := &flushingAudit{NoopAudit: &NoopAudit{}}
+	failing := &flushingAudit{NoopAudit: &NoopAudit{}, flushErr: fmt.Errorf("disk full")}
+	b.Register("buffered/", buffered, nil, nil, nil, false, false)
+	b.Register("unbuffered/", &NoopAudit{}, nil, nil, nil, false, false)
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if buffered.flushes != 1 {
+		t.Fatalf("expected one flush, got %d", buffered.flushes)
+	}
+
+	b.Register("failing/", failing, nil, nil, nil, false, false)
+	err := b.Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `"failing/"`) {
+		t.Fatalf("expected the failing backend error, got %v", err)
+	}
+	if buffered.flushes != 2 || failing.flushes != 1 {
+		t.Fatalf("expected every backend to be flushed, got %d and %d", buffered.flushes, failing.flushes)
+	}
+}
@@ -105,8 +105,9 @@ func (c *Core) enableCredentialInternal(ctx context.Context, entry *MountEntry,
 		}
 	}
 
-	// Ensure the token backend is a singleton
-	if entry.Type == "token" {
+	// Ensure the token backend is a singleton, mounted once in each
+	// namespace
+	if entry.Type == "token" && (ns.ID == namespace.RootNamespaceID || entry.Path != "token/") {
 		return fmt.Errorf("token credential backend cannot be instantiated")
 	}
 
@@ -327,6 +328,13 @@ func (c *Core) disableCredentialInternal(ctx context.Context, path string, updat
 		}
 	}
 
+	if updateStorage && entry.Config.UserLockoutConfig != nil {
+		if err := c.clearUserLockout(ctx, entry.Accessor); err != nil {
+			c.logger.Error("failed to clear the user lockout of the auth mount being disabled", "error", err, "path", path)
+			return err
+		}
+	}
+
 	// Remove the mount table entry
 	if err := c.removeCredEntry(ctx, strings.TrimPrefix(path, credentialRoutePrefix), updateStorage); err != nil {
 		return err
@@ -721,7 +729,7 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 		}
 
 		// Check if this is the token store
-		if entry.Type == "token" {
+		if entry.Type == "token" && entry.NamespaceID == namespace.RootNamespaceID {
 			c.tokenStore = backend.(*TokenStore)
 
 			// At some point when this isn't beta we may persist this but for
@@ -790,13 +798,17 @@ func (c *Core) teardownCredentials(ctx context.Context) error {
 
 // newCredentialBackend is used to create and configure a new credential backend by name
 func (c *Core) newCredentialBackend(ctx context.Context, entry *MountEntry, sysView logical.SystemView, view logical.Storage) (logical.Backend, error) {
+	if backend := c.namespaceSharedBackend(entry); backend != nil {
+		return backend, nil
+	}
+
 	t := entry.Type
 	if alias, ok := credentialAliases[t]; ok {
 		t = alias
 	}
 
 	f, ok := c.credentialBackends[t]
-	if !ok {
+	if !ok || entry.Version != "" {
 		f = plugin.Factory
 	}
 
@@ -813,6 +825,10 @@ func (c *Core) newCredentialBackend(ctx context.Context, entry *MountEntry, sysV
 		conf["plugin_name"] = t
 	}
 
+	if entry.Version != "" {
+		conf["plugin_version"] = entry.Version
+	}
+
 	conf["plugin_type"] = consts.PluginTypeCredential.String()
 
 	authLogger := c.baseLogger.Named(fmt.Sprintf("auth.%s.%s", t, entry.Accessor))
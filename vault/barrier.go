@@ -136,6 +136,19 @@ type SecurityBarrier interface {
 	// ActiveKeyInfo is used to inform details about the active key
 	ActiveKeyInfo() (*KeyInfo, error)
 
+	// RotationConfig returns the policy for automatically rotating the
+	// active key
+	RotationConfig() (KeyRotationConfig, error)
+
+	// SetRotationConfig updates the policy for automatically rotating the
+	// active key
+	SetRotationConfig(context.Context, KeyRotationConfig) error
+
+	// CheckBarrierAutoRotate persists the estimated number of encryptions
+	// done with the active key, and returns the reason to rotate it according
+	// to the rotation policy, if any
+	CheckBarrierAutoRotate(context.Context) (string, error)
+
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey(context.Context, []byte) error
 
@@ -177,4 +190,5 @@ type BarrierEncryptor interface {
 type KeyInfo struct {
 	Term        int
 	InstallTime time.Time
+	Encryptions int64
 }
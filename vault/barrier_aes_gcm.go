@@ -73,6 +73,10 @@ type AESGCMBarrier struct {
 	currentAESGCMVersionByte byte
 
 	initialized atomic.Bool
+
+	// unaccountedEncryptions is the number of encryptions done with the
+	// active key since they were last added to the keyring
+	unaccountedEncryptions atomic.Int64
 }
 
 // NewAESGCMBarrier is used to construct a new barrier that uses
@@ -496,6 +500,7 @@ func (b *AESGCMBarrier) Seal() error {
 	b.keyring.Zeroize(true)
 	b.keyring = nil
 	b.sealed = true
+	b.unaccountedEncryptions.Store(0)
 	return nil
 }
 
@@ -518,18 +523,22 @@ func (b *AESGCMBarrier) Rotate(ctx context.Context, reader io.Reader) (uint32, e
 	term := b.keyring.ActiveTerm()
 	newTerm := term + 1
 
-	// Add a new encryption key
-	newKeyring, err := b.keyring.AddKey(&Key{
+	// Account for the encryptions done with the current key, and add a new
+	// encryption key
+	encryptions := b.unaccountedEncryptions.Swap(0)
+	newKeyring, err := b.keyring.AddEncryptions(term, uint64(encryptions)).AddKey(&Key{
 		Term:    newTerm,
 		Version: 1,
 		Value:   encrypt,
 	})
 	if err != nil {
+		b.unaccountedEncryptions.Add(encryptions)
 		return 0, errwrap.Wrapf("failed to add new encryption key: {{err}}", err)
 	}
 
 	// Persist the new keyring
 	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		b.unaccountedEncryptions.Add(encryptions)
 		return 0, err
 	}
 
@@ -665,10 +674,88 @@ func (b *AESGCMBarrier) ActiveKeyInfo() (*KeyInfo, error) {
 	info := &KeyInfo{
 		Term:        int(term),
 		InstallTime: key.InstallTime,
+		Encryptions: int64(key.Encryptions) + b.unaccountedEncryptions.Load(),
 	}
 	return info, nil
 }
 
+// RotationConfig returns the policy for automatically rotating the active key
+func (b *AESGCMBarrier) RotationConfig() (KeyRotationConfig, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return KeyRotationConfig{}, ErrBarrierSealed
+	}
+
+	return b.keyring.RotationConfig(), nil
+}
+
+// SetRotationConfig updates and persists the policy for automatically
+// rotating the active key
+func (b *AESGCMBarrier) SetRotationConfig(ctx context.Context, config KeyRotationConfig) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	newKeyring := b.keyring.SetRotationConfig(config)
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		return err
+	}
+
+	b.keyring = newKeyring
+	return nil
+}
+
+// persistEncryptionCountLocked adds the encryptions done with the active key
+// since they were last accounted for to the keyring, and persists it. The lock
+// must be held.
+func (b *AESGCMBarrier) persistEncryptionCountLocked(ctx context.Context) error {
+	encryptions := b.unaccountedEncryptions.Swap(0)
+	if encryptions == 0 {
+		return nil
+	}
+
+	newKeyring := b.keyring.AddEncryptions(b.keyring.ActiveTerm(), uint64(encryptions))
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		b.unaccountedEncryptions.Add(encryptions)
+		return err
+	}
+
+	b.keyring = newKeyring
+	return nil
+}
+
+// CheckBarrierAutoRotate persists the number of encryptions done with the
+// active key, and returns the reason to rotate it according to the rotation
+// policy, or an empty string if it doesn't need to be rotated
+func (b *AESGCMBarrier) CheckBarrierAutoRotate(ctx context.Context) (string, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return "", ErrBarrierSealed
+	}
+
+	if err := b.persistEncryptionCountLocked(ctx); err != nil {
+		return "", err
+	}
+
+	config := b.keyring.RotationConfig()
+	if config.Disabled {
+		return "", nil
+	}
+
+	key := b.keyring.ActiveKey()
+	switch {
+	case config.MaxOperations > 0 && key.Encryptions >= uint64(config.MaxOperations):
+		return "reached max operations", nil
+	case config.Interval > 0 && time.Since(key.InstallTime) >= config.Interval:
+		return "reached rotation interval", nil
+	}
+	return "", nil
+}
+
 // Rekey is used to change the master key used to protect the keyring
 func (b *AESGCMBarrier) Rekey(ctx context.Context, key []byte) error {
 	b.l.Lock()
@@ -741,6 +828,7 @@ func (b *AESGCMBarrier) Put(ctx context.Context, entry *logical.StorageEntry) er
 		return err
 	}
 
+	b.unaccountedEncryptions.Inc()
 	return b.putInternal(ctx, term, primary, entry)
 }
 
@@ -986,6 +1074,7 @@ func (b *AESGCMBarrier) Encrypt(ctx context.Context, key string, plaintext []byt
 		return nil, err
 	}
 
+	b.unaccountedEncryptions.Inc()
 	ciphertext, err := b.encrypt(key, term, primary, plaintext)
 	if err != nil {
 		return nil, err
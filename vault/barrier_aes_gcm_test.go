@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"testing"
+	"time"
 
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/logging"
@@ -60,6 +61,114 @@ func TestAESGCMBarrier_Rotate(t *testing.T) {
 	testBarrier_Rotate(t, b)
 }
 
+func TestAESGCMBarrier_AutoRotate(t *testing.T) {
+	_, b, key := mockBarrier(t)
+	ctx := context.Background()
+
+	config, err := b.RotationConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Disabled || config.MaxOperations != keyRotationMaxOperations || config.Interval != 0 {
+		t.Fatalf("bad: default rotation config: %#v", config)
+	}
+
+	config.MaxOperations = 2
+	if err := b.SetRotationConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+
+	// Encryptions are counted
+	if err := b.Put(ctx, &logical.StorageEntry{Key: "test", Value: []byte("test")}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Encryptions != 1 {
+		t.Fatalf("bad: encryptions: %d", info.Encryptions)
+	}
+
+	reason, err := b.CheckBarrierAutoRotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "" {
+		t.Fatalf("bad: reason: %q", reason)
+	}
+
+	if _, err := b.Encrypt(ctx, "test", []byte("test")); err != nil {
+		t.Fatal(err)
+	}
+	reason, err = b.CheckBarrierAutoRotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "reached max operations" {
+		t.Fatalf("bad: reason: %q", reason)
+	}
+
+	// The count and the policy survive a reseal
+	b.Seal()
+	if err := b.Unseal(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Encryptions != 2 {
+		t.Fatalf("bad: encryptions: %d", info.Encryptions)
+	}
+	config, err = b.RotationConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxOperations != 2 {
+		t.Fatalf("bad: rotation config: %#v", config)
+	}
+
+	// A new key starts with no encryptions
+	if _, err := b.Rotate(ctx, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Term != 2 || info.Encryptions != 0 {
+		t.Fatalf("bad: key info: %#v", info)
+	}
+
+	// Keys are rotated based on their age
+	config.Interval = time.Hour
+	if err := b.SetRotationConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	b.(*AESGCMBarrier).keyring.ActiveKey().InstallTime = time.Now().Add(-2 * time.Hour)
+	reason, err = b.CheckBarrierAutoRotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "reached rotation interval" {
+		t.Fatalf("bad: reason: %q", reason)
+	}
+
+	// Nothing is rotated when the policy is disabled
+	config.Disabled = true
+	if err := b.SetRotationConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	reason, err = b.CheckBarrierAutoRotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "" {
+		t.Fatalf("bad: reason: %q", reason)
+	}
+}
+
 func TestAESGCMBarrier_Upgrade(t *testing.T) {
 	inm, err := inmem.NewInmem(nil, logger)
 	if err != nil {
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// InconsistentFail fails the requests requiring a storage index the node
+	// hasn't reached yet. This is the default.
+	InconsistentFail = "fail"
+
+	// InconsistentForwardActiveNode forwards the requests requiring a storage
+	// index the node hasn't reached yet to the active node.
+	InconsistentForwardActiveNode = "forward-active-node"
+
+	requiredStateVersion = "v1"
+)
+
+var (
+	// requiredStateTimeout is how long a node waits to reach the storage
+	// index required by a request before giving up on it.
+	requiredStateTimeout = 2 * time.Second
+
+	requiredStatePollInterval = 10 * time.Millisecond
+
+	// ErrStaleState is returned when the node hasn't reached the storage
+	// index required by a request in time.
+	ErrStaleState = errors.New("node has not yet applied the required storage writes")
+)
+
+// storageIndexer is implemented by the physical backends that number the
+// writes they apply, such as raft.
+type storageIndexer interface {
+	AppliedIndex() uint64
+}
+
+// StorageIndex returns the index of the latest write applied to the local
+// storage, and false if the storage doesn't number its writes.
+func (c *Core) StorageIndex() (uint64, bool) {
+	indexer, ok := c.underlyingPhysical.(storageIndexer)
+	if !ok {
+		return 0, false
+	}
+	return indexer.AppliedIndex(), true
+}
+
+// ResponseState returns the value of the X-Vault-Index header conveying the
+// storage index of the node, or an empty string if the storage doesn't
+// number its writes.
+func (c *Core) ResponseState() string {
+	index, ok := c.StorageIndex()
+	if !ok || index == 0 {
+		return ""
+	}
+	state := fmt.Sprintf("%s:%d", requiredStateVersion, index)
+	return base64.StdEncoding.EncodeToString([]byte(state))
+}
+
+// ParseRequiredState parses the value of the X-Vault-Index header of a
+// request and returns the storage index it requires.
+func ParseRequiredState(raw string) (uint64, error) {
+	state, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, errors.New("invalid index header")
+	}
+
+	pieces := strings.SplitN(string(state), ":", 2)
+	if len(pieces) != 2 || pieces[0] != requiredStateVersion {
+		return 0, errors.New("unsupported index header version")
+	}
+
+	index, err := strconv.ParseUint(pieces[1], 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid index header")
+	}
+	return index, nil
+}
+
+// WaitForRequiredState waits until the local storage has applied the write
+// with the given index. It returns ErrStaleState if it doesn't happen before
+// the timeout, and succeeds right away if the storage doesn't number its
+// writes, as consistency then can't be enforced.
+func (c *Core) WaitForRequiredState(ctx context.Context, required uint64) error {
+	index, ok := c.StorageIndex()
+	if !ok || index >= required {
+		return nil
+	}
+
+	timer := time.NewTimer(requiredStateTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(requiredStatePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return ErrStaleState
+		case <-ticker.C:
+			if index, _ := c.StorageIndex(); index >= required {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+// indexedBackend is a physical backend numbering its writes like raft.
+type indexedBackend struct {
+	physical.Backend
+	index uint64
+}
+
+func (b *indexedBackend) AppliedIndex() uint64 {
+	return atomic.LoadUint64(&b.index)
+}
+
+func TestCore_RequiredState(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	// Consistency can't be enforced without indexes
+	if state := c.ResponseState(); state != "" {
+		t.Fatalf("unexpected state: %q", state)
+	}
+	if err := c.WaitForRequiredState(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &indexedBackend{Backend: c.underlyingPhysical, index: 10}
+	c.underlyingPhysical = backend
+
+	required, err := ParseRequiredState(c.ResponseState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if required != 10 {
+		t.Fatalf("bad index: %d", required)
+	}
+
+	for _, raw := range []string{"bogus", "djI6MTA=", "djE6Zm9v"} {
+		if _, err := ParseRequiredState(raw); err == nil {
+			t.Fatalf("expected error parsing %q", raw)
+		}
+	}
+
+	if err := c.WaitForRequiredState(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	oldTimeout := requiredStateTimeout
+	requiredStateTimeout = 100 * time.Millisecond
+	defer func() {
+		requiredStateTimeout = oldTimeout
+	}()
+
+	if err := c.WaitForRequiredState(context.Background(), 11); err != ErrStaleState {
+		t.Fatalf("expected stale state, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreUint64(&backend.index, 11)
+	}()
+	if err := c.WaitForRequiredState(context.Background(), 11); err != nil {
+		t.Fatal(err)
+	}
+}
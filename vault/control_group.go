@@ -0,0 +1,474 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreControlGroupConfigPath is the path used to store the control group
+	// configuration
+	coreControlGroupConfigPath = "core/control-group-config"
+
+	// controlGroupRequestPath is the path, in the cubbyhole of a control
+	// group token, of the request waiting for its authorizations
+	controlGroupRequestPath = "cubbyhole/control-group"
+
+	// defaultControlGroupTTL is the TTL of the control group tokens when the
+	// policy doesn't set one
+	defaultControlGroupTTL = 24 * time.Hour
+)
+
+// controlGroupRequiredError is returned by the policy checks of a request
+// that is allowed once the authorizers of its control group approve it.
+type controlGroupRequiredError struct {
+	controlGroup *ControlGroup
+}
+
+func (e *controlGroupRequiredError) Error() string {
+	return "control group authorization required"
+}
+
+// controlGroupConfig is the configuration of the control groups
+type controlGroupConfig struct {
+	MaxTTL time.Duration `json:"max_ttl"`
+}
+
+// controlGroupRequest is a request waiting for the authorizations of its
+// control group. It's stored in the cubbyhole of the control group token and
+// run on behalf of the requester once the token is unwrapped.
+type controlGroupRequest struct {
+	Operation      logical.Operation      `json:"operation"`
+	Path           string                 `json:"path"`
+	Data           map[string]interface{} `json:"data"`
+	ClientToken    string                 `json:"client_token"`
+	RemoteAddr     string                 `json:"remote_addr"`
+	EntityID       string                 `json:"entity_id"`
+	NamespaceID    string                 `json:"namespace_id"`
+	RequestTime    time.Time              `json:"request_time"`
+	Authorizations []*logical.Authz       `json:"authorizations"`
+	Approved       bool                   `json:"approved"`
+}
+
+// controlGroupConfig returns the control group configuration
+func (c *Core) controlGroupConfig(ctx context.Context) (*controlGroupConfig, error) {
+	config := &controlGroupConfig{}
+	entry, err := c.barrier.Get(ctx, coreControlGroupConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// createControlGroupRequest stores the request in the cubbyhole of a new
+// control group token and returns the token wrapped in the response. The
+// requester hands the token accessor to the authorizers and unwraps the token
+// once they approved the request.
+func (c *Core) createControlGroupRequest(ctx context.Context, req *logical.Request, auth *logical.Auth, cg *ControlGroup) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultControlGroupTTL
+	if cg.TTL != 0 {
+		ttl = cg.TTL
+	}
+	config, err := c.controlGroupConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxTTL != 0 && ttl > config.MaxTTL {
+		ttl = config.MaxTTL
+	}
+
+	creationTime := time.Now()
+	te := logical.TokenEntry{
+		Path:           req.Path,
+		Policies:       []string{controlGroupPolicyName},
+		CreationTime:   creationTime.Unix(),
+		TTL:            ttl,
+		ExplicitMaxTTL: ttl,
+		NamespaceID:    ns.ID,
+	}
+	if err := c.tokenStore.create(ctx, &te); err != nil {
+		c.logger.Error("failed to create control group token", "error", err)
+		return nil, ErrInternalError
+	}
+
+	cgReq := &controlGroupRequest{
+		Operation:   req.Operation,
+		Path:        req.Path,
+		Data:        req.Data,
+		ClientToken: req.ClientToken,
+		NamespaceID: ns.ID,
+		RequestTime: creationTime,
+	}
+	if req.Connection != nil {
+		cgReq.RemoteAddr = req.Connection.RemoteAddr
+	}
+	if auth != nil {
+		cgReq.EntityID = auth.EntityID
+	}
+	if err := c.storeControlGroupRequest(ctx, &te, cgReq); err != nil {
+		c.tokenStore.revokeOrphan(ctx, te.ID)
+		c.logger.Error("failed to store control group request", "error", err)
+		return nil, ErrInternalError
+	}
+
+	cgAuth := &logical.Auth{
+		ClientToken: te.ID,
+		Policies:    []string{controlGroupPolicyName},
+		LeaseOptions: logical.LeaseOptions{
+			TTL:       te.TTL,
+			Renewable: false,
+		},
+	}
+	if err := c.expiration.RegisterAuth(ctx, &te, cgAuth); err != nil {
+		c.tokenStore.revokeOrphan(ctx, te.ID)
+		c.logger.Error("failed to register control group token lease", "request_path", req.Path, "error", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.Response{
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			Token:           te.ID,
+			Accessor:        te.Accessor,
+			TTL:             ttl,
+			CreationTime:    creationTime,
+			CreationPath:    req.Path,
+			WrappedEntityID: cgReq.EntityID,
+		},
+	}, nil
+}
+
+// controlGroupToken returns the control group token with the given accessor
+// and a context in its namespace
+func (c *Core) controlGroupToken(ctx context.Context, accessor string) (*logical.TokenEntry, context.Context, error) {
+	aEntry, err := c.tokenStore.lookupByAccessor(ctx, accessor, false, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	te, err := c.tokenStore.Lookup(ctx, aEntry.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if te == nil || len(te.Policies) != 1 || te.Policies[0] != controlGroupPolicyName {
+		return nil, nil, &logical.StatusBadRequest{Err: "accessor is not a control group token accessor"}
+	}
+
+	tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tokenNS == nil {
+		return nil, nil, namespace.ErrNoNamespace
+	}
+	return te, namespace.ContextWithNamespace(ctx, tokenNS), nil
+}
+
+// controlGroupRequest reads the request stored in the cubbyhole of the
+// control group token
+func (c *Core) controlGroupRequest(ctx context.Context, te *logical.TokenEntry) (*controlGroupRequest, error) {
+	cubbyReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupRequestPath,
+		ClientToken: te.ID,
+	}
+	cubbyReq.SetTokenEntry(te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("error looking up control group request: {{err}}", err)
+	}
+	if cubbyResp == nil || cubbyResp.Data["request"] == nil {
+		return nil, fmt.Errorf("no control group request found")
+	}
+
+	cgReq := &controlGroupRequest{}
+	if err := jsonutil.DecodeJSON([]byte(cubbyResp.Data["request"].(string)), cgReq); err != nil {
+		return nil, errwrap.Wrapf("error decoding control group request: {{err}}", err)
+	}
+	return cgReq, nil
+}
+
+// storeControlGroupRequest writes the request in the cubbyhole of the control
+// group token
+func (c *Core) storeControlGroupRequest(ctx context.Context, te *logical.TokenEntry, cgReq *controlGroupRequest) error {
+	marshaled, err := json.Marshal(cgReq)
+	if err != nil {
+		return err
+	}
+
+	cubbyReq := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        controlGroupRequestPath,
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"request": string(marshaled),
+		},
+	}
+	cubbyReq.SetTokenEntry(te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err != nil {
+		return err
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp.Error()
+	}
+	return nil
+}
+
+// requesterControlGroup returns the control group the request is subject to
+// under the current policies of the requester
+func (c *Core) requesterControlGroup(ctx context.Context, cgReq *controlGroupRequest) (*ControlGroup, error) {
+	req := &logical.Request{
+		Operation:   cgReq.Operation,
+		Path:        cgReq.Path,
+		ClientToken: cgReq.ClientToken,
+		Connection:  &logical.Connection{RemoteAddr: cgReq.RemoteAddr},
+	}
+	acl, _, _, _, err := c.fetchACLTokenEntryAndEntity(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return acl.AllowOperation(ctx, req, false).ControlGroup, nil
+}
+
+// controlGroupSatisfied returns whether the authorizations meet the approvals
+// required by every factor of the control group
+func (c *Core) controlGroupSatisfied(ctx context.Context, cg *ControlGroup, authzs []*logical.Authz) (bool, error) {
+	for _, factor := range cg.Factors {
+		groupIDs, err := c.controlGroupFactorGroupIDs(ctx, factor)
+		if err != nil {
+			return false, err
+		}
+
+		var approvals int
+		for _, authz := range authzs {
+			member, err := c.entityInGroups(authz.EntityID, groupIDs)
+			if err != nil {
+				return false, err
+			}
+			if member {
+				approvals++
+			}
+		}
+		if approvals < factor.Identity.ApprovalsRequired {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// controlGroupFactorGroupIDs returns the IDs of the identity groups whose
+// members can authorize the factor
+func (c *Core) controlGroupFactorGroupIDs(ctx context.Context, factor *ControlGroupFactor) (map[string]bool, error) {
+	groupIDs := make(map[string]bool, len(factor.Identity.GroupIDs)+len(factor.Identity.GroupNames))
+	for _, id := range factor.Identity.GroupIDs {
+		groupIDs[id] = true
+	}
+	for _, name := range factor.Identity.GroupNames {
+		group, err := c.identityStore.MemDBGroupByName(ctx, name, false)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			groupIDs[group.ID] = true
+		}
+	}
+	return groupIDs, nil
+}
+
+// entityInGroups returns whether the entity is a member, directly or through
+// a child group, of one of the groups
+func (c *Core) entityInGroups(entityID string, groupIDs map[string]bool) (bool, error) {
+	if entityID == "" || len(groupIDs) == 0 {
+		return false, nil
+	}
+
+	groups, inheritedGroups, err := c.identityStore.groupsByEntityID(entityID)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range append(groups, inheritedGroups...) {
+		if groupIDs[group.ID] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// authorizeControlGroupRequest records the authorization of the entity on the
+// request of the control group token with the given accessor, and returns
+// whether the request is now approved.
+func (c *Core) authorizeControlGroupRequest(ctx context.Context, accessor, entityID string) (bool, error) {
+	if entityID == "" {
+		return false, &logical.StatusBadRequest{Err: "control group requests can only be authorized by tokens tied to an entity"}
+	}
+
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	te, tokenCtx, err := c.controlGroupToken(ctx, accessor)
+	if err != nil {
+		return false, err
+	}
+	cgReq, err := c.controlGroupRequest(tokenCtx, te)
+	if err != nil {
+		return false, err
+	}
+	if cgReq.EntityID == entityID {
+		return false, &logical.StatusBadRequest{Err: "requesters can't authorize their own control group request"}
+	}
+
+	cg, err := c.requesterControlGroup(tokenCtx, cgReq)
+	if err != nil {
+		return false, err
+	}
+	if cg == nil {
+		return false, &logical.StatusBadRequest{Err: "request is no longer subject to a control group"}
+	}
+
+	// The entity has to be an authorizer of at least one of the factors
+	var authorizer bool
+	for _, factor := range cg.Factors {
+		groupIDs, err := c.controlGroupFactorGroupIDs(tokenCtx, factor)
+		if err != nil {
+			return false, err
+		}
+		if authorizer, err = c.entityInGroups(entityID, groupIDs); err != nil {
+			return false, err
+		}
+		if authorizer {
+			break
+		}
+	}
+	if !authorizer {
+		return false, logical.ErrPermissionDenied
+	}
+
+	var authorized bool
+	for _, authz := range cgReq.Authorizations {
+		if authz.EntityID == entityID {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		cgReq.Authorizations = append(cgReq.Authorizations, &logical.Authz{
+			EntityID:          entityID,
+			AuthorizationTime: time.Now(),
+		})
+	}
+
+	cgReq.Approved, err = c.controlGroupSatisfied(tokenCtx, cg, cgReq.Authorizations)
+	if err != nil {
+		return false, err
+	}
+	if err := c.storeControlGroupRequest(tokenCtx, te, cgReq); err != nil {
+		return false, err
+	}
+	return cgReq.Approved, nil
+}
+
+// unwrapControlGroupRequest runs the approved request of the control group
+// token on behalf of the requester and returns the marshaled HTTP response.
+func (c *Core) unwrapControlGroupRequest(ctx context.Context, token string) (string, error) {
+	cgReq, err := c.consumeControlGroupRequest(ctx, token)
+	if err != nil || cgReq == nil {
+		return "", err
+	}
+	if !cgReq.Approved {
+		return "control group request has not been approved", logical.ErrPermissionDenied
+	}
+
+	reqID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	req := &logical.Request{
+		ID:          reqID,
+		Operation:   cgReq.Operation,
+		Path:        cgReq.Path,
+		Data:        cgReq.Data,
+		ClientToken: cgReq.ClientToken,
+		Connection:  &logical.Connection{RemoteAddr: cgReq.RemoteAddr},
+		ControlGroup: &logical.ControlGroup{
+			Authorizations: cgReq.Authorizations,
+			RequestTime:    cgReq.RequestTime,
+			Approved:       cgReq.Approved,
+			NamespaceID:    cgReq.NamespaceID,
+		},
+	}
+	resp, _, err := c.handleRequest(ctx, req)
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return resp.Error().Error(), err
+		}
+		return "", err
+	}
+	if resp == nil {
+		return "", nil
+	}
+
+	httpResp := logical.LogicalResponseToHTTPResponse(resp)
+	httpResp.RequestID = req.ID
+	marshaled, err := json.Marshal(httpResp)
+	if err != nil {
+		return "", errwrap.Wrapf("failed to marshal control group response: {{err}}", err)
+	}
+	return string(marshaled), nil
+}
+
+// consumeControlGroupRequest returns the request of the control group token,
+// revoking the token if the request is approved so it only runs once.
+func (c *Core) consumeControlGroupRequest(ctx context.Context, token string) (*controlGroupRequest, error) {
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	te, err := c.tokenStore.Lookup(ctx, token)
+	if err != nil || te == nil {
+		return nil, err
+	}
+	cgReq, err := c.controlGroupRequest(ctx, te)
+	if err != nil {
+		return nil, err
+	}
+	if cgReq.Approved {
+		if err := c.tokenStore.revokeOrphan(ctx, te.ID); err != nil {
+			return nil, errwrap.Wrapf("failed to revoke control group token: {{err}}", err)
+		}
+	}
+	return cgReq, nil
+}
+
+// checkControlGroup ensures the request was approved by the authorizers of
+// its control group
+func (c *Core) checkControlGroup(ctx context.Context, req *logical.Request, cg *ControlGroup) error {
+	if req.ControlGroup == nil {
+		return &controlGroupRequiredError{controlGroup: cg}
+	}
+
+	approved, err := c.controlGroupSatisfied(ctx, cg, req.ControlGroup.Authorizations)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return logical.ErrPermissionDenied
+	}
+	return nil
+}
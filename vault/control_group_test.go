@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_ControlGroup(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	request := func(op logical.Operation, path, token string, data map[string]interface{}) (*logical.Response, error) {
+		return c.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Data:        data,
+			ClientToken: token,
+		})
+	}
+	mustRequest := func(op logical.Operation, path, token string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(op, path, token, data)
+		if err != nil {
+			t.Fatalf("%s %s: err: %v, resp: %#v", op, path, err, resp)
+		}
+		return resp
+	}
+
+	mustRequest(logical.UpdateOperation, "secret/foo", root, map[string]interface{}{"value": "bar"})
+	mustRequest(logical.UpdateOperation, "sys/policy/requester", root, map[string]interface{}{
+		"policy": `
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 2
+			}
+		}
+	}
+}`,
+	})
+	mustRequest(logical.UpdateOperation, "sys/policy/authorizer", root, map[string]interface{}{
+		"policy": `path "sys/control-group/authorize" { capabilities = ["update"] }`,
+	})
+
+	// Set up the requester, two managers and a member of no group
+	entityToken := func(name string, policies ...string) (string, string) {
+		resp := mustRequest(logical.UpdateOperation, "identity/entity", root, map[string]interface{}{"name": name})
+		entityID := resp.Data["id"].(string)
+		te := &logical.TokenEntry{
+			Path:     "auth/token/create",
+			Policies: append([]string{"default"}, policies...),
+			EntityID: entityID,
+			TTL:      time.Hour,
+		}
+		testMakeTokenDirectly(t, c.tokenStore, te)
+		return entityID, te.ID
+	}
+	requesterID, requester := entityToken("requester", "requester")
+	manager1ID, manager1 := entityToken("manager1", "authorizer")
+	manager2ID, manager2 := entityToken("manager2", "authorizer")
+	_, outsider := entityToken("outsider", "authorizer")
+	mustRequest(logical.UpdateOperation, "identity/group", root, map[string]interface{}{
+		"name":              "managers",
+		"member_entity_ids": []string{manager1ID, manager2ID},
+	})
+
+	// The read returns a control group token instead of the secret
+	resp := mustRequest(logical.ReadOperation, "secret/foo", requester, nil)
+	if resp == nil || resp.WrapInfo == nil || resp.WrapInfo.Token == "" || resp.Data != nil {
+		t.Fatalf("expected a control group token, got: %#v", resp)
+	}
+	if resp.WrapInfo.TTL != time.Hour || resp.WrapInfo.CreationPath != "secret/foo" {
+		t.Fatalf("bad: %#v", resp.WrapInfo)
+	}
+	cgToken, accessor := resp.WrapInfo.Token, resp.WrapInfo.Accessor
+
+	status := func() *logical.Response {
+		t.Helper()
+		return mustRequest(logical.UpdateOperation, "sys/control-group/request", requester, map[string]interface{}{"accessor": accessor})
+	}
+	resp = status()
+	if resp.Data["approved"] != false || resp.Data["request_path"] != "secret/foo" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if entity := resp.Data["request_entity"].(map[string]interface{}); entity["id"] != requesterID || entity["name"] != "requester" {
+		t.Fatalf("bad: %#v", entity)
+	}
+
+	// The request can't run before it is approved
+	if _, err := request(logical.UpdateOperation, "sys/wrapping/unwrap", cgToken, nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Only the members of the groups of the factors can authorize
+	if _, err := request(logical.UpdateOperation, "sys/control-group/authorize", outsider, map[string]interface{}{"accessor": accessor}); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// An authorizer counts once
+	for _, token := range []string{manager1, manager1} {
+		resp = mustRequest(logical.UpdateOperation, "sys/control-group/authorize", token, map[string]interface{}{"accessor": accessor})
+		if resp.Data["approved"] != false {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+	if authzs := status().Data["authorizations"].([]map[string]interface{}); len(authzs) != 1 || authzs[0]["entity_name"] != "manager1" {
+		t.Fatalf("bad: %#v", authzs)
+	}
+	resp = mustRequest(logical.UpdateOperation, "sys/control-group/authorize", manager2, map[string]interface{}{"accessor": accessor})
+	if resp.Data["approved"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Unwrapping the token runs the request once
+	resp = mustRequest(logical.UpdateOperation, "sys/wrapping/unwrap", cgToken, nil)
+	httpResp := &logical.HTTPResponse{}
+	if err := jsonutil.DecodeJSON(resp.Data[logical.HTTPRawBody].([]byte), httpResp); err != nil {
+		t.Fatal(err)
+	}
+	if httpResp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", httpResp)
+	}
+	if _, err := request(logical.UpdateOperation, "sys/wrapping/unwrap", cgToken, nil); err == nil {
+		t.Fatal("expected error unwrapping the control group token twice")
+	}
+
+	// The control group token TTL is capped by the configuration
+	mustRequest(logical.UpdateOperation, "sys/config/control-group", root, map[string]interface{}{"max_ttl": "10m"})
+	resp = mustRequest(logical.ReadOperation, "sys/config/control-group", root, nil)
+	if resp.Data["max_ttl"] != int64(600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = mustRequest(logical.ReadOperation, "secret/foo", requester, nil)
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 10*time.Minute {
+		t.Fatalf("bad: %#v", resp.WrapInfo)
+	}
+}
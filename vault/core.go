@@ -23,6 +23,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-kms-wrapping/entropy"
 	aeadwrapper "github.com/hashicorp/go-kms-wrapping/wrappers/aead"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
@@ -31,6 +32,7 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
+	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -96,6 +98,11 @@ var (
 	// It's var not const so that tests can manipulate it.
 	manualStepDownSleepPeriod = 10 * time.Second
 
+	// autoRotateBarrierInterval is how often the active node checks whether
+	// the barrier's encryption key must be rotated. It's var not const so that
+	// tests can manipulate it.
+	autoRotateBarrierInterval = 5 * time.Minute
+
 	// Functions only in the Enterprise version
 	enterprisePostUnseal         = enterprisePostUnsealImpl
 	enterprisePreSeal            = enterprisePreSealImpl
@@ -243,6 +250,12 @@ type Core struct {
 	// unwrapSeal is the old seal when migrating to a new seal.
 	unwrapSeal Seal
 
+	// sealFactory creates the seals to migrate to while unsealed, and
+	// onlineSealMigration holds the state of such a migration.
+	sealFactory             SealFactory
+	onlineSealMigration     *onlineSealMigration
+	onlineSealMigrationLock sync.Mutex
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -270,6 +283,16 @@ type Core struct {
 	keepHALockOnStepDown *uint32
 	heldHALock           physical.Lock
 
+	// perfStandbyReadOnly is set while the storage is read-only because this
+	// node serves as a performance standby
+	perfStandbyReadOnly *uint32
+	// perfStandbyTables holds the tables the performance standby was set up
+	// from, to detect changes made by the active node
+	perfStandbyTables map[string][]byte
+	// perfStandbyInvalidator streams the keys written on the active node to
+	// the performance standbys
+	perfStandbyInvalidator *perfStandbyInvalidator
+
 	// shutdownDoneCh is used to notify when Shutdown() completes
 	shutdownDoneCh chan struct{}
 
@@ -289,6 +312,13 @@ type Core struct {
 	recoveryRekeyConfig *SealConfig
 	rekeyLock           sync.RWMutex
 
+	// namespaces maps the namespaces other than the root namespace by ID.
+	// They are loaded after unseal since they are a protected configuration
+	namespaces map[string]*namespace.Namespace
+
+	// namespacesLock guards the namespaces
+	namespacesLock sync.RWMutex
+
 	// mounts is loaded after unseal since it is a protected
 	// configuration
 	mounts *MountTable
@@ -360,6 +390,14 @@ type Core struct {
 	// a cluster label.
 	metricSink *metricsutil.ClusterMetricSink
 
+	// requestMetricLabels bounds the cardinality of the per-mount request
+	// metrics.
+	requestMetricLabels requestMetricLabels
+
+	// activeTime is the time, in Unix nanoseconds, at which this node last
+	// became the active node of an HA cluster, or zero if it is not active.
+	activeTime *uberAtomic.Int64
+
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
@@ -387,6 +425,21 @@ type Core struct {
 	// wrapping information
 	wrappingJWTKey *ecdsa.PrivateKey
 
+	// controlGroupLock serializes the updates of the control group requests
+	controlGroupLock sync.Mutex
+
+	// loginMFALock protects the logins waiting for the validation of their
+	// MFA requirement, the MFA passcodes already used, and the pending
+	// WebAuthn registrations
+	loginMFALock       sync.Mutex
+	loginMFARequests   map[string]*loginMFARequest
+	usedMFAPasscodes   map[string]time.Time
+	webAuthnChallenges map[string]*webAuthnChallenge
+
+	// userLockoutLock serializes the updates of the failed logins counted by
+	// the user lockout of the auth mounts
+	userLockoutLock sync.Mutex
+
 	//
 	// Cluster information
 	//
@@ -417,6 +470,9 @@ type Core struct {
 	clusterLeaderParams *atomic.Value
 	// Info on cluster members
 	clusterPeerClusterAddrsCache *cache.Cache
+	// Pending confirmations of destroy requests on KV mounts with deletion
+	// protection enabled
+	kvDestroyConfirmations *cache.Cache
 	// The context for the client
 	rpcClientConnContext context.Context
 	// The function for canceling the client connection
@@ -425,6 +481,17 @@ type Core struct {
 	rpcClientConn *grpc.ClientConn
 	// The grpc forwarding client
 	rpcForwardingClient *forwardingClient
+	// The additional grpc ClientConns of the forwarding pool
+	rpcPoolConns []*grpc.ClientConn
+	// The clients the requests are forwarded over in turn, starting with
+	// rpcForwardingClient
+	rpcForwardingPool []RequestForwardingClient
+	// The index of the next client of the pool to forward over
+	rpcForwardingPoolNext uint32
+	// Whether the forwarded requests and their responses are compressed
+	forwardingCompression bool
+	// The number of connections the requests are forwarded over
+	forwardingConnections int
 	// The UUID used to hold the leader lock. Only set on active node
 	leaderUUID string
 
@@ -501,7 +568,8 @@ type Core struct {
 	clusterListener *atomic.Value
 
 	// Telemetry objects
-	metricsHelper *metricsutil.MetricsHelper
+	metricsHelper      *metricsutil.MetricsHelper
+	metricsRollingSink *vaultmetrics.RollingSink
 
 	// Stores request counters
 	counters counters
@@ -521,6 +589,10 @@ type Core struct {
 	// secureRandomReader is the reader used for CSP operations
 	secureRandomReader io.Reader
 
+	// entropySourcer is the external source of entropy available to the
+	// mounts with external entropy access, if any
+	entropySourcer entropy.Sourcer
+
 	recoveryMode bool
 
 	clusterNetworkLayer cluster.NetworkLayer
@@ -534,6 +606,14 @@ type Core struct {
 	quotaManager *quotas.Manager
 
 	clusterHeartbeatInterval time.Duration
+
+	// drainTimeout is how long the requests being handled are given to
+	// complete before stepping down
+	drainTimeout time.Duration
+
+	// inFlightRequests holds the InFlightRequest of the requests being
+	// handled by the HTTP listeners, keyed by request ID
+	inFlightRequests sync.Map
 }
 
 // CoreConfig is used to parameterize a core
@@ -562,8 +642,28 @@ type CoreConfig struct {
 	Seal       Seal
 	UnwrapSeal Seal
 
+	// SealFactory creates the seals to migrate to while Vault is unsealed.
+	// Online seal migrations are not supported if nil.
+	SealFactory SealFactory
+
 	SecureRandomReader io.Reader
 
+	// EntropySourcer is the external source of entropy mixed into the key
+	// material of the mounts with external entropy access.
+	EntropySourcer entropy.Sourcer
+
+	// DrainTimeout is how long the requests being handled are given to
+	// complete before the active node steps down. Zero disables draining.
+	DrainTimeout time.Duration
+
+	// ForwardingCompression compresses the requests forwarded to the active
+	// node and their responses.
+	ForwardingCompression bool
+
+	// ForwardingConnections is the number of connections the requests are
+	// forwarded to the active node over. Defaults to 1.
+	ForwardingConnections int
+
 	Logger log.Logger
 
 	// Disables the trace display for Sentinel checks
@@ -611,6 +711,9 @@ type CoreConfig struct {
 	// Don't set this unless in dev mode, ideally only when using inmem
 	DevLicenseDuration time.Duration
 
+	// EnablePerformanceStandby lets standbys serve read-only requests
+	// locally instead of forwarding them to the active node
+	EnablePerformanceStandby  bool
 	DisablePerformanceStandby bool
 	DisableIndexing           bool
 	DisableKeyEncodingChecks  bool
@@ -618,8 +721,13 @@ type CoreConfig struct {
 	AllLoggers []log.Logger
 
 	// Telemetry objects
-	MetricsHelper *metricsutil.MetricsHelper
-	MetricSink    *metricsutil.ClusterMetricSink
+	MetricsHelper      *metricsutil.MetricsHelper
+	MetricSink         *metricsutil.ClusterMetricSink
+	MetricsRollingSink *vaultmetrics.RollingSink
+
+	// RuntimeMetricsInterval is the interval at which Go runtime statistics
+	// are published with the cluster label. Zero disables them.
+	RuntimeMetricsInterval time.Duration
 
 	CounterSyncInterval time.Duration
 
@@ -705,6 +813,11 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		conf.SecureRandomReader = rand.Reader
 	}
 
+	forwardingConnections := conf.ForwardingConnections
+	if forwardingConnections < 1 {
+		forwardingConnections = 1
+	}
+
 	clusterHeartbeatInterval := conf.ClusterHeartbeatInterval
 	if clusterHeartbeatInterval == 0 {
 		clusterHeartbeatInterval = 5 * time.Second
@@ -722,10 +835,12 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterAddr:         new(atomic.Value),
 		clusterListener:     new(atomic.Value),
 		seal:                conf.Seal,
+		sealFactory:         conf.SealFactory,
 		router:              NewRouter(),
 		sealed:              new(uint32),
 		sealMigrated:        new(uint32),
 		inSealMigration:     uberAtomic.NewBool(false),
+		activeTime:          uberAtomic.NewInt64(0),
 		standby:             true,
 		standbyStopCh:       new(atomic.Value),
 		baseLogger:          conf.Logger,
@@ -738,6 +853,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterName:                  conf.ClusterName,
 		clusterNetworkLayer:          conf.ClusterNetworkLayer,
 		clusterPeerClusterAddrsCache: cache.New(3*clusterHeartbeatInterval, time.Second),
+		kvDestroyConfirmations:       cache.New(kvDestroyConfirmationTTL, time.Minute),
 		enableMlock:                  !conf.DisableMlock,
 		rawEnabled:                   conf.EnableRaw,
 		shutdownDoneCh:               make(chan struct{}),
@@ -750,15 +866,22 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		activeNodeReplicationState:   new(uint32),
 		keepHALockOnStepDown:         new(uint32),
 		replicationFailure:           new(uint32),
-		disablePerfStandby:           true,
+		disablePerfStandby:           !conf.EnablePerformanceStandby || conf.DisablePerformanceStandby,
+		perfStandbyReadOnly:          new(uint32),
+		perfStandbyInvalidator:       newPerfStandbyInvalidator(),
 		activeContextCancelFunc:      new(atomic.Value),
 		allLoggers:                   conf.AllLoggers,
 		builtinRegistry:              conf.BuiltinRegistry,
 		neverBecomeActive:            new(uint32),
 		clusterLeaderParams:          new(atomic.Value),
 		metricsHelper:                conf.MetricsHelper,
+		metricsRollingSink:           conf.MetricsRollingSink,
 		metricSink:                   conf.MetricSink,
 		secureRandomReader:           conf.SecureRandomReader,
+		entropySourcer:               conf.EntropySourcer,
+		drainTimeout:                 conf.DrainTimeout,
+		forwardingCompression:        conf.ForwardingCompression,
+		forwardingConnections:        forwardingConnections,
 		rawConfig:                    new(atomic.Value),
 		counters: counters{
 			requests:     new(uint64),
@@ -947,6 +1070,10 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		return nil, err
 	}
 
+	if conf.RuntimeMetricsInterval > 0 {
+		go c.runtimeMetricsLoop(conf.RuntimeMetricsInterval, c.shutdownDoneCh)
+	}
+
 	return c, nil
 }
 
@@ -1023,8 +1150,11 @@ func (c *Core) UnsealWithRecoveryKeys(key []byte) (bool, error) {
 	return c.unseal(key, true)
 }
 
-func (c *Core) unseal(key []byte, useRecoveryKeys bool) (bool, error) {
+func (c *Core) unseal(key []byte, useRecoveryKeys bool) (unsealed bool, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "unseal"}, time.Now())
+	defer func() {
+		c.emitUnsealKeySubmission(retErr)
+	}()
 
 	c.stateLock.Lock()
 	defer c.stateLock.Unlock()
@@ -1513,6 +1643,7 @@ func (c *Core) unsealInternal(ctx context.Context, masterKey []byte) (bool, erro
 	// Success!
 	atomic.StoreUint32(c.sealed, 0)
 	c.metricSink.SetGaugeWithLabels([]string{"core", "unsealed"}, 1, nil)
+	c.emitSealStatusChange(false)
 
 	if c.logger.IsInfo() {
 		c.logger.Info("vault is unsealed")
@@ -1732,6 +1863,7 @@ func (c *Core) sealInternalWithOptions(grabStateLock, keepHALock, performCleanup
 		return nil
 	}
 	c.metricSink.SetGaugeWithLabels([]string{"core", "unsealed"}, 0, nil)
+	c.emitSealStatusChange(true)
 
 	c.logger.Info("marked as sealed")
 
@@ -1804,6 +1936,10 @@ func (c *Core) sealInternalWithOptions(grabStateLock, keepHALock, performCleanup
 		<-c.standbyDoneCh
 		atomic.StoreUint32(c.keepHALockOnStepDown, 0)
 		c.logger.Debug("runStandby done")
+
+		// The performance standby can't be torn down by its own routine
+		// as we hold the state lock
+		c.teardownPerfStandby()
 	}
 
 	c.teardownReplicationResolverHandler()
@@ -1884,6 +2020,9 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.setupPluginCatalog(ctx); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(ctx); err != nil {
+		return err
+	}
 	if err := c.loadMounts(ctx); err != nil {
 		return err
 	}
@@ -1995,7 +2134,7 @@ func (c *Core) postUnseal(ctx context.Context, ctxCancelFunc context.CancelFunc,
 
 	// Enable the cache
 	c.physicalCache.Purge(ctx)
-	if !c.cachingDisabled {
+	if !c.cachingDisabled && !c.perfStandby {
 		c.physicalCache.SetEnabled(true)
 	}
 
@@ -2015,10 +2154,20 @@ func (c *Core) postUnseal(ctx context.Context, ctxCancelFunc context.CancelFunc,
 	// is normally supported for a configurable time period. Re-encrypting
 	// the keys used for auto unsealing ensures Vault and its data will
 	// continue to be accessible even after prior seal keys are destroyed.
-	if seal, ok := c.seal.(*autoSeal); ok {
+	if seal, ok := c.seal.(*autoSeal); ok && !c.perfStandby {
 		if err := seal.UpgradeKeys(c.activeContext); err != nil {
 			c.logger.Warn("post-unseal upgrade seal keys failed", "error", err)
 		}
+
+		// Keep re-encrypting them when the seal's key is rotated while this
+		// node is active
+		go seal.upgradeKeysLoop(c.activeContext)
+	}
+
+	// Rotate the barrier's encryption key according to its rotation policy
+	// while this node is active
+	if !c.perfStandby {
+		go c.autoRotateBarrierLoop(c.activeContext)
 	}
 
 	c.metricsCh = make(chan struct{})
@@ -2077,6 +2226,9 @@ func (c *Core) preSeal() error {
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
+	if err := c.teardownActivityLog(context.Background()); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down activity log: {{err}}", err))
+	}
 	if err := c.teardownCredentials(context.Background()); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
@@ -2089,6 +2241,7 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(context.Background()); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	c.teardownNamespaces()
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -2150,6 +2303,96 @@ func (c *Core) BarrierKeyLength() (min, max int) {
 	return
 }
 
+// rotateBarrierKey installs a new barrier encryption key, and lets the standby
+// nodes and replicated clusters know about it.
+func (c *Core) rotateBarrierKey(ctx context.Context) (uint32, error) {
+	// Rotate to the new term
+	newTerm, err := c.barrier.Rotate(ctx, c.secureRandomReader)
+	if err != nil {
+		return 0, err
+	}
+	c.logger.Info("installed new encryption key", "term", newTerm)
+
+	// In HA mode, we need to an upgrade path for the standby instances
+	if c.ha != nil {
+		// Create the upgrade path to the new term
+		if err := c.barrier.CreateUpgrade(ctx, newTerm); err != nil {
+			c.logger.Error("failed to create new upgrade", "term", newTerm, "error", err)
+		}
+
+		// Schedule the destroy of the upgrade path
+		time.AfterFunc(KeyRotateGracePeriod, func() {
+			c.logger.Debug("cleaning up upgrade keys", "waited", KeyRotateGracePeriod)
+			if err := c.barrier.DestroyUpgrade(c.activeContext, newTerm); err != nil {
+				c.logger.Error("failed to destroy upgrade", "term", newTerm, "error", err)
+			}
+		})
+	}
+
+	// Write to the canary path, which will force a synchronous truing during
+	// replication
+	if err := c.barrier.Put(ctx, &logical.StorageEntry{
+		Key:   coreKeyringCanaryPath,
+		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
+	}); err != nil {
+		c.logger.Error("error saving keyring canary", "error", err)
+		return 0, errwrap.Wrapf("failed to save keyring canary: {{err}}", err)
+	}
+
+	return newTerm, nil
+}
+
+// autoRotateBarrierLoop periodically persists the estimated number of
+// encryptions done with the barrier's encryption key, and rotates the key
+// according to the rotation policy while this node is active.
+func (c *Core) autoRotateBarrierLoop(ctx context.Context) {
+	ticker := time.NewTicker(autoRotateBarrierInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkBarrierAutoRotate(ctx)
+		}
+	}
+}
+
+func (c *Core) checkBarrierAutoRotate(ctx context.Context) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.Sealed() || c.perfStandby || ctx.Err() != nil {
+		return
+	}
+	if c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return
+	}
+
+	reason, err := c.barrier.CheckBarrierAutoRotate(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Warn("failed to check barrier key rotation", "error", err)
+		}
+		return
+	}
+
+	if info, err := c.barrier.ActiveKeyInfo(); err == nil {
+		c.metricSink.SetGaugeWithLabels([]string{"barrier", "estimated_encryptions"}, float32(info.Encryptions), nil)
+	}
+
+	if reason == "" {
+		return
+	}
+
+	c.logger.Info("automatically rotating barrier key", "reason", reason)
+	if _, err := c.rotateBarrierKey(ctx); err != nil {
+		c.logger.Error("failed to automatically rotate barrier key", "error", err)
+		return
+	}
+	c.metricSink.IncrCounterWithLabels([]string{"barrier", "auto_rotation"}, 1, []metrics.Label{{Name: "reason", Value: reason}})
+}
+
 func (c *Core) AuditedHeadersConfig() *AuditedHeadersConfig {
 	return c.auditedHeaders
 }
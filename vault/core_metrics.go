@@ -2,8 +2,12 @@ package vault
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,9 +15,14 @@ import (
 	"github.com/hashicorp/shared-secure-libs/metricsutil"
 	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// jsonMetricFormat is the format of sys/metrics that aggregates metrics over a
+// rolling window.
+const jsonMetricFormat = "json"
+
 func (c *Core) metricsLoop(stopCh chan struct{}) {
 	emitTimer := time.Tick(time.Second)
 	writeTimer := time.Tick(c.counters.syncInterval)
@@ -43,6 +52,11 @@ func (c *Core) metricsLoop(stopCh chan struct{}) {
 				c.metricSink.SetGaugeWithLabels([]string{"core", "unsealed"}, 1, nil)
 			}
 
+			// Refresh the leadership gauges, on all nodes of HA clusters
+			if c.ha != nil {
+				c.emitLeadershipGauges(time.Now())
+			}
+
 		case <-writeTimer:
 			if stopped := grabLockOrStop(c.stateLock.RLock, c.stateLock.RUnlock, stopCh); stopped {
 				// Go through the loop again, this time the stop channel case
@@ -56,6 +70,11 @@ func (c *Core) metricsLoop(stopCh chan struct{}) {
 				if err != nil {
 					c.logger.Error("writing request counters to barrier", "err", err)
 				}
+				if c.activityLog != nil {
+					if err := c.activityLog.saveCurrentMonth(context.Background()); err != nil {
+						c.logger.Error("writing activity log to barrier", "err", err)
+					}
+				}
 			}
 			c.stateLock.RUnlock()
 		case <-identityCountTimer:
@@ -83,6 +102,98 @@ func (c *Core) metricsLoop(stopCh chan struct{}) {
 	}
 }
 
+// emitSealStatusChange counts the transitions of this node between the sealed
+// and unsealed states.
+func (c *Core) emitSealStatusChange(sealed bool) {
+	c.metricSink.IncrCounterWithLabels([]string{"core", "seal_status_changes"}, 1,
+		[]metrics.Label{{"sealed", strconv.FormatBool(sealed)}})
+}
+
+// emitUnsealKeySubmission counts the unseal keys submitted to this node, by
+// whether the key was accepted.
+func (c *Core) emitUnsealKeySubmission(err error) {
+	result := "accepted"
+	if err != nil {
+		result = "rejected"
+	}
+	c.metricSink.IncrCounterWithLabels([]string{"core", "unseal_key_submissions"}, 1,
+		[]metrics.Label{{"result", result}})
+}
+
+// emitLeadershipChange counts the times this node acquires and loses
+// leadership, and tracks when it became active for the time-as-leader gauge.
+func (c *Core) emitLeadershipChange(acquired bool) {
+	event := "lost"
+	if acquired {
+		event = "acquired"
+		c.activeTime.Store(time.Now().UnixNano())
+	} else {
+		c.activeTime.Store(0)
+	}
+	c.metricSink.IncrCounterWithLabels([]string{"core", "leadership_changes"}, 1,
+		[]metrics.Label{{"event", event}})
+}
+
+// emitLeadershipGauges reports whether this node is the active node, and for
+// how long it has been.
+func (c *Core) emitLeadershipGauges(now time.Time) {
+	var active, duration float32
+	if activeTime := c.activeTime.Load(); activeTime != 0 {
+		active = 1
+		duration = float32(now.Sub(time.Unix(0, activeTime)).Seconds())
+	}
+	c.metricSink.SetGaugeWithLabels([]string{"core", "active"}, active, nil)
+	c.metricSink.SetGaugeWithLabels([]string{"core", "active_duration"}, duration, nil)
+}
+
+// MetricsResponse returns the metrics of this node in the given format. The
+// "json" format aggregates the in-memory metrics over a rolling window, the
+// shortest one if no window is given; other formats are handled by the
+// metrics helper.
+func (c *Core) MetricsResponse(format, window string) *logical.Response {
+	if format != jsonMetricFormat {
+		return c.metricsHelper.ResponseForFormat(format)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPStatusCode:  http.StatusBadRequest,
+		},
+	}
+	if c.metricsRollingSink == nil {
+		resp.Data[logical.HTTPRawBody] = "rolling window metrics are not enabled"
+		return resp
+	}
+
+	d := c.metricsRollingSink.Windows()[0]
+	if window != "" {
+		var err error
+		d, err = parseutil.ParseDurationSecond(window)
+		if err != nil {
+			resp.Data[logical.HTTPRawBody] = fmt.Sprintf("invalid window: %s", err)
+			return resp
+		}
+	}
+
+	summary, err := c.metricsRollingSink.Summary(d, time.Now())
+	if err != nil {
+		resp.Data[logical.HTTPRawBody] = err.Error()
+		return resp
+	}
+	content, err := json.Marshal(summary)
+	if err != nil {
+		resp.Data[logical.HTTPRawBody] = fmt.Sprintf("error while marshalling the in-memory metrics: %s", err)
+		resp.Data[logical.HTTPStatusCode] = http.StatusInternalServerError
+		return resp
+	}
+
+	resp.Data[logical.HTTPContentType] = "application/json"
+	resp.Data[logical.HTTPRawBody] = content
+	resp.Data[logical.HTTPStatusCode] = http.StatusOK
+	return resp
+}
+
 // These wrappers are responsible for redirecting to the current instance of
 // TokenStore; there is one per method because an additional level of abstraction
 // seems confusing.
@@ -127,6 +238,16 @@ func (c *Core) tokenGaugeTtlCollector(ctx context.Context) ([]metricsutil.GaugeL
 	return ts.gaugeCollectorByTtl(ctx)
 }
 
+func (c *Core) leaseGaugeMountCollector(ctx context.Context) ([]metricsutil.GaugeLabelValues, error) {
+	c.stateLock.RLock()
+	m := c.expiration
+	c.stateLock.RUnlock()
+	if m == nil {
+		return []metricsutil.GaugeLabelValues{}, errors.New("nil expiration manager")
+	}
+	return m.leaseGaugeCollectorByMount(ctx)
+}
+
 // emitMetrics is used to start all the periodc metrics; all of them should
 // be shut down when stopCh is closed.
 func (c *Core) emitMetrics(stopCh chan struct{}) {
@@ -167,6 +288,12 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 			c.tokenGaugeTtlCollector,
 			"",
 		},
+		{
+			[]string{"expire", "leases", "by_mount"},
+			[]metrics.Label{{"gauge", "leases_by_mount"}},
+			c.leaseGaugeMountCollector,
+			"",
+		},
 		{
 			[]string{"secret", "kv", "count"},
 			[]metrics.Label{{"gauge", "kv_secrets_by_mountpoint"}},
@@ -322,3 +322,50 @@ func TestCoreMetrics_EntityGauges(t *testing.T) {
 			"mount_point": "auth/github/",
 		})
 }
+
+func TestCoreMetrics_SealAndLeadership(t *testing.T) {
+	core, _, _, sink := TestCoreUnsealedWithMetrics(t)
+
+	// A key of the wrong length is rejected
+	if _, err := core.Unseal([]byte("short")); err == nil {
+		t.Fatal("expected error")
+	}
+
+	core.emitLeadershipChange(true)
+	core.emitLeadershipGauges(time.Now().Add(time.Minute))
+
+	intervals := sink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	counters := make(map[string]int)
+	for key, c := range intervals[0].Counters {
+		counters[key] = c.Count
+	}
+	for name, min := range map[string]int{
+		"core.seal_status_changes;sealed=false;cluster=test-cluster":       1,
+		"core.unseal_key_submissions;result=accepted;cluster=test-cluster": 1,
+		"core.unseal_key_submissions;result=rejected;cluster=test-cluster": 1,
+		"core.leadership_changes;event=acquired;cluster=test-cluster":      1,
+	} {
+		if counters[name] < min {
+			t.Fatalf("expected counter %q, got %v", name, counters)
+		}
+	}
+
+	gauges := intervals[0].Gauges
+	if g := gauges["core.active;cluster=test-cluster"]; g.Value != 1 {
+		t.Fatalf("bad active gauge: %v", gauges)
+	}
+	if g := gauges["core.active_duration;cluster=test-cluster"]; g.Value < 59 {
+		t.Fatalf("bad active duration gauge: %v", gauges)
+	}
+
+	core.emitLeadershipChange(false)
+	core.emitLeadershipGauges(time.Now())
+	gauges = sink.Data()[0].Gauges
+	if g := gauges["core.active;cluster=test-cluster"]; g.Value != 0 {
+		t.Fatalf("bad active gauge: %v", gauges)
+	}
+}
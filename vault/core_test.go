@@ -2567,3 +2567,78 @@ func TestCore_ServiceRegistration(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestCore_StorageMetrics(t *testing.T) {
+	core, _, root, sink := TestCoreUnsealedWithMetrics(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = root
+	if _, err := core.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	intervals := sink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	// The test core doesn't set a storage type
+	expected := map[string]string{
+		"cluster": "test-cluster",
+		"type":    "unknown",
+	}
+	for _, op := range []string{"get", "put"} {
+		var found bool
+		for _, s := range intervals[0].Samples {
+			if s.Name != "storage."+op {
+				continue
+			}
+			labels := make(map[string]string)
+			for _, l := range s.Labels {
+				labels[l.Name] = l.Value
+			}
+			if labelsMatch(labels, expected) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("no storage.%s sample found", op)
+		}
+	}
+}
+
+func TestCore_AutoRotateBarrier(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	config, err := c.barrier.RotationConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is not rotated while under the limit
+	c.checkBarrierAutoRotate(ctx)
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Term != 1 {
+		t.Fatalf("bad: term: %d", info.Term)
+	}
+
+	config.MaxOperations = info.Encryptions
+	if err := c.barrier.SetRotationConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	c.checkBarrierAutoRotate(ctx)
+	info, err = c.barrier.ActiveKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The canary written after the rotation is the only encryption with the
+	// new key
+	if info.Term != 2 || info.Encryptions != 1 {
+		t.Fatalf("bad: key info: %#v", info)
+	}
+}
@@ -4,6 +4,7 @@ package vault
 
 import (
 	"context"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/license"
@@ -27,6 +28,12 @@ type LicensingConfig struct {
 func coreInit(c *Core, conf *CoreConfig) error {
 	phys := conf.Physical
 	_, txnOK := phys.(physical.Transactional)
+	// Record latency and errors of the requests made to the backend
+	if txnOK {
+		phys = physical.NewTransactionalMetricsBackend(phys, conf.StorageType, c.metricSink)
+	} else {
+		phys = physical.NewMetricsBackend(phys, conf.StorageType, c.metricSink)
+	}
 	sealUnwrapperLogger := conf.Logger.Named("storage.sealunwrapper")
 	c.allLoggers = append(c.allLoggers, sealUnwrapperLogger)
 	c.sealUnwrapper = NewSealUnwrapper(phys, sealUnwrapperLogger)
@@ -44,6 +51,11 @@ func coreInit(c *Core, conf *CoreConfig) error {
 	if !conf.DisableKeyEncodingChecks {
 		c.physical = physical.NewStorageEncoding(c.physical)
 	}
+
+	// Reject the writes while serving as a performance standby
+	if !c.disablePerfStandby {
+		c.physical = newPerfStandbyStorage(c.physical, c.perfStandbyReadOnly, c.perfStandbyInvalidator)
+	}
 	return nil
 }
 func (c *Core) setupReplicationResolverHandler() error {
@@ -88,7 +100,7 @@ func loadMFAConfigs(context.Context, *Core) error { return nil }
 
 func shouldStartClusterListener(*Core) bool { return true }
 
-func hasNamespaces(*Core) bool { return false }
+func hasNamespaces(*Core) bool { return true }
 
 func (c *Core) Features() license.Features {
 	return license.FeatureNone
@@ -99,13 +111,11 @@ func (c *Core) HasFeature(license.Features) bool {
 }
 
 func (c *Core) collectNamespaces() []*namespace.Namespace {
-	return []*namespace.Namespace{
-		namespace.RootNamespace,
-	}
+	return append([]*namespace.Namespace{namespace.RootNamespace}, c.listNamespaces()...)
 }
 
-func (c *Core) namepaceByPath(string) *namespace.Namespace {
-	return namespace.RootNamespace
+func (c *Core) namepaceByPath(path string) *namespace.Namespace {
+	return c.namespaceByPath(path)
 }
 
 func (c *Core) setupReplicatedClusterPrimary(*replication.Cluster) error { return nil }
@@ -135,21 +145,86 @@ func (c *Core) initSealsForMigration() {}
 func (c *Core) postSealMigration(ctx context.Context) error { return nil }
 
 func (c *Core) applyLeaseCountQuota(in *quotas.Request) (*quotas.Response, error) {
-	return &quotas.Response{Allowed: true}, nil
+	if c.quotaManager == nil {
+		return &quotas.Response{Allowed: true}, nil
+	}
+
+	in.Type = quotas.TypeLeaseCount
+	resp, err := c.quotaManager.ApplyQuota(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
 }
 
+// ackLeaseQuota releases the lease reserved by applyLeaseCountQuota. A lease
+// generated by the request has been counted by the expiration manager by then.
 func (c *Core) ackLeaseQuota(access quotas.Access, leaseGenerated bool) error {
-	return nil
+	if c.quotaManager == nil {
+		return nil
+	}
+
+	return c.quotaManager.AckLeaseQuota(access)
 }
 
 func (c *Core) quotaLeaseWalker(ctx context.Context, callback func(request *quotas.Request) bool) error {
+	if c.expiration == nil {
+		return nil
+	}
+
+	c.expiration.pending.Range(func(k, _ interface{}) bool {
+		req := c.leaseQuotaRequest(ctx, k.(string))
+		if req == nil {
+			return true
+		}
+		return callback(req)
+	})
+
 	return nil
 }
 
 func (c *Core) quotasHandleLeases(ctx context.Context, action quotas.LeaseAction, leaseIDs []string) error {
-	return nil
+	if c.quotaManager == nil {
+		return nil
+	}
+
+	reqs := make([]*quotas.Request, 0, len(leaseIDs))
+	for _, leaseID := range leaseIDs {
+		if req := c.leaseQuotaRequest(ctx, leaseID); req != nil {
+			reqs = append(reqs, req)
+		}
+	}
+
+	return c.quotaManager.HandleLeases(action, reqs)
+}
+
+// leaseQuotaRequest builds the quota request matching the request that
+// generated the lease, or nil if its namespace no longer exists.
+func (c *Core) leaseQuotaRequest(ctx context.Context, leaseID string) *quotas.Request {
+	leasePath, nsID := namespace.SplitIDFromString(leaseID)
+	if idx := strings.LastIndex(leasePath, "/"); idx != -1 {
+		leasePath = leasePath[:idx]
+	}
+
+	ns := namespace.RootNamespace
+	if nsID != "" {
+		var err error
+		ns, err = NamespaceByID(ctx, nsID, c)
+		if err != nil || ns == nil {
+			return nil
+		}
+	}
+
+	mountPath := c.router.MatchingMount(namespace.ContextWithNamespace(ctx, ns), leasePath)
+	return &quotas.Request{
+		Type:          quotas.TypeLeaseCount,
+		Path:          leasePath,
+		MountPath:     strings.TrimPrefix(mountPath, ns.Path),
+		NamespacePath: ns.Path,
+	}
 }
 
 func (c *Core) namespaceByPath(path string) *namespace.Namespace {
-	return namespace.RootNamespace
+	return c.namespaceByPathInternal(path)
 }
@@ -28,6 +28,8 @@ var StdAllowedHeaders = []string{
 	"X-Vault-Policy-Override",
 	"Authorization",
 	consts.AuthHeaderName,
+	consts.IndexHeaderName,
+	consts.InconsistentHeaderName,
 }
 
 // CORSConfig stores the state of the CORS configuration.
@@ -213,7 +213,7 @@ func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string, plugin
 	if d.core.pluginCatalog == nil {
 		return nil, fmt.Errorf("system view core plugin catalog is nil")
 	}
-	r, err := d.core.pluginCatalog.Get(ctx, name, pluginType)
+	r, err := d.core.pluginCatalog.Get(ctx, name, pluginType, "")
 	if err != nil {
 		return nil, err
 	}
@@ -224,6 +224,26 @@ func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string, plugin
 	return r, nil
 }
 
+// LookupPluginVersion looks for the given version of an external plugin in the
+// plugin catalog. It returns a PluginRunner or an error if no plugin was found.
+func (d dynamicSystemView) LookupPluginVersion(ctx context.Context, name string, pluginType consts.PluginType, version string) (*pluginutil.PluginRunner, error) {
+	if d.core == nil {
+		return nil, fmt.Errorf("system view core is nil")
+	}
+	if d.core.pluginCatalog == nil {
+		return nil, fmt.Errorf("system view core plugin catalog is nil")
+	}
+	r, err := d.core.pluginCatalog.Get(ctx, name, pluginType, version)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s version %s", name, version), ErrPluginNotFound)
+	}
+
+	return r, nil
+}
+
 // MlockEnabled returns the configuration setting for enabling mlock on plugins.
 func (d dynamicSystemView) MlockEnabled() bool {
 	return d.core.enableMlock
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,6 +17,9 @@ import (
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
+	"github.com/hashicorp/vault/helper/fairshare"
+	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/base62"
@@ -44,6 +48,11 @@ const (
 	// revokeRetryBase is a baseline retry time
 	revokeRetryBase = 10 * time.Second
 
+	// numRevocationWorkersDefault is the default number of workers revoking
+	// the expired leases. It can be overridden with the
+	// VAULT_LEASE_REVOCATION_WORKERS environment variable.
+	numRevocationWorkersDefault = 200
+
 	// maxLeaseDuration is the default maximum lease duration
 	maxLeaseTTL = 32 * 24 * time.Hour
 
@@ -60,6 +69,16 @@ type pendingInfo struct {
 	timer           *time.Timer
 }
 
+// irrevocableLease is the in-memory information kept about a lease whose
+// revocation failed maxRevokeAttempts times
+type irrevocableLease struct {
+	leaseID    string
+	path       string
+	namespace  *namespace.Namespace
+	revokeErr  string
+	expireTime time.Time
+}
+
 // ExpirationManager is used by the Core to manage leases. Secrets
 // can provide a lease, meaning that they can be renewed or revoked.
 // If a secret is not renewed in timely manner, it may be expired, and
@@ -105,6 +124,19 @@ type ExpirationManager struct {
 	logLeaseExpirations bool
 	expireFunc          ExpireLeaseStrategy
 
+	// revocationJobManager runs the revocations of the expired leases on a
+	// bounded pool of workers, with a queue per mount so that a slow or
+	// failing mount doesn't hold up the revocations of the others.
+	revocationJobManager *fairshare.JobManager
+	revokeRetryBase      time.Duration
+
+	// The irrevocable map holds the leases whose revocation failed
+	// maxRevokeAttempts times. They're no longer retried automatically, and
+	// are kept until they're revoked by an operator. The leases are counted in
+	// leaseCount as well.
+	irrevocable           sync.Map
+	irrevocableLeaseCount int
+
 	// testRegisterAuthFailure, if set to true, triggers an explicit failure on
 	// RegisterAuth to simulate a partial failure during a token creation
 	// request. This value should only be set by tests.
@@ -113,45 +145,127 @@ type ExpirationManager struct {
 
 type ExpireLeaseStrategy func(context.Context, *ExpirationManager, *leaseEntry)
 
-// revokeIDFunc is invoked when a given ID is expired
+// expireLeaseStrategyRevoke queues the revocation of the expired lease on the
+// queue of its mount
 func expireLeaseStrategyRevoke(ctx context.Context, m *ExpirationManager, le *leaseEntry) {
-	for attempt := uint(0); attempt < maxRevokeAttempts; attempt++ {
-		revokeCtx, cancel := context.WithTimeout(ctx, DefaultMaxRequestDuration)
-		revokeCtx = namespace.ContextWithNamespace(revokeCtx, le.namespace)
+	job := &revocationJob{
+		m:         m,
+		ctx:       ctx,
+		leaseID:   le.LeaseID,
+		namespace: le.namespace,
+		queueID:   m.revocationQueueID(ctx, le),
+	}
+	m.revocationJobManager.AddJob(job, job.queueID)
+}
 
-		go func() {
-			select {
-			case <-ctx.Done():
-			case <-m.quitCh:
-				cancel()
-			case <-revokeCtx.Done():
-			}
-		}()
+// revocationQueueID returns the ID of the revocation queue of the lease, which
+// is the accessor of its mount
+func (m *ExpirationManager) revocationQueueID(ctx context.Context, le *leaseEntry) string {
+	nsCtx := namespace.ContextWithNamespace(ctx, le.namespace)
+	if entry := m.router.MatchingMountEntry(nsCtx, le.Path); entry != nil {
+		return entry.Accessor
+	}
+	return le.namespace.ID + "/" + le.Path
+}
 
+// revocationJob revokes an expired lease. Failed revocations are retried with
+// an exponential backoff, after which the lease is marked irrevocable.
+type revocationJob struct {
+	m         *ExpirationManager
+	ctx       context.Context
+	leaseID   string
+	namespace *namespace.Namespace
+	queueID   string
+	attempts  uint
+}
+
+func (r *revocationJob) Execute() error {
+	m := r.m
+
+	select {
+	case <-m.quitCh:
+		m.logger.Error("shutting down, not attempting further revocation of lease", "lease_id", r.leaseID)
+		return nil
+	case <-m.quitContext.Done():
+		m.logger.Error("core context canceled, not attempting further revocation of lease", "lease_id", r.leaseID)
+		return nil
+	default:
+	}
+
+	revokeCtx, cancel := context.WithTimeout(r.ctx, DefaultMaxRequestDuration)
+	defer cancel()
+	revokeCtx = namespace.ContextWithNamespace(revokeCtx, r.namespace)
+
+	go func() {
 		select {
+		case <-r.ctx.Done():
 		case <-m.quitCh:
-			m.logger.Error("shutting down, not attempting further revocation of lease", "lease_id", le.LeaseID)
 			cancel()
-			return
-		case <-m.quitContext.Done():
-			m.logger.Error("core context canceled, not attempting further revocation of lease", "lease_id", le.LeaseID)
-			cancel()
-			return
-		default:
+		case <-revokeCtx.Done():
 		}
+	}()
 
-		m.coreStateLock.RLock()
-		err := m.Revoke(revokeCtx, le.LeaseID)
-		m.coreStateLock.RUnlock()
-		cancel()
-		if err == nil {
-			return
+	m.coreStateLock.RLock()
+	defer m.coreStateLock.RUnlock()
+
+	// The manager may have been stopped while waiting for the lock
+	select {
+	case <-m.quitCh:
+		return nil
+	default:
+	}
+
+	return m.Revoke(revokeCtx, r.leaseID)
+}
+
+func (r *revocationJob) OnFailure(err error) {
+	m := r.m
+	m.logger.Error("failed to revoke lease", "lease_id", r.leaseID, "error", err)
+
+	r.attempts++
+	if r.attempts >= maxRevokeAttempts {
+		m.logger.Error("maximum revoke attempts reached, marking lease irrevocable", "lease_id", r.leaseID)
+		m.markLeaseIrrevocable(r.ctx, r.namespace, r.leaseID, err)
+		return
+	}
+
+	// Queue the job again once the backoff elapsed, rather than holding up a
+	// worker in the meantime
+	time.AfterFunc((1<<(r.attempts-1))*m.revokeRetryBase, func() {
+		select {
+		case <-m.quitCh:
+		default:
+			m.revocationJobManager.AddJob(r, r.queueID)
 		}
+	})
+}
+
+// markLeaseIrrevocable records the revocation error on the lease and stops
+// its automatic revocation
+func (m *ExpirationManager) markLeaseIrrevocable(ctx context.Context, ns *namespace.Namespace, leaseID string, revokeErr error) {
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	m.coreStateLock.RLock()
+	defer m.coreStateLock.RUnlock()
+
+	le, err := m.loadEntry(nsCtx, leaseID)
+	if err != nil {
+		m.logger.Error("failed to load irrevocable lease", "lease_id", leaseID, "error", err)
+		return
+	}
+	// The lease was revoked in the meantime
+	if le == nil {
+		return
+	}
 
-		m.logger.Error("failed to revoke lease", "lease_id", le.LeaseID, "error", err)
-		time.Sleep((1 << attempt) * revokeRetryBase)
+	le.RevokeErr = revokeErr.Error()
+
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	if err := m.persistEntry(nsCtx, le); err != nil {
+		m.logger.Error("failed to persist irrevocable lease", "lease_id", leaseID, "error", err)
 	}
-	m.logger.Error("maximum revoke attempts reached", "lease_id", le.LeaseID)
+	m.updatePendingInternal(le)
 }
 
 // NewExpirationManager creates a new ExpirationManager that is backed
@@ -184,6 +298,8 @@ func NewExpirationManager(c *Core, view *BarrierView, e ExpireLeaseStrategy, log
 
 		logLeaseExpirations: os.Getenv("VAULT_SKIP_LOGGING_LEASE_EXPIRATIONS") == "",
 		expireFunc:          e,
+
+		revokeRetryBase: revokeRetryBase,
 	}
 	*exp.restoreMode = 1
 
@@ -192,6 +308,17 @@ func NewExpirationManager(c *Core, view *BarrierView, e ExpireLeaseStrategy, log
 		exp.logger = log.New(&opts)
 	}
 
+	numWorkers := numRevocationWorkersDefault
+	if v := os.Getenv("VAULT_LEASE_REVOCATION_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			numWorkers = n
+		} else {
+			exp.logger.Warn("invalid number of lease revocation workers, using the default", "value", v, "default", numRevocationWorkersDefault)
+		}
+	}
+	exp.revocationJobManager = fairshare.NewJobManager("expire", numWorkers, exp.logger.Named("job-manager"))
+	exp.revocationJobManager.Start()
+
 	go exp.uniquePoliciesGc()
 
 	return exp
@@ -632,9 +759,16 @@ func (m *ExpirationManager) Stop() error {
 		m.nonexpiring.Delete(key)
 		return true
 	})
+	m.irrevocable.Range(func(key, value interface{}) bool {
+		m.irrevocable.Delete(key)
+		return true
+	})
+	m.irrevocableLeaseCount = 0
 	m.uniquePolicies = make(map[string][]string)
 	m.pendingLock.Unlock()
 
+	m.revocationJobManager.Stop()
+
 	if m.inRestoreMode() {
 		for {
 			if !m.inRestoreMode() {
@@ -673,6 +807,8 @@ func (m *ExpirationManager) LazyRevoke(ctx context.Context, leaseID string) erro
 		return nil
 	}
 
+	// Revoking an irrevocable lease lazily gives it another round of attempts
+	le.RevokeErr = ""
 	le.ExpireTime = time.Now()
 	{
 		m.pendingLock.Lock()
@@ -741,6 +877,14 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 			m.logger.Error("failed to update quota on revocation", "error", err)
 		}
 	}
+	if _, ok := m.irrevocable.Load(leaseID); ok {
+		m.irrevocable.Delete(leaseID)
+		m.irrevocableLeaseCount--
+		m.leaseCount--
+		if err := m.core.quotasHandleLeases(ctx, quotas.LeaseActionDeleted, []string{leaseID}); err != nil {
+			m.logger.Error("failed to update quota on revocation", "error", err)
+		}
+	}
 	m.nonexpiring.Delete(leaseID)
 	m.pendingLock.Unlock()
 
@@ -1094,6 +1238,9 @@ func (m *ExpirationManager) RenewToken(ctx context.Context, req *logical.Request
 
 	// Refresh groups
 	if resp.Auth.EntityID != "" && m.core.identityStore != nil {
+		if err := m.core.identityStore.syncExternalGroups(ctx, resp.Auth.GroupAliases); err != nil {
+			return nil, err
+		}
 		validAliases, err := m.core.identityStore.refreshExternalGroupMembershipsByEntityID(ctx, resp.Auth.EntityID, resp.Auth.GroupAliases)
 		if err != nil {
 			return nil, err
@@ -1410,7 +1557,8 @@ func (m *ExpirationManager) inMemoryLeaseInfo(le *leaseEntry) *leaseEntry {
 	// Need to index:
 	//   namespace -- derived from lease ID
 	//   policies -- stored in Auth object
-	//   auth method -- derived from lease.Path
+	//   auth method and mount -- derived from lease.Path
+	ret.Path = le.Path
 	if le.Auth != nil {
 		// Ensure that list of policies is not copied more than
 		// once. This method is called with pendingLock held.
@@ -1425,7 +1573,6 @@ func (m *ExpirationManager) inMemoryLeaseInfo(le *leaseEntry) *leaseEntry {
 			m.uniquePolicies[key] = le.Auth.Policies
 			ret.Auth.Policies = le.Auth.Policies
 		}
-		ret.Path = le.Path
 	}
 	return ret
 }
@@ -1459,6 +1606,25 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry) {
 	// Check for an existing timer
 	info, ok := m.pending.Load(le.LeaseID)
 
+	if le.RevokeErr != "" {
+		m.updateIrrevocableInternal(le, ok)
+		if ok {
+			info.(pendingInfo).timer.Stop()
+			m.pending.Delete(le.LeaseID)
+		}
+		return
+	}
+
+	// The lease is no longer irrevocable, it's counted again below
+	if _, irrevocable := m.irrevocable.Load(le.LeaseID); irrevocable {
+		m.irrevocable.Delete(le.LeaseID)
+		m.irrevocableLeaseCount--
+		m.leaseCount--
+		if err := m.core.quotasHandleLeases(m.quitContext, quotas.LeaseActionDeleted, []string{le.LeaseID}); err != nil {
+			m.logger.Error("failed to update quota on lease deletion", "error", err)
+		}
+	}
+
 	if le.ExpireTime.IsZero() {
 		if le.nonexpiringToken() {
 			// Store this in the nonexpiring map instead of pending.
@@ -1516,6 +1682,51 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry) {
 	}
 }
 
+// updateIrrevocableInternal tracks the irrevocable lease; do not call this
+// without a write lock on m.pending. wasPending tells whether the lease is
+// already counted as a pending lease.
+func (m *ExpirationManager) updateIrrevocableInternal(le *leaseEntry, wasPending bool) {
+	_, ok := m.irrevocable.Load(le.LeaseID)
+	m.irrevocable.Store(le.LeaseID, &irrevocableLease{
+		leaseID:    le.LeaseID,
+		path:       le.Path,
+		namespace:  le.namespace,
+		revokeErr:  le.RevokeErr,
+		expireTime: le.ExpireTime,
+	})
+	if ok {
+		return
+	}
+
+	m.irrevocableLeaseCount++
+	if !wasPending {
+		// The lease is being restored
+		m.leaseCount++
+		if err := m.core.quotasHandleLeases(m.quitContext, quotas.LeaseActionCreated, []string{le.LeaseID}); err != nil {
+			m.logger.Error("failed to update quota on lease creation", "error", err)
+		}
+	}
+}
+
+// IrrevocableLeases returns the irrevocable leases of the namespace of the
+// context, keyed by lease ID
+func (m *ExpirationManager) IrrevocableLeases(ctx context.Context) (map[string]*irrevocableLease, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make(map[string]*irrevocableLease)
+	m.irrevocable.Range(func(key, value interface{}) bool {
+		lease := value.(*irrevocableLease)
+		if lease.namespace.ID == ns.ID {
+			leases[key.(string)] = lease
+		}
+		return true
+	})
+	return leases, nil
+}
+
 // revokeEntry is used to attempt revocation of an internal entry
 func (m *ExpirationManager) revokeEntry(ctx context.Context, le *leaseEntry) error {
 	// Revocation of login tokens is special since we can by-pass the
@@ -1951,9 +2162,12 @@ func (m *ExpirationManager) emitMetrics() {
 	// All updates of this value are with the pendingLock held.
 	m.pendingLock.RLock()
 	num := m.leaseCount
+	numIrrevocable := m.irrevocableLeaseCount
 	m.pendingLock.RUnlock()
 
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
+	metrics.SetGauge([]string{"expire", "num_irrevocable_leases"}, float32(numIrrevocable))
+	metrics.SetGauge([]string{"expire", "revocation", "pending"}, float32(m.revocationJobManager.GetPendingJobCount()))
 	// Check if lease count is greater than the threshold
 	if num > maxLeaseThreshold {
 		if atomic.LoadUint32(m.leaseCheckCounter) > 59 {
@@ -1965,6 +2179,80 @@ func (m *ExpirationManager) emitMetrics() {
 	}
 }
 
+// leaseGaugeCollectorByMount counts the active leases in each namespace and
+// mount, bucketed by the time remaining until they expire.
+func (m *ExpirationManager) leaseGaugeCollectorByMount(ctx context.Context) ([]metricsutil.GaugeLabelValues, error) {
+	if m.inRestoreMode() {
+		return []metricsutil.GaugeLabelValues{}, nil
+	}
+
+	allNamespaces := m.core.collectNamespaces()
+	byID := make(map[string]*namespace.Namespace, len(allNamespaces))
+	for _, ns := range allNamespaces {
+		byID[ns.ID] = ns
+	}
+
+	type leaseKey struct {
+		nsID     string
+		mount    string
+		expiring string
+	}
+	counts := make(map[leaseKey]int)
+	now := time.Now()
+
+	m.pending.Range(func(key, value interface{}) bool {
+		select {
+		// Abort and return empty collection if it's taking too much time, nonblocking check.
+		case <-ctx.Done():
+			return false
+		default:
+		}
+
+		p := value.(pendingInfo)
+		if p.cachedLeaseInfo == nil {
+			return true
+		}
+
+		_, nsID := namespace.SplitIDFromString(key.(string))
+		if nsID == "" {
+			nsID = namespace.RootNamespaceID
+		}
+		ns, ok := byID[nsID]
+		if !ok {
+			return true
+		}
+
+		mount := m.router.MatchingMount(namespace.ContextWithNamespace(ctx, ns), p.cachedLeaseInfo.Path)
+		k := leaseKey{
+			nsID:     nsID,
+			mount:    ns.TrimmedPath(mount),
+			expiring: metricsutil.TTLBucket(p.cachedLeaseInfo.ExpireTime.Sub(now)),
+		}
+		counts[k]++
+		return true
+	})
+
+	// If collection was cancelled, return an empty array.
+	select {
+	case <-ctx.Done():
+		return []metricsutil.GaugeLabelValues{}, nil
+	default:
+	}
+
+	values := make([]metricsutil.GaugeLabelValues, 0, len(counts))
+	for k, count := range counts {
+		values = append(values, metricsutil.GaugeLabelValues{
+			Labels: []metrics.Label{
+				vaultmetrics.NamespaceLabel(byID[k.nsID]),
+				{"mount_point", k.mount},
+				{"expiring", k.expiring},
+			},
+			Value: float32(count),
+		})
+	}
+	return values, nil
+}
+
 // Callback function type to walk tokens referenced in the expiration
 // manager. Don't want to use leaseEntry here because it's an unexported
 // type (though most likely we would only call this from within the "vault" core package.)
@@ -2018,6 +2306,10 @@ type leaseEntry struct {
 	// namespace, and V1 has secondary indexes live in the matching namespace.
 	Version int `json:"version"`
 
+	// RevokeErr is the error of the last revocation attempt of an irrevocable
+	// lease
+	RevokeErr string `json:"revoke_err,omitempty"`
+
 	namespace *namespace.Namespace
 }
 
@@ -819,6 +819,128 @@ func TestExpiration_RevokeOnExpire(t *testing.T) {
 	}
 }
 
+func TestExpiration_RevokeOnExpire_irrevocable(t *testing.T) {
+	var failRevoke int32 = 1
+	noop := &NoopBackend{
+		RequestHandler: func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+			switch req.Operation {
+			case logical.RevokeOperation:
+				if atomic.LoadInt32(&failRevoke) == 1 {
+					return nil, errors.New("revocation failed")
+				}
+				return nil, nil
+			default:
+				return &logical.Response{
+					Secret: &logical.Secret{
+						LeaseOptions: logical.LeaseOptions{
+							TTL: 20 * time.Millisecond,
+						},
+					},
+					Data: map[string]interface{}{
+						"access_key": "xyz",
+					},
+				}, nil
+			}
+		},
+	}
+	c, keys, root := TestCoreUnsealed(t)
+	c.expiration.revokeRetryBase = time.Millisecond
+	c.logicalBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/prod/aws")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "prod/aws/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	leaseID := resp.Secret.LeaseID
+
+	waitForIrrevocable := func(exp *ExpirationManager) *irrevocableLease {
+		t.Helper()
+		start := time.Now()
+		for time.Now().Sub(start) < 5*time.Second {
+			leases, err := exp.IrrevocableLeases(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lease, ok := leases[leaseID]; ok {
+				return lease
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("lease %q never became irrevocable", leaseID)
+		return nil
+	}
+
+	lease := waitForIrrevocable(c.expiration)
+	if lease.path != "prod/aws/foo" || !strings.Contains(lease.revokeErr, "revocation failed") {
+		t.Fatalf("bad: %#v", lease)
+	}
+
+	// The lease is listed
+	req = logical.TestRequest(t, logical.ListOperation, "sys/leases/irrevocable")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != leaseID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})[leaseID].(map[string]interface{})
+	if info["path"] != "prod/aws/foo" || !strings.Contains(info["error"].(string), "revocation failed") {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	// The lease stays irrevocable after an unseal
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForRestore(t, c.expiration)
+	waitForIrrevocable(c.expiration)
+
+	// Once the backend recovers, a manual revocation clears the lease
+	atomic.StoreInt32(&failRevoke, 0)
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/leases/revoke")
+	req.Data["lease_id"] = leaseID
+	req.Data["sync"] = true
+	req.ClientToken = root
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leases, err := c.expiration.IrrevocableLeases(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 0 {
+		t.Fatalf("expected no irrevocable leases, got: %#v", leases)
+	}
+	c.expiration.pendingLock.RLock()
+	count := c.expiration.irrevocableLeaseCount
+	c.expiration.pendingLock.RUnlock()
+	if count != 0 {
+		t.Fatalf("expected no irrevocable leases, got %d", count)
+	}
+}
+
 func TestExpiration_RevokePrefix(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -2267,3 +2389,39 @@ func TestExpiration_CachedPolicyIsShared(t *testing.T) {
 		}
 	}
 }
+
+func TestExpiration_LeaseGaugeCollectorByMount(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.Data["ttl"] = "1h"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = root
+		resp, err := c.HandleRequest(ctx, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	waitForRestore(t, c.expiration)
+
+	values, err := c.expiration.leaseGaugeCollectorByMount(ctx)
+	if err != nil {
+		t.Fatalf("bad collector run: %v", err)
+	}
+	expectInGaugeCollection(t,
+		map[string]string{"namespace": "root", "mount_point": "secret/", "expiring": "1h"},
+		2.0,
+		values)
+}
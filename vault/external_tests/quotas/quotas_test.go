@@ -411,3 +411,118 @@ func TestQuotas_RateLimitQuota(t *testing.T) {
 		t.Fatalf("unexpected number of failed requests: %d", numFail)
 	}
 }
+
+func TestQuotas_RateLimitQuota_Path(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	client := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	setupMounts(t, client)
+	defer teardownMounts(t, client)
+
+	// Only requests to the CA chain are limited, and a burst of 5 requests
+	// is allowed at once
+	_, err := client.Logical().Write("sys/quotas/rate-limit/rlq", map[string]interface{}{
+		"rate":     1,
+		"interval": "10s",
+		"burst":    5,
+		"path":     "pki/cert/ca_chain",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Logical().Read("sys/quotas/rate-limit/rlq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Equal(t, "pki/cert/ca_chain", resp.Data["path"])
+	require.Equal(t, "5", fmt.Sprint(resp.Data["burst"]))
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.Logical().Read("pki/cert/ca_chain"); err != nil {
+			t.Fatalf("expected request %d to be allowed: %v", i, err)
+		}
+	}
+	_, err = client.Logical().Read("pki/cert/ca_chain")
+	if err == nil {
+		t.Fatal("expected request beyond the burst to be rejected")
+	}
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != 429 {
+		t.Fatalf("expected a 429 response, got: %v", err)
+	}
+
+	// Other paths of the mount are not limited
+	for i := 0; i < 10; i++ {
+		if _, err := client.Logical().Read("pki/cert/ca"); err != nil {
+			t.Fatalf("expected request %d to be allowed: %v", i, err)
+		}
+	}
+}
+
+func TestQuotas_LeaseCountQuota(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	client := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	setupMounts(t, client)
+	defer teardownMounts(t, client)
+
+	_, err := client.Logical().Write("sys/quotas/lease-count/lcq", map[string]interface{}{
+		"max_leases":      3,
+		"soft_max_leases": 2,
+		"path":            "pki",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leaseIDs []string
+	for i := 0; i < 3; i++ {
+		secret, err := client.Logical().Write("pki/issue/test", map[string]interface{}{
+			"common_name": "test.testvault.com",
+		})
+		if err != nil {
+			t.Fatalf("expected request %d to be allowed: %v", i, err)
+		}
+		leaseIDs = append(leaseIDs, secret.LeaseID)
+	}
+
+	_, err = client.Logical().Write("pki/issue/test", map[string]interface{}{
+		"common_name": "test.testvault.com",
+	})
+	if err == nil {
+		t.Fatal("expected request beyond the lease count quota to be rejected")
+	}
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != 429 {
+		t.Fatalf("expected a 429 response, got: %v", err)
+	}
+
+	resp, err := client.Logical().Read("sys/quotas/lease-count/lcq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Equal(t, "pki/", resp.Data["path"])
+	require.Equal(t, "3", fmt.Sprint(resp.Data["counter"]))
+
+	// Revoking a lease allows a new one to be created
+	if err := client.Sys().Revoke(leaseIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Logical().Write("pki/issue/test", map[string]interface{}{
+		"common_name": "test.testvault.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -21,6 +22,7 @@ import (
 	"github.com/hashicorp/vault/helper/testhelpers/teststorage"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
 	"golang.org/x/net/http2"
@@ -269,6 +271,62 @@ func TestRaft_ShamirUnseal(t *testing.T) {
 	}
 }
 
+func TestRaft_ReadYourWrites(t *testing.T) {
+	t.Parallel()
+	cluster := raftCluster(t)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	client.SetReadYourWrites(true)
+
+	// Writes return the storage index including them
+	req := client.NewRequest("PUT", "/v1/secret/foo")
+	if err := req.SetJSONBody(map[string]interface{}{"test": "data"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.RawRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	state := resp.Header.Get(consts.IndexHeaderName)
+	if state == "" {
+		t.Fatal("expected index header")
+	}
+	index, err := vault.ParseRequiredState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, _ := cluster.Cores[0].Core.StorageIndex()
+	if index == 0 || index > applied {
+		t.Fatalf("bad index %d, applied %d", index, applied)
+	}
+
+	// Reads requiring it are served
+	secret, err := client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["test"] != "data" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// Requests requiring an index in the future fail
+	client.SetReadYourWrites(false)
+	client.SetMaxRetries(0)
+	future := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("v1:%d", applied+1000000)))
+	req = client.NewRequest("GET", "/v1/secret/foo")
+	req.Headers.Set(consts.IndexHeaderName, future)
+	resp, err = client.RawRequest(req)
+	if resp == nil {
+		t.Fatalf("expected response, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", resp.StatusCode)
+	}
+}
+
 func TestRaft_SnapshotAPI(t *testing.T) {
 	t.Parallel()
 	cluster := raftCluster(t)
@@ -580,6 +580,13 @@ func TestTokenStore_RevocationOnStartup(t *testing.T) {
 		t.Fatalf("unexpected number of leases: %d", len(leases))
 	}
 
+	// Allow rewriting the leases through the raw endpoint
+	if _, err := client.Logical().Write("sys/config/raw", map[string]interface{}{
+		"allow_destructive": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
 	// Holds non-root leases
 	var validLeases []string
 	// Fake times in the past
@@ -48,13 +48,10 @@ var (
 	KeyRotateGracePeriod = 2 * time.Minute
 
 	addEnterpriseHaActors func(*Core, *run.Group) chan func()            = addEnterpriseHaActorsNoop
-	interruptPerfStandby  func(chan func(), chan struct{}) chan struct{} = interruptPerfStandbyNoop
+	interruptPerfStandby  func(chan func(), chan struct{}) chan struct{} = interruptPerfStandbyImpl
 )
 
 func addEnterpriseHaActorsNoop(*Core, *run.Group) chan func() { return nil }
-func interruptPerfStandbyNoop(chan func(), chan struct{}) chan struct{} {
-	return make(chan struct{})
-}
 
 // Standby checks if the Vault is in standby mode
 func (c *Core) Standby() (bool, error) {
@@ -332,6 +329,9 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 
 	var g run.Group
 	newLeaderCh := addEnterpriseHaActors(c, &g)
+	if newLeaderCh == nil && !c.disablePerfStandby {
+		newLeaderCh = c.addPerfStandbyActor(&g)
+	}
 	{
 		// This will cause all the other actors to close when the stop channel
 		// is closed.
@@ -551,6 +551,7 @@ func (c *Core) waitForLeadership(newLeaderCh chan func(), manualStepDownCh, stop
 		if err == nil {
 			c.standby = false
 			c.leaderUUID = uuid
+			c.emitLeadershipChange(true)
 		}
 
 		close(continueCh)
@@ -574,6 +575,13 @@ func (c *Core) waitForLeadership(newLeaderCh chan func(), manualStepDownCh, stop
 			c.logger.Warn("stepping down from active operation to standby")
 		}
 
+		// Give the requests being handled a chance to complete before
+		// stepping down. Sealing isn't drained as the seal request itself
+		// waits for the step down.
+		if manualStepDown {
+			c.drainInFlightRequests()
+		}
+
 		// Stop Active Duty
 		{
 			// Spawn this in a go routine so we can cancel the context and
@@ -594,6 +602,7 @@ func (c *Core) waitForLeadership(newLeaderCh chan func(), manualStepDownCh, stop
 			// yet
 			activeCtxCancel()
 			metrics.MeasureSince([]string{"core", "leadership_lost"}, activeTime)
+			c.emitLeadershipChange(false)
 
 			// Mark as standby
 			c.standby = true
@@ -111,6 +111,7 @@ func (i *IdentityStore) paths() []*framework.Path {
 		lookupPaths(i),
 		upgradePaths(i),
 		oidcPaths(i),
+		mfaPaths(i),
 	)
 }
 
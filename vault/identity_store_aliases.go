@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/storagepacker"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -44,6 +45,15 @@ This field is deprecated, use canonical_id.`,
 					Type:        framework.TypeString,
 					Description: "Name of the alias; unused for a modify",
 				},
+				"custom_metadata": {
+					Type: framework.TypeKVPairs,
+					Description: `Custom metadata to be associated with the alias.
+Unlike the metadata set by the auth method, it is not modified on login. In CLI,
+this parameter can be repeated multiple times, and it all gets merged together.
+For example:
+vault <command> <path> custom_metadata=key1=value1 custom_metadata=key2=value2
+`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.handleAliasCreateUpdate(),
@@ -77,6 +87,10 @@ This field is deprecated, use canonical_id.`,
 					Type:        framework.TypeString,
 					Description: "(Unused)",
 				},
+				"custom_metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Custom metadata to be associated with the alias",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.handleAliasCreateUpdate(),
@@ -125,6 +139,19 @@ func (i *IdentityStore) handleAliasCreateUpdate() framework.OperationFunc {
 			canonicalID = d.Get("entity_id").(string)
 		}
 
+		// Get custom metadata, if any. A nil map means it is left unchanged.
+		var customMetadata map[string]string
+		customMetadataRaw, ok, err := d.GetOkErr("custom_metadata")
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to parse custom_metadata: %v", err)), nil
+		}
+		if ok {
+			customMetadata = customMetadataRaw.(map[string]string)
+			if err := validateMetadata(customMetadata); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid custom_metadata: %v", err)), nil
+			}
+		}
+
 		i.lock.Lock()
 		defer i.lock.Unlock()
 
@@ -146,8 +173,8 @@ func (i *IdentityStore) handleAliasCreateUpdate() framework.OperationFunc {
 
 				switch {
 				case mountAccessor == "" && name == "":
-					// Just a canonical ID update, maybe
-					if canonicalID == "" {
+					// Just a canonical ID or custom metadata update, maybe
+					if canonicalID == "" && customMetadata == nil {
 						// Nothing to do, so be idempotent
 						return nil, nil
 					}
@@ -167,7 +194,7 @@ func (i *IdentityStore) handleAliasCreateUpdate() framework.OperationFunc {
 					// Both provided
 				}
 
-				return i.handleAliasUpdate(ctx, req, canonicalID, name, mountAccessor, alias)
+				return i.handleAliasUpdate(ctx, req, canonicalID, name, mountAccessor, customMetadata, alias)
 			}
 		}
 
@@ -196,23 +223,24 @@ func (i *IdentityStore) handleAliasCreateUpdate() framework.OperationFunc {
 				return logical.ErrorResponse("cannot modify aliases across namespaces"), logical.ErrPermissionDenied
 			}
 
-			return i.handleAliasUpdate(ctx, req, alias.CanonicalID, name, mountAccessor, alias)
+			return i.handleAliasUpdate(ctx, req, alias.CanonicalID, name, mountAccessor, customMetadata, alias)
 		}
 
 		// At this point we know it's a new creation request
-		return i.handleAliasCreate(ctx, req, canonicalID, name, mountAccessor)
+		return i.handleAliasCreate(ctx, req, canonicalID, name, mountAccessor, customMetadata)
 	}
 }
 
-func (i *IdentityStore) handleAliasCreate(ctx context.Context, req *logical.Request, canonicalID, name, mountAccessor string) (*logical.Response, error) {
+func (i *IdentityStore) handleAliasCreate(ctx context.Context, req *logical.Request, canonicalID, name, mountAccessor string, customMetadata map[string]string) (*logical.Response, error) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	alias := &identity.Alias{
-		MountAccessor: mountAccessor,
-		Name:          name,
+		MountAccessor:  mountAccessor,
+		Name:           name,
+		CustomMetadata: customMetadata,
 	}
 	entity := &identity.Entity{}
 
@@ -266,16 +294,21 @@ func (i *IdentityStore) handleAliasCreate(ctx context.Context, req *logical.Requ
 	}, nil
 }
 
-func (i *IdentityStore) handleAliasUpdate(ctx context.Context, req *logical.Request, canonicalID, name, mountAccessor string, alias *identity.Alias) (*logical.Response, error) {
+func (i *IdentityStore) handleAliasUpdate(ctx context.Context, req *logical.Request, canonicalID, name, mountAccessor string, customMetadata map[string]string, alias *identity.Alias) (*logical.Response, error) {
 	if name == alias.Name &&
 		mountAccessor == alias.MountAccessor &&
-		(canonicalID == alias.CanonicalID || canonicalID == "") {
+		(canonicalID == alias.CanonicalID || canonicalID == "") &&
+		(customMetadata == nil || strutil.EqualStringMaps(customMetadata, alias.CustomMetadata)) {
 		// Nothing to do; return nil to be idempotent
 		return nil, nil
 	}
 
 	alias.LastUpdateTime = ptypes.TimestampNow()
 
+	if customMetadata != nil {
+		alias.CustomMetadata = customMetadata
+	}
+
 	// If we're changing one or the other or both of these, make sure that
 	// there isn't a matching alias already, and make sure it's in the same
 	// namespace.
@@ -409,6 +442,7 @@ func (i *IdentityStore) handleAliasReadCommon(ctx context.Context, alias *identi
 	respData["canonical_id"] = alias.CanonicalID
 	respData["mount_accessor"] = alias.MountAccessor
 	respData["metadata"] = alias.Metadata
+	respData["custom_metadata"] = alias.CustomMetadata
 	respData["name"] = alias.Name
 	respData["merged_from_canonical_ids"] = alias.MergedFromCanonicalIDs
 	respData["namespace_id"] = alias.NamespaceID
@@ -399,6 +399,77 @@ func TestIdentityStore_AliasUpdate(t *testing.T) {
 	}
 }
 
+func TestIdentityStore_AliasCustomMetadata(t *testing.T) {
+	var err error
+	var resp *logical.Response
+	ctx := namespace.RootContext(nil)
+	is, githubAccessor, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	aliasReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity-alias",
+		Data: map[string]interface{}{
+			"name":            "testaliasname",
+			"mount_accessor":  githubAccessor,
+			"custom_metadata": []string{"team=payments"},
+		},
+	}
+	resp, err = is.HandleRequest(ctx, aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	aliasID := resp.Data["id"].(string)
+
+	// Updating only the custom metadata by ID should not require the name or
+	// the mount accessor
+	aliasReq.Path = "entity-alias/id/" + aliasID
+	aliasReq.Data = map[string]interface{}{
+		"custom_metadata": []string{"team=billing"},
+	}
+	resp, err = is.HandleRequest(ctx, aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// Logging in updates the metadata set by the auth method but must leave
+	// the custom metadata untouched
+	_, err = is.CreateOrFetchEntity(ctx, &logical.Alias{
+		MountAccessor: githubAccessor,
+		MountType:     "github",
+		Name:          "testaliasname",
+		Metadata:      map[string]string{"team": "login"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aliasReq.Operation = logical.ReadOperation
+	aliasReq.Data = nil
+	resp, err = is.HandleRequest(ctx, aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	expected := map[string]string{"team": "billing"}
+	if !reflect.DeepEqual(resp.Data["custom_metadata"], expected) {
+		t.Fatalf("bad: custom metadata; expected: %#v, actual: %#v", expected, resp.Data["custom_metadata"])
+	}
+	if !reflect.DeepEqual(resp.Data["metadata"], map[string]string{"team": "login"}) {
+		t.Fatalf("bad: metadata: %#v", resp.Data["metadata"])
+	}
+
+	aliasReq.Operation = logical.UpdateOperation
+	aliasReq.Data = map[string]interface{}{
+		"custom_metadata": []string{"bad:key=value"},
+	}
+	resp, err = is.HandleRequest(ctx, aliasReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an invalid custom metadata key, got: %#v", resp)
+	}
+}
+
 func TestIdentityStore_AliasUpdate_ByID(t *testing.T) {
 	var err error
 	var resp *logical.Response
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/golang/protobuf/ptypes"
@@ -137,6 +138,14 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeBool,
 					Description: "Setting this will follow the 'mine' strategy for merging MFA secrets. If there are secrets of the same type both in entities that are merged from and in entity into which all others are getting merged, secrets in the destination will be unaltered. If not set, this API will throw an error containing all the conflicts.",
 				},
+				"merge_policies": {
+					Type:        framework.TypeBool,
+					Description: "Setting this will add the policies of the entities that are merged from to the entity into which all others are getting merged. The resulting policies are deduplicated and sorted.",
+				},
+				"preview": {
+					Type:        framework.TypeBool,
+					Description: "Setting this will return the entity that the merge would produce, along with any conflicting MFA secrets, without modifying any of the entities.",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.pathEntityMergeID(),
@@ -145,6 +154,15 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-merge-id"][0]),
 			HelpDescription: strings.TrimSpace(entityHelp["entity-merge-id"][1]),
 		},
+		{
+			Pattern: "entity/duplicates/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathEntityDuplicates(),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-duplicates"][0]),
+			HelpDescription: strings.TrimSpace(entityHelp["entity-duplicates"][1]),
+		},
 	}
 }
 
@@ -162,6 +180,11 @@ func (i *IdentityStore) pathEntityMergeID() framework.OperationFunc {
 		}
 
 		force := d.Get("force").(bool)
+		mergePolicies := d.Get("merge_policies").(bool)
+
+		if d.Get("preview").(bool) {
+			return i.entityMergePreview(ctx, toEntityID, fromEntityIDs, force, mergePolicies)
+		}
 
 		// Create a MemDB transaction to merge entities
 		txn := i.db.Txn(true)
@@ -172,7 +195,7 @@ func (i *IdentityStore) pathEntityMergeID() framework.OperationFunc {
 			return nil, err
 		}
 
-		userErr, intErr := i.mergeEntity(ctx, txn, toEntity, fromEntityIDs, force, true, false, true)
+		userErr, intErr := i.mergeEntity(ctx, txn, toEntity, fromEntityIDs, force, true, mergePolicies, true)
 		if userErr != nil {
 			return logical.ErrorResponse(userErr.Error()), nil
 		}
@@ -184,7 +207,179 @@ func (i *IdentityStore) pathEntityMergeID() framework.OperationFunc {
 		// persistence
 		txn.Commit()
 
-		return nil, nil
+		i.logger.Info("merged entities", "to_entity_id", toEntity.ID, "from_entity_ids", fromEntityIDs)
+
+		// Return the outcome of the merge so that it is recorded in the audit
+		// log along with the request
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"to_entity_id":      toEntity.ID,
+				"from_entity_ids":   fromEntityIDs,
+				"merged_entity_ids": toEntity.MergedEntityIDs,
+				"policies":          toEntity.Policies,
+			},
+		}, nil
+	}
+}
+
+// entityMergePreview returns the entity that merging the given entities would
+// produce, without modifying any of them
+func (i *IdentityStore) entityMergePreview(ctx context.Context, toEntityID string, fromEntityIDs []string, force, mergePolicies bool) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	toEntity, err := i.MemDBEntityByID(toEntityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if toEntity == nil {
+		return logical.ErrorResponse("entity id to merge to is invalid"), nil
+	}
+	if toEntity.NamespaceID != ns.ID {
+		return logical.ErrorResponse("entity id to merge into does not belong to the request's namespace"), nil
+	}
+
+	aliases := make([]map[string]interface{}, 0)
+	for _, alias := range toEntity.Aliases {
+		aliases = append(aliases, map[string]interface{}{
+			"id":             alias.ID,
+			"name":           alias.Name,
+			"mount_accessor": alias.MountAccessor,
+			"entity_id":      toEntity.ID,
+		})
+	}
+
+	mfaConflicts := make([]string, 0)
+	mergedEntityIDs := toEntity.MergedEntityIDs
+	policies := toEntity.Policies
+	for _, fromEntityID := range fromEntityIDs {
+		if fromEntityID == toEntity.ID {
+			return logical.ErrorResponse("to_entity_id should not be present in from_entity_ids"), nil
+		}
+
+		fromEntity, err := i.MemDBEntityByID(fromEntityID, true)
+		if err != nil {
+			return nil, err
+		}
+		if fromEntity == nil {
+			return logical.ErrorResponse("entity id to merge from is invalid"), nil
+		}
+		if fromEntity.NamespaceID != toEntity.NamespaceID {
+			return logical.ErrorResponse("entity id to merge from does not belong to this namespace"), nil
+		}
+
+		for configID := range fromEntity.MFASecrets {
+			if _, ok := toEntity.MFASecrets[configID]; ok && !force {
+				mfaConflicts = append(mfaConflicts, configID)
+			}
+		}
+
+		for _, alias := range fromEntity.Aliases {
+			aliases = append(aliases, map[string]interface{}{
+				"id":             alias.ID,
+				"name":           alias.Name,
+				"mount_accessor": alias.MountAccessor,
+				"entity_id":      fromEntity.ID,
+			})
+		}
+
+		if mergePolicies {
+			policies = strutil.MergeSlices(policies, fromEntity.Policies)
+		}
+
+		mergedEntityIDs = append(mergedEntityIDs, fromEntity.MergedEntityIDs...)
+		mergedEntityIDs = append(mergedEntityIDs, fromEntity.ID)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"to_entity_id":      toEntity.ID,
+			"from_entity_ids":   fromEntityIDs,
+			"aliases":           aliases,
+			"merged_entity_ids": mergedEntityIDs,
+			"policies":          policies,
+			"mfa_conflicts":     strutil.RemoveDuplicates(mfaConflicts, false),
+		},
+	}, nil
+}
+
+// pathEntityDuplicates lists the entities in the namespace that have aliases
+// of the same name on different auth mounts, which usually means that they
+// belong to the same person
+func (i *IdentityStore) pathEntityDuplicates() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		txn := i.db.Txn(false)
+
+		iter, err := txn.Get(entitiesTable, "namespace_id", ns.ID)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to fetch iterator for entities in memdb: {{err}}", err)
+		}
+
+		entitiesByAliasName := make(map[string][]*identity.Entity)
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			entity := raw.(*identity.Entity)
+			seen := make(map[string]bool, len(entity.Aliases))
+			for _, alias := range entity.Aliases {
+				if seen[alias.Name] {
+					continue
+				}
+				seen[alias.Name] = true
+				entitiesByAliasName[alias.Name] = append(entitiesByAliasName[alias.Name], entity)
+			}
+		}
+
+		var aliasNames []string
+		for aliasName, entities := range entitiesByAliasName {
+			if len(entities) > 1 {
+				aliasNames = append(aliasNames, aliasName)
+			}
+		}
+		sort.Strings(aliasNames)
+
+		duplicates := make([]map[string]interface{}, 0, len(aliasNames))
+		for _, aliasName := range aliasNames {
+			entities := entitiesByAliasName[aliasName]
+
+			// Suggest merging into the oldest entity, so that the entity that
+			// has been in use the longest keeps its ID
+			sort.Slice(entities, func(a, b int) bool {
+				createdA, createdB := entities[a].CreationTime, entities[b].CreationTime
+				if createdA.GetSeconds() != createdB.GetSeconds() {
+					return createdA.GetSeconds() < createdB.GetSeconds()
+				}
+				if createdA.GetNanos() != createdB.GetNanos() {
+					return createdA.GetNanos() < createdB.GetNanos()
+				}
+				return entities[a].ID < entities[b].ID
+			})
+
+			fromEntityIDs := make([]string, 0, len(entities)-1)
+			for _, entity := range entities[1:] {
+				fromEntityIDs = append(fromEntityIDs, entity.ID)
+			}
+
+			duplicates = append(duplicates, map[string]interface{}{
+				"alias_name":      aliasName,
+				"to_entity_id":    entities[0].ID,
+				"from_entity_ids": fromEntityIDs,
+			})
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"duplicates": duplicates,
+			},
+		}, nil
 	}
 }
 
@@ -367,6 +562,7 @@ func (i *IdentityStore) handleEntityReadCommon(ctx context.Context, entity *iden
 		aliasMap["canonical_id"] = alias.CanonicalID
 		aliasMap["mount_accessor"] = alias.MountAccessor
 		aliasMap["metadata"] = alias.Metadata
+		aliasMap["custom_metadata"] = alias.CustomMetadata
 		aliasMap["name"] = alias.Name
 		aliasMap["merged_from_canonical_ids"] = alias.MergedFromCanonicalIDs
 		aliasMap["creation_time"] = ptypes.TimestampString(alias.CreationTime)
@@ -875,6 +1071,16 @@ var entityHelp = map[string][2]string{
 		"Merge two or more entities together",
 		"",
 	},
+	"entity-duplicates": {
+		"List the entities that have aliases of the same name on different mounts",
+		`
+Entities are reported as duplicates when they have aliases of the same name,
+which usually means that the same user has logged in through different auth
+mounts. For each alias name, the oldest entity is suggested as the entity to
+merge into. The suggestion can be checked by calling the merge endpoint with
+"preview" set before performing the merge.
+`,
+	},
 	"batch-delete": {
 		"Delete all of the entities provided",
 		"",
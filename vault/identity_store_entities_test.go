@@ -1164,3 +1164,135 @@ func TestIdentityStore_MergeEntitiesByID(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentityStore_EntityDuplicates_MergePreview(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, githubAccessor, c := testIdentityStoreWithGithubAuth(ctx, t)
+
+	meGH := &MountEntry{
+		Table:       credentialTableType,
+		Path:        "github-enterprise/",
+		Type:        "github",
+		Description: "github enterprise auth",
+	}
+	err := c.enableCredential(ctx, meGH)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entityIDs []string
+	for index, data := range []struct {
+		accessor string
+		policies []string
+	}{
+		{githubAccessor, []string{"dev", "default"}},
+		{meGH.Accessor, []string{"ops", "default"}},
+	} {
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity",
+			Data: map[string]interface{}{
+				"name":     fmt.Sprintf("testentityname%d", index),
+				"policies": data.policies,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		entityID := resp.Data["id"].(string)
+		entityIDs = append(entityIDs, entityID)
+
+		resp, err = is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity-alias",
+			Data: map[string]interface{}{
+				"name":           "jane",
+				"mount_accessor": data.accessor,
+				"canonical_id":   entityID,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+	}
+
+	duplicatesReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "entity/duplicates",
+	}
+	resp, err := is.HandleRequest(ctx, duplicatesReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	duplicates := resp.Data["duplicates"].([]map[string]interface{})
+	if len(duplicates) != 1 {
+		t.Fatalf("bad: number of duplicates; expected: 1, actual: %d", len(duplicates))
+	}
+	if duplicates[0]["alias_name"] != "jane" {
+		t.Fatalf("bad: alias name: %#v", duplicates[0]["alias_name"])
+	}
+	toEntityID := duplicates[0]["to_entity_id"].(string)
+	fromEntityIDs := duplicates[0]["from_entity_ids"].([]string)
+	actualIDs := append([]string{toEntityID}, fromEntityIDs...)
+	sort.Strings(actualIDs)
+	sort.Strings(entityIDs)
+	if !reflect.DeepEqual(actualIDs, entityIDs) {
+		t.Fatalf("bad: entity ids; expected: %v, actual: %v", entityIDs, actualIDs)
+	}
+
+	mergeReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity/merge",
+		Data: map[string]interface{}{
+			"to_entity_id":    toEntityID,
+			"from_entity_ids": fromEntityIDs,
+			"merge_policies":  true,
+			"preview":         true,
+		},
+	}
+	resp, err = is.HandleRequest(ctx, mergeReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	expectedPolicies := []string{"default", "dev", "ops"}
+	if !reflect.DeepEqual(resp.Data["policies"], expectedPolicies) {
+		t.Fatalf("bad: policies; expected: %v, actual: %v", expectedPolicies, resp.Data["policies"])
+	}
+	if aliases := resp.Data["aliases"].([]map[string]interface{}); len(aliases) != 2 {
+		t.Fatalf("bad: number of aliases; expected: 2, actual: %d", len(aliases))
+	}
+
+	// The preview must not modify the entities
+	fromEntity, err := is.MemDBEntityByID(fromEntityIDs[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromEntity == nil || fromEntity.Aliases[0].CanonicalID != fromEntity.ID {
+		t.Fatalf("entity was modified by the merge preview: %#v", fromEntity)
+	}
+
+	delete(mergeReq.Data, "preview")
+	resp, err = is.HandleRequest(ctx, mergeReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["merged_entity_ids"], fromEntityIDs) {
+		t.Fatalf("bad: merged entity ids; expected: %v, actual: %v", fromEntityIDs, resp.Data["merged_entity_ids"])
+	}
+
+	toEntity, err := is.MemDBEntityByID(toEntityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(toEntity.Policies, expectedPolicies) {
+		t.Fatalf("bad: policies; expected: %v, actual: %v", expectedPolicies, toEntity.Policies)
+	}
+
+	resp, err = is.HandleRequest(ctx, duplicatesReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if duplicates := resp.Data["duplicates"].([]map[string]interface{}); len(duplicates) != 0 {
+		t.Fatalf("bad: duplicates after merge: %#v", duplicates)
+	}
+}
@@ -583,3 +583,68 @@ func TestIdentityStore_GroupAliasesUpdate(t *testing.T) {
 		t.Fatalf("still found alias with old group: %s", pretty.Sprint(resp.Data))
 	}
 }
+
+func TestIdentityStore_GroupAliases_ExternalGroupSync(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	i, accessor, c := testIdentityStoreWithGithubAuth(ctx, t)
+
+	entity, err := i.CreateOrFetchEntity(ctx, &logical.Alias{
+		MountAccessor: accessor,
+		MountType:     "github",
+		Name:          "jane",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupAliases := []*logical.Alias{{MountAccessor: accessor, Name: "devs"}}
+
+	sync := func(groupAliases []*logical.Alias) {
+		t.Helper()
+		if err := i.syncExternalGroups(ctx, groupAliases); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.refreshExternalGroupMembershipsByEntityID(ctx, entity.ID, groupAliases); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unknown group aliases are ignored unless the mount syncs the groups
+	sync(groupAliases)
+	group, err := i.MemDBGroupByName(ctx, "devs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group != nil {
+		t.Fatalf("expected no group, got: %#v", group)
+	}
+
+	c.router.MatchingMountByAccessor(accessor).Config.ExternalGroupSync = true
+	sync(groupAliases)
+	group, err = i.MemDBGroupByName(ctx, "devs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group == nil || group.Type != groupTypeExternal || group.Alias == nil || group.Alias.Name != "devs" {
+		t.Fatalf("bad: group: %#v", group)
+	}
+	if len(group.MemberEntityIDs) != 1 || group.MemberEntityIDs[0] != entity.ID {
+		t.Fatalf("bad: members: %#v", group.MemberEntityIDs)
+	}
+
+	// The group is pruned once its last member leaves
+	sync(nil)
+	group, err = i.MemDBGroupByName(ctx, "devs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group != nil {
+		t.Fatalf("expected the group to be pruned, got: %#v", group)
+	}
+	alias, err := i.MemDBAliasByFactors(accessor, "devs", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias != nil {
+		t.Fatalf("expected the group alias to be pruned, got: %#v", alias)
+	}
+}
@@ -17,6 +17,12 @@ import (
 const (
 	groupTypeInternal = "internal"
 	groupTypeExternal = "external"
+
+	// externalGroupSyncMetadataKey and externalGroupSyncMetadataValue mark
+	// the external groups created at login by the external group sync of
+	// their auth mount
+	externalGroupSyncMetadataKey   = "created_by"
+	externalGroupSyncMetadataValue = "external-group-sync"
 )
 
 func groupPathFields() map[string]*framework.FieldSchema {
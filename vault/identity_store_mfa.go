@@ -0,0 +1,1249 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/identity/mfa"
+	"github.com/hashicorp/vault/helper/mfa/webauthn"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	otplib "github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	mfaMethodTypeTOTP     = "totp"
+	mfaMethodTypeDuo      = "duo"
+	mfaMethodTypeWebAuthn = "webauthn"
+
+	// mfaMethodPrefix is the storage prefix of the MFA method configurations
+	mfaMethodPrefix = "mfa/method/"
+
+	// mfaLoginEnforcementPrefix is the storage prefix of the login MFA
+	// enforcements
+	mfaLoginEnforcementPrefix = "mfa/login-enforcement/"
+)
+
+// mfaLoginEnforcement requires the logins it applies to to be validated by
+// one of its MFA methods. An enforcement applies to the logins on the listed
+// auth mounts, and to the logins of the listed entities and group members.
+type mfaLoginEnforcement struct {
+	Name                string   `json:"name"`
+	MFAMethodNames      []string `json:"mfa_method_names"`
+	AuthMethodAccessors []string `json:"auth_method_accessors"`
+	AuthMethodTypes     []string `json:"auth_method_types"`
+	IdentityEntityIDs   []string `json:"identity_entity_ids"`
+	IdentityGroupIDs    []string `json:"identity_group_ids"`
+}
+
+func mfaPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"issuer": {
+					Type:        framework.TypeString,
+					Description: "The name of the key's issuing organization.",
+				},
+				"period": {
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: "The length of time used to generate a counter for the TOTP token calculation.",
+				},
+				"key_size": {
+					Type:        framework.TypeInt,
+					Default:     20,
+					Description: "Determines the size in bytes of the generated key.",
+				},
+				"qr_size": {
+					Type:        framework.TypeInt,
+					Default:     200,
+					Description: "The pixel size of the generated square QR code.",
+				},
+				"algorithm": {
+					Type:        framework.TypeString,
+					Default:     "SHA1",
+					Description: `The hashing algorithm used to generate the TOTP token. Options include SHA1, SHA256 and SHA512.`,
+				},
+				"digits": {
+					Type:        framework.TypeInt,
+					Default:     6,
+					Description: "The number of digits in the generated TOTP token. This value can either be 6 or 8.",
+				},
+				"skew": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "The number of delay periods that are allowed when validating a TOTP token. This value can either be 0 or 1.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathMFAMethodRead(mfaMethodTypeTOTP),
+				logical.UpdateOperation: i.pathMFAMethodTOTPUpdate,
+				logical.DeleteOperation: i.pathMFAMethodDelete(mfaMethodTypeTOTP),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathMFAMethodList(mfaMethodTypeTOTP),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-list"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/generate$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFAMethodTOTPGenerate,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-generate"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-generate$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity the TOTP secret is generated for.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFAMethodTOTPAdminGenerate,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-generate"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-destroy$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity the TOTP secret is destroyed for.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFAMethodTOTPAdminDestroy,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-destroy"][1]),
+		},
+		{
+			Pattern: "mfa/method/duo/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount whose entity alias names are used as the Duo usernames. Defaults to the alias of the login, or to the entity name.",
+				},
+				"username_format": {
+					Type:        framework.TypeString,
+					Default:     "%s",
+					Description: "Format string used to build the Duo username from the alias name.",
+				},
+				"integration_key": {
+					Type:        framework.TypeString,
+					Description: "Integration key of the Duo application.",
+				},
+				"secret_key": {
+					Type:        framework.TypeString,
+					Description: "Secret key of the Duo application.",
+				},
+				"api_hostname": {
+					Type:        framework.TypeString,
+					Description: "API hostname of the Duo application.",
+				},
+				"push_info": {
+					Type:        framework.TypeString,
+					Description: "Information, in URL query string format, displayed with the push notifications.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathMFAMethodRead(mfaMethodTypeDuo),
+				logical.UpdateOperation: i.pathMFAMethodDuoUpdate,
+				logical.DeleteOperation: i.pathMFAMethodDelete(mfaMethodTypeDuo),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["duo"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["duo"][1]),
+		},
+		{
+			Pattern: "mfa/method/duo/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathMFAMethodList(mfaMethodTypeDuo),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["duo-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["duo-list"][1]),
+		},
+		{
+			Pattern: "mfa/method/webauthn/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"relying_party_id": {
+					Type:        framework.TypeString,
+					Description: "Relying party ID, which is the domain the WebAuthn credentials are scoped to.",
+				},
+				"relying_party_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the relying party displayed by the authenticators. Defaults to relying_party_id.",
+				},
+				"origins": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Origins, such as https://vault.example.com, the WebAuthn ceremonies are allowed to come from.",
+				},
+				"user_verification": {
+					Type:        framework.TypeBool,
+					Default:     false,
+					Description: "Require the authenticators to verify the user, with a PIN or biometrics, in addition to testing their presence.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathMFAMethodRead(mfaMethodTypeWebAuthn),
+				logical.UpdateOperation: i.pathMFAMethodWebAuthnUpdate,
+				logical.DeleteOperation: i.pathMFAMethodDelete(mfaMethodTypeWebAuthn),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["webauthn"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["webauthn"][1]),
+		},
+		{
+			Pattern: "mfa/method/webauthn/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathMFAMethodList(mfaMethodTypeWebAuthn),
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["webauthn-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["webauthn-list"][1]),
+		},
+		{
+			Pattern: "mfa/method/webauthn/" + framework.GenericNameRegex("name") + "/register$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"client_data_json": {
+					Type:        framework.TypeString,
+					Description: "Base64url encoded client data of the credential creation. Omit it to start the registration.",
+				},
+				"attestation_object": {
+					Type:        framework.TypeString,
+					Description: "Base64url encoded attestation object of the credential creation. Omit it to start the registration.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFAMethodWebAuthnRegister,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["webauthn-register"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["webauthn-register"][1]),
+		},
+		{
+			Pattern: "mfa/method/webauthn/" + framework.GenericNameRegex("name") + "/admin-destroy$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity the WebAuthn credentials are destroyed for.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFAMethodWebAuthnAdminDestroy,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["webauthn-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["webauthn-admin-destroy"][1]),
+		},
+		{
+			Pattern: "mfa/login-enforcement/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the login enforcement.",
+				},
+				"mfa_method_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the MFA methods, one of which validates the logins.",
+				},
+				"auth_method_accessors": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accessors of the auth mounts the enforcement applies to.",
+				},
+				"auth_method_types": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Types of the auth methods the enforcement applies to.",
+				},
+				"identity_entity_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the entities the enforcement applies to.",
+				},
+				"identity_group_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the groups whose members the enforcement applies to.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathMFALoginEnforcementRead,
+				logical.UpdateOperation: i.pathMFALoginEnforcementUpdate,
+				logical.DeleteOperation: i.pathMFALoginEnforcementDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["login-enforcement"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["login-enforcement"][1]),
+		},
+		{
+			Pattern: "mfa/login-enforcement/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathMFALoginEnforcementList,
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["login-enforcement-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["login-enforcement-list"][1]),
+		},
+	}
+}
+
+// mfaMethod returns the configuration of the MFA method, or nil if it
+// doesn't exist. The caller must hold mfaLock.
+func (i *IdentityStore) mfaMethod(ctx context.Context, s logical.Storage, name string) (*mfa.Config, error) {
+	entry, err := s.Get(ctx, mfaMethodPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config mfa.Config
+	if err := proto.Unmarshal(entry.Value, &config); err != nil {
+		return nil, errwrap.Wrapf("failed to decode MFA method: {{err}}", err)
+	}
+	return &config, nil
+}
+
+func (i *IdentityStore) putMFAMethod(ctx context.Context, s logical.Storage, config *mfa.Config) error {
+	value, err := proto.Marshal(config)
+	if err != nil {
+		return errwrap.Wrapf("failed to encode MFA method: {{err}}", err)
+	}
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   mfaMethodPrefix + config.Name,
+		Value: value,
+	})
+}
+
+// mfaLoginEnforcements returns the login enforcements. The caller must hold
+// mfaLock.
+func (i *IdentityStore) mfaLoginEnforcements(ctx context.Context, s logical.Storage) ([]*mfaLoginEnforcement, error) {
+	names, err := s.List(ctx, mfaLoginEnforcementPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var enforcements []*mfaLoginEnforcement
+	for _, name := range names {
+		enforcement, err := i.mfaLoginEnforcement(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+		if enforcement != nil {
+			enforcements = append(enforcements, enforcement)
+		}
+	}
+	return enforcements, nil
+}
+
+func (i *IdentityStore) mfaLoginEnforcement(ctx context.Context, s logical.Storage, name string) (*mfaLoginEnforcement, error) {
+	entry, err := s.Get(ctx, mfaLoginEnforcementPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var enforcement mfaLoginEnforcement
+	if err := entry.DecodeJSON(&enforcement); err != nil {
+		return nil, err
+	}
+	return &enforcement, nil
+}
+
+// existingMFAMethod returns the configuration of an MFA method being
+// updated, or a new configuration of the given type
+func (i *IdentityStore) existingMFAMethod(ctx context.Context, s logical.Storage, name, methodType string) (*mfa.Config, *logical.Response, error) {
+	config, err := i.mfaMethod(ctx, s, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config == nil {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &mfa.Config{
+			Type: methodType,
+			Name: name,
+			ID:   id,
+		}, nil, nil
+	}
+	if config.Type != methodType {
+		return nil, logical.ErrorResponse("MFA method %q is of type %q", name, config.Type), nil
+	}
+	return config, nil, nil
+}
+
+func (i *IdentityStore) pathMFAMethodTOTPUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.mfaLock.Lock()
+	defer i.mfaLock.Unlock()
+
+	config, resp, err := i.existingMFAMethod(ctx, req.Storage, name, mfaMethodTypeTOTP)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	totpConfig := config.GetTOTPConfig()
+	newConfig := totpConfig == nil
+	if newConfig {
+		totpConfig = &mfa.TOTPConfig{}
+	}
+
+	if issuer, ok := mfaMethodField(d, "issuer", newConfig); ok {
+		totpConfig.Issuer = issuer.(string)
+	}
+	if totpConfig.Issuer == "" {
+		return logical.ErrorResponse("issuer must be set"), nil
+	}
+
+	if period, ok := mfaMethodField(d, "period", newConfig); ok {
+		if period.(int) <= 0 {
+			return logical.ErrorResponse("period must be greater than zero"), nil
+		}
+		totpConfig.Period = uint32(period.(int))
+	}
+
+	if keySize, ok := mfaMethodField(d, "key_size", newConfig); ok {
+		if keySize.(int) <= 0 {
+			return logical.ErrorResponse("key_size must be greater than zero"), nil
+		}
+		totpConfig.KeySize = uint32(keySize.(int))
+	}
+
+	if qrSize, ok := mfaMethodField(d, "qr_size", newConfig); ok {
+		if qrSize.(int) < 0 {
+			return logical.ErrorResponse("qr_size cannot be negative"), nil
+		}
+		totpConfig.QRSize = int32(qrSize.(int))
+	}
+
+	if algorithm, ok := mfaMethodField(d, "algorithm", newConfig); ok {
+		switch algorithm.(string) {
+		case "SHA1":
+			totpConfig.Algorithm = int32(otplib.AlgorithmSHA1)
+		case "SHA256":
+			totpConfig.Algorithm = int32(otplib.AlgorithmSHA256)
+		case "SHA512":
+			totpConfig.Algorithm = int32(otplib.AlgorithmSHA512)
+		default:
+			return logical.ErrorResponse("algorithm must be SHA1, SHA256 or SHA512"), nil
+		}
+	}
+
+	if digits, ok := mfaMethodField(d, "digits", newConfig); ok {
+		switch digits.(int) {
+		case 6:
+			totpConfig.Digits = int32(otplib.DigitsSix)
+		case 8:
+			totpConfig.Digits = int32(otplib.DigitsEight)
+		default:
+			return logical.ErrorResponse("digits must be 6 or 8"), nil
+		}
+	}
+
+	if skew, ok := mfaMethodField(d, "skew", newConfig); ok {
+		if skew.(int) != 0 && skew.(int) != 1 {
+			return logical.ErrorResponse("skew must be 0 or 1"), nil
+		}
+		totpConfig.Skew = uint32(skew.(int))
+	}
+
+	config.Config = &mfa.Config_TOTPConfig{TOTPConfig: totpConfig}
+	if err := i.putMFAMethod(ctx, req.Storage, config); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) pathMFAMethodDuoUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.mfaLock.Lock()
+	defer i.mfaLock.Unlock()
+
+	config, resp, err := i.existingMFAMethod(ctx, req.Storage, name, mfaMethodTypeDuo)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	duoConfig := config.GetDuoConfig()
+	newConfig := duoConfig == nil
+	if newConfig {
+		duoConfig = &mfa.DuoConfig{}
+	}
+
+	if mountAccessor, ok := d.GetOk("mount_accessor"); ok {
+		config.MountAccessor = mountAccessor.(string)
+	}
+	if config.MountAccessor != "" && i.core.router.MatchingMountByAccessor(config.MountAccessor) == nil {
+		return logical.ErrorResponse("invalid mount accessor %q", config.MountAccessor), nil
+	}
+
+	if usernameFormat, ok := mfaMethodField(d, "username_format", newConfig); ok {
+		config.UsernameFormat = usernameFormat.(string)
+	}
+	if strings.Count(config.UsernameFormat, "%s") != 1 {
+		return logical.ErrorResponse("username_format must contain exactly one %%s"), nil
+	}
+
+	if integrationKey, ok := d.GetOk("integration_key"); ok {
+		duoConfig.IntegrationKey = integrationKey.(string)
+	}
+	if secretKey, ok := d.GetOk("secret_key"); ok {
+		duoConfig.SecretKey = secretKey.(string)
+	}
+	if apiHostname, ok := d.GetOk("api_hostname"); ok {
+		duoConfig.APIHostname = apiHostname.(string)
+	}
+	if pushInfo, ok := d.GetOk("push_info"); ok {
+		duoConfig.PushInfo = pushInfo.(string)
+	}
+	if duoConfig.IntegrationKey == "" || duoConfig.SecretKey == "" || duoConfig.APIHostname == "" {
+		return logical.ErrorResponse("integration_key, secret_key and api_hostname must be set"), nil
+	}
+
+	config.Config = &mfa.Config_DuoConfig{DuoConfig: duoConfig}
+	if err := i.putMFAMethod(ctx, req.Storage, config); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) pathMFAMethodWebAuthnUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.mfaLock.Lock()
+	defer i.mfaLock.Unlock()
+
+	config, resp, err := i.existingMFAMethod(ctx, req.Storage, name, mfaMethodTypeWebAuthn)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	webAuthnConfig := config.GetWebAuthnConfig()
+	newConfig := webAuthnConfig == nil
+	if newConfig {
+		webAuthnConfig = &mfa.WebAuthnConfig{}
+	}
+
+	if rpID, ok := d.GetOk("relying_party_id"); ok {
+		// The credentials are scoped to the relying party ID, so changing it
+		// would invalidate the registered ones
+		if !newConfig && rpID.(string) != webAuthnConfig.RelyingPartyID {
+			return logical.ErrorResponse("relying_party_id cannot be changed"), nil
+		}
+		webAuthnConfig.RelyingPartyID = rpID.(string)
+	}
+	if webAuthnConfig.RelyingPartyID == "" {
+		return logical.ErrorResponse("relying_party_id must be set"), nil
+	}
+
+	if rpName, ok := d.GetOk("relying_party_name"); ok {
+		webAuthnConfig.RelyingPartyName = rpName.(string)
+	}
+	if webAuthnConfig.RelyingPartyName == "" {
+		webAuthnConfig.RelyingPartyName = webAuthnConfig.RelyingPartyID
+	}
+
+	if origins, ok := d.GetOk("origins"); ok {
+		webAuthnConfig.Origins = origins.([]string)
+	}
+	if len(webAuthnConfig.Origins) == 0 {
+		return logical.ErrorResponse("origins must be set"), nil
+	}
+	for _, origin := range webAuthnConfig.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return logical.ErrorResponse("invalid origin %q, expected a scheme and a host", origin), nil
+		}
+	}
+
+	if userVerification, ok := mfaMethodField(d, "user_verification", newConfig); ok {
+		webAuthnConfig.UserVerification = userVerification.(bool)
+	}
+
+	config.Config = &mfa.Config_WebAuthnConfig{WebAuthnConfig: webAuthnConfig}
+	if err := i.putMFAMethod(ctx, req.Storage, config); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) pathMFAMethodRead(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		i.mfaLock.RLock()
+		defer i.mfaLock.RUnlock()
+
+		config, err := i.mfaMethod(ctx, req.Storage, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if config == nil || config.Type != methodType {
+			return nil, nil
+		}
+
+		data := map[string]interface{}{
+			"id":   config.ID,
+			"name": config.Name,
+			"type": config.Type,
+		}
+		switch methodType {
+		case mfaMethodTypeTOTP:
+			totpConfig := config.GetTOTPConfig()
+			data["issuer"] = totpConfig.Issuer
+			data["period"] = int64(totpConfig.Period)
+			data["key_size"] = int64(totpConfig.KeySize)
+			data["qr_size"] = int64(totpConfig.QRSize)
+			data["algorithm"] = otplib.Algorithm(totpConfig.Algorithm).String()
+			data["digits"] = int64(totpConfig.Digits)
+			data["skew"] = int64(totpConfig.Skew)
+		case mfaMethodTypeDuo:
+			duoConfig := config.GetDuoConfig()
+			data["mount_accessor"] = config.MountAccessor
+			data["username_format"] = config.UsernameFormat
+			data["integration_key"] = duoConfig.IntegrationKey
+			data["api_hostname"] = duoConfig.APIHostname
+			data["push_info"] = duoConfig.PushInfo
+		case mfaMethodTypeWebAuthn:
+			webAuthnConfig := config.GetWebAuthnConfig()
+			data["relying_party_id"] = webAuthnConfig.RelyingPartyID
+			data["relying_party_name"] = webAuthnConfig.RelyingPartyName
+			data["origins"] = webAuthnConfig.Origins
+			data["user_verification"] = webAuthnConfig.UserVerification
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (i *IdentityStore) pathMFAMethodList(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		i.mfaLock.RLock()
+		defer i.mfaLock.RUnlock()
+
+		names, err := req.Storage.List(ctx, mfaMethodPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		for _, name := range names {
+			config, err := i.mfaMethod(ctx, req.Storage, name)
+			if err != nil {
+				return nil, err
+			}
+			if config != nil && config.Type == methodType {
+				keys = append(keys, name)
+			}
+		}
+		return logical.ListResponse(keys), nil
+	}
+}
+
+func (i *IdentityStore) pathMFAMethodDelete(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		i.mfaLock.Lock()
+		defer i.mfaLock.Unlock()
+
+		config, err := i.mfaMethod(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil || config.Type != methodType {
+			return nil, nil
+		}
+
+		// It is an error to delete a method that is used by a login
+		// enforcement
+		enforcements, err := i.mfaLoginEnforcements(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		var enforcementNames []string
+		for _, enforcement := range enforcements {
+			if strutil.StrListContains(enforcement.MFAMethodNames, name) {
+				enforcementNames = append(enforcementNames, enforcement.Name)
+			}
+		}
+		if len(enforcementNames) > 0 {
+			return logical.ErrorResponse("unable to delete MFA method %q because it is used by the login enforcements: %s", name, strings.Join(enforcementNames, ", ")), logical.ErrInvalidRequest
+		}
+
+		return nil, req.Storage.Delete(ctx, mfaMethodPrefix+name)
+	}
+}
+
+// pathMFAMethodTOTPGenerate generates a TOTP secret for the entity of the
+// requester
+func (i *IdentityStore) pathMFAMethodTOTPGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("the token of the request has no entity"), logical.ErrInvalidRequest
+	}
+	return i.generateTOTPSecret(ctx, req, d.Get("name").(string), req.EntityID)
+}
+
+// pathMFAMethodTOTPAdminGenerate generates a TOTP secret for the given entity
+func (i *IdentityStore) pathMFAMethodTOTPAdminGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), logical.ErrInvalidRequest
+	}
+	return i.generateTOTPSecret(ctx, req, d.Get("name").(string), entityID)
+}
+
+func (i *IdentityStore) generateTOTPSecret(ctx context.Context, req *logical.Request, name, entityID string) (*logical.Response, error) {
+	i.mfaLock.RLock()
+	config, err := i.mfaMethod(ctx, req.Storage, name)
+	i.mfaLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Type != mfaMethodTypeTOTP {
+		return logical.ErrorResponse("TOTP MFA method %q not found", name), logical.ErrInvalidRequest
+	}
+	totpConfig := config.GetTOTPConfig()
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.MemDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+	if entity.MFASecrets[config.ID] != nil {
+		return logical.ErrorResponse("the entity already has a TOTP secret for MFA method %q", name), logical.ErrInvalidRequest
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpConfig.Issuer,
+		AccountName: entity.Name,
+		Period:      uint(totpConfig.Period),
+		SecretSize:  uint(totpConfig.KeySize),
+		Digits:      otplib.Digits(totpConfig.Digits),
+		Algorithm:   otplib.Algorithm(totpConfig.Algorithm),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate TOTP key: {{err}}", err)
+	}
+
+	if entity.MFASecrets == nil {
+		entity.MFASecrets = make(map[string]*mfa.Secret)
+	}
+	entity.MFASecrets[config.ID] = &mfa.Secret{
+		MethodName: name,
+		Value: &mfa.Secret_TOTPSecret{
+			TOTPSecret: &mfa.TOTPSecret{
+				Issuer:      totpConfig.Issuer,
+				Period:      totpConfig.Period,
+				Algorithm:   totpConfig.Algorithm,
+				Digits:      totpConfig.Digits,
+				Skew:        totpConfig.Skew,
+				KeySize:     totpConfig.KeySize,
+				AccountName: entity.Name,
+				Key:         key.Secret(),
+			},
+		},
+	}
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"url": key.String(),
+		},
+	}
+	if totpConfig.QRSize > 0 {
+		barcode, err := key.Image(int(totpConfig.QRSize), int(totpConfig.QRSize))
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to generate QR code image: {{err}}", err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, barcode); err != nil {
+			return nil, err
+		}
+		resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return resp, nil
+}
+
+// pathMFAMethodTOTPAdminDestroy removes the TOTP secret of the given entity
+func (i *IdentityStore) pathMFAMethodTOTPAdminDestroy(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), logical.ErrInvalidRequest
+	}
+
+	i.mfaLock.RLock()
+	config, err := i.mfaMethod(ctx, req.Storage, name)
+	i.mfaLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Type != mfaMethodTypeTOTP {
+		return logical.ErrorResponse("TOTP MFA method %q not found", name), logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.MemDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+	if entity.MFASecrets[config.ID] == nil {
+		return nil, nil
+	}
+
+	delete(entity.MFASecrets, config.ID)
+	return nil, i.upsertEntity(ctx, entity, nil, true)
+}
+
+// pathMFAMethodWebAuthnRegister registers a WebAuthn credential for the entity
+// of the requester. The registration is started without the response of the
+// authenticator, and returns the options of the credential creation along
+// with its challenge.
+func (i *IdentityStore) pathMFAMethodWebAuthnRegister(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("the token of the request has no entity"), logical.ErrInvalidRequest
+	}
+	name := d.Get("name").(string)
+
+	i.mfaLock.RLock()
+	config, err := i.mfaMethod(ctx, req.Storage, name)
+	i.mfaLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Type != mfaMethodTypeWebAuthn {
+		return logical.ErrorResponse("WebAuthn MFA method %q not found", name), logical.ErrInvalidRequest
+	}
+	webAuthnConfig := config.GetWebAuthnConfig()
+	challengeKey := config.ID + "/" + req.EntityID
+
+	clientDataRaw := d.Get("client_data_json").(string)
+	attestationRaw := d.Get("attestation_object").(string)
+	if clientDataRaw == "" && attestationRaw == "" {
+		return i.startWebAuthnRegistration(config, req.EntityID, challengeKey)
+	}
+	if clientDataRaw == "" || attestationRaw == "" {
+		return logical.ErrorResponse("client_data_json and attestation_object must both be set"), logical.ErrInvalidRequest
+	}
+	clientDataJSON, err := webauthn.DecodeBase64(clientDataRaw)
+	if err != nil {
+		return logical.ErrorResponse("invalid client_data_json: %s", err), logical.ErrInvalidRequest
+	}
+	attestation, err := webauthn.DecodeBase64(attestationRaw)
+	if err != nil {
+		return logical.ErrorResponse("invalid attestation_object: %s", err), logical.ErrInvalidRequest
+	}
+
+	challenge := i.core.takeWebAuthnChallenge(challengeKey)
+	if challenge == nil {
+		return logical.ErrorResponse("no pending registration for MFA method %q, or it expired", name), logical.ErrInvalidRequest
+	}
+	credential, err := webauthn.VerifyRegistration(webAuthnOptions(webAuthnConfig), challenge, clientDataJSON, attestation)
+	if err != nil {
+		return logical.ErrorResponse("failed to verify the registration: %s", err), logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.MemDBEntityByID(req.EntityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+	secret := entity.MFASecrets[config.ID].GetWebAuthnSecret()
+	if secret == nil {
+		secret = &mfa.WebAuthnSecret{}
+	}
+	for _, existing := range secret.Credentials {
+		if bytes.Equal(existing.ID, credential.ID) {
+			return logical.ErrorResponse("the credential is already registered"), logical.ErrInvalidRequest
+		}
+	}
+	secret.Credentials = append(secret.Credentials, &mfa.WebAuthnCredential{
+		ID:        credential.ID,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	})
+
+	if entity.MFASecrets == nil {
+		entity.MFASecrets = make(map[string]*mfa.Secret)
+	}
+	entity.MFASecrets[config.ID] = &mfa.Secret{
+		MethodName: name,
+		Value: &mfa.Secret_WebAuthnSecret{
+			WebAuthnSecret: secret,
+		},
+	}
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"credential_id": webauthn.EncodeBase64(credential.ID),
+		},
+	}, nil
+}
+
+func (i *IdentityStore) startWebAuthnRegistration(config *mfa.Config, entityID, challengeKey string) (*logical.Response, error) {
+	webAuthnConfig := config.GetWebAuthnConfig()
+
+	entity, err := i.MemDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+
+	// Authenticators refuse to create a second credential for the method
+	excludeCredentials := []string{}
+	for _, credential := range entity.MFASecrets[config.ID].GetWebAuthnSecret().GetCredentials() {
+		excludeCredentials = append(excludeCredentials, webauthn.EncodeBase64(credential.ID))
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	i.core.storeWebAuthnChallenge(challengeKey, challenge)
+
+	userVerification := "preferred"
+	if webAuthnConfig.UserVerification {
+		userVerification = "required"
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"challenge":           webauthn.EncodeBase64(challenge),
+			"relying_party_id":    webAuthnConfig.RelyingPartyID,
+			"relying_party_name":  webAuthnConfig.RelyingPartyName,
+			"user_id":             webauthn.EncodeBase64([]byte(entity.ID)),
+			"user_name":           entity.Name,
+			"algorithms":          webauthn.Algorithms,
+			"exclude_credentials": excludeCredentials,
+			"user_verification":   userVerification,
+			"timeout":             int64(loginMFARequestTTL / time.Millisecond),
+		},
+	}, nil
+}
+
+// pathMFAMethodWebAuthnAdminDestroy removes the WebAuthn credentials of the
+// given entity
+func (i *IdentityStore) pathMFAMethodWebAuthnAdminDestroy(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), logical.ErrInvalidRequest
+	}
+
+	i.mfaLock.RLock()
+	config, err := i.mfaMethod(ctx, req.Storage, name)
+	i.mfaLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Type != mfaMethodTypeWebAuthn {
+		return logical.ErrorResponse("WebAuthn MFA method %q not found", name), logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.MemDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+	if entity.MFASecrets[config.ID] == nil {
+		return nil, nil
+	}
+
+	delete(entity.MFASecrets, config.ID)
+	return nil, i.upsertEntity(ctx, entity, nil, true)
+}
+
+func (i *IdentityStore) pathMFALoginEnforcementUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.mfaLock.Lock()
+	defer i.mfaLock.Unlock()
+
+	enforcement, err := i.mfaLoginEnforcement(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if enforcement == nil {
+		enforcement = &mfaLoginEnforcement{
+			Name: name,
+		}
+	}
+
+	if methodNames, ok := d.GetOk("mfa_method_names"); ok {
+		enforcement.MFAMethodNames = strutil.RemoveDuplicates(methodNames.([]string), false)
+	}
+	if len(enforcement.MFAMethodNames) == 0 {
+		return logical.ErrorResponse("mfa_method_names must be set"), nil
+	}
+	for _, methodName := range enforcement.MFAMethodNames {
+		config, err := i.mfaMethod(ctx, req.Storage, methodName)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return logical.ErrorResponse("MFA method %q not found", methodName), nil
+		}
+	}
+
+	if accessors, ok := d.GetOk("auth_method_accessors"); ok {
+		enforcement.AuthMethodAccessors = strutil.RemoveDuplicates(accessors.([]string), false)
+	}
+	for _, accessor := range enforcement.AuthMethodAccessors {
+		if i.core.router.MatchingMountByAccessor(accessor) == nil {
+			return logical.ErrorResponse("invalid auth method accessor %q", accessor), nil
+		}
+	}
+	if types, ok := d.GetOk("auth_method_types"); ok {
+		enforcement.AuthMethodTypes = strutil.RemoveDuplicates(types.([]string), false)
+	}
+	if entityIDs, ok := d.GetOk("identity_entity_ids"); ok {
+		enforcement.IdentityEntityIDs = strutil.RemoveDuplicates(entityIDs.([]string), false)
+	}
+	if groupIDs, ok := d.GetOk("identity_group_ids"); ok {
+		enforcement.IdentityGroupIDs = strutil.RemoveDuplicates(groupIDs.([]string), false)
+	}
+	if len(enforcement.AuthMethodAccessors) == 0 && len(enforcement.AuthMethodTypes) == 0 &&
+		len(enforcement.IdentityEntityIDs) == 0 && len(enforcement.IdentityGroupIDs) == 0 {
+		return logical.ErrorResponse("one of auth_method_accessors, auth_method_types, identity_entity_ids or identity_group_ids must be set"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(mfaLoginEnforcementPrefix+name, enforcement)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (i *IdentityStore) pathMFALoginEnforcementRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.mfaLock.RLock()
+	defer i.mfaLock.RUnlock()
+
+	enforcement, err := i.mfaLoginEnforcement(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if enforcement == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"name":                  enforcement.Name,
+		"mfa_method_names":      enforcement.MFAMethodNames,
+		"auth_method_accessors": enforcement.AuthMethodAccessors,
+		"auth_method_types":     enforcement.AuthMethodTypes,
+		"identity_entity_ids":   enforcement.IdentityEntityIDs,
+		"identity_group_ids":    enforcement.IdentityGroupIDs,
+	}
+	for key, value := range data {
+		if list, ok := value.([]string); ok && list == nil {
+			data[key] = []string{}
+		}
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (i *IdentityStore) pathMFALoginEnforcementList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.mfaLock.RLock()
+	defer i.mfaLock.RUnlock()
+
+	names, err := req.Storage.List(ctx, mfaLoginEnforcementPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+func (i *IdentityStore) pathMFALoginEnforcementDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.mfaLock.Lock()
+	defer i.mfaLock.Unlock()
+
+	return nil, req.Storage.Delete(ctx, mfaLoginEnforcementPrefix+d.Get("name").(string))
+}
+
+// entityAliasName returns the name of the alias of the entity on the mount
+func entityAliasName(entity *identity.Entity, mountAccessor string) string {
+	for _, alias := range entity.Aliases {
+		if alias.MountAccessor == mountAccessor {
+			return alias.Name
+		}
+	}
+	return ""
+}
+
+var mfaHelp = map[string][2]string{
+	"totp": {
+		"Configure a TOTP MFA method.",
+		`
+The TOTP secrets of the entities are generated with the 'generate' and
+'admin-generate' endpoints, using the configuration of the method. The
+passcodes of the secrets are validated with the options they were generated
+with.
+		`,
+	},
+	"totp-list": {
+		"List the TOTP MFA methods.",
+		"",
+	},
+	"totp-generate": {
+		"Generate a TOTP secret for the entity of the requester.",
+		`
+The response contains the otpauth URL of the secret and, unless qr_size is 0,
+a base64 encoded PNG image of its QR code. An entity can only have one secret
+per method.
+		`,
+	},
+	"totp-admin-generate": {
+		"Generate a TOTP secret for an entity.",
+		`
+The response contains the otpauth URL of the secret and, unless qr_size is 0,
+a base64 encoded PNG image of its QR code. An entity can only have one secret
+per method.
+		`,
+	},
+	"totp-admin-destroy": {
+		"Destroy the TOTP secret of an entity.",
+		"",
+	},
+	"duo": {
+		"Configure a Duo MFA method.",
+		`
+The Duo username of an entity is the name of its alias on mount_accessor, or
+the name of the alias of the login, formatted with username_format. Without a
+passcode, a push notification is sent to the devices of the user.
+		`,
+	},
+	"duo-list": {
+		"List the Duo MFA methods.",
+		"",
+	},
+	"webauthn": {
+		"Configure a WebAuthn MFA method.",
+		`
+The entities register the credentials of their authenticators, such as security
+keys or platform authenticators, with the 'register' endpoint. The credentials
+are scoped to relying_party_id, and the ceremonies must come from one of the
+origins. Attestation statements are not verified.
+		`,
+	},
+	"webauthn-list": {
+		"List the WebAuthn MFA methods.",
+		"",
+	},
+	"webauthn-register": {
+		"Register a WebAuthn credential for the entity of the requester.",
+		`
+Without client_data_json and attestation_object, the registration is started
+and the response contains the options of the credential creation, including its
+challenge. The response of the authenticator is then sent within 5 minutes to
+complete the registration. An entity can register several credentials per
+method.
+		`,
+	},
+	"webauthn-admin-destroy": {
+		"Destroy the WebAuthn credentials of an entity.",
+		"",
+	},
+	"login-enforcement": {
+		"Require MFA on the logins matching the enforcement.",
+		`
+The logins on the listed auth mounts or auth method types, and the logins of
+the listed entities or of the members of the listed groups, must be validated
+by one of the MFA methods of the enforcement. When several enforcements apply
+to a login, all of them must be validated.
+		`,
+	},
+	"login-enforcement-list": {
+		"List the login MFA enforcements.",
+		"",
+	},
+}
+
+// mfaMethodField returns the value of a field of an MFA method update. Unset
+// fields keep their value, and get their default when the method is created.
+func mfaMethodField(d *framework.FieldData, key string, newConfig bool) (interface{}, bool) {
+	if raw, ok := d.GetOk(key); ok {
+		return raw, true
+	}
+	return d.Get(key), newConfig
+}
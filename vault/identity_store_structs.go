@@ -55,6 +55,9 @@ type IdentityStore struct {
 	lock     sync.RWMutex
 	oidcLock sync.RWMutex
 
+	// mfaLock is used to protect the MFA methods and login enforcements
+	mfaLock sync.RWMutex
+
 	// groupLock is used to protect modifications to group entries
 	groupLock sync.RWMutex
 
@@ -113,6 +113,10 @@ vault <command> <path> metadata=key1=value1 metadata=key2=value2
 					Type:        framework.TypeString,
 					Description: "Name of the alias",
 				},
+				"custom_metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Custom metadata to be associated with the alias",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.handleAliasCreateUpdate(),
@@ -145,6 +149,10 @@ vault <command> <path> metadata=key1=value1 metadata=key2=value2
 					Type:        framework.TypeString,
 					Description: "Name of the alias",
 				},
+				"custom_metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Custom metadata to be associated with the alias",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.handleAliasCreateUpdate(),
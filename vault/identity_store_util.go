@@ -1981,6 +1981,23 @@ func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(ctx context.Co
 
 			group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entityID)
 
+			// Groups created by the sync of their mount are pruned along
+			// with their alias once their last member leaves
+			if len(group.MemberEntityIDs) == 0 && i.externalGroupSyncPrunable(group) {
+				i.logger.Debug("deleting synced external group without members", "group_id", group.ID)
+
+				if err := i.MemDBDeleteAliasByIDInTxn(txn, group.Alias.ID, true); err != nil {
+					return false, nil, err
+				}
+				if err := i.MemDBDeleteGroupByIDInTxn(txn, group.ID); err != nil {
+					return false, nil, err
+				}
+				if err := i.groupPacker.DeleteItem(ctx, group.ID); err != nil {
+					return false, nil, err
+				}
+				continue
+			}
+
 			err = i.UpsertGroupInTxn(ctx, txn, group, true)
 			if err != nil {
 				return false, nil, err
@@ -2014,6 +2031,102 @@ func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(ctx context.Co
 	return validAliases, nil
 }
 
+// syncExternalGroups creates an external group, with a group alias, for each
+// of the group aliases returned by the login that don't map to a group yet,
+// when the auth mount they come from has the external group sync enabled.
+// The memberships of the entity are then reconciled by
+// refreshExternalGroupMembershipsByEntityID.
+func (i *IdentityStore) syncExternalGroups(ctx context.Context, groupAliases []*logical.Alias) error {
+	defer metrics.MeasureSince([]string{"identity", "sync_external_groups"}, time.Now())
+
+	var locked bool
+	defer func() {
+		if locked {
+			i.groupLock.Unlock()
+		}
+	}()
+
+	for _, alias := range groupAliases {
+		mountEntry := i.core.router.MatchingMountByAccessor(alias.MountAccessor)
+		if mountEntry == nil || mountEntry.Local || !mountEntry.Config.ExternalGroupSync {
+			continue
+		}
+
+		// Skip the lock entirely in the common case where all the groups
+		// already exist
+		existing, err := i.MemDBAliasByFactors(alias.MountAccessor, alias.Name, false, true)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+
+		if !locked {
+			i.groupLock.Lock()
+			locked = true
+
+			// Check again now that concurrent logins can't create the group
+			existing, err = i.MemDBAliasByFactors(alias.MountAccessor, alias.Name, false, true)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				continue
+			}
+		}
+
+		nsCtx := namespace.ContextWithNamespace(ctx, mountEntry.Namespace())
+
+		// Name the group after the alias unless the name is taken, in which
+		// case a name is generated
+		name := alias.Name
+		group, err := i.MemDBGroupByName(nsCtx, name, false)
+		if err != nil {
+			return err
+		}
+		if group != nil {
+			name = ""
+		}
+
+		group = &identity.Group{
+			Name: name,
+			Type: groupTypeExternal,
+			Metadata: map[string]string{
+				externalGroupSyncMetadataKey: externalGroupSyncMetadataValue,
+			},
+			Alias: &identity.Alias{
+				Name:          alias.Name,
+				MountAccessor: alias.MountAccessor,
+				NamespaceID:   mountEntry.NamespaceID,
+				CreationTime:  ptypes.TimestampNow(),
+			},
+		}
+		group.Alias.LastUpdateTime = group.Alias.CreationTime
+
+		i.logger.Debug("creating synced external group", "group_alias", alias.Name, "mount_accessor", alias.MountAccessor)
+
+		if err := i.sanitizeAndUpsertGroup(nsCtx, group, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// externalGroupSyncPrunable returns whether the group was created by the
+// external group sync of a mount that still has it enabled
+func (i *IdentityStore) externalGroupSyncPrunable(group *identity.Group) bool {
+	if group.Type != groupTypeExternal || group.Alias == nil {
+		return false
+	}
+	if group.Metadata[externalGroupSyncMetadataKey] != externalGroupSyncMetadataValue {
+		return false
+	}
+	mountEntry := i.core.router.MatchingMountByAccessor(group.Alias.MountAccessor)
+	return mountEntry != nil && mountEntry.Config.ExternalGroupSync
+}
+
 // diffGroups is used to diff two sets of groups
 func diffGroups(old, new []*identity.Group) *groupDiff {
 	diff := &groupDiff{}
@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"time"
+)
+
+// drainPollInterval is how often drainInFlightRequests checks whether the
+// requests being drained completed.
+var drainPollInterval = 50 * time.Millisecond
+
+// InFlightRequest describes a request being handled by one of the HTTP
+// listeners of the node.
+type InFlightRequest struct {
+	StartTime        time.Time
+	ClientRemoteAddr string
+	ReqPath          string
+	ReqMethod        string
+}
+
+// StoreInFlightRequest records a request being handled until it is finalized
+// by FinalizeInFlightRequest.
+func (c *Core) StoreInFlightRequest(id string, req InFlightRequest) {
+	c.inFlightRequests.Store(id, req)
+}
+
+// FinalizeInFlightRequest removes a request recorded by StoreInFlightRequest.
+func (c *Core) FinalizeInFlightRequest(id string) {
+	c.inFlightRequests.Delete(id)
+}
+
+// InFlightRequests returns the requests being handled by the node, keyed by
+// request ID.
+func (c *Core) InFlightRequests() map[string]InFlightRequest {
+	result := make(map[string]InFlightRequest)
+	c.inFlightRequests.Range(func(k, v interface{}) bool {
+		result[k.(string)] = v.(InFlightRequest)
+		return true
+	})
+	return result
+}
+
+// InFlightRequestsResponse returns the requests being handled by the node in
+// the format of the sys/in-flight-req endpoint.
+func (c *Core) InFlightRequestsResponse() map[string]interface{} {
+	data := make(map[string]interface{})
+	for id, req := range c.InFlightRequests() {
+		data[id] = map[string]interface{}{
+			"start_time":            req.StartTime.Format(time.RFC3339Nano),
+			"client_remote_address": req.ClientRemoteAddr,
+			"request_path":          req.ReqPath,
+			"request_method":        req.ReqMethod,
+		}
+	}
+	return data
+}
+
+// drainInFlightRequests waits until the requests being handled when it's
+// called complete, for at most the drain timeout of the core. The requests
+// received while draining aren't waited for.
+func (c *Core) drainInFlightRequests() {
+	if c.drainTimeout <= 0 {
+		return
+	}
+
+	pending := make(map[string]struct{})
+	c.inFlightRequests.Range(func(k, _ interface{}) bool {
+		pending[k.(string)] = struct{}{}
+		return true
+	})
+	if len(pending) == 0 {
+		return
+	}
+
+	c.logger.Info("draining in-flight requests", "requests", len(pending), "timeout", c.drainTimeout)
+
+	deadline := time.NewTimer(c.drainTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for id := range pending {
+			if _, ok := c.inFlightRequests.Load(id); !ok {
+				delete(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			c.logger.Info("in-flight requests drained")
+			return
+		}
+
+		select {
+		case <-deadline.C:
+			c.logger.Warn("drain timeout reached, abandoning in-flight requests", "requests", len(pending))
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCore_DrainInFlightRequests(t *testing.T) {
+	c := TestCore(t)
+	c.drainTimeout = 5 * time.Second
+
+	c.StoreInFlightRequest("slow", InFlightRequest{StartTime: time.Now(), ReqPath: "/v1/secret/foo"})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		// Requests received while draining aren't waited for
+		c.StoreInFlightRequest("new", InFlightRequest{StartTime: time.Now(), ReqPath: "/v1/secret/bar"})
+		time.Sleep(200 * time.Millisecond)
+		c.FinalizeInFlightRequest("slow")
+	}()
+
+	start := time.Now()
+	c.drainInFlightRequests()
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed >= c.drainTimeout {
+		t.Fatalf("expected the drain to wait for the in-flight request, took %s", elapsed)
+	}
+	if _, ok := c.InFlightRequests()["new"]; !ok {
+		t.Fatal("expected the new request to be in flight")
+	}
+
+	// The drain gives up at the timeout
+	c.drainTimeout = 200 * time.Millisecond
+	start = time.Now()
+	c.drainInFlightRequests()
+	if elapsed := time.Since(start); elapsed < c.drainTimeout || elapsed >= time.Second {
+		t.Fatalf("expected the drain to time out, took %s", elapsed)
+	}
+}
@@ -10,6 +10,21 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 )
 
+const (
+	// keyRotationMaxOperations is the default and maximum number of
+	// encryptions done with a key before it is rotated: about 90% of 2^32, the
+	// limit that NIST sets for AES-GCM with random nonces.
+	keyRotationMaxOperations int64 = 3865470566
+
+	// keyRotationMinOperations is the minimum number of encryptions done with
+	// a key before it is rotated.
+	keyRotationMinOperations int64 = 1000000
+
+	// keyRotationMinInterval is the minimum age of a key before it is
+	// rotated.
+	keyRotationMinInterval = 24 * time.Hour
+)
+
 // Keyring is used to manage multiple encryption keys used by
 // the barrier. New keys can be installed and each has a sequential term.
 // The term used to encrypt a key is prefixed to the key written out.
@@ -19,15 +34,17 @@ import (
 // when a new key is added to the keyring, we can encrypt with the master key
 // and write out the new keyring.
 type Keyring struct {
-	masterKey  []byte
-	keys       map[uint32]*Key
-	activeTerm uint32
+	masterKey      []byte
+	keys           map[uint32]*Key
+	activeTerm     uint32
+	rotationConfig KeyRotationConfig
 }
 
 // EncodedKeyring is used for serialization of the keyring
 type EncodedKeyring struct {
-	MasterKey []byte
-	Keys      []*Key
+	MasterKey      []byte
+	Keys           []*Key
+	RotationConfig KeyRotationConfig
 }
 
 // Key represents a single term, along with the key used.
@@ -36,6 +53,36 @@ type Key struct {
 	Version     int
 	Value       []byte
 	InstallTime time.Time
+
+	// Encryptions is the estimated number of encryptions done with the key
+	Encryptions uint64
+}
+
+// KeyRotationConfig is the policy for automatically rotating the active key
+// of the keyring. A key is rotated once it has been used for MaxOperations
+// encryptions or, if Interval is set, once it is older than Interval.
+type KeyRotationConfig struct {
+	Disabled      bool
+	MaxOperations int64
+	Interval      time.Duration
+}
+
+// Sanitize sets the default maximum number of operations if none is set
+func (c *KeyRotationConfig) Sanitize() {
+	if c.MaxOperations == 0 {
+		c.MaxOperations = keyRotationMaxOperations
+	}
+}
+
+// Validate checks that the policy is within the safe bounds of AES-GCM
+func (c *KeyRotationConfig) Validate() error {
+	switch {
+	case c.MaxOperations < keyRotationMinOperations || c.MaxOperations > keyRotationMaxOperations:
+		return fmt.Errorf("max_operations must be between %d and %d", keyRotationMinOperations, keyRotationMaxOperations)
+	case c.Interval != 0 && c.Interval < keyRotationMinInterval:
+		return fmt.Errorf("interval must be 0 or at least %s", keyRotationMinInterval)
+	}
+	return nil
 }
 
 // Serialize is used to create a byte encoded key
@@ -57,6 +104,9 @@ func NewKeyring() *Keyring {
 	k := &Keyring{
 		keys:       make(map[uint32]*Key),
 		activeTerm: 0,
+		rotationConfig: KeyRotationConfig{
+			MaxOperations: keyRotationMaxOperations,
+		},
 	}
 	return k
 }
@@ -64,9 +114,10 @@ func NewKeyring() *Keyring {
 // Clone returns a new copy of the keyring
 func (k *Keyring) Clone() *Keyring {
 	clone := &Keyring{
-		masterKey:  k.masterKey,
-		keys:       make(map[uint32]*Key, len(k.keys)),
-		activeTerm: k.activeTerm,
+		masterKey:      k.masterKey,
+		keys:           make(map[uint32]*Key, len(k.keys)),
+		activeTerm:     k.activeTerm,
+		rotationConfig: k.rotationConfig,
 	}
 	for idx, key := range k.keys {
 		clone.keys[idx] = key
@@ -135,6 +186,35 @@ func (k *Keyring) TermKey(term uint32) *Key {
 	return k.keys[term]
 }
 
+// AddEncryptions returns a copy of the keyring in which the estimated number of
+// encryptions of the key of the given term is increased by count
+func (k *Keyring) AddEncryptions(term uint32, count uint64) *Keyring {
+	key, ok := k.keys[term]
+	if !ok || count == 0 {
+		return k
+	}
+
+	// Keys are shared between clones, so the key is copied
+	keyCopy := *key
+	keyCopy.Encryptions += count
+	clone := k.Clone()
+	clone.keys[term] = &keyCopy
+	return clone
+}
+
+// RotationConfig returns the policy for rotating the active key
+func (k *Keyring) RotationConfig() KeyRotationConfig {
+	return k.rotationConfig
+}
+
+// SetRotationConfig returns a copy of the keyring with the given policy for
+// rotating the active key
+func (k *Keyring) SetRotationConfig(config KeyRotationConfig) *Keyring {
+	clone := k.Clone()
+	clone.rotationConfig = config
+	return clone
+}
+
 // SetMasterKey is used to update the master key
 func (k *Keyring) SetMasterKey(val []byte) *Keyring {
 	valCopy := make([]byte, len(val))
@@ -153,7 +233,8 @@ func (k *Keyring) MasterKey() []byte {
 func (k *Keyring) Serialize() ([]byte, error) {
 	// Create the encoded entry
 	enc := EncodedKeyring{
-		MasterKey: k.masterKey,
+		MasterKey:      k.masterKey,
+		RotationConfig: k.rotationConfig,
 	}
 	for _, key := range k.keys {
 		enc.Keys = append(enc.Keys, key)
@@ -175,6 +256,8 @@ func DeserializeKeyring(buf []byte) (*Keyring, error) {
 	// Create a new keyring
 	k := NewKeyring()
 	k.masterKey = enc.MasterKey
+	k.rotationConfig = enc.RotationConfig
+	k.rotationConfig.Sanitize()
 	for _, key := range enc.Keys {
 		k.keys[key.Term] = key
 		if key.Term > k.activeTerm {
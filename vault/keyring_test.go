@@ -143,6 +143,11 @@ func TestKeyring_Serialize(t *testing.T) {
 	testSecond := []byte("second")
 	k, _ = k.AddKey(&Key{Term: 1, Version: 1, Value: testKey, InstallTime: now})
 	k, _ = k.AddKey(&Key{Term: 2, Version: 1, Value: testSecond, InstallTime: now})
+	k = k.AddEncryptions(1, 10)
+	k = k.SetRotationConfig(KeyRotationConfig{
+		MaxOperations: keyRotationMinOperations,
+		Interval:      keyRotationMinInterval,
+	})
 
 	buf, err := k.Serialize()
 	if err != nil {
@@ -163,6 +168,13 @@ func TestKeyring_Serialize(t *testing.T) {
 		t.Fatalf("Term mismatch")
 	}
 
+	if k2.RotationConfig() != k.RotationConfig() {
+		t.Fatalf("bad: rotation config: %#v", k2.RotationConfig())
+	}
+	if k2.TermKey(1).Encryptions != 10 {
+		t.Fatalf("bad: encryptions: %d", k2.TermKey(1).Encryptions)
+	}
+
 	var i uint32
 	for i = 1; i < k.ActiveTerm(); i++ {
 		key1 := k2.TermKey(i)
@@ -206,3 +218,20 @@ func TestKey_Serialize(t *testing.T) {
 		t.Fatalf("bad: %#v", out)
 	}
 }
+
+func TestKeyring_AddEncryptions(t *testing.T) {
+	k := NewKeyring()
+	k, _ = k.AddKey(&Key{Term: 1, Version: 1, Value: []byte("testing")})
+	clone := k.Clone()
+
+	k = k.AddEncryptions(1, 5)
+	k = k.AddEncryptions(1, 3)
+	if k.ActiveKey().Encryptions != 8 {
+		t.Fatalf("bad: encryptions: %d", k.ActiveKey().Encryptions)
+	}
+
+	// The keys of other keyrings are left untouched
+	if clone.ActiveKey().Encryptions != 0 {
+		t.Fatalf("bad: encryptions: %d", clone.ActiveKey().Encryptions)
+	}
+}
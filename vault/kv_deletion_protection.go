@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// kvDeletionProtectionOption is the mount option that enables deletion
+	// protection on a version 2 KV mount
+	kvDeletionProtectionOption = "deletion_protection"
+
+	// kvDestroyConfirmationTTL is how long a destroy confirmation handed out
+	// on a protected mount remains valid
+	kvDestroyConfirmationTTL = 5 * time.Minute
+)
+
+// checkKVDestroyConfirmation enforces deletion protection on version 2 KV
+// mounts. Requests that permanently remove secret data, i.e. destroying
+// versions and deleting a key's metadata, must be made twice: the first
+// request isn't routed to the backend and returns a confirmation that has to
+// be passed as the confirm parameter of the second request. A non-nil
+// response means that the request must not be routed.
+func (c *Core) checkKVDestroyConfirmation(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil || entry.Type != "kv" || entry.Options["version"] != "2" {
+		return nil, nil
+	}
+	if protect, _ := parseutil.ParseBool(entry.Options[kvDeletionProtectionOption]); !protect {
+		return nil, nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mount := c.router.MatchingMount(ctx, req.Path)
+	relPath := strings.TrimPrefix(ns.Path+req.Path, mount)
+
+	switch {
+	case req.Operation == logical.UpdateOperation && strings.HasPrefix(relPath, "destroy/"):
+	case req.Operation == logical.DeleteOperation && strings.HasPrefix(relPath, "metadata/"):
+	default:
+		return nil, nil
+	}
+
+	// The confirmation can only be used by the same token, for the same
+	// operation on the same path
+	binding := fmt.Sprintf("%s:%s:%s:%s", ns.ID, req.Operation, req.Path, req.ClientTokenAccessor)
+
+	confirmRaw, ok := req.Data["confirm"]
+	if !ok {
+		confirm, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		c.kvDestroyConfirmations.Set(confirm, binding, kvDestroyConfirmationTTL)
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"confirm":    confirm,
+				"expires_in": int64(kvDestroyConfirmationTTL.Seconds()),
+			},
+		}
+		resp.AddWarning(fmt.Sprintf("Deletion protection is enabled on mount %q; repeat the request with the confirm parameter set to the returned value to proceed", mount))
+		return resp, nil
+	}
+
+	confirm, ok := confirmRaw.(string)
+	if !ok {
+		return logical.ErrorResponse("confirm must be a string"), logical.ErrInvalidRequest
+	}
+	expected, ok := c.kvDestroyConfirmations.Get(confirm)
+	if !ok || expected.(string) != binding {
+		return logical.ErrorResponse("invalid or expired confirm parameter"), logical.ErrInvalidRequest
+	}
+	c.kvDestroyConfirmations.Delete(confirm)
+
+	// The backend doesn't know about the parameter
+	delete(req.Data, "confirm")
+	return nil, nil
+}
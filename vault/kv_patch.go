@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// routeKVPatch applies a patch request to a secret in a version 2 KV mount
+// whose backend doesn't handle the patch operation itself. The current
+// version of the secret is read, the patch is applied to its data with JSON
+// merge patch semantics and the result is written back with check-and-set, so
+// a write that lands between the read and the write fails the patch instead of
+// being silently overwritten.
+func (c *Core) routeKVPatch(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil || entry.Type != "kv" || entry.Options["version"] != "2" {
+		return nil, logical.ErrUnsupportedOperation
+	}
+	mount := c.router.MatchingMount(ctx, req.Path)
+	if !strings.HasPrefix(strings.TrimPrefix(ns.Path+req.Path, mount), "data/") {
+		return nil, logical.ErrUnsupportedOperation
+	}
+
+	patch, ok := req.Data["data"].(map[string]interface{})
+	if !ok {
+		return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+	}
+
+	readResp, err := c.router.Route(ctx, kvPatchSubRequest(req, logical.ReadOperation, nil))
+	if err != nil {
+		return readResp, err
+	}
+	if readResp == nil || readResp.Data == nil {
+		return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no value found at %s", req.Path))
+	}
+	current, ok := readResp.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no value found at %s", req.Path))
+	}
+	metadata, ok := readResp.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no metadata found at %s", req.Path)
+	}
+
+	var version int
+	if err := mapstructure.WeakDecode(metadata["version"], &version); err != nil {
+		return nil, fmt.Errorf("invalid version found at %s: %w", req.Path, err)
+	}
+
+	// The client can ask for its own check-and-set on top of the one used to
+	// apply the patch, to make sure it patches the version it has seen
+	if options, ok := req.Data["options"].(map[string]interface{}); ok {
+		if casRaw, ok := options["cas"]; ok {
+			var cas int
+			if err := mapstructure.WeakDecode(casRaw, &cas); err != nil {
+				return logical.ErrorResponse("error parsing check-and-set parameter"), logical.ErrInvalidRequest
+			}
+			if cas != version {
+				return logical.ErrorResponse("check-and-set parameter did not match the current version"), logical.ErrInvalidRequest
+			}
+		}
+	}
+
+	return c.router.Route(ctx, kvPatchSubRequest(req, logical.UpdateOperation, map[string]interface{}{
+		"data": jsonMergePatch(current, patch),
+		"options": map[string]interface{}{
+			"cas": version,
+		},
+	}))
+}
+
+// kvPatchSubRequest returns a request for the given operation on the path of
+// the patch request, made on behalf of the same client
+func kvPatchSubRequest(req *logical.Request, op logical.Operation, data map[string]interface{}) *logical.Request {
+	subReq := &logical.Request{
+		ID:                       req.ID,
+		Operation:                op,
+		Path:                     req.Path,
+		Data:                     data,
+		ClientToken:              req.ClientToken,
+		ClientTokenAccessor:      req.ClientTokenAccessor,
+		ClientTokenRemainingUses: req.ClientTokenRemainingUses,
+		DisplayName:              req.DisplayName,
+		EntityID:                 req.EntityID,
+		Connection:               req.Connection,
+		Headers:                  req.Headers,
+	}
+	subReq.SetTokenEntry(req.TokenEntry())
+	return subReq
+}
+
+// jsonMergePatch returns the result of applying the patch to the target as
+// described in RFC 7386: objects are merged recursively, null values remove
+// the key and any other value replaces the target's value
+func jsonMergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, _ := target.(map[string]interface{})
+	result := make(map[string]interface{}, len(targetMap)+len(patchMap))
+	for k, v := range targetMap {
+		result[k] = v
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = jsonMergePatch(result[k], v)
+	}
+	return result
+}
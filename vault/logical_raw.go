@@ -3,14 +3,22 @@ package vault
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/compressutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+const (
+	// coreRawConfigPath is the path used to store the configuration of the
+	// raw endpoint
+	coreRawConfigPath = "core/raw-config"
+)
+
 var (
 	// protectedPaths cannot be accessed via the raw APIs.
 	// This is both for security and to prevent disrupting Vault.
@@ -29,6 +37,12 @@ type RawBackend struct {
 	recoveryMode bool
 }
 
+// rawConfig is the configuration of the raw endpoint
+type rawConfig struct {
+	// AllowDestructive must be set before writes and deletes are accepted
+	AllowDestructive bool `json:"allow_destructive"`
+}
+
 func NewRawBackend(core *Core) *RawBackend {
 	r := &RawBackend{
 		barrier: core.barrier,
@@ -112,6 +126,10 @@ func (b *RawBackend) handleRawWrite(ctx context.Context, req *logical.Request, d
 		}
 	}
 
+	if err := b.checkDestructive(ctx); err != nil {
+		return logical.ErrorResponse("cannot write '%s': %s", path, err), logical.ErrInvalidRequest
+	}
+
 	value := data.Get("value").(string)
 	entry := &logical.StorageEntry{
 		Key:   path,
@@ -139,6 +157,10 @@ func (b *RawBackend) handleRawDelete(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	if err := b.checkDestructive(ctx); err != nil {
+		return logical.ErrorResponse("cannot delete '%s': %s", path, err), logical.ErrInvalidRequest
+	}
+
 	if err := b.barrier.Delete(ctx, path); err != nil {
 		return handleErrorNoReadOnlyForward(err)
 	}
@@ -170,11 +192,175 @@ func (b *RawBackend) handleRawList(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse("cannot list '%s'", path), logical.ErrInvalidRequest
 	}
 
-	keys, err := b.barrier.List(ctx, path)
+	pageToken := data.Get("page_token").(string)
+	limit := data.Get("limit").(int)
+
+	var keys []string
+	var err error
+	if data.Get("recursive").(bool) {
+		keys, err = b.listRecursive(ctx, path, pageToken, limit)
+		if err != nil {
+			return handleErrorNoReadOnlyForward(err)
+		}
+	} else {
+		keys, err = b.barrier.List(ctx, path)
+		if err != nil {
+			return handleErrorNoReadOnlyForward(err)
+		}
+	}
+	sort.Strings(keys)
+
+	// Skip the keys returned by the previous pages
+	if pageToken != "" {
+		keys = keys[sort.SearchStrings(keys, pageToken):]
+		if len(keys) > 0 && keys[0] == pageToken {
+			keys = keys[1:]
+		}
+	}
+
+	var nextPageToken string
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+		nextPageToken = keys[limit-1]
+	}
+
+	resp := logical.ListResponse(keys)
+	if nextPageToken != "" {
+		resp.Data["next_page_token"] = nextPageToken
+	}
+	return resp, nil
+}
+
+// listRecursive returns the keys under the path that can be read through the
+// raw endpoint, in order. Only the keys after pageToken are returned, and the
+// walk stops once one more key than the limit was found, so that paging
+// through a large tree doesn't load all of its keys on every request.
+func (b *RawBackend) listRecursive(ctx context.Context, path, pageToken string, limit int) ([]string, error) {
+	var keys []string
+
+	// Listing the folders in order, and each folder before its next entry,
+	// visits the keys in lexical order
+	var walk func(prefix string) (bool, error)
+	walk = func(prefix string) (bool, error) {
+		entries, err := b.barrier.List(ctx, path+prefix)
+		if err != nil {
+			return false, errwrap.Wrapf(fmt.Sprintf("list failed at path %q: {{err}}", path+prefix), err)
+		}
+		sort.Strings(entries)
+
+	ENTRIES:
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+
+			key := prefix + entry
+			for _, p := range protectedPaths {
+				if strings.HasPrefix(path+key, p) {
+					continue ENTRIES
+				}
+			}
+
+			if strings.HasSuffix(entry, "/") {
+				// Skip the folders returned entirely by the previous pages
+				if key < pageToken && !strings.HasPrefix(pageToken, key) {
+					continue
+				}
+				done, err := walk(key)
+				if err != nil || done {
+					return done, err
+				}
+				continue
+			}
+
+			if key <= pageToken {
+				continue
+			}
+			if err := b.checkRaw(path + key); err != nil {
+				continue
+			}
+			keys = append(keys, key)
+			if limit > 0 && len(keys) > limit {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if _, err := walk(""); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// checkDestructive returns an error if the raw endpoint isn't configured to
+// accept writes and deletes. Recovery mode is meant for storage surgery, so
+// they are always allowed there.
+func (b *RawBackend) checkDestructive(ctx context.Context) error {
+	if b.recoveryMode {
+		return nil
+	}
+
+	config, err := b.rawConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.AllowDestructive {
+		return fmt.Errorf("destructive operations are disabled, set allow_destructive on sys/config/raw to enable them")
+	}
+	return nil
+}
+
+// rawConfig returns the stored configuration of the raw endpoint
+func (b *RawBackend) rawConfig(ctx context.Context) (*rawConfig, error) {
+	config := &rawConfig{}
+	entry, err := b.barrier.Get(ctx, coreRawConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// handleRawConfigRead returns the configuration of the raw endpoint
+func (b *RawBackend) handleRawConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.rawConfig(ctx)
+	if err != nil {
+		return handleErrorNoReadOnlyForward(err)
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allow_destructive": config.AllowDestructive,
+		},
+	}, nil
+}
+
+// handleRawConfigUpdate updates the configuration of the raw endpoint
+func (b *RawBackend) handleRawConfigUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.rawConfig(ctx)
+	if err != nil {
+		return handleErrorNoReadOnlyForward(err)
+	}
+	if allowDestructive, ok := data.GetOk("allow_destructive"); ok {
+		config.AllowDestructive = allowDestructive.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(coreRawConfigPath, config)
 	if err != nil {
+		return nil, err
+	}
+	if err := b.barrier.Put(ctx, entry); err != nil {
 		return handleErrorNoReadOnlyForward(err)
 	}
-	return logical.ListResponse(keys), nil
+
+	if config.AllowDestructive {
+		b.logger.Warn("destructive operations enabled on the raw endpoint")
+	}
+	return nil, nil
 }
 
 func rawPaths(prefix string, r *RawBackend) []*framework.Path {
@@ -189,6 +375,18 @@ func rawPaths(prefix string, r *RawBackend) []*framework.Path {
 				"value": &framework.FieldSchema{
 					Type: framework.TypeString,
 				},
+				"recursive": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "Whether to list all the keys under the given path prefix instead of its direct children.",
+				},
+				"limit": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "The maximum number of keys to return when listing. Defaults to no limit.",
+				},
+				"page_token": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The next_page_token returned by a previous list request, to resume listing after it.",
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -215,3 +413,32 @@ func rawPaths(prefix string, r *RawBackend) []*framework.Path {
 		},
 	}
 }
+
+func rawConfigPaths(prefix string, r *RawBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: prefix + "config/raw$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"allow_destructive": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "Allow writes and deletes through the raw endpoint.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: r.handleRawConfigRead,
+					Summary:  "Return the configuration of the raw endpoint.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: r.handleRawConfigUpdate,
+					Summary:  "Configure the raw endpoint.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raw-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raw-config"][1]),
+		},
+	}
+}
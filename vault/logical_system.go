@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -91,15 +92,22 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"replication/dr/reindex",
 				"replication/performance/reindex",
 				"rotate",
+				"rotate/config",
+				"seal/migrate",
 				"config/cors",
 				"config/auditing/*",
 				"config/ui/headers/*",
+				"config/raw",
+				"config/control-group",
 				"plugins/catalog/*",
+				"plugins/upgrade/backend",
 				"revoke-prefix/*",
 				"revoke-force/*",
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"leases/irrevocable",
+				"in-flight-req",
 			},
 
 			Unauthenticated: []string{
@@ -134,6 +142,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"rekey-recovery-key/init",
 				"rekey-recovery-key/update",
 				"rekey-recovery-key/verify",
+				"mfa/validate",
 			},
 
 			LocalStorage: []string{
@@ -149,6 +158,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.pluginsCatalogListPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.pluginsCatalogCRUDPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.pluginsReloadPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.pluginsUpgradePath())
 	b.Backend.Paths = append(b.Backend.Paths, b.auditPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.mountPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.authPaths()...)
@@ -163,7 +173,12 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.metricsPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.monitorPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.hostInfoPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.inFlightRequestPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.namespacesPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.controlGroupPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.mfaValidatePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.lockedUsersPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, b.rawPaths()...)
@@ -192,7 +207,7 @@ func (b *SystemBackend) rawPaths() []*framework.Path {
 			return checkRaw(b, path)
 		},
 	}
-	return rawPaths("", r)
+	return append(rawPaths("", r), rawConfigPaths("", r)...)
 }
 
 // SystemBackend implements logical.Backend and is used to interact with
@@ -354,7 +369,15 @@ func (b *SystemBackend) handlePluginCatalogUpdate(ctx context.Context, req *logi
 		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
 	}
 
-	err = b.Core.pluginCatalog.Set(ctx, pluginName, pluginType, parts[0], args, env, sha256Bytes)
+	pluginVersion, err := parsePluginVersion(d.Get("version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if pluginVersion != "" && pluginType == consts.PluginTypeUnknown {
+		return logical.ErrorResponse("missing type of the versioned plugin"), nil
+	}
+
+	err = b.Core.pluginCatalog.Set(ctx, pluginName, pluginType, pluginVersion, parts[0], args, env, sha256Bytes)
 	if err != nil {
 		return nil, err
 	}
@@ -383,7 +406,12 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		return nil, err
 	}
 
-	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginType)
+	pluginVersion, err := parsePluginVersion(d.Get("version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginType, pluginVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -391,6 +419,11 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		return nil, nil
 	}
 
+	versions, err := b.Core.pluginCatalog.Versions(ctx, pluginName, pluginType)
+	if err != nil {
+		return nil, err
+	}
+
 	command := ""
 	if !plugin.Builtin {
 		command, err = filepath.Rel(b.Core.pluginCatalog.directory, plugin.Command)
@@ -406,6 +439,12 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		"sha256":  hex.EncodeToString(plugin.Sha256),
 		"builtin": plugin.Builtin,
 	}
+	if plugin.Version != "" {
+		data["version"] = plugin.Version
+	}
+	if len(versions) > 0 {
+		data["versions"] = versions
+	}
 
 	return &logical.Response{
 		Data: data,
@@ -434,7 +473,11 @@ func (b *SystemBackend) handlePluginCatalogDelete(ctx context.Context, req *logi
 	if err != nil {
 		return nil, err
 	}
-	if err := b.Core.pluginCatalog.Delete(ctx, pluginName, pluginType); err != nil {
+	pluginVersion, err := parsePluginVersion(d.Get("version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := b.Core.pluginCatalog.Delete(ctx, pluginName, pluginType, pluginVersion); err != nil {
 		return nil, err
 	}
 
@@ -485,6 +528,37 @@ func (b *SystemBackend) handlePluginReloadUpdate(ctx context.Context, req *logic
 	return &r, nil
 }
 
+// handlePluginUpgradeUpdate pins the mounts of a plugin to another version of
+// it and reloads them
+func (b *SystemBackend) handlePluginUpgradeUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("plugin").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), nil
+	}
+	if d.Get("version").(string) == "" {
+		return logical.ErrorResponse("missing plugin version"), nil
+	}
+	pluginVersion, err := parsePluginVersion(d.Get("version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	paths, err := b.Core.upgradeMatchingPlugin(ctx, pluginName, pluginVersion, d.Get("mounts").([]string))
+	if err != nil {
+		if _, ok := err.(logical.HTTPCodedError); ok {
+			return handleError(err)
+		}
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"mounts":  paths,
+			"version": pluginVersion,
+		},
+	}, nil
+}
+
 // handleAuditedHeaderUpdate creates or overwrites a header entry
 func (b *SystemBackend) handleAuditedHeaderUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	header := d.Get("header").(string)
@@ -699,6 +773,9 @@ func mountInfo(entry *MountEntry) map[string]interface{} {
 		"options":                 entry.Options,
 		"uuid":                    entry.UUID,
 	}
+	if entry.Version != "" {
+		info["plugin_version"] = entry.Version
+	}
 	entryConfig := map[string]interface{}{
 		"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
 		"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
@@ -723,6 +800,18 @@ func mountInfo(entry *MountEntry) map[string]interface{} {
 	}
 	if entry.Table == credentialTableType {
 		entryConfig["token_type"] = entry.Config.TokenType.String()
+		if entry.Config.ExternalGroupSync {
+			entryConfig["external_group_sync"] = true
+		}
+		if entry.Config.UserLockoutConfig != nil {
+			entryConfig["user_lockout_config"] = entry.Config.UserLockoutConfig.responseData()
+		}
+		if entry.Config.MinLeaseTTL != 0 {
+			entryConfig["min_lease_ttl"] = int64(entry.Config.MinLeaseTTL.Seconds())
+		}
+		if len(entry.Config.AllowedEntityAliases) > 0 {
+			entryConfig["allowed_entity_aliases"] = entry.Config.AllowedEntityAliases
+		}
 	}
 
 	info["config"] = entryConfig
@@ -908,6 +997,29 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 	if len(apiConfig.AllowedResponseHeaders) > 0 {
 		config.AllowedResponseHeaders = apiConfig.AllowedResponseHeaders
 	}
+	if apiConfig.ExternalGroupSync {
+		if local {
+			return logical.ErrorResponse("'external_group_sync' cannot be set for local auth mounts"), logical.ErrInvalidRequest
+		}
+		config.ExternalGroupSync = true
+	}
+
+	// A versioned plugin must be registered in the catalog
+	pluginVersion, err := parsePluginVersion(data.Get("plugin_version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if pluginVersion != "" {
+		plugin, err := b.Core.pluginCatalog.Get(ctx, logicalType, consts.PluginTypeSecrets, pluginVersion)
+		if err != nil {
+			return nil, err
+		}
+		if plugin == nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+					"plugin %q version %q not found in the plugin catalog", logicalType, pluginVersion)),
+				logical.ErrInvalidRequest
+		}
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -920,6 +1032,7 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 		SealWrap:              sealWrap,
 		ExternalEntropyAccess: externalEntropyAccess,
 		Options:               options,
+		Version:               pluginVersion,
 	}
 
 	// Attempt mount
@@ -1125,6 +1238,18 @@ func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (
 
 	if mountEntry.Table == credentialTableType {
 		resp.Data["token_type"] = mountEntry.Config.TokenType.String()
+		if mountEntry.Config.ExternalGroupSync {
+			resp.Data["external_group_sync"] = true
+		}
+		if mountEntry.Config.UserLockoutConfig != nil {
+			resp.Data["user_lockout_config"] = mountEntry.Config.UserLockoutConfig.responseData()
+		}
+		if mountEntry.Config.MinLeaseTTL != 0 {
+			resp.Data["min_lease_ttl"] = int64(mountEntry.Config.MinLeaseTTL.Seconds())
+		}
+		if len(mountEntry.Config.AllowedEntityAliases) > 0 {
+			resp.Data["allowed_entity_aliases"] = mountEntry.Config.AllowedEntityAliases
+		}
 	}
 
 	if rawVal, ok := mountEntry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
@@ -1404,6 +1529,97 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("external_group_sync"); ok {
+		if !strings.HasPrefix(path, "auth/") {
+			return logical.ErrorResponse("'external_group_sync' can only be modified on auth mounts"), logical.ErrInvalidRequest
+		}
+		if mountEntry.Local {
+			return logical.ErrorResponse("'external_group_sync' cannot be set for local auth mounts"), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.ExternalGroupSync
+		mountEntry.Config.ExternalGroupSync = rawVal.(bool)
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.ExternalGroupSync = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of external_group_sync successful", "path", path, "external_group_sync", mountEntry.Config.ExternalGroupSync)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("user_lockout_config"); ok {
+		if !strings.HasPrefix(path, "auth/") {
+			return logical.ErrorResponse("'user_lockout_config' can only be modified on auth mounts"), logical.ErrInvalidRequest
+		}
+		lockoutConfig, err := parseUserLockoutConfig(mountEntry.Type, mountEntry.Config.UserLockoutConfig, rawVal.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.UserLockoutConfig
+		mountEntry.Config.UserLockoutConfig = lockoutConfig
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.UserLockoutConfig = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of user_lockout_config successful", "path", path)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("min_lease_ttl"); ok {
+		if !strings.HasPrefix(path, "auth/") {
+			return logical.ErrorResponse("'min_lease_ttl' can only be modified on auth mounts"), logical.ErrInvalidRequest
+		}
+		minTTL := time.Duration(rawVal.(int)) * time.Second
+		maxTTL := mountEntry.Config.MaxLeaseTTL
+		if maxTTL == 0 {
+			maxTTL = b.Core.maxLeaseTTL
+		}
+		if minTTL < 0 || minTTL > maxTTL {
+			return logical.ErrorResponse("'min_lease_ttl' must be between 0 and the max lease TTL of the mount"), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.MinLeaseTTL
+		mountEntry.Config.MinLeaseTTL = minTTL
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.MinLeaseTTL = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of min_lease_ttl successful", "path", path, "min_lease_ttl", minTTL)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("allowed_entity_aliases"); ok {
+		if !strings.HasPrefix(path, "auth/") {
+			return logical.ErrorResponse("'allowed_entity_aliases' can only be modified on auth mounts"), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.AllowedEntityAliases
+		mountEntry.Config.AllowedEntityAliases = rawVal.([]string)
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.AllowedEntityAliases = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of allowed_entity_aliases successful", "path", path)
+		}
+	}
+
 	if rawVal, ok := data.GetOk("passthrough_request_headers"); ok {
 		headers := rawVal.([]string)
 
@@ -1500,6 +1716,16 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 			}
 		}
 
+		// Store deletion protection in its canonical form, since that's what
+		// is checked when routing requests
+		if v, ok := options[kvDeletionProtectionOption]; ok && len(v) > 0 {
+			protect, err := parseutil.ParseBool(v)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid %s provided: %q", kvDeletionProtectionOption, v)), logical.ErrInvalidRequest
+			}
+			options[kvDeletionProtectionOption] = strconv.FormatBool(protect)
+		}
+
 		// Upsert options value to a copy of the existing mountEntry's options
 		for k, v := range mountEntry.Options {
 			newOptions[k] = v
@@ -1602,6 +1828,28 @@ func (b *SystemBackend) handleLeaseLookupList(ctx context.Context, req *logical.
 	return logical.ListResponse(keys), nil
 }
 
+// handleLeaseIrrevocableList lists the irrevocable leases of the namespace
+func (b *SystemBackend) handleLeaseIrrevocableList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leases, err := b.Core.expiration.IrrevocableLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(leases))
+	keyInfo := make(map[string]interface{}, len(leases))
+	for leaseID, lease := range leases {
+		keys = append(keys, leaseID)
+		keyInfo[leaseID] = map[string]interface{}{
+			"path":        lease.path,
+			"error":       lease.revokeErr,
+			"expire_time": lease.expireTime,
+		}
+	}
+	sort.Strings(keys)
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
 // handleRenew is used to renew a lease with a given LeaseID
 func (b *SystemBackend) handleRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// Get all the options
@@ -1867,6 +2115,55 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 	if len(apiConfig.AllowedResponseHeaders) > 0 {
 		config.AllowedResponseHeaders = apiConfig.AllowedResponseHeaders
 	}
+	if apiConfig.ExternalGroupSync {
+		if local {
+			return logical.ErrorResponse("'external_group_sync' cannot be set for local auth mounts"), logical.ErrInvalidRequest
+		}
+		config.ExternalGroupSync = true
+	}
+	if len(apiConfig.UserLockoutConfig) > 0 {
+		lockoutConfig, err := parseUserLockoutConfig(logicalType, nil, apiConfig.UserLockoutConfig)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.UserLockoutConfig = lockoutConfig
+	}
+	if apiConfig.MinLeaseTTL != "" {
+		minTTL, err := parseutil.ParseDurationSecond(apiConfig.MinLeaseTTL)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+					"unable to parse min TTL of %s: %s", apiConfig.MinLeaseTTL, err)),
+				logical.ErrInvalidRequest
+		}
+		maxTTL := config.MaxLeaseTTL
+		if maxTTL == 0 {
+			maxTTL = b.Core.maxLeaseTTL
+		}
+		if minTTL < 0 || minTTL > maxTTL {
+			return logical.ErrorResponse("min TTL must be between 0 and the max TTL of the mount"), logical.ErrInvalidRequest
+		}
+		config.MinLeaseTTL = minTTL
+	}
+	if len(apiConfig.AllowedEntityAliases) > 0 {
+		config.AllowedEntityAliases = apiConfig.AllowedEntityAliases
+	}
+
+	// A versioned plugin must be registered in the catalog
+	pluginVersion, err := parsePluginVersion(data.Get("plugin_version").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if pluginVersion != "" {
+		plugin, err := b.Core.pluginCatalog.Get(ctx, logicalType, consts.PluginTypeCredential, pluginVersion)
+		if err != nil {
+			return nil, err
+		}
+		if plugin == nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+					"plugin %q version %q not found in the plugin catalog", logicalType, pluginVersion)),
+				logical.ErrInvalidRequest
+		}
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -1879,6 +2176,7 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 		SealWrap:              sealWrap,
 		ExternalEntropyAccess: externalEntropyAccess,
 		Options:               options,
+		Version:               pluginVersion,
 	}
 
 	// Attempt enabling
@@ -2104,8 +2402,10 @@ type passwordPolicyConfig struct {
 	HCLPolicy string `json:"policy"`
 }
 
+const passwordPolicySubPath = "password_policy/"
+
 func getPasswordPolicyKey(policyName string) string {
-	return fmt.Sprintf("password_policy/%s", policyName)
+	return passwordPolicySubPath + policyName
 }
 
 const (
@@ -2183,6 +2483,17 @@ func (*SystemBackend) handlePoliciesPasswordSet(ctx context.Context, req *logica
 	return logical.RespondWithStatusCode(nil, req, http.StatusNoContent)
 }
 
+// handlePoliciesPasswordList returns the names of the password policies
+func (*SystemBackend) handlePoliciesPasswordList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	keys, err := logical.CollectKeys(ctx, logical.NewStorageView(req.Storage, passwordPolicySubPath))
+	if err != nil {
+		return nil, logical.CodedError(http.StatusInternalServerError,
+			fmt.Sprintf("failed to list password policies: %s", err))
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
 // handlePoliciesPasswordGet retrieves a password policy if it exists
 func (*SystemBackend) handlePoliciesPasswordGet(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	policyName := data.Get("name").(string)
@@ -2279,6 +2590,11 @@ func (*SystemBackend) handlePoliciesPasswordGenerate(ctx context.Context, req *l
 
 // handleAuditTable handles the "audit" endpoint to provide the audit table
 func (b *SystemBackend) handleAuditTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	b.Core.auditLock.RLock()
 	defer b.Core.auditLock.RUnlock()
 
@@ -2286,6 +2602,11 @@ func (b *SystemBackend) handleAuditTable(ctx context.Context, req *logical.Reque
 		Data: make(map[string]interface{}),
 	}
 	for _, entry := range b.Core.audit.Entries {
+		// Only show entries for current namespace
+		if entry.Namespace().Path != ns.Path {
+			continue
+		}
+
 		info := map[string]interface{}{
 			"path":        entry.Path,
 			"type":        entry.Type,
@@ -2307,7 +2628,12 @@ func (b *SystemBackend) handleAuditHash(ctx context.Context, req *logical.Reques
 		return logical.ErrorResponse("the \"input\" parameter is empty"), nil
 	}
 
-	path = sanitizePath(path)
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path = ns.Path + sanitizePath(path)
 
 	hash, err := b.Core.auditBroker.GetHash(ctx, path, input)
 	if err != nil {
@@ -2321,6 +2647,101 @@ func (b *SystemBackend) handleAuditHash(ctx context.Context, req *logical.Reques
 	}, nil
 }
 
+// handleAuditTail streams audit entries to the client as they are logged,
+// one JSON object per line, until the client disconnects or Vault is sealed.
+func (b *SystemBackend) handleAuditTail(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	w := req.ResponseWriter
+	if w == nil {
+		return logical.ErrorResponse("streaming not supported"), nil
+	}
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return logical.ErrorResponse("streaming not supported"), nil
+	}
+
+	if standby, _ := b.Core.Standby(); standby {
+		return logical.ErrorResponse("audit entries can only be streamed from the active node"), nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &AuditTailFilter{
+		Type:       data.Get("type").(string),
+		PathPrefix: data.Get("path_prefix").(string),
+		ErrorsOnly: data.Get("errors_only").(bool),
+	}
+	switch filter.Type {
+	case "", "request", "response":
+	default:
+		return logical.ErrorResponse("type must be \"request\" or \"response\""), nil
+	}
+	for _, op := range data.Get("operation").([]string) {
+		filter.Operations = append(filter.Operations, logical.Operation(op))
+	}
+
+	var device string
+	if raw := data.Get("device").(string); raw != "" {
+		device = ns.Path + sanitizePath(raw)
+	}
+
+	broker := b.Core.auditBroker
+	if broker == nil {
+		return nil, consts.ErrSealed
+	}
+	sub, err := broker.Subscribe(ns, device, filter)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	defer broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// 0 byte write is needed before the Flush call so that if we are using
+	// a gzip stream it will go ahead and write out the HTTP response header
+	_, err = w.Write([]byte(""))
+	if err != nil {
+		return nil, fmt.Errorf("error seeding flusher: %w", err)
+	}
+
+	flusher.Flush()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	// Stream entries until the connection is closed.
+	for {
+		select {
+		// Periodically check for the seal status and report dropped entries.
+		case <-ticker.C:
+			// We still return the errors, but they will be ignored upstream
+			// due to the fact that we've already sent a response by writing
+			// the header and flushing the writer above.
+			if b.Core.Sealed() {
+				_, err = fmt.Fprintln(w, `{"type":"sealed"}`)
+				return nil, err
+			}
+			if dropped := sub.Dropped(); dropped > 0 {
+				if _, err = fmt.Fprintf(w, "{\"type\":\"dropped\",\"count\":%d}\n", dropped); err != nil {
+					return nil, fmt.Errorf("error streaming audit entries: %w", err)
+				}
+				flusher.Flush()
+			}
+		case <-ctx.Done():
+			return nil, nil
+		case entry := <-sub.Entries():
+			if _, err = w.Write(entry); err != nil {
+				return nil, fmt.Errorf("error streaming audit entries: %w", err)
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
 // handleEnableAudit is used to enable a new audit backend
 func (b *SystemBackend) handleEnableAudit(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.ReplicationState()
@@ -2480,11 +2901,43 @@ func (b *SystemBackend) handleKeyStatus(ctx context.Context, req *logical.Reques
 		Data: map[string]interface{}{
 			"term":         info.Term,
 			"install_time": info.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":  info.Encryptions,
 		},
 	}
 	return resp, nil
 }
 
+// handleSealMigrate migrates the keys to a new seal while Vault is unsealed
+func (b *SystemBackend) handleSealMigrate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var key []byte
+	if keyStr := data.Get("key").(string); keyStr != "" {
+		// Decode the key, which is base64 or hex encoded
+		min, max := b.Core.BarrierKeyLength()
+		var err error
+		key, err = hex.DecodeString(keyStr)
+		if err != nil || len(key) < min || len(key) > max {
+			key, err = base64.StdEncoding.DecodeString(keyStr)
+			if err != nil {
+				return logical.ErrorResponse("'key' must be a valid hex or base64 string"), logical.ErrInvalidRequest
+			}
+		}
+	}
+
+	status, err := b.Core.MigrateSealOnline(ctx, data.Get("type").(string), data.Get("config").(map[string]string), key, data.Get("reset").(bool))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"type":     status.Type,
+			"complete": status.Complete,
+			"progress": status.Progress,
+			"required": status.Required,
+		},
+	}, nil
+}
+
 // handleRotate is used to trigger a key rotation
 func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.ReplicationState()
@@ -2492,38 +2945,59 @@ func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
 	}
 
-	// Rotate to the new term
-	newTerm, err := b.Core.barrier.Rotate(ctx, b.Core.secureRandomReader)
-	if err != nil {
+	if _, err := b.Core.rotateBarrierKey(ctx); err != nil {
 		b.Backend.Logger().Error("failed to create new encryption key", "error", err)
 		return handleError(err)
 	}
-	b.Backend.Logger().Info("installed new encryption key")
 
-	// In HA mode, we need to an upgrade path for the standby instances
-	if b.Core.ha != nil {
-		// Create the upgrade path to the new term
-		if err := b.Core.barrier.CreateUpgrade(ctx, newTerm); err != nil {
-			b.Backend.Logger().Error("failed to create new upgrade", "term", newTerm, "error", err)
-		}
+	return nil, nil
+}
 
-		// Schedule the destroy of the upgrade path
-		time.AfterFunc(KeyRotateGracePeriod, func() {
-			b.Backend.Logger().Debug("cleaning up upgrade keys", "waited", KeyRotateGracePeriod)
-			if err := b.Core.barrier.DestroyUpgrade(b.Core.activeContext, newTerm); err != nil {
-				b.Backend.Logger().Error("failed to destroy upgrade", "term", newTerm, "error", err)
-			}
-		})
+// handleRotateConfigRead returns the policy for automatically rotating the
+// backend encryption key
+func (b *SystemBackend) handleRotateConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.barrier.RotationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":        !config.Disabled,
+			"max_operations": config.MaxOperations,
+			"interval":       config.Interval.String(),
+		},
+	}, nil
+}
+
+// handleRotateConfigUpdate updates the policy for automatically rotating the
+// backend encryption key
+func (b *SystemBackend) handleRotateConfigUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.ReplicationState()
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot configure rotation on a replication secondary"), nil
+	}
+
+	config, err := b.Core.barrier.RotationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Disabled = !enabledRaw.(bool)
+	}
+	if maxOperationsRaw, ok := data.GetOk("max_operations"); ok {
+		config.MaxOperations = int64(maxOperationsRaw.(int))
+	}
+	if intervalRaw, ok := data.GetOk("interval"); ok {
+		config.Interval = time.Duration(intervalRaw.(int)) * time.Second
+	}
+	if err := config.Validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	// Write to the canary path, which will force a synchronous truing during
-	// replication
-	if err := b.Core.barrier.Put(ctx, &logical.StorageEntry{
-		Key:   coreKeyringCanaryPath,
-		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
-	}); err != nil {
-		b.Core.logger.Error("error saving keyring canary", "error", err)
-		return nil, errwrap.Wrapf("failed to save keyring canary: {{err}}", err)
+	if err := b.Core.barrier.SetRotationConfig(ctx, config); err != nil {
+		return handleError(err)
 	}
 
 	return nil, nil
@@ -2725,7 +3199,7 @@ func (b *SystemBackend) handleMetrics(ctx context.Context, req *logical.Request,
 	if format == "" {
 		format = metricsutil.FormatFromRequest(req)
 	}
-	return b.Core.metricsHelper.ResponseForFormat(format), nil
+	return b.Core.MetricsResponse(format, data.Get("window").(string)), nil
 }
 
 func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -2809,6 +3283,14 @@ func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request,
 	}
 }
 
+// handleInFlightRequestData returns the requests being handled by the HTTP
+// listeners of this node, keyed by request ID.
+func (b *SystemBackend) handleInFlightRequestData(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: b.Core.InFlightRequestsResponse(),
+	}, nil
+}
+
 // handleHostInfo collects and returns host-related information, which includes
 // system information, cpu, disk, and memory usage. Any capture-related errors
 // returned by the collection method will be returned as response warnings.
@@ -3410,6 +3892,66 @@ func (b *SystemBackend) pathInternalCountersEntities(ctx context.Context, req *l
 	return resp, nil
 }
 
+func (b *SystemBackend) pathInternalCountersActivity(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	a := b.Core.activityLog
+	if a == nil {
+		return logical.ErrorResponse("activity log is not available on this node"), logical.ErrInvalidRequest
+	}
+
+	end := time.Now().UTC()
+	if raw, ok := d.GetOk("end_time"); ok {
+		end = raw.(time.Time)
+	}
+	// Default to the last twelve months, including the current one
+	start := activityMonthStart(end).AddDate(0, -11, 0)
+	if raw, ok := d.GetOk("start_time"); ok {
+		start = raw.(time.Time)
+	}
+	if start.After(end) {
+		return logical.ErrorResponse("start_time is after end_time"), logical.ErrInvalidRequest
+	}
+
+	format := d.Get("format").(string)
+	if format != "json" && format != "csv" {
+		return logical.ErrorResponse("unsupported format %q", format), logical.ErrInvalidRequest
+	}
+
+	summary, err := a.Summary(ctx, start, end, func(id string) string {
+		ns, err := NamespaceByID(ctx, id, b.Core)
+		if err != nil || ns == nil {
+			return ""
+		}
+		return ns.Path
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "csv" {
+		var buf bytes.Buffer
+		if err := summary.WriteCSV(&buf); err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: "text/csv",
+				logical.HTTPRawBody:     buf.Bytes(),
+				logical.HTTPStatusCode:  http.StatusOK,
+			},
+		}, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"start_time":   summary.StartTime,
+			"end_time":     summary.EndTime,
+			"total":        summary.Total,
+			"by_namespace": summary.Namespaces,
+			"months":       summary.Months,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) pathInternalUIResultantACL(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	if req.ClientToken == "" {
 		// 204 -- no ACL
@@ -3461,6 +4003,9 @@ func (b *SystemBackend) pathInternalUIResultantACL(ctx context.Context, req *log
 		if perms.CapabilitiesBitmap&ListCapabilityInt > 0 {
 			capabilities = append(capabilities, ListCapability)
 		}
+		if perms.CapabilitiesBitmap&PatchCapabilityInt > 0 {
+			capabilities = append(capabilities, PatchCapability)
+		}
 		if perms.CapabilitiesBitmap&ReadCapabilityInt > 0 {
 			capabilities = append(capabilities, ReadCapability)
 		}
@@ -3842,6 +4387,10 @@ in the plugin catalog.`,
 		`Whether to turn on seal wrapping for the mount.`,
 	},
 
+	"plugin_version": {
+		`The version of the external plugin to mount, as registered in the plugin catalog.`,
+	},
+
 	"external_entropy_access": {
 		`Whether to give the mount access to Vault's external entropy.`,
 	},
@@ -4075,6 +4624,15 @@ or delete a policy.
 		"",
 	},
 
+	"audit-tail": {
+		"Stream audit entries as they are logged.",
+		`
+Streams audit entries, as newline-delimited JSON, for requests made in the
+current namespace and its children. Values are hashed with the salt of the
+given audit device, or of the first device enabled in the namespace.
+		`,
+	},
+
 	"audit-table": {
 		"List the currently enabled audit backends.",
 		`
@@ -4121,7 +4679,8 @@ Enable a new audit backend or disable an existing backend.
 	"key-status": {
 		"Provides information about the backend encryption key.",
 		`
-		Provides the current backend encryption key term and installation time.
+		Provides the current backend encryption key term, installation time,
+		and estimated number of encryptions.
 		`,
 	},
 
@@ -4134,6 +4693,26 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"rotate-config": {
+		"Configures the automatic rotation of the backend encryption key.",
+		`
+		The backend encryption key is rotated automatically once it has been
+		used for the given number of encryptions or, if an interval is set,
+		once it is older than the interval.
+		`,
+	},
+
+	"seal-migrate": {
+		"Migrates the keys to a new seal while Vault is unsealed.",
+		`
+		Wraps the master key and the recovery key with a new seal on the active
+		node, while the cluster keeps serving requests. Migrating from a Shamir
+		seal requires a threshold of unseal key shares, which become the recovery
+		key shares. Each node must use the new seal in its configuration before
+		being restarted.
+		`,
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -4265,6 +4844,19 @@ plugin directory.`,
 Each entry is of the form "key=value".`,
 		"",
 	},
+	"plugin-catalog_version": {
+		`The semantic version of the plugin. Several versions of a plugin can be
+registered side by side, and mounts can be pinned to one of them.`,
+		"",
+	},
+	"leases-irrevocable": {
+		"List the leases whose revocation failed.",
+		`Vault retries the revocation of an expired lease with an exponential backoff.
+Once the maximum number of attempts is reached, the lease is marked
+irrevocable: it's no longer revoked automatically, and is listed here along
+with the last revocation error. The lease can be revoked again through
+sys/leases/revoke, or forcefully through sys/leases/revoke-force.`,
+	},
 	"leases": {
 		`View or list lease metadata.`,
 		`
@@ -4297,6 +4889,25 @@ This path responds to the following HTTP methods.
 		`The mount paths of the plugin backends to reload.`,
 		"",
 	},
+	"plugin-upgrade": {
+		"Upgrade mounts to another version of their plugin.",
+		`Pins the mounts running a plugin to another version of the plugin, as
+		registered in the plugin catalog, and reloads them. If the mounts are
+		provided, only these mounts are upgraded. If any mount fails to reload, all
+		the mounts are rolled back to their previous version.`,
+	},
+	"plugin-backend-upgrade-plugin": {
+		`The name of the plugin to upgrade, as registered in the plugin catalog.`,
+		"",
+	},
+	"plugin-backend-upgrade-version": {
+		`The version of the plugin to upgrade the mounts to.`,
+		"",
+	},
+	"plugin-backend-upgrade-mounts": {
+		`The mount paths of the plugin backends to upgrade. Defaults to all the mounts of the plugin.`,
+		"",
+	},
 	"hash": {
 		"Generate a hash sum for input data",
 		"Generates a hash sum of the given algorithm against the given input data.",
@@ -4321,9 +4932,37 @@ This path responds to the following HTTP methods.
 		"The type of token to issue (service or batch).",
 		"",
 	},
+	"external_group_sync": {
+		"Whether to create and prune external groups from the group aliases returned at login.",
+		"",
+	},
+	"min_lease_ttl": {
+		"The floor of the TTL of the tokens issued by the auth mount. Tokens requested with a lower TTL get this TTL, within the max lease TTL of the mount.",
+		"",
+	},
+	"allowed_entity_aliases": {
+		"The glob patterns of the entity alias names allowed to log in with the auth mount. Defaults to every alias.",
+		"",
+	},
+	"user_lockout_config": {
+		"The user lockout configuration, with the lockout_threshold, lockout_duration and lockout_counter_reset of the failed logins.",
+		"",
+	},
 	"raw": {
 		"Write, Read, and Delete data directly in the Storage backend.",
-		"",
+		`
+Writes and deletes are refused until they are allowed with the
+allow_destructive option of sys/config/raw, except in recovery mode.
+Listing accepts the "recursive" option to return every key under the
+prefix, and the "limit" and "page_token" options to page through them.
+		`,
+	},
+	"raw-config": {
+		"Configures the raw endpoint.",
+		`
+The allow_destructive option must be set before the raw endpoint accepts
+writes and deletes. Unset it once the storage surgery is done.
+		`,
 	},
 	"internal-ui-mounts": {
 		"Information about mounts returned according to their tuned visibility. Internal API; its location, inputs, and outputs may change.",
@@ -4355,10 +4994,23 @@ This path responds to the following HTTP methods.
 		"Count of active entities in this Vault cluster.",
 		"Count of active entities in this Vault cluster.",
 	},
+	"internal-counters-activity": {
+		"Count of distinct clients and requests by namespace and month.",
+		`Count of the distinct clients, entities and tokens without an entity, and of the
+		requests seen by this Vault cluster in each namespace, by month. Clients seen in
+		several months are counted once in the totals of the range. The counts can be
+		exported as CSV.`,
+	},
 	"host-info": {
 		"Information about the host instance that this Vault server is running on.",
 		`Information about the host instance that this Vault server is running on.
 		The information that gets collected includes host hardware information, and CPU,
 		disk, and memory utilization`,
 	},
+	"in-flight-req": {
+		"Requests being handled by this Vault server.",
+		`Requests being handled by the HTTP listeners of this Vault server, keyed by
+		request ID. The start time, the path, the method and the remote address of
+		the client are returned for each request.`,
+	},
 }
@@ -0,0 +1,237 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// controlGroupPaths returns the paths used to authorize the requests subject
+// to a control group and to configure the control groups
+func (b *SystemBackend) controlGroupPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "control-group/authorize$",
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group token of the request.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupAuthorize(),
+					Summary:  "Authorize a request subject to a control group.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(controlGroupHelp["control-group-authorize"][0]),
+			HelpDescription: strings.TrimSpace(controlGroupHelp["control-group-authorize"][1]),
+		},
+		{
+			Pattern: "control-group/request$",
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group token of the request.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupRequest(),
+					Summary:  "Check the status of a request subject to a control group.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(controlGroupHelp["control-group-request"][0]),
+			HelpDescription: strings.TrimSpace(controlGroupHelp["control-group-request"][1]),
+		},
+		{
+			Pattern: "config/control-group$",
+			Fields: map[string]*framework.FieldSchema{
+				"max_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Maximum TTL of the control group tokens.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupConfigRead(),
+					Summary:  "Return the control group configuration.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupConfigUpdate(),
+					Summary:  "Configure the control groups.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupConfigDelete(),
+					Summary:  "Remove the control group configuration.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(controlGroupHelp["config-control-group"][0]),
+			HelpDescription: strings.TrimSpace(controlGroupHelp["config-control-group"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleControlGroupAuthorize() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		accessor := d.Get("accessor").(string)
+		if accessor == "" {
+			return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+		}
+
+		approved, err := b.Core.authorizeControlGroupRequest(ctx, accessor, req.EntityID)
+		switch {
+		case err == logical.ErrPermissionDenied:
+			return nil, err
+		case err != nil:
+			return handleError(err)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"approved": approved,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleControlGroupRequest() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		accessor := d.Get("accessor").(string)
+		if accessor == "" {
+			return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+		}
+
+		te, tokenCtx, err := b.Core.controlGroupToken(ctx, accessor)
+		if err != nil {
+			return handleError(err)
+		}
+		cgReq, err := b.Core.controlGroupRequest(tokenCtx, te)
+		if err != nil {
+			return nil, err
+		}
+
+		requestEntity, err := b.controlGroupEntity(cgReq.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+		for _, authz := range cgReq.Authorizations {
+			entity, err := b.controlGroupEntity(authz.EntityID)
+			if err != nil {
+				return nil, err
+			}
+			authorizations = append(authorizations, map[string]interface{}{
+				"entity_id":          entity["id"],
+				"entity_name":        entity["name"],
+				"authorization_time": authz.AuthorizationTime.Format(time.RFC3339Nano),
+			})
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"approved":       cgReq.Approved,
+				"request_path":   cgReq.Path,
+				"request_entity": requestEntity,
+				"request_time":   cgReq.RequestTime.Format(time.RFC3339Nano),
+				"authorizations": authorizations,
+			},
+		}, nil
+	}
+}
+
+// controlGroupEntity returns the ID and name of the entity
+func (b *SystemBackend) controlGroupEntity(entityID string) (map[string]interface{}, error) {
+	info := map[string]interface{}{
+		"id":   entityID,
+		"name": "",
+	}
+	if entityID == "" {
+		return info, nil
+	}
+
+	entity, err := b.Core.identityStore.MemDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity != nil {
+		info["name"] = entity.Name
+	}
+	return info, nil
+}
+
+func (b *SystemBackend) handleControlGroupConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		config, err := b.Core.controlGroupConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"max_ttl": int64(config.MaxTTL.Seconds()),
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleControlGroupConfigUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		config, err := b.Core.controlGroupConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if maxTTL, ok := d.GetOk("max_ttl"); ok {
+			config.MaxTTL = time.Duration(maxTTL.(int)) * time.Second
+		}
+
+		entry, err := logical.StorageEntryJSON(coreControlGroupConfigPath, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Core.barrier.Put(ctx, entry); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleControlGroupConfigDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.barrier.Delete(ctx, coreControlGroupConfigPath); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+var controlGroupHelp = map[string][2]string{
+	"control-group-authorize": {
+		"Authorize a request subject to a control group.",
+		`
+The request is identified by the accessor of the control group token returned
+to the requester. The token of the authorizer must be tied to an entity that is
+a member of one of the identity groups of the control group factors. The
+response tells whether the request gathered all the approvals it requires.
+		`,
+	},
+	"control-group-request": {
+		"Check the status of a request subject to a control group.",
+		`
+The request is identified by the accessor of the control group token returned
+to the requester. The response contains the path of the request, the entity of
+the requester, the authorizations given so far and whether the request is
+approved. Once approved, unwrapping the control group token runs the request.
+		`,
+	},
+	"config-control-group": {
+		"Configure the control groups.",
+		`
+The max_ttl option caps the TTL of the control group tokens, which otherwise
+defaults to the ttl of the control_group stanza of the policy, or 24 hours.
+		`,
+	},
+}
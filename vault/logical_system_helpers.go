@@ -2,7 +2,6 @@ package vault
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -26,8 +25,8 @@ var (
 	addSentinelPolicyData     = func(map[string]interface{}, *Policy) {}
 	inputSentinelPolicyData   = func(*framework.FieldData, *Policy) *logical.Response { return nil }
 
-	controlGroupUnwrap = func(context.Context, *SystemBackend, string, bool) (string, error) {
-		return "", errors.New("control groups unavailable")
+	controlGroupUnwrap = func(ctx context.Context, b *SystemBackend, token string, _ bool) (string, error) {
+		return b.Core.unwrapControlGroupRequest(ctx, token)
 	}
 
 	pathInternalUINamespacesRead = func(b *SystemBackend) framework.OperationFunc {
@@ -51,7 +50,33 @@ var (
 				return nil, logical.ErrPermissionDenied
 			}
 
-			return logical.ListResponse([]string{""}), nil
+			ns, err := namespace.FromContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tokenNS := namespace.RootNamespace
+			if te != nil {
+				tokenNS, err = NamespaceByID(ctx, te.NamespaceID, b.Core)
+				if err != nil {
+					return nil, err
+				}
+				if tokenNS == nil {
+					return nil, logical.ErrPermissionDenied
+				}
+			}
+
+			// List the namespaces under the namespace of the request that
+			// the token can be used in
+			keys := []string{""}
+			for _, child := range b.Core.collectNamespaces() {
+				if child.Path == ns.Path || !child.HasParent(ns) {
+					continue
+				}
+				if child.Path == tokenNS.Path || child.HasParent(tokenNS) {
+					keys = append(keys, ns.TrimmedPath(child.Path))
+				}
+			}
+			return logical.ListResponse(keys), nil
 		}
 	}
 
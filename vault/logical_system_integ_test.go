@@ -1,6 +1,7 @@
 package vault_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -671,6 +672,46 @@ func TestBackend_PluginMainCredentials(t *testing.T) {
 	}
 }
 
+// TestBackend_PluginMainMultiplexed is a mock plugin hosting the backends of
+// several mounts, which only runs with automatic mTLS.
+func TestBackend_PluginMainMultiplexed(t *testing.T) {
+	if os.Getenv(pluginutil.PluginAutoMTLSEnv) != "true" {
+		return
+	}
+
+	factoryFunc := func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b, err := mock.FactoryType(logical.TypeLogical)(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		return &pidBackend{Backend: b}, nil
+	}
+
+	err := lplugin.ServeMultiplex(&lplugin.ServeOpts{
+		BackendFactoryFunc: factoryFunc,
+		TLSProviderFunc:    api.VaultPluginTLSProvider(nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pidBackend reports the process ID of the plugin on the pid path
+type pidBackend struct {
+	logical.Backend
+}
+
+func (b *pidBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Path == "pid" {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"pid": os.Getpid(),
+			},
+		}, nil
+	}
+	return b.Backend.HandleRequest(ctx, req)
+}
+
 // TestBackend_PluginMainEnv is a mock plugin that simply checks for the existence of FOO env var.
 func TestBackend_PluginMainEnv(t *testing.T) {
 	args := []string{}
@@ -707,6 +748,176 @@ func TestBackend_PluginMainEnv(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_Plugin_multiplexed(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	vault.TestWaitActive(t, core.Core)
+	client := core.Client
+
+	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", consts.PluginTypeSecrets, "TestBackend_PluginMainMultiplexed", []string{}, cluster.TempDir)
+
+	for _, path := range []string{"mock-0", "mock-1"} {
+		if err := client.Sys().Mount(path, &api.MountInput{Type: "mock-plugin"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each mount has its own backend instance
+	if _, err := client.Logical().Write("mock-0/internal", map[string]interface{}{"value": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	var pids []interface{}
+	for path, expected := range map[string]string{"mock-0": "foo", "mock-1": "bar"} {
+		resp, err := client.Logical().Read(path + "/internal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data["value"] != expected {
+			t.Fatalf("expected %q for %s, got: %#v", expected, path, resp.Data)
+		}
+
+		resp, err = client.Logical().Read(path + "/pid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pids = append(pids, resp.Data["pid"])
+	}
+
+	// Both backends are hosted by the same plugin process
+	if pids[0] == nil || pids[0] != pids[1] {
+		t.Fatalf("expected the mounts to share the plugin process, got pids: %v", pids)
+	}
+}
+
+func TestSystemBackend_Plugin_versioned(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	vault.TestWaitActive(t, core.Core)
+	client := core.Client
+
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", consts.PluginTypeSecrets, "1.0.0", "TestBackend_PluginMainMultiplexed", []string{}, cluster.TempDir)
+
+	plugin, err := client.Sys().GetPlugin(&api.GetPluginInput{
+		Name:    "mock-plugin",
+		Type:    consts.PluginTypeSecrets,
+		Version: "v1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plugin.Version != "v1.0.0" || len(plugin.Versions) != 1 || plugin.Versions[0] != "v1.0.0" {
+		t.Fatalf("bad: %#v", plugin)
+	}
+
+	// Mounts can only pin a registered version
+	err = client.Sys().Mount("mock", &api.MountInput{Type: "mock-plugin", PluginVersion: "2.0.0"})
+	if err == nil {
+		t.Fatal("expected error mounting an unknown version of the plugin")
+	}
+	if err := client.Sys().Mount("mock", &api.MountInput{Type: "mock-plugin", PluginVersion: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mounts["mock/"].PluginVersion != "v1.0.0" {
+		t.Fatalf("bad: %#v", mounts["mock/"])
+	}
+	if _, err := client.Logical().Write("mock/kv/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSystemBackend_Plugin_upgrade(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	vault.TestWaitActive(t, core.Core)
+	client := core.Client
+
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", consts.PluginTypeSecrets, "1.0.0", "TestBackend_PluginMainMultiplexed", []string{}, cluster.TempDir)
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", consts.PluginTypeSecrets, "1.1.0", "TestBackend_PluginMainMultiplexed", []string{}, cluster.TempDir)
+	// This version fails to start
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", consts.PluginTypeSecrets, "2.0.0", "TestBackend_PluginMainMissing", []string{}, cluster.TempDir)
+
+	for _, path := range []string{"mock-0", "mock-1"} {
+		if err := client.Sys().Mount(path, &api.MountInput{Type: "mock-plugin", PluginVersion: "1.0.0"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Logical().Write("mock-0/kv/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertVersions := func(expected string) {
+		t.Helper()
+		mounts, err := client.Sys().ListMounts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{"mock-0/", "mock-1/"} {
+			if mounts[path].PluginVersion != expected {
+				t.Fatalf("expected version %q on %s, got: %#v", expected, path, mounts[path])
+			}
+		}
+		resp, err := client.Logical().Read("mock-0/kv/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.Data["value"] != "bar" {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	// Unknown versions are rejected before touching the mounts
+	if _, err := client.Sys().UpgradePlugin(&api.UpgradePluginInput{Plugin: "mock-plugin", Version: "3.0.0"}); err == nil {
+		t.Fatal("expected error upgrading to an unknown version")
+	}
+
+	mounts, err := client.Sys().UpgradePlugin(&api.UpgradePluginInput{Plugin: "mock-plugin", Version: "1.1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("bad: %v", mounts)
+	}
+	assertVersions("v1.1.0")
+
+	// A failed upgrade is rolled back
+	if _, err := client.Sys().UpgradePlugin(&api.UpgradePluginInput{Plugin: "mock-plugin", Version: "2.0.0"}); err == nil {
+		t.Fatal("expected error upgrading to a broken version")
+	}
+	assertVersions("v1.1.0")
+
+	// The upgrade can be limited to some mounts
+	mounts, err = client.Sys().UpgradePlugin(&api.UpgradePluginInput{Plugin: "mock-plugin", Version: "1.0.0", Mounts: []string{"mock-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 1 || mounts[0] != "mock-1/" {
+		t.Fatalf("bad: %v", mounts)
+	}
+}
+
 func TestSystemBackend_InternalUIResultantACL(t *testing.T) {
 	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
 		HandlerFunc: vaulthttp.Handler,
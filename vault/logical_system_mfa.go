@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// mfaValidatePaths returns the path used to validate the MFA requirement of the
+// logins subject to MFA
+func (b *SystemBackend) mfaValidatePaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mfa/validate$",
+			Fields: map[string]*framework.FieldSchema{
+				"mfa_request_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the MFA request returned by the login.",
+				},
+				"mfa_payload": {
+					Type:        framework.TypeMap,
+					Description: "Map of the MFA method names to the lists of their MFA credentials.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleMFAValidate(),
+					Summary:  "Validate the MFA requirement of a login and return its token.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(mfaValidateHelp["mfa-validate"][0]),
+			HelpDescription: strings.TrimSpace(mfaValidateHelp["mfa-validate"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleMFAValidate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		requestID := d.Get("mfa_request_id").(string)
+		if requestID == "" {
+			return logical.ErrorResponse("missing mfa_request_id"), logical.ErrInvalidRequest
+		}
+		payload, err := parseMFAPayload(d.Get("mfa_payload").(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		mfaReq, err := b.Core.validateLoginMFA(ctx, requestID, payload)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		loginCtx := namespace.ContextWithNamespace(ctx, mfaReq.namespace)
+		if errResp, err := b.Core.registerLogin(loginCtx, mfaReq.namespace, mfaReq.login); err != nil {
+			return errResp, err
+		}
+
+		// The aliases were already attached to the token, and would be
+		// updated with the accessor of the system backend
+		auth := *mfaReq.login.auth
+		auth.Alias = nil
+		auth.GroupAliases = nil
+
+		return &logical.Response{
+			Auth: &auth,
+		}, nil
+	}
+}
+
+// parseMFAPayload converts the MFA credentials of a validation request, given
+// as a single value or as a list of values per method
+func parseMFAPayload(payload map[string]interface{}) (logical.MFACreds, error) {
+	creds := make(logical.MFACreds, len(payload))
+	for methodName, value := range payload {
+		switch value := value.(type) {
+		case nil:
+			creds[methodName] = []string{}
+		case string:
+			creds[methodName] = []string{value}
+		case []interface{}:
+			for _, item := range value {
+				passcode, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid MFA credentials for the method %q", methodName)
+				}
+				creds[methodName] = append(creds[methodName], passcode)
+			}
+			if creds[methodName] == nil {
+				creds[methodName] = []string{}
+			}
+		case map[string]interface{}:
+			// Credentials given as objects, such as WebAuthn assertions, are
+			// passed on as JSON
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MFA credentials for the method %q", methodName)
+			}
+			creds[methodName] = []string{string(encoded)}
+		default:
+			return nil, fmt.Errorf("invalid MFA credentials for the method %q", methodName)
+		}
+	}
+	return creds, nil
+}
+
+var mfaValidateHelp = map[string][2]string{
+	"mfa-validate": {
+		"Validate the MFA requirement of a login.",
+		`
+A login subject to a login MFA enforcement returns an MFA request ID and the
+MFA methods that can validate it, instead of a token. The MFA credentials of the
+methods are sent with the request ID to get the token of the login. A request ID
+can only be used once, and expires after 5 minutes.
+		`,
+	},
+}
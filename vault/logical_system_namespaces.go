@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// namespacesPaths returns paths that enable namespace management. The
+// namespaces are managed from their parent namespace.
+func (b *SystemBackend) namespacesPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "namespaces/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesList(),
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces-list"][1]),
+		},
+		{
+			Pattern: "namespaces/" + framework.GenericNameRegex("path"),
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Name of the namespace, relative to the namespace of the request.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesCreate(),
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesRead(),
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesDelete(),
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces"][1]),
+		},
+	}
+}
+
+// childNamespace returns the child namespace of the namespace of the context
+// with the given name, or nil if there is none
+func (b *SystemBackend) childNamespace(ctx context.Context, name string) (*namespace.Namespace, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	childPath := ns.Path + namespace.Canonicalize(name)
+	for _, child := range b.Core.childNamespaces(ns) {
+		if child.Path == childPath {
+			return child, nil
+		}
+	}
+	return nil, nil
+}
+
+func namespaceResponseData(ns *namespace.Namespace) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   ns.ID,
+		"path": ns.Path,
+	}
+}
+
+func (b *SystemBackend) handleNamespacesList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		keyInfo := make(map[string]interface{})
+		for _, child := range b.Core.childNamespaces(ns) {
+			key := ns.TrimmedPath(child.Path)
+			keys = append(keys, key)
+			keyInfo[key] = namespaceResponseData(child)
+		}
+
+		return logical.ListResponseWithInfo(keys, keyInfo), nil
+	}
+}
+
+func (b *SystemBackend) handleNamespacesCreate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := b.Core.createNamespace(ctx, d.Get("path").(string))
+		if err != nil {
+			return handleError(err)
+		}
+
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleNamespacesRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := b.childNamespace(ctx, d.Get("path").(string))
+		if err != nil {
+			return nil, err
+		}
+		if ns == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleNamespacesDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := b.childNamespace(ctx, d.Get("path").(string))
+		if err != nil {
+			return nil, err
+		}
+		if ns == nil {
+			return nil, nil
+		}
+
+		if err := b.Core.deleteNamespace(ctx, ns); err != nil {
+			return handleError(err)
+		}
+
+		return nil, nil
+	}
+}
+
+var namespacesHelp = map[string][2]string{
+	"namespaces": {
+		"Create, read and delete a child namespace.",
+		`A namespace isolates its mounts, auth methods, policies and tokens from the
+other namespaces. Each namespace gets its own system, token and cubbyhole
+mounts and default policy. A namespace can only be deleted once its child
+namespaces are deleted; deleting it revokes its tokens and leases and removes
+its mounts and policies.`,
+	},
+	"namespaces-list": {
+		"Lists the child namespaces.",
+		"This list contains the namespaces directly under the namespace of the request.",
+	},
+}
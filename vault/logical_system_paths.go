@@ -490,6 +490,49 @@ func (b *SystemBackend) auditPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
 		},
 
+		{
+			// This must be listed before the path used to enable devices
+			Pattern: "audit/tail$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"device": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Path of the audit device whose salt is used to hash the entries.",
+					Query:       true,
+				},
+				"type": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Only stream entries of the given type, either \"request\" or \"response\".",
+					Query:       true,
+				},
+				"path_prefix": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Only stream entries for requests to paths with the given prefix.",
+					Query:       true,
+				},
+				"operation": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Only stream entries for requests with one of the given operations.",
+					Query:       true,
+				},
+				"errors_only": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "Only stream entries for requests that resulted in an error.",
+					Query:       true,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleAuditTail,
+					Summary:  "Stream audit entries as they are logged.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["audit-tail"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["audit-tail"][1]),
+		},
+
 		{
 			Pattern: "audit/(?P<path>.+)",
 
@@ -602,6 +645,63 @@ func (b *SystemBackend) sealPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
 		},
+
+		{
+			Pattern: "rotate/config$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"enabled": {
+					Type:        framework.TypeBool,
+					Description: "Whether the encryption key is rotated automatically.",
+				},
+				"max_operations": {
+					Type:        framework.TypeInt,
+					Description: "The number of encryptions after which the encryption key is rotated.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "The age after which the encryption key is rotated. If 0, the key is not rotated based on its age.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleRotateConfigRead,
+				logical.UpdateOperation: b.handleRotateConfigUpdate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["rotate-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["rotate-config"][1]),
+		},
+
+		{
+			Pattern: "seal/migrate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"type": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Type of the seal to migrate to.",
+				},
+				"config": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: "Configuration of the seal to migrate to, as in its seal stanza.",
+				},
+				"key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Unseal key share, hex or base64 encoded, when migrating from a Shamir seal.",
+				},
+				"reset": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "If set, cancels the migration in progress.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleSealMigrate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["seal-migrate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["seal-migrate"][1]),
+		},
 	}
 }
 
@@ -638,6 +738,10 @@ func (b *SystemBackend) pluginsCatalogCRUDPath() *framework.Path {
 				Type:        framework.TypeStringSlice,
 				Description: strings.TrimSpace(sysHelp["plugin-catalog_env"][0]),
 			},
+			"version": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -727,6 +831,38 @@ func (b *SystemBackend) pluginsReloadPath() *framework.Path {
 	}
 }
 
+func (b *SystemBackend) pluginsUpgradePath() *framework.Path {
+	return &framework.Path{
+		Pattern: "plugins/upgrade/backend$",
+
+		Fields: map[string]*framework.FieldSchema{
+			"plugin": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: strings.TrimSpace(sysHelp["plugin-backend-upgrade-plugin"][0]),
+			},
+			"version": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: strings.TrimSpace(sysHelp["plugin-backend-upgrade-version"][0]),
+			},
+			"mounts": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: strings.TrimSpace(sysHelp["plugin-backend-upgrade-mounts"][0]),
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:    b.handlePluginUpgradeUpdate,
+				Summary:     "Upgrade mounted plugin backends to another version of the plugin.",
+				Description: "The mounts running the plugin (`plugin`) are pinned to the version (`version`) and reloaded. If (`mounts`) is provided, only these mounts are upgraded. If any mount fails to reload, the upgrade is rolled back.",
+			},
+		},
+
+		HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-upgrade"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["plugin-upgrade"][1]),
+	}
+}
+
 func (b *SystemBackend) toolsPaths() []*framework.Path {
 	return []*framework.Path{
 		{
@@ -908,6 +1044,32 @@ func (b *SystemBackend) internalPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-entities"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-entities"][1]),
 		},
+		{
+			Pattern: "internal/counters/activity$",
+			Fields: map[string]*framework.FieldSchema{
+				"start_time": {
+					Type:        framework.TypeTime,
+					Description: "Start of the query interval, as an RFC3339 timestamp or Unix epoch. Defaults to the start of the month eleven months before end_time.",
+				},
+				"end_time": {
+					Type:        framework.TypeTime,
+					Description: "End of the query interval, as an RFC3339 timestamp or Unix epoch. Defaults to the current time.",
+				},
+				"format": {
+					Type:        framework.TypeString,
+					Default:     "json",
+					Description: `Format of the counts, "json" or "csv".`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback:    b.pathInternalCountersActivity,
+					Unpublished: true,
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-activity"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-activity"][1]),
+		},
 	}
 }
 
@@ -1019,6 +1181,20 @@ func (b *SystemBackend) leasePaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["leases"][1]),
 		},
 
+		{
+			Pattern: "leases/irrevocable/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleLeaseIrrevocableList,
+					Summary:  "Returns the leases whose revocation failed.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable"][1]),
+		},
+
 		{
 			Pattern: "leases/lookup",
 
@@ -1190,7 +1366,12 @@ func (b *SystemBackend) metricsPath() *framework.Path {
 		Fields: map[string]*framework.FieldSchema{
 			"format": &framework.FieldSchema{
 				Type:        framework.TypeString,
-				Description: "Format to export metrics into. Currently accepts only \"prometheus\".",
+				Description: "Format to export metrics into. Accepts \"prometheus\", and \"json\" for metrics aggregated over a rolling window.",
+				Query:       true,
+			},
+			"window": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Rolling window to aggregate metrics over with the \"json\" format, such as \"5m\". Defaults to the shortest configured window.",
 				Query:       true,
 			},
 		},
@@ -1237,6 +1418,21 @@ func (b *SystemBackend) hostInfoPath() *framework.Path {
 	}
 }
 
+func (b *SystemBackend) inFlightRequestPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "in-flight-req/?",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:    b.handleInFlightRequestData,
+				Summary:     strings.TrimSpace(sysHelp["in-flight-req"][0]),
+				Description: strings.TrimSpace(sysHelp["in-flight-req"][1]),
+			},
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["in-flight-req"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["in-flight-req"][1]),
+	}
+}
+
 func (b *SystemBackend) authPaths() []*framework.Path {
 	return []*framework.Path{
 		{
@@ -1294,6 +1490,22 @@ func (b *SystemBackend) authPaths() []*framework.Path {
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["token_type"][0]),
 				},
+				"external_group_sync": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: strings.TrimSpace(sysHelp["external_group_sync"][0]),
+				},
+				"user_lockout_config": &framework.FieldSchema{
+					Type:        framework.TypeMap,
+					Description: strings.TrimSpace(sysHelp["user_lockout_config"][0]),
+				},
+				"min_lease_ttl": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: strings.TrimSpace(sysHelp["min_lease_ttl"][0]),
+				},
+				"allowed_entity_aliases": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["allowed_entity_aliases"][0]),
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ReadOperation: &framework.PathOperation{
@@ -1348,6 +1560,10 @@ func (b *SystemBackend) authPaths() []*framework.Path {
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["auth_plugin"][0]),
 				},
+				"plugin_version": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin_version"][0]),
+				},
 				"options": &framework.FieldSchema{
 					Type:        framework.TypeKVPairs,
 					Description: strings.TrimSpace(sysHelp["auth_options"][0]),
@@ -1468,6 +1684,20 @@ func (b *SystemBackend) policyPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["policy"][1]),
 		},
 
+		{
+			Pattern: "policies/password/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesPasswordList,
+					Summary:  "List the existing password policies.",
+				},
+			},
+
+			HelpSynopsis:    "List the existing password policies.",
+			HelpDescription: "List the names of the existing password policies.",
+		},
+
 		{
 			Pattern: "policies/password/(?P<name>.+)/generate$",
 
@@ -1698,6 +1928,10 @@ func (b *SystemBackend) mountPaths() []*framework.Path {
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["mount_plugin_name"][0]),
 				},
+				"plugin_version": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin_version"][0]),
+				},
 				"options": &framework.FieldSchema{
 					Type:        framework.TypeKVPairs,
 					Description: strings.TrimSpace(sysHelp["mount_options"][0]),
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -62,7 +63,9 @@ func (b *SystemBackend) quotasPaths() []*framework.Path {
 					Type: framework.TypeString,
 					Description: `Path of the mount or namespace to apply the quota. A blank path configures a
 global quota. For example namespace1/ adds a quota to a full namespace,
-namespace1/auth/userpass adds a quota to userpass in namespace1.`,
+namespace1/auth/userpass adds a quota to userpass in namespace1. A path within a
+mount, such as secret/data/app, adds a quota to the requests to paths that start
+with it.`,
 				},
 				"rate": {
 					Type: framework.TypeFloat,
@@ -73,6 +76,12 @@ The 'rate' must be positive.`,
 					Type:        framework.TypeDurationSecond,
 					Description: "The duration to enforce rate limiting for (default '1s').",
 				},
+				"burst": {
+					Type: framework.TypeInt,
+					Description: `The maximum number of requests a client can make at once. If set, the
+client's allowance is refilled with 'burst' requests as often as needed to
+sustain 'rate' requests per 'interval'. Defaults to 'rate'.`,
+				},
 				"block_interval": {
 					Type: framework.TypeDurationSecond,
 					Description: `If set, when a client reaches a rate limit threshold, the client will be prohibited
@@ -93,6 +102,61 @@ from any further requests until after the 'block_interval' has elapsed.`,
 			HelpSynopsis:    strings.TrimSpace(quotasHelp["rate-limit"][0]),
 			HelpDescription: strings.TrimSpace(quotasHelp["rate-limit"][1]),
 		},
+		{
+			Pattern: "quotas/lease-count/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotasList(),
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(quotasHelp["lease-count-list"][0]),
+			HelpDescription: strings.TrimSpace(quotasHelp["lease-count-list"][1]),
+		},
+		{
+			Pattern: "quotas/lease-count/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"type": {
+					Type:        framework.TypeString,
+					Description: "Type of the quota rule.",
+				},
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the quota rule.",
+				},
+				"path": {
+					Type: framework.TypeString,
+					Description: `Path of the mount or namespace to apply the quota. A blank path configures a
+global quota. For example namespace1/ adds a quota to a full namespace,
+namespace1/auth/userpass adds a quota to userpass in namespace1. A path within a
+mount, such as database/creds/app, adds a quota to the leases generated by
+requests to paths that start with it.`,
+				},
+				"max_leases": {
+					Type: framework.TypeInt,
+					Description: `The maximum number of leases to be allowed by the quota rule. The 'max_leases'
+must be positive.`,
+				},
+				"soft_max_leases": {
+					Type: framework.TypeInt,
+					Description: `If set, the number of leases beyond which a warning is logged and the
+quota.lease_count.warning metric is emitted for each new lease. It must be
+lower than 'max_leases'.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotasUpdate(),
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotasRead(),
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotasDelete(),
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(quotasHelp["lease-count"][0]),
+			HelpDescription: strings.TrimSpace(quotasHelp["lease-count"][1]),
+		},
 	}
 }
 
@@ -133,6 +197,29 @@ func (b *SystemBackend) handleQuotasConfigRead() framework.OperationFunc {
 	}
 }
 
+// quotaPathFactors splits the path of a quota into its namespace, the mount
+// and the path within the mount.
+func (b *SystemBackend) quotaPathFactors(ctx context.Context, path string) (*namespace.Namespace, string, string, error) {
+	mountPath := sanitizePath(path)
+	ns := b.Core.namespaceByPath(mountPath)
+	if ns.ID != namespace.RootNamespaceID {
+		mountPath = strings.TrimPrefix(mountPath, ns.Path)
+	}
+
+	// A path within a mount is split into the mount and the path suffix
+	var pathSuffix string
+	if mountPath != "" {
+		match := b.Core.router.MatchingMount(namespace.ContextWithNamespace(ctx, ns), mountPath)
+		if match == "" {
+			return nil, "", "", fmt.Errorf("invalid mount path %q", mountPath)
+		}
+		pathSuffix = strings.TrimSuffix(strings.TrimPrefix(mountPath, match), "/")
+		mountPath = match
+	}
+
+	return ns, mountPath, pathSuffix, nil
+}
+
 func (b *SystemBackend) handleRateLimitQuotasList() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 		names, err := b.Core.quotaManager.QuotaNames(quotas.TypeRateLimit)
@@ -164,17 +251,14 @@ func (b *SystemBackend) handleRateLimitQuotasUpdate() framework.OperationFunc {
 			return logical.ErrorResponse("'block' is invalid"), nil
 		}
 
-		mountPath := sanitizePath(d.Get("path").(string))
-		ns := b.Core.namespaceByPath(mountPath)
-		if ns.ID != namespace.RootNamespaceID {
-			mountPath = strings.TrimPrefix(mountPath, ns.Path)
+		burst := d.Get("burst").(int)
+		if burst < 0 {
+			return logical.ErrorResponse("'burst' is invalid"), nil
 		}
 
-		if mountPath != "" {
-			match := b.Core.router.MatchingMount(namespace.ContextWithNamespace(ctx, ns), mountPath)
-			if match == "" {
-				return logical.ErrorResponse("invalid mount path %q", mountPath), nil
-			}
+		ns, mountPath, pathSuffix, err := b.quotaPathFactors(ctx, d.Get("path").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
 		}
 
 		// If a quota already exists, fetch and update it.
@@ -187,7 +271,7 @@ func (b *SystemBackend) handleRateLimitQuotasUpdate() framework.OperationFunc {
 		case quota == nil:
 			// Disallow creation of new quota that has properties similar to an
 			// existing quota.
-			quotaByFactors, err := b.Core.quotaManager.QuotaByFactors(ctx, qType, ns.Path, mountPath)
+			quotaByFactors, err := b.Core.quotaManager.QuotaByFactors(ctx, qType, ns.Path, mountPath, pathSuffix)
 			if err != nil {
 				return nil, err
 			}
@@ -195,12 +279,17 @@ func (b *SystemBackend) handleRateLimitQuotasUpdate() framework.OperationFunc {
 				return logical.ErrorResponse("quota rule with similar properties exists under the name %q", quotaByFactors.QuotaName()), nil
 			}
 
-			quota = quotas.NewRateLimitQuota(name, ns.Path, mountPath, rate, interval, blockInterval)
+			rlq := quotas.NewRateLimitQuota(name, ns.Path, mountPath, rate, interval, blockInterval)
+			rlq.PathSuffix = pathSuffix
+			rlq.Burst = burst
+			quota = rlq
 		default:
 			rlq := quota.(*quotas.RateLimitQuota)
 			rlq.NamespacePath = ns.Path
 			rlq.MountPath = mountPath
+			rlq.PathSuffix = pathSuffix
 			rlq.Rate = rate
+			rlq.Burst = burst
 			rlq.Interval = interval
 			rlq.BlockInterval = blockInterval
 		}
@@ -245,8 +334,9 @@ func (b *SystemBackend) handleRateLimitQuotasRead() framework.OperationFunc {
 		data := map[string]interface{}{
 			"type":           qType,
 			"name":           rlq.Name,
-			"path":           nsPath + rlq.MountPath,
+			"path":           nsPath + rlq.MountPath + rlq.PathSuffix,
 			"rate":           rlq.Rate,
+			"burst":          rlq.Burst,
 			"interval":       int(rlq.Interval.Seconds()),
 			"block_interval": int(rlq.BlockInterval.Seconds()),
 		}
@@ -274,6 +364,136 @@ func (b *SystemBackend) handleRateLimitQuotasDelete() framework.OperationFunc {
 	}
 }
 
+func (b *SystemBackend) handleLeaseCountQuotasList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		names, err := b.Core.quotaManager.QuotaNames(quotas.TypeLeaseCount)
+		if err != nil {
+			return nil, err
+		}
+
+		return logical.ListResponse(names), nil
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotasUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		qType := quotas.TypeLeaseCount.String()
+		maxLeases := d.Get("max_leases").(int)
+		if maxLeases <= 0 {
+			return logical.ErrorResponse("'max_leases' is invalid"), nil
+		}
+
+		softMaxLeases := d.Get("soft_max_leases").(int)
+		if softMaxLeases < 0 || softMaxLeases >= maxLeases {
+			return logical.ErrorResponse("'soft_max_leases' is invalid"), nil
+		}
+
+		ns, mountPath, pathSuffix, err := b.quotaPathFactors(ctx, d.Get("path").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		// If a quota already exists, fetch and update it.
+		quota, err := b.Core.quotaManager.QuotaByName(qType, name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case quota == nil:
+			// Disallow creation of new quota that has properties similar to an
+			// existing quota.
+			quotaByFactors, err := b.Core.quotaManager.QuotaByFactors(ctx, qType, ns.Path, mountPath, pathSuffix)
+			if err != nil {
+				return nil, err
+			}
+			if quotaByFactors != nil && quotaByFactors.QuotaName() != name {
+				return logical.ErrorResponse("quota rule with similar properties exists under the name %q", quotaByFactors.QuotaName()), nil
+			}
+
+			lcq := quotas.NewLeaseCountQuota(name, ns.Path, mountPath, maxLeases, softMaxLeases)
+			lcq.PathSuffix = pathSuffix
+			quota = lcq
+		default:
+			lcq := quota.(*quotas.LeaseCountQuota)
+			lcq.NamespacePath = ns.Path
+			lcq.MountPath = mountPath
+			lcq.PathSuffix = pathSuffix
+			lcq.MaxLeases = maxLeases
+			lcq.SoftMaxLeases = softMaxLeases
+		}
+
+		entry, err := logical.StorageEntryJSON(quotas.QuotaStoragePath(qType, name), quota)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+
+		if err := b.Core.quotaManager.SetQuota(ctx, qType, quota, false); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotasRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+		qType := quotas.TypeLeaseCount.String()
+
+		quota, err := b.Core.quotaManager.QuotaByName(qType, name)
+		if err != nil {
+			return nil, err
+		}
+		if quota == nil {
+			return nil, nil
+		}
+
+		lcq := quota.(*quotas.LeaseCountQuota)
+
+		nsPath := lcq.NamespacePath
+		if lcq.NamespacePath == "root" {
+			nsPath = ""
+		}
+
+		data := map[string]interface{}{
+			"type":            qType,
+			"name":            lcq.Name,
+			"path":            nsPath + lcq.MountPath + lcq.PathSuffix,
+			"max_leases":      lcq.MaxLeases,
+			"soft_max_leases": lcq.SoftMaxLeases,
+			"counter":         lcq.Count(),
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotasDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+		qType := quotas.TypeLeaseCount.String()
+
+		if err := req.Storage.Delete(ctx, quotas.QuotaStoragePath(qType, name)); err != nil {
+			return nil, err
+		}
+
+		if err := b.Core.quotaManager.DeleteQuota(ctx, qType, name); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
 var quotasHelp = map[string][2]string{
 	"quotas-config": {
 		"Create, update and read the quota configuration.",
@@ -284,11 +504,24 @@ var quotasHelp = map[string][2]string{
 mount.`,
 		`A rate limit quota will enforce API rate limiting in a specified interval. A
 rate limit quota can be created at the root level or defined on a namespace or
-mount by specifying a 'path'. The rate limiter is applied to each unique client
-IP address.`,
+mount by specifying a 'path', or on the requests to a path within a mount. The
+rate limiter is applied to each unique client IP address, and requests beyond
+the limit are rejected with a 429 status code.`,
 	},
 	"rate-limit-list": {
 		"Lists the names of all the rate limit quotas.",
 		"This list contains quota definitions from all the namespaces.",
 	},
+	"lease-count": {
+		`Get, create or update lease count quota for an optional namespace or mount.`,
+		`A lease count quota limits the number of leases that can be created within a
+namespace or mount, or by the requests to a path within a mount. Requests that
+would generate a lease beyond the limit are rejected with a 429 status code. If
+'soft_max_leases' is set, a warning is logged and metrics are emitted once the
+number of leases reaches it.`,
+	},
+	"lease-count-list": {
+		"Lists the names of all the lease count quotas.",
+		"This list contains quota definitions from all the namespaces.",
+	},
 }
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -47,15 +48,22 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"replication/dr/reindex",
 		"replication/performance/reindex",
 		"rotate",
+		"rotate/config",
+		"seal/migrate",
 		"config/cors",
 		"config/auditing/*",
 		"config/ui/headers/*",
+		"config/raw",
+		"config/control-group",
 		"plugins/catalog/*",
+		"plugins/upgrade/backend",
 		"revoke-prefix/*",
 		"revoke-force/*",
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"leases/irrevocable",
+		"in-flight-req",
 	}
 
 	b := testSystemBackend(t)
@@ -1766,6 +1774,38 @@ func TestSystemBackend_enableAudit(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_enableAudit_tail(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	// The audit tail endpoint is matched before the path used to enable
+	// devices
+	req := logical.TestRequest(t, logical.UpdateOperation, "audit/tail")
+	req.Data["type"] = "noop"
+	_, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrUnsupportedOperation {
+		t.Fatalf("expected unsupported operation, got: %v", err)
+	}
+
+	// The path is reserved for it
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "tail",
+		Type:  "noop",
+	}
+	err = c.enableAudit(namespace.RootContext(nil), me, true)
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected the tail path to be rejected, got: %v", err)
+	}
+	if c.auditBroker.IsRegistered("tail/") {
+		t.Fatal("tail device should not be registered")
+	}
+}
+
 func TestSystemBackend_auditHash(t *testing.T) {
 	c, b, _ := testCoreSystemBackend(t)
 	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
@@ -1929,6 +1969,7 @@ func TestSystemBackend_rawWrite_Protected(t *testing.T) {
 
 func TestSystemBackend_rawReadWrite(t *testing.T) {
 	_, b, _ := testCoreSystemBackendRaw(t)
+	testSystemBackendRawAllowDestructive(t, b)
 
 	req := logical.TestRequest(t, logical.UpdateOperation, "raw/sys/policy/test")
 	req.Data["value"] = `path "secret/" { policy = "read" }`
@@ -1979,6 +2020,7 @@ func TestSystemBackend_rawDelete(t *testing.T) {
 	}
 
 	// Delete the policy
+	testSystemBackendRawAllowDestructive(t, b)
 	req := logical.TestRequest(t, logical.DeleteOperation, "raw/sys/policy/test")
 	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
 	if err != nil {
@@ -1999,6 +2041,109 @@ func TestSystemBackend_rawDelete(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_rawDestructiveDisabled(t *testing.T) {
+	c, b, _ := testCoreSystemBackendRaw(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config/raw")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["allow_destructive"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "raw/sys/policy/test")
+	req.Data["value"] = `path "secret/" { policy = "read" }`
+	_, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.DeleteOperation, "raw/core/mounts")
+	_, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	if entry, err := c.barrier.Get(namespace.RootContext(nil), "core/mounts"); err != nil || entry == nil {
+		t.Fatalf("expected the mount table to be kept, got: %v, %v", entry, err)
+	}
+
+	// Disabling the destructive operations again refuses them
+	testSystemBackendRawAllowDestructive(t, b)
+	req = logical.TestRequest(t, logical.UpdateOperation, "config/raw")
+	req.Data["allow_destructive"] = false
+	if _, err := b.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "raw/sys/policy/test")
+	req.Data["value"] = `path "secret/" { policy = "read" }`
+	_, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestSystemBackend_rawList_RecursivePaged(t *testing.T) {
+	c, b, _ := testCoreSystemBackendRaw(t)
+
+	expected := []string{"a", "b", "b/c", "b/d/e", "b/d/f", "b0", "f"}
+	for _, key := range expected {
+		if err := c.barrier.Put(namespace.RootContext(nil), &logical.StorageEntry{Key: "test/" + key, Value: []byte("1")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.ListOperation, "raw/test")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"a", "b", "b/", "b0", "f"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Page through the keys with every limit, so that pages end both within
+	// and at the end of folders
+	for limit := 0; limit <= len(expected); limit++ {
+		var keys []string
+		var pageToken string
+		for pages := 0; ; pages++ {
+			if pages > len(expected) {
+				t.Fatal("too many pages")
+			}
+			req = logical.TestRequest(t, logical.ListOperation, "raw/test/")
+			req.Data["recursive"] = true
+			req.Data["limit"] = limit
+			req.Data["page_token"] = pageToken
+			resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			keys = append(keys, resp.Data["keys"].([]string)...)
+			if resp.Data["next_page_token"] == nil {
+				break
+			}
+			pageToken = resp.Data["next_page_token"].(string)
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("bad keys with a limit of %d: %#v", limit, keys)
+		}
+	}
+
+	// Protected paths are left out of recursive listings
+	req = logical.TestRequest(t, logical.ListOperation, "raw/core/")
+	req.Data["recursive"] = true
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range resp.Data["keys"].([]string) {
+		if "core/"+key == keyringPath {
+			t.Fatalf("expected the keyring not to be listed, got: %#v", resp.Data["keys"])
+		}
+	}
+}
+
 func TestSystemBackend_keyStatus(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.ReadOperation, "key-status")
@@ -2010,7 +2155,11 @@ func TestSystemBackend_keyStatus(t *testing.T) {
 	exp := map[string]interface{}{
 		"term": 1,
 	}
+	if _, ok := resp.Data["encryptions"].(int64); !ok {
+		t.Fatalf("bad: encryptions: %#v", resp.Data["encryptions"])
+	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -2037,10 +2186,79 @@ func TestSystemBackend_rotate(t *testing.T) {
 	exp := map[string]interface{}{
 		"term": 2,
 	}
+	if _, ok := resp.Data["encryptions"].(int64); !ok {
+		t.Fatalf("bad: encryptions: %#v", resp.Data["encryptions"])
+	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+}
+
+func TestSystemBackend_rotateConfig(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"enabled":        true,
+		"max_operations": keyRotationMaxOperations,
+		"interval":       "0s",
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data = map[string]interface{}{
+		"max_operations": 2000000,
+		"interval":       "2160h",
+	}
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data = map[string]interface{}{
+		"enabled": false,
+	}
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp = map[string]interface{}{
+		"enabled":        false,
+		"max_operations": int64(2000000),
+		"interval":       "2160h0m0s",
+	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
+
+	// Policies outside of the safe bounds are rejected
+	for _, data := range []map[string]interface{}{
+		{"max_operations": 1000},
+		{"max_operations": keyRotationMaxOperations + 1},
+		{"interval": "1h"},
+	} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+		req.Data = data
+		resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("expected an invalid request for %v, got: resp: %#v, err: %v", data, resp, err)
+		}
+	}
 }
 
 func testSystemBackend(t *testing.T) logical.Backend {
@@ -2063,6 +2281,15 @@ func testCoreSystemBackendRaw(t *testing.T) (*Core, logical.Backend, string) {
 	return c, c.systemBackend, root
 }
 
+func testSystemBackendRawAllowDestructive(t *testing.T, b logical.Backend) {
+	t.Helper()
+	req := logical.TestRequest(t, logical.UpdateOperation, "config/raw")
+	req.Data["allow_destructive"] = true
+	if _, err := b.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestSystemBackend_PluginCatalog_CRUD(t *testing.T) {
 	c, b, _ := testCoreSystemBackend(t)
 	// Bootstrap the pluginCatalog
@@ -3094,6 +3321,44 @@ func TestHandlePoliciesPasswordDelete(t *testing.T) {
 	}
 }
 
+func TestHandlePoliciesPasswordList(t *testing.T) {
+	ctx := context.Background()
+	b := &SystemBackend{}
+
+	resp, err := b.handlePoliciesPasswordList(ctx, &logical.Request{Storage: new(logical.InmemStorage)}, nil)
+	if err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+	if keys, ok := resp.Data["keys"]; ok && keys != nil {
+		t.Fatalf("expected no policies, got: %#v", resp.Data)
+	}
+
+	policy := "length = 20\n" +
+		"rule \"charset\" {\n" +
+		"	charset=\"abcdefghij\"\n" +
+		"}"
+	storage := makeStorage(t,
+		storageEntry(t, "testpolicy", policy),
+		storageEntry(t, "team/otherpolicy", policy),
+		&logical.StorageEntry{Key: "unrelated", Value: []byte("{}")},
+	)
+	resp, err = b.handlePoliciesPasswordList(ctx, &logical.Request{Storage: storage}, nil)
+	if err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+	keys := resp.Data["keys"].([]string)
+	sort.Strings(keys)
+	expected := []string{"team/otherpolicy", "testpolicy"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Actual: %#v\nExpected: %#v", keys, expected)
+	}
+
+	_, err = b.handlePoliciesPasswordList(ctx, &logical.Request{Storage: new(logical.InmemStorage).FailList(true)}, nil)
+	if err == nil {
+		t.Fatalf("err expected, got nil")
+	}
+}
+
 func TestHandlePoliciesPasswordGenerate(t *testing.T) {
 	t.Run("errors", func(t *testing.T) {
 		type testCase struct {
@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// lockedUsersPaths returns the paths used to list and unlock the users locked
+// out of the auth mounts
+func (b *SystemBackend) lockedUsersPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "locked-users/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount to list the locked users of. Defaults to every auth mount of the namespace.",
+					Query:       true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLockedUsersRead(),
+					Summary:  "List the users locked out of the auth mounts.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(lockedUsersHelp["locked-users"][0]),
+			HelpDescription: strings.TrimSpace(lockedUsersHelp["locked-users"][1]),
+		},
+		{
+			Pattern: "locked-users/" + framework.GenericNameRegex("mount_accessor") + "/unlock/" + framework.MatchAllRegex("alias_identifier"),
+			Fields: map[string]*framework.FieldSchema{
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount the user is locked out of.",
+				},
+				"alias_identifier": {
+					Type:        framework.TypeString,
+					Description: "Alias name of the locked user, such as the username of userpass and ldap, or the role ID of approle.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleUnlockUser(),
+					Summary:  "Unlock a user locked out of an auth mount.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(lockedUsersHelp["unlock-user"][0]),
+			HelpDescription: strings.TrimSpace(lockedUsersHelp["unlock-user"][1]),
+		},
+	}
+}
+
+// lockoutMountEntry returns the auth mount with the given accessor, if it is in
+// the namespace of the request or in one of its children
+func (b *SystemBackend) lockoutMountEntry(ns *namespace.Namespace, mountAccessor string) *MountEntry {
+	entry := b.Core.router.MatchingMountByAccessor(mountAccessor)
+	if entry == nil || entry.Table != credentialTableType {
+		return nil
+	}
+	if entry.Namespace().ID != ns.ID && !entry.Namespace().HasParent(ns) {
+		return nil
+	}
+	return entry
+}
+
+func (b *SystemBackend) handleLockedUsersRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []*MountEntry
+		if mountAccessor := d.Get("mount_accessor").(string); mountAccessor != "" {
+			entry := b.lockoutMountEntry(ns, mountAccessor)
+			if entry == nil {
+				return logical.ErrorResponse("no auth mount found for accessor %q", mountAccessor), logical.ErrInvalidRequest
+			}
+			entries = append(entries, entry)
+		} else {
+			b.Core.authLock.RLock()
+			for _, entry := range b.Core.auth.Entries {
+				if entry.Namespace().ID == ns.ID || entry.Namespace().HasParent(ns) {
+					entries = append(entries, entry)
+				}
+			}
+			b.Core.authLock.RUnlock()
+		}
+
+		now := time.Now()
+		lockedUsers := []map[string]interface{}{}
+		for _, entry := range entries {
+			if entry.Config.UserLockoutConfig == nil {
+				continue
+			}
+			users, err := b.Core.lockedUsers(ctx, entry)
+			if err != nil {
+				return nil, err
+			}
+			for _, user := range users {
+				lockedUsers = append(lockedUsers, map[string]interface{}{
+					"mount_accessor":        entry.Accessor,
+					"mount_path":            entry.Namespace().Path + credentialRoutePrefix + entry.Path,
+					"alias_identifier":      user.AliasName,
+					"failed_login_attempts": user.Count,
+					"last_failed_login":     user.LastFailedLogin.Format(time.RFC3339),
+					"lockout_expiration":    user.lockedUntil(entry.Config.UserLockoutConfig, now).Format(time.RFC3339),
+				})
+			}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"locked_users": lockedUsers,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleUnlockUser() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		mountAccessor := d.Get("mount_accessor").(string)
+		aliasName := d.Get("alias_identifier").(string)
+		if aliasName == "" {
+			return logical.ErrorResponse("missing alias_identifier"), logical.ErrInvalidRequest
+		}
+		entry := b.lockoutMountEntry(ns, mountAccessor)
+		if entry == nil {
+			return logical.ErrorResponse("no auth mount found for accessor %q", mountAccessor), logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.unlockUser(ctx, entry.Accessor, aliasName); err != nil {
+			return nil, err
+		}
+		b.Core.logger.Info("user unlocked", "mount_point", entry.Path, "mount_accessor", entry.Accessor)
+		return nil, nil
+	}
+}
+
+var lockedUsersHelp = map[string][2]string{
+	"locked-users": {
+		"List the users locked out of the auth mounts.",
+		`
+The users of the auth mounts with a user lockout configuration are locked out
+once their failed logins reach the lockout threshold of the mount. This returns
+the locked users of the auth mounts of the namespace and of its children, or of
+the auth mount with the given accessor.
+		`,
+	},
+	"unlock-user": {
+		"Unlock a user locked out of an auth mount.",
+		`
+This clears the failed logins of the user with the given alias name, such as the
+username of userpass and ldap or the role ID of approle, on the auth mount with
+the given accessor. The user can log in again right away.
+		`,
+	},
+}
@@ -0,0 +1,630 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	duoapi "github.com/duosecurity/duo_api_golang"
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/identity/mfa"
+	"github.com/hashicorp/vault/helper/mfa/duo"
+	"github.com/hashicorp/vault/helper/mfa/webauthn"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	otplib "github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// mfaValidatePath is the path completing the logins subject to MFA
+	mfaValidatePath = "sys/mfa/validate"
+
+	// loginMFARequestTTL is how long a login waits for the validation of its
+	// MFA requirement
+	loginMFARequestTTL = 5 * time.Minute
+)
+
+// newDuoAuthClient returns the client of the Duo Auth API used to validate
+// the Duo MFA methods
+var newDuoAuthClient = func(config *mfa.DuoConfig) duo.AuthClient {
+	return authapi.NewAuthApi(*duoapi.NewDuoApi(
+		config.IntegrationKey,
+		config.SecretKey,
+		config.APIHostname,
+		"vault",
+		duoapi.SetTimeout(30*time.Second),
+	))
+}
+
+// authenticatedLogin is a login whose token is about to be created
+type authenticatedLogin struct {
+	auth             *logical.Auth
+	path             string
+	mountType        string
+	mountPoint       string
+	tokenTTL         time.Duration
+	identityPolicies map[string][]string
+	allPolicies      []string
+}
+
+// mfaConstraint is satisfied by the validation of any of its MFA methods
+type mfaConstraint struct {
+	name    string
+	methods []*mfa.Config
+}
+
+// loginMFARequest is a login waiting for the validation of its MFA
+// requirement on sys/mfa/validate
+type loginMFARequest struct {
+	login       *authenticatedLogin
+	namespace   *namespace.Namespace
+	entityID    string
+	aliasName   string
+	remoteAddr  string
+	constraints []*mfaConstraint
+	expiration  time.Time
+
+	// webAuthnChallenge is the challenge signed by the authenticators of the
+	// WebAuthn methods of the constraints
+	webAuthnChallenge []byte
+}
+
+// webAuthnChallenge is the challenge of a pending WebAuthn registration
+type webAuthnChallenge struct {
+	challenge  []byte
+	expiration time.Time
+}
+
+// webAuthnAssertion is the response of an authenticator to the challenge of a
+// login, given as the MFA credential of a WebAuthn method
+type webAuthnAssertion struct {
+	CredentialID      string `json:"credential_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+}
+
+// loginMFAConstraints returns a constraint for each login enforcement that
+// applies to the login request
+func (c *Core) loginMFAConstraints(ctx context.Context, req *logical.Request, entity *identity.Entity) ([]*mfaConstraint, error) {
+	i := c.identityStore
+	if i == nil {
+		return nil, nil
+	}
+
+	var entityID string
+	var groupIDs []string
+	if entity != nil {
+		entityID = entity.ID
+		groups, inheritedGroups, err := i.groupsByEntityID(entity.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range append(groups, inheritedGroups...) {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+
+	i.mfaLock.RLock()
+	defer i.mfaLock.RUnlock()
+
+	enforcements, err := i.mfaLoginEnforcements(ctx, i.view)
+	if err != nil {
+		return nil, err
+	}
+
+	var constraints []*mfaConstraint
+	for _, enforcement := range enforcements {
+		switch {
+		case strutil.StrListContains(enforcement.AuthMethodAccessors, req.MountAccessor):
+		case strutil.StrListContains(enforcement.AuthMethodTypes, req.MountType):
+		case entityID != "" && strutil.StrListContains(enforcement.IdentityEntityIDs, entityID):
+		case strListContainsAny(enforcement.IdentityGroupIDs, groupIDs):
+		default:
+			continue
+		}
+
+		constraint := &mfaConstraint{
+			name: enforcement.Name,
+		}
+		for _, methodName := range enforcement.MFAMethodNames {
+			config, err := i.mfaMethod(ctx, i.view, methodName)
+			if err != nil {
+				return nil, err
+			}
+			if config != nil {
+				constraint.methods = append(constraint.methods, config)
+			}
+		}
+		if len(constraint.methods) == 0 {
+			return nil, fmt.Errorf("login enforcement %q has no MFA methods", enforcement.Name)
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints, nil
+}
+
+// strListContainsAny reports whether the haystack contains any of the needles
+func strListContainsAny(haystack, needles []string) bool {
+	for _, needle := range needles {
+		if strutil.StrListContains(haystack, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceLoginMFA checks the MFA requirement of an authenticated login. The
+// login is validated with the MFA credentials of the request when they are
+// supplied. Otherwise, it waits for its validation on sys/mfa/validate and the
+// returned response holds its MFA requirement. A nil response means the token
+// of the login can be created.
+func (c *Core) enforceLoginMFA(ctx context.Context, req *logical.Request, entity *identity.Entity, login *authenticatedLogin) (*logical.Response, error) {
+	constraints, err := c.loginMFAConstraints(ctx, req, entity)
+	if err != nil {
+		return nil, err
+	}
+	if len(constraints) == 0 {
+		return nil, nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaReq := &loginMFARequest{
+		login:       login,
+		namespace:   ns,
+		constraints: constraints,
+		expiration:  time.Now().Add(loginMFARequestTTL),
+	}
+	if entity != nil {
+		mfaReq.entityID = entity.ID
+	}
+	if login.auth.Alias != nil {
+		mfaReq.aliasName = login.auth.Alias.Name
+	}
+	if req.Connection != nil {
+		mfaReq.remoteAddr = req.Connection.RemoteAddr
+	}
+
+	if len(req.MFACreds) > 0 {
+		if err := c.validateMFAConstraints(ctx, mfaReq, req.MFACreds); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+		return nil, nil
+	}
+
+	requestID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	mfaReq.webAuthnChallenge, err = webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	c.storeLoginMFARequest(requestID, mfaReq)
+
+	mfaConstraints := make(map[string]interface{}, len(constraints))
+	for _, constraint := range constraints {
+		methods := make([]map[string]interface{}, 0, len(constraint.methods))
+		for _, method := range constraint.methods {
+			methodData := map[string]interface{}{
+				"type":          method.Type,
+				"id":            method.ID,
+				"name":          method.Name,
+				"uses_passcode": method.Type == mfaMethodTypeTOTP,
+			}
+			if method.Type == mfaMethodTypeWebAuthn {
+				addWebAuthnRequestOptions(methodData, method, entity, mfaReq.webAuthnChallenge)
+			}
+			methods = append(methods, methodData)
+		}
+		mfaConstraints[constraint.name] = map[string]interface{}{
+			"any": methods,
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"mfa_request_id":  requestID,
+			"mfa_constraints": mfaConstraints,
+		},
+	}
+	resp.AddWarning("The login is subject to MFA validation. Complete it by sending the MFA credentials to the sys/mfa/validate endpoint.")
+	return resp, nil
+}
+
+// storeLoginMFARequest keeps the login until its MFA requirement is
+// validated, and drops the expired ones
+func (c *Core) storeLoginMFARequest(requestID string, mfaReq *loginMFARequest) {
+	c.loginMFALock.Lock()
+	defer c.loginMFALock.Unlock()
+
+	if c.loginMFARequests == nil {
+		c.loginMFARequests = make(map[string]*loginMFARequest)
+	}
+	now := time.Now()
+	for id, pending := range c.loginMFARequests {
+		if now.After(pending.expiration) {
+			delete(c.loginMFARequests, id)
+		}
+	}
+	c.loginMFARequests[requestID] = mfaReq
+}
+
+// addWebAuthnRequestOptions adds the options of the authentication with a
+// WebAuthn method to its description in the MFA requirement of a login
+func addWebAuthnRequestOptions(methodData map[string]interface{}, config *mfa.Config, entity *identity.Entity, challenge []byte) {
+	webAuthnConfig := config.GetWebAuthnConfig()
+	allowCredentials := []string{}
+	if entity != nil {
+		for _, credential := range entity.MFASecrets[config.ID].GetWebAuthnSecret().GetCredentials() {
+			allowCredentials = append(allowCredentials, webauthn.EncodeBase64(credential.ID))
+		}
+	}
+	userVerification := "preferred"
+	if webAuthnConfig.GetUserVerification() {
+		userVerification = "required"
+	}
+
+	methodData["challenge"] = webauthn.EncodeBase64(challenge)
+	methodData["relying_party_id"] = webAuthnConfig.GetRelyingPartyID()
+	methodData["allow_credentials"] = allowCredentials
+	methodData["user_verification"] = userVerification
+}
+
+// storeWebAuthnChallenge keeps the challenge of a WebAuthn registration until
+// it's completed, and drops the expired ones. A new registration replaces the
+// pending one of the same method and entity.
+func (c *Core) storeWebAuthnChallenge(key string, challenge []byte) {
+	c.loginMFALock.Lock()
+	defer c.loginMFALock.Unlock()
+
+	if c.webAuthnChallenges == nil {
+		c.webAuthnChallenges = make(map[string]*webAuthnChallenge)
+	}
+	now := time.Now()
+	for pendingKey, pending := range c.webAuthnChallenges {
+		if now.After(pending.expiration) {
+			delete(c.webAuthnChallenges, pendingKey)
+		}
+	}
+	c.webAuthnChallenges[key] = &webAuthnChallenge{
+		challenge:  challenge,
+		expiration: now.Add(loginMFARequestTTL),
+	}
+}
+
+// takeWebAuthnChallenge returns the challenge of a pending WebAuthn
+// registration, or nil if there is none. A challenge can only be used once.
+func (c *Core) takeWebAuthnChallenge(key string) []byte {
+	c.loginMFALock.Lock()
+	defer c.loginMFALock.Unlock()
+
+	pending := c.webAuthnChallenges[key]
+	delete(c.webAuthnChallenges, key)
+	if pending == nil || time.Now().After(pending.expiration) {
+		return nil
+	}
+	return pending.challenge
+}
+
+// validateLoginMFA validates the MFA requirement of a login waiting on
+// sys/mfa/validate, and returns the login. A login can only be validated
+// once, whether the validation succeeds or not.
+func (c *Core) validateLoginMFA(ctx context.Context, requestID string, creds logical.MFACreds) (*loginMFARequest, error) {
+	c.loginMFALock.Lock()
+	mfaReq := c.loginMFARequests[requestID]
+	delete(c.loginMFARequests, requestID)
+	c.loginMFALock.Unlock()
+
+	if mfaReq == nil || time.Now().After(mfaReq.expiration) {
+		return nil, fmt.Errorf("MFA request %q not found or expired", requestID)
+	}
+	if err := c.validateMFAConstraints(ctx, mfaReq, creds); err != nil {
+		return nil, err
+	}
+	return mfaReq, nil
+}
+
+// validateMFAConstraints checks that each constraint of the login is
+// satisfied by one of its methods
+func (c *Core) validateMFAConstraints(ctx context.Context, mfaReq *loginMFARequest, creds logical.MFACreds) error {
+	var entity *identity.Entity
+	if mfaReq.entityID != "" {
+		var err error
+		entity, err = c.identityStore.MemDBEntityByID(mfaReq.entityID, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, constraint := range mfaReq.constraints {
+		var errs *multierror.Error
+		var methodNames []string
+		satisfied := false
+		for _, method := range constraint.methods {
+			methodNames = append(methodNames, method.Name)
+			passcodes, ok := creds[method.Name]
+			if !ok {
+				continue
+			}
+			err := c.validateMFAMethod(ctx, method, entity, mfaReq.aliasName, passcodes, mfaReq.remoteAddr, mfaReq.webAuthnChallenge)
+			if err == nil {
+				satisfied = true
+				break
+			}
+			errs = multierror.Append(errs, err)
+		}
+		if satisfied {
+			continue
+		}
+		if errs != nil {
+			return errs
+		}
+		return fmt.Errorf("login enforcement %q requires the validation of one of the MFA methods: %s", constraint.name, strings.Join(methodNames, ", "))
+	}
+	return nil
+}
+
+// validatePathMFA checks that the MFA credentials of the request validate all
+// the MFA methods required by the policies granting access to its path
+func (c *Core) validatePathMFA(ctx context.Context, req *logical.Request, entity *identity.Entity, methodNames []string) error {
+	if entity == nil {
+		return fmt.Errorf("MFA is required but the token of the request has no entity")
+	}
+
+	i := c.identityStore
+	for _, methodName := range methodNames {
+		i.mfaLock.RLock()
+		config, err := i.mfaMethod(ctx, i.view, methodName)
+		i.mfaLock.RUnlock()
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			return fmt.Errorf("MFA method %q not found", methodName)
+		}
+
+		passcodes, ok := req.MFACreds[methodName]
+		if !ok {
+			return fmt.Errorf("MFA credentials for the method %q are required", methodName)
+		}
+		var remoteAddr string
+		if req.Connection != nil {
+			remoteAddr = req.Connection.RemoteAddr
+		}
+		if err := c.validateMFAMethod(ctx, config, entity, "", passcodes, remoteAddr, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMFAMethod validates the MFA credentials of the method for the
+// entity. The alias name is the name of the alias of the login, if any, and
+// the challenge is the WebAuthn challenge of the login MFA request, if any.
+func (c *Core) validateMFAMethod(ctx context.Context, config *mfa.Config, entity *identity.Entity, aliasName string, passcodes []string, remoteAddr string, challenge []byte) error {
+	switch config.Type {
+	case mfaMethodTypeTOTP:
+		return c.validateTOTP(config, entity, passcodes)
+	case mfaMethodTypeDuo:
+		return validateDuo(config, entity, aliasName, passcodes, remoteAddr)
+	case mfaMethodTypeWebAuthn:
+		return c.validateWebAuthn(ctx, config, entity, passcodes, challenge)
+	default:
+		return fmt.Errorf("unsupported type %q of MFA method %q", config.Type, config.Name)
+	}
+}
+
+func (c *Core) validateTOTP(config *mfa.Config, entity *identity.Entity, passcodes []string) error {
+	if entity == nil {
+		return fmt.Errorf("MFA method %q requires an entity", config.Name)
+	}
+	secret := entity.MFASecrets[config.ID].GetTOTPSecret()
+	if secret == nil {
+		return fmt.Errorf("the entity has no TOTP secret for the MFA method %q", config.Name)
+	}
+	if len(passcodes) != 1 || passcodes[0] == "" {
+		return fmt.Errorf("a single passcode is required for the MFA method %q", config.Name)
+	}
+	passcode := passcodes[0]
+
+	valid, err := totp.ValidateCustom(passcode, secret.Key, time.Now(), totp.ValidateOpts{
+		Period:    uint(secret.Period),
+		Skew:      uint(secret.Skew),
+		Digits:    otplib.Digits(secret.Digits),
+		Algorithm: otplib.Algorithm(secret.Algorithm),
+	})
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return errwrap.Wrapf("failed to validate the passcode: {{err}}", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid passcode for the MFA method %q", config.Name)
+	}
+
+	// A passcode can't be used again while it's valid
+	validity := time.Duration(secret.Period) * time.Second * time.Duration(2*secret.Skew+1)
+	if !c.useMFAPasscode(config.ID+"/"+entity.ID+"/"+passcode, validity) {
+		return fmt.Errorf("the passcode of the MFA method %q was already used", config.Name)
+	}
+	return nil
+}
+
+// useMFAPasscode records the use of a passcode, and reports whether it wasn't
+// used already
+func (c *Core) useMFAPasscode(key string, validity time.Duration) bool {
+	c.loginMFALock.Lock()
+	defer c.loginMFALock.Unlock()
+
+	if c.usedMFAPasscodes == nil {
+		c.usedMFAPasscodes = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for usedKey, expiration := range c.usedMFAPasscodes {
+		if now.After(expiration) {
+			delete(c.usedMFAPasscodes, usedKey)
+		}
+	}
+	if _, ok := c.usedMFAPasscodes[key]; ok {
+		return false
+	}
+	c.usedMFAPasscodes[key] = now.Add(validity)
+	return true
+}
+
+func validateDuo(config *mfa.Config, entity *identity.Entity, aliasName string, passcodes []string, remoteAddr string) error {
+	duoConfig := config.GetDuoConfig()
+	if duoConfig == nil {
+		return fmt.Errorf("MFA method %q has no Duo configuration", config.Name)
+	}
+
+	var username string
+	switch {
+	case config.MountAccessor != "":
+		if entity != nil {
+			username = entityAliasName(entity, config.MountAccessor)
+		}
+	case aliasName != "":
+		username = aliasName
+	case entity != nil:
+		username = entity.Name
+	}
+	if username == "" {
+		return fmt.Errorf("could not find the Duo username for the MFA method %q", config.Name)
+	}
+	usernameFormat := config.UsernameFormat
+	if usernameFormat == "" {
+		usernameFormat = "%s"
+	}
+
+	options := []func(*url.Values){authapi.AuthUsername(fmt.Sprintf(usernameFormat, username))}
+	if remoteAddr != "" {
+		options = append(options, authapi.AuthIpAddr(remoteAddr))
+	}
+	factor := "push"
+	if len(passcodes) > 0 && passcodes[0] != "" {
+		factor = "passcode"
+		options = append(options, authapi.AuthPasscode(passcodes[0]))
+	} else {
+		options = append(options, authapi.AuthDevice("auto"))
+		if duoConfig.PushInfo != "" {
+			options = append(options, authapi.AuthPushinfo(duoConfig.PushInfo))
+		}
+	}
+
+	result, err := newDuoAuthClient(duoConfig).Auth(factor, options...)
+	if err != nil || result == nil {
+		return fmt.Errorf("could not call Duo auth for the MFA method %q", config.Name)
+	}
+	if result.StatResult.Stat != "OK" {
+		errorMsg := "could not authenticate Duo user"
+		if result.StatResult.Message != nil {
+			errorMsg = errorMsg + ": " + *result.StatResult.Message
+		}
+		if result.StatResult.Message_Detail != nil {
+			errorMsg = errorMsg + " (" + *result.StatResult.Message_Detail + ")"
+		}
+		return fmt.Errorf("%s", errorMsg)
+	}
+	if result.Response.Result != "allow" {
+		return fmt.Errorf("Duo denied the MFA method %q: %s", config.Name, result.Response.Status_Msg)
+	}
+	return nil
+}
+
+// validateWebAuthn verifies the assertion of one of the WebAuthn credentials
+// of the entity for the challenge, and records the signature counter of the
+// authenticator
+func (c *Core) validateWebAuthn(ctx context.Context, config *mfa.Config, entity *identity.Entity, passcodes []string, challenge []byte) error {
+	if entity == nil {
+		return fmt.Errorf("MFA method %q requires an entity", config.Name)
+	}
+	if challenge == nil {
+		return fmt.Errorf("MFA method %q requires the challenge of a login MFA request, and must be validated on %s", config.Name, mfaValidatePath)
+	}
+	if len(passcodes) != 1 || passcodes[0] == "" {
+		return fmt.Errorf("a single WebAuthn assertion is required for the MFA method %q", config.Name)
+	}
+
+	var assertion webAuthnAssertion
+	if err := jsonutil.DecodeJSON([]byte(passcodes[0]), &assertion); err != nil {
+		return fmt.Errorf("invalid WebAuthn assertion for the MFA method %q", config.Name)
+	}
+	var credentialID, clientDataJSON, authData, signature []byte
+	for _, field := range []struct {
+		value  string
+		target *[]byte
+	}{
+		{assertion.CredentialID, &credentialID},
+		{assertion.ClientDataJSON, &clientDataJSON},
+		{assertion.AuthenticatorData, &authData},
+		{assertion.Signature, &signature},
+	} {
+		decoded, err := webauthn.DecodeBase64(field.value)
+		if err != nil || len(decoded) == 0 {
+			return fmt.Errorf("invalid WebAuthn assertion for the MFA method %q", config.Name)
+		}
+		*field.target = decoded
+	}
+
+	// The entity is locked while the counter is checked and updated, so that
+	// an assertion can't be replayed concurrently
+	i := c.identityStore
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.MemDBEntityByID(entity.ID, true)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("entity not found")
+	}
+	var stored *mfa.WebAuthnCredential
+	for _, credential := range entity.MFASecrets[config.ID].GetWebAuthnSecret().GetCredentials() {
+		if bytes.Equal(credential.ID, credentialID) {
+			stored = credential
+			break
+		}
+	}
+	if stored == nil {
+		return fmt.Errorf("the entity has no such WebAuthn credential for the MFA method %q", config.Name)
+	}
+
+	signCount, err := webauthn.VerifyAssertion(webAuthnOptions(config.GetWebAuthnConfig()), &webauthn.Credential{
+		ID:        stored.ID,
+		PublicKey: stored.PublicKey,
+		SignCount: stored.SignCount,
+	}, challenge, clientDataJSON, authData, signature)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to verify the WebAuthn assertion of the MFA method %q: {{err}}", config.Name), err)
+	}
+
+	if signCount == stored.SignCount {
+		return nil
+	}
+	stored.SignCount = signCount
+	return i.upsertEntity(ctx, entity, nil, true)
+}
+
+// webAuthnOptions returns the relying party of a WebAuthn method
+func webAuthnOptions(config *mfa.WebAuthnConfig) *webauthn.Options {
+	return &webauthn.Options{
+		RPID:             config.GetRelyingPartyID(),
+		Origins:          config.GetOrigins(),
+		UserVerification: config.GetUserVerification(),
+	}
+}
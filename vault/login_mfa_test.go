@@ -0,0 +1,411 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/identity/mfa"
+	"github.com/hashicorp/vault/helper/mfa/webauthn"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	otplib "github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestLoginMFA_TOTP(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	request := func(req *logical.Request) (*logical.Response, error) {
+		t.Helper()
+		if req.Operation == "" {
+			req.Operation = logical.UpdateOperation
+		}
+		req.Connection = &logical.Connection{}
+		return core.HandleRequest(ctx, req)
+	}
+	mustRequest := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		if req.ClientToken == "" {
+			req.ClientToken = root
+		}
+		resp, err := request(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("path %q: err: %v, resp: %#v", req.Path, err, resp)
+		}
+		return resp
+	}
+	login := func(creds logical.MFACreds) (*logical.Response, error) {
+		t.Helper()
+		return request(&logical.Request{
+			Path:     "auth/userpass/login/test",
+			Data:     map[string]interface{}{"password": "foo"},
+			MFACreds: creds,
+		})
+	}
+
+	mustRequest(&logical.Request{
+		Path: "sys/auth/userpass",
+		Data: map[string]interface{}{"type": "userpass"},
+	})
+	mustRequest(&logical.Request{
+		Path: "auth/userpass/users/test",
+		Data: map[string]interface{}{"password": "foo", "policies": "mfa-path"},
+	})
+	mustRequest(&logical.Request{
+		Path: "sys/policies/acl/mfa-path",
+		Data: map[string]interface{}{"policy": `
+path "sys/mounts" {
+	capabilities = ["read"]
+	mfa_methods = ["my_totp"]
+}`},
+	})
+
+	// The first login creates the entity
+	resp, err := login(nil)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	entityID := resp.Auth.EntityID
+
+	mustRequest(&logical.Request{
+		Path: "identity/mfa/method/totp/my_totp",
+		Data: map[string]interface{}{"issuer": "Vault"},
+	})
+	resp = mustRequest(&logical.Request{
+		Path: "identity/mfa/method/totp/my_totp/admin-generate",
+		Data: map[string]interface{}{"entity_id": entityID},
+	})
+	if resp.Data["barcode"] == "" {
+		t.Fatalf("missing barcode: %#v", resp.Data)
+	}
+	key, err := otplib.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The method can't be deleted while an enforcement uses it
+	mustRequest(&logical.Request{
+		Path: "identity/mfa/login-enforcement/userpass",
+		Data: map[string]interface{}{
+			"mfa_method_names":  "my_totp",
+			"auth_method_types": "userpass",
+		},
+	})
+	resp, err = request(&logical.Request{
+		Path:        "identity/mfa/method/totp/my_totp",
+		Operation:   logical.DeleteOperation,
+		ClientToken: root,
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	mfaRequestID := func() string {
+		t.Helper()
+		resp, err := login(nil)
+		if err != nil || resp == nil || resp.Auth != nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		constraints := resp.Data["mfa_constraints"].(map[string]interface{})
+		if _, ok := constraints["userpass"]; !ok {
+			t.Fatalf("bad: constraints %#v", constraints)
+		}
+		return resp.Data["mfa_request_id"].(string)
+	}
+
+	// A wrong passcode consumes the MFA request
+	requestID := mfaRequestID()
+	for i := 0; i < 2; i++ {
+		resp, err = request(&logical.Request{
+			Path: "sys/mfa/validate",
+			Data: map[string]interface{}{
+				"mfa_request_id": requestID,
+				"mfa_payload":    map[string]interface{}{"my_totp": []interface{}{"000000"}},
+			},
+		})
+		if err == nil || !resp.IsError() {
+			t.Fatalf("expected an error, got %#v", resp)
+		}
+	}
+
+	now := time.Now()
+	passcode, err := totp.GenerateCode(key.Secret(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(&logical.Request{
+		Path: "sys/mfa/validate",
+		Data: map[string]interface{}{
+			"mfa_request_id": mfaRequestID(),
+			"mfa_payload":    map[string]interface{}{"my_totp": []interface{}{passcode}},
+		},
+	})
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Auth.EntityID != entityID {
+		t.Fatalf("bad: entity ID %q", resp.Auth.EntityID)
+	}
+	token := resp.Auth.ClientToken
+	resp = mustRequest(&logical.Request{
+		Path:        "auth/token/lookup-self",
+		Operation:   logical.ReadOperation,
+		ClientToken: token,
+	})
+	if resp.Data["path"] != "auth/userpass/login/test" {
+		t.Fatalf("bad: token path %v", resp.Data["path"])
+	}
+
+	// A passcode can't be used twice
+	resp, err = login(logical.MFACreds{"my_totp": {passcode}})
+	if err == nil || !strings.Contains(resp.Error().Error(), "already used") {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// The MFA credentials can be supplied with the login
+	passcode, err = totp.GenerateCode(key.Secret(), now.Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = login(logical.MFACreds{"my_totp": {passcode}})
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The paths with MFA methods require MFA credentials
+	_, err = request(&logical.Request{
+		Path:        "sys/mounts",
+		Operation:   logical.ReadOperation,
+		ClientToken: token,
+	})
+	if err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	passcode, err = totp.GenerateCode(key.Secret(), now.Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRequest(&logical.Request{
+		Path:        "sys/mounts",
+		Operation:   logical.ReadOperation,
+		ClientToken: token,
+		MFACreds:    logical.MFACreds{"my_totp": {passcode}},
+	})
+}
+
+func TestLoginMFA_WebAuthn(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	request := func(req *logical.Request) (*logical.Response, error) {
+		t.Helper()
+		if req.Operation == "" {
+			req.Operation = logical.UpdateOperation
+		}
+		req.Connection = &logical.Connection{}
+		return core.HandleRequest(ctx, req)
+	}
+	mustRequest := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		if req.ClientToken == "" {
+			req.ClientToken = root
+		}
+		resp, err := request(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("path %q: err: %v, resp: %#v", req.Path, err, resp)
+		}
+		return resp
+	}
+	login := func() (*logical.Response, error) {
+		t.Helper()
+		return request(&logical.Request{
+			Path: "auth/userpass/login/test",
+			Data: map[string]interface{}{"password": "foo"},
+		})
+	}
+
+	mustRequest(&logical.Request{
+		Path: "sys/auth/userpass",
+		Data: map[string]interface{}{"type": "userpass"},
+	})
+	mustRequest(&logical.Request{
+		Path: "auth/userpass/users/test",
+		Data: map[string]interface{}{"password": "foo", "policies": "webauthn-register"},
+	})
+	mustRequest(&logical.Request{
+		Path: "sys/policies/acl/webauthn-register",
+		Data: map[string]interface{}{"policy": `
+path "identity/mfa/method/webauthn/my_webauthn/register" {
+	capabilities = ["update"]
+}`},
+	})
+	mustRequest(&logical.Request{
+		Path: "identity/mfa/method/webauthn/my_webauthn",
+		Data: map[string]interface{}{
+			"relying_party_id": "example.com",
+			"origins":          "https://vault.example.com",
+		},
+	})
+	resp := mustRequest(&logical.Request{
+		Path:      "identity/mfa/method/webauthn/my_webauthn",
+		Operation: logical.ReadOperation,
+	})
+	if resp.Data["relying_party_name"] != "example.com" || resp.Data["user_verification"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The first login creates the entity, whose token registers the
+	// credential of the authenticator
+	resp, err := login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	entityID := resp.Auth.EntityID
+	token := resp.Auth.ClientToken
+
+	authenticator := webauthn.NewTestAuthenticator(t, "example.com", "https://vault.example.com")
+	resp = mustRequest(&logical.Request{
+		Path:        "identity/mfa/method/webauthn/my_webauthn/register",
+		ClientToken: token,
+	})
+	if resp.Data["relying_party_id"] != "example.com" || resp.Data["user_name"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	challenge, err := webauthn.DecodeBase64(resp.Data["challenge"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, attestation := authenticator.Register(challenge)
+	resp = mustRequest(&logical.Request{
+		Path:        "identity/mfa/method/webauthn/my_webauthn/register",
+		ClientToken: token,
+		Data: map[string]interface{}{
+			"client_data_json":   webauthn.EncodeBase64(clientDataJSON),
+			"attestation_object": webauthn.EncodeBase64(attestation),
+		},
+	})
+	credentialID := webauthn.EncodeBase64(authenticator.ID())
+	if resp.Data["credential_id"] != credentialID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The challenge of a registration can only be used once
+	resp, err = request(&logical.Request{
+		Path:        "identity/mfa/method/webauthn/my_webauthn/register",
+		ClientToken: token,
+		Data: map[string]interface{}{
+			"client_data_json":   webauthn.EncodeBase64(clientDataJSON),
+			"attestation_object": webauthn.EncodeBase64(attestation),
+		},
+	})
+	if err == nil || !strings.Contains(resp.Error().Error(), "no pending registration") {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	mustRequest(&logical.Request{
+		Path: "identity/mfa/login-enforcement/userpass",
+		Data: map[string]interface{}{
+			"mfa_method_names":  "my_webauthn",
+			"auth_method_types": "userpass",
+		},
+	})
+
+	mfaRequest := func() (string, []byte) {
+		t.Helper()
+		resp, err := login()
+		if err != nil || resp == nil || resp.Auth != nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		constraint := resp.Data["mfa_constraints"].(map[string]interface{})["userpass"].(map[string]interface{})
+		method := constraint["any"].([]map[string]interface{})[0]
+		allowed := method["allow_credentials"].([]string)
+		if method["relying_party_id"] != "example.com" || len(allowed) != 1 || allowed[0] != credentialID {
+			t.Fatalf("bad: method %#v", method)
+		}
+		challenge, err := webauthn.DecodeBase64(method["challenge"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data["mfa_request_id"].(string), challenge
+	}
+	assertion := func(challenge []byte) map[string]interface{} {
+		clientDataJSON, authData, signature := authenticator.Assert(challenge)
+		return map[string]interface{}{
+			"credential_id":      credentialID,
+			"client_data_json":   webauthn.EncodeBase64(clientDataJSON),
+			"authenticator_data": webauthn.EncodeBase64(authData),
+			"signature":          webauthn.EncodeBase64(signature),
+		}
+	}
+	validate := func(requestID string, payload map[string]interface{}) (*logical.Response, error) {
+		return request(&logical.Request{
+			Path: "sys/mfa/validate",
+			Data: map[string]interface{}{
+				"mfa_request_id": requestID,
+				"mfa_payload":    map[string]interface{}{"my_webauthn": payload},
+			},
+		})
+	}
+
+	// An assertion of the challenge of another MFA request is rejected
+	_, otherChallenge := mfaRequest()
+	requestID, _ := mfaRequest()
+	resp, err = validate(requestID, assertion(otherChallenge))
+	if err == nil || !strings.Contains(resp.Error().Error(), "challenge") {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	requestID, challenge = mfaRequest()
+	payload := assertion(challenge)
+	resp, err = validate(requestID, payload)
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Auth.EntityID != entityID {
+		t.Fatalf("bad: entity ID %q", resp.Auth.EntityID)
+	}
+
+	// The signature counter of the authenticator is recorded
+	entity, err := core.identityStore.MemDBEntityByID(entityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var credentials []*mfa.WebAuthnCredential
+	for _, secret := range entity.MFASecrets {
+		credentials = append(credentials, secret.GetWebAuthnSecret().GetCredentials()...)
+	}
+	if len(credentials) != 1 || credentials[0].SignCount != 2 {
+		t.Fatalf("bad: credentials %#v", credentials)
+	}
+
+	// WebAuthn can't validate the MFA credentials supplied with the login,
+	// which have no challenge
+	resp, err = request(&logical.Request{
+		Path:     "auth/userpass/login/test",
+		Data:     map[string]interface{}{"password": "foo"},
+		MFACreds: logical.MFACreds{"my_webauthn": {"{}"}},
+	})
+	if err == nil || !strings.Contains(resp.Error().Error(), "sys/mfa/validate") {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// The credentials of the entity are destroyed with the admin-destroy
+	// endpoint
+	mustRequest(&logical.Request{
+		Path: "identity/mfa/method/webauthn/my_webauthn/admin-destroy",
+		Data: map[string]interface{}{"entity_id": entityID},
+	})
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	resp, err = validate(resp.Data["mfa_request_id"].(string), assertion(challenge))
+	if err == nil || !strings.Contains(resp.Error().Error(), "no such WebAuthn credential") {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
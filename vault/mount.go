@@ -223,6 +223,7 @@ type MountEntry struct {
 	ExternalEntropyAccess bool              `json:"external_entropy_access"` // Whether to allow external entropy source access
 	Tainted               bool              `json:"tainted,omitempty"`       // Set as a Write-Ahead flag for unmount/remount
 	NamespaceID           string            `json:"namespace_id"`
+	Version               string            `json:"plugin_version,omitempty"` // Version of the external plugin pinned by the mount
 
 	// namespace contains the populated namespace
 	namespace *namespace.Namespace
@@ -245,6 +246,10 @@ type MountConfig struct {
 	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
 	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
 	TokenType                 logical.TokenType     `json:"token_type" structs:"token_type" mapstructure:"token_type"`
+	ExternalGroupSync         bool                  `json:"external_group_sync,omitempty" structs:"external_group_sync" mapstructure:"external_group_sync"`
+	UserLockoutConfig         *UserLockoutConfig    `json:"user_lockout_config,omitempty" structs:"user_lockout_config" mapstructure:"user_lockout_config"`
+	MinLeaseTTL               time.Duration         `json:"min_lease_ttl,omitempty" structs:"min_lease_ttl" mapstructure:"min_lease_ttl"`
+	AllowedEntityAliases      []string              `json:"allowed_entity_aliases,omitempty" structs:"allowed_entity_aliases" mapstructure:"allowed_entity_aliases"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -254,15 +259,19 @@ type MountConfig struct {
 
 // APIMountConfig is an embedded struct of api.MountConfigInput
 type APIMountConfig struct {
-	DefaultLeaseTTL           string                `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
-	MaxLeaseTTL               string                `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
-	ForceNoCache              bool                  `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
-	AuditNonHMACRequestKeys   []string              `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
-	AuditNonHMACResponseKeys  []string              `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
-	ListingVisibility         ListingVisibilityType `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
-	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
-	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
-	TokenType                 string                `json:"token_type" structs:"token_type" mapstructure:"token_type"`
+	DefaultLeaseTTL           string                 `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
+	MaxLeaseTTL               string                 `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
+	ForceNoCache              bool                   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
+	AuditNonHMACRequestKeys   []string               `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string               `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         ListingVisibilityType  `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string               `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string               `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+	TokenType                 string                 `json:"token_type" structs:"token_type" mapstructure:"token_type"`
+	ExternalGroupSync         bool                   `json:"external_group_sync,omitempty" structs:"external_group_sync" mapstructure:"external_group_sync"`
+	UserLockoutConfig         map[string]interface{} `json:"user_lockout_config,omitempty" structs:"user_lockout_config" mapstructure:"user_lockout_config"`
+	MinLeaseTTL               string                 `json:"min_lease_ttl,omitempty" structs:"min_lease_ttl" mapstructure:"min_lease_ttl"`
+	AllowedEntityAliases      []string               `json:"allowed_entity_aliases,omitempty" structs:"allowed_entity_aliases" mapstructure:"allowed_entity_aliases"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -1250,13 +1259,17 @@ func (c *Core) unloadMounts(ctx context.Context) error {
 
 // newLogicalBackend is used to create and configure a new logical backend by name
 func (c *Core) newLogicalBackend(ctx context.Context, entry *MountEntry, sysView logical.SystemView, view logical.Storage) (logical.Backend, error) {
+	if backend := c.namespaceSharedBackend(entry); backend != nil {
+		return backend, nil
+	}
+
 	t := entry.Type
 	if alias, ok := mountAliases[t]; ok {
 		t = alias
 	}
 
 	f, ok := c.logicalBackends[t]
-	if !ok {
+	if !ok || entry.Version != "" {
 		f = plugin.Factory
 	}
 
@@ -1273,6 +1286,10 @@ func (c *Core) newLogicalBackend(ctx context.Context, entry *MountEntry, sysView
 		conf["plugin_name"] = t
 	}
 
+	if entry.Version != "" {
+		conf["plugin_version"] = entry.Version
+	}
+
 	conf["plugin_type"] = consts.PluginTypeSecrets.String()
 
 	backendLogger := c.baseLogger.Named(fmt.Sprintf("secrets.%s.%s", t, entry.Accessor))
@@ -1450,15 +1467,20 @@ func (c *Core) singletonMountTables() (mounts, auth *MountTable) {
 }
 
 func (c *Core) setCoreBackend(entry *MountEntry, backend logical.Backend, view *BarrierView) {
+	rootMount := entry.NamespaceID == namespace.RootNamespaceID
 	switch entry.Type {
 	case systemMountType:
-		c.systemBackend = backend.(*SystemBackend)
-		c.systemBarrierView = view
+		if rootMount {
+			c.systemBackend = backend.(*SystemBackend)
+			c.systemBarrierView = view
+		}
 	case cubbyholeMountType:
 		ch := backend.(*CubbyholeBackend)
 		ch.saltUUID = entry.UUID
 		ch.storageView = view
-		c.cubbyholeBackend = ch
+		if rootMount {
+			c.cubbyholeBackend = ch
+		}
 	case identityMountType:
 		c.identityStore = backend.(*IdentityStore)
 	}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/go-test/deep"
+	"github.com/hashicorp/go-kms-wrapping/entropy"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/compressutil"
@@ -804,3 +805,38 @@ func TestCore_MountInitialize(t *testing.T) {
 		}
 	}
 }
+
+type testEntropySourcer struct{}
+
+func (testEntropySourcer) GetRandom(bytes int) ([]byte, error) {
+	return make([]byte, bytes), nil
+}
+
+func TestCore_MountEntrySysView_ExternalEntropy(t *testing.T) {
+	c, _, _ := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		EntropySourcer: testEntropySourcer{},
+	})
+
+	sysView := c.mountEntrySysView(&MountEntry{Path: "foo/"})
+	if _, ok := sysView.(entropy.Sourcer); ok {
+		t.Fatal("expected no entropy access without external_entropy_access")
+	}
+
+	sysView = c.mountEntrySysView(&MountEntry{Path: "foo/", ExternalEntropyAccess: true})
+	sourcer, ok := sysView.(entropy.Sourcer)
+	if !ok {
+		t.Fatal("expected entropy access with external_entropy_access")
+	}
+	out, err := sourcer.GetRandom(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 16 {
+		t.Fatalf("bad length: %d", len(out))
+	}
+
+	c.entropySourcer = nil
+	if _, ok := c.mountEntrySysView(&MountEntry{Path: "foo/", ExternalEntropyAccess: true}).(entropy.Sourcer); ok {
+		t.Fatal("expected no entropy access without a source")
+	}
+}
@@ -4,7 +4,11 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"path"
+	"strings"
+
+	"github.com/hashicorp/go-kms-wrapping/entropy"
 
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -24,9 +28,9 @@ func runFilteredPathsEvaluation(context.Context, *Core) error                 {
 func (e *MountEntry) ViewPath() string {
 	switch e.Type {
 	case systemMountType:
-		return systemBarrierPrefix
+		return namespaceBarrierPath(e.NamespaceID) + systemBarrierPrefix
 	case "token":
-		return path.Join(systemBarrierPrefix, tokenSubPath) + "/"
+		return namespaceBarrierPath(e.NamespaceID) + path.Join(systemBarrierPrefix, tokenSubPath) + "/"
 	}
 
 	switch e.Table {
@@ -41,16 +45,49 @@ func (e *MountEntry) ViewPath() string {
 	panic("invalid mount entry")
 }
 
-func verifyNamespace(*Core, *namespace.Namespace, *MountEntry) error { return nil }
+// verifyNamespace ensures a mount doesn't shadow a child namespace of the
+// namespace it is mounted in
+func verifyNamespace(c *Core, ns *namespace.Namespace, entry *MountEntry) error {
+	mountPath := ns.Path + entry.Path
+	for _, child := range c.childNamespaces(ns) {
+		if strings.HasPrefix(mountPath, child.Path) || strings.HasPrefix(child.Path, mountPath) {
+			return logical.CodedError(409, fmt.Sprintf("path is already in use by namespace %s", child.Path))
+		}
+	}
+	return nil
+}
 
 // mountEntrySysView creates a logical.SystemView from global and
 // mount-specific entries; because this should be called when setting
 // up a mountEntry, it doesn't check to ensure that me is not nil
 func (c *Core) mountEntrySysView(entry *MountEntry) extendedSystemView {
-	return extendedSystemViewImpl{
+	sysView := extendedSystemViewImpl{
 		dynamicSystemView{
 			core:       c,
 			mountEntry: entry,
 		},
 	}
+
+	// Mounts with external entropy access mix the configured source into
+	// the key material they generate
+	if entry.ExternalEntropyAccess && c.entropySourcer != nil {
+		return entropySystemView{
+			extendedSystemViewImpl: sysView,
+			sourcer:                c.entropySourcer,
+		}
+	}
+
+	return sysView
+}
+
+// entropySystemView is a system view that implements entropy.Sourcer, which
+// backends check for to read random bytes from the external entropy source.
+type entropySystemView struct {
+	extendedSystemViewImpl
+
+	sourcer entropy.Sourcer
+}
+
+func (e entropySystemView) GetRandom(bytes int) ([]byte, error) {
+	return e.sourcer.GetRandom(bytes)
 }
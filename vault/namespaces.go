@@ -2,17 +2,421 @@ package vault
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreNamespacesPath is the path used to store the namespaces other
+	// than the root namespace
+	coreNamespacesPath = "core/namespaces"
+
+	// namespaceBarrierPrefix is the prefix of the storage of the namespaces
+	// other than the root namespace. The storage of each namespace mirrors
+	// the layout of the system storage of the root namespace.
+	namespaceBarrierPrefix = "namespaces/"
+
+	// namespaceIDLength is the length of the generated namespace IDs
+	namespaceIDLength = 5
 )
 
 var (
 	NamespaceByID func(context.Context, string, *Core) (*namespace.Namespace, error) = namespaceByID
+
+	// namespaceNameRegex is the format of the name of a namespace, which is
+	// a single segment of its path
+	namespaceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+	// reservedNamespaceNames cannot be used as namespace names, as the
+	// namespace would shadow the paths handled by Vault itself
+	reservedNamespaceNames = []string{
+		namespace.RootNamespaceID,
+		"audit",
+		"auth",
+		"cubbyhole",
+		"identity",
+		"sys",
+	}
+
+	// namespaceSystemPaths are the paths of the system backend served in the
+	// namespaces other than the root namespace. The other paths configure
+	// the whole cluster and are only served in the root namespace.
+	namespaceSystemPaths = []string{
+		"sys/auth",
+		"sys/capabilities",
+		"sys/capabilities-accessor",
+		"sys/capabilities-self",
+		"sys/control-group",
+		"sys/internal/ui/mounts",
+		"sys/internal/ui/resultant-acl",
+		"sys/leases",
+		"sys/mounts",
+		"sys/namespaces",
+		"sys/policies/acl",
+		"sys/policy",
+		"sys/remount",
+		"sys/renew",
+		"sys/revoke",
+		"sys/revoke-force",
+		"sys/revoke-prefix",
+		"sys/tools",
+		"sys/wrapping",
+	}
+
+	errNamespaceNotEmpty = errors.New("namespace has child namespaces")
 )
 
 func namespaceByID(ctx context.Context, nsID string, c *Core) (*namespace.Namespace, error) {
 	if nsID == namespace.RootNamespaceID {
 		return namespace.RootNamespace, nil
 	}
-	return nil, namespace.ErrNoNamespace
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	// A namespace which doesn't exist, such as the namespace of a token
+	// created in a deleted namespace, is nil
+	return c.namespaces[nsID], nil
+}
+
+// namespaceBarrierPath returns the storage prefix of the namespace with the
+// given ID, which is empty for the root namespace
+func namespaceBarrierPath(nsID string) string {
+	if nsID == "" || nsID == namespace.RootNamespaceID {
+		return ""
+	}
+	return namespaceBarrierPrefix + nsID + "/"
+}
+
+// namespaceSystemView returns the view of the system storage of the
+// namespace, where its policies, tokens and leases are stored
+func (c *Core) namespaceSystemView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return c.systemBarrierView
+	}
+	return NewBarrierView(c.barrier, namespaceBarrierPath(ns.ID)+systemBarrierPrefix)
+}
+
+// NamespaceSystemPath returns whether the system backend serves the path in
+// the namespaces other than the root namespace
+func NamespaceSystemPath(path string) bool {
+	for _, p := range namespaceSystemPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceSharedBackend returns the backend of the system and token mounts
+// of the namespaces other than the root namespace. These mounts are served by
+// the backends of the root namespace, which are namespace-aware. It returns
+// nil if the mount entry gets a backend of its own.
+func (c *Core) namespaceSharedBackend(entry *MountEntry) logical.Backend {
+	if entry.NamespaceID == "" || entry.NamespaceID == namespace.RootNamespaceID {
+		return nil
+	}
+
+	switch {
+	case entry.Type == systemMountType && c.systemBackend != nil:
+		return c.systemBackend
+	case entry.Type == "token" && c.tokenStore != nil:
+		return c.tokenStore
+	}
+	return nil
+}
+
+// loadNamespaces loads the namespaces from storage
+func (c *Core) loadNamespaces(ctx context.Context) error {
+	raw, err := c.barrier.Get(ctx, coreNamespacesPath)
+	if err != nil {
+		return errwrap.Wrapf("failed to read namespaces: {{err}}", err)
+	}
+
+	var entries []*namespace.Namespace
+	if raw != nil {
+		if err := jsonutil.DecodeJSON(raw.Value, &entries); err != nil {
+			return errwrap.Wrapf("failed to decode namespaces: {{err}}", err)
+		}
+	}
+
+	namespaces := make(map[string]*namespace.Namespace, len(entries))
+	for _, ns := range entries {
+		namespaces[ns.ID] = ns
+	}
+
+	c.namespacesLock.Lock()
+	c.namespaces = namespaces
+	c.namespacesLock.Unlock()
+	return nil
+}
+
+// teardownNamespaces unloads the namespaces on seal
+func (c *Core) teardownNamespaces() {
+	c.namespacesLock.Lock()
+	c.namespaces = nil
+	c.namespacesLock.Unlock()
+}
+
+// persistNamespaces stores the namespaces. The namespaces lock must be held
+// for writing.
+func (c *Core) persistNamespaces(ctx context.Context, namespaces map[string]*namespace.Namespace) error {
+	entries := make([]*namespace.Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		entries = append(entries, ns)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	value, err := jsonutil.EncodeJSON(entries)
+	if err != nil {
+		return err
+	}
+	return c.barrier.Put(ctx, &logical.StorageEntry{
+		Key:   coreNamespacesPath,
+		Value: value,
+	})
+}
+
+// listNamespaces returns the namespaces other than the root namespace,
+// sorted by path
+func (c *Core) listNamespaces() []*namespace.Namespace {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	namespaces := make([]*namespace.Namespace, 0, len(c.namespaces))
+	for _, ns := range c.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Path < namespaces[j].Path })
+	return namespaces
+}
+
+// namespaceByPathInternal returns the namespace with the longest path
+// prefixing the given path, which is the root namespace if there is none
+func (c *Core) namespaceByPathInternal(path string) *namespace.Namespace {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	match := namespace.RootNamespace
+	for _, ns := range c.namespaces {
+		if strings.HasPrefix(path, ns.Path) && len(ns.Path) > len(match.Path) {
+			match = ns
+		}
+	}
+	return match
+}
+
+// NamespaceByPath returns the namespace a request path belongs to, and the
+// path relative to that namespace
+func (c *Core) NamespaceByPath(path string) (*namespace.Namespace, string) {
+	ns := c.namespaceByPath(path)
+	return ns, ns.TrimmedPath(path)
+}
+
+// childNamespaces returns the namespaces whose parent is the given namespace
+func (c *Core) childNamespaces(parent *namespace.Namespace) []*namespace.Namespace {
+	var children []*namespace.Namespace
+	for _, ns := range c.listNamespaces() {
+		name := strings.TrimSuffix(parent.TrimmedPath(ns.Path), "/")
+		if ns.HasParent(parent) && ns.Path != parent.Path && !strings.Contains(name, "/") {
+			children = append(children, ns)
+		}
+	}
+	return children
+}
+
+// createNamespace creates a child namespace of the namespace of the context,
+// with its own system, token and cubbyhole mounts and default policy
+func (c *Core) createNamespace(ctx context.Context, name string) (*namespace.Namespace, error) {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.Trim(name, "/")
+	switch {
+	case !namespaceNameRegex.MatchString(name):
+		return nil, logical.CodedError(400, fmt.Sprintf("invalid namespace name %q", name))
+	case strutil.StrListContains(reservedNamespaceNames, name):
+		return nil, logical.CodedError(400, fmt.Sprintf("namespace name %q is reserved", name))
+	}
+
+	// Namespaces and mounts share the paths of their parent namespace
+	if conflict := c.router.MountConflict(ctx, name+"/"); conflict != "" {
+		return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %s", conflict))
+	}
+
+	c.namespacesLock.Lock()
+	ns := &namespace.Namespace{
+		Path: parent.Path + name + "/",
+	}
+	for _, existing := range c.namespaces {
+		if existing.Path == ns.Path {
+			c.namespacesLock.Unlock()
+			return nil, logical.CodedError(409, fmt.Sprintf("namespace %q already exists", ns.Path))
+		}
+	}
+	for ns.ID == "" {
+		id, err := base62.Random(namespaceIDLength)
+		if err != nil {
+			c.namespacesLock.Unlock()
+			return nil, err
+		}
+		if _, ok := c.namespaces[id]; !ok {
+			ns.ID = id
+		}
+	}
+
+	namespaces := make(map[string]*namespace.Namespace, len(c.namespaces)+1)
+	for id, existing := range c.namespaces {
+		namespaces[id] = existing
+	}
+	namespaces[ns.ID] = ns
+	if err := c.persistNamespaces(ctx, namespaces); err != nil {
+		c.namespacesLock.Unlock()
+		return nil, errwrap.Wrapf("failed to persist namespaces: {{err}}", err)
+	}
+	c.namespaces = namespaces
+	c.namespacesLock.Unlock()
+
+	if err := c.setupNamespace(namespace.ContextWithNamespace(ctx, ns)); err != nil {
+		if deleteErr := c.deleteNamespace(ctx, ns); deleteErr != nil {
+			c.logger.Error("failed to clean up namespace", "namespace", ns.Path, "error", deleteErr)
+		}
+		return nil, err
+	}
+
+	c.logger.Info("created namespace", "namespace", ns.Path, "id", ns.ID)
+	return ns, nil
+}
+
+// setupNamespace creates the default mounts and policy of the namespace of
+// the context
+func (c *Core) setupNamespace(ctx context.Context) error {
+	mounts := []*MountEntry{
+		{
+			Table:       mountTableType,
+			Path:        systemMountPath,
+			Type:        systemMountType,
+			Description: "system endpoints used for control, policy and debugging",
+		},
+		{
+			Table:       mountTableType,
+			Path:        cubbyholeMountPath,
+			Type:        cubbyholeMountType,
+			Description: "per-token private secret storage",
+			Local:       true,
+		},
+	}
+	for _, entry := range mounts {
+		if err := c.mountInternal(ctx, entry, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to mount %q: {{err}}", entry.Path), err)
+		}
+	}
+
+	tokenEntry := &MountEntry{
+		Table:       credentialTableType,
+		Path:        "token/",
+		Type:        "token",
+		Description: "token based credentials",
+		Config: MountConfig{
+			TokenType: logical.TokenTypeDefaultService,
+		},
+	}
+	if err := c.enableCredentialInternal(ctx, tokenEntry, MountTableUpdateStorage); err != nil {
+		return errwrap.Wrapf("failed to enable the token auth method: {{err}}", err)
+	}
+
+	if err := c.policyStore.loadACLPolicyInternal(ctx, defaultPolicyName, defaultPolicy); err != nil {
+		return errwrap.Wrapf("failed to create the default policy: {{err}}", err)
+	}
+	return nil
+}
+
+// deleteNamespace deletes a namespace without child namespaces, along with
+// its mounts, policies, tokens and leases
+func (c *Core) deleteNamespace(ctx context.Context, ns *namespace.Namespace) error {
+	if len(c.childNamespaces(ns)) > 0 {
+		return logical.CodedError(400, errNamespaceNotEmpty.Error())
+	}
+
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	// Disable the auth methods first, revoking the tokens of the namespace,
+	// then the mounts, with the system mount last
+	var authPaths, mountPaths []string
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID && entry.Type != "token" {
+			authPaths = append(authPaths, entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID && entry.Type != systemMountType {
+			mountPaths = append(mountPaths, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+
+	for _, path := range append(authPaths, "token/") {
+		if c.router.MatchingMount(nsCtx, credentialRoutePrefix+path) == "" {
+			continue
+		}
+		if err := c.disableCredentialInternal(nsCtx, path, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to disable auth method %q: {{err}}", path), err)
+		}
+	}
+	for _, path := range append(mountPaths, systemMountPath) {
+		if c.router.MatchingMount(nsCtx, path) == "" {
+			continue
+		}
+		if err := c.unmountInternal(nsCtx, path, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to unmount %q: {{err}}", path), err)
+		}
+	}
+
+	var result error
+	if err := logical.ClearView(ctx, NewBarrierView(c.barrier, namespaceBarrierPath(ns.ID))); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("failed to clear namespace storage: {{err}}", err))
+	}
+
+	c.namespacesLock.Lock()
+	namespaces := make(map[string]*namespace.Namespace, len(c.namespaces))
+	for id, existing := range c.namespaces {
+		if id != ns.ID {
+			namespaces[id] = existing
+		}
+	}
+	if err := c.persistNamespaces(ctx, namespaces); err != nil {
+		c.namespacesLock.Unlock()
+		return multierror.Append(result, errwrap.Wrapf("failed to persist namespaces: {{err}}", err))
+	}
+	c.namespaces = namespaces
+	c.namespacesLock.Unlock()
+
+	// Drop the cached policies and salt of the namespace
+	if c.policyStore != nil && c.policyStore.tokenPoliciesLRU != nil {
+		c.policyStore.tokenPoliciesLRU.Purge()
+	}
+	if c.tokenStore != nil {
+		c.tokenStore.saltLock.Lock()
+		delete(c.tokenStore.salts, ns.ID)
+		c.tokenStore.saltLock.Unlock()
+	}
+
+	c.logger.Info("deleted namespace", "namespace", ns.Path, "id", ns.ID)
+	return result
 }
@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_Namespaces(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	rootCtx := namespace.RootContext(nil)
+
+	request := func(ctx context.Context, op logical.Operation, path, token string, data map[string]interface{}) (*logical.Response, error) {
+		return c.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Data:        data,
+			ClientToken: token,
+		})
+	}
+
+	// Create a namespace and a child namespace
+	resp, err := request(rootCtx, logical.UpdateOperation, "sys/namespaces/ns1", root, nil)
+	if err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	nsID := resp.Data["id"].(string)
+	if resp.Data["path"] != "ns1/" || nsID == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	ns, err := NamespaceByID(rootCtx, nsID, c)
+	if err != nil || ns == nil {
+		t.Fatalf("expected namespace, got: %v, %v", ns, err)
+	}
+	nsCtx := namespace.ContextWithNamespace(rootCtx, ns)
+	if _, err := request(nsCtx, logical.UpdateOperation, "sys/namespaces/child", root, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(rootCtx, logical.ListOperation, "sys/namespaces", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "ns1/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Names are validated and can't shadow mounts
+	for _, name := range []string{"sys", "ns1", "secret", "a.b"} {
+		if _, err := request(rootCtx, logical.UpdateOperation, "sys/namespaces/"+name, root, nil); err == nil {
+			t.Fatalf("expected error creating namespace %q", name)
+		}
+	}
+	_, err = request(rootCtx, logical.UpdateOperation, "sys/mounts/ns1", root, map[string]interface{}{"type": "kv"})
+	if err == nil {
+		t.Fatal("expected error mounting over a namespace")
+	}
+
+	// Mounts are isolated
+	if _, err := request(nsCtx, logical.UpdateOperation, "sys/mounts/kv", root, map[string]interface{}{"type": "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request(nsCtx, logical.UpdateOperation, "kv/foo", root, map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(rootCtx, logical.ReadOperation, "sys/mounts", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Data["kv/"]; ok {
+		t.Fatal("expected the mount of the namespace not to be listed in the root namespace")
+	}
+	resp, err = request(nsCtx, logical.ReadOperation, "sys/mounts", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"kv/", "sys/", "cubbyhole/"} {
+		if _, ok := resp.Data[path]; !ok {
+			t.Fatalf("expected mount %q in the namespace, got: %#v", path, resp.Data)
+		}
+	}
+
+	// The system paths configuring the cluster are only served in the root
+	// namespace
+	if _, err := request(nsCtx, logical.ReadOperation, "sys/config/cors", root, nil); err != logical.ErrUnsupportedPath {
+		t.Fatalf("expected unsupported path, got: %v", err)
+	}
+
+	// Policies are scoped to their namespace
+	policy := `path "kv/*" { capabilities = ["read"] }`
+	if _, err := request(nsCtx, logical.UpdateOperation, "sys/policy/reader", root, map[string]interface{}{"policy": policy}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := c.policyStore.GetPolicy(rootCtx, "reader", PolicyTypeACL); err != nil || p != nil {
+		t.Fatalf("expected no policy in the root namespace, got: %v, %v", p, err)
+	}
+	resp, err = request(nsCtx, logical.ListOperation, "sys/policy", root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 || keys[0] != "default" || keys[1] != "reader" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tokens of the namespace are bound to its policies
+	resp, err = request(nsCtx, logical.UpdateOperation, "auth/token/create", root, map[string]interface{}{"policies": []string{"reader"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := resp.Auth.ClientToken
+	if !strings.HasSuffix(token, "."+nsID) {
+		t.Fatalf("expected the token to belong to the namespace, got %q", token)
+	}
+	resp, err = request(nsCtx, logical.ReadOperation, "kv/foo", token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, err := request(nsCtx, logical.UpdateOperation, "kv/foo", token, map[string]interface{}{"value": "baz"}); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	if _, err := request(rootCtx, logical.ReadOperation, "sys/mounts", token, nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// The namespaces are restored on unseal
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err = request(nsCtx, logical.ReadOperation, "kv/foo", token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A namespace with child namespaces can't be deleted
+	if _, err := request(rootCtx, logical.DeleteOperation, "sys/namespaces/ns1", root, nil); err == nil {
+		t.Fatal("expected error deleting a namespace with child namespaces")
+	}
+	if _, err := request(nsCtx, logical.DeleteOperation, "sys/namespaces/child", root, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request(rootCtx, logical.DeleteOperation, "sys/namespaces/ns1", root, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ns, err := NamespaceByID(rootCtx, nsID, c); err != nil || ns != nil {
+		t.Fatalf("expected the namespace to be deleted, got: %v, %v", ns, err)
+	}
+	if _, err := request(rootCtx, logical.ReadOperation, "sys/policy/default", token, nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == nsID {
+			t.Fatalf("expected the mounts of the namespace to be removed, got %q", entry.Path)
+		}
+	}
+	storageKeys, err := c.barrier.List(rootCtx, namespaceBarrierPath(nsID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storageKeys) != 0 {
+		t.Fatalf("expected the storage of the namespace to be cleared, got: %v", storageKeys)
+	}
+}
@@ -0,0 +1,673 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/oklog/run"
+)
+
+var (
+	// perfStandbyRefreshInterval is how often a performance standby checks
+	// whether the active node changed the tables it was set up from. The
+	// changes are normally picked up as soon as they are streamed by the
+	// active node; this catches up while the stream is down.
+	perfStandbyRefreshInterval = 5 * time.Second
+
+	// perfStandbyInvalidationsRetryInterval is how long a performance
+	// standby waits before streaming the invalidations again once the stream
+	// broke
+	perfStandbyInvalidationsRetryInterval = time.Second
+
+	// perfStandbyInvalidationsBuffer is the number of keys buffered for each
+	// performance standby. A standby that falls further behind is
+	// disconnected, and refreshes everything once it streams again.
+	perfStandbyInvalidationsBuffer = 1024
+
+	// perfStandbyTablePaths are the storage entries a performance standby
+	// is set up from. A change to any of them sets the standby up again.
+	perfStandbyTablePaths = []string{
+		coreNamespacesPath,
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+		systemBarrierPrefix + "config/cors",
+	}
+
+	// perfStandbyLocalMountTypes are the types of the mounts whose read
+	// requests are served by a performance standby. Reads of the other
+	// mounts may have side effects, such as creating credentials, and are
+	// forwarded to the active node.
+	perfStandbyLocalMountTypes = []string{
+		"kv",
+		"generic",
+		"cubbyhole",
+		"token",
+	}
+
+	// perfStandbyTokenLookupPaths are the token store paths that look up a
+	// token with an update operation yet don't modify it
+	perfStandbyTokenLookupPaths = []string{
+		"lookup",
+		"lookup-self",
+		"lookup-accessor",
+	}
+)
+
+// perfStandbyStorage rejects the writes to the physical storage while the
+// node serves as a performance standby, so that only the active node ever
+// modifies the storage. The keys written otherwise are published to the
+// performance standbys, to invalidate what they cached.
+type perfStandbyStorage struct {
+	physical.Backend
+	readOnly    *uint32
+	invalidator *perfStandbyInvalidator
+}
+
+// transactionalPerfStandbyStorage is the transactional version of
+// perfStandbyStorage
+type transactionalPerfStandbyStorage struct {
+	*perfStandbyStorage
+	physical.Transactional
+}
+
+// Verify perfStandbyStorage satisfies the correct interfaces
+var _ physical.Backend = (*perfStandbyStorage)(nil)
+var _ physical.Transactional = (*transactionalPerfStandbyStorage)(nil)
+
+func newPerfStandbyStorage(b physical.Backend, readOnly *uint32, invalidator *perfStandbyInvalidator) physical.Backend {
+	s := &perfStandbyStorage{
+		Backend:     b,
+		readOnly:    readOnly,
+		invalidator: invalidator,
+	}
+
+	if bTxn, ok := b.(physical.Transactional); ok {
+		return &transactionalPerfStandbyStorage{
+			perfStandbyStorage: s,
+			Transactional:      bTxn,
+		}
+	}
+
+	return s
+}
+
+func (s *perfStandbyStorage) Put(ctx context.Context, entry *physical.Entry) error {
+	if atomic.LoadUint32(s.readOnly) == 1 {
+		return logical.ErrReadOnly
+	}
+	if err := s.Backend.Put(ctx, entry); err != nil {
+		return err
+	}
+	s.invalidator.publish(entry.Key)
+	return nil
+}
+
+func (s *perfStandbyStorage) Delete(ctx context.Context, key string) error {
+	if atomic.LoadUint32(s.readOnly) == 1 {
+		return logical.ErrReadOnly
+	}
+	if err := s.Backend.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.invalidator.publish(key)
+	return nil
+}
+
+func (s *transactionalPerfStandbyStorage) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	if atomic.LoadUint32(s.readOnly) == 1 {
+		return logical.ErrReadOnly
+	}
+	if err := s.Transactional.Transaction(ctx, txns); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		if txn.Operation != physical.GetOperation {
+			keys = append(keys, txn.Entry.Key)
+		}
+	}
+	s.invalidator.publish(keys...)
+	return nil
+}
+
+func (s *perfStandbyStorage) Purge(ctx context.Context) {
+	if purgeable, ok := s.Backend.(physical.ToggleablePurgemonster); ok {
+		purgeable.Purge(ctx)
+	}
+}
+
+func (s *perfStandbyStorage) SetEnabled(enabled bool) {
+	if purgeable, ok := s.Backend.(physical.ToggleablePurgemonster); ok {
+		purgeable.SetEnabled(enabled)
+	}
+}
+
+// perfStandbyInvalidator fans the keys written on the active node out to the
+// performance standbys streaming them
+type perfStandbyInvalidator struct {
+	l           sync.Mutex
+	subscribers map[chan string]struct{}
+}
+
+func newPerfStandbyInvalidator() *perfStandbyInvalidator {
+	return &perfStandbyInvalidator{
+		subscribers: make(map[chan string]struct{}),
+	}
+}
+
+// subscribe returns a channel receiving the keys published from now on. The
+// channel is closed if the subscriber falls behind.
+func (i *perfStandbyInvalidator) subscribe() chan string {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	ch := make(chan string, perfStandbyInvalidationsBuffer)
+	i.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (i *perfStandbyInvalidator) unsubscribe(ch chan string) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if _, ok := i.subscribers[ch]; ok {
+		delete(i.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (i *perfStandbyInvalidator) publish(keys ...string) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	for ch := range i.subscribers {
+	KEYS:
+		for _, key := range keys {
+			select {
+			case ch <- key:
+			default:
+				delete(i.subscribers, ch)
+				close(ch)
+				break KEYS
+			}
+		}
+	}
+}
+
+// perfStandbyUnsealStrategy sets up the subsystems needed to serve read-only
+// requests. Everything that writes to the storage or runs in the background,
+// such as the lease restoration or the rollback manager, is left to the
+// active node.
+type perfStandbyUnsealStrategy struct{}
+
+func (s perfStandbyUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c *Core) error {
+	if err := c.ensureWrappingKey(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPluginCatalog(ctx); err != nil {
+		return err
+	}
+	if err := c.loadNamespaces(ctx); err != nil {
+		return err
+	}
+	if err := c.loadMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCORSConfig(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(ctx, true); err != nil {
+		return err
+	}
+	c.setupPerfStandbyExpiration()
+	if err := c.loadAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.loadIdentityStoreArtifacts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAuditedHeadersConfig(ctx); err != nil {
+		return err
+	}
+
+	logger.Info("performance standby setup complete")
+	return nil
+}
+
+// setupPerfStandbyExpiration creates an expiration manager that only reads
+// the leases, for the token store to check the expiration of the tokens.
+func (c *Core) setupPerfStandbyExpiration() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	expLogger := c.baseLogger.Named("expiration")
+	c.AddLogger(expLogger)
+	mgr := NewExpirationManager(c, c.systemBarrierView.SubView(expirationSubPath), expireLeaseStrategyRevoke, expLogger)
+
+	// The leases are restored, and revoked once expired, by the active node
+	atomic.StoreInt32(mgr.restoreMode, 0)
+
+	c.expiration = mgr
+	c.tokenStore.SetExpirationManager(mgr)
+}
+
+// setupPerfStandby sets this standby up to serve read-only requests. This
+// must be called with the state lock held for writing.
+func (c *Core) setupPerfStandby() error {
+	if !c.standby || c.perfStandby || c.Sealed() {
+		return nil
+	}
+
+	tables, err := c.readPerfStandbyTables(context.Background())
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("entering performance standby mode")
+	c.perfStandby = true
+	atomic.StoreUint32(c.perfStandbyReadOnly, 1)
+
+	ctx, ctxCancel := context.WithCancel(namespace.RootContext(nil))
+	if err := c.postUnseal(ctx, ctxCancel, perfStandbyUnsealStrategy{}); err != nil {
+		c.perfStandby = false
+		atomic.StoreUint32(c.perfStandbyReadOnly, 0)
+		return err
+	}
+
+	c.perfStandbyTables = tables
+	return nil
+}
+
+// teardownPerfStandby reverses setupPerfStandby. This must be called with
+// the state lock held for writing.
+func (c *Core) teardownPerfStandby() {
+	if !c.perfStandby {
+		return
+	}
+
+	c.logger.Info("leaving performance standby mode")
+	if cancel, ok := c.activeContextCancelFunc.Load().(context.CancelFunc); ok && cancel != nil {
+		cancel()
+	}
+	if err := c.preSeal(); err != nil {
+		c.logger.Error("performance standby teardown failed", "error", err)
+	}
+
+	c.perfStandby = false
+	c.perfStandbyTables = nil
+	atomic.StoreUint32(c.perfStandbyReadOnly, 0)
+}
+
+// perfStandbyNeedsSetup returns whether the performance standby needs to be
+// set up, or set up again because the tables it was set up from have
+// changed on the active node. Otherwise the cached policies are dropped to
+// pick up their latest version from the storage. This must be called with
+// the state lock held.
+func (c *Core) perfStandbyNeedsSetup(ctx context.Context) (bool, error) {
+	if !c.standby || c.Sealed() {
+		return false, nil
+	}
+	if !c.perfStandby {
+		return true, nil
+	}
+
+	tables, err := c.readPerfStandbyTables(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(tables) != len(c.perfStandbyTables) {
+		return true, nil
+	}
+	for path, value := range tables {
+		if prev, ok := c.perfStandbyTables[path]; !ok || !bytes.Equal(prev, value) {
+			return true, nil
+		}
+	}
+
+	if c.policyStore != nil {
+		if c.policyStore.tokenPoliciesLRU != nil {
+			c.policyStore.tokenPoliciesLRU.Purge()
+		}
+		if c.policyStore.egpLRU != nil {
+			c.policyStore.egpLRU.Purge()
+		}
+	}
+	return false, nil
+}
+
+func (c *Core) readPerfStandbyTables(ctx context.Context) (map[string][]byte, error) {
+	tables := make(map[string][]byte, len(perfStandbyTablePaths))
+	for _, path := range perfStandbyTablePaths {
+		entry, err := c.barrier.Get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			tables[path] = entry.Value
+		}
+	}
+	return tables, nil
+}
+
+// perfStandbyCanServe returns whether a performance standby can serve the
+// request locally. The requests which may write to the storage are
+// forwarded to the active node.
+func (c *Core) perfStandbyCanServe(ctx context.Context, req *logical.Request) bool {
+	if c.router.LoginPath(ctx, req.Path) {
+		return false
+	}
+
+	// Wrapping the response creates a token
+	if req.WrapInfo != nil && req.WrapInfo.TTL != 0 {
+		return false
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return false
+	}
+
+	mountType := entry.Type
+	if mountType == "plugin" {
+		mountType = entry.Config.PluginName
+	}
+	if !strutil.StrListContains(perfStandbyLocalMountTypes, mountType) {
+		return false
+	}
+
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation, logical.HelpOperation:
+		return true
+	case logical.UpdateOperation:
+		return mountType == "token" &&
+			strutil.StrListContains(perfStandbyTokenLookupPaths, strings.TrimPrefix(req.Path, c.router.MatchingMount(ctx, req.Path)))
+	}
+	return false
+}
+
+// addPerfStandbyActor adds to the standby routines the one which sets up the
+// performance standby and keeps it in sync with the active node. The
+// returned channel is used to signal the routine: a nil value tells it that
+// another node became active, while a function tells it to stand down as
+// this node is about to become active, and is called once it did.
+func (c *Core) addPerfStandbyActor(g *run.Group) chan func() {
+	newLeaderCh := make(chan func())
+	stopCh := make(chan struct{})
+
+	g.Add(func() error {
+		c.runPerfStandby(newLeaderCh, stopCh)
+		return nil
+	}, func(error) {
+		close(stopCh)
+		c.logger.Debug("shutting down performance standby")
+	})
+
+	return newLeaderCh
+}
+
+func (c *Core) runPerfStandby(newLeaderCh chan func(), stopCh chan struct{}) {
+	// withStateLock runs f with the state lock held, unless the standby is
+	// being stopped, in which case whoever stops it holds the lock and is in
+	// charge of the teardown
+	withStateLock := func(f func() error) bool {
+		if stopped := grabLockOrStop(c.stateLock.Lock, c.stateLock.Unlock, stopCh); stopped {
+			return false
+		}
+		defer c.stateLock.Unlock()
+
+		if err := f(); err != nil {
+			c.logger.Error("performance standby setup failed", "error", err)
+		}
+		return true
+	}
+
+	// refresh sets the standby up again if the tables changed on the active
+	// node, and returns false if the standby is being stopped
+	refresh := func() bool {
+		if stopped := grabLockOrStop(c.stateLock.RLock, c.stateLock.RUnlock, stopCh); stopped {
+			return false
+		}
+		needsSetup, err := c.perfStandbyNeedsSetup(context.Background())
+		c.stateLock.RUnlock()
+		if err != nil {
+			c.logger.Error("performance standby refresh failed", "error", err)
+			return true
+		}
+		if !needsSetup {
+			return true
+		}
+
+		c.logger.Debug("setting up the performance standby from the state of the active node")
+		return withStateLock(func() error {
+			c.teardownPerfStandby()
+			return c.setupPerfStandby()
+		})
+	}
+
+	if !withStateLock(c.setupPerfStandby) {
+		return
+	}
+
+	refreshCh := make(chan struct{}, 1)
+	go c.streamPerfStandbyInvalidations(refreshCh, stopCh)
+
+	ticker := time.NewTicker(perfStandbyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+
+		case <-ticker.C:
+			if !refresh() {
+				return
+			}
+
+		case <-refreshCh:
+			if !refresh() {
+				return
+			}
+
+		case f := <-newLeaderCh:
+			// Stand down, either to let this node become active or to set up
+			// again from the state of the new active node
+			if !withStateLock(func() error {
+				c.teardownPerfStandby()
+				if f == nil {
+					return c.setupPerfStandby()
+				}
+				return nil
+			}) {
+				return
+			}
+			if f != nil {
+				f()
+			}
+		}
+	}
+}
+
+// interruptPerfStandbyImpl makes the performance standby routine stand down
+// before this node becomes active. The routine waits for the returned
+// channel to be closed, once the attempt to become active is over, before
+// setting up the performance standby again if it is still needed.
+func interruptPerfStandbyImpl(newLeaderCh chan func(), stopCh chan struct{}) chan struct{} {
+	continueCh := make(chan struct{})
+	if newLeaderCh == nil {
+		return continueCh
+	}
+
+	doneCh := make(chan struct{})
+	select {
+	case newLeaderCh <- func() {
+		close(doneCh)
+		select {
+		case <-continueCh:
+		case <-stopCh:
+		}
+	}:
+	case <-stopCh:
+		return continueCh
+	}
+
+	select {
+	case <-doneCh:
+	case <-stopCh:
+	}
+	return continueCh
+}
+
+// streamPerfStandbyInvalidations invalidates what this performance standby
+// cached as the active node writes to the storage, streaming the keys it
+// writes until the standby is stopped. refreshCh is signaled to check the
+// tables the standby was set up from.
+func (c *Core) streamPerfStandbyInvalidations(refreshCh chan struct{}, stopCh chan struct{}) {
+	ctx, cancel := context.WithCancel(namespace.RootContext(nil))
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		err := c.receivePerfStandbyInvalidations(ctx, refreshCh)
+		if ctx.Err() != nil {
+			return
+		}
+		c.logger.Debug("performance standby invalidations stream closed", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(perfStandbyInvalidationsRetryInterval):
+		}
+	}
+}
+
+func (c *Core) receivePerfStandbyInvalidations(ctx context.Context, refreshCh chan struct{}) error {
+	c.requestForwardingConnectionLock.RLock()
+	client := c.rpcForwardingClient
+	c.requestForwardingConnectionLock.RUnlock()
+	if client == nil {
+		return errors.New("no connection to the active node")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.PerfStandbyInvalidations(ctx, &PerfStandbyInvalidationsInput{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		// The keys written before the stream was set up may have been
+		// missed, so the first message asks for a full refresh
+		if len(msg.Keys) == 0 {
+			select {
+			case refreshCh <- struct{}{}:
+			default:
+			}
+			continue
+		}
+
+		c.stateLock.RLock()
+		if c.perfStandby {
+			c.perfStandbyInvalidate(ctx, msg.Keys, refreshCh)
+		}
+		c.stateLock.RUnlock()
+	}
+}
+
+// perfStandbyInvalidate invalidates what this performance standby cached
+// from the storage entries of the keys. This must be called with the state
+// lock held.
+func (c *Core) perfStandbyInvalidate(ctx context.Context, keys []string, refreshCh chan struct{}) {
+	for _, key := range keys {
+		if strutil.StrListContains(perfStandbyTablePaths, key) {
+			select {
+			case refreshCh <- struct{}{}:
+			default:
+			}
+			continue
+		}
+
+		if c.perfStandbyInvalidatePolicy(ctx, key) {
+			continue
+		}
+
+		c.router.Invalidate(ctx, key)
+	}
+}
+
+// perfStandbyInvalidatePolicy invalidates the cached policy stored at the key,
+// and returns whether the key is a policy
+func (c *Core) perfStandbyInvalidatePolicy(ctx context.Context, key string) bool {
+	ns := namespace.RootNamespace
+	if strings.HasPrefix(key, namespaceBarrierPrefix) {
+		nsID := strings.TrimPrefix(key, namespaceBarrierPrefix)
+		idx := strings.Index(nsID, "/")
+		if idx == -1 {
+			return false
+		}
+		nsID, key = nsID[:idx], nsID[idx+1:]
+
+		var err error
+		ns, err = namespaceByID(ctx, nsID, c)
+		if err != nil || ns == nil {
+			return false
+		}
+	}
+
+	for _, policy := range []struct {
+		prefix     string
+		policyType PolicyType
+	}{
+		{systemBarrierPrefix + policyACLSubPath, PolicyTypeACL},
+		{systemBarrierPrefix + policyRGPSubPath, PolicyTypeRGP},
+		{systemBarrierPrefix + policyEGPSubPath, PolicyTypeEGP},
+	} {
+		if strings.HasPrefix(key, policy.prefix) {
+			c.policyStore.invalidate(namespace.ContextWithNamespace(ctx, ns), strings.TrimPrefix(key, policy.prefix), policy.policyType)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_PerfStandby(t *testing.T) {
+	oldInterval := perfStandbyRefreshInterval
+	perfStandbyRefreshInterval = 100 * time.Millisecond
+	defer func() { perfStandbyRefreshInterval = oldInterval }()
+
+	cluster := NewTestCluster(t, &CoreConfig{
+		EnablePerformanceStandby: true,
+	}, nil)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	cores := cluster.Cores
+	root := cluster.RootToken
+	ctx := namespace.RootContext(nil)
+	TestWaitActive(t, cores[0].Core)
+
+	waitFor := func(desc string, f func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	activeCore := func() *TestClusterCore {
+		for _, core := range cores {
+			if standby, _ := core.Standby(); !standby {
+				return core
+			}
+		}
+		return nil
+	}
+	write := func(core *TestClusterCore, path string, data map[string]interface{}) error {
+		_, err := core.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        path,
+			Data:        data,
+			ClientToken: root,
+		})
+		return err
+	}
+	read := func(core *TestClusterCore, path string) (*logical.Response, error) {
+		return core.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: root,
+		})
+	}
+	readsValue := func(core *TestClusterCore, path, value string) func() bool {
+		return func() bool {
+			resp, err := read(core, path)
+			return err == nil && resp != nil && resp.Data["value"] == value
+		}
+	}
+
+	for _, core := range cores[1:] {
+		waitFor("performance standby", core.PerfStandby)
+	}
+
+	// Mounting on the active node sets the standbys up again
+	if err := write(cores[0], "sys/mounts/kv", map[string]interface{}{"type": "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(cores[0], "kv/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, core := range cores[1:] {
+		waitFor("read on the performance standby", readsValue(core, "kv/foo", "bar"))
+	}
+
+	// The storage isn't cached, so the reads see the latest writes
+	if err := write(cores[0], "kv/foo", map[string]interface{}{"value": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, core := range cores[1:] {
+		if !readsValue(core, "kv/foo", "baz")() {
+			t.Fatal("expected the performance standby to read the latest value")
+		}
+	}
+
+	// Writes and reads of the other mounts are forwarded
+	err := write(cores[1], "kv/foo", map[string]interface{}{"value": "qux"})
+	if !errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()) {
+		t.Fatalf("expected the write to be forwarded, got: %v", err)
+	}
+	_, err = read(cores[1], "sys/mounts")
+	if !errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()) {
+		t.Fatalf("expected the read to be forwarded, got: %v", err)
+	}
+
+	// Token lookups are served locally
+	resp, err := cores[1].HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "auth/token/lookup-self",
+		ClientToken: root,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["id"] != root {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// A performance standby stands down to become active, and the former
+	// active node becomes a performance standby
+	if err := cores[0].StepDown(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/step-down",
+		ClientToken: root,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var active *TestClusterCore
+	waitFor("new active node", func() bool {
+		active = activeCore()
+		return active != nil && active != cores[0]
+	})
+	if active.PerfStandby() {
+		t.Fatal("expected the active node not to be a performance standby")
+	}
+	if err := write(active, "kv/foo", map[string]interface{}{"value": "quux"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("former active node read", readsValue(cores[0], "kv/foo", "quux"))
+	if !cores[0].PerfStandby() {
+		t.Fatal("expected the former active node to be a performance standby")
+	}
+}
+
+func TestCore_PerfStandby_Invalidations(t *testing.T) {
+	// The standbys only pick the changes up from the invalidations
+	oldInterval := perfStandbyRefreshInterval
+	perfStandbyRefreshInterval = time.Hour
+	defer func() { perfStandbyRefreshInterval = oldInterval }()
+
+	cluster := NewTestCluster(t, &CoreConfig{
+		EnablePerformanceStandby: true,
+	}, nil)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	cores := cluster.Cores
+	ctx := namespace.RootContext(nil)
+	TestWaitActive(t, cores[0].Core)
+
+	waitFor := func(desc string, f func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	request := func(core *TestClusterCore, op logical.Operation, path, token string, data map[string]interface{}) (*logical.Response, error) {
+		return core.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Data:        data,
+			ClientToken: token,
+		})
+	}
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(cores[0], logical.UpdateOperation, path, cluster.RootToken, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, core := range cores[1:] {
+		waitFor("performance standby", core.PerfStandby)
+	}
+
+	// Mounting changes the tables the standbys were set up from
+	write("sys/mounts/kv", map[string]interface{}{"type": "kv"})
+	write("kv/foo", map[string]interface{}{"value": "bar"})
+	write("sys/policies/acl/reader", map[string]interface{}{
+		"policy": `path "kv/other" { capabilities = ["read"] }`,
+	})
+	resp := write("auth/token/create", map[string]interface{}{
+		"policies": []string{"reader"},
+	})
+	token := resp.Auth.ClientToken
+
+	canRead := func(core *TestClusterCore) func() bool {
+		return func() bool {
+			resp, err := request(core, logical.ReadOperation, "kv/foo", token, nil)
+			return err == nil && resp != nil && resp.Data["value"] == "bar"
+		}
+	}
+	for _, core := range cores[1:] {
+		waitFor("denied read on the performance standby", func() bool {
+			_, err := request(core, logical.ReadOperation, "kv/foo", token, nil)
+			return errwrap.Contains(err, logical.ErrPermissionDenied.Error())
+		})
+	}
+
+	// Updating the policy invalidates the policy the standbys cached
+	write("sys/policies/acl/reader", map[string]interface{}{
+		"policy": `path "kv/*" { capabilities = ["read"] }`,
+	})
+	for _, core := range cores[1:] {
+		waitFor("policy update on the performance standby", canRead(core))
+	}
+}
+
+func TestCore_PerfStandby_disabled(t *testing.T) {
+	cluster := NewTestCluster(t, nil, nil)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	TestWaitActive(t, cluster.Cores[0].Core)
+	time.Sleep(time.Second)
+
+	if cluster.Cores[1].PerfStandby() {
+		t.Fatal("expected the standby not to be a performance standby")
+	}
+	_, err := cluster.Cores[1].HandleRequest(context.Background(), &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "auth/token/lookup-self",
+		ClientToken: cluster.RootToken,
+	})
+	if !errwrap.Contains(err, consts.ErrStandby.Error()) {
+		t.Fatalf("expected standby error, got: %v", err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/vault/sdk/database/dbplugin"
 	"github.com/hashicorp/vault/sdk/database/newdbplugin"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -27,8 +28,13 @@ var (
 	ErrDirectoryNotConfigured = errors.New("could not set plugin, plugin directory is not configured")
 	ErrPluginNotFound         = errors.New("plugin not found in the catalog")
 	ErrPluginBadType          = errors.New("unable to determine plugin type")
+	ErrPluginBadVersion       = errors.New("plugin version must be a valid semantic version")
 )
 
+// pluginCatalogVersionsPrefix is the prefix under which the versioned plugins
+// are stored, as <type>/<name>/<version>.
+const pluginCatalogVersionsPrefix = "versions/"
+
 // PluginCatalog keeps a record of plugins known to vault. External plugins need
 // to be registered to the catalog before they can be used in backends. Builtin
 // plugins are automatically detected and included in the catalog.
@@ -182,7 +188,7 @@ func (c *PluginCatalog) UpgradePlugins(ctx context.Context, logger log.Logger) e
 		}
 
 		// Upgrade the storage
-		err = c.setInternal(ctx, pluginName, pluginType, cmdOld, plugin.Args, plugin.Env, plugin.Sha256)
+		err = c.setInternal(ctx, pluginName, pluginType, "", cmdOld, plugin.Args, plugin.Env, plugin.Sha256)
 		if err != nil {
 			retErr = multierror.Append(retErr, fmt.Errorf("could not upgrade plugin %s: %s", pluginName, err))
 			continue
@@ -199,17 +205,40 @@ func (c *PluginCatalog) UpgradePlugins(ctx context.Context, logger log.Logger) e
 	return retErr
 }
 
+// parsePluginVersion validates the version of a plugin, returning it in its
+// canonical form.
+func parsePluginVersion(pluginVersion string) (string, error) {
+	if pluginVersion == "" {
+		return "", nil
+	}
+
+	v, err := version.NewSemver(pluginVersion)
+	if err != nil {
+		return "", ErrPluginBadVersion
+	}
+	return "v" + v.String(), nil
+}
+
+func pluginVersionKey(name string, pluginType consts.PluginType, pluginVersion string) string {
+	return pluginCatalogVersionsPrefix + pluginType.String() + "/" + name + "/" + pluginVersion
+}
+
 // Get retrieves a plugin with the specified name from the catalog. It first
 // looks for external plugins with this name and then looks for builtin plugins.
-// It returns a PluginRunner or an error if no plugin was found.
-func (c *PluginCatalog) Get(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
+// If a version is given, only the external plugin registered with this version
+// is looked up. It returns a PluginRunner or an error if no plugin was found.
+func (c *PluginCatalog) Get(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string) (*pluginutil.PluginRunner, error) {
 	c.lock.RLock()
-	runner, err := c.get(ctx, name, pluginType)
+	runner, err := c.get(ctx, name, pluginType, pluginVersion)
 	c.lock.RUnlock()
 	return runner, err
 }
 
-func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
+func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string) (*pluginutil.PluginRunner, error) {
+	if pluginVersion != "" {
+		return c.getVersion(ctx, name, pluginType, pluginVersion)
+	}
+
 	// If the directory isn't set only look for builtin plugins.
 	if c.directory != "" {
 		// Look for external plugins in the barrier
@@ -253,9 +282,68 @@ func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.
 	return nil, nil
 }
 
+func (c *PluginCatalog) getVersion(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string) (*pluginutil.PluginRunner, error) {
+	pluginVersion, err := parsePluginVersion(pluginVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.directory == "" {
+		return nil, nil
+	}
+
+	out, err := c.catalogView.Get(ctx, pluginVersionKey(name, pluginType, pluginVersion))
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to retrieve plugin %q version %q: {{err}}", name, pluginVersion), err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	entry := new(pluginutil.PluginRunner)
+	if err := jsonutil.DecodeJSON(out.Value, entry); err != nil {
+		return nil, errwrap.Wrapf("failed to decode plugin entry: {{err}}", err)
+	}
+
+	// prepend the plugin directory to the command
+	entry.Command = filepath.Join(c.directory, entry.Command)
+
+	return entry, nil
+}
+
+// Versions returns the sorted versions with which an external plugin is
+// registered in the catalog.
+func (c *PluginCatalog) Versions(ctx context.Context, name string, pluginType consts.PluginType) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys, err := c.catalogView.List(ctx, pluginCatalogVersionsPrefix+pluginType.String()+"/"+name+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*version.Version, 0, len(keys))
+	for _, key := range keys {
+		v, err := version.NewSemver(key)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(version.Collection(versions))
+
+	ret := make([]string, len(versions))
+	for i, v := range versions {
+		ret[i] = v.Original()
+	}
+	return ret, nil
+}
+
 // Set registers a new external plugin with the catalog, or updates an existing
-// external plugin. It takes the name, command and SHA256 of the plugin.
-func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.PluginType, command string, args []string, env []string, sha256 []byte) error {
+// external plugin. It takes the name, command and SHA256 of the plugin. If a
+// version is given, the plugin is registered alongside the other versions of
+// the plugin instead of replacing it.
+func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string, command string, args []string, env []string, sha256 []byte) error {
 	if c.directory == "" {
 		return ErrDirectoryNotConfigured
 	}
@@ -267,13 +355,18 @@ func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.
 		return consts.ErrPathContainsParentReferences
 	}
 
+	pluginVersion, err := parsePluginVersion(pluginVersion)
+	if err != nil {
+		return err
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	return c.setInternal(ctx, name, pluginType, command, args, env, sha256)
+	return c.setInternal(ctx, name, pluginType, pluginVersion, command, args, env, sha256)
 }
 
-func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType consts.PluginType, command string, args []string, env []string, sha256 []byte) error {
+func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string, command string, args []string, env []string, sha256 []byte) error {
 	// Best effort check to make sure the command isn't breaking out of the
 	// configured plugin directory.
 	commandFull := filepath.Join(c.directory, command)
@@ -315,6 +408,7 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 	entry := &pluginutil.PluginRunner{
 		Name:    name,
 		Type:    pluginType,
+		Version: pluginVersion,
 		Command: command,
 		Args:    args,
 		Env:     env,
@@ -327,8 +421,12 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 		return errwrap.Wrapf("failed to encode plugin entry: {{err}}", err)
 	}
 
+	key := pluginType.String() + "/" + name
+	if pluginVersion != "" {
+		key = pluginVersionKey(name, pluginType, pluginVersion)
+	}
 	logicalEntry := logical.StorageEntry{
-		Key:   pluginType.String() + "/" + name,
+		Key:   key,
 		Value: buf,
 	}
 	if err := c.catalogView.Put(ctx, &logicalEntry); err != nil {
@@ -338,11 +436,21 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 }
 
 // Delete is used to remove an external plugin from the catalog. Builtin plugins
-// can not be deleted.
-func (c *PluginCatalog) Delete(ctx context.Context, name string, pluginType consts.PluginType) error {
+// can not be deleted. If a version is given, only this version of the plugin
+// is removed.
+func (c *PluginCatalog) Delete(ctx context.Context, name string, pluginType consts.PluginType, pluginVersion string) error {
+	pluginVersion, err := parsePluginVersion(pluginVersion)
+	if err != nil {
+		return err
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if pluginVersion != "" {
+		return c.catalogView.Delete(ctx, pluginVersionKey(name, pluginType, pluginVersion))
+	}
+
 	// Check the name under which the plugin exists, but if it's unfound, don't return any error.
 	pluginKey := pluginType.String() + "/" + name
 	out, err := c.catalogView.Get(ctx, pluginKey)
@@ -373,10 +481,21 @@ func (c *PluginCatalog) List(ctx context.Context, pluginType consts.PluginType)
 
 	pluginTypePrefix := pluginType.String() + "/"
 
+	pluginVersionsPrefix := pluginCatalogVersionsPrefix + pluginTypePrefix
+
 	for _, plugin := range keys {
+		// The versioned plugins are listed under their name
+		if strings.HasPrefix(plugin, pluginCatalogVersionsPrefix) {
+			if strings.HasPrefix(plugin, pluginVersionsPrefix) {
+				if idx := strings.LastIndex(plugin, "/"); idx > len(pluginVersionsPrefix) {
+					mapKeys[plugin[len(pluginVersionsPrefix):idx]] = true
+				}
+			}
+			continue
+		}
 
 		// Only list user-added plugins if they're of the given type.
-		if entry, err := c.get(ctx, plugin, pluginType); err == nil && entry != nil {
+		if entry, err := c.get(ctx, plugin, pluginType, ""); err == nil && entry != nil {
 
 			// Some keys will be prepended with the plugin type, but other ones won't.
 			// Users don't expect to see the plugin type, so we need to strip that here.
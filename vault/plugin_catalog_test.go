@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 )
 
 func TestPluginCatalog_CRUD(t *testing.T) {
@@ -25,7 +26,7 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	core.pluginCatalog.directory = sym
 
 	// Get builtin plugin
-	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -54,13 +55,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	defer file.Close()
 
 	command := fmt.Sprintf("%s", filepath.Base(file.Name()))
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, command, []string{"--test"}, []string{"FOO=BAR"}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{"FOO=BAR"}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Get the plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -80,13 +81,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	}
 
 	// Delete the plugin
-	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 
 	// Get builtin plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -146,13 +147,13 @@ func TestPluginCatalog_List(t *testing.T) {
 	defer file.Close()
 
 	command := filepath.Base(file.Name())
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, command, []string{"--test"}, []string{}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Set another plugin
-	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", consts.PluginTypeDatabase, command, []string{"--test"}, []string{}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -181,3 +182,71 @@ func TestPluginCatalog_List(t *testing.T) {
 	}
 
 }
+
+func TestPluginCatalog_Versions(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+
+	sym, err := filepath.EvalSymlinks(os.TempDir())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	core.pluginCatalog.directory = sym
+
+	file, err := ioutil.TempFile(os.TempDir(), "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	command := filepath.Base(file.Name())
+
+	ctx := context.Background()
+	for _, version := range []string{"v1.10.0", "v1.2.0"} {
+		err = core.pluginCatalog.Set(ctx, "versioned", consts.PluginTypeSecrets, version, command, []string{"--test"}, []string{}, []byte{'1'})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versioned plugins are only found by their version
+	p, err := core.pluginCatalog.Get(ctx, "versioned", consts.PluginTypeSecrets, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("expected no unversioned plugin, got: %#v", p)
+	}
+	p, err = core.pluginCatalog.Get(ctx, "versioned", consts.PluginTypeSecrets, "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Version != "v1.2.0" || p.Name != "versioned" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	versions, err := core.pluginCatalog.Versions(ctx, "versioned", consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"v1.2.0", "v1.10.0"}) {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	plugins, err := core.pluginCatalog.List(ctx, consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strutil.StrListContains(plugins, "versioned") {
+		t.Fatalf("expected the versioned plugin to be listed, got: %v", plugins)
+	}
+
+	if err := core.pluginCatalog.Delete(ctx, "versioned", consts.PluginTypeSecrets, "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err = core.pluginCatalog.Versions(ctx, "versioned", consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"v1.10.0"}) {
+		t.Fatalf("bad: %#v", versions)
+	}
+}
@@ -3,12 +3,14 @@ package vault
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/hashicorp/vault/helper/namespace"
 
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -103,6 +105,124 @@ func (c *Core) reloadMatchingPlugin(ctx context.Context, pluginName string) erro
 	return nil
 }
 
+// pluginUpgradeTarget is a mount being upgraded to another version of its
+// plugin
+type pluginUpgradeTarget struct {
+	entry           *MountEntry
+	isAuth          bool
+	previousVersion string
+}
+
+// upgradeMatchingPlugin pins the mounted backends of plugin pluginName to the
+// given version and reloads them. If mounts is empty, all the mounts of the
+// plugin in the namespace are upgraded. If any mount fails to reload, the
+// mounts upgraded so far are rolled back to their previous version.
+func (c *Core) upgradeMatchingPlugin(ctx context.Context, pluginName, version string, mounts []string) ([]string, error) {
+	c.mountsLock.Lock()
+	defer c.mountsLock.Unlock()
+	c.authLock.Lock()
+	defer c.authLock.Unlock()
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*pluginUpgradeTarget
+	if len(mounts) > 0 {
+		for _, mount := range mounts {
+			entry := c.router.MatchingMountEntry(ctx, sanitizePath(mount))
+			if entry == nil || ns.ID != entry.Namespace().ID {
+				return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("cannot fetch mount entry on %q", mount))
+			}
+			if entry.Type != pluginName {
+				return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("mount %q doesn't run plugin %q", mount, pluginName))
+			}
+			targets = append(targets, &pluginUpgradeTarget{
+				entry:  entry,
+				isAuth: entry.Table == credentialTableType,
+			})
+		}
+	} else {
+		for _, table := range []*MountTable{c.mounts, c.auth} {
+			for _, entry := range table.Entries {
+				if ns.ID != entry.Namespace().ID || entry.Type != pluginName {
+					continue
+				}
+				targets = append(targets, &pluginUpgradeTarget{
+					entry:  entry,
+					isAuth: entry.Table == credentialTableType,
+				})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("no mounts of plugin %q found", pluginName))
+	}
+
+	// Make sure the version is registered before touching any mount
+	for _, target := range targets {
+		pluginType := consts.PluginTypeSecrets
+		if target.isAuth {
+			pluginType = consts.PluginTypeCredential
+		}
+		runner, err := c.pluginCatalog.Get(ctx, pluginName, pluginType, version)
+		if err != nil {
+			return nil, err
+		}
+		if runner == nil {
+			return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("version %q of %s plugin %q is not registered", version, pluginType, pluginName))
+		}
+	}
+
+	var upgraded []*pluginUpgradeTarget
+	var paths []string
+	for _, target := range targets {
+		target.previousVersion = target.entry.Version
+		target.entry.Version = version
+		upgraded = append(upgraded, target)
+
+		if err := c.reloadBackendCommon(ctx, target.entry, target.isAuth); err != nil {
+			c.rollbackPluginUpgrade(ctx, upgraded)
+			return nil, errwrap.Wrapf(fmt.Sprintf("cannot upgrade plugin on %q, the upgrade was rolled back: {{err}}", target.entry.Path), err)
+		}
+
+		path := target.entry.Path
+		if target.isAuth {
+			path = credentialRoutePrefix + path
+		}
+		paths = append(paths, path)
+	}
+
+	if err := c.persistMounts(ctx, c.mounts, nil); err != nil {
+		c.rollbackPluginUpgrade(ctx, upgraded)
+		return nil, errwrap.Wrapf("failed to persist the mount table, the upgrade was rolled back: {{err}}", err)
+	}
+	if err := c.persistAuth(ctx, c.auth, nil); err != nil {
+		c.rollbackPluginUpgrade(ctx, upgraded)
+		if err := c.persistMounts(ctx, c.mounts, nil); err != nil {
+			c.logger.Error("failed to persist the mount table on rollback", "error", err)
+		}
+		return nil, errwrap.Wrapf("failed to persist the auth table, the upgrade was rolled back: {{err}}", err)
+	}
+
+	for _, target := range upgraded {
+		c.logger.Info("successfully upgraded plugin", "plugin", pluginName, "version", version, "path", target.entry.Path)
+	}
+	return paths, nil
+}
+
+// rollbackPluginUpgrade restores the previous plugin version of the given
+// mounts and reloads them
+func (c *Core) rollbackPluginUpgrade(ctx context.Context, upgraded []*pluginUpgradeTarget) {
+	for _, target := range upgraded {
+		target.entry.Version = target.previousVersion
+		if err := c.reloadBackendCommon(ctx, target.entry, target.isAuth); err != nil {
+			c.logger.Error("failed to roll back plugin upgrade", "path", target.entry.Path, "version", target.previousVersion, "error", err)
+		}
+	}
+}
+
 // reloadBackendCommon is a generic method to reload a backend provided a
 // MountEntry.
 func (c *Core) reloadBackendCommon(ctx context.Context, entry *MountEntry, isAuth bool) error {
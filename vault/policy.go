@@ -8,6 +8,7 @@ import (
 
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
+	sockaddr "github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/identity"
@@ -15,6 +16,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/hclutil"
 	"github.com/hashicorp/vault/sdk/helper/identitytpl"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -27,6 +29,7 @@ const (
 	ListCapability   = "list"
 	SudoCapability   = "sudo"
 	RootCapability   = "root"
+	PatchCapability  = "patch"
 
 	// Backwards compatibility
 	OldDenyPathPolicy  = "deny"
@@ -43,6 +46,7 @@ const (
 	DeleteCapabilityInt
 	ListCapabilityInt
 	SudoCapabilityInt
+	PatchCapabilityInt
 )
 
 type PolicyType uint32
@@ -78,6 +82,7 @@ var (
 		DeleteCapability: DeleteCapabilityInt,
 		ListCapability:   ListCapabilityInt,
 		SudoCapability:   SudoCapabilityInt,
+		PatchCapability:  PatchCapabilityInt,
 	}
 )
 
@@ -129,6 +134,8 @@ type PathRules struct {
 	RequiredParametersHCL []string                 `hcl:"required_parameters"`
 	MFAMethodsHCL         []string                 `hcl:"mfa_methods"`
 	ControlGroupHCL       *ControlGroupHCL         `hcl:"control_group"`
+	BoundCIDRsHCL         []string                 `hcl:"bound_cidrs"`
+	AllowedTimeOfDayHCL   []string                 `hcl:"allowed_time_of_day"`
 }
 
 type ControlGroupHCL struct {
@@ -161,6 +168,19 @@ type ACLPermissions struct {
 	RequiredParameters []string
 	MFAMethods         []string
 	ControlGroup       *ControlGroup
+	BoundCIDRs         []*sockaddr.SockAddrMarshaler
+	AllowedTimeOfDay   []*timeOfDayWindow
+
+	// GrantingPoliciesMap tracks, per capability, the policies that granted
+	// it. It is only populated on the merged permissions held by an ACL.
+	GrantingPoliciesMap map[uint32][]logical.PolicyInfo
+
+	// ConditionsMap tracks, per capability, the bound CIDRs and time of day
+	// windows of the path rules that granted it. A capability granted by any
+	// rule without conditions has no entry. It is only populated on the
+	// merged permissions held by an ACL, which don't hold BoundCIDRs and
+	// AllowedTimeOfDay themselves.
+	ConditionsMap map[uint32][]*pathConditions
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -217,6 +237,32 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		ret.ControlGroup = clonedControlGroup.(*ControlGroup)
 	}
 
+	if p.BoundCIDRs != nil {
+		ret.BoundCIDRs = append([]*sockaddr.SockAddrMarshaler{}, p.BoundCIDRs...)
+	}
+
+	if p.AllowedTimeOfDay != nil {
+		ret.AllowedTimeOfDay = append([]*timeOfDayWindow{}, p.AllowedTimeOfDay...)
+	}
+
+	switch {
+	case p.GrantingPoliciesMap == nil:
+	default:
+		ret.GrantingPoliciesMap = make(map[uint32][]logical.PolicyInfo, len(p.GrantingPoliciesMap))
+		for capability, policies := range p.GrantingPoliciesMap {
+			ret.GrantingPoliciesMap[capability] = append([]logical.PolicyInfo(nil), policies...)
+		}
+	}
+
+	switch {
+	case p.ConditionsMap == nil:
+	default:
+		ret.ConditionsMap = make(map[uint32][]*pathConditions, len(p.ConditionsMap))
+		for capability, conditions := range p.ConditionsMap {
+			ret.ConditionsMap[capability] = append([]*pathConditions(nil), conditions...)
+		}
+	}
+
 	return ret, nil
 }
 
@@ -318,6 +364,8 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 			"max_wrapping_ttl",
 			"mfa_methods",
 			"control_group",
+			"bound_cidrs",
+			"allowed_time_of_day",
 		}
 		if err := hclutil.CheckHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -385,7 +433,7 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 				pc.Capabilities = []string{DenyCapability}
 				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
 				goto PathFinished
-			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability:
+			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability, PatchCapability:
 				pc.Permissions.CapabilitiesBitmap |= cap2Int[cap]
 			default:
 				return fmt.Errorf("path %q: invalid capability %q", key, cap)
@@ -395,14 +443,14 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 		if pc.AllowedParametersHCL != nil {
 			pc.Permissions.AllowedParameters = make(map[string][]interface{}, len(pc.AllowedParametersHCL))
 			for key, val := range pc.AllowedParametersHCL {
-				pc.Permissions.AllowedParameters[strings.ToLower(key)] = val
+				pc.Permissions.AllowedParameters[strings.ToLower(key)] = compileParameterValues(val)
 			}
 		}
 		if pc.DeniedParametersHCL != nil {
 			pc.Permissions.DeniedParameters = make(map[string][]interface{}, len(pc.DeniedParametersHCL))
 
 			for key, val := range pc.DeniedParametersHCL {
-				pc.Permissions.DeniedParameters[strings.ToLower(key)] = val
+				pc.Permissions.DeniedParameters[strings.ToLower(key)] = compileParameterValues(val)
 			}
 		}
 		if pc.MinWrappingTTLHCL != nil {
@@ -468,6 +516,23 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 		if len(pc.RequiredParametersHCL) > 0 {
 			pc.Permissions.RequiredParameters = pc.RequiredParametersHCL[:]
 		}
+		if len(pc.BoundCIDRsHCL) > 0 {
+			cidrs, err := parseutil.ParseAddrs(pc.BoundCIDRsHCL)
+			if err != nil {
+				return errwrap.Wrapf("error parsing bound_cidrs: {{err}}", err)
+			}
+			pc.Permissions.BoundCIDRs = cidrs
+		}
+		if len(pc.AllowedTimeOfDayHCL) > 0 {
+			pc.Permissions.AllowedTimeOfDay = make([]*timeOfDayWindow, 0, len(pc.AllowedTimeOfDayHCL))
+			for _, item := range pc.AllowedTimeOfDayHCL {
+				window, err := parseTimeOfDayWindow(item)
+				if err != nil {
+					return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
+				}
+				pc.Permissions.AllowedTimeOfDay = append(pc.Permissions.AllowedTimeOfDay, window)
+			}
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
@@ -0,0 +1,261 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	sockaddr "github.com/hashicorp/go-sockaddr"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// parameterComparisonRegex matches the allowed and denied parameter values
+// that are numeric comparisons, such as "<= 3600"
+var parameterComparisonRegex = regexp.MustCompile(`^\s*(<=|>=|<|>)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+// parameterComparison is a numeric comparison compiled from an allowed or
+// denied parameter value when the policy is parsed
+type parameterComparison struct {
+	Operator string
+	Value    float64
+}
+
+// compileParameterValues returns the parameter values with the numeric
+// comparisons compiled, so they don't have to be parsed on every request
+func compileParameterValues(values []interface{}) []interface{} {
+	if values == nil {
+		return nil
+	}
+
+	compiled := make([]interface{}, len(values))
+	for i, value := range values {
+		compiled[i] = value
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		matches := parameterComparisonRegex.FindStringSubmatch(s)
+		if matches == nil {
+			continue
+		}
+		number, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil {
+			continue
+		}
+		compiled[i] = parameterComparison{
+			Operator: matches[1],
+			Value:    number,
+		}
+	}
+	return compiled
+}
+
+// parameterNumber returns the value of a parameter as a number. Strings can
+// hold a number or a duration such as "48h", which is converted to seconds.
+func parameterNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return parameterNumber(v.String())
+	case string:
+		v = strings.TrimSpace(v)
+		if number, err := strconv.ParseFloat(v, 64); err == nil {
+			return number, true
+		}
+		if v == "" {
+			return 0, false
+		}
+		dur, err := parseutil.ParseDurationSecond(v)
+		if err != nil {
+			return 0, false
+		}
+		return dur.Seconds(), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// matches returns whether the value is a number satisfying the comparison.
+// A value that isn't a number matches when the comparison denies values, so
+// the comparisons of denied_parameters fail closed.
+func (c parameterComparison) matches(v interface{}, denied bool) bool {
+	number, ok := parameterNumber(v)
+	if !ok {
+		return denied
+	}
+
+	switch c.Operator {
+	case "<":
+		return number < c.Value
+	case "<=":
+		return number <= c.Value
+	case ">":
+		return number > c.Value
+	case ">=":
+		return number >= c.Value
+	}
+	return false
+}
+
+// MarshalJSON returns the comparison as written in the policy
+func (c parameterComparison) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%s %s", c.Operator, strconv.FormatFloat(c.Value, 'f', -1, 64)))
+}
+
+// timeOfDayWindow is a window of the day, in minutes since midnight UTC,
+// during which a path can be accessed. The window wraps around midnight when
+// its end is before its start.
+type timeOfDayWindow struct {
+	Start int
+	End   int
+}
+
+// parseTimeOfDayWindow parses a window such as "09:00-17:30"
+func parseTimeOfDayWindow(window string) (*timeOfDayWindow, error) {
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid time of day window %q", window)
+	}
+
+	minutes := make([]int, 2)
+	for i, bound := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day window %q", window)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	if minutes[0] == minutes[1] {
+		return nil, fmt.Errorf("invalid time of day window %q: start and end are equal", window)
+	}
+
+	return &timeOfDayWindow{
+		Start: minutes[0],
+		End:   minutes[1],
+	}, nil
+}
+
+// contains returns whether the time falls in the window
+func (w *timeOfDayWindow) contains(t time.Time) bool {
+	t = t.UTC()
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// timeOfDayAllowed returns whether the time falls in one of the windows
+func timeOfDayAllowed(windows []*timeOfDayWindow, t time.Time) bool {
+	for _, window := range windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddrAllowed returns whether the remote address is in one of the
+// CIDR blocks
+func remoteAddrAllowed(cidrs []*sockaddr.SockAddrMarshaler, remoteAddr string) bool {
+	if remoteAddr == "" {
+		return false
+	}
+	remoteSockAddr, err := sockaddr.NewSockAddr(remoteAddr)
+	if err != nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if cidr.Contains(remoteSockAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathConditions are the bound CIDRs and time of day windows of a path rule
+type pathConditions struct {
+	BoundCIDRs       []*sockaddr.SockAddrMarshaler
+	AllowedTimeOfDay []*timeOfDayWindow
+}
+
+// satisfied returns whether the request, made at the given time, satisfies
+// the conditions
+func (c *pathConditions) satisfied(req *logical.Request, t time.Time) bool {
+	if len(c.BoundCIDRs) > 0 {
+		if req.Connection == nil || !remoteAddrAllowed(c.BoundCIDRs, req.Connection.RemoteAddr) {
+			return false
+		}
+	}
+	if len(c.AllowedTimeOfDay) > 0 && !timeOfDayAllowed(c.AllowedTimeOfDay, t) {
+		return false
+	}
+	return true
+}
+
+// conditionsAllowed returns whether the request satisfies the conditions of
+// any of the path rules that granted a capability. A capability without
+// conditions is always allowed.
+func conditionsAllowed(conditions []*pathConditions, req *logical.Request, t time.Time) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+	for _, c := range conditions {
+		if c.satisfied(req, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// addConditionsToMap records the conditions of a path rule for each of the
+// capabilities it grants, given the capabilities already granted by the
+// other rules. The capabilities granted by a rule without conditions stay
+// unconditional, whatever the conditions of the other rules granting them.
+func addConditionsToMap(perms *ACLPermissions, rule *ACLPermissions, granted uint32) {
+	if rule.CapabilitiesBitmap&DenyCapabilityInt > 0 {
+		return
+	}
+
+	var conditions *pathConditions
+	if len(rule.BoundCIDRs) > 0 || len(rule.AllowedTimeOfDay) > 0 {
+		conditions = &pathConditions{
+			BoundCIDRs:       rule.BoundCIDRs,
+			AllowedTimeOfDay: rule.AllowedTimeOfDay,
+		}
+	}
+
+	for _, capability := range []uint32{
+		CreateCapabilityInt,
+		ReadCapabilityInt,
+		UpdateCapabilityInt,
+		DeleteCapabilityInt,
+		ListCapabilityInt,
+		SudoCapabilityInt,
+		PatchCapabilityInt,
+	} {
+		if rule.CapabilitiesBitmap&capability == 0 {
+			continue
+		}
+		_, conditional := perms.ConditionsMap[capability]
+		switch {
+		case conditions == nil:
+			delete(perms.ConditionsMap, capability)
+		case granted&capability > 0 && !conditional:
+			// Already granted without conditions
+		default:
+			if perms.ConditionsMap == nil {
+				perms.ConditionsMap = make(map[uint32][]*pathConditions)
+			}
+			perms.ConditionsMap[capability] = append(perms.ConditionsMap[capability], conditions)
+		}
+	}
+}
@@ -259,6 +259,10 @@ func (c *Core) setupPolicyStore(ctx context.Context) error {
 		// Policies will sync from the primary
 		return nil
 	}
+	if c.perfStandby {
+		// The active node ensures the policies exist
+		return nil
+	}
 
 	// Ensure that the default policy exists, and if not, create it
 	if err := c.policyStore.loadACLPolicy(ctx, defaultPolicyName, defaultPolicy); err != nil {
@@ -14,10 +14,28 @@ type entPolicyStore struct{}
 func (ps *PolicyStore) extraInit() {
 }
 
-func (ps *PolicyStore) loadNamespacePolicies(context.Context, *Core) error { return nil }
+// loadNamespacePolicies records the type of the policies of the namespaces
+// other than the root namespace
+func (ps *PolicyStore) loadNamespacePolicies(ctx context.Context, core *Core) error {
+	for _, ns := range core.listNamespaces() {
+		keys, err := logical.CollectKeys(namespace.ContextWithNamespace(ctx, ns), ps.getACLView(ns))
+		if err != nil {
+			ps.logger.Error("error collecting acl policy keys", "namespace", ns.Path, "error", err)
+			return err
+		}
+		for _, key := range keys {
+			index := ps.cacheKey(ns, ps.sanitizeName(key))
+			ps.policyTypeMap.Store(index, PolicyTypeACL)
+		}
+	}
+	return nil
+}
 
-func (ps *PolicyStore) getACLView(*namespace.Namespace) *BarrierView {
-	return ps.aclView
+func (ps *PolicyStore) getACLView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return ps.aclView
+	}
+	return ps.core.namespaceSystemView(ns).SubView(policyACLSubPath)
 }
 
 func (ps *PolicyStore) getRGPView(ns *namespace.Namespace) *BarrierView {
@@ -43,5 +61,10 @@ func (ps *PolicyStore) invalidateEGPTreePath(string) {}
 func (ps *PolicyStore) pathsToEGPPaths(*Policy) ([]*egpPath, error) { return nil, nil }
 
 func (ps *PolicyStore) loadACLPolicyNamespaces(ctx context.Context, policyName, policyText string) error {
-	return ps.loadACLPolicyInternal(namespace.RootContext(ctx), policyName, policyText)
+	for _, ns := range ps.core.collectNamespaces() {
+		if err := ps.loadACLPolicyInternal(namespace.ContextWithNamespace(ctx, ns), policyName, policyText); err != nil {
+			return err
+		}
+	}
+	return nil
 }
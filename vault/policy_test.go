@@ -400,6 +400,28 @@ path "/" {
 	}
 }
 
+func TestPolicy_ParseBadConditions(t *testing.T) {
+	_, err := ParseACLPolicy(namespace.RootNamespace, strings.TrimSpace(`
+path "/" {
+	policy = "read"
+	allowed_time_of_day = ["9am-5pm"]
+}
+`))
+	if err == nil || !strings.Contains(err.Error(), `path "/": invalid time of day window "9am-5pm"`) {
+		t.Errorf("bad error: %v", err)
+	}
+
+	_, err = ParseACLPolicy(namespace.RootNamespace, strings.TrimSpace(`
+path "/" {
+	policy = "read"
+	bound_cidrs = ["not-a-cidr"]
+}
+`))
+	if err == nil || !strings.Contains(err.Error(), "error parsing bound_cidrs") {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
 func TestPolicy_ParseBadCapabilities(t *testing.T) {
 	_, err := ParseACLPolicy(namespace.RootNamespace, strings.TrimSpace(`
 path "/" {
@@ -165,6 +165,10 @@ type Quota interface {
 
 	// handleRemount takes in the new mount path in the quota
 	handleRemount(string)
+
+	// pathSuffix is the path within the mount to which the quota applies, or
+	// empty if it applies to the whole mount
+	pathSuffix() string
 }
 
 // Response holds information about the result of the Allow() call. The response
@@ -344,7 +348,7 @@ func (m *Manager) QuotaByName(qType string, name string) (Quota, error) {
 }
 
 // QuotaByFactors returns the quota rule that matches the provided factors
-func (m *Manager) QuotaByFactors(ctx context.Context, qType, nsPath, mountPath string) (Quota, error) {
+func (m *Manager) QuotaByFactors(ctx context.Context, qType, nsPath, mountPath, pathSuffix string) (Quota, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
@@ -368,7 +372,11 @@ func (m *Manager) QuotaByFactors(ctx context.Context, qType, nsPath, mountPath s
 	}
 	var quotas []Quota
 	for raw := iter.Next(); raw != nil; raw = iter.Next() {
-		quotas = append(quotas, raw.(Quota))
+		quota := raw.(Quota)
+		if quota.pathSuffix() != pathSuffix {
+			continue
+		}
+		quotas = append(quotas, quota)
 	}
 	if len(quotas) > 1 {
 		return nil, fmt.Errorf("conflicting quota definitions detected")
@@ -395,6 +403,8 @@ func (m *Manager) QueryQuota(req *Request) (Quota, error) {
 // Priority rules are as follows:
 // - namespace specific quota takes precedence over global quota
 // - mount specific quota takes precedence over namespace specific quota
+// - path specific quota takes precedence over mount specific quota, and the
+//   quota on the longest matching path within the mount wins
 func (m *Manager) queryQuota(txn *memdb.Txn, req *Request) (Quota, error) {
 	if txn == nil {
 		txn = m.db.Txn(false)
@@ -429,8 +439,8 @@ func (m *Manager) queryQuota(txn *memdb.Txn, req *Request) (Quota, error) {
 		return quotas[0], nil
 	}
 
-	// Fetch mount quota
-	quota, err := quotaFetchFunc(indexNamespaceMount, req.NamespacePath, req.MountPath)
+	// Fetch path or mount quota
+	quota, err := m.queryMountQuota(txn, req)
 	if err != nil {
 		return nil, err
 	}
@@ -467,6 +477,35 @@ func (m *Manager) queryQuota(txn *memdb.Txn, req *Request) (Quota, error) {
 	return nil, nil
 }
 
+// queryMountQuota returns the quota rule of the mount of the request that is
+// defined on the longest path matching the request path within the mount. A
+// quota with no path applies to the whole mount.
+func (m *Manager) queryMountQuota(txn *memdb.Txn, req *Request) (Quota, error) {
+	iter, err := txn.Get(req.Type.String(), indexNamespaceMount, req.NamespacePath, req.MountPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reqSuffix := strings.TrimPrefix(req.Path, req.MountPath)
+	var match Quota
+	longest := -1
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		quota := raw.(Quota)
+		suffix := quota.pathSuffix()
+		if !strings.HasPrefix(reqSuffix, suffix) {
+			continue
+		}
+		switch {
+		case len(suffix) > longest:
+			match, longest = quota, len(suffix)
+		case len(suffix) == longest:
+			return nil, fmt.Errorf("conflicting quota definitions detected")
+		}
+	}
+
+	return match, nil
+}
+
 // DeleteQuota removes a quota rule from the db for a given name
 func (m *Manager) DeleteQuota(ctx context.Context, qType string, name string) error {
 	m.lock.Lock()
@@ -888,3 +927,53 @@ func (m *Manager) HandleBackendDisabling(ctx context.Context, nsPath, mountPath
 
 	return nil
 }
+
+// HandleLeases updates the lease count quotas with leases that were created,
+// loaded or deleted by the expiration manager. Each request describes the path
+// of a lease.
+func (m *Manager) HandleLeases(action LeaseAction, reqs []*Request) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txn := m.db.Txn(false)
+	for _, req := range reqs {
+		req.Type = TypeLeaseCount
+
+		var delta int64
+		switch action {
+		case LeaseActionCreated, LeaseActionLoaded:
+			m.leasePaths.Store(req.Path, struct{}{})
+			delta = 1
+		case LeaseActionDeleted:
+			delta = -1
+		default:
+			continue
+		}
+
+		quota, err := m.queryQuota(txn, req)
+		if err != nil {
+			return err
+		}
+		if quota != nil {
+			quota.(*LeaseCountQuota).addLeases(delta)
+		}
+	}
+
+	return nil
+}
+
+// AckLeaseQuota releases the lease reserved by a lease count quota when it
+// allowed a request.
+func (m *Manager) AckLeaseQuota(access Access) error {
+	quota, err := m.QuotaByID(TypeLeaseCount.String(), access.QuotaID())
+	if err != nil {
+		return err
+	}
+	if quota == nil {
+		// The quota was deleted in the meantime
+		return nil
+	}
+
+	quota.(*LeaseCountQuota).ack()
+	return nil
+}
@@ -0,0 +1,213 @@
+package quotas
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
+	"go.uber.org/atomic"
+)
+
+// Ensure that LeaseCountQuota implements the Quota interface
+var _ Quota = (*LeaseCountQuota)(nil)
+
+// LeaseCountQuota represents the quota rule properties that is used to limit
+// the number of leases in a namespace or mount.
+type LeaseCountQuota struct {
+	// ID is the identifier of the quota
+	ID string `json:"id"`
+
+	// Type of quota this represents
+	Type Type `json:"type"`
+
+	// Name of the quota rule
+	Name string `json:"name"`
+
+	// NamespacePath is the path of the namespace to which this quota is
+	// applicable.
+	NamespacePath string `json:"namespace_path"`
+
+	// MountPath is the path of the mount to which this quota is applicable
+	MountPath string `json:"mount_path"`
+
+	// PathSuffix is the path within the mount to which this quota is
+	// applicable. It applies to the whole mount if empty.
+	PathSuffix string `json:"path_suffix"`
+
+	// MaxLeases is the maximum number of leases allowed by the quota. New
+	// leases are rejected once it is reached.
+	MaxLeases int `json:"max_leases"`
+
+	// SoftMaxLeases, if non-zero, is the number of leases beyond which a
+	// warning is logged and warning metrics are emitted for each new lease.
+	SoftMaxLeases int `json:"soft_max_leases"`
+
+	lock       *sync.RWMutex
+	counter    *atomic.Int64
+	warned     *atomic.Bool
+	logger     log.Logger
+	metricSink *metricsutil.ClusterMetricSink
+}
+
+// NewLeaseCountQuota creates a quota checker for imposing limits on the number
+// of leases in a namespace or mount.
+func NewLeaseCountQuota(name, nsPath, mountPath string, maxLeases, softMaxLeases int) *LeaseCountQuota {
+	return &LeaseCountQuota{
+		Name:          name,
+		Type:          TypeLeaseCount,
+		NamespacePath: nsPath,
+		MountPath:     mountPath,
+		MaxLeases:     maxLeases,
+		SoftMaxLeases: softMaxLeases,
+	}
+}
+
+// initialize ensures the namespace and limits are valid and sets the ID if
+// it's currently empty. The lease counter is kept across updates of the quota.
+func (lcq *LeaseCountQuota) initialize(logger log.Logger, ms *metricsutil.ClusterMetricSink) error {
+	if lcq.lock == nil {
+		lcq.lock = new(sync.RWMutex)
+	}
+
+	lcq.lock.Lock()
+	defer lcq.lock.Unlock()
+
+	// Memdb requires a non-empty value for indexing
+	if lcq.NamespacePath == "" {
+		lcq.NamespacePath = "root"
+	}
+
+	if lcq.MaxLeases <= 0 {
+		return fmt.Errorf("invalid max leases: %v", lcq.MaxLeases)
+	}
+
+	if lcq.SoftMaxLeases < 0 || lcq.SoftMaxLeases >= lcq.MaxLeases {
+		return fmt.Errorf("invalid soft max leases: %v", lcq.SoftMaxLeases)
+	}
+
+	if logger != nil {
+		lcq.logger = logger
+	}
+
+	if lcq.metricSink == nil {
+		lcq.metricSink = ms
+	}
+
+	if lcq.ID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return err
+		}
+
+		lcq.ID = id
+	}
+
+	if lcq.counter == nil {
+		lcq.counter = atomic.NewInt64(0)
+		lcq.warned = atomic.NewBool(false)
+	}
+
+	return nil
+}
+
+// quotaID returns the identifier of the quota rule
+func (lcq *LeaseCountQuota) quotaID() string {
+	return lcq.ID
+}
+
+// QuotaName returns the name of the quota rule
+func (lcq *LeaseCountQuota) QuotaName() string {
+	return lcq.Name
+}
+
+// Count returns the number of leases currently counted against the quota,
+// including the leases reserved by requests in flight.
+func (lcq *LeaseCountQuota) Count() int64 {
+	return lcq.counter.Load()
+}
+
+// allow reserves a lease for the request if the quota isn't exhausted. The
+// reservation is released by ack once the request completes; by then, a lease
+// generated by the request is counted on its own.
+func (lcq *LeaseCountQuota) allow(req *Request) (Response, error) {
+	var resp Response
+
+	for {
+		count := lcq.counter.Load()
+		if count >= int64(lcq.MaxLeases) {
+			lcq.metricSink.IncrCounterWithLabels([]string{"quota", "lease_count", "violation"}, 1, []metrics.Label{{"name", lcq.Name}})
+			return resp, nil
+		}
+		if lcq.counter.CAS(count, count+1) {
+			lcq.emitMetrics(count + 1)
+			break
+		}
+	}
+
+	resp.Allowed = true
+	resp.Access = &access{quotaID: lcq.ID}
+	return resp, nil
+}
+
+// ack releases the lease reserved by allow.
+func (lcq *LeaseCountQuota) ack() {
+	lcq.addLeases(-1)
+}
+
+// addLeases adjusts the number of leases counted against the quota, which
+// never goes below zero.
+func (lcq *LeaseCountQuota) addLeases(delta int64) {
+	for {
+		count := lcq.counter.Load()
+		updated := count + delta
+		if updated < 0 {
+			updated = 0
+		}
+		if lcq.counter.CAS(count, updated) {
+			lcq.emitMetrics(updated)
+			return
+		}
+	}
+}
+
+// resetLeases clears the lease counter before the leases are counted again.
+func (lcq *LeaseCountQuota) resetLeases() {
+	lcq.counter.Store(0)
+	lcq.warned.Store(false)
+}
+
+// emitMetrics publishes the lease count of the quota and warns when the count
+// reaches the soft limit.
+func (lcq *LeaseCountQuota) emitMetrics(count int64) {
+	labels := []metrics.Label{{"name", lcq.Name}}
+	lcq.metricSink.SetGaugeWithLabels([]string{"quota", "lease_count", "counter"}, float32(count), labels)
+	lcq.metricSink.SetGaugeWithLabels([]string{"quota", "lease_count", "max"}, float32(lcq.MaxLeases), labels)
+
+	if lcq.SoftMaxLeases == 0 {
+		return
+	}
+	if count < int64(lcq.SoftMaxLeases) {
+		lcq.warned.Store(false)
+		return
+	}
+	lcq.metricSink.IncrCounterWithLabels([]string{"quota", "lease_count", "warning"}, 1, labels)
+	if lcq.warned.CAS(false, true) && lcq.logger != nil {
+		lcq.logger.Warn("lease count quota reached its soft limit", "name", lcq.Name, "soft_max_leases", lcq.SoftMaxLeases, "max_leases", lcq.MaxLeases)
+	}
+}
+
+// close is a no-op for lease count quotas.
+func (lcq *LeaseCountQuota) close() error {
+	return nil
+}
+
+func (lcq *LeaseCountQuota) handleRemount(toPath string) {
+	lcq.MountPath = toPath
+}
+
+func (lcq *LeaseCountQuota) pathSuffix() string {
+	return lcq.PathSuffix
+}
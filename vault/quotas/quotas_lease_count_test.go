@@ -0,0 +1,117 @@
+package quotas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/shared-secure-libs/metricsutil"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLeaseCountQuota(t *testing.T) {
+	testCases := []struct {
+		name      string
+		lcq       *LeaseCountQuota
+		expectErr bool
+	}{
+		{"valid max", NewLeaseCountQuota("test-lease-count-quota", "qa", "/foo/bar", 10, 0), false},
+		{"valid soft max", NewLeaseCountQuota("test-lease-count-quota", "qa", "/foo/bar", 10, 8), false},
+		{"invalid max", NewLeaseCountQuota("test-lease-count-quota", "qa", "/foo/bar", 0, 0), true},
+		{"invalid soft max", NewLeaseCountQuota("test-lease-count-quota", "qa", "/foo/bar", 10, 10), true},
+		{"negative soft max", NewLeaseCountQuota("test-lease-count-quota", "qa", "/foo/bar", 10, -1), true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.lcq.initialize(logging.NewVaultLogger(log.Trace), metricsutil.BlackholeSink())
+			require.Equal(t, tc.expectErr, err != nil, err)
+		})
+	}
+}
+
+func TestLeaseCountQuota_Allow(t *testing.T) {
+	inmemSink := metrics.NewInmemSink(1000000*time.Hour, 2000000*time.Hour)
+	lcq := NewLeaseCountQuota("test-lease-count-quota", "", "", 3, 2)
+	require.NoError(t, lcq.initialize(logging.NewVaultLogger(log.Trace), metricsutil.NewClusterMetricSink("test-cluster", inmemSink)))
+
+	// Each allowed request reserves a lease until it's acknowledged
+	resp, err := lcq.allow(&Request{})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+	require.Equal(t, int64(1), lcq.Count())
+	lcq.ack()
+	require.Equal(t, int64(0), lcq.Count())
+
+	lcq.addLeases(2)
+	resp, err = lcq.allow(&Request{})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+
+	// The quota is exhausted until the reservation is released
+	resp, err = lcq.allow(&Request{})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+	lcq.ack()
+
+	// The counter never goes below zero
+	lcq.addLeases(-10)
+	require.Equal(t, int64(0), lcq.Count())
+
+	intervals := inmemSink.Data()
+	counters := intervals[len(intervals)-1].Counters
+	labels := ";name=test-lease-count-quota;cluster=test-cluster"
+	require.Contains(t, counters, "quota.lease_count.violation"+labels)
+	require.Contains(t, counters, "quota.lease_count.warning"+labels)
+	require.Contains(t, intervals[len(intervals)-1].Gauges, "quota.lease_count.counter"+labels)
+}
+
+func TestLeaseCountQuota_HandleLeases(t *testing.T) {
+	leases := []*Request{
+		{Path: "secret/creds/a", MountPath: "secret/", NamespacePath: "root"},
+		{Path: "secret/creds/b", MountPath: "secret/", NamespacePath: "root"},
+		{Path: "other/creds/a", MountPath: "other/", NamespacePath: "root"},
+	}
+	walkFunc := func(ctx context.Context, cb func(*Request) bool) error {
+		for _, lease := range leases {
+			req := *lease
+			if !cb(&req) {
+				break
+			}
+		}
+		return nil
+	}
+
+	qm, err := NewManager(logging.NewVaultLogger(log.Trace), walkFunc, metricsutil.BlackholeSink())
+	require.NoError(t, err)
+
+	// Existing leases are counted when the quota is created
+	lcq := NewLeaseCountQuota("lcq", "", "secret/", 2, 0)
+	require.NoError(t, qm.SetQuota(context.Background(), TypeLeaseCount.String(), lcq, false))
+	require.Equal(t, int64(2), lcq.Count())
+	require.True(t, qm.inLeasePathCache("secret/creds/a"))
+	require.False(t, qm.inLeasePathCache("secret/creds/c"))
+
+	resp, err := qm.ApplyQuota(&Request{Type: TypeLeaseCount, Path: "secret/creds/a", MountPath: "secret/"})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+
+	// Deleted leases free the quota
+	require.NoError(t, qm.HandleLeases(LeaseActionDeleted, []*Request{{Path: "secret/creds/b", MountPath: "secret/"}}))
+	require.Equal(t, int64(1), lcq.Count())
+
+	resp, err = qm.ApplyQuota(&Request{Type: TypeLeaseCount, Path: "secret/creds/a", MountPath: "secret/"})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+	require.NoError(t, qm.HandleLeases(LeaseActionCreated, []*Request{{Path: "secret/creds/a", MountPath: "secret/"}}))
+	require.NoError(t, qm.AckLeaseQuota(resp.Access))
+	require.Equal(t, int64(2), lcq.Count())
+
+	// Leases of other mounts aren't counted
+	require.NoError(t, qm.HandleLeases(LeaseActionCreated, []*Request{{Path: "other/creds/b", MountPath: "other/"}}))
+	require.Equal(t, int64(2), lcq.Count())
+}
@@ -67,9 +67,19 @@ type RateLimitQuota struct {
 	// MountPath is the path of the mount to which this quota is applicable
 	MountPath string `json:"mount_path"`
 
+	// PathSuffix is the path within the mount to which this quota is
+	// applicable. It applies to the whole mount if empty.
+	PathSuffix string `json:"path_suffix"`
+
 	// Rate defines the number of requests allowed per Interval.
 	Rate float64 `json:"rate"`
 
+	// Burst defines the number of requests a client may make at once. If
+	// non-zero, the client's allowance is refilled with Burst requests as
+	// often as needed to sustain Rate requests per Interval; otherwise, it is
+	// refilled with Rate requests every Interval.
+	Burst int `json:"burst"`
+
 	// Interval defines the duration to which rate limiting is applied.
 	Interval time.Duration `json:"interval"`
 
@@ -137,6 +147,10 @@ func (rlq *RateLimitQuota) initialize(logger log.Logger, ms *metricsutil.Cluster
 		return fmt.Errorf("invalid block interval: %v", rlq.BlockInterval)
 	}
 
+	if rlq.Burst < 0 {
+		return fmt.Errorf("invalid burst: %v", rlq.Burst)
+	}
+
 	if logger != nil {
 		rlq.logger = logger
 	}
@@ -154,11 +168,22 @@ func (rlq *RateLimitQuota) initialize(logger log.Logger, ms *metricsutil.Cluster
 		rlq.ID = id
 	}
 
+	tokens, interval := uint64(math.Round(rlq.Rate)), rlq.Interval
+	if rlq.Burst > 0 {
+		// Refill 'rlq.Burst' tokens at the interval that averages to 'rlq.Rate'
+		// requests per 'Interval'
+		tokens = uint64(rlq.Burst)
+		interval = time.Duration(float64(rlq.Interval) * float64(rlq.Burst) / rlq.Rate)
+		if interval <= 0 {
+			return fmt.Errorf("invalid burst %d for rate %v", rlq.Burst, rlq.Rate)
+		}
+	}
+
 	rlStore, err := memorystore.New(&memorystore.Config{
-		Tokens:        uint64(math.Round(rlq.Rate)), // allow 'rlq.Rate' number of requests per 'Interval'
-		Interval:      rlq.Interval,                 // time interval in which to enforce rate limiting
-		SweepInterval: rlq.purgeInterval,            // how often stale clients are removed
-		SweepMinTTL:   rlq.staleAge,                 // how long since the last request a client is considered stale
+		Tokens:        tokens,            // allow 'tokens' number of requests per 'interval'
+		Interval:      interval,          // time interval in which to enforce rate limiting
+		SweepInterval: rlq.purgeInterval, // how often stale clients are removed
+		SweepMinTTL:   rlq.staleAge,      // how long since the last request a client is considered stale
 	})
 	if err != nil {
 		return err
@@ -266,7 +291,11 @@ func (rlq *RateLimitQuota) allow(req *Request) (Response, error) {
 	defer func() {
 		if !resp.Allowed {
 			resp.Headers[httplimit.HeaderRetryAfter] = retryAfter
-			rlq.metricSink.IncrCounterWithLabels([]string{"quota", "rate_limit", "violation"}, 1, []metrics.Label{{"name", rlq.Name}})
+			rlq.metricSink.IncrCounterWithLabels([]string{"quota", "rate_limit", "violation"}, 1, []metrics.Label{
+				{"name", rlq.Name},
+				{"namespace", rlq.NamespacePath},
+				{"mount_point", rlq.MountPath},
+			})
 		}
 	}()
 
@@ -322,3 +351,7 @@ func (rlq *RateLimitQuota) close() error {
 func (rlq *RateLimitQuota) handleRemount(toPath string) {
 	rlq.MountPath = toPath
 }
+
+func (rlq *RateLimitQuota) pathSuffix() string {
+	return rlq.PathSuffix
+}
@@ -10,6 +10,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/shared-secure-libs/metricsutil"
 	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/sethvargo/go-limiter/httplimit"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 )
@@ -26,6 +27,7 @@ func TestNewRateLimitQuota(t *testing.T) {
 		expectErr bool
 	}{
 		{"valid rate", NewRateLimitQuota("test-rate-limiter", "qa", "/foo/bar", 16.7, time.Second, 0), false},
+		{"invalid burst", &RateLimitQuota{Name: "test-rate-limiter", Rate: 16.7, Burst: -1}, true},
 	}
 
 	for _, tc := range testCases {
@@ -120,6 +122,41 @@ func TestRateLimitQuota_Allow(t *testing.T) {
 	}
 }
 
+func TestRateLimitQuota_Allow_WithBurst(t *testing.T) {
+	rlq := &RateLimitQuota{
+		Name:          "test-rate-limiter",
+		Type:          TypeRateLimit,
+		NamespacePath: "qa",
+		MountPath:     "/foo/bar",
+		Rate:          10,
+		Interval:      time.Second,
+		Burst:         30,
+
+		// override values to lower durations for testing purposes
+		purgeInterval: 10 * time.Second,
+		staleAge:      10 * time.Second,
+	}
+
+	require.NoError(t, rlq.initialize(logging.NewVaultLogger(log.Trace), metricsutil.BlackholeSink()))
+
+	// The whole burst is allowed at once, which is refilled every 3s to
+	// sustain the rate
+	for i := 0; i < 30; i++ {
+		resp, err := rlq.allow(&Request{ClientAddress: "127.0.0.1"})
+		require.NoError(t, err)
+		require.Truef(t, resp.Allowed, "expected request %d to be allowed", i)
+	}
+	resp, err := rlq.allow(&Request{ClientAddress: "127.0.0.1"})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed, "expected request beyond the burst to be rejected")
+	require.Equal(t, "30", resp.Headers[httplimit.HeaderRateLimitLimit])
+
+	// Other clients have their own allowance
+	resp, err = rlq.allow(&Request{ClientAddress: "127.0.0.2"})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+}
+
 func TestRateLimitQuota_Allow_WithBlock(t *testing.T) {
 	rlq := &RateLimitQuota{
 		Name:          "test-rate-limiter",
@@ -61,3 +61,45 @@ func TestQuotas_Precedence(t *testing.T) {
 	checkQuotaFunc(t, "", "", rateLimitGlobalQuota)
 	checkQuotaFunc(t, "testns", "", rateLimitNSQuota)
 }
+
+func TestQuotas_PathPrecedence(t *testing.T) {
+	qm, err := NewManager(logging.NewVaultLogger(log.Trace), nil, metricsutil.BlackholeSink())
+	require.NoError(t, err)
+
+	setQuotaFunc := func(t *testing.T, name, mountPath, pathSuffix string) Quota {
+		t.Helper()
+		quota := NewRateLimitQuota(name, "", mountPath, 10, time.Second, 0)
+		quota.PathSuffix = pathSuffix
+		require.NoError(t, qm.SetQuota(context.Background(), TypeRateLimit.String(), quota, true))
+		return quota
+	}
+
+	checkQuotaFunc := func(t *testing.T, path string, expected Quota) {
+		t.Helper()
+		quota, err := qm.QueryQuota(&Request{
+			Type:      TypeRateLimit,
+			Path:      path,
+			MountPath: "secret/",
+		})
+		require.NoError(t, err)
+
+		if diff := deep.Equal(expected, quota); len(diff) > 0 {
+			t.Fatal(diff)
+		}
+	}
+
+	mountQuota := setQuotaFunc(t, "mount", "secret/", "")
+	appQuota := setQuotaFunc(t, "app", "secret/", "data/app")
+	nestedQuota := setQuotaFunc(t, "nested", "secret/", "data/app/nested")
+
+	// The quota on the longest matching path wins
+	checkQuotaFunc(t, "secret/data/other", mountQuota)
+	checkQuotaFunc(t, "secret/data/app", appQuota)
+	checkQuotaFunc(t, "secret/data/app/config", appQuota)
+	checkQuotaFunc(t, "secret/data/app/nested/config", nestedQuota)
+
+	// Quotas on other paths of the mount are not conflicting
+	quota, err := qm.QuotaByFactors(context.Background(), TypeRateLimit.String(), "", "secret/", "data/app")
+	require.NoError(t, err)
+	require.Equal(t, appQuota, quota)
+}
@@ -4,62 +4,77 @@ package quotas
 
 import (
 	"context"
-
-	log "github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/shared-secure-libs/metricsutil"
+	"sync"
 
 	memdb "github.com/hashicorp/go-memdb"
 )
 
 func quotaTypes() []string {
 	return []string{
+		TypeLeaseCount.String(),
 		TypeRateLimit.String(),
 	}
 }
 
-func (m *Manager) init(walkFunc leaseWalkFunc) {}
+func (m *Manager) init(walkFunc leaseWalkFunc) {
+	m.walkFunc = walkFunc
+}
 
+// recomputeLeaseCounts counts the leases of each lease count quota again,
+// from the leases known to the expiration manager. It is called when the lease
+// count quotas change, with the manager's lock held.
 func (m *Manager) recomputeLeaseCounts(ctx context.Context, txn *memdb.Txn) error {
-	return nil
+	iter, err := txn.Get(TypeLeaseCount.String(), indexID)
+	if err != nil {
+		return err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		raw.(*LeaseCountQuota).resetLeases()
+	}
+
+	if m.walkFunc == nil {
+		return nil
+	}
+
+	return m.walkFunc(ctx, func(req *Request) bool {
+		req.Type = TypeLeaseCount
+		m.leasePaths.Store(req.Path, struct{}{})
+
+		quota, err := m.queryQuota(txn, req)
+		if err != nil {
+			m.logger.Error("failed to find lease count quota of lease", "path", req.Path, "error", err)
+			return true
+		}
+		if quota != nil {
+			quota.(*LeaseCountQuota).addLeases(1)
+		}
+		return true
+	})
 }
 
 func (m *Manager) setIsPerfStandby(quota Quota) {}
 
+// inLeasePathCache returns whether leases were generated by requests to the
+// path.
 func (m *Manager) inLeasePathCache(path string) bool {
-	return false
+	_, ok := m.leasePaths.Load(path)
+	return ok
 }
 
 type entManager struct {
 	isPerfStandby bool
-}
-
-func (*entManager) Reset() error {
-	return nil
-}
-
-type LeaseCountQuota struct {
-}
 
-func (l LeaseCountQuota) allow(request *Request) (Response, error) {
-	panic("implement me")
-}
-
-func (l LeaseCountQuota) quotaID() string {
-	panic("implement me")
-}
+	// walkFunc walks the leases known to the expiration manager
+	walkFunc leaseWalkFunc
 
-func (l LeaseCountQuota) QuotaName() string {
-	panic("implement me")
+	// leasePaths holds the request paths that generated leases
+	leasePaths sync.Map
 }
 
-func (l LeaseCountQuota) initialize(logger log.Logger, sink *metricsutil.ClusterMetricSink) error {
-	panic("implement me")
-}
-
-func (l LeaseCountQuota) close() error {
-	panic("implement me")
-}
-
-func (l LeaseCountQuota) handleRemount(s string) {
-	panic("implement me")
+func (e *entManager) Reset() error {
+	e.leasePaths.Range(func(k, _ interface{}) bool {
+		e.leasePaths.Delete(k)
+		return true
+	})
+	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
@@ -22,6 +23,7 @@ import (
 	"github.com/hashicorp/vault/vault/replication"
 	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -275,7 +277,7 @@ func (c *Core) refreshRequestForwardingConnection(ctx context.Context, clusterAd
 	// ALPN header right. It's just "insecure" because GRPC isn't managing
 	// the TLS state.
 	dctx, cancelFunc := context.WithCancel(ctx)
-	c.rpcClientConn, err = grpc.DialContext(dctx, clusterURL.Host,
+	dialOpts := []grpc.DialOption{
 		grpc.WithDialer(clusterListener.GetDialerFunc(ctx, consts.RequestForwardingALPN)),
 		grpc.WithInsecure(), // it's not, we handle it in the dialer
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -284,7 +286,9 @@ func (c *Core) refreshRequestForwardingConnection(ctx context.Context, clusterAd
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(math.MaxInt32),
 			grpc.MaxCallSendMsgSize(math.MaxInt32),
-		))
+		),
+	}
+	c.rpcClientConn, err = grpc.DialContext(dctx, clusterURL.Host, dialOpts...)
 	if err != nil {
 		cancelFunc()
 		c.logger.Error("err setting up forwarding rpc client", "error", err)
@@ -298,11 +302,37 @@ func (c *Core) refreshRequestForwardingConnection(ctx context.Context, clusterAd
 		echoTicker:              time.NewTicker(c.clusterHeartbeatInterval),
 		echoContext:             dctx,
 	}
+
+	// The requests are forwarded over the pool in turn, so that a large
+	// payload doesn't hold up the requests forwarded after it
+	c.rpcForwardingPool = []RequestForwardingClient{c.rpcForwardingClient}
+	for i := 1; i < c.forwardingConnections; i++ {
+		conn, err := grpc.DialContext(dctx, clusterURL.Host, dialOpts...)
+		if err != nil {
+			c.rpcForwardingClient.echoTicker.Stop()
+			c.clearForwardingClients()
+			c.logger.Error("err setting up forwarding rpc pool", "error", err)
+			return err
+		}
+		c.rpcPoolConns = append(c.rpcPoolConns, conn)
+		c.rpcForwardingPool = append(c.rpcForwardingPool, NewRequestForwardingClient(conn))
+	}
+
 	c.rpcForwardingClient.startHeartbeat()
 
 	return nil
 }
 
+// nextForwardingClient returns the client of the forwarding pool to forward
+// the next request over. The forwarding connection lock must be held.
+func (c *Core) nextForwardingClient() RequestForwardingClient {
+	if len(c.rpcForwardingPool) == 0 {
+		return c.rpcForwardingClient
+	}
+	next := atomic.AddUint32(&c.rpcForwardingPoolNext, 1)
+	return c.rpcForwardingPool[int(next)%len(c.rpcForwardingPool)]
+}
+
 func (c *Core) clearForwardingClients() {
 	c.logger.Debug("clearing forwarding clients")
 	defer c.logger.Debug("done clearing forwarding clients")
@@ -315,9 +345,14 @@ func (c *Core) clearForwardingClients() {
 		c.rpcClientConn.Close()
 		c.rpcClientConn = nil
 	}
+	for _, conn := range c.rpcPoolConns {
+		conn.Close()
+	}
+	c.rpcPoolConns = nil
 
 	c.rpcClientConnContext = nil
 	c.rpcForwardingClient = nil
+	c.rpcForwardingPool = nil
 
 	clusterListener := c.getClusterListener()
 	if clusterListener != nil {
@@ -352,7 +387,12 @@ func (c *Core) ForwardRequest(req *http.Request) (int, http.Header, []byte, erro
 		c.logger.Error("got nil forwarding RPC request")
 		return 0, nil, nil, fmt.Errorf("got nil forwarding RPC request")
 	}
-	resp, err := c.rpcForwardingClient.ForwardRequest(c.rpcClientConnContext, freq)
+
+	var callOpts []grpc.CallOption
+	if c.forwardingCompression {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	resp, err := c.nextForwardingClient().ForwardRequest(c.rpcClientConnContext, freq, callOpts...)
 	if err != nil {
 		c.logger.Error("error during forwarded RPC request", "error", err)
 		return 0, nil, nil, fmt.Errorf("error during forwarding RPC request")
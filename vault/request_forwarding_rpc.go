@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"runtime"
 	"sync/atomic"
@@ -91,6 +92,48 @@ func (s *forwardedRequestRPCServer) Echo(ctx context.Context, in *EchoRequest) (
 	return reply, nil
 }
 
+// PerfStandbyInvalidations streams the keys written to the storage to a
+// performance standby, until it disconnects or falls behind
+func (s *forwardedRequestRPCServer) PerfStandbyInvalidations(in *PerfStandbyInvalidationsInput, stream RequestForwarding_PerfStandbyInvalidationsServer) error {
+	ch := s.core.perfStandbyInvalidator.subscribe()
+	defer s.core.perfStandbyInvalidator.unsubscribe(ch)
+
+	// Now that the keys are published to the standby, it can refresh
+	// whatever it may have missed
+	if err := stream.Send(&PerfStandbyInvalidation{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case key, ok := <-ch:
+			if !ok {
+				return errors.New("performance standby fell behind the invalidations")
+			}
+
+			// Send the keys already queued along
+			keys := []string{key}
+		QUEUED:
+			for len(keys) < perfStandbyInvalidationsBuffer {
+				select {
+				case key, ok := <-ch:
+					if !ok {
+						break QUEUED
+					}
+					keys = append(keys, key)
+				default:
+					break QUEUED
+				}
+			}
+			if err := stream.Send(&PerfStandbyInvalidation{Keys: keys}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 type forwardingClient struct {
 	RequestForwardingClient
 
@@ -483,6 +483,93 @@ func (x *PerfStandbyElectionResponse) GetClientKey() *ClientKey {
 	return nil
 }
 
+type PerfStandbyInvalidationsInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PerfStandbyInvalidationsInput) Reset() {
+	*x = PerfStandbyInvalidationsInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vault_request_forwarding_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PerfStandbyInvalidationsInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PerfStandbyInvalidationsInput) ProtoMessage() {}
+
+func (x *PerfStandbyInvalidationsInput) ProtoReflect() protoreflect.Message {
+	mi := &file_vault_request_forwarding_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PerfStandbyInvalidationsInput.ProtoReflect.Descriptor instead.
+func (*PerfStandbyInvalidationsInput) Descriptor() ([]byte, []int) {
+	return file_vault_request_forwarding_service_proto_rawDescGZIP(), []int{6}
+}
+
+type PerfStandbyInvalidation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Keys are the storage keys written on the active node. The first
+	// message of the stream has none and asks for a full refresh.
+	Keys []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (x *PerfStandbyInvalidation) Reset() {
+	*x = PerfStandbyInvalidation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vault_request_forwarding_service_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PerfStandbyInvalidation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PerfStandbyInvalidation) ProtoMessage() {}
+
+func (x *PerfStandbyInvalidation) ProtoReflect() protoreflect.Message {
+	mi := &file_vault_request_forwarding_service_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PerfStandbyInvalidation.ProtoReflect.Descriptor instead.
+func (*PerfStandbyInvalidation) Descriptor() ([]byte, []int) {
+	return file_vault_request_forwarding_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PerfStandbyInvalidation) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
 var File_vault_request_forwarding_service_proto protoreflect.FileDescriptor
 
 var file_vault_request_forwarding_service_proto_rawDesc = []byte{
@@ -554,7 +641,12 @@ var file_vault_request_forwarding_service_proto_rawDesc = []byte{
 	0x72, 0x74, 0x12, 0x2f, 0x0a, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x79,
 	0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2e, 0x43,
 	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x4b, 0x65, 0x79, 0x52, 0x09, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74,
-	0x4b, 0x65, 0x79, 0x32, 0xf0, 0x01, 0x0a, 0x11, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x46,
+	0x4b, 0x65, 0x79, 0x22, 0x1f, 0x0a, 0x1d, 0x50, 0x65, 0x72, 0x66, 0x53, 0x74, 0x61, 0x6e, 0x64,
+	0x62, 0x79, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x22, 0x2d, 0x0a, 0x17, 0x50, 0x65, 0x72, 0x66, 0x53, 0x74, 0x61, 0x6e,
+	0x64, 0x62, 0x79, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x6b,
+	0x65, 0x79, 0x73, 0x32, 0xd6, 0x02, 0x0a, 0x11, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x46,
 	0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x3d, 0x0a, 0x0e, 0x46, 0x6f, 0x72,
 	0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x13, 0x2e, 0x66, 0x6f,
 	0x72, 0x77, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
@@ -569,10 +661,16 @@ var file_vault_request_forwarding_service_proto_rawDesc = []byte{
 	0x79, 0x45, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x1a, 0x22,
 	0x2e, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2e, 0x50, 0x65, 0x72, 0x66, 0x53, 0x74, 0x61, 0x6e, 0x64,
 	0x62, 0x79, 0x45, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x22, 0x5a, 0x20, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x68, 0x61, 0x73, 0x68, 0x69, 0x63, 0x6f, 0x72, 0x70, 0x2f, 0x76,
-	0x61, 0x75, 0x6c, 0x74, 0x2f, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x64, 0x0a, 0x18, 0x50, 0x65, 0x72, 0x66, 0x53, 0x74,
+	0x61, 0x6e, 0x64, 0x62, 0x79, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x12, 0x24, 0x2e, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2e, 0x50, 0x65, 0x72, 0x66, 0x53,
+	0x74, 0x61, 0x6e, 0x64, 0x62, 0x79, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x1a, 0x1e, 0x2e, 0x76, 0x61, 0x75, 0x6c, 0x74,
+	0x2e, 0x50, 0x65, 0x72, 0x66, 0x53, 0x74, 0x61, 0x6e, 0x64, 0x62, 0x79, 0x49, 0x6e, 0x76, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x00, 0x30, 0x01, 0x42, 0x22, 0x5a, 0x20,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x68, 0x61, 0x73, 0x68, 0x69,
+	0x63, 0x6f, 0x72, 0x70, 0x2f, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2f, 0x76, 0x61, 0x75, 0x6c, 0x74,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -587,29 +685,33 @@ func file_vault_request_forwarding_service_proto_rawDescGZIP() []byte {
 	return file_vault_request_forwarding_service_proto_rawDescData
 }
 
-var file_vault_request_forwarding_service_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_vault_request_forwarding_service_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_vault_request_forwarding_service_proto_goTypes = []interface{}{
-	(*EchoRequest)(nil),                 // 0: vault.EchoRequest
-	(*EchoReply)(nil),                   // 1: vault.EchoReply
-	(*NodeInformation)(nil),             // 2: vault.NodeInformation
-	(*ClientKey)(nil),                   // 3: vault.ClientKey
-	(*PerfStandbyElectionInput)(nil),    // 4: vault.PerfStandbyElectionInput
-	(*PerfStandbyElectionResponse)(nil), // 5: vault.PerfStandbyElectionResponse
-	(*forwarding.Request)(nil),          // 6: forwarding.Request
-	(*forwarding.Response)(nil),         // 7: forwarding.Response
+	(*EchoRequest)(nil),                   // 0: vault.EchoRequest
+	(*EchoReply)(nil),                     // 1: vault.EchoReply
+	(*NodeInformation)(nil),               // 2: vault.NodeInformation
+	(*ClientKey)(nil),                     // 3: vault.ClientKey
+	(*PerfStandbyElectionInput)(nil),      // 4: vault.PerfStandbyElectionInput
+	(*PerfStandbyElectionResponse)(nil),   // 5: vault.PerfStandbyElectionResponse
+	(*PerfStandbyInvalidationsInput)(nil), // 6: vault.PerfStandbyInvalidationsInput
+	(*PerfStandbyInvalidation)(nil),       // 7: vault.PerfStandbyInvalidation
+	(*forwarding.Request)(nil),            // 8: forwarding.Request
+	(*forwarding.Response)(nil),           // 9: forwarding.Response
 }
 var file_vault_request_forwarding_service_proto_depIDxs = []int32{
 	2, // 0: vault.EchoRequest.node_info:type_name -> vault.NodeInformation
 	2, // 1: vault.EchoReply.node_info:type_name -> vault.NodeInformation
 	3, // 2: vault.PerfStandbyElectionResponse.client_key:type_name -> vault.ClientKey
-	6, // 3: vault.RequestForwarding.ForwardRequest:input_type -> forwarding.Request
+	8, // 3: vault.RequestForwarding.ForwardRequest:input_type -> forwarding.Request
 	0, // 4: vault.RequestForwarding.Echo:input_type -> vault.EchoRequest
 	4, // 5: vault.RequestForwarding.PerformanceStandbyElectionRequest:input_type -> vault.PerfStandbyElectionInput
-	7, // 6: vault.RequestForwarding.ForwardRequest:output_type -> forwarding.Response
-	1, // 7: vault.RequestForwarding.Echo:output_type -> vault.EchoReply
-	5, // 8: vault.RequestForwarding.PerformanceStandbyElectionRequest:output_type -> vault.PerfStandbyElectionResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
+	6, // 6: vault.RequestForwarding.PerfStandbyInvalidations:input_type -> vault.PerfStandbyInvalidationsInput
+	9, // 7: vault.RequestForwarding.ForwardRequest:output_type -> forwarding.Response
+	1, // 8: vault.RequestForwarding.Echo:output_type -> vault.EchoReply
+	5, // 9: vault.RequestForwarding.PerformanceStandbyElectionRequest:output_type -> vault.PerfStandbyElectionResponse
+	7, // 10: vault.RequestForwarding.PerfStandbyInvalidations:output_type -> vault.PerfStandbyInvalidation
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
 	3, // [3:3] is the sub-list for extension type_name
 	3, // [3:3] is the sub-list for extension extendee
 	0, // [0:3] is the sub-list for field type_name
@@ -693,6 +795,30 @@ func file_vault_request_forwarding_service_proto_init() {
 				return nil
 			}
 		}
+		file_vault_request_forwarding_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PerfStandbyInvalidationsInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vault_request_forwarding_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PerfStandbyInvalidation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -700,7 +826,7 @@ func file_vault_request_forwarding_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_vault_request_forwarding_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -729,6 +855,7 @@ type RequestForwardingClient interface {
 	ForwardRequest(ctx context.Context, in *forwarding.Request, opts ...grpc.CallOption) (*forwarding.Response, error)
 	Echo(ctx context.Context, in *EchoRequest, opts ...grpc.CallOption) (*EchoReply, error)
 	PerformanceStandbyElectionRequest(ctx context.Context, in *PerfStandbyElectionInput, opts ...grpc.CallOption) (RequestForwarding_PerformanceStandbyElectionRequestClient, error)
+	PerfStandbyInvalidations(ctx context.Context, in *PerfStandbyInvalidationsInput, opts ...grpc.CallOption) (RequestForwarding_PerfStandbyInvalidationsClient, error)
 }
 
 type requestForwardingClient struct {
@@ -789,11 +916,44 @@ func (x *requestForwardingPerformanceStandbyElectionRequestClient) Recv() (*Perf
 	return m, nil
 }
 
+func (c *requestForwardingClient) PerfStandbyInvalidations(ctx context.Context, in *PerfStandbyInvalidationsInput, opts ...grpc.CallOption) (RequestForwarding_PerfStandbyInvalidationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RequestForwarding_serviceDesc.Streams[1], "/vault.RequestForwarding/PerfStandbyInvalidations", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &requestForwardingPerfStandbyInvalidationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RequestForwarding_PerfStandbyInvalidationsClient interface {
+	Recv() (*PerfStandbyInvalidation, error)
+	grpc.ClientStream
+}
+
+type requestForwardingPerfStandbyInvalidationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *requestForwardingPerfStandbyInvalidationsClient) Recv() (*PerfStandbyInvalidation, error) {
+	m := new(PerfStandbyInvalidation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RequestForwardingServer is the server API for RequestForwarding service.
 type RequestForwardingServer interface {
 	ForwardRequest(context.Context, *forwarding.Request) (*forwarding.Response, error)
 	Echo(context.Context, *EchoRequest) (*EchoReply, error)
 	PerformanceStandbyElectionRequest(*PerfStandbyElectionInput, RequestForwarding_PerformanceStandbyElectionRequestServer) error
+	PerfStandbyInvalidations(*PerfStandbyInvalidationsInput, RequestForwarding_PerfStandbyInvalidationsServer) error
 }
 
 // UnimplementedRequestForwardingServer can be embedded to have forward compatible implementations.
@@ -809,6 +969,9 @@ func (*UnimplementedRequestForwardingServer) Echo(context.Context, *EchoRequest)
 func (*UnimplementedRequestForwardingServer) PerformanceStandbyElectionRequest(*PerfStandbyElectionInput, RequestForwarding_PerformanceStandbyElectionRequestServer) error {
 	return status.Errorf(codes.Unimplemented, "method PerformanceStandbyElectionRequest not implemented")
 }
+func (*UnimplementedRequestForwardingServer) PerfStandbyInvalidations(*PerfStandbyInvalidationsInput, RequestForwarding_PerfStandbyInvalidationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method PerfStandbyInvalidations not implemented")
+}
 
 func RegisterRequestForwardingServer(s *grpc.Server, srv RequestForwardingServer) {
 	s.RegisterService(&_RequestForwarding_serviceDesc, srv)
@@ -871,6 +1034,27 @@ func (x *requestForwardingPerformanceStandbyElectionRequestServer) Send(m *PerfS
 	return x.ServerStream.SendMsg(m)
 }
 
+func _RequestForwarding_PerfStandbyInvalidations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PerfStandbyInvalidationsInput)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RequestForwardingServer).PerfStandbyInvalidations(m, &requestForwardingPerfStandbyInvalidationsServer{stream})
+}
+
+type RequestForwarding_PerfStandbyInvalidationsServer interface {
+	Send(*PerfStandbyInvalidation) error
+	grpc.ServerStream
+}
+
+type requestForwardingPerfStandbyInvalidationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *requestForwardingPerfStandbyInvalidationsServer) Send(m *PerfStandbyInvalidation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _RequestForwarding_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "vault.RequestForwarding",
 	HandlerType: (*RequestForwardingServer)(nil),
@@ -890,6 +1074,11 @@ var _RequestForwarding_serviceDesc = grpc.ServiceDesc{
 			Handler:       _RequestForwarding_PerformanceStandbyElectionRequest_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "PerfStandbyInvalidations",
+			Handler:       _RequestForwarding_PerfStandbyInvalidations_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "vault/request_forwarding_service.proto",
 }
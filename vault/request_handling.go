@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -39,6 +40,11 @@ var (
 	DefaultMaxRequestDuration = 90 * time.Second
 
 	egpDebugLogging bool
+
+	// errBoundCIDRsNotAllowed and errBoundCIDRsUnparsableAddr accompany the
+	// permission denied errors of the tokens failing their bound CIDRs check
+	errBoundCIDRsNotAllowed     = errors.New("remote address is not in the bound CIDRs of the token")
+	errBoundCIDRsUnparsableAddr = errors.New("remote address could not be checked against the bound CIDRs of the token")
 )
 
 // HandlerProperties is used to seed configuration into a vaulthttp.Handler.
@@ -49,6 +55,23 @@ type HandlerProperties struct {
 	DisablePrintableCheck bool
 	RecoveryMode          bool
 	RecoveryToken         *uberAtomic.String
+
+	// CustomResponseHeaders are the headers added to the responses of the
+	// listener, keyed by status code, status class ("4xx") or "default"
+	CustomResponseHeaders map[string]http.Header
+
+	// DisableHealthDetails and DisableSealStatusDetails strip the version,
+	// cluster and replication details from the sys/health and
+	// sys/seal-status responses to the requests without a valid token
+	DisableHealthDetails     bool
+	DisableSealStatusDetails bool
+
+	// DisableUI stops serving the web UI on the listener
+	DisableUI bool
+
+	// UnauthenticatedInFlightRequests allows reading sys/in-flight-req
+	// without a token
+	UnauthenticatedInFlightRequests bool
 }
 
 // fetchEntityAndDerivedPolicies returns the entity object for the given entity
@@ -138,6 +161,9 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 	case nil:
 		var err error
 		te, err = c.tokenStore.Lookup(ctx, req.ClientToken)
+		if err == logical.ErrPerfStandbyPleaseForward {
+			return nil, nil, nil, nil, err
+		}
 		if err != nil {
 			c.logger.Error("failed to lookup token", "error", err)
 			return nil, nil, nil, nil, ErrInternalError
@@ -153,7 +179,8 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		return nil, nil, nil, nil, logical.ErrPermissionDenied
 	}
 
-	// CIDR checks bind all tokens except non-expiring root tokens
+	// CIDR checks bind all tokens except non-expiring root tokens. The error
+	// tells which constraint failed so that it shows up in the audit log.
 	if te.TTL != 0 && len(te.BoundCIDRs) > 0 {
 		var valid bool
 		remoteSockAddr, err := sockaddr.NewSockAddr(req.Connection.RemoteAddr)
@@ -161,7 +188,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 			if c.Logger().IsDebug() {
 				c.Logger().Debug("could not parse remote addr into sockaddr", "error", err, "remote_addr", req.Connection.RemoteAddr)
 			}
-			return nil, nil, nil, nil, logical.ErrPermissionDenied
+			return nil, nil, nil, nil, multierror.Append(logical.ErrPermissionDenied, errBoundCIDRsUnparsableAddr)
 		}
 		for _, cidr := range te.BoundCIDRs {
 			if cidr.Contains(remoteSockAddr) {
@@ -170,7 +197,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 			}
 		}
 		if !valid {
-			return nil, nil, nil, nil, logical.ErrPermissionDenied
+			return nil, nil, nil, nil, multierror.Append(logical.ErrPermissionDenied, errBoundCIDRsNotAllowed)
 		}
 	}
 
@@ -359,6 +386,13 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		RootPrivsRequired: rootPath,
 	})
 
+	auth.PolicyResults = &logical.PolicyResults{
+		Allowed: authResults.Allowed,
+	}
+	if authResults.ACLResults != nil && authResults.Allowed {
+		auth.PolicyResults.GrantingPolicies = authResults.ACLResults.GrantingPolicies
+	}
+
 	if !authResults.Allowed {
 		retErr := authResults.Error
 
@@ -432,6 +466,8 @@ func (c *Core) switchedLockHandleRequest(httpCtx context.Context, req *logical.R
 }
 
 func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namespace, req *logical.Request) (resp *logical.Response, err error) {
+	start := time.Now()
+
 	// Allowing writing to a path ending in / makes it extremely difficult to
 	// understand user intent for the filesystem-like backends (kv,
 	// cubbyhole) -- did they want a key named foo/ or did they want to write
@@ -453,6 +489,18 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 		return nil, logical.CodedError(403, "namespaces feature not enabled")
 	}
 
+	// Outside of the root namespace, the system backend only serves the
+	// paths scoped to a namespace
+	if ns.ID != namespace.RootNamespaceID && strings.HasPrefix(req.Path, "sys/") && !NamespaceSystemPath(req.Path) {
+		return nil, logical.ErrUnsupportedPath
+	}
+
+	// A performance standby only serves the requests which don't write to
+	// the storage
+	if c.perfStandby && !c.perfStandbyCanServe(ctx, req) {
+		return nil, logical.ErrPerfStandbyPleaseForward
+	}
+
 	var auth *logical.Auth
 	if c.router.LoginPath(ctx, req.Path) {
 		resp, auth, err = c.handleLoginRequest(ctx, req)
@@ -540,6 +588,8 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 		}
 	}
 
+	c.emitRequestMetrics(ns, entry, start, resp, err)
+
 	// Create an audit trail of the response
 	if !isControlGroupRun(req) {
 		switch req.Path {
@@ -552,6 +602,7 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 				OuterErr:            err,
 				NonHMACReqDataKeys:  nonHMACReqDataKeys,
 				NonHMACRespDataKeys: nonHMACRespDataKeys,
+				Duration:            time.Since(start),
 			}
 			if auditErr := c.auditBroker.LogResponse(ctx, logInput, c.auditedHeaders); auditErr != nil {
 				c.logger.Error("failed to audit response", "request_path", req.Path, "error", auditErr)
@@ -568,12 +619,24 @@ func isControlGroupRun(req *logical.Request) bool {
 }
 
 func (c *Core) doRouting(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if resp, err := c.checkKVDestroyConfirmation(ctx, req); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// If we're replicating and we get a read-only error from a backend, need to forward to primary
 	resp, err := c.router.Route(ctx, req)
+	if req.Operation == logical.PatchOperation && err == logical.ErrUnsupportedOperation {
+		resp, err = c.routeKVPatch(ctx, req)
+	}
 	if shouldForward(c, resp, err) {
 		return forward(ctx, c, req)
 	}
 	atomic.AddUint64(c.counters.requests, 1)
+	if c.activityLog != nil {
+		if ns, err := namespace.FromContext(ctx); err == nil {
+			c.activityLog.AddRequest(ns.ID, time.Now())
+		}
+	}
 	return resp, err
 }
 
@@ -604,6 +667,11 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		return nil, nil, ctErr
 	}
 
+	// Using a limited use token writes to the storage
+	if c.perfStandby && te != nil && te.NumUses != 0 && !isControlGroupRun(req) {
+		return nil, nil, logical.ErrPerfStandbyPleaseForward
+	}
+
 	// We run this logic first because we want to decrement the use count even
 	// in the case of an error (assuming we can successfully look up; if we
 	// need to forward, we exit before now)
@@ -697,6 +765,11 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
+	// Count the client behind the token for usage metering
+	if c.activityLog != nil {
+		c.activityLog.HandleTokenUsage(te, time.Now())
+	}
+
 	// Create an audit trail of the request
 	if !isControlGroupRun(req) {
 		logInput := &logical.LogInput{
@@ -1047,8 +1120,27 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		return nil, nil, ErrInternalError
 	}
 
+	// The logins of the users locked out after repeated failed logins are
+	// rejected before their credentials are checked
+	lockoutAlias := c.userLockoutAlias(ctx, entry, req)
+	if lockoutAlias != "" {
+		lockedOut, err := c.isUserLockedOut(ctx, entry, lockoutAlias)
+		if err != nil {
+			c.logger.Error("failed to check the user lockout", "request_path", req.Path, "error", err)
+			return nil, nil, ErrInternalError
+		}
+		if lockedOut {
+			return nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
+	if lockoutAlias != "" {
+		if err := c.updateUserLockout(ctx, entry, lockoutAlias, resp, routeErr); err != nil {
+			c.logger.Error("failed to update the user lockout", "request_path", req.Path, "error", err)
+		}
+	}
 	if resp != nil {
 		// If wrapping is used, use the shortest between the request and response
 		var wrapTTL time.Duration
@@ -1096,6 +1188,12 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		return nil, nil, ErrInternalError
 	}
 
+	// The logins completed on sys/mfa/validate already have their token
+	if req.Path == mfaValidatePath && resp != nil && resp.Auth != nil {
+		req.DisplayName = resp.Auth.DisplayName
+		return resp, resp.Auth, routeErr
+	}
+
 	// If the response generated an authentication, then generate the token
 	if resp != nil && resp.Auth != nil {
 		ns, err := namespace.FromContext(ctx)
@@ -1144,6 +1242,14 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 
 		mEntry := c.router.MatchingMountEntry(ctx, req.Path)
 
+		// The auth mount may only allow some of the aliases of its auth
+		// method to log in
+		if mEntry != nil && len(mEntry.Config.AllowedEntityAliases) > 0 {
+			if auth.Alias == nil || !strutil.StrListContainsGlob(mEntry.Config.AllowedEntityAliases, auth.Alias.Name) {
+				return nil, nil, logical.ErrPermissionDenied
+			}
+		}
+
 		if auth.Alias != nil &&
 			mEntry != nil &&
 			!mEntry.Local &&
@@ -1177,6 +1283,9 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			}
 
 			auth.EntityID = entity.ID
+			if err := c.identityStore.syncExternalGroups(ctx, auth.GroupAliases); err != nil {
+				return nil, nil, err
+			}
 			validAliases, err := c.identityStore.refreshExternalGroupMembershipsByEntityID(ctx, auth.EntityID, auth.GroupAliases)
 			if err != nil {
 				return nil, nil, err
@@ -1206,6 +1315,22 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			resp.AddWarning(warning)
 		}
 
+		// Raise the TTL of non-periodic tokens to the floor of the auth mount,
+		// without going over their max TTL
+		if mEntry != nil && mEntry.Config.MinLeaseTTL > tokenTTL && auth.Period == 0 {
+			minTTL := mEntry.Config.MinLeaseTTL
+			if maxTTL := sysView.MaxLeaseTTL(); minTTL > maxTTL {
+				minTTL = maxTTL
+			}
+			if auth.ExplicitMaxTTL > 0 && minTTL > auth.ExplicitMaxTTL {
+				minTTL = auth.ExplicitMaxTTL
+			}
+			if minTTL > tokenTTL {
+				resp.AddWarning(fmt.Sprintf("TTL of %q is below the min_lease_ttl of the auth mount; TTL value is raised to %q", tokenTTL, minTTL))
+				tokenTTL = minTTL
+			}
+		}
+
 		_, identityPolicies, err := c.fetchEntityAndDerivedPolicies(ctx, ns, auth.EntityID)
 		if err != nil {
 			return nil, nil, ErrInternalError
@@ -1226,59 +1351,90 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			}
 		}
 
-		var registerFunc RegisterAuthFunc
-		var funcGetErr error
-		// Batch tokens should not be forwarded to perf standby
-		if auth.TokenType == logical.TokenTypeBatch {
-			registerFunc = c.RegisterAuth
-		} else {
-			registerFunc, funcGetErr = getAuthRegisterFunc(c)
-		}
-		if funcGetErr != nil {
-			retErr = multierror.Append(retErr, funcGetErr)
-			return nil, auth, retErr
+		login := &authenticatedLogin{
+			auth:             auth,
+			path:             req.Path,
+			mountType:        req.MountType,
+			mountPoint:       req.MountPoint,
+			tokenTTL:         tokenTTL,
+			identityPolicies: identityPolicies,
+			allPolicies:      allPolicies,
 		}
 
-		err = registerFunc(ctx, tokenTTL, req.Path, auth)
-		switch {
-		case err == nil:
-			if auth.TokenType != logical.TokenTypeBatch {
-				leaseGenerated = true
-			}
-		case err == ErrInternalError:
-			return nil, auth, err
-		default:
-			return logical.ErrorResponse(err.Error()), auth, logical.ErrInvalidRequest
+		// Logins subject to MFA get their token once their MFA requirement
+		// is validated, either with the MFA credentials of the request or on
+		// sys/mfa/validate
+		mfaResp, err := c.enforceLoginMFA(ctx, req, entity, login)
+		if err != nil || mfaResp != nil {
+			return mfaResp, nil, err
 		}
 
-		auth.IdentityPolicies = policyutil.SanitizePolicies(identityPolicies[ns.ID], policyutil.DoNotAddDefaultPolicy)
-		delete(identityPolicies, ns.ID)
-		auth.ExternalNamespacePolicies = identityPolicies
-		auth.Policies = allPolicies
+		errResp, err := c.registerLogin(ctx, ns, login)
+		if err != nil {
+			return errResp, auth, err
+		}
+		if auth.TokenType != logical.TokenTypeBatch {
+			leaseGenerated = true
+		}
 
 		// Attach the display name, might be used by audit backends
 		req.DisplayName = auth.DisplayName
-
-		// Count the successful token creation
-		ttl_label := metricsutil.TTLBucket(tokenTTL)
-		// Do not include namespace path in mount point; already present as separate label.
-		mountPointWithoutNs := ns.TrimmedPath(req.MountPoint)
-		c.metricSink.IncrCounterWithLabels(
-			[]string{"token", "creation"},
-			1,
-			[]metrics.Label{
-				vaultmetrics.NamespaceLabel(ns),
-				{"auth_method", req.MountType},
-				{"mount_point", mountPointWithoutNs},
-				{"creation_ttl", ttl_label},
-				{"token_type", auth.TokenType.String()},
-			},
-		)
 	}
 
 	return resp, auth, routeErr
 }
 
+// registerLogin creates the token of an authenticated login. The returned
+// response holds the error of a login that can't get a token.
+func (c *Core) registerLogin(ctx context.Context, ns *namespace.Namespace, login *authenticatedLogin) (*logical.Response, error) {
+	auth := login.auth
+
+	var registerFunc RegisterAuthFunc
+	var funcGetErr error
+	// Batch tokens should not be forwarded to perf standby
+	if auth.TokenType == logical.TokenTypeBatch {
+		registerFunc = c.RegisterAuth
+	} else {
+		registerFunc, funcGetErr = getAuthRegisterFunc(c)
+	}
+	if funcGetErr != nil {
+		return nil, funcGetErr
+	}
+
+	err := registerFunc(ctx, login.tokenTTL, login.path, auth)
+	switch {
+	case err == nil:
+	case err == ErrInternalError:
+		return nil, err
+	default:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	identityPolicies := login.identityPolicies
+	auth.IdentityPolicies = policyutil.SanitizePolicies(identityPolicies[ns.ID], policyutil.DoNotAddDefaultPolicy)
+	delete(identityPolicies, ns.ID)
+	auth.ExternalNamespacePolicies = identityPolicies
+	auth.Policies = login.allPolicies
+
+	// Count the successful token creation
+	ttl_label := metricsutil.TTLBucket(login.tokenTTL)
+	// Do not include namespace path in mount point; already present as separate label.
+	mountPointWithoutNs := ns.TrimmedPath(login.mountPoint)
+	c.metricSink.IncrCounterWithLabels(
+		[]string{"token", "creation"},
+		1,
+		[]metrics.Label{
+			vaultmetrics.NamespaceLabel(ns),
+			{"auth_method", login.mountType},
+			{"mount_point", mountPointWithoutNs},
+			{"creation_ttl", ttl_label},
+			{"token_type", auth.TokenType.String()},
+		},
+	)
+
+	return nil, nil
+}
+
 // RegisterAuth uses a logical.Auth object to create a token entry in the token
 // store, and registers a corresponding token lease to the expiration manager.
 func (c *Core) RegisterAuth(ctx context.Context, tokenTTL time.Duration, path string, auth *logical.Auth) error {
@@ -1,14 +1,18 @@
 package vault
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -326,3 +330,124 @@ func TestRequestHandling_SecretLeaseMetric(t *testing.T) {
 		},
 	)
 }
+
+func TestRequestHandling_BoundCIDRsAudit(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	var noop *NoopAudit
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		noop = &NoopAudit{
+			Config: config,
+		}
+		return noop, nil
+	}
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	boundCIDRs, err := parseutil.ParseAddrs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	te := &logical.TokenEntry{
+		Path:       "auth/token/create",
+		Policies:   []string{"default"},
+		TTL:        time.Hour,
+		BoundCIDRs: boundCIDRs,
+	}
+	testMakeTokenDirectly(t, c.tokenStore, te)
+
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "auth/token/lookup-self",
+		ClientToken: te.ID,
+		Connection:  &logical.Connection{RemoteAddr: "127.0.0.1"},
+	}
+	_, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	auditErr := noop.ReqErrs[len(noop.ReqErrs)-1]
+	if !errwrap.Contains(auditErr, errBoundCIDRsNotAllowed.Error()) {
+		t.Fatalf("expected the audited error to name the bound CIDRs, got: %v", auditErr)
+	}
+
+	req.Connection.RemoteAddr = "10.1.2.3"
+	if _, err := c.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestRequestHandling_LoginMountConstraints(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	mustRequest := func(path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := core.HandleRequest(ctx, &logical.Request{
+			Path:        path,
+			ClientToken: root,
+			Operation:   logical.UpdateOperation,
+			Data:        data,
+			Connection:  &logical.Connection{},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("path %q: err: %v, resp: %#v", path, err, resp)
+		}
+	}
+	login := func(username string) (*logical.Response, error) {
+		t.Helper()
+		return core.HandleRequest(ctx, &logical.Request{
+			Path:       "auth/userpass/login/" + username,
+			Operation:  logical.UpdateOperation,
+			Data:       map[string]interface{}{"password": "foo"},
+			Connection: &logical.Connection{},
+		})
+	}
+
+	mustRequest("sys/auth/userpass", map[string]interface{}{"type": "userpass"})
+	for _, username := range []string{"dev-alice", "ops-bob"} {
+		mustRequest("auth/userpass/users/"+username, map[string]interface{}{
+			"password":  "foo",
+			"token_ttl": "10m",
+		})
+	}
+	mustRequest("sys/auth/userpass/tune", map[string]interface{}{
+		"min_lease_ttl":          "1h",
+		"allowed_entity_aliases": "dev-*",
+	})
+
+	// The TTL of the token is raised to the floor of the mount
+	resp, err := login("dev-alice")
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Auth.TTL != time.Hour {
+		t.Fatalf("bad: TTL %s", resp.Auth.TTL)
+	}
+
+	// The aliases that don't match the mount patterns can't log in
+	_, err = login("ops-bob")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// The floor can't go over the max TTL of the mount
+	resp, err = core.HandleRequest(ctx, &logical.Request{
+		Path:        "sys/auth/userpass/tune",
+		ClientToken: root,
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"max_lease_ttl": "2h",
+			"min_lease_ttl": "3h",
+		},
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
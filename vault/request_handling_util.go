@@ -5,18 +5,38 @@ package vault
 import (
 	"context"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 func waitForReplicationState(context.Context, *Core, *logical.Request) error { return nil }
 
-func checkNeedsCG(context.Context, *Core, *logical.Request, *logical.Auth, error, []string) (error, *logical.Response, *logical.Auth, error) {
-	return nil, nil, nil, nil
+func checkNeedsCG(ctx context.Context, c *Core, req *logical.Request, auth *logical.Auth, err error, nonHMACReqDataKeys []string) (error, *logical.Response, *logical.Auth, error) {
+	cgErr, ok := errwrap.GetType(err, &controlGroupRequiredError{}).(*controlGroupRequiredError)
+	if !ok {
+		return nil, nil, nil, nil
+	}
+
+	resp, err := c.createControlGroupRequest(ctx, req, auth, cgErr.controlGroup)
+	if err != nil {
+		return err, nil, nil, nil
+	}
+
+	logInput := &logical.LogInput{
+		Auth:               auth,
+		Request:            req,
+		NonHMACReqDataKeys: nonHMACReqDataKeys,
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit request", "path", req.Path, "error", err)
+		return nil, nil, auth, ErrInternalError
+	}
+	return nil, resp, auth, nil
 }
 
 func checkErrControlGroupTokenNeedsCreated(err error) bool {
-	return false
+	return errwrap.ContainsType(err, &controlGroupRequiredError{})
 }
 
 func shouldForward(c *Core, resp *logical.Response, err error) bool {
@@ -35,10 +55,16 @@ func forward(ctx context.Context, c *Core, req *logical.Request) (*logical.Respo
 }
 
 func getLeaseRegisterFunc(c *Core) (func(context.Context, *logical.Request, *logical.Response) (string, error), error) {
+	if c.perfStandby {
+		return nil, logical.ErrPerfStandbyPleaseForward
+	}
 	return c.expiration.Register, nil
 }
 
 func getAuthRegisterFunc(c *Core) (RegisterAuthFunc, error) {
+	if c.perfStandby {
+		return nil, logical.ErrPerfStandbyPleaseForward
+	}
 	return c.RegisterAuth, nil
 }
 
@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	vaultmetrics "github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// otherLabelValue replaces the mount labels of requests once the number of
+// distinct label sets reaches the cardinality limit.
+const otherLabelValue = "other"
+
+// requestMetricLabels keeps track of the mount label sets that have been used
+// for request metrics, so that their cardinality stays within the limit
+// configured for the metric sink.
+type requestMetricLabels struct {
+	l    sync.Mutex
+	seen map[string]struct{}
+}
+
+// labels returns the namespace, mount point and mount type labels for a
+// request to the given mount. Mounts first seen after max label sets are in
+// use are reported as "other"; a max of zero means no limit.
+func (r *requestMetricLabels) labels(ns *namespace.Namespace, entry *MountEntry, max int) []metrics.Label {
+	mountPoint, mountType := otherLabelValue, otherLabelValue
+	if entry != nil {
+		if entryNS := entry.Namespace(); entryNS != nil {
+			ns = entryNS
+		}
+		mountPoint = ns.TrimmedPath(entry.APIPath())
+		mountType = entry.Type
+	}
+	labels := []metrics.Label{
+		vaultmetrics.NamespaceLabel(ns),
+		{"mount_point", mountPoint},
+		{"mount_type", mountType},
+	}
+	if entry == nil || max <= 0 {
+		return labels
+	}
+
+	key := ns.ID + "/" + entry.Accessor
+
+	r.l.Lock()
+	defer r.l.Unlock()
+	if _, ok := r.seen[key]; !ok {
+		if len(r.seen) >= max {
+			labels[1].Value = otherLabelValue
+			labels[2].Value = otherLabelValue
+			return labels
+		}
+		if r.seen == nil {
+			r.seen = make(map[string]struct{})
+		}
+		r.seen[key] = struct{}{}
+	}
+	return labels
+}
+
+// emitRequestMetrics records the duration of a request and, if it failed,
+// an error, labeled with the namespace and mount the request was made to.
+func (c *Core) emitRequestMetrics(ns *namespace.Namespace, entry *MountEntry, start time.Time, resp *logical.Response, err error) {
+	labels := c.requestMetricLabels.labels(ns, entry, c.metricSink.MaxGaugeCardinality)
+	c.metricSink.MeasureSinceWithLabels([]string{"core", "request"}, start, labels)
+	if err != nil || resp.IsError() {
+		c.metricSink.IncrCounterWithLabels([]string{"core", "request", "error"}, 1, labels)
+	}
+}
+
+// EmitRequestSizeMetrics records the sizes of the payloads of an HTTP request
+// and its response, labeled with the namespace and mount the request was made
+// to. The path is relative to the namespace of the context.
+func (c *Core) EmitRequestSizeMetrics(ctx context.Context, path string, requestSize, responseSize int64) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return
+	}
+	entry := c.router.MatchingMountEntry(ctx, path)
+	labels := c.requestMetricLabels.labels(ns, entry, c.metricSink.MaxGaugeCardinality)
+	c.metricSink.AddSampleWithLabels([]string{"core", "request_size"}, float32(requestSize), labels)
+	c.metricSink.AddSampleWithLabels([]string{"core", "response_size"}, float32(responseSize), labels)
+}
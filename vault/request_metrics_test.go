@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestRequestMetrics_labels(t *testing.T) {
+	var r requestMetricLabels
+	ns1 := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+
+	kv := &MountEntry{Table: mountTableType, Path: "secret/", Type: "kv", Accessor: "kv_1", namespace: ns1}
+	pki := &MountEntry{Table: mountTableType, Path: "pki/", Type: "pki", Accessor: "pki_1", namespace: namespace.RootNamespace}
+	userpass := &MountEntry{Table: credentialTableType, Path: "userpass/", Type: "userpass", Accessor: "userpass_1", namespace: namespace.RootNamespace}
+
+	expect := func(entry *MountEntry, namespace, mountPoint, mountType string) {
+		t.Helper()
+		labels := r.labels(ns1, entry, 2)
+		if labels[0].Value != namespace || labels[1].Value != mountPoint || labels[2].Value != mountType {
+			t.Fatalf("bad labels: %v", labels)
+		}
+	}
+
+	expect(kv, "ns1", "secret/", "kv")
+	expect(userpass, "root", "auth/userpass/", "userpass")
+
+	// New mounts over the limit are folded into a single label set, but
+	// mounts that were already seen keep theirs
+	expect(pki, "root", "other", "other")
+	expect(kv, "ns1", "secret/", "kv")
+	expect(nil, "ns1", "other", "other")
+}
+
+func TestRequestHandling_RequestMetrics(t *testing.T) {
+	core, _, root, sink := TestCoreUnsealedWithMetrics(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = root
+	if _, err := core.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests to paths that don't exist are counted as errors
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/does-not-exist")
+	req.ClientToken = root
+	if _, err := core.HandleRequest(namespace.RootContext(nil), req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	intervals := sink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	var found bool
+	for _, s := range intervals[0].Samples {
+		if s.Name != "core.request" {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+		if labelsMatch(labels, map[string]string{
+			"cluster":     "test-cluster",
+			"namespace":   "root",
+			"mount_point": "secret/",
+			"mount_type":  "kv",
+		}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("no core.request sample found for the kv mount")
+	}
+
+	checkCounter(t, sink, "core.request.error",
+		map[string]string{
+			"cluster":     "test-cluster",
+			"namespace":   "root",
+			"mount_point": "sys/",
+			"mount_type":  "system",
+		},
+	)
+}
@@ -458,6 +458,28 @@ func (r *Router) MatchingAPIPrefixByStoragePath(ctx context.Context, path string
 	return me.Namespace(), mountPath, prefix, found
 }
 
+// Invalidate calls InvalidateKey on the backend whose storage holds the
+// given storage key, with the key relative to the storage of the backend
+func (r *Router) Invalidate(ctx context.Context, key string) {
+	r.l.RLock()
+	_, raw, ok := r.storagePrefix.LongestPrefix(key)
+	r.l.RUnlock()
+	if !ok {
+		return
+	}
+
+	re := raw.(*routeEntry)
+	re.l.RLock()
+	backend := re.backend
+	re.l.RUnlock()
+	if backend == nil {
+		return
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, re.mountEntry.Namespace())
+	backend.InvalidateKey(ctx, strings.TrimPrefix(key, re.storagePrefix))
+}
+
 func (r *Router) matchingMountEntryByPath(ctx context.Context, path string, apiPath bool) (*MountEntry, string, bool) {
 	var raw interface{}
 	var ok bool
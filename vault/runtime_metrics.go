@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"io/ioutil"
+	"runtime"
+	"time"
+)
+
+// DefaultRuntimeMetricsInterval is the interval at which runtime metrics are
+// collected when it isn't configured.
+const DefaultRuntimeMetricsInterval = 10 * time.Second
+
+// runtimeMetricsLoop publishes Go runtime and process statistics through the
+// cluster metric sink, so that they carry the cluster label, until stopCh is
+// closed. These complement the runtime metrics of go-metrics, which are
+// emitted without labels.
+func (c *Core) runtimeMetricsLoop(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Only pauses from now on are sampled
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	lastNumGC := stats.NumGC
+
+	for {
+		select {
+		case <-ticker.C:
+			lastNumGC = c.emitRuntimeMetrics(lastNumGC)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// emitRuntimeMetrics publishes a single set of runtime statistics. Pauses of
+// the collections that completed since lastNumGC are added as samples; the
+// current number of collections is returned.
+func (c *Core) emitRuntimeMetrics(lastNumGC uint32) uint32 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	c.metricSink.SetGaugeWithLabels([]string{"process", "goroutines"}, float32(runtime.NumGoroutine()), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "heap_alloc_bytes"}, float32(stats.HeapAlloc), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "heap_inuse_bytes"}, float32(stats.HeapInuse), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "heap_objects"}, float32(stats.HeapObjects), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "sys_bytes"}, float32(stats.Sys), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "gc_runs"}, float32(stats.NumGC), nil)
+	c.metricSink.SetGaugeWithLabels([]string{"process", "gc_pause_total_ns"}, float32(stats.PauseTotalNs), nil)
+
+	// PauseNs is a circular buffer of the most recent pauses; if more
+	// collections than its size happened since the last run, the oldest are
+	// lost
+	num := stats.NumGC
+	if num-lastNumGC >= uint32(len(stats.PauseNs)) {
+		lastNumGC = num - uint32(len(stats.PauseNs))
+	}
+	for i := lastNumGC; i < num; i++ {
+		pause := stats.PauseNs[i%uint32(len(stats.PauseNs))]
+		c.metricSink.AddSampleWithLabels([]string{"process", "gc_pause_ns"}, float32(pause), nil)
+	}
+
+	if fds, err := openFDs(); err == nil {
+		c.metricSink.SetGaugeWithLabels([]string{"process", "open_fds"}, float32(fds), nil)
+	}
+
+	return num
+}
+
+// openFDs returns the number of files opened by the process. It is only
+// supported on systems with procfs.
+func openFDs() (int, error) {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+	return len(fds), nil
+}
@@ -0,0 +1,36 @@
+package vault
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCore_EmitRuntimeMetrics(t *testing.T) {
+	core, _, _, sink := TestCoreUnsealedWithMetrics(t)
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	runtime.GC()
+
+	if numGC := core.emitRuntimeMetrics(stats.NumGC); numGC <= stats.NumGC {
+		t.Fatalf("expected the number of collections to increase from %d, got %d", stats.NumGC, numGC)
+	}
+
+	intervals := sink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	for _, name := range []string{"goroutines", "heap_alloc_bytes", "heap_objects", "gc_runs"} {
+		g, ok := intervals[0].Gauges["process."+name+";cluster=test-cluster"]
+		if !ok {
+			t.Fatalf("missing gauge process.%s: %v", name, intervals[0].Gauges)
+		}
+		if g.Value <= 0 {
+			t.Fatalf("bad value of gauge process.%s: %v", name, g.Value)
+		}
+	}
+	if _, ok := intervals[0].Samples["process.gc_pause_ns;cluster=test-cluster"]; !ok {
+		t.Fatalf("missing gc pause samples: %v", intervals[0].Samples)
+	}
+}
@@ -0,0 +1,172 @@
+package azurekeyvault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// testKeyVaultClient is a keyVaultClient that "wraps" keys by prefixing them
+// with the version of the key used.
+type testKeyVaultClient struct {
+	latestVersion string
+	versions      map[string]bool
+}
+
+func (c *testKeyVaultClient) kid(keyName, keyVersion string) (string, error) {
+	if keyVersion == "" {
+		keyVersion = c.latestVersion
+	}
+	if !c.versions[keyVersion] {
+		return "", errors.New("key version not found")
+	}
+	return "https://test.vault.azure.net/keys/" + keyName + "/" + keyVersion, nil
+}
+
+func (c *testKeyVaultClient) GetKey(ctx context.Context, vaultBaseURL, keyName, keyVersion string) (keyvault.KeyBundle, error) {
+	kid, err := c.kid(keyName, keyVersion)
+	if err != nil {
+		return keyvault.KeyBundle{}, err
+	}
+	return keyvault.KeyBundle{Key: &keyvault.JSONWebKey{Kid: to.StringPtr(kid)}}, nil
+}
+
+func (c *testKeyVaultClient) WrapKey(ctx context.Context, vaultBaseURL, keyName, keyVersion string, parameters keyvault.KeyOperationsParameters) (keyvault.KeyOperationResult, error) {
+	kid, err := c.kid(keyName, keyVersion)
+	if err != nil {
+		return keyvault.KeyOperationResult{}, err
+	}
+	return keyvault.KeyOperationResult{
+		Kid:    to.StringPtr(kid),
+		Result: to.StringPtr(parseKeyVersion(kid) + ":" + to.String(parameters.Value)),
+	}, nil
+}
+
+func (c *testKeyVaultClient) UnwrapKey(ctx context.Context, vaultBaseURL, keyName, keyVersion string, parameters keyvault.KeyOperationsParameters) (keyvault.KeyOperationResult, error) {
+	kid, err := c.kid(keyName, keyVersion)
+	if err != nil {
+		return keyvault.KeyOperationResult{}, err
+	}
+	prefix := parseKeyVersion(kid) + ":"
+	value := to.String(parameters.Value)
+	if len(value) < len(prefix) || value[:len(prefix)] != prefix {
+		return keyvault.KeyOperationResult{}, errors.New("key was wrapped with another version")
+	}
+	// Key Vault returns unpadded values
+	result := value[len(prefix):]
+	for len(result) > 0 && result[len(result)-1] == '=' {
+		result = result[:len(result)-1]
+	}
+	return keyvault.KeyOperationResult{Kid: to.StringPtr(kid), Result: to.StringPtr(result)}, nil
+}
+
+func testWrapper(t *testing.T, client *testKeyVaultClient, config map[string]string) *Wrapper {
+	t.Helper()
+
+	v := NewWrapper(nil)
+	v.client = client
+	if config == nil {
+		config = map[string]string{}
+	}
+	config["vault_name"] = "test"
+	config["key_name"] = "vault-key"
+	if _, err := v.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if v.environment.Name != azure.PublicCloud.Name {
+		t.Fatalf("unexpected environment: %s", v.environment.Name)
+	}
+	return v
+}
+
+func TestAzureKeyVault_Rotation(t *testing.T) {
+	client := &testKeyVaultClient{
+		latestVersion: "v1",
+		versions:      map[string]bool{"v1": true},
+	}
+	v := testWrapper(t, client, nil)
+	if v.KeyID() != "v1" {
+		t.Fatalf("expected key ID v1, got %q", v.KeyID())
+	}
+
+	ctx := context.Background()
+	input := []byte("foo")
+	blob, err := v.Encrypt(ctx, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the key; data encrypted with the prior version can still be
+	// decrypted, and new data is encrypted with the latest version
+	client.versions["v2"] = true
+	client.latestVersion = "v2"
+
+	changed, err := v.refreshKeyID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || v.KeyID() != "v2" {
+		t.Fatalf("expected key ID to change to v2, got %q", v.KeyID())
+	}
+
+	pt, err := v.Decrypt(ctx, blob, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, pt) {
+		t.Fatalf("expected %s, got %s", input, pt)
+	}
+
+	blob, err = v.Encrypt(ctx, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != "v2" {
+		t.Fatalf("expected data to be encrypted with v2, got %q", blob.KeyInfo.KeyID)
+	}
+}
+
+func TestAzureKeyVault_PinnedVersion(t *testing.T) {
+	client := &testKeyVaultClient{
+		latestVersion: "v2",
+		versions:      map[string]bool{"v1": true, "v2": true},
+	}
+	v := testWrapper(t, client, map[string]string{"key_version": "v1"})
+	if v.KeyID() != "v1" {
+		t.Fatalf("expected key ID v1, got %q", v.KeyID())
+	}
+
+	ctx := context.Background()
+	blob, err := v.Encrypt(ctx, []byte("foo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != "v1" {
+		t.Fatalf("expected data to be encrypted with the pinned version, got %q", blob.KeyInfo.KeyID)
+	}
+
+	// Rotating the key doesn't change the version used
+	client.versions["v3"] = true
+	client.latestVersion = "v3"
+	changed, err := v.refreshKeyID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || v.KeyID() != "v1" {
+		t.Fatalf("expected key ID to stay v1, got %q", v.KeyID())
+	}
+
+	// A missing pinned version is rejected
+	v = NewWrapper(nil)
+	v.client = client
+	_, err = v.SetConfig(map[string]string{"vault_name": "test", "key_name": "vault-key", "key_version": "v4"})
+	if err == nil {
+		t.Fatal("expected an error for a missing key version")
+	}
+}
+
@@ -0,0 +1,215 @@
+package seal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-multierror"
+)
+
+// MultiSealEnvelopeAESGCMEncrypt is the mechanism of the data encrypted by a
+// MultiWrapper: the data is encrypted with an envelope key, which is wrapped
+// by each of the seals.
+const MultiSealEnvelopeAESGCMEncrypt uint64 = 0x6d756c7469
+
+// multiSealKey is the envelope key wrapped by one of the seals.
+type multiSealKey struct {
+	Type string `json:"type"`
+	Blob []byte `json:"blob"`
+}
+
+// MultiWrapper is a Wrapper that encrypts data with an envelope key wrapped by
+// each of several seals, such as the KMS of two regions. The data can be
+// decrypted as long as any of the seals is reachable.
+//
+// The first wrapper is the primary one: it determines the type of the seal,
+// and decrypts the data encrypted before the other seals were configured.
+type MultiWrapper struct {
+	logger   log.Logger
+	wrappers []wrapping.Wrapper
+}
+
+// Ensure that we are implementing Wrapper
+var _ wrapping.Wrapper = (*MultiWrapper)(nil)
+
+// NewMultiWrapper creates a wrapper combining the given wrappers, in order of
+// priority.
+func NewMultiWrapper(logger log.Logger, wrappers ...wrapping.Wrapper) (*MultiWrapper, error) {
+	if len(wrappers) == 0 {
+		return nil, errors.New("no wrapper provided")
+	}
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
+	return &MultiWrapper{
+		logger:   logger,
+		wrappers: wrappers,
+	}, nil
+}
+
+// Wrappers returns the combined wrappers, in order of priority.
+func (m *MultiWrapper) Wrappers() []wrapping.Wrapper {
+	return m.wrappers
+}
+
+// Type returns the type of the primary wrapper
+func (m *MultiWrapper) Type() string {
+	return m.wrappers[0].Type()
+}
+
+// KeyID returns the key IDs of all the wrappers. Data whose envelope key
+// couldn't be wrapped by all the seals has a different key ID, so that it's
+// wrapped again once they are reachable.
+func (m *MultiWrapper) KeyID() string {
+	keyIDs := make([]string, 0, len(m.wrappers))
+	for _, w := range m.wrappers {
+		keyIDs = append(keyIDs, w.KeyID())
+	}
+	return strings.Join(keyIDs, ",")
+}
+
+// HMACKeyID returns the HMAC key ID of the primary wrapper
+func (m *MultiWrapper) HMACKeyID() string {
+	return m.wrappers[0].HMACKeyID()
+}
+
+// Init initializes all the wrappers. It only fails if none of them can be
+// initialized.
+func (m *MultiWrapper) Init(ctx context.Context) error {
+	var errs *multierror.Error
+	for _, w := range m.wrappers {
+		if err := w.Init(ctx); err != nil {
+			m.logger.Warn("failed to initialize seal", "type", w.Type(), "error", err)
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if errs != nil && len(errs.Errors) == len(m.wrappers) {
+		return errs
+	}
+	return nil
+}
+
+// Finalize finalizes all the wrappers.
+func (m *MultiWrapper) Finalize(ctx context.Context) error {
+	var errs *multierror.Error
+	for _, w := range m.wrappers {
+		if err := w.Finalize(ctx); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// Encrypt encrypts the data with an envelope key, and wraps the key with each
+// of the seals. It only fails if none of the seals can wrap the key.
+func (m *MultiWrapper) Encrypt(ctx context.Context, plaintext, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	if plaintext == nil {
+		return nil, errors.New("given plaintext for encryption is nil")
+	}
+
+	env, err := wrapping.NewEnvelope(nil).Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	var errs *multierror.Error
+	keys := make([]*multiSealKey, 0, len(m.wrappers))
+	keyIDs := make([]string, 0, len(m.wrappers))
+	for _, w := range m.wrappers {
+		blob, err := w.Encrypt(ctx, env.Key, nil)
+		if err == nil {
+			var blobBytes []byte
+			blobBytes, err = proto.Marshal(blob)
+			if err == nil {
+				keys = append(keys, &multiSealKey{Type: w.Type(), Blob: blobBytes})
+				keyIDs = append(keyIDs, w.KeyID())
+				continue
+			}
+		}
+
+		m.logger.Warn("failed to wrap key with seal", "type", w.Type(), "error", err)
+		errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("error wrapping key with %s seal: {{err}}", w.Type()), err))
+		keyIDs = append(keyIDs, "")
+	}
+	if len(keys) == 0 {
+		return nil, errs
+	}
+
+	wrappedKey, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wrapping.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &wrapping.KeyInfo{
+			Mechanism:  MultiSealEnvelopeAESGCMEncrypt,
+			KeyID:      strings.Join(keyIDs, ","),
+			WrappedKey: wrappedKey,
+		},
+	}, nil
+}
+
+// Decrypt unwraps the envelope key with the first seal that can, and decrypts
+// the data with it. Data that wasn't encrypted by a MultiWrapper is decrypted
+// by the primary wrapper.
+func (m *MultiWrapper) Decrypt(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+
+	if in.KeyInfo == nil || in.KeyInfo.Mechanism != MultiSealEnvelopeAESGCMEncrypt {
+		return m.wrappers[0].Decrypt(ctx, in, aad)
+	}
+
+	var keys []*multiSealKey
+	if err := json.Unmarshal(in.KeyInfo.WrappedKey, &keys); err != nil {
+		return nil, errwrap.Wrapf("error decoding wrapped keys: {{err}}", err)
+	}
+
+	var errs *multierror.Error
+	for _, key := range keys {
+		blob := new(wrapping.EncryptedBlobInfo)
+		if err := proto.Unmarshal(key.Blob, blob); err != nil {
+			errs = multierror.Append(errs, errwrap.Wrapf("error decoding wrapped key: {{err}}", err))
+			continue
+		}
+
+		for _, w := range m.wrappers {
+			if w.Type() != key.Type {
+				continue
+			}
+
+			envKey, err := w.Decrypt(ctx, blob, nil)
+			if err != nil {
+				errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("error unwrapping key with %s seal: {{err}}", w.Type()), err))
+				continue
+			}
+
+			pt, err := wrapping.NewEnvelope(nil).Decrypt(&wrapping.EnvelopeInfo{
+				Key:        envKey,
+				IV:         in.IV,
+				Ciphertext: in.Ciphertext,
+			}, aad)
+			if err != nil {
+				errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("error decrypting data with key unwrapped by %s seal: {{err}}", w.Type()), err))
+				continue
+			}
+			return pt, nil
+		}
+	}
+
+	if errs == nil {
+		return nil, errors.New("no configured seal can unwrap the key")
+	}
+	return nil, errs
+}
@@ -0,0 +1,104 @@
+package seal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+)
+
+// unreachableWrapper is a test wrapper whose KMS can be made unreachable.
+type unreachableWrapper struct {
+	*wrapping.TestWrapper
+	unreachable bool
+}
+
+func (u *unreachableWrapper) Encrypt(ctx context.Context, plaintext, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	if u.unreachable {
+		return nil, errors.New("unreachable")
+	}
+	return u.TestWrapper.Encrypt(ctx, plaintext, aad)
+}
+
+func (u *unreachableWrapper) Decrypt(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if u.unreachable {
+		return nil, errors.New("unreachable")
+	}
+	return u.TestWrapper.Decrypt(ctx, in, aad)
+}
+
+func TestMultiWrapper(t *testing.T) {
+	primary := &unreachableWrapper{TestWrapper: wrapping.NewTestWrapper([]byte("primary"))}
+	primary.SetKeyID("primary-key")
+	secondary := &unreachableWrapper{TestWrapper: wrapping.NewTestWrapper([]byte("secondary"))}
+	secondary.SetKeyID("secondary-key")
+
+	m, err := NewMultiWrapper(nil, primary, secondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	input := []byte("foo")
+	aad := []byte("bar")
+
+	blob, err := m.Encrypt(ctx, input, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != m.KeyID() {
+		t.Fatalf("expected key ID %q, got %q", m.KeyID(), blob.KeyInfo.KeyID)
+	}
+
+	// The data can be decrypted as long as either seal is reachable
+	for _, unreachable := range []*unreachableWrapper{nil, primary, secondary} {
+		if unreachable != nil {
+			unreachable.unreachable = true
+		}
+		pt, err := m.Decrypt(ctx, blob, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(input, pt) {
+			t.Fatalf("expected %s, got %s", input, pt)
+		}
+		if unreachable != nil {
+			unreachable.unreachable = false
+		}
+	}
+
+	primary.unreachable = true
+	secondary.unreachable = true
+	if _, err := m.Decrypt(ctx, blob, aad); err == nil {
+		t.Fatal("expected an error with no reachable seal")
+	}
+	if _, err := m.Encrypt(ctx, input, aad); err == nil {
+		t.Fatal("expected an error with no reachable seal")
+	}
+
+	// Data encrypted while a seal is unreachable has a different key ID, so
+	// that it's wrapped again once the seal is reachable
+	primary.unreachable = false
+	blob, err = m.Encrypt(ctx, input, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID == m.KeyID() {
+		t.Fatalf("expected key ID to differ from %q", m.KeyID())
+	}
+
+	// Data encrypted by the primary seal alone is decrypted by it
+	blob, err = primary.Encrypt(ctx, input, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := m.Decrypt(ctx, blob, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(input, pt) {
+		t.Fatalf("expected %s, got %s", input, pt)
+	}
+}
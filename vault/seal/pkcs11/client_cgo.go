@@ -0,0 +1,482 @@
+// +build cgo
+
+package pkcs11
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The subset of the PKCS#11 v2.40 types used by the wrapper.
+typedef unsigned char CK_BYTE;
+typedef unsigned char CK_BBOOL;
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_SLOT_ID;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef CK_ULONG CK_OBJECT_HANDLE;
+typedef CK_ULONG CK_ATTRIBUTE_TYPE;
+typedef CK_ULONG CK_MECHANISM_TYPE;
+
+typedef struct {
+	CK_ATTRIBUTE_TYPE type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_MECHANISM_TYPE mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	CK_BYTE *pIv;
+	CK_ULONG ulIvLen;
+	CK_ULONG ulIvBits;
+	CK_BYTE *pAAD;
+	CK_ULONG ulAADLen;
+	CK_ULONG ulTagBits;
+} CK_GCM_PARAMS;
+
+#define CKR_OK                            0x000
+#define CKR_USER_ALREADY_LOGGED_IN        0x100
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED  0x191
+
+#define CKF_RW_SESSION      0x2
+#define CKF_SERIAL_SESSION  0x4
+#define CKU_USER            1
+
+#define CKO_SECRET_KEY      0x4
+#define CKK_AES             0x1f
+
+#define CKA_CLASS        0x000
+#define CKA_TOKEN        0x001
+#define CKA_PRIVATE      0x002
+#define CKA_LABEL        0x003
+#define CKA_KEY_TYPE     0x100
+#define CKA_SENSITIVE    0x103
+#define CKA_ENCRYPT      0x104
+#define CKA_DECRYPT      0x105
+#define CKA_VALUE_LEN    0x161
+#define CKA_EXTRACTABLE  0x162
+
+#define CKM_AES_KEY_GEN  0x1080
+#define CKM_AES_GCM      0x1087
+
+// CK_FUNCTION_LIST lists the functions in the order set by the
+// specification; the functions that are not used are left untyped.
+typedef struct {
+	CK_BYTE major;
+	CK_BYTE minor;
+} CK_VERSION;
+
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo;
+	void *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BBOOL, CK_SLOT_ID *, CK_ULONG *);
+	void *C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_SLOT_ID, void *);
+	void *C_GetMechanismList;
+	void *C_GetMechanismInfo;
+	void *C_InitToken;
+	void *C_InitPIN;
+	void *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_SLOT_ID, CK_ULONG, void *, void *, CK_SESSION_HANDLE *);
+	CK_RV (*C_CloseSession)(CK_SESSION_HANDLE);
+	void *C_CloseAllSessions;
+	void *C_GetSessionInfo;
+	void *C_GetOperationState;
+	void *C_SetOperationState;
+	CK_RV (*C_Login)(CK_SESSION_HANDLE, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*C_Logout)(CK_SESSION_HANDLE);
+	void *C_CreateObject;
+	void *C_CopyObject;
+	void *C_DestroyObject;
+	void *C_GetObjectSize;
+	void *C_GetAttributeValue;
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_SESSION_HANDLE);
+	CK_RV (*C_EncryptInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Encrypt)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	void *C_EncryptUpdate;
+	void *C_EncryptFinal;
+	CK_RV (*C_DecryptInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Decrypt)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	void *C_DecryptUpdate;
+	void *C_DecryptFinal;
+	void *C_DigestInit;
+	void *C_Digest;
+	void *C_DigestUpdate;
+	void *C_DigestKey;
+	void *C_DigestFinal;
+	void *C_SignInit;
+	void *C_Sign;
+	void *C_SignUpdate;
+	void *C_SignFinal;
+	void *C_SignRecoverInit;
+	void *C_SignRecover;
+	void *C_VerifyInit;
+	void *C_Verify;
+	void *C_VerifyUpdate;
+	void *C_VerifyFinal;
+	void *C_VerifyRecoverInit;
+	void *C_VerifyRecover;
+	void *C_DigestEncryptUpdate;
+	void *C_DecryptDigestUpdate;
+	void *C_SignEncryptUpdate;
+	void *C_DecryptVerifyUpdate;
+	CK_RV (*C_GenerateKey)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_ATTRIBUTE *, CK_ULONG, CK_OBJECT_HANDLE *);
+	void *C_GenerateKeyPair;
+	void *C_WrapKey;
+	void *C_UnwrapKey;
+	void *C_DeriveKey;
+	void *C_SeedRandom;
+	CK_RV (*C_GenerateRandom)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG);
+} CK_FUNCTION_LIST;
+
+typedef CK_RV (*CK_C_GetFunctionList)(CK_FUNCTION_LIST **);
+
+static CK_RV ck_load(const char *path, void **handle, CK_FUNCTION_LIST **fl) {
+	CK_C_GetFunctionList getFunctionList;
+	CK_RV rv;
+
+	*handle = dlopen(path, RTLD_NOW);
+	if (*handle == NULL) {
+		return (CK_RV)-1;
+	}
+	getFunctionList = (CK_C_GetFunctionList)dlsym(*handle, "C_GetFunctionList");
+	if (getFunctionList == NULL) {
+		dlclose(*handle);
+		return (CK_RV)-1;
+	}
+	rv = getFunctionList(fl);
+	if (rv != CKR_OK) {
+		dlclose(*handle);
+		return rv;
+	}
+	rv = (*fl)->C_Initialize(NULL);
+	if (rv != CKR_OK && rv != CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		dlclose(*handle);
+		return rv;
+	}
+	return CKR_OK;
+}
+
+static void ck_unload(void *handle, CK_FUNCTION_LIST *fl) {
+	fl->C_Finalize(NULL);
+	dlclose(handle);
+}
+
+// ck_find_slot finds the slot of the token with the given label. Token labels
+// are padded with blanks to 32 bytes.
+static CK_RV ck_find_slot(CK_FUNCTION_LIST *fl, const char *label, CK_ULONG labelLen, CK_SLOT_ID *slot, CK_BBOOL *found) {
+	CK_SLOT_ID *slots;
+	CK_ULONG count = 0, i;
+	CK_BYTE padded[32], info[1024];
+	CK_RV rv;
+
+	*found = 0;
+	if (labelLen > sizeof(padded)) {
+		return CKR_OK;
+	}
+	memset(padded, ' ', sizeof(padded));
+	memcpy(padded, label, labelLen);
+
+	rv = fl->C_GetSlotList(1, NULL, &count);
+	if (rv != CKR_OK || count == 0) {
+		return rv;
+	}
+	slots = calloc(count, sizeof(CK_SLOT_ID));
+	if (slots == NULL) {
+		return (CK_RV)-1;
+	}
+	rv = fl->C_GetSlotList(1, slots, &count);
+	for (i = 0; rv == CKR_OK && i < count; i++) {
+		// The label is the first field of CK_TOKEN_INFO
+		rv = fl->C_GetTokenInfo(slots[i], info);
+		if (rv == CKR_OK && memcmp(info, padded, sizeof(padded)) == 0) {
+			*slot = slots[i];
+			*found = 1;
+			break;
+		}
+	}
+	free(slots);
+	return rv;
+}
+
+static CK_RV ck_open_session(CK_FUNCTION_LIST *fl, CK_SLOT_ID slot, const char *pin, CK_ULONG pinLen, CK_SESSION_HANDLE *session) {
+	CK_RV rv;
+
+	rv = fl->C_OpenSession(slot, CKF_SERIAL_SESSION | CKF_RW_SESSION, NULL, NULL, session);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = fl->C_Login(*session, CKU_USER, (CK_BYTE *)pin, pinLen);
+	if (rv != CKR_OK && rv != CKR_USER_ALREADY_LOGGED_IN) {
+		fl->C_CloseSession(*session);
+		return rv;
+	}
+	return CKR_OK;
+}
+
+static void ck_close_session(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session) {
+	fl->C_Logout(session);
+	fl->C_CloseSession(session);
+}
+
+static CK_RV ck_find_key(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, char *label, CK_ULONG labelLen, CK_OBJECT_HANDLE *key, CK_ULONG *count) {
+	CK_ULONG class = CKO_SECRET_KEY;
+	CK_ULONG keyType = CKK_AES;
+	CK_OBJECT_HANDLE keys[2];
+	CK_ATTRIBUTE template[] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_KEY_TYPE, &keyType, sizeof(keyType)},
+		{CKA_LABEL, label, labelLen},
+	};
+	CK_RV rv;
+
+	rv = fl->C_FindObjectsInit(session, template, 3);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = fl->C_FindObjects(session, keys, 2, count);
+	fl->C_FindObjectsFinal(session);
+	if (rv == CKR_OK && *count > 0) {
+		*key = keys[0];
+	}
+	return rv;
+}
+
+static CK_RV ck_generate_key(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, char *label, CK_ULONG labelLen, CK_OBJECT_HANDLE *key) {
+	CK_ULONG class = CKO_SECRET_KEY;
+	CK_ULONG keyType = CKK_AES;
+	CK_ULONG valueLen = 32;
+	CK_BBOOL yes = 1, no = 0;
+	CK_MECHANISM mechanism = {CKM_AES_KEY_GEN, NULL, 0};
+	CK_ATTRIBUTE template[] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_KEY_TYPE, &keyType, sizeof(keyType)},
+		{CKA_VALUE_LEN, &valueLen, sizeof(valueLen)},
+		{CKA_LABEL, label, labelLen},
+		{CKA_TOKEN, &yes, sizeof(yes)},
+		{CKA_PRIVATE, &yes, sizeof(yes)},
+		{CKA_SENSITIVE, &yes, sizeof(yes)},
+		{CKA_EXTRACTABLE, &no, sizeof(no)},
+		{CKA_ENCRYPT, &yes, sizeof(yes)},
+		{CKA_DECRYPT, &yes, sizeof(yes)},
+	};
+
+	return fl->C_GenerateKey(session, &mechanism, template, 10, key);
+}
+
+static CK_RV ck_crypt_gcm(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE key, int encrypt,
+		CK_BYTE *iv, CK_ULONG ivLen, CK_BYTE *aad, CK_ULONG aadLen,
+		CK_BYTE *in, CK_ULONG inLen, CK_BYTE *out, CK_ULONG *outLen) {
+	CK_GCM_PARAMS params = {iv, ivLen, ivLen * 8, aad, aadLen, 128};
+	CK_MECHANISM mechanism = {CKM_AES_GCM, &params, sizeof(params)};
+	CK_RV rv;
+
+	if (encrypt) {
+		rv = fl->C_EncryptInit(session, &mechanism, key);
+		if (rv != CKR_OK) {
+			return rv;
+		}
+		return fl->C_Encrypt(session, in, inLen, out, outLen);
+	}
+	rv = fl->C_DecryptInit(session, &mechanism, key);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	return fl->C_Decrypt(session, in, inLen, out, outLen);
+}
+
+static CK_RV ck_generate_random(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_BYTE *out, CK_ULONG outLen) {
+	return fl->C_GenerateRandom(session, out, outLen);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// gcmTagSize is the size of the tag appended to the AES-GCM ciphertexts.
+const gcmTagSize = 16
+
+// ckError describes a PKCS#11 return value.
+type ckError struct {
+	op string
+	rv C.CK_RV
+}
+
+func (e *ckError) Error() string {
+	return fmt.Sprintf("%s failed: PKCS#11 error 0x%x", e.op, uint64(e.rv))
+}
+
+// cgoClient is an hsmClient that calls the PKCS#11 library of the HSM. The
+// handles of the keys are cached by label.
+type cgoClient struct {
+	handle  unsafe.Pointer
+	fl      *C.CK_FUNCTION_LIST
+	session C.CK_SESSION_HANDLE
+	keys    map[string]C.CK_OBJECT_HANDLE
+}
+
+func newHSMClient(config *hsmConfig) (hsmClient, error) {
+	lib := C.CString(config.lib)
+	defer C.free(unsafe.Pointer(lib))
+
+	c := &cgoClient{
+		keys: make(map[string]C.CK_OBJECT_HANDLE),
+	}
+	if rv := C.ck_load(lib, &c.handle, &c.fl); rv != C.CKR_OK {
+		if msg := C.dlerror(); msg != nil {
+			return nil, fmt.Errorf("error loading PKCS#11 library %q: %s", config.lib, C.GoString(msg))
+		}
+		return nil, &ckError{op: "initializing PKCS#11 library", rv: rv}
+	}
+
+	if err := c.open(config); err != nil {
+		C.ck_unload(c.handle, c.fl)
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *cgoClient) open(config *hsmConfig) error {
+	var slot C.CK_SLOT_ID
+	if config.slot != nil {
+		slot = C.CK_SLOT_ID(*config.slot)
+	} else {
+		tokenLabel := C.CString(config.tokenLabel)
+		defer C.free(unsafe.Pointer(tokenLabel))
+
+		var found C.CK_BBOOL
+		if rv := C.ck_find_slot(c.fl, tokenLabel, C.CK_ULONG(len(config.tokenLabel)), &slot, &found); rv != C.CKR_OK {
+			return &ckError{op: "finding token", rv: rv}
+		}
+		if found == 0 {
+			return fmt.Errorf("no token with label %q found", config.tokenLabel)
+		}
+	}
+
+	pin := C.CString(config.pin)
+	defer C.free(unsafe.Pointer(pin))
+	if rv := C.ck_open_session(c.fl, slot, pin, C.CK_ULONG(len(config.pin)), &c.session); rv != C.CKR_OK {
+		return &ckError{op: "opening session", rv: rv}
+	}
+
+	if _, err := c.key(config.keyLabel, config.generateKey); err != nil {
+		C.ck_close_session(c.fl, c.session)
+		return err
+	}
+	return nil
+}
+
+// key returns the handle of the key with the given label, generating it if
+// it doesn't exist and generate is set.
+func (c *cgoClient) key(label string, generate bool) (C.CK_OBJECT_HANDLE, error) {
+	if key, ok := c.keys[label]; ok {
+		return key, nil
+	}
+
+	cLabel := C.CString(label)
+	defer C.free(unsafe.Pointer(cLabel))
+	labelLen := C.CK_ULONG(len(label))
+
+	var key C.CK_OBJECT_HANDLE
+	var count C.CK_ULONG
+	rv := C.ck_find_key(c.fl, c.session, cLabel, labelLen, &key, &count)
+	switch {
+	case rv != C.CKR_OK:
+		return 0, &ckError{op: "finding key", rv: rv}
+	case count > 1:
+		return 0, fmt.Errorf("more than one key with label %q found", label)
+	case count == 0 && !generate:
+		return 0, fmt.Errorf("no key with label %q found", label)
+	case count == 0:
+		if rv := C.ck_generate_key(c.fl, c.session, cLabel, labelLen, &key); rv != C.CKR_OK {
+			return 0, &ckError{op: "generating key", rv: rv}
+		}
+	}
+
+	c.keys[label] = key
+	return key, nil
+}
+
+func (c *cgoClient) Encrypt(keyLabel string, iv, aad, plaintext []byte) ([]byte, error) {
+	return c.crypt(true, keyLabel, iv, aad, plaintext, len(plaintext)+gcmTagSize)
+}
+
+func (c *cgoClient) Decrypt(keyLabel string, iv, aad, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < gcmTagSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return c.crypt(false, keyLabel, iv, aad, ciphertext, len(ciphertext))
+}
+
+func (c *cgoClient) crypt(encrypt bool, keyLabel string, iv, aad, in []byte, outLen int) ([]byte, error) {
+	key, err := c.key(keyLabel, false)
+	if err != nil {
+		return nil, err
+	}
+
+	op := "decrypting"
+	enc := C.int(0)
+	if encrypt {
+		op = "encrypting"
+		enc = 1
+	}
+
+	// The buffers are copied to C memory since the AES-GCM parameters hold
+	// pointers to them.
+	cIV := C.CBytes(iv)
+	defer C.free(cIV)
+	var cAAD unsafe.Pointer
+	if len(aad) > 0 {
+		cAAD = C.CBytes(aad)
+		defer C.free(cAAD)
+	}
+	cIn := C.CBytes(in)
+	defer C.free(cIn)
+	cOut := C.malloc(C.size_t(outLen))
+	defer C.free(cOut)
+
+	n := C.CK_ULONG(outLen)
+	rv := C.ck_crypt_gcm(c.fl, c.session, key, enc,
+		(*C.CK_BYTE)(cIV), C.CK_ULONG(len(iv)), (*C.CK_BYTE)(cAAD), C.CK_ULONG(len(aad)),
+		(*C.CK_BYTE)(cIn), C.CK_ULONG(len(in)), (*C.CK_BYTE)(cOut), &n)
+	if rv != C.CKR_OK {
+		return nil, &ckError{op: op, rv: rv}
+	}
+	return C.GoBytes(cOut, C.int(n)), nil
+}
+
+func (c *cgoClient) GenerateRandom(n int) ([]byte, error) {
+	if n <= 0 {
+		return []byte{}, nil
+	}
+
+	out := C.malloc(C.size_t(n))
+	defer C.free(out)
+	if rv := C.ck_generate_random(c.fl, c.session, (*C.CK_BYTE)(out), C.CK_ULONG(n)); rv != C.CKR_OK {
+		return nil, &ckError{op: "generating random bytes", rv: rv}
+	}
+	return C.GoBytes(out, C.int(n)), nil
+}
+
+func (c *cgoClient) Close() error {
+	C.ck_close_session(c.fl, c.session)
+	C.ck_unload(c.handle, c.fl)
+	return nil
+}
@@ -0,0 +1,9 @@
+// +build !cgo
+
+package pkcs11
+
+import "errors"
+
+func newHSMClient(config *hsmConfig) (hsmClient, error) {
+	return nil, errors.New("the PKCS#11 seal requires Vault to be built with cgo")
+}
@@ -0,0 +1,301 @@
+package pkcs11
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-kms-wrapping/entropy"
+)
+
+const (
+	EnvHSMLib        = "VAULT_HSM_LIB"
+	EnvHSMSlot       = "VAULT_HSM_SLOT"
+	EnvHSMTokenLabel = "VAULT_HSM_TOKEN_LABEL"
+	EnvHSMPin        = "VAULT_HSM_PIN"
+	EnvHSMKeyLabel   = "VAULT_HSM_KEY_LABEL"
+	EnvHSMGenerate   = "VAULT_HSM_GENERATE_KEY"
+
+	// mechanismAESGCM is CKM_AES_GCM, the mechanism used to wrap the data
+	// encryption keys.
+	mechanismAESGCM = 0x1087
+
+	gcmIVSize = 12
+)
+
+// hsmConfig holds the parameters used to open a session on the HSM and find
+// the wrapping key.
+type hsmConfig struct {
+	lib         string
+	slot        *uint64
+	tokenLabel  string
+	pin         string
+	keyLabel    string
+	generateKey bool
+}
+
+// hsmClient is the subset of the PKCS#11 API used by the wrapper. Keys are
+// AES keys stored on the HSM, found by their label.
+type hsmClient interface {
+	Encrypt(keyLabel string, iv, aad, plaintext []byte) ([]byte, error)
+	Decrypt(keyLabel string, iv, aad, ciphertext []byte) ([]byte, error)
+	GenerateRandom(n int) ([]byte, error)
+	Close() error
+}
+
+// Wrapper is a Wrapper that uses an HSM through its PKCS#11 library for
+// crypto operations. An AES key is generated for each encryption and wrapped
+// by the HSM key using AES-GCM, and stored with the data.
+//
+// The label of the key is stored with the data so that the keys can be
+// rotated: new data is encrypted with the configured key, while the data
+// encrypted with prior keys is decrypted using their labels.
+type Wrapper struct {
+	config hsmConfig
+
+	l      sync.Mutex
+	client hsmClient
+}
+
+// Ensure that we are implementing Wrapper and Sourcer
+var (
+	_ wrapping.Wrapper = (*Wrapper)(nil)
+	_ entropy.Sourcer  = (*Wrapper)(nil)
+)
+
+// NewWrapper creates a new wrapper with the given options
+func NewWrapper(opts *wrapping.WrapperOptions) *Wrapper {
+	return new(Wrapper)
+}
+
+// SetConfig sets the fields on the Wrapper object based on values from the
+// config parameter, and opens a session on the HSM.
+//
+// Order of precedence:
+// * Environment variable
+// * Value from Vault configuration file
+func (h *Wrapper) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	switch {
+	case os.Getenv(EnvHSMLib) != "":
+		h.config.lib = os.Getenv(EnvHSMLib)
+	case config["lib"] != "":
+		h.config.lib = config["lib"]
+	default:
+		return nil, errors.New("PKCS#11 library path is required")
+	}
+
+	slot := os.Getenv(EnvHSMSlot)
+	if slot == "" {
+		slot = config["slot"]
+	}
+	if slot != "" {
+		id, err := strconv.ParseUint(slot, 0, 64)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing slot: {{err}}", err)
+		}
+		h.config.slot = &id
+	}
+
+	switch {
+	case os.Getenv(EnvHSMTokenLabel) != "":
+		h.config.tokenLabel = os.Getenv(EnvHSMTokenLabel)
+	case config["token_label"] != "":
+		h.config.tokenLabel = config["token_label"]
+	}
+
+	switch {
+	case h.config.slot == nil && h.config.tokenLabel == "":
+		return nil, errors.New("either slot or token label is required")
+	case h.config.slot != nil && h.config.tokenLabel != "":
+		return nil, errors.New("slot and token label can't both be set")
+	}
+
+	switch {
+	case os.Getenv(EnvHSMPin) != "":
+		h.config.pin = os.Getenv(EnvHSMPin)
+	case config["pin"] != "":
+		h.config.pin = config["pin"]
+	default:
+		return nil, errors.New("PIN is required")
+	}
+
+	switch {
+	case os.Getenv(EnvHSMKeyLabel) != "":
+		h.config.keyLabel = os.Getenv(EnvHSMKeyLabel)
+	case config["key_label"] != "":
+		h.config.keyLabel = config["key_label"]
+	default:
+		return nil, errors.New("key label is required")
+	}
+
+	generateKey := os.Getenv(EnvHSMGenerate)
+	if generateKey == "" {
+		generateKey = config["generate_key"]
+	}
+	if generateKey != "" {
+		var err error
+		h.config.generateKey, err = strconv.ParseBool(generateKey)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing generate_key: {{err}}", err)
+		}
+	}
+
+	if h.client == nil {
+		client, err := newHSMClient(&h.config)
+		if err != nil {
+			return nil, errwrap.Wrapf("error initializing PKCS#11 wrapper client: {{err}}", err)
+		}
+		h.client = client
+	}
+
+	// Map that holds non-sensitive configuration info
+	wrapperInfo := make(map[string]string)
+	wrapperInfo["lib"] = h.config.lib
+	if h.config.slot != nil {
+		wrapperInfo["slot"] = strconv.FormatUint(*h.config.slot, 10)
+	} else {
+		wrapperInfo["token_label"] = h.config.tokenLabel
+	}
+	wrapperInfo["key_label"] = h.config.keyLabel
+	wrapperInfo["mechanism"] = fmt.Sprintf("0x%x", mechanismAESGCM)
+
+	return wrapperInfo, nil
+}
+
+// Init is called during core.Initialize.  This is a no-op.
+func (h *Wrapper) Init(context.Context) error {
+	return nil
+}
+
+// Finalize is called during shutdown and closes the session on the HSM.
+func (h *Wrapper) Finalize(context.Context) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	if h.client == nil {
+		return nil
+	}
+	err := h.client.Close()
+	h.client = nil
+	return err
+}
+
+// Type returns the type for this particular Wrapper implementation
+func (h *Wrapper) Type() string {
+	return wrapping.PKCS11
+}
+
+// KeyID returns the label of the HSM key
+func (h *Wrapper) KeyID() string {
+	return h.config.keyLabel
+}
+
+// HMACKeyID returns the last known HMAC key id
+func (h *Wrapper) HMACKeyID() string {
+	return ""
+}
+
+// Encrypt is used to encrypt using the HSM. This returns the ciphertext,
+// and/or any errors from this call.
+func (h *Wrapper) Encrypt(_ context.Context, plaintext, aad []byte) (blob *wrapping.EncryptedBlobInfo, err error) {
+	if plaintext == nil {
+		return nil, errors.New("given plaintext for encryption is nil")
+	}
+
+	env, err := wrapping.NewEnvelope(nil).Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	iv := make([]byte, gcmIVSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	h.l.Lock()
+	defer h.l.Unlock()
+	if h.client == nil {
+		return nil, errors.New("nil client")
+	}
+
+	// Wrap the DEK using the HSM key
+	wrappedKey, err := h.client.Encrypt(h.config.keyLabel, iv, nil, env.Key)
+	if err != nil {
+		return nil, errwrap.Wrapf("error encrypting data: {{err}}", err)
+	}
+
+	ret := &wrapping.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &wrapping.KeyInfo{
+			Mechanism:  mechanismAESGCM,
+			KeyID:      h.config.keyLabel,
+			WrappedKey: append(iv, wrappedKey...),
+		},
+	}
+
+	return ret, nil
+}
+
+// Decrypt is used to decrypt the ciphertext.
+func (h *Wrapper) Decrypt(_ context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) (pt []byte, err error) {
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+
+	if in.KeyInfo == nil {
+		return nil, errors.New("key info is nil")
+	}
+	if in.KeyInfo.Mechanism != mechanismAESGCM {
+		return nil, fmt.Errorf("unsupported mechanism 0x%x", in.KeyInfo.Mechanism)
+	}
+	keyLabel := in.KeyInfo.KeyID
+	if keyLabel == "" {
+		keyLabel = h.config.keyLabel
+	}
+	if len(in.KeyInfo.WrappedKey) <= gcmIVSize {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	h.l.Lock()
+	defer h.l.Unlock()
+	if h.client == nil {
+		return nil, errors.New("nil client")
+	}
+
+	// Unwrap the key
+	iv, wrappedKey := in.KeyInfo.WrappedKey[:gcmIVSize], in.KeyInfo.WrappedKey[gcmIVSize:]
+	keyBytes, err := h.client.Decrypt(keyLabel, iv, nil, wrappedKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decrypting data encryption key: {{err}}", err)
+	}
+
+	envInfo := &wrapping.EnvelopeInfo{
+		Key:        keyBytes,
+		IV:         in.IV,
+		Ciphertext: in.Ciphertext,
+	}
+	return wrapping.NewEnvelope(nil).Decrypt(envInfo, aad)
+}
+
+// GetRandom returns random bytes generated by the HSM, so that it can be used
+// as an external source of entropy.
+func (h *Wrapper) GetRandom(bytes int) ([]byte, error) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	if h.client == nil {
+		return nil, errors.New("nil client")
+	}
+
+	return h.client.GenerateRandom(bytes)
+}
@@ -0,0 +1,258 @@
+package pkcs11
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+// testHSMClient is an hsmClient that encrypts with AES-GCM keys held in
+// memory.
+type testHSMClient struct {
+	keys   map[string]cipher.AEAD
+	closed bool
+}
+
+func newTestHSMClient(t *testing.T, keyLabels ...string) *testHSMClient {
+	t.Helper()
+
+	c := &testHSMClient{keys: make(map[string]cipher.AEAD)}
+	for _, label := range keyLabels {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			t.Fatal(err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.keys[label] = aead
+	}
+	return c
+}
+
+func (c *testHSMClient) key(keyLabel string) (cipher.AEAD, error) {
+	aead, ok := c.keys[keyLabel]
+	if !ok {
+		return nil, fmt.Errorf("no key with label %q found", keyLabel)
+	}
+	return aead, nil
+}
+
+func (c *testHSMClient) Encrypt(keyLabel string, iv, aad, plaintext []byte) ([]byte, error) {
+	aead, err := c.key(keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, iv, plaintext, aad), nil
+}
+
+func (c *testHSMClient) Decrypt(keyLabel string, iv, aad, ciphertext []byte) ([]byte, error) {
+	aead, err := c.key(keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, iv, ciphertext, aad)
+}
+
+func (c *testHSMClient) GenerateRandom(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (c *testHSMClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPKCS11Wrapper_SetConfig(t *testing.T) {
+	cases := map[string]struct {
+		config  map[string]string
+		wantErr bool
+	}{
+		"slot": {
+			config: map[string]string{"lib": "/usr/lib/libhsm.so", "slot": "0x1", "pin": "1234", "key_label": "vault"},
+		},
+		"token label": {
+			config: map[string]string{"lib": "/usr/lib/libhsm.so", "token_label": "vault", "pin": "1234", "key_label": "vault"},
+		},
+		"no lib": {
+			config:  map[string]string{"slot": "1", "pin": "1234", "key_label": "vault"},
+			wantErr: true,
+		},
+		"no slot": {
+			config:  map[string]string{"lib": "/usr/lib/libhsm.so", "pin": "1234", "key_label": "vault"},
+			wantErr: true,
+		},
+		"slot and token label": {
+			config:  map[string]string{"lib": "/usr/lib/libhsm.so", "slot": "1", "token_label": "vault", "pin": "1234", "key_label": "vault"},
+			wantErr: true,
+		},
+		"no pin": {
+			config:  map[string]string{"lib": "/usr/lib/libhsm.so", "slot": "1", "key_label": "vault"},
+			wantErr: true,
+		},
+		"no key label": {
+			config:  map[string]string{"lib": "/usr/lib/libhsm.so", "slot": "1", "pin": "1234"},
+			wantErr: true,
+		},
+		"invalid generate_key": {
+			config:  map[string]string{"lib": "/usr/lib/libhsm.so", "slot": "1", "pin": "1234", "key_label": "vault", "generate_key": "maybe"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewWrapper(nil)
+			h.client = newTestHSMClient(t, "vault")
+			info, err := h.SetConfig(tc.config)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("bad: err: %v, wantErr: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, ok := info["pin"]; ok {
+				t.Fatal("PIN was returned in the wrapper info")
+			}
+			if h.KeyID() != "vault" {
+				t.Fatalf("bad: key ID: %q", h.KeyID())
+			}
+		})
+	}
+}
+
+func TestPKCS11Wrapper_Lifecycle(t *testing.T) {
+	client := newTestHSMClient(t, "vault")
+	h := NewWrapper(nil)
+	h.client = client
+	if _, err := h.SetConfig(map[string]string{
+		"lib":       "/usr/lib/libhsm.so",
+		"slot":      "0",
+		"pin":       "1234",
+		"key_label": "vault",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte("foo")
+	aad := []byte("bar")
+	blob, err := h.Encrypt(context.Background(), input, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != "vault" || blob.KeyInfo.Mechanism != mechanismAESGCM {
+		t.Fatalf("bad: key info: %#v", blob.KeyInfo)
+	}
+
+	pt, err := h.Decrypt(context.Background(), blob, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, input) {
+		t.Fatalf("bad: expected %q, got %q", input, pt)
+	}
+
+	if _, err := h.Decrypt(context.Background(), blob, []byte("baz")); err == nil {
+		t.Fatal("expected an error decrypting with the wrong AAD")
+	}
+
+	// A blob encrypted with an unknown key must not be decrypted
+	blob.KeyInfo.KeyID = "other"
+	if _, err := h.Decrypt(context.Background(), blob, aad); err == nil {
+		t.Fatal("expected an error decrypting with an unknown key")
+	}
+
+	if err := h.Finalize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !client.closed {
+		t.Fatal("expected the client to be closed")
+	}
+	if _, err := h.Encrypt(context.Background(), input, aad); err == nil {
+		t.Fatal("expected an error encrypting after finalizing")
+	}
+}
+
+func TestPKCS11Wrapper_KeyRotation(t *testing.T) {
+	client := newTestHSMClient(t, "vault-1", "vault-2")
+	config := map[string]string{
+		"lib":       "/usr/lib/libhsm.so",
+		"slot":      "0",
+		"pin":       "1234",
+		"key_label": "vault-1",
+	}
+
+	h := NewWrapper(nil)
+	h.client = client
+	if _, err := h.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	input := []byte("foo")
+	blob, err := h.Encrypt(context.Background(), input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the key by configuring a new label
+	config["key_label"] = "vault-2"
+	h = NewWrapper(nil)
+	h.client = client
+	if _, err := h.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if h.KeyID() != "vault-2" {
+		t.Fatalf("bad: key ID: %q", h.KeyID())
+	}
+
+	// Data encrypted with the prior key is still decrypted
+	pt, err := h.Decrypt(context.Background(), blob, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, input) {
+		t.Fatalf("bad: expected %q, got %q", input, pt)
+	}
+
+	blob, err = h.Encrypt(context.Background(), input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != "vault-2" {
+		t.Fatalf("bad: key ID: %q", blob.KeyInfo.KeyID)
+	}
+}
+
+func TestPKCS11Wrapper_GetRandom(t *testing.T) {
+	w := &Wrapper{
+		config: hsmConfig{keyLabel: "vault"},
+		client: newTestHSMClient(t, "vault"),
+	}
+
+	out, err := w.GetRandom(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 32 {
+		t.Fatalf("bad length: %d", len(out))
+	}
+
+	if err := w.Finalize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.GetRandom(32); err == nil {
+		t.Fatal("expected error after finalize")
+	}
+}
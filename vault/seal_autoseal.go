@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	proto "github.com/golang/protobuf/proto"
 	"github.com/hashicorp/errwrap"
@@ -15,6 +16,11 @@ import (
 	"github.com/hashicorp/vault/vault/seal"
 )
 
+// autoSealKeyUpgradeInterval is how often the active node checks whether the
+// seal's key was rotated, in order to re-encrypt the stored keys and the
+// recovery key with the new key.
+var autoSealKeyUpgradeInterval = 10 * time.Minute
+
 // barrierTypeUpgradeCheck checks for backwards compat on barrier type, not
 // applicable in the OSS side
 var barrierTypeUpgradeCheck = func(_ string, _ *SealConfig) {}
@@ -153,6 +159,33 @@ func (d *autoSeal) UpgradeKeys(ctx context.Context) error {
 	return nil
 }
 
+// upgradeKeysLoop periodically re-encrypts the stored keys and the recovery
+// key when the seal's key is rotated, until the context is canceled. It holds
+// the rekey lock so that the keys being rekeyed can't be overwritten.
+func (d *autoSeal) upgradeKeysLoop(ctx context.Context) {
+	ticker := time.NewTicker(autoSealKeyUpgradeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.core.rekeyLock.Lock()
+			// The keys are wrapped by another seal after an online migration
+			if d.core.seal != Seal(d) {
+				d.core.rekeyLock.Unlock()
+				return
+			}
+			err := d.UpgradeKeys(ctx)
+			d.core.rekeyLock.Unlock()
+			if err != nil && ctx.Err() == nil {
+				d.logger.Warn("failed to upgrade seal keys", "error", err)
+			}
+		}
+	}
+}
+
 func (d *autoSeal) BarrierConfig(ctx context.Context) (*SealConfig, error) {
 	if d.barrierConfig.Load().(*SealConfig) != nil {
 		return d.barrierConfig.Load().(*SealConfig).Clone(), nil
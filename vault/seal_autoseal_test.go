@@ -5,6 +5,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	proto "github.com/golang/protobuf/proto"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
@@ -157,3 +158,68 @@ func TestAutoSeal_UpgradeKeys(t *testing.T) {
 	}
 	check()
 }
+
+func TestAutoSeal_UpgradeKeysLoop(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+	testSeal := seal.NewTestSeal(nil)
+	testSeal.Wrapper.(*wrapping.TestWrapper).SetKeyID("kaz")
+
+	autoSeal := NewAutoSeal(testSeal)
+	autoSeal.SetCore(core)
+	core.seal = autoSeal
+	pBackend := newTestBackend(t)
+	core.physical = pBackend
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inkeys := [][]byte{[]byte("grist"), []byte("house")}
+	if err := autoSeal.SetStoredKeys(ctx, inkeys); err != nil {
+		t.Fatalf("SetStoredKeys: want no error, got %v", err)
+	}
+	if err := autoSeal.SetRecoveryKey(ctx, []byte("falernum")); err != nil {
+		t.Fatalf("SetRecoveryKey: want no error, got %v", err)
+	}
+
+	// Rotate the seal's key, and expect the loop to re-encrypt the stored
+	// keys with it
+	testSeal.Wrapper.(*wrapping.TestWrapper).SetKeyID("primanti")
+
+	oldInterval := autoSealKeyUpgradeInterval
+	autoSealKeyUpgradeInterval = 10 * time.Millisecond
+	defer func() { autoSealKeyUpgradeInterval = oldInterval }()
+	go autoSeal.upgradeKeysLoop(ctx)
+
+	keyID := func() string {
+		core.rekeyLock.Lock()
+		defer core.rekeyLock.Unlock()
+
+		pe, err := pBackend.Get(ctx, StoredBarrierKeysPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blobInfo := &wrapping.EncryptedBlobInfo{}
+		if err := proto.Unmarshal(pe.Value, blobInfo); err != nil {
+			t.Fatal(err)
+		}
+		return blobInfo.KeyInfo.KeyID
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for keyID() != "primanti" {
+		if time.Now().After(deadline) {
+			t.Fatal("stored keys were not re-encrypted with the rotated key")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	core.rekeyLock.Lock()
+	defer core.rekeyLock.Unlock()
+	outkeys, err := autoSeal.GetStoredKeys(ctx)
+	if err != nil {
+		t.Fatalf("GetStoredKeys: want no error, got %v", err)
+	}
+	if !reflect.DeepEqual(inkeys, outkeys) {
+		t.Errorf("incorrect stored keys: want %v, got %v", inkeys, outkeys)
+	}
+}
@@ -0,0 +1,274 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/shamir"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+)
+
+// SealFactory creates a seal of the given type from its configuration.
+type SealFactory func(sealType string, config map[string]string) (Seal, error)
+
+// onlineSealMigration holds the state of a seal migration performed while
+// Vault is unsealed.
+type onlineSealMigration struct {
+	seal Seal
+
+	// keys holds the unseal key shares provided so far when migrating from
+	// Shamir
+	keys [][]byte
+}
+
+// SealMigrationStatus is the status of an online seal migration.
+type SealMigrationStatus struct {
+	// Type is the type of the seal being migrated to
+	Type string
+
+	// Complete is set once the keys are wrapped by the new seal
+	Complete bool
+
+	// Progress and Required are the number of unseal key shares provided and
+	// required when migrating from Shamir
+	Progress int
+	Required int
+}
+
+// sealMigrationPaths are the physical entries written when the keys are
+// wrapped by a new seal.
+var sealMigrationPaths = []string{
+	barrierSealConfigPath,
+	recoverySealConfigPlaintextPath,
+	recoveryKeyPath,
+	StoredBarrierKeysPath,
+}
+
+// MigrateSealOnline migrates the keys to a new seal while Vault is unsealed,
+// so that the cluster keeps serving requests. The keys of an auto seal are
+// wrapped by the new seal at once; migrating from Shamir requires a threshold
+// of unseal key shares, provided over one or more calls, which become the
+// recovery key shares. The master key doesn't change, so standby nodes aren't
+// affected; each node must use the new seal in its configuration before being
+// restarted.
+func (c *Core) MigrateSealOnline(ctx context.Context, sealType string, config map[string]string, key []byte, reset bool) (*SealMigrationStatus, error) {
+	c.onlineSealMigrationLock.Lock()
+	defer c.onlineSealMigrationLock.Unlock()
+
+	if reset {
+		c.onlineSealMigration = nil
+		if sealType == "" {
+			return &SealMigrationStatus{}, nil
+		}
+	}
+
+	if c.sealFactory == nil {
+		return nil, errors.New("online seal migration is not supported by this server")
+	}
+
+	m := c.onlineSealMigration
+	switch {
+	case m == nil && sealType == "":
+		return nil, errors.New("seal type is required to start a migration")
+	case m == nil:
+		if sealType == wrapping.Shamir {
+			return nil, errors.New("migrating to a shamir seal is not supported while unsealed")
+		}
+
+		newSeal, err := c.sealFactory(sealType, config)
+		if err != nil {
+			return nil, errwrap.Wrapf("error creating the seal to migrate to: {{err}}", err)
+		}
+		if newSeal.StoredKeysSupported() != vaultseal.StoredKeysSupportedGeneric {
+			return nil, fmt.Errorf("migrating to a %q seal is not supported while unsealed", sealType)
+		}
+		newSeal.SetCore(c)
+		if err := newSeal.Init(ctx); err != nil {
+			return nil, errwrap.Wrapf("error initializing the seal to migrate to: {{err}}", err)
+		}
+
+		m = &onlineSealMigration{
+			seal: newSeal,
+		}
+		c.onlineSealMigration = m
+	case sealType != "" && sealType != m.seal.BarrierType():
+		return nil, fmt.Errorf("a migration to a %q seal is in progress", m.seal.BarrierType())
+	}
+
+	status := &SealMigrationStatus{
+		Type: m.seal.BarrierType(),
+	}
+
+	var err error
+	switch c.seal.StoredKeysSupported() {
+	case vaultseal.StoredKeysSupportedGeneric:
+		err = c.migrateSealFromAuto(ctx, m.seal)
+
+	case vaultseal.StoredKeysSupportedShamirMaster:
+		var barrierConf *SealConfig
+		barrierConf, err = c.seal.BarrierConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status.Required = barrierConf.SecretThreshold
+
+		if key != nil {
+			for _, existing := range m.keys {
+				if bytes.Equal(existing, key) {
+					return nil, errors.New("given key has already been provided during this migration")
+				}
+			}
+			m.keys = append(m.keys, key)
+		}
+		status.Progress = len(m.keys)
+		if len(m.keys) < barrierConf.SecretThreshold {
+			return status, nil
+		}
+
+		combinedKey := m.keys[0]
+		if barrierConf.SecretThreshold > 1 {
+			combinedKey, err = shamir.Combine(m.keys)
+		}
+		m.keys = nil
+		status.Progress = 0
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to compute combined key: {{err}}", err)
+		}
+
+		err = c.migrateSealFromShamir(ctx, m.seal, combinedKey, barrierConf)
+
+	default:
+		err = errors.New("the current seal can't be migrated while unsealed")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.onlineSealMigration = nil
+	status.Complete = true
+
+	// The seal is swapped once the request holding the state lock completes.
+	// The keys are wrapped by the new seal already, so the old one can't
+	// overwrite them in the meantime.
+	go c.swapSeal(m.seal)
+
+	c.logger.Info("seal migration complete", "to", m.seal.BarrierType())
+	return status, nil
+}
+
+func (c *Core) migrateSealFromAuto(ctx context.Context, newSeal Seal) error {
+	c.logger.Info("migrating from one auto-unseal to another", "from", c.seal.BarrierType(), "to", newSeal.BarrierType())
+
+	recoveryKey, err := c.seal.RecoveryKey(ctx)
+	if err != nil {
+		return errwrap.Wrapf("error getting recovery key to set on new seal: {{err}}", err)
+	}
+	barrierKeys, err := c.seal.GetStoredKeys(ctx)
+	if err != nil {
+		return errwrap.Wrapf("error getting stored keys to set on new seal: {{err}}", err)
+	}
+	barrierConf, err := c.seal.BarrierConfig(ctx)
+	if err != nil {
+		return err
+	}
+	recoveryConf, err := c.seal.RecoveryConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.writeMigratedSeal(ctx, newSeal, barrierKeys, recoveryKey, barrierConf, recoveryConf)
+}
+
+func (c *Core) migrateSealFromShamir(ctx context.Context, newSeal Seal, combinedKey []byte, shamirConf *SealConfig) error {
+	c.logger.Info("migrating from shamir to auto-unseal", "to", newSeal.BarrierType())
+
+	masterKey, err := c.unsealKeyToMasterKey(ctx, combinedKey)
+	if err != nil {
+		return errwrap.Wrapf("invalid unseal key shares: {{err}}", err)
+	}
+
+	// The unseal key shares become the recovery key shares, like when
+	// migrating offline
+	barrierConf := &SealConfig{
+		Type:            newSeal.BarrierType(),
+		SecretShares:    1,
+		SecretThreshold: 1,
+		StoredShares:    1,
+	}
+	recoveryConf := shamirConf.Clone()
+	recoveryConf.StoredShares = 0
+
+	return c.writeMigratedSeal(ctx, newSeal, [][]byte{masterKey}, combinedKey, barrierConf, recoveryConf)
+}
+
+// writeMigratedSeal wraps the keys with the new seal and stores the seal
+// configurations. The previous entries are restored if any write fails.
+func (c *Core) writeMigratedSeal(ctx context.Context, newSeal Seal, barrierKeys [][]byte, recoveryKey []byte, barrierConf, recoveryConf *SealConfig) (retErr error) {
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	previous := make(map[string]*physical.Entry, len(sealMigrationPaths))
+	for _, path := range sealMigrationPaths {
+		entry, err := c.physical.Get(ctx, path)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to read %q before migration: {{err}}", path), err)
+		}
+		previous[path] = entry
+	}
+
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for path, entry := range previous {
+			var err error
+			if entry == nil {
+				err = c.physical.Delete(ctx, path)
+			} else {
+				err = c.physical.Put(ctx, entry)
+			}
+			if err != nil {
+				c.logger.Error("failed to restore seal entry after failed migration", "path", path, "error", err)
+			}
+		}
+	}()
+
+	barrierConf = barrierConf.Clone()
+	recoveryConf = recoveryConf.Clone()
+	newSeal.SetCachedBarrierConfig(barrierConf)
+	newSeal.SetCachedRecoveryConfig(recoveryConf)
+
+	if err := newSeal.SetRecoveryKey(ctx, recoveryKey); err != nil {
+		return errwrap.Wrapf("error setting new recovery key information during migrate: {{err}}", err)
+	}
+	if err := newSeal.SetStoredKeys(ctx, barrierKeys); err != nil {
+		return errwrap.Wrapf("error setting new barrier key information during migrate: {{err}}", err)
+	}
+	if err := newSeal.SetBarrierConfig(ctx, barrierConf); err != nil {
+		return errwrap.Wrapf("error storing barrier config after migration: {{err}}", err)
+	}
+	if err := newSeal.SetRecoveryConfig(ctx, recoveryConf); err != nil {
+		return errwrap.Wrapf("error storing recovery config after migration: {{err}}", err)
+	}
+
+	return nil
+}
+
+// swapSeal replaces the seal of the core once its keys were migrated.
+func (c *Core) swapSeal(newSeal Seal) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	c.rekeyLock.Lock()
+	c.seal = newSeal
+	c.rekeyLock.Unlock()
+
+	if autoSeal, ok := newSeal.(*autoSeal); ok && c.activeContext != nil {
+		go autoSeal.upgradeKeysLoop(c.activeContext)
+	}
+}
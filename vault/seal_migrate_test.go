@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func testOnlineSealFactory(sealType string, config map[string]string) (Seal, error) {
+	return NewAutoSeal(seal.NewTestSeal(&seal.TestSealOpts{
+		Name:   sealType,
+		Secret: []byte(config["secret"]),
+	})), nil
+}
+
+// testWaitForSeal waits until the core uses a seal of the given type.
+func testWaitForSeal(t *testing.T, c *Core, sealType string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.stateLock.RLock()
+		current := c.seal.BarrierType()
+		c.stateLock.RUnlock()
+		if current == sealType {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected seal type %q, got %q", sealType, current)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testResealWithStoredKeys seals the core and unseals it with the keys stored
+// by its seal.
+func testResealWithStoredKeys(t *testing.T, c *Core, token string) {
+	t.Helper()
+
+	if err := c.Seal(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.Sealed() {
+		t.Fatal("expected core to be unsealed with the stored keys of the new seal")
+	}
+}
+
+func TestCore_MigrateSealOnline_AutoToAuto(t *testing.T) {
+	c, _, recoveryKeys, root := TestCoreUnsealedWithConfigSealOpts(t,
+		&SealConfig{StoredShares: 1, SecretShares: 1, SecretThreshold: 1},
+		&SealConfig{SecretShares: 1, SecretThreshold: 1},
+		&seal.TestSealOpts{Name: "test-a", Secret: []byte("old")})
+	ctx := context.Background()
+
+	if _, err := c.MigrateSealOnline(ctx, "test-b", nil, nil, false); err == nil {
+		t.Fatal("expected an error without a seal factory")
+	}
+	c.sealFactory = testOnlineSealFactory
+
+	status, err := c.MigrateSealOnline(ctx, "test-b", map[string]string{"secret": "new"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Complete || status.Type != "test-b" {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	testWaitForSeal(t, c, "test-b")
+
+	// The recovery key is unchanged
+	if err := c.seal.VerifyRecoveryKey(ctx, recoveryKeys[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	testResealWithStoredKeys(t, c, root)
+}
+
+func TestCore_MigrateSealOnline_ShamirToAuto(t *testing.T) {
+	c, keys, _, root := TestCoreUnsealedWithConfigs(t,
+		&SealConfig{StoredShares: 1, SecretShares: 3, SecretThreshold: 2}, nil)
+	c.sealFactory = testOnlineSealFactory
+	ctx := context.Background()
+
+	status, err := c.MigrateSealOnline(ctx, "test-b", nil, keys[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Complete || status.Progress != 1 || status.Required != 2 {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+
+	if _, err := c.MigrateSealOnline(ctx, "", nil, keys[0], false); err == nil {
+		t.Fatal("expected an error for a duplicate key")
+	}
+	if _, err := c.MigrateSealOnline(ctx, "test-c", nil, nil, false); err == nil {
+		t.Fatal("expected an error for another seal type")
+	}
+
+	status, err = c.MigrateSealOnline(ctx, "", nil, keys[1], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Complete {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	testWaitForSeal(t, c, "test-b")
+
+	recoveryConf, err := c.seal.RecoveryConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recoveryConf.SecretShares != 3 || recoveryConf.SecretThreshold != 2 {
+		t.Fatalf("expected the unseal key shares to become recovery key shares: %#v", recoveryConf)
+	}
+
+	testResealWithStoredKeys(t, c, root)
+}
@@ -157,6 +157,10 @@ func TestCoreWithSealAndUI(t testing.T, opts *CoreConfig) *Core {
 	conf.DisableKeyEncodingChecks = opts.DisableKeyEncodingChecks
 	conf.MetricsHelper = opts.MetricsHelper
 	conf.MetricSink = opts.MetricSink
+	conf.MetricsRollingSink = opts.MetricsRollingSink
+	conf.SecureRandomReader = opts.SecureRandomReader
+	conf.EntropySourcer = opts.EntropySourcer
+	conf.DrainTimeout = opts.DrainTimeout
 
 	if opts.Logger != nil {
 		conf.Logger = opts.Logger
@@ -427,6 +431,13 @@ func TestDynamicSystemView(c *Core) *dynamicSystemView {
 // TestAddTestPlugin registers the testFunc as part of the plugin command to the
 // plugin catalog. If provided, uses tmpDir as the plugin directory.
 func TestAddTestPlugin(t testing.T, c *Core, name string, pluginType consts.PluginType, testFunc string, env []string, tempDir string) {
+	TestAddTestPluginVersion(t, c, name, pluginType, "", testFunc, env, tempDir)
+}
+
+// TestAddTestPluginVersion registers the testFunc as part of the plugin command
+// to the plugin catalog with the given version. If provided, uses tmpDir as the
+// plugin directory.
+func TestAddTestPluginVersion(t testing.T, c *Core, name string, pluginType consts.PluginType, version string, testFunc string, env []string, tempDir string) {
 	file, err := os.Open(os.Args[0])
 	if err != nil {
 		t.Fatal(err)
@@ -488,7 +499,7 @@ func TestAddTestPlugin(t testing.T, c *Core, name string, pluginType consts.Plug
 	c.pluginCatalog.directory = fullPath
 
 	args := []string{fmt.Sprintf("--test.run=%s", testFunc)}
-	err = c.pluginCatalog.Set(context.Background(), name, pluginType, fileName, args, env, sum)
+	err = c.pluginCatalog.Set(context.Background(), name, pluginType, version, fileName, args, env, sum)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1407,10 +1418,15 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.DevLicenseDuration = base.DevLicenseDuration
 		coreConfig.DisableCache = base.DisableCache
 		coreConfig.LicensingConfig = base.LicensingConfig
+		coreConfig.EnablePerformanceStandby = base.EnablePerformanceStandby
 		coreConfig.DisablePerformanceStandby = base.DisablePerformanceStandby
 		coreConfig.MetricsHelper = base.MetricsHelper
+		coreConfig.MetricsRollingSink = base.MetricsRollingSink
 		coreConfig.SecureRandomReader = base.SecureRandomReader
 		coreConfig.DisableSentinelTrace = base.DisableSentinelTrace
+		coreConfig.DrainTimeout = base.DrainTimeout
+		coreConfig.ForwardingCompression = base.ForwardingCompression
+		coreConfig.ForwardingConnections = base.ForwardingConnections
 
 		if base.BuiltinRegistry != nil {
 			coreConfig.BuiltinRegistry = base.BuiltinRegistry
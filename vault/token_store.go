@@ -99,7 +99,31 @@ var (
 			if te.CubbyholeID == "" {
 				return fmt.Errorf("missing cubbyhole ID while destroying")
 			}
-			return ts.cubbyholeBackend.revoke(ctx, te.CubbyholeID)
+			if err := ts.cubbyholeBackend.revoke(ctx, te.CubbyholeID); err != nil {
+				return err
+			}
+
+			// The token may have used the cubbyholes of the child
+			// namespaces of its namespace as well
+			tokenNS, err := NamespaceByID(ctx, te.NamespaceID, ts.core)
+			if err != nil {
+				return err
+			}
+			if tokenNS == nil {
+				return nil
+			}
+			for _, ns := range ts.core.collectNamespaces() {
+				if ns.ID == namespace.RootNamespaceID || !(ns.ID == tokenNS.ID || ns.HasParent(tokenNS)) {
+					continue
+				}
+				nsCtx := namespace.ContextWithNamespace(ctx, ns)
+				if ch, ok := ts.core.router.MatchingBackend(nsCtx, cubbyholeMountPath).(*CubbyholeBackend); ok {
+					if err := ch.revoke(ctx, te.CubbyholeID); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
 		}
 	}
 )
@@ -1261,6 +1285,9 @@ func (ts *TokenStore) lookupInternal(ctx context.Context, id string, salted, tai
 	if len(entry.Policies) == 1 && entry.Policies[0] == "root" && entry.TTL == 0 {
 		// If fields are getting upgraded, store the changes
 		if persistNeeded {
+			if ts.core.perfStandby {
+				return nil, logical.ErrPerfStandbyPleaseForward
+			}
 			if err := ts.store(ctx, entry); err != nil {
 				return nil, errwrap.Wrapf("failed to persist token upgrade: {{err}}", err)
 			}
@@ -1285,6 +1312,10 @@ func (ts *TokenStore) lookupInternal(ctx context.Context, id string, salted, tai
 	switch {
 	// It's any kind of expiring token with no lease, immediately delete it
 	case le == nil:
+		if ts.core.perfStandby {
+			return nil, logical.ErrPerfStandbyPleaseForward
+		}
+
 		tokenNS, err := NamespaceByID(ctx, entry.NamespaceID, ts.core)
 		if err != nil {
 			return nil, err
@@ -1314,6 +1345,9 @@ func (ts *TokenStore) lookupInternal(ctx context.Context, id string, salted, tai
 
 	// If fields are getting upgraded, store the changes
 	if persistNeeded {
+		if ts.core.perfStandby {
+			return nil, logical.ErrPerfStandbyPleaseForward
+		}
 		if err := ts.store(ctx, entry); err != nil {
 			return nil, errwrap.Wrapf("failed to persist token upgrade: {{err}}", err)
 		}
@@ -0,0 +1,291 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// userLockoutPath is the storage prefix of the failed logins of the users
+	// of the auth mounts with a user lockout, by mount accessor
+	userLockoutPath = "core/login/lockout/"
+
+	defaultUserLockoutDuration     = 15 * time.Minute
+	defaultUserLockoutCounterReset = 15 * time.Minute
+)
+
+// userLockoutMountTypes are the types of the auth methods that support the
+// user lockout. They implement the alias lookahead of their login path, and
+// return logical.ErrInvalidCredentials on failed logins.
+var userLockoutMountTypes = []string{"approle", "ldap", "userpass"}
+
+// UserLockoutConfig locks out the users of an auth mount after repeated failed
+// logins
+type UserLockoutConfig struct {
+	// LockoutThreshold is the number of failed logins after which a user is
+	// locked out. The lockout is disabled when it is zero.
+	LockoutThreshold uint64 `json:"lockout_threshold" structs:"lockout_threshold" mapstructure:"lockout_threshold"`
+
+	// LockoutDuration is how long a user stays locked out after the last
+	// failed login.
+	LockoutDuration time.Duration `json:"lockout_duration" structs:"lockout_duration" mapstructure:"lockout_duration"`
+
+	// LockoutCounterReset is the time after the last failed login at which
+	// the count of failed logins restarts.
+	LockoutCounterReset time.Duration `json:"lockout_counter_reset" structs:"lockout_counter_reset" mapstructure:"lockout_counter_reset"`
+}
+
+// responseData returns the user lockout configuration for the API responses
+func (l *UserLockoutConfig) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"lockout_threshold":     l.LockoutThreshold,
+		"lockout_duration":      int64(l.LockoutDuration.Seconds()),
+		"lockout_counter_reset": int64(l.LockoutCounterReset.Seconds()),
+	}
+}
+
+// parseUserLockoutConfig updates the user lockout configuration of an auth
+// mount of the given type with the given values. It returns nil if the lockout
+// is disabled.
+func parseUserLockoutConfig(mountType string, current *UserLockoutConfig, raw map[string]interface{}) (*UserLockoutConfig, error) {
+	if !strutil.StrListContains(userLockoutMountTypes, mountType) {
+		return nil, fmt.Errorf("user lockout is only supported by the %s auth methods", strings.Join(userLockoutMountTypes, ", "))
+	}
+
+	config := &UserLockoutConfig{}
+	if current != nil {
+		*config = *current
+	}
+	for key, value := range raw {
+		switch key {
+		case "lockout_threshold":
+			threshold, err := parseutil.ParseInt(value)
+			if err != nil || threshold < 0 {
+				return nil, fmt.Errorf("invalid lockout_threshold %v", value)
+			}
+			config.LockoutThreshold = uint64(threshold)
+		case "lockout_duration":
+			duration, err := parseutil.ParseDurationSecond(value)
+			if err != nil || duration < 0 {
+				return nil, fmt.Errorf("invalid lockout_duration %v", value)
+			}
+			config.LockoutDuration = duration
+		case "lockout_counter_reset":
+			duration, err := parseutil.ParseDurationSecond(value)
+			if err != nil || duration < 0 {
+				return nil, fmt.Errorf("invalid lockout_counter_reset %v", value)
+			}
+			config.LockoutCounterReset = duration
+		default:
+			return nil, fmt.Errorf("unknown user lockout parameter %q", key)
+		}
+	}
+
+	if config.LockoutThreshold == 0 {
+		return nil, nil
+	}
+	if config.LockoutDuration == 0 {
+		config.LockoutDuration = defaultUserLockoutDuration
+	}
+	if config.LockoutCounterReset == 0 {
+		config.LockoutCounterReset = defaultUserLockoutCounterReset
+	}
+	return config, nil
+}
+
+// userFailedLogins counts the recent failed logins of a user of an auth mount
+type userFailedLogins struct {
+	MountAccessor   string    `json:"mount_accessor"`
+	AliasName       string    `json:"alias_name"`
+	Count           uint64    `json:"count"`
+	LastFailedLogin time.Time `json:"last_failed_login"`
+}
+
+// lockedUntil returns the end of the lockout of the user, which is zero if the
+// user isn't locked out
+func (u *userFailedLogins) lockedUntil(config *UserLockoutConfig, now time.Time) time.Time {
+	if config == nil || u.Count < config.LockoutThreshold {
+		return time.Time{}
+	}
+	expiration := u.LastFailedLogin.Add(config.LockoutDuration)
+	if !now.Before(expiration) {
+		return time.Time{}
+	}
+	return expiration
+}
+
+func userLockoutKey(mountAccessor, aliasName string) string {
+	return userLockoutPath + mountAccessor + "/" + base64.RawURLEncoding.EncodeToString([]byte(aliasName))
+}
+
+// userLockoutAlias returns the alias name the failed logins of a login request
+// are counted against, or an empty string if the auth mount has no user
+// lockout
+func (c *Core) userLockoutAlias(ctx context.Context, entry *MountEntry, req *logical.Request) string {
+	if entry == nil || entry.Table != credentialTableType || entry.Config.UserLockoutConfig == nil {
+		return ""
+	}
+	if !strutil.StrListContains(userLockoutMountTypes, entry.Type) {
+		return ""
+	}
+
+	// The requests the auth method can't resolve to an alias are left to it
+	lookaheadReq := &logical.Request{
+		Operation:  logical.AliasLookaheadOperation,
+		Path:       req.Path,
+		Data:       req.Data,
+		Connection: req.Connection,
+	}
+	resp, err := c.router.Route(ctx, lookaheadReq)
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.Alias == nil {
+		return ""
+	}
+	return resp.Auth.Alias.Name
+}
+
+func (c *Core) userFailedLogins(ctx context.Context, mountAccessor, aliasName string) (*userFailedLogins, error) {
+	storageEntry, err := c.barrier.Get(ctx, userLockoutKey(mountAccessor, aliasName))
+	if err != nil {
+		return nil, err
+	}
+	if storageEntry == nil {
+		return nil, nil
+	}
+
+	var failedLogins userFailedLogins
+	if err := json.Unmarshal(storageEntry.Value, &failedLogins); err != nil {
+		return nil, err
+	}
+	return &failedLogins, nil
+}
+
+// isUserLockedOut returns whether the user of the given alias name is locked
+// out of the auth mount
+func (c *Core) isUserLockedOut(ctx context.Context, entry *MountEntry, aliasName string) (bool, error) {
+	c.userLockoutLock.Lock()
+	defer c.userLockoutLock.Unlock()
+
+	failedLogins, err := c.userFailedLogins(ctx, entry.Accessor, aliasName)
+	if err != nil || failedLogins == nil {
+		return false, err
+	}
+	lockedOut := !failedLogins.lockedUntil(entry.Config.UserLockoutConfig, time.Now()).IsZero()
+	if lockedOut {
+		metrics.IncrCounterWithLabels([]string{"core", "user_lockout", "rejected_login"}, 1, []metrics.Label{
+			{Name: "auth_method", Value: entry.Type},
+			{Name: "mount_point", Value: entry.Path},
+		})
+	}
+	return lockedOut, nil
+}
+
+// updateUserLockout counts the failed login of a user, and clears its failed
+// logins once it logs in successfully
+func (c *Core) updateUserLockout(ctx context.Context, entry *MountEntry, aliasName string, resp *logical.Response, routeErr error) error {
+	config := entry.Config.UserLockoutConfig
+	if config == nil {
+		return nil
+	}
+	failed := routeErr != nil && errwrap.Contains(routeErr, logical.ErrInvalidCredentials.Error())
+	succeeded := routeErr == nil && resp != nil && resp.Auth != nil
+	if !failed && !succeeded {
+		return nil
+	}
+
+	c.userLockoutLock.Lock()
+	defer c.userLockoutLock.Unlock()
+
+	key := userLockoutKey(entry.Accessor, aliasName)
+	if succeeded {
+		return c.barrier.Delete(ctx, key)
+	}
+
+	failedLogins, err := c.userFailedLogins(ctx, entry.Accessor, aliasName)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if failedLogins == nil || now.Sub(failedLogins.LastFailedLogin) >= config.LockoutCounterReset {
+		failedLogins = &userFailedLogins{
+			MountAccessor: entry.Accessor,
+			AliasName:     aliasName,
+		}
+	}
+	failedLogins.Count++
+	failedLogins.LastFailedLogin = now
+
+	storageEntry, err := logical.StorageEntryJSON(key, failedLogins)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, storageEntry); err != nil {
+		return err
+	}
+
+	if failedLogins.Count == config.LockoutThreshold {
+		c.logger.Warn("user locked out after repeated failed logins", "mount_point", entry.Path, "mount_accessor", entry.Accessor, "failed_logins", failedLogins.Count)
+		metrics.IncrCounterWithLabels([]string{"core", "user_lockout"}, 1, []metrics.Label{
+			{Name: "auth_method", Value: entry.Type},
+			{Name: "mount_point", Value: entry.Path},
+		})
+	}
+	return nil
+}
+
+// lockedUsers returns the failed logins of the users locked out of an auth
+// mount
+func (c *Core) lockedUsers(ctx context.Context, entry *MountEntry) ([]*userFailedLogins, error) {
+	c.userLockoutLock.Lock()
+	defer c.userLockoutLock.Unlock()
+
+	prefix := userLockoutPath + entry.Accessor + "/"
+	keys, err := c.barrier.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var lockedUsers []*userFailedLogins
+	for _, key := range keys {
+		aliasName, err := base64.RawURLEncoding.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		failedLogins, err := c.userFailedLogins(ctx, entry.Accessor, string(aliasName))
+		if err != nil {
+			return nil, err
+		}
+		if failedLogins == nil || failedLogins.lockedUntil(entry.Config.UserLockoutConfig, now).IsZero() {
+			continue
+		}
+		lockedUsers = append(lockedUsers, failedLogins)
+	}
+	return lockedUsers, nil
+}
+
+// unlockUser clears the failed logins of a user of an auth mount
+func (c *Core) unlockUser(ctx context.Context, mountAccessor, aliasName string) error {
+	c.userLockoutLock.Lock()
+	defer c.userLockoutLock.Unlock()
+
+	return c.barrier.Delete(ctx, userLockoutKey(mountAccessor, aliasName))
+}
+
+// clearUserLockout removes the failed logins of the users of an auth mount
+// when it is disabled
+func (c *Core) clearUserLockout(ctx context.Context, mountAccessor string) error {
+	c.userLockoutLock.Lock()
+	defer c.userLockoutLock.Unlock()
+
+	return logical.ClearView(ctx, NewBarrierView(c.barrier, userLockoutPath+mountAccessor+"/"))
+}
@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestUserLockout(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	request := func(req *logical.Request) (*logical.Response, error) {
+		t.Helper()
+		if req.Operation == "" {
+			req.Operation = logical.UpdateOperation
+		}
+		req.Connection = &logical.Connection{}
+		return core.HandleRequest(ctx, req)
+	}
+	mustRequest := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		req.ClientToken = root
+		resp, err := request(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("path %q: err: %v, resp: %#v", req.Path, err, resp)
+		}
+		return resp
+	}
+	login := func(password string) (*logical.Response, error) {
+		t.Helper()
+		return request(&logical.Request{
+			Path: "auth/userpass/login/test",
+			Data: map[string]interface{}{"password": password},
+		})
+	}
+	mustLogin := func() {
+		t.Helper()
+		resp, err := login("foo")
+		if err != nil || resp == nil || resp.Auth == nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+	}
+	failLogin := func() {
+		t.Helper()
+		_, err := login("bar")
+		if err == nil || !strings.Contains(err.Error(), logical.ErrInvalidCredentials.Error()) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+	lockedUsers := func() []map[string]interface{} {
+		t.Helper()
+		resp := mustRequest(&logical.Request{
+			Path:      "sys/locked-users",
+			Operation: logical.ReadOperation,
+		})
+		return resp.Data["locked_users"].([]map[string]interface{})
+	}
+
+	mustRequest(&logical.Request{
+		Path: "sys/auth/userpass",
+		Data: map[string]interface{}{"type": "userpass"},
+	})
+	mustRequest(&logical.Request{
+		Path: "auth/userpass/users/test",
+		Data: map[string]interface{}{"password": "foo"},
+	})
+
+	// The user lockout is only supported by some auth methods
+	resp, err := request(&logical.Request{
+		Path:        "sys/auth/token/tune",
+		ClientToken: root,
+		Data: map[string]interface{}{
+			"user_lockout_config": map[string]interface{}{"lockout_threshold": 2},
+		},
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	mustRequest(&logical.Request{
+		Path: "sys/auth/userpass/tune",
+		Data: map[string]interface{}{
+			"user_lockout_config": map[string]interface{}{
+				"lockout_threshold": 2,
+				"lockout_duration":  "1h",
+			},
+		},
+	})
+	resp = mustRequest(&logical.Request{
+		Path:      "sys/auth/userpass/tune",
+		Operation: logical.ReadOperation,
+	})
+	lockoutConfig := resp.Data["user_lockout_config"].(map[string]interface{})
+	if lockoutConfig["lockout_threshold"] != uint64(2) ||
+		lockoutConfig["lockout_duration"] != int64(3600) ||
+		lockoutConfig["lockout_counter_reset"] != int64(900) {
+		t.Fatalf("bad: user lockout config %#v", lockoutConfig)
+	}
+
+	// A successful login clears the failed logins
+	failLogin()
+	mustLogin()
+	failLogin()
+	mustLogin()
+	if users := lockedUsers(); len(users) != 0 {
+		t.Fatalf("bad: locked users %#v", users)
+	}
+
+	// The user is locked out once the threshold is reached, even with the
+	// right password
+	failLogin()
+	failLogin()
+	_, err = login("foo")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	users := lockedUsers()
+	if len(users) != 1 || users[0]["alias_identifier"] != "test" || users[0]["failed_login_attempts"] != uint64(2) {
+		t.Fatalf("bad: locked users %#v", users)
+	}
+
+	mountAccessor := users[0]["mount_accessor"].(string)
+	mustRequest(&logical.Request{
+		Path: "sys/locked-users/" + mountAccessor + "/unlock/test",
+	})
+	if users := lockedUsers(); len(users) != 0 {
+		t.Fatalf("bad: locked users %#v", users)
+	}
+	mustLogin()
+
+	// Disabling the lockout lets the users log in
+	failLogin()
+	failLogin()
+	mustRequest(&logical.Request{
+		Path: "sys/auth/userpass/tune",
+		Data: map[string]interface{}{
+			"user_lockout_config": map[string]interface{}{"lockout_threshold": 0},
+		},
+	})
+	mustLogin()
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// forwardWrapRequest has the active node wrap the response, as a
+// performance standby can't create the wrapping token
 func forwardWrapRequest(context.Context, *Core, *logical.Request, *logical.Response, *logical.Auth) (*logical.Response, error) {
-	return nil, nil
+	return nil, logical.ErrPerfStandbyPleaseForward
 }
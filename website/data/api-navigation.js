@@ -39,6 +39,7 @@ export default [
       { category: 'gcp' },
       { category: 'gcpkms' },
       { category: 'kmip' },
+      { category: 'kubernetes' },
       {
         category: 'kv',
         content: ['kv-v1', 'kv-v2'],
@@ -52,6 +53,7 @@ export default [
           'group-alias',
           'tokens',
           'lookup',
+          'mfa',
         ],
       },
       { category: 'mongodbatlas' },
@@ -60,6 +62,7 @@ export default [
       { category: 'pki' },
       { category: 'rabbitmq' },
       { category: 'ssh' },
+      { category: 'terraform' },
       { category: 'totp' },
       { category: 'transform' },
       { category: 'transit' },
@@ -106,6 +109,7 @@ export default [
       'generate-root',
       'health',
       'host-info',
+      'in-flight-req',
       'init',
       'internal-counters',
       'internal-specs-openapi',
@@ -114,6 +118,7 @@ export default [
       'leader',
       'leases',
       'license',
+      'locked-users',
       'metrics',
       {
         category: 'mfa',
@@ -123,6 +128,7 @@ export default [
       'mounts',
       'namespaces',
       'plugins-reload-backend',
+      'plugins-upgrade-backend',
       'plugins-catalog',
       'policy',
       'policies',
@@ -140,8 +146,10 @@ export default [
         content: ['replication-performance', 'replication-dr'],
       },
       'rotate',
+      'rotate-config',
       'seal',
       'seal-status',
+      'seal-migrate',
       'sealwrap-rewrap',
       'step-down',
       {
@@ -103,7 +103,7 @@ export default [
       'agent',
       {
         category: 'audit',
-        content: ['disable', 'enable', 'list'],
+        content: ['disable', 'enable', 'list', 'verify'],
       },
       {
         category: 'auth',
@@ -111,6 +111,11 @@ export default [
       },
       'debug',
       'delete',
+      'exec',
+      {
+        category: 'identity',
+        content: ['entity'],
+      },
       {
         category: 'kv',
         content: [
@@ -137,6 +142,7 @@ export default [
       {
         category: 'operator',
         content: [
+          'diagnose',
           'generate-root',
           'init',
           'key-status',
@@ -152,7 +158,7 @@ export default [
       'path-help',
       {
         category: 'plugin',
-        content: ['deregister', 'info', 'list', 'register'],
+        content: ['deregister', 'info', 'list', 'register', 'upgrade'],
       },
       {
         category: 'policy',
@@ -238,6 +244,7 @@ export default [
       { category: 'gcp' },
       { category: 'gcpkms' },
       { category: 'kmip' },
+      { category: 'kubernetes' },
       {
         category: 'kv',
         content: ['kv-v1', 'kv-v2'],
@@ -256,6 +263,7 @@ export default [
           'dynamic-ssh-keys',
         ],
       },
+      { category: 'terraform' },
       { category: 'totp' },
       { category: 'transform' },
       { category: 'transit' },
@@ -289,7 +297,7 @@ export default [
   },
   {
     category: 'audit',
-    content: ['file', 'syslog', 'socket'],
+    content: ['file', 'syslog', 'socket', 'otel'],
   },
   {
     category: 'plugin',